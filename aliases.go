@@ -0,0 +1,94 @@
+package mcpgrafana
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// DeprecationMetaKey is the key of the deprecation notice in the metadata
+// of results of tool calls made using a deprecated alias.
+const DeprecationMetaKey = "deprecation"
+
+// Deprecation is the notice added to the metadata of results of tool calls
+// made using a deprecated alias.
+type Deprecation struct {
+	// Alias is the deprecated name the tool was called with.
+	Alias string `json:"alias"`
+	// Tool is the current name of the tool.
+	Tool    string `json:"tool"`
+	Message string `json:"message"`
+}
+
+// SetToolAliases sets the deprecated names of renamed tools, mapping each
+// old name to the tool's current name. Tools registered afterwards are also
+// registered under their old names, so that clients configured with them
+// keep working while the names are cleaned up.
+func SetToolAliases(aliases map[string]string) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.aliases = map[string][]string{}
+	for alias, name := range aliases {
+		registry.aliases[name] = append(registry.aliases[name], alias)
+	}
+	for _, names := range registry.aliases {
+		slices.Sort(names)
+	}
+}
+
+// toolAliases returns the deprecated aliases of the named tool.
+func toolAliases(name string) []string {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	return registry.aliases[name]
+}
+
+// alias returns a copy of the tool named alias, whose calls are handled by
+// the tool and whose results carry a deprecation notice in their metadata.
+func (t *Tool) alias(alias string) Tool {
+	deprecation := Deprecation{
+		Alias:   alias,
+		Tool:    t.Tool.Name,
+		Message: fmt.Sprintf("The tool %s is deprecated and will be removed in a future release; use %s instead.", alias, t.Tool.Name),
+	}
+	tool := t.Tool
+	tool.Name = alias
+	tool.Description = fmt.Sprintf("Deprecated: use %s instead. %s", t.Tool.Name, t.Tool.Description)
+	handler := t.Handler
+	return Tool{
+		Tool: tool,
+		Handler: func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			slog.WarnContext(ctx, "Deprecated tool alias called", "alias", alias, "tool", deprecation.Tool)
+			result, err := handler(ctx, request)
+			if err != nil {
+				return nil, err
+			}
+			r := mcp.CallToolResult{Content: []mcp.Content{}}
+			if result != nil {
+				r = *result
+			}
+			r.Meta = maps.Clone(r.Meta)
+			if r.Meta == nil {
+				r.Meta = map[string]any{}
+			}
+			r.Meta[DeprecationMetaKey] = deprecation
+			return &r, nil
+		},
+	}
+}
+
+// registerAliases registers the deprecated aliases of the tool, if any, in
+// the same category as the tool.
+func (t *Tool) registerAliases(s *server.MCPServer) {
+	for _, name := range toolAliases(t.Tool.Name) {
+		alias := t.alias(name)
+		alias.Tool = withCallArguments(withPolicyNotes(alias.Tool))
+		s.AddTool(alias.Tool, alias.Handler)
+		registry.record(alias.Tool, t.Tool.Name)
+	}
+}
@@ -0,0 +1,59 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolAliases(t *testing.T) {
+	SetToolAliases(map[string]string{"test_old_name": "test_aliased", "test_older_name": "test_aliased"})
+	t.Cleanup(func() { SetToolAliases(nil) })
+
+	s := server.NewMCPServer("test", "0.0.0")
+	tool := MustTool("test_aliased", "A renamed tool", emptyToolHandler, mcp.WithReadOnlyHintAnnotation(true))
+	RegisterCategory(s, "test-aliases", true, func(s *server.MCPServer) {
+		tool.Register(s)
+	})
+
+	info, ok := LookupTool("test_old_name")
+	require.True(t, ok)
+	assert.Equal(t, ToolInfo{Name: "test_old_name", Category: "test-aliases", ReadOnly: true, AliasOf: "test_aliased"}, info)
+	assert.Equal(t, ToolParameters("test_aliased"), ToolParameters("test_old_name"))
+	_, ok = LookupTool("test_older_name")
+	assert.True(t, ok)
+
+	alias := tool.alias("test_old_name")
+	assert.Equal(t, "test_old_name", alias.Tool.Name)
+	assert.Equal(t, "Deprecated: use test_aliased instead. A renamed tool", alias.Tool.Description)
+
+	result, err := alias.Handler(context.Background(), mcp.CallToolRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "empty", result.Content[0].(mcp.TextContent).Text)
+	deprecation, ok := result.Meta[DeprecationMetaKey].(Deprecation)
+	require.True(t, ok)
+	assert.Equal(t, "test_old_name", deprecation.Alias)
+	assert.Equal(t, "test_aliased", deprecation.Tool)
+	assert.Contains(t, deprecation.Message, "use test_aliased instead")
+
+	// Calls of the tool itself carry no deprecation notice.
+	result, err = tool.Handler(context.Background(), mcp.CallToolRequest{})
+	require.NoError(t, err)
+	assert.Nil(t, result.Meta)
+
+	// Empty results still carry the notice.
+	empty := MustTool("test_empty_aliased", "A tool without output", func(ctx context.Context, args emptyToolParams) (string, error) {
+		return "", nil
+	})
+	result, err = empty.alias("test_empty_old_name").Handler(context.Background(), mcp.CallToolRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, result.Content)
+	assert.Contains(t, result.Meta, DeprecationMetaKey)
+}
@@ -0,0 +1,233 @@
+package mcpgrafana
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// redactedParam replaces the values of sensitive parameters in audit logs.
+const redactedParam = "[REDACTED]"
+
+// sensitiveParams are the names of parameters whose values must never be
+// written to audit logs, in lower case and without dashes or underscores.
+// Whole names are matched, so that e.g. the nextToken of a paginated listing
+// is logged.
+var sensitiveParams = map[string]bool{
+	"password":          true,
+	"passwd":            true,
+	"basicauthpassword": true,
+	"secret":            true,
+	"secrets":           true,
+	"clientsecret":      true,
+	"securejsondata":    true,
+	"token":             true,
+	"accesstoken":       true,
+	"idtoken":           true,
+	"refreshtoken":      true,
+	"bearertoken":       true,
+	"apikey":            true,
+	"xapikey":           true,
+	"authorization":     true,
+	"credential":        true,
+	"credentials":       true,
+	"privatekey":        true,
+	"cookie":            true,
+	"setcookie":         true,
+}
+
+// isSensitiveParam returns true if the value of the named parameter must not
+// be written to audit logs.
+func isSensitiveParam(name string) bool {
+	name = strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(name))
+	return sensitiveParams[name]
+}
+
+// NewAuditLogger returns a logger writing JSON audit records to the given
+// destination, which is either "stdout", "stderr" or the path of a file to
+// append to. The returned io.Closer must be closed when the logger is no
+// longer needed.
+func NewAuditLogger(destination string) (*slog.Logger, io.Closer, error) {
+	var w io.WriteCloser
+	switch destination {
+	case "stdout", "-":
+		w = nopCloser{os.Stdout}
+	case "stderr":
+		w = nopCloser{os.Stderr}
+	default:
+		f, err := os.OpenFile(destination, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open audit log: %w", err)
+		}
+		w = f
+	}
+	return slog.New(slog.NewJSONHandler(w, nil)), w, nil
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// redactParams returns a copy of the tool call arguments with the values of
+// sensitive parameters replaced, at any depth. Query expressions are
+// redacted by the query redactor, if one is set.
+func redactParams(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(v))
+		for k, child := range v {
+			if isSensitiveParam(k) {
+				result[k] = redactedParam
+				continue
+			}
+			if queryParamPattern.MatchString(k) && queryRedactionEnabled() {
+				result[k] = redactQueries(child)
+				continue
+			}
+			result[k] = redactParams(child)
+		}
+		return result
+	case []any:
+		result := make([]any, len(v))
+		for i, child := range v {
+			result[i] = redactParams(child)
+		}
+		return result
+	}
+	return v
+}
+
+// redactQueries returns a copy of the value of a query parameter with all
+// strings redacted by the query redactor, at any depth, so that lists of
+// queries and query objects are redacted too.
+func redactQueries(v any) any {
+	switch v := v.(type) {
+	case string:
+		return RedactQuery(v)
+	case map[string]any:
+		result := make(map[string]any, len(v))
+		for k, child := range v {
+			if isSensitiveParam(k) {
+				result[k] = redactedParam
+				continue
+			}
+			result[k] = redactQueries(child)
+		}
+		return result
+	case []any:
+		result := make([]any, len(v))
+		for i, child := range v {
+			result[i] = redactQueries(child)
+		}
+		return result
+	}
+	return v
+}
+
+// hashSecret returns a short, stable hash of a secret so that callers can be
+// told apart in logs without revealing their credentials.
+func hashSecret(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+// jwtSubject returns the subject of a JWT without verifying it, or an empty
+// string if the token cannot be parsed.
+func jwtSubject(token string) string {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+	var claims struct {
+		Subject string `json:"sub"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return ""
+	}
+	return claims.Subject
+}
+
+// callerIdentity describes the caller of a tool without revealing their
+// credentials.
+func callerIdentity(ctx context.Context) slog.Attr {
+	cfg := GrafanaConfigFromContext(ctx)
+	var attrs []any
+	if cfg.URL != "" {
+		attrs = append(attrs, slog.String("grafanaUrl", cfg.URL))
+	}
+	if cfg.OrgID != 0 {
+		attrs = append(attrs, slog.Int64("orgId", cfg.OrgID))
+	}
+	if cfg.APIKey != "" {
+		attrs = append(attrs, slog.String("apiKeyHash", hashSecret(cfg.APIKey)))
+		// Forwarded user access tokens are usually JWTs identifying the user.
+		if sub := jwtSubject(cfg.APIKey); sub != "" {
+			attrs = append(attrs, slog.String("userSubject", sub))
+		}
+	}
+	if cfg.AccessToken != "" {
+		attrs = append(attrs, slog.String("accessTokenHash", hashSecret(cfg.AccessToken)))
+	}
+	if cfg.IDToken != "" {
+		if sub := jwtSubject(cfg.IDToken); sub != "" {
+			attrs = append(attrs, slog.String("idTokenSubject", sub))
+		} else {
+			attrs = append(attrs, slog.String("idTokenHash", hashSecret(cfg.IDToken)))
+		}
+	}
+	if id := sessionIDFromContext(ctx); id != "" {
+		attrs = append(attrs, slog.String("sessionId", id))
+	}
+	return slog.Group("caller", attrs...)
+}
+
+// AuditMiddleware returns a tool handler middleware recording every tool
+// call to the given logger, including the tool name, its arguments with
+// sensitive values and queries redacted, the identity of the caller, the
+// duration of the call and its outcome.
+func AuditMiddleware(logger *slog.Logger) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			start := time.Now()
+			result, err := next(ctx, request)
+
+			attrs := []slog.Attr{
+				slog.String("tool", request.Params.Name),
+				slog.Any("params", redactParams(request.Params.Arguments)),
+				callerIdentity(ctx),
+				slog.Int64("durationMs", time.Since(start).Milliseconds()),
+			}
+			if id := RequestIDFromContext(ctx); id != "" {
+				attrs = append(attrs, slog.String("requestId", id))
+			}
+			if category, ok := ToolCategory(request.Params.Name); ok {
+				attrs = append(attrs, slog.String("category", category))
+			}
+			switch {
+			case err != nil:
+				attrs = append(attrs, slog.String("outcome", "error"), slog.String("error", RedactQuery(err.Error())))
+			case result != nil && result.IsError:
+				attrs = append(attrs, slog.String("outcome", "tool_error"))
+			default:
+				attrs = append(attrs, slog.String("outcome", "success"))
+			}
+			logger.LogAttrs(ctx, slog.LevelInfo, "tool call", attrs...)
+			return result, err
+		}
+	}
+}
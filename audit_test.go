@@ -0,0 +1,93 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactParams(t *testing.T) {
+	params := map[string]any{
+		"uid":      "abc",
+		"apiKey":   "glsa_secret",
+		"settings": map[string]any{"password": "hunter2", "url": "http://example.com"},
+		"headers":  []any{map[string]any{"Authorization": "Bearer x"}},
+	}
+	assert.Equal(t, map[string]any{
+		"uid":      "abc",
+		"apiKey":   redactedParam,
+		"settings": map[string]any{"password": redactedParam, "url": "http://example.com"},
+		"headers":  []any{map[string]any{"Authorization": redactedParam}},
+	}, redactParams(params))
+	// The original arguments must not be modified.
+	assert.Equal(t, "hunter2", params["settings"].(map[string]any)["password"])
+}
+
+func TestIsSensitiveParam(t *testing.T) {
+	for _, name := range []string{"token", "accessToken", "api_key", "X-Api-Key", "basicAuthPassword", "secureJsonData", "Set-Cookie"} {
+		assert.True(t, isSensitiveParam(name), name)
+	}
+	for _, name := range []string{"nextToken", "groupNextToken", "confirmationToken", "decryptSecrets", "entityKeys", "tokenCount"} {
+		assert.False(t, isSensitiveParam(name), name)
+	}
+}
+
+func TestJWTSubject(t *testing.T) {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub": "user:42"}`))
+	assert.Equal(t, "user:42", jwtSubject("header."+payload+".signature"))
+	assert.Empty(t, jwtSubject("not-a-jwt"))
+}
+
+func TestAuditMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	ctx := WithGrafanaConfig(context.Background(), GrafanaConfig{URL: "http://grafana", APIKey: "glsa_secret"})
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "test_audit"
+	request.Params.Arguments = map[string]any{"query": "up", "token": "abc"}
+
+	t.Run("success", func(t *testing.T) {
+		buf.Reset()
+		handler := AuditMiddleware(logger)(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText("ok"), nil
+		})
+		_, err := handler(ctx, request)
+		require.NoError(t, err)
+
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+		assert.Equal(t, "tool call", record["msg"])
+		assert.Equal(t, "test_audit", record["tool"])
+		assert.Equal(t, map[string]any{"query": "up", "token": redactedParam}, record["params"])
+		assert.Equal(t, map[string]any{"grafanaUrl": "http://grafana", "apiKeyHash": hashSecret("glsa_secret")}, record["caller"])
+		assert.Equal(t, "success", record["outcome"])
+		assert.Contains(t, record, "durationMs")
+		assert.NotContains(t, buf.String(), "glsa_secret")
+	})
+
+	t.Run("error", func(t *testing.T) {
+		buf.Reset()
+		handler := AuditMiddleware(logger)(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return nil, errors.New("boom")
+		})
+		_, err := handler(ctx, request)
+		require.Error(t, err)
+
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+		assert.Equal(t, "error", record["outcome"])
+		assert.Equal(t, "boom", record["error"])
+	})
+}
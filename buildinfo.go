@@ -0,0 +1,78 @@
+package mcpgrafana
+
+import (
+	"runtime/debug"
+	"slices"
+	"sync"
+)
+
+// BuildInfo describes the build of the mcp-grafana binary.
+type BuildInfo struct {
+	// Version is the module version of the binary, or "(devel)" if it was
+	// built from the source repository rather than with `go install`.
+	Version   string `json:"version"`
+	GoVersion string `json:"goVersion,omitempty"`
+	// Revision, Time and Modified describe the VCS commit the binary was
+	// built from, if known.
+	Revision string `json:"revision,omitempty"`
+	Time     string `json:"time,omitempty"`
+	Modified bool   `json:"modified,omitempty"`
+	// Dependencies are the versions of the client libraries used by the
+	// tools, by module path.
+	Dependencies map[string]string `json:"dependencies,omitempty"`
+}
+
+// buildInfoDependencies are the modules whose versions are reported in the
+// build info: the MCP implementation and the clients of Grafana and its
+// plugins.
+var buildInfoDependencies = []string{
+	"github.com/mark3labs/mcp-go",
+	"github.com/grafana/grafana-openapi-client-go",
+	"github.com/grafana/amixr-api-go-client",
+	"github.com/grafana/incident-go",
+	"github.com/prometheus/client_golang",
+}
+
+// ReadBuildInfo returns the build info of the binary, populated by the
+// `runtime/debug` package which fetches git information from the build
+// directory.
+var ReadBuildInfo = sync.OnceValue(func() BuildInfo {
+	info := BuildInfo{Version: "(devel)"}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	if bi.Main.Version != "" {
+		info.Version = bi.Main.Version
+	}
+	info.GoVersion = bi.GoVersion
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Revision = s.Value
+		case "vcs.time":
+			info.Time = s.Value
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+	for _, dep := range bi.Deps {
+		if !slices.Contains(buildInfoDependencies, dep.Path) {
+			continue
+		}
+		if info.Dependencies == nil {
+			info.Dependencies = map[string]string{}
+		}
+		version := dep.Version
+		if dep.Replace != nil {
+			version = dep.Replace.Version
+		}
+		info.Dependencies[dep.Path] = version
+	}
+	return info
+})
+
+// Version returns the version of the mcp-grafana binary.
+func Version() string {
+	return ReadBuildInfo().Version
+}
@@ -0,0 +1,175 @@
+package mcpgrafana
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// DefaultCacheMaxEntries is the default maximum number of tool results held
+// in the response cache.
+const DefaultCacheMaxEntries = 1000
+
+// CacheConfig configures the response cache for read-only tools.
+type CacheConfig struct {
+	// TTL is how long a cached tool result remains valid. A zero TTL
+	// disables the cache.
+	TTL time.Duration
+	// MaxEntries is the maximum number of tool results held in the cache.
+	// The least recently used result is evicted when the cache is full.
+	// Defaults to DefaultCacheMaxEntries if not set.
+	MaxEntries int
+}
+
+// Enabled returns true if the cache is enabled.
+func (c CacheConfig) Enabled() bool {
+	return c.TTL > 0
+}
+
+type cacheEntry struct {
+	key     string
+	result  *mcp.CallToolResult
+	expires time.Time
+}
+
+// responseCache is a size-limited LRU cache of tool results with a TTL.
+type responseCache struct {
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+func newResponseCache(config CacheConfig) *responseCache {
+	maxEntries := config.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = DefaultCacheMaxEntries
+	}
+	return &responseCache{
+		ttl:        config.TTL,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    map[string]*list.Element{},
+		order:      list.New(),
+	}
+}
+
+func (c *responseCache) get(key string) (*mcp.CallToolResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*cacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return entry.result, true
+}
+
+func (c *responseCache) set(key string, result *mcp.CallToolResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expires := c.now().Add(c.ttl)
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*cacheEntry)
+		entry.result = result
+		entry.expires = expires
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, result: result, expires: expires})
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// identityHash returns a hash identifying the Grafana instance, organization
+// and credentials used for the current request, so that results fetched with
+// one identity are never returned to another. The locale is included as well,
+// since it changes the text of results.
+func identityHash(ctx context.Context) string {
+	cfg := GrafanaConfigFromContext(ctx)
+	h := sha256.New()
+	for _, s := range []string{cfg.URL, strconv.FormatInt(cfg.OrgID, 10), cfg.APIKey, cfg.AccessToken, cfg.IDToken, cfg.Locale.String()} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cacheKey returns the cache key for a tool call, or false if the arguments
+// cannot be serialized.
+func cacheKey(ctx context.Context, request mcp.CallToolRequest) (string, bool) {
+	args, err := json.Marshal(request.Params.Arguments)
+	if err != nil {
+		return "", false
+	}
+	h := sha256.New()
+	h.Write([]byte(request.Params.Name))
+	h.Write([]byte{0})
+	h.Write(args)
+	h.Write([]byte{0})
+	h.Write([]byte(identityHash(ctx)))
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+type noCacheKey struct{}
+
+// DisableResultCache prevents the result of the current tool call from being
+// cached by CacheMiddleware, e.g. because it contains decrypted secrets. It
+// does nothing if the cache is disabled.
+func DisableResultCache(ctx context.Context) {
+	if noCache, ok := ctx.Value(noCacheKey{}).(*atomic.Bool); ok {
+		noCache.Store(true)
+	}
+}
+
+// CacheMiddleware returns a tool handler middleware caching the results of
+// read-only, idempotent tools for the configured TTL. Results are keyed by
+// the tool name, its arguments and the identity of the caller. Errors, and
+// results of calls which called DisableResultCache, are never cached.
+func CacheMiddleware(config CacheConfig) server.ToolHandlerMiddleware {
+	cache := newResponseCache(config)
+	RecordLimit("cacheTTLSeconds", config.TTL.Seconds())
+	RecordLimit("cacheMaxEntries", cache.maxEntries)
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			info, ok := LookupTool(request.Params.Name)
+			if !ok || !info.ReadOnly || !info.Idempotent {
+				return next(ctx, request)
+			}
+			key, ok := cacheKey(ctx, request)
+			if !ok {
+				return next(ctx, request)
+			}
+			if result, ok := cache.get(key); ok {
+				return result, nil
+			}
+			noCache := &atomic.Bool{}
+			result, err := next(context.WithValue(ctx, noCacheKey{}, noCache), request)
+			if err == nil && result != nil && !result.IsError && !noCache.Load() {
+				cache.set(key, result)
+			}
+			return result, err
+		}
+	}
+}
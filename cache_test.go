@@ -0,0 +1,112 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseCache(t *testing.T) {
+	now := time.Now()
+	c := newResponseCache(CacheConfig{TTL: time.Minute, MaxEntries: 2})
+	c.now = func() time.Time { return now }
+
+	a, b, d := mcp.NewToolResultText("a"), mcp.NewToolResultText("b"), mcp.NewToolResultText("d")
+	c.set("a", a)
+	c.set("b", b)
+
+	result, ok := c.get("a")
+	require.True(t, ok)
+	assert.Same(t, a, result)
+
+	// "b" is now the least recently used entry and is evicted.
+	c.set("d", d)
+	_, ok = c.get("b")
+	assert.False(t, ok)
+	_, ok = c.get("a")
+	assert.True(t, ok)
+
+	// Entries expire after the TTL.
+	now = now.Add(time.Minute)
+	_, ok = c.get("a")
+	assert.False(t, ok)
+}
+
+func TestCacheMiddleware(t *testing.T) {
+	s := server.NewMCPServer("test", "0.0.0")
+	cached := MustTool("test_cache_read", "A cacheable tool", emptyToolHandler, mcp.WithReadOnlyHintAnnotation(true), mcp.WithIdempotentHintAnnotation(true))
+	uncached := MustTool("test_cache_write", "A write tool", emptyToolHandler)
+	cached.Register(s)
+	uncached.Register(s)
+
+	calls := 0
+	var fail bool
+	handler := CacheMiddleware(CacheConfig{TTL: time.Minute})(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		calls++
+		if request.GetArguments()["secret"] == true {
+			DisableResultCache(ctx)
+		}
+		if fail {
+			return nil, errors.New("failed")
+		}
+		return mcp.NewToolResultText("ok"), nil
+	})
+
+	request := func(name string, args map[string]any) mcp.CallToolRequest {
+		req := mcp.CallToolRequest{}
+		req.Params.Name = name
+		req.Params.Arguments = args
+		return req
+	}
+	ctx := WithGrafanaConfig(context.Background(), GrafanaConfig{URL: "http://localhost:3000", APIKey: "one"})
+
+	_, err := handler(ctx, request("test_cache_read", map[string]any{"a": 1, "b": 2}))
+	require.NoError(t, err)
+	_, err = handler(ctx, request("test_cache_read", map[string]any{"b": 2, "a": 1}))
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls, "identical calls should be served from the cache")
+
+	_, err = handler(ctx, request("test_cache_read", map[string]any{"a": 2}))
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls, "calls with different arguments should not be served from the cache")
+
+	otherCtx := WithGrafanaConfig(context.Background(), GrafanaConfig{URL: "http://localhost:3000", APIKey: "two"})
+	_, err = handler(otherCtx, request("test_cache_read", map[string]any{"a": 1, "b": 2}))
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls, "calls with different credentials should not be served from the cache")
+
+	de, err := ParseLocale("de")
+	require.NoError(t, err)
+	localeCtx := WithGrafanaConfig(context.Background(), GrafanaConfig{URL: "http://localhost:3000", APIKey: "one", Locale: de})
+	_, err = handler(localeCtx, request("test_cache_read", map[string]any{"a": 1, "b": 2}))
+	require.NoError(t, err)
+	assert.Equal(t, 4, calls, "calls with different locales should not be served from the cache")
+
+	_, err = handler(ctx, request("test_cache_write", nil))
+	require.NoError(t, err)
+	_, err = handler(ctx, request("test_cache_write", nil))
+	require.NoError(t, err)
+	assert.Equal(t, 6, calls, "tools that are not read-only and idempotent should not be cached")
+
+	_, err = handler(ctx, request("test_cache_read", map[string]any{"secret": true}))
+	require.NoError(t, err)
+	_, err = handler(ctx, request("test_cache_read", map[string]any{"secret": true}))
+	require.NoError(t, err)
+	assert.Equal(t, 8, calls, "results of calls disabling the cache should not be cached")
+
+	fail = true
+	_, err = handler(ctx, request("test_cache_read", map[string]any{"c": 1}))
+	require.Error(t, err)
+	_, err = handler(ctx, request("test_cache_read", map[string]any{"c": 1}))
+	require.Error(t, err)
+	assert.Equal(t, 10, calls, "errors should not be cached")
+}
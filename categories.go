@@ -0,0 +1,244 @@
+package mcpgrafana
+
+import (
+	"maps"
+	"sort"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ToolInfo describes a tool that has been registered with the server.
+type ToolInfo struct {
+	Name        string `json:"name"`
+	Category    string `json:"category,omitempty"`
+	ReadOnly    bool   `json:"readOnly"`
+	Idempotent  bool   `json:"idempotent"`
+	Destructive bool   `json:"destructive"`
+	// AliasOf is the current name of the tool if this is a deprecated
+	// alias of it.
+	AliasOf string `json:"aliasOf,omitempty"`
+}
+
+// FeatureWrite is the feature of a category of tools to create, modify or
+// delete resources.
+const FeatureWrite = "write"
+
+// CategoryInfo describes a category of tools and whether it is enabled.
+type CategoryInfo struct {
+	Name    string   `json:"name"`
+	Enabled bool     `json:"enabled"`
+	Tools   []string `json:"tools,omitempty"`
+	// Features are the features of the category, such as FeatureWrite,
+	// which were set with SetCategoryFeature, and whether they are enabled.
+	Features map[string]bool `json:"features,omitempty"`
+}
+
+// toolRegistry keeps track of the tools registered with the server and the
+// category each of them belongs to, so that tools and middleware can reason
+// about the server's configuration at runtime.
+type toolRegistry struct {
+	mu         sync.RWMutex
+	current    string
+	tools      map[string]ToolInfo
+	params     map[string][]string
+	categories map[string]*CategoryInfo
+	limits     map[string]any
+	// aliases are the deprecated aliases of tools by the tools' names.
+	aliases map[string][]string
+}
+
+func newToolRegistry() *toolRegistry {
+	return &toolRegistry{
+		tools:      map[string]ToolInfo{},
+		params:     map[string][]string{},
+		categories: map[string]*CategoryInfo{},
+		limits:     map[string]any{},
+	}
+}
+
+var registry = newToolRegistry()
+
+// record records a registered tool, which is a deprecated alias of the tool
+// named aliasOf if that is set.
+func (r *toolRegistry) record(t mcp.Tool, aliasOf string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info := ToolInfo{
+		Name:        t.Name,
+		Category:    r.current,
+		ReadOnly:    t.Annotations.ReadOnlyHint != nil && *t.Annotations.ReadOnlyHint,
+		Idempotent:  t.Annotations.IdempotentHint != nil && *t.Annotations.IdempotentHint,
+		Destructive: t.Annotations.DestructiveHint != nil && *t.Annotations.DestructiveHint,
+		AliasOf:     aliasOf,
+	}
+	r.tools[t.Name] = info
+	params := make([]string, 0, len(t.InputSchema.Properties))
+	for name := range t.InputSchema.Properties {
+		params = append(params, name)
+	}
+	sort.Strings(params)
+	r.params[t.Name] = params
+	if c, ok := r.categories[r.current]; ok {
+		c.Tools = append(c.Tools, t.Name)
+	}
+}
+
+// RegisterCategory registers a category of tools with the server by calling
+// add, if the category is enabled. Each tool registered by add is recorded as
+// belonging to the category so it can later be looked up using
+// ToolCategory. Disabled categories are recorded too, so that they can be
+// reported to clients.
+func RegisterCategory(s *server.MCPServer, category string, enabled bool, add func(*server.MCPServer)) {
+	registry.mu.Lock()
+	if _, ok := registry.categories[category]; !ok {
+		registry.categories[category] = &CategoryInfo{Name: category}
+	}
+	registry.categories[category].Enabled = enabled
+	registry.current = category
+	registry.mu.Unlock()
+
+	defer func() {
+		registry.mu.Lock()
+		registry.current = ""
+		registry.mu.Unlock()
+	}()
+
+	if enabled {
+		add(s)
+	}
+}
+
+// ToolCategory returns the category of the named tool, if it was registered
+// using RegisterCategory.
+func ToolCategory(name string) (string, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	info, ok := registry.tools[name]
+	if !ok || info.Category == "" {
+		return "", false
+	}
+	return info.Category, true
+}
+
+// CategoryEnabled returns true if the named category has been registered
+// and is enabled.
+func CategoryEnabled(name string) bool {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	c, ok := registry.categories[name]
+	return ok && c.Enabled
+}
+
+// LookupTool returns information about the named tool, if it has been
+// registered with the server.
+func LookupTool(name string) (ToolInfo, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	info, ok := registry.tools[name]
+	return info, ok
+}
+
+// ToolParameters returns the names of the parameters accepted by the named
+// tool, sorted by name.
+func ToolParameters(name string) []string {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	return append([]string(nil), registry.params[name]...)
+}
+
+// RegisteredTools returns information about all tools registered with the
+// server, sorted by name.
+func RegisteredTools() []ToolInfo {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	tools := make([]ToolInfo, 0, len(registry.tools))
+	for _, t := range registry.tools {
+		tools = append(tools, t)
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	return tools
+}
+
+// Categories returns information about all known tool categories, sorted
+// by name.
+func Categories() []CategoryInfo {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	categories := make([]CategoryInfo, 0, len(registry.categories))
+	for _, c := range registry.categories {
+		info := *c
+		info.Tools = append([]string(nil), c.Tools...)
+		info.Features = maps.Clone(c.Features)
+		sort.Strings(info.Tools)
+		categories = append(categories, info)
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].Name < categories[j].Name })
+	return categories
+}
+
+// SetCategoryFeature records whether a feature of a category of tools, such
+// as FeatureWrite, is enabled on this server. It must be called before the
+// category is registered, so that the descriptions of its tools can tell
+// clients about disabled features.
+func SetCategoryFeature(category, feature string, enabled bool) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	c, ok := registry.categories[category]
+	if !ok {
+		c = &CategoryInfo{Name: category}
+		registry.categories[category] = c
+	}
+	if c.Features == nil {
+		c.Features = map[string]bool{}
+	}
+	c.Features[feature] = enabled
+}
+
+// CategoryFeature returns whether a feature of a category of tools is
+// enabled, and false for ok if it was never set.
+func CategoryFeature(category, feature string) (enabled, ok bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	c, found := registry.categories[category]
+	if !found {
+		return false, false
+	}
+	enabled, ok = c.Features[feature]
+	return enabled, ok
+}
+
+// WriteToolsEnabled returns whether the tools which create, modify or delete
+// resources should be registered by the category being registered with
+// RegisterCategory. They are unless FeatureWrite was disabled for the
+// category with SetCategoryFeature.
+func WriteToolsEnabled() bool {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	if c, ok := registry.categories[registry.current]; ok {
+		if enabled, ok := c.Features[FeatureWrite]; ok {
+			return enabled
+		}
+	}
+	return true
+}
+
+// RecordLimit records a server-wide configuration limit so that it can be
+// reported to clients, e.g. by the capabilities tool.
+func RecordLimit(name string, value any) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.limits[name] = value
+}
+
+// Limits returns a copy of all recorded server-wide configuration limits.
+func Limits() map[string]any {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	limits := make(map[string]any, len(registry.limits))
+	for k, v := range registry.limits {
+		limits[k] = v
+	}
+	return limits
+}
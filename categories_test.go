@@ -0,0 +1,95 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterCategory(t *testing.T) {
+	s := server.NewMCPServer("test", "0.0.0")
+	readOnly := MustTool("test_category_read", "A read-only tool", emptyToolHandler, mcp.WithReadOnlyHintAnnotation(true))
+	write := MustTool("test_category_write", "A write tool", emptyToolHandler, mcp.WithDestructiveHintAnnotation(true))
+	disabled := MustTool("test_category_disabled", "A disabled tool", emptyToolHandler)
+
+	RegisterCategory(s, "test-enabled", true, func(s *server.MCPServer) {
+		readOnly.Register(s)
+		write.Register(s)
+	})
+	RegisterCategory(s, "test-disabled", false, func(s *server.MCPServer) {
+		disabled.Register(s)
+	})
+
+	category, ok := ToolCategory("test_category_read")
+	require.True(t, ok)
+	assert.Equal(t, "test-enabled", category)
+
+	_, ok = ToolCategory("test_category_disabled")
+	assert.False(t, ok, "tools in disabled categories should not be registered")
+
+	var enabled, notEnabled *CategoryInfo
+	for _, c := range Categories() {
+		switch c.Name {
+		case "test-enabled":
+			enabled = &c
+		case "test-disabled":
+			notEnabled = &c
+		}
+	}
+	require.NotNil(t, enabled)
+	require.NotNil(t, notEnabled)
+	assert.True(t, enabled.Enabled)
+	assert.Equal(t, []string{"test_category_read", "test_category_write"}, enabled.Tools)
+	assert.False(t, notEnabled.Enabled)
+	assert.Empty(t, notEnabled.Tools)
+
+	var infos []ToolInfo
+	for _, info := range RegisteredTools() {
+		if info.Category == "test-enabled" {
+			infos = append(infos, info)
+		}
+	}
+	assert.Equal(t, []ToolInfo{
+		{Name: "test_category_read", Category: "test-enabled", ReadOnly: true},
+		{Name: "test_category_write", Category: "test-enabled", Destructive: true},
+	}, infos)
+
+	// Tools registered outside of a category have no category.
+	uncategorized := MustTool("test_uncategorized", "An uncategorized tool", func(ctx context.Context, args emptyToolParams) (string, error) {
+		return "", nil
+	})
+	uncategorized.Register(s)
+	_, ok = ToolCategory("test_uncategorized")
+	assert.False(t, ok)
+}
+
+func TestRecordLimit(t *testing.T) {
+	RecordLimit("testLimit", 42)
+	limits := Limits()
+	assert.Equal(t, 42, limits["testLimit"])
+
+	// Mutating the returned map must not affect the registry.
+	limits["testLimit"] = 0
+	assert.Equal(t, 42, Limits()["testLimit"])
+}
+
+func TestWriteToolsEnabled(t *testing.T) {
+	s := server.NewMCPServer("test", "0.0.0")
+	assert.True(t, WriteToolsEnabled(), "write tools are enabled outside of categories")
+
+	SetCategoryFeature("test-write-disabled", FeatureWrite, false)
+	var enabled []bool
+	for _, category := range []string{"test-write-disabled", "test-write-default"} {
+		RegisterCategory(s, category, true, func(s *server.MCPServer) {
+			enabled = append(enabled, WriteToolsEnabled())
+		})
+	}
+	assert.Equal(t, []bool{false, true}, enabled)
+}
@@ -2,14 +2,17 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
+	"net/http"
 	"os"
-	"runtime/debug"
 	"slices"
 	"strings"
-	"sync"
+	"time"
 
 	"github.com/mark3labs/mcp-go/server"
 
@@ -17,32 +20,29 @@ import (
 	"github.com/grafana/mcp-grafana/tools"
 )
 
-// version returns the version of the mcp-grafana binary.
-// It is populated by the `runtime/debug` package which
-// fetches git information from the build directory.
-var version = sync.OnceValue(func() string {
-	// Default version string returned by `runtime/debug` if built
-	// from the source repository rather than with `go install`.
-	v := "(devel)"
-	if bi, ok := debug.ReadBuildInfo(); ok {
-		v = bi.Main.Version
-	}
-	return v
-})
-
 func maybeAddTools(s *server.MCPServer, tf func(*server.MCPServer), enabledTools []string, disable bool, category string) {
 	if !slices.Contains(enabledTools, category) {
 		slog.Debug("Not enabling tools", "category", category)
+		mcpgrafana.RegisterCategory(s, category, false, tf)
 		return
 	}
 	if disable {
 		slog.Info("Disabling tools", "category", category)
+		mcpgrafana.RegisterCategory(s, category, false, tf)
 		return
 	}
 	slog.Debug("Enabling tools", "category", category)
-	tf(s)
+	mcpgrafana.RegisterCategory(s, category, true, tf)
 }
 
+// toolCategories are the categories of tools which can be enabled or
+// disabled.
+var toolCategories = []string{"search", "datasource", "incident", "prometheus", "loki", "alerting", "dashboard", "oncall", "asserts", "sift", "admin", "pyroscope", "tempo", "elasticsearch", "graphite", "migration", "notebook", "workspace"}
+
+// writeToolCategories are the categories of tools with write tools, which
+// are disabled by --disable-write and --read-only-categories.
+var writeToolCategories = []string{"incident", "alerting", "dashboard", "oncall", "admin", "notebook", "workspace"}
+
 // disabledTools indicates whether each category of tools should be disabled.
 type disabledTools struct {
 	enabledTools string
@@ -50,7 +50,17 @@ type disabledTools struct {
 	search, datasource, incident,
 	prometheus, loki, alerting,
 	dashboard, oncall, asserts, sift, admin,
-	pyroscope bool
+	pyroscope, tempo, elasticsearch, graphite, migration,
+	notebook, workspace bool
+
+	// write disables tools which create, modify or delete resources.
+	write bool
+	// readOnlyCategories is a comma separated list of categories whose write
+	// tools are disabled.
+	readOnlyCategories string
+
+	// deprecatedAliases disables the deprecated aliases of renamed tools.
+	deprecatedAliases bool
 }
 
 // Configuration for the Grafana client.
@@ -58,15 +68,209 @@ type grafanaConfig struct {
 	// Whether to enable debug mode for the Grafana transport.
 	debug bool
 
+	// The ID of the Grafana organization to use, or 0 for the default
+	// organization of the credentials.
+	orgID int64
+
+	// The locale of numbers, durations and dates in rendered text.
+	locale string
+
+	// Extra headers sent with every request to Grafana, set with
+	// --grafana-header, and the file to read more of them from.
+	headers     http.Header
+	headersFile string
+
+	// Default scope of tools listing or searching resources.
+	scope mcpgrafana.Scope
+
+	// Types of datasources queried through /api/ds/query.
+	dsQuery []string
+
+	// Maximum sizes of the results of Loki queries.
+	lokiLimits mcpgrafana.LokiLimits
+
+	// YAML file of the rubric of incident severity suggestions.
+	severityRubricFile string
+
+	// Retries of requests failing with 429 or transient 5xx responses.
+	retry mcpgrafana.RetryConfig
+
+	// Disk cache of immutable artifacts.
+	diskCache mcpgrafana.DiskCacheConfig
+
+	// Failover to a standby Grafana instance.
+	failover mcpgrafana.FailoverConfig
+
+	// Pushing the metrics of the server to a datasource.
+	selfMetrics mcpgrafana.SelfMetricsConfig
+
+	// Grafana instance compared with by the migration tools.
+	migrationTarget mcpgrafana.MigrationTarget
+
 	// TLS configuration
 	tlsCertFile   string
 	tlsKeyFile    string
 	tlsCAFile     string
 	tlsSkipVerify bool
+
+	// OAuth token passthrough configuration
+	oauthPassthrough bool
+	oauthIssuer      string
+	oauthAudience    string
+}
+
+// Configuration for the limits and output processing applied to tool calls.
+type limitsConfig struct {
+	rateLimit  mcpgrafana.RateLimitConfig
+	timeout    mcpgrafana.TimeoutConfig
+	cache      mcpgrafana.CacheConfig
+	truncation mcpgrafana.TruncationConfig
+	redaction  mcpgrafana.RedactionConfig
+
+	// The tool policy file and the policy loaded from it.
+	policyFile string
+	policy     *mcpgrafana.Policy
+}
+
+// dsQueryTypes are the types of datasources whose queries can be run
+// through /api/ds/query with --ds-query.
+var dsQueryTypes = []string{"loki", "prometheus"}
+
+// defaultCategoryTimeouts are the default timeouts of tool categories whose
+// tools take longer than --timeout.
+var defaultCategoryTimeouts = map[string]time.Duration{
+	// Sift tools wait up to 5 minutes for investigations to complete.
+	"sift": 6 * time.Minute,
+	// The OnCall paging drill waits up to 5 minutes for test pages to be
+	// acknowledged.
+	"oncall": 6 * time.Minute,
+}
+
+func (lc *limitsConfig) addFlags() {
+	flag.Float64Var(&lc.rateLimit.GlobalRate, "rate-limit", 0, "Maximum number of tool calls per second across all sessions (0 for no limit)")
+	flag.IntVar(&lc.rateLimit.GlobalBurst, "rate-limit-burst", 0, "Number of tool calls allowed in a burst above --rate-limit (defaults to the rate)")
+	flag.Float64Var(&lc.rateLimit.SessionRate, "session-rate-limit", 0, "Maximum number of tool calls per second for a single client session (0 for no limit)")
+	flag.IntVar(&lc.rateLimit.SessionBurst, "session-rate-limit-burst", 0, "Number of tool calls allowed in a burst above --session-rate-limit (defaults to the rate)")
+	flag.IntVar(&lc.rateLimit.MaxConcurrent, "max-concurrent-tool-calls", 0, "Maximum number of tool calls executing at the same time (0 for no limit)")
+
+	flag.DurationVar(&lc.timeout.Default, "timeout", 2*time.Minute, "Maximum duration of a tool call (0 for no timeout). Override it for a category of tools with --timeout-<category>, e.g. --timeout-loki=60s")
+	flag.DurationVar(&lc.timeout.Max, "max-call-timeout", 10*time.Minute, "Maximum timeout a tool call may request with its timeoutSeconds argument, overriding --timeout (0 to ignore the argument)")
+	lc.timeout.Categories = maps.Clone(defaultCategoryTimeouts)
+	for _, category := range toolCategories {
+		usage := fmt.Sprintf("Maximum duration of tool calls in the %s category, overriding --timeout (0 for no timeout)", category)
+		if d, ok := defaultCategoryTimeouts[category]; ok {
+			usage += fmt.Sprintf(" (default %s)", d)
+		}
+		flag.Func("timeout-"+category, usage, func(s string) error {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return err
+			}
+			lc.timeout.Categories[category] = d
+			return nil
+		})
+	}
+
+	flag.DurationVar(&lc.cache.TTL, "cache-ttl", 0, "How long to cache the results of read-only tools, e.g. 30s (0 to disable caching)")
+	flag.IntVar(&lc.cache.MaxEntries, "cache-max-entries", mcpgrafana.DefaultCacheMaxEntries, "Maximum number of tool results held in the cache")
+
+	flag.IntVar(&lc.truncation.MaxBytes, "max-output-bytes", 0, "Maximum size in bytes of a tool result; larger results are truncated (0 for no limit)")
+
+	flag.Func("redact-labels", "Comma separated list of sensitive label names, e.g. email,customer_id, whose values are redacted in tool results", func(s string) error {
+		for label := range strings.SplitSeq(s, ",") {
+			if label = strings.TrimSpace(label); label != "" {
+				lc.redaction.Labels = append(lc.redaction.Labels, label)
+			}
+		}
+		return nil
+	})
+	flag.StringVar(&lc.policyFile, "tool-policy-file", "", "YAML file mapping the identities of callers (API key hash or JWT subject) to the tools and tool categories they may call and their rate limits, checked on every tool call (disabled by default)")
+	flag.StringVar(&lc.redaction.Mode, "redact-mode", mcpgrafana.RedactionModeHash, "How values of --redact-labels are redacted: hash, replacing them with a hash which is stable while the server runs, or mask")
+}
+
+// serverOptions returns the MCP server options implementing the configured limits.
+func (lc *limitsConfig) serverOptions() []server.ServerOption {
+	var opts []server.ServerOption
+	// The policy is checked first, so that callers don't get cached results
+	// of tools they may not call.
+	if lc.policy != nil {
+		opts = append(opts,
+			server.WithToolHandlerMiddleware(mcpgrafana.PolicyMiddleware(lc.policy)),
+			server.WithToolFilter(mcpgrafana.PolicyToolFilter(lc.policy)),
+		)
+	}
+	// The client's deadline applies to the whole call, including time spent
+	// waiting for a rate limit slot.
+	opts = append(opts, server.WithToolHandlerMiddleware(mcpgrafana.ClientDeadlineMiddleware()))
+	// The cache is the outermost of the other middleware so that cached
+	// results don't count towards the rate limits.
+	if lc.cache.Enabled() {
+		opts = append(opts, server.WithToolHandlerMiddleware(mcpgrafana.CacheMiddleware(lc.cache)))
+	}
+	if lc.rateLimit.Enabled() {
+		opts = append(opts, server.WithToolHandlerMiddleware(mcpgrafana.RateLimitMiddleware(lc.rateLimit)))
+	}
+	// The timeout applies inside the rate limits, so that time spent waiting
+	// for a free slot doesn't count towards it.
+	if lc.timeout.Enabled() {
+		opts = append(opts, server.WithToolHandlerMiddleware(mcpgrafana.TimeoutMiddleware(lc.timeout)))
+	}
+	if lc.truncation.Enabled() {
+		opts = append(opts, server.WithToolHandlerMiddleware(mcpgrafana.TruncationMiddleware(lc.truncation)))
+	}
+	// Redaction is the innermost middleware so that sensitive values are
+	// neither cached nor measured when truncating results.
+	if lc.redaction.Enabled() {
+		opts = append(opts, server.WithToolHandlerMiddleware(mcpgrafana.RedactionMiddleware(lc.redaction)))
+	}
+	return opts
+}
+
+// Configuration for the audit log of tool calls.
+type auditConfig struct {
+	// destination is "stdout", "stderr" or the path of a file. Empty
+	// disables the audit log.
+	destination string
+	// queryRedaction redacts sensitive identifiers in the queries written
+	// to the audit log and to logs.
+	queryRedaction mcpgrafana.QueryRedactionConfig
+}
+
+func (ac *auditConfig) addFlags() {
+	flag.StringVar(&ac.destination, "audit-log", "", "Write a JSON audit record of every tool call to this destination: stdout, stderr or a file path (disabled by default)")
+	flag.Func("redact-query-labels", "Comma separated list of label names, e.g. customer_id,email, whose values in label matchers of queries are redacted in the audit log and logs", func(s string) error {
+		for label := range strings.SplitSeq(s, ",") {
+			if label = strings.TrimSpace(label); label != "" {
+				ac.queryRedaction.Labels = append(ac.queryRedaction.Labels, label)
+			}
+		}
+		return nil
+	})
+	flag.Func("redact-query-pattern", "Regular expression whose matches in queries are redacted in the audit log and logs, e.g. to redact identifiers in line filters. Can be repeated", func(s string) error {
+		ac.queryRedaction.Patterns = append(ac.queryRedaction.Patterns, s)
+		return nil
+	})
+	flag.StringVar(&ac.queryRedaction.Mode, "redact-query-mode", mcpgrafana.RedactionModeHash, "How values redacted from queries are replaced: hash, with a hash which is stable while the server runs, or mask")
+}
+
+// serverOptions returns the MCP server options recording the audit log, and
+// a closer for the log's destination.
+func (ac *auditConfig) serverOptions(transport string) ([]server.ServerOption, io.Closer, error) {
+	if ac.destination == "" {
+		return nil, io.NopCloser(nil), nil
+	}
+	if transport == "stdio" && (ac.destination == "stdout" || ac.destination == "-") {
+		return nil, nil, errors.New("the audit log cannot be written to stdout when using the stdio transport")
+	}
+	logger, closer, err := mcpgrafana.NewAuditLogger(ac.destination)
+	if err != nil {
+		return nil, nil, err
+	}
+	return []server.ServerOption{server.WithToolHandlerMiddleware(mcpgrafana.AuditMiddleware(logger))}, closer, nil
 }
 
 func (dt *disabledTools) addFlags() {
-	flag.StringVar(&dt.enabledTools, "enabled-tools", "search,datasource,incident,prometheus,loki,alerting,dashboard,oncall,asserts,sift,admin,pyroscope", "A comma separated list of tools enabled for this server. Can be overwritten entirely or by disabling specific components, e.g. --disable-search.")
+	flag.StringVar(&dt.enabledTools, "enabled-tools", strings.Join(toolCategories, ","), "A comma separated list of tools enabled for this server. Can be overwritten entirely or by disabling specific components, e.g. --disable-search.")
 
 	flag.BoolVar(&dt.search, "disable-search", false, "Disable search tools")
 	flag.BoolVar(&dt.datasource, "disable-datasource", false, "Disable datasource tools")
@@ -80,20 +284,172 @@ func (dt *disabledTools) addFlags() {
 	flag.BoolVar(&dt.sift, "disable-sift", false, "Disable sift tools")
 	flag.BoolVar(&dt.admin, "disable-admin", false, "Disable admin tools")
 	flag.BoolVar(&dt.pyroscope, "disable-pyroscope", false, "Disable pyroscope tools")
+	flag.BoolVar(&dt.tempo, "disable-tempo", false, "Disable tempo tools")
+	flag.BoolVar(&dt.elasticsearch, "disable-elasticsearch", false, "Disable elasticsearch tools")
+	flag.BoolVar(&dt.graphite, "disable-graphite", false, "Disable graphite tools")
+	flag.BoolVar(&dt.migration, "disable-migration", false, "Disable migration tools")
+	flag.BoolVar(&dt.notebook, "disable-notebook", false, "Disable investigation notebook tools")
+	flag.BoolVar(&dt.workspace, "disable-workspace", false, "Disable workspace bootstrap tools")
+
+	flag.BoolVar(&dt.write, "disable-write", false, "Disable tools which create, modify or delete resources, making the server read-only")
+	flag.StringVar(&dt.readOnlyCategories, "read-only-categories", "", "A comma separated list of tool categories, e.g. dashboard,alerting, whose tools which create, modify or delete resources are disabled, like --disable-write does for all categories")
+	flag.BoolVar(&dt.deprecatedAliases, "disable-deprecated-aliases", false, "Don't register renamed tools under their deprecated old names")
 }
 
 func (gc *grafanaConfig) addFlags() {
 	flag.BoolVar(&gc.debug, "debug", false, "Enable debug mode for the Grafana transport")
+	flag.Int64Var(&gc.orgID, "org-id", 0, "ID of the Grafana organization to use, sent as the X-Grafana-Org-Id header. Defaults to the GRAFANA_ORG_ID environment variable, or the default organization of the credentials. Clients of the SSE and streamable HTTP transports can override it with the X-Grafana-Org-Id header")
+	flag.Func("grafana-header", "Extra header to send with every request to Grafana, in the format 'Name: value', e.g. for reverse proxies requiring their own authentication or tenancy headers. Can be repeated", func(s string) error {
+		name, value, err := mcpgrafana.ParseHeader(s)
+		if err != nil {
+			return err
+		}
+		if gc.headers == nil {
+			gc.headers = http.Header{}
+		}
+		gc.headers.Add(name, value)
+		return nil
+	})
+	flag.StringVar(&gc.headersFile, "grafana-headers-file", "", "File with extra headers to send with every request to Grafana, one per line in the format 'Name: value'. Useful for headers containing secrets")
+	flag.StringVar(&gc.locale, "locale", "", "Locale of numbers, durations and dates in rendered summaries, e.g. 'de' or 'en-GB' (defaults to English with ISO 8601 dates). Clients of the SSE and streamable HTTP transports can override it with the X-Grafana-Locale header")
+
+	// Scope flags
+	flag.Func("scope-label", "Label in the format 'name=value', e.g. 'team=payments', which alert rules listed by default must have. Can be repeated", func(s string) error {
+		name, value, err := mcpgrafana.ParseScopeLabel(s)
+		if err != nil {
+			return err
+		}
+		if gc.scope.Labels == nil {
+			gc.scope.Labels = map[string]string{}
+		}
+		gc.scope.Labels[name] = value
+		return nil
+	})
+	flag.Func("scope-dashboard-tag", "Tag which dashboards found by dashboard searches must have by default. Can be repeated", func(s string) error {
+		gc.scope.DashboardTags = append(gc.scope.DashboardTags, s)
+		return nil
+	})
+	flag.StringVar(&gc.scope.OnCallTeamID, "scope-oncall-team", "", "ID of the OnCall team whose schedules are listed by default")
+
+	flag.Func("ds-query", fmt.Sprintf("Comma separated list of datasource types whose queries are run through Grafana's /api/ds/query endpoint instead of the datasource proxy, so that LBAC rules, query caching and per-user datasource permissions apply. Supported types: %s", strings.Join(dsQueryTypes, ", ")), func(s string) error {
+		for _, t := range strings.Split(s, ",") {
+			t = strings.TrimSpace(t)
+			if !slices.Contains(dsQueryTypes, t) {
+				return fmt.Errorf("unsupported datasource type %q: must be one of %s", t, strings.Join(dsQueryTypes, ", "))
+			}
+			gc.dsQuery = append(gc.dsQuery, t)
+		}
+		return nil
+	})
+
+	flag.IntVar(&gc.lokiLimits.MaxLogLines, "loki-max-log-lines", tools.MaxLokiLogLimit, "Maximum number of log lines a Loki log query may return")
+	flag.IntVar(&gc.lokiLimits.MaxMetricSamples, "loki-max-metric-samples", tools.MaxLokiMetricLimit, "Maximum number of samples a Loki metric query may return, across all its series")
+
+	flag.StringVar(&gc.severityRubricFile, "incident-severity-rubric-file", "", "YAML file of the rubric used to suggest incident severities from firing alerts and affected services: the severity levels, the labels indicating customer impact and the rules giving severities (defaults to a built-in rubric)")
+
+	// Retry flags
+	flag.IntVar(&gc.retry.MaxRetries, "max-retries", mcpgrafana.DefaultMaxRetries, "Maximum number of retries of requests to Grafana failing with 429 or transient 5xx responses. Set to 0 to disable retries")
+	flag.DurationVar(&gc.retry.InitialBackoff, "retry-initial-backoff", mcpgrafana.DefaultRetryInitialBackoff, "Time to wait before the first retry of a request to Grafana, doubling after each retry")
+	flag.DurationVar(&gc.retry.MaxBackoff, "retry-max-backoff", mcpgrafana.DefaultRetryMaxBackoff, "Maximum time to wait before a retry of a request to Grafana. Responses with a longer Retry-After are not retried")
+	flag.IntVar(&gc.retry.MaxCallRetries, "max-call-retries", 5, "Maximum number of retries a tool call may request with its maxRetries argument, overriding --max-retries (0 to ignore the argument)")
+
+	// Disk cache flags
+	flag.StringVar(&gc.diskCache.Dir, "disk-cache-dir", "", "Directory in which to cache immutable artifacts fetched from Grafana, such as dashboard versions and finished Sift analyses, across sessions (disabled by default)")
+	flag.Int64Var(&gc.diskCache.MaxBytes, "disk-cache-max-bytes", mcpgrafana.DefaultDiskCacheMaxBytes, "Maximum total size in bytes of the disk cache; the least recently used artifacts are removed when it is full")
+
+	// Migration flags
+	flag.StringVar(&gc.migrationTarget.URL, "migration-target-url", os.Getenv("GRAFANA_MIGRATION_TARGET_URL"), "URL of a second Grafana instance, e.g. the destination of a migration, which the migration tools compare the instance at GRAFANA_URL with. Defaults to the GRAFANA_MIGRATION_TARGET_URL environment variable. Its API key is read from GRAFANA_MIGRATION_TARGET_API_KEY")
+	flag.Int64Var(&gc.migrationTarget.OrgID, "migration-target-org-id", 0, "ID of the organization of the migration target to compare with. Defaults to the default organization of its API key")
+
+	// Failover flags
+	flag.StringVar(&gc.failover.SecondaryURL, "secondary-grafana-url", "", "URL of a standby Grafana instance to use while the instance at GRAFANA_URL fails health checks. It must accept the same credentials")
+	flag.DurationVar(&gc.failover.CheckInterval, "failover-check-interval", mcpgrafana.DefaultFailoverCheckInterval, "Interval between health checks of the primary and secondary Grafana instances")
+	flag.IntVar(&gc.failover.Threshold, "failover-threshold", mcpgrafana.DefaultFailoverThreshold, "Number of consecutive failed health checks of the primary Grafana instance before failing over, and of successful ones before failing back")
+
+	// Self-metrics flags
+	flag.StringVar(&gc.selfMetrics.DatasourceUID, "self-metrics-datasource-uid", "", "UID of a Prometheus or Mimir datasource of Grafana to push the metrics of the server to, such as the number and duration of tool calls, using the remote-write protocol (disabled by default)")
+	flag.StringVar(&gc.selfMetrics.WritePath, "self-metrics-write-path", mcpgrafana.DefaultSelfMetricsWritePath, "Path of the remote-write endpoint of the self-metrics datasource: /api/v1/push for Mimir and Grafana Cloud Metrics, /api/v1/write for Prometheus")
+	flag.DurationVar(&gc.selfMetrics.Interval, "self-metrics-interval", mcpgrafana.DefaultSelfMetricsInterval, "Interval between pushes of the metrics of the server")
 
 	// TLS configuration flags
 	flag.StringVar(&gc.tlsCertFile, "tls-cert-file", "", "Path to TLS certificate file for client authentication")
 	flag.StringVar(&gc.tlsKeyFile, "tls-key-file", "", "Path to TLS private key file for client authentication")
 	flag.StringVar(&gc.tlsCAFile, "tls-ca-file", "", "Path to TLS CA certificate file for server verification")
 	flag.BoolVar(&gc.tlsSkipVerify, "tls-skip-verify", false, "Skip TLS certificate verification (insecure)")
+
+	// OAuth token passthrough flags
+	flag.BoolVar(&gc.oauthPassthrough, "oauth-passthrough", false, "Forward the bearer token from the Authorization header of SSE and streamable HTTP requests to Grafana in place of the API key")
+	flag.StringVar(&gc.oauthIssuer, "oauth-issuer", "", "OIDC issuer URL forwarded tokens must be JWTs signed by. Requests without a valid token are rejected. Requires --oauth-passthrough")
+	flag.StringVar(&gc.oauthAudience, "oauth-audience", "", "Audience forwarded tokens must be issued for. Requires --oauth-issuer")
+}
+
+// extraHeaders returns the extra headers to send with every request to
+// Grafana. Headers set with --grafana-header are added to those read from
+// --grafana-headers-file.
+func (gc *grafanaConfig) extraHeaders() (http.Header, error) {
+	headers := http.Header{}
+	if gc.headersFile != "" {
+		var err error
+		if headers, err = mcpgrafana.ReadHeadersFile(gc.headersFile); err != nil {
+			return nil, err
+		}
+	}
+	for name, values := range gc.headers {
+		headers[name] = append(headers[name], values...)
+	}
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	return headers, nil
+}
+
+// oauthConfig returns the configuration of OAuth token passthrough, or nil
+// if it is disabled.
+func (gc *grafanaConfig) oauthConfig(transport string) (*mcpgrafana.OAuthConfig, error) {
+	if !gc.oauthPassthrough {
+		if gc.oauthIssuer != "" || gc.oauthAudience != "" {
+			return nil, errors.New("--oauth-issuer and --oauth-audience require --oauth-passthrough")
+		}
+		return nil, nil
+	}
+	if transport == "stdio" {
+		return nil, errors.New("--oauth-passthrough requires the sse or streamable-http transport")
+	}
+	if gc.oauthAudience != "" && gc.oauthIssuer == "" {
+		return nil, errors.New("--oauth-audience requires --oauth-issuer")
+	}
+	return &mcpgrafana.OAuthConfig{Issuer: gc.oauthIssuer, Audience: gc.oauthAudience}, nil
+}
+
+// withOAuth wraps handler to validate forwarded tokens, if configured.
+func withOAuth(gc mcpgrafana.GrafanaConfig, handler http.Handler) http.Handler {
+	if gc.OAuth == nil {
+		return handler
+	}
+	return mcpgrafana.OAuthMiddleware(*gc.OAuth)(handler)
 }
 
 func (dt *disabledTools) addTools(s *server.MCPServer) {
 	enabledTools := strings.Split(dt.enabledTools, ",")
+	if dt.write {
+		slog.Info("Disabling write tools")
+	}
+	readOnly := map[string]bool{}
+	for category := range strings.SplitSeq(dt.readOnlyCategories, ",") {
+		if category = strings.TrimSpace(category); category != "" {
+			readOnly[category] = true
+		}
+	}
+	// Recording whether the write tools of each category are enabled gates
+	// their registration, and the descriptions of the category's tools
+	// reflect it.
+	for _, category := range writeToolCategories {
+		mcpgrafana.SetCategoryFeature(category, mcpgrafana.FeatureWrite, !dt.write && !readOnly[category])
+	}
+	if !dt.deprecatedAliases {
+		mcpgrafana.SetToolAliases(tools.DeprecatedToolNames)
+	}
+
 	maybeAddTools(s, tools.AddSearchTools, enabledTools, dt.search, "search")
 	maybeAddTools(s, tools.AddDatasourceTools, enabledTools, dt.datasource, "datasource")
 	maybeAddTools(s, tools.AddIncidentTools, enabledTools, dt.incident, "incident")
@@ -106,52 +462,126 @@ func (dt *disabledTools) addTools(s *server.MCPServer) {
 	maybeAddTools(s, tools.AddSiftTools, enabledTools, dt.sift, "sift")
 	maybeAddTools(s, tools.AddAdminTools, enabledTools, dt.admin, "admin")
 	maybeAddTools(s, tools.AddPyroscopeTools, enabledTools, dt.pyroscope, "pyroscope")
+	maybeAddTools(s, tools.AddTempoTools, enabledTools, dt.tempo, "tempo")
+	maybeAddTools(s, tools.AddElasticsearchTools, enabledTools, dt.elasticsearch, "elasticsearch")
+	maybeAddTools(s, tools.AddGraphiteTools, enabledTools, dt.graphite, "graphite")
+	maybeAddTools(s, tools.AddMigrationTools, enabledTools, dt.migration, "migration")
+	maybeAddTools(s, tools.AddNotebookTools, enabledTools, dt.notebook, "notebook")
+	maybeAddTools(s, tools.AddWorkspaceTools, enabledTools, dt.workspace, "workspace")
+
+	// The capabilities tools describe the server itself and are always enabled.
+	mcpgrafana.RegisterCategory(s, "capabilities", true, tools.AddCapabilitiesTools)
 }
 
-func newServer(dt disabledTools) *server.MCPServer {
-	s := server.NewMCPServer("mcp-grafana", version(), server.WithInstructions(`
+func newServer(dt disabledTools, lc limitsConfig, middleware ...server.ServerOption) *server.MCPServer {
+	opts := append([]server.ServerOption{server.WithInstructions(`
 	This server provides access to your Grafana instance and the surrounding ecosystem.
 
 	Available Capabilities:
-	- Dashboards: Search, retrieve, update, and create dashboards. Extract panel queries and datasource information.
+	- Dashboards: Search, retrieve, update, and create dashboards. Extract panel queries and datasource information. Summarize multiple dashboards at once.
 	- Datasources: List and fetch details for datasources.
 	- Prometheus & Loki: Run PromQL and LogQL queries, retrieve metric/log metadata, and explore label names/values.
 	- Incidents: Search, create, update, and resolve incidents in Grafana Incident.
 	- Sift Investigations: Start and manage Sift investigations, analyze logs/traces, find error patterns, and detect slow requests.
-	- Alerting: List and fetch alert rules and notification contact points.
+	- Alerting: List and fetch alert rules and notification contact points, and get a health snapshot of a service. Create, update, and delete alert rules when write tools are enabled. Export and import the alerting configuration, and detect drift between alert rules and their provisioning definitions.
 	- OnCall: View and manage on-call schedules, shifts, teams, and users.
 	- Admin: List teams and perform administrative tasks.
 	- Pyroscope: Profile applications and fetch profiling data.
-	`))
+	- Tempo: Fetch traces, with logs and metrics queries correlated with each span.
+	- Elasticsearch: Search logs with Lucene or Query DSL queries, and list index fields.
+	- Graphite: Evaluate Graphite target expressions.
+	- Migration: Compare dashboards, datasources, and alert rules with a migration target Grafana instance.
+	- Notebook: Record the queries, findings, and conclusions of an investigation, and export them to an incident or dashboard.
+	- Workspace: Create the folder, dashboard, alert rules, and OnCall route of a service in one call.
+	- Capabilities: List enabled and degraded tool categories, and the server's limits.
+
+	Prompts are available for common workflows such as investigating an alert, analyzing a dashboard and finding error logs for a service.
+	`),
+		// Records the request ID and tool name of each tool call for logging.
+		server.WithToolHandlerMiddleware(mcpgrafana.LogContextMiddleware()),
+	}, middleware...)
+	opts = append(opts, lc.serverOptions()...)
+	s := server.NewMCPServer("mcp-grafana", mcpgrafana.Version(), opts...)
 	dt.addTools(s)
+	tools.AddPrompts(s)
 	return s
 }
 
-func run(transport, addr, basePath, endpointPath string, logLevel slog.Level, dt disabledTools, gc mcpgrafana.GrafanaConfig) error {
-	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel})))
-	s := newServer(dt)
+func run(transport, addr, basePath, endpointPath, debugAddr string, logLevel slog.Level, logFormat string, dt disabledTools, lc limitsConfig, ac auditConfig, gc mcpgrafana.GrafanaConfig) error {
+	logHandler, err := mcpgrafana.NewLogHandler(os.Stderr, logFormat, logLevel)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(logHandler))
+
+	// The audit log is the outermost middleware so that it records every
+	// tool call, including those rejected by limits.
+	auditOpts, auditLog, err := ac.serverOptions(transport)
+	if err != nil {
+		return err
+	}
+	defer auditLog.Close()
+	middleware := auditOpts
+	if gc.SelfMetrics != nil {
+		go gc.SelfMetrics.Run(context.Background())
+		middleware = append(middleware, server.WithToolHandlerMiddleware(gc.SelfMetrics.Middleware()))
+	}
+	if gc.APIKeyFile != nil {
+		middleware = append(middleware, server.WithToolHandlerMiddleware(mcpgrafana.TokenFileMiddleware(gc.APIKeyFile)))
+	}
+	if gc.Failover != nil {
+		go gc.Failover.Run(context.Background())
+		middleware = append(middleware, server.WithToolHandlerMiddleware(mcpgrafana.FailoverMiddleware(gc.Failover)))
+	}
+	if gc.Retry != nil && gc.Retry.MaxCallRetries > 0 {
+		middleware = append(middleware, server.WithToolHandlerMiddleware(mcpgrafana.MaxRetriesMiddleware(*gc.Retry)))
+	}
+	s := newServer(dt, lc, middleware...)
+
+	if debugAddr != "" {
+		if err := mcpgrafana.ValidateDebugAddress(debugAddr); err != nil {
+			return err
+		}
+		debugSrv := &http.Server{Addr: debugAddr, Handler: mcpgrafana.DebugHandler()}
+		go func() {
+			slog.Info("Serving pprof and expvar debug endpoints", "address", debugAddr)
+			if err := debugSrv.ListenAndServe(); err != nil {
+				slog.Error("Debug server error", "error", err)
+			}
+		}()
+	}
 
 	switch transport {
 	case "stdio":
 		srv := server.NewStdioServer(s)
 		srv.SetContextFunc(mcpgrafana.ComposedStdioContextFunc(gc))
-		slog.Info("Starting Grafana MCP server using stdio transport", "version", version())
+		slog.Info("Starting Grafana MCP server using stdio transport", "version", mcpgrafana.Version())
 		return srv.Listen(context.Background(), os.Stdin, os.Stdout)
 	case "sse":
+		httpSrv := &http.Server{}
 		srv := server.NewSSEServer(s,
 			server.WithSSEContextFunc(mcpgrafana.ComposedSSEContextFunc(gc)),
 			server.WithStaticBasePath(basePath),
+			server.WithHTTPServer(httpSrv),
 		)
-		slog.Info("Starting Grafana MCP server using SSE transport", "version", version(), "address", addr, "basePath", basePath)
+		mux := http.NewServeMux()
+		mux.Handle("/healthz", mcpgrafana.HealthHandler(gc.Failover))
+		mux.Handle("/", withOAuth(gc, srv))
+		httpSrv.Handler = mux
+		slog.Info("Starting Grafana MCP server using SSE transport", "version", mcpgrafana.Version(), "address", addr, "basePath", basePath)
 		if err := srv.Start(addr); err != nil {
 			return fmt.Errorf("Server error: %v", err)
 		}
 	case "streamable-http":
+		mux := http.NewServeMux()
 		srv := server.NewStreamableHTTPServer(s, server.WithHTTPContextFunc(mcpgrafana.ComposedHTTPContextFunc(gc)),
 			server.WithStateLess(true),
 			server.WithEndpointPath(endpointPath),
+			server.WithStreamableHTTPServer(&http.Server{Handler: mux}),
 		)
-		slog.Info("Starting Grafana MCP server using StreamableHTTP transport", "version", version(), "address", addr, "endpointPath", endpointPath)
+		mux.Handle("/healthz", mcpgrafana.HealthHandler(gc.Failover))
+		mux.Handle(endpointPath, withOAuth(gc, srv))
+		slog.Info("Starting Grafana MCP server using StreamableHTTP transport", "version", mcpgrafana.Version(), "address", addr, "endpointPath", endpointPath)
 		if err := srv.Start(addr); err != nil {
 			return fmt.Errorf("Server error: %v", err)
 		}
@@ -177,20 +607,75 @@ func main() {
 	basePath := flag.String("base-path", "", "Base path for the sse server")
 	endpointPath := flag.String("endpoint-path", "/mcp", "Endpoint path for the streamable-http server")
 	logLevel := flag.String("log-level", "info", "Log level (debug, info, warn, error)")
+	logFormat := flag.String("log-format", mcpgrafana.LogFormatText, "Log format (text, json). Log lines written during tool calls include the session ID, request ID and tool name")
+	debugAddr := flag.String("debug-address", "", "Serve pprof profiles under /debug/pprof/ and expvar variables under /debug/vars on this localhost-only address, e.g. localhost:6060 (disabled by default)")
 	showVersion := flag.Bool("version", false, "Print the version and exit")
 	var dt disabledTools
 	dt.addFlags()
 	var gc grafanaConfig
 	gc.addFlags()
+	var lc limitsConfig
+	lc.addFlags()
+	var ac auditConfig
+	ac.addFlags()
 	flag.Parse()
 
 	if *showVersion {
-		fmt.Println(version())
+		fmt.Println(mcpgrafana.Version())
 		os.Exit(0)
 	}
+	if err := lc.redaction.Validate(); err != nil {
+		panic(err)
+	}
+	if ac.queryRedaction.Enabled() {
+		redactor, err := mcpgrafana.NewQueryRedactor(ac.queryRedaction)
+		if err != nil {
+			panic(err)
+		}
+		mcpgrafana.SetQueryRedactor(redactor)
+	}
+	if lc.policyFile != "" {
+		var err error
+		if lc.policy, err = mcpgrafana.LoadPolicy(lc.policyFile); err != nil {
+			panic(err)
+		}
+	}
 
 	// Convert local grafanaConfig to mcpgrafana.GrafanaConfig
-	grafanaConfig := mcpgrafana.GrafanaConfig{Debug: gc.debug}
+	grafanaConfig := mcpgrafana.GrafanaConfig{Debug: gc.debug, OrgID: gc.orgID}
+	locale, err := mcpgrafana.ParseLocale(gc.locale)
+	if err != nil {
+		panic(err)
+	}
+	grafanaConfig.Locale = locale
+	grafanaConfig.Scope = gc.scope
+	grafanaConfig.DSQuery = gc.dsQuery
+	grafanaConfig.LokiLimits = gc.lokiLimits
+	mcpgrafana.RecordLimit("lokiMaxLogLimit", gc.lokiLimits.MaxLogLines)
+	mcpgrafana.RecordLimit("lokiMaxMetricLimit", gc.lokiLimits.MaxMetricSamples)
+	if gc.severityRubricFile != "" {
+		if grafanaConfig.SeverityRubric, err = mcpgrafana.LoadSeverityRubric(gc.severityRubricFile); err != nil {
+			panic(err)
+		}
+	}
+	if !gc.scope.IsZero() {
+		slog.Info("Applying default scope to list tools", "labels", gc.scope.Labels, "dashboardTags", gc.scope.DashboardTags, "oncallTeam", gc.scope.OnCallTeamID)
+	}
+	grafanaConfig.ExtraHeaders, err = gc.extraHeaders()
+	if err != nil {
+		panic(err)
+	}
+	gc.failover.Headers = grafanaConfig.ExtraHeaders
+	if gc.retry.MaxRetries > 0 || gc.retry.MaxCallRetries > 0 {
+		grafanaConfig.Retry = &gc.retry
+	}
+	if gc.diskCache.Enabled() {
+		diskCache, err := mcpgrafana.NewDiskCache(gc.diskCache)
+		if err != nil {
+			panic(err)
+		}
+		grafanaConfig.DiskCache = diskCache
+	}
 	if gc.tlsCertFile != "" || gc.tlsKeyFile != "" || gc.tlsCAFile != "" || gc.tlsSkipVerify {
 		grafanaConfig.TLSConfig = &mcpgrafana.TLSConfig{
 			CertFile:   gc.tlsCertFile,
@@ -199,8 +684,39 @@ func main() {
 			SkipVerify: gc.tlsSkipVerify,
 		}
 	}
+	if grafanaConfig.APIKeyFile, err = mcpgrafana.APIKeyFileFromEnv(); err != nil {
+		panic(err)
+	}
+	if grafanaConfig.APIKeyFile != nil && os.Getenv("GRAFANA_API_KEY") != "" {
+		panic(errors.New("only one of GRAFANA_API_KEY and GRAFANA_API_KEY_FILE can be set"))
+	}
+	if gc.migrationTarget.URL != "" {
+		gc.migrationTarget.APIKey = os.Getenv("GRAFANA_MIGRATION_TARGET_API_KEY")
+		grafanaConfig.MigrationTarget = &gc.migrationTarget
+	}
+	if gc.failover.SecondaryURL != "" {
+		failover, err := mcpgrafana.NewFailover(gc.failover, grafanaConfig.TLSConfig)
+		if err != nil {
+			panic(err)
+		}
+		grafanaConfig.Failover = failover
+	}
+	if gc.selfMetrics.DatasourceUID != "" {
+		gc.selfMetrics.OrgID = grafanaConfig.OrgID
+		gc.selfMetrics.Headers = grafanaConfig.ExtraHeaders
+		selfMetrics, err := mcpgrafana.NewSelfMetrics(gc.selfMetrics, grafanaConfig.TLSConfig)
+		if err != nil {
+			panic(err)
+		}
+		grafanaConfig.SelfMetrics = selfMetrics
+	}
+	oauth, err := gc.oauthConfig(transport)
+	if err != nil {
+		panic(err)
+	}
+	grafanaConfig.OAuth = oauth
 
-	if err := run(transport, *addr, *basePath, *endpointPath, parseLevel(*logLevel), dt, grafanaConfig); err != nil {
+	if err := run(transport, *addr, *basePath, *endpointPath, *debugAddr, parseLevel(*logLevel), *logFormat, dt, lc, ac, grafanaConfig); err != nil {
 		panic(err)
 	}
 }
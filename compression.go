@@ -0,0 +1,112 @@
+package mcpgrafana
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// MaxResponseSize is the maximum size of a response body read by the HTTP
+// clients of the tools, after decompression.
+const MaxResponseSize = 48 << 20 // 48MB
+
+// ErrResponseTooLarge is returned when reading a response body larger than
+// its limit.
+var ErrResponseTooLarge = errors.New("response body too large")
+
+// compressionRoundTripper requests gzip-compressed responses and
+// decompresses them.
+type compressionRoundTripper struct {
+	underlying http.RoundTripper
+}
+
+func (rt *compressionRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Leave requests asking for an encoding or a byte range, whose offsets
+	// refer to the encoded body, as they are.
+	if req.Header.Get("Accept-Encoding") != "" || req.Header.Get("Range") != "" || req.Method == http.MethodHead {
+		return rt.underlying.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := rt.underlying.RoundTrip(req)
+	if err != nil || !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return resp, err
+	}
+	resp.Body = &gzipBody{body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+// gzipBody decompresses a response body, reading the gzip header on the
+// first read rather than when the response is received.
+type gzipBody struct {
+	body io.ReadCloser
+	zr   *gzip.Reader
+	err  error
+}
+
+func (b *gzipBody) Read(p []byte) (int, error) {
+	if b.zr == nil && b.err == nil {
+		b.zr, b.err = gzip.NewReader(b.body)
+		if b.err != nil {
+			b.err = fmt.Errorf("decompressing response body: %w", b.err)
+		}
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.zr.Read(p)
+}
+
+func (b *gzipBody) Close() error {
+	return b.body.Close()
+}
+
+// WithCompression wraps rt so that responses are requested with gzip
+// compression, which is transparently decompressed. Unlike the compression of
+// http.Transport, this also works when rt is not an http.Transport and when
+// the requests pass through other round trippers, e.g. to add headers.
+func WithCompression(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return &compressionRoundTripper{underlying: rt}
+}
+
+// limitedReader reads at most n bytes, returning ErrResponseTooLarge instead
+// of io.EOF if there are more.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		// Check whether the body ends exactly at the limit.
+		var b [1]byte
+		if n, _ := io.ReadFull(l.r, b[:]); n == 0 {
+			return 0, io.EOF
+		}
+		return 0, ErrResponseTooLarge
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+// LimitResponseBody returns a reader of r which fails with
+// ErrResponseTooLarge after limit bytes, so that large bodies are reported
+// instead of being truncated. Bodies are meant to be decoded while they are
+// read, e.g. with json.Decoder, rather than read into memory first.
+func LimitResponseBody(r io.Reader, limit int64) io.Reader {
+	return &limitedReader{r: r, n: limit}
+}
@@ -0,0 +1,61 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCompression(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			_, _ = w.Write([]byte("identity"))
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		_, _ = zw.Write([]byte("compressed"))
+		_ = zw.Close()
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: WithCompression(nil)}
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "compressed", string(body))
+	assert.True(t, resp.Uncompressed)
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+
+	// Requests choosing their own encoding are left alone.
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept-Encoding", "identity")
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "identity", string(body))
+}
+
+func TestLimitResponseBody(t *testing.T) {
+	body, err := io.ReadAll(LimitResponseBody(strings.NewReader("12345"), 5))
+	require.NoError(t, err)
+	assert.Equal(t, "12345", string(body))
+
+	_, err = io.ReadAll(LimitResponseBody(bytes.NewReader(make([]byte, 6)), 5))
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+}
@@ -0,0 +1,95 @@
+package mcpgrafana
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfirmationTTL is how long the confirmation tokens of destructive tools
+// are valid for.
+const ConfirmationTTL = 5 * time.Minute
+
+// ErrInvalidConfirmationToken is returned when a destructive tool is called
+// with a confirmation token which wasn't issued for the same action, on
+// the same resource in the same state, with the same credentials, or which
+// expired.
+var ErrInvalidConfirmationToken = errors.New("invalid confirmation token")
+
+// confirmationKey signs confirmation tokens. It is generated when the
+// server starts, so tokens are only valid on the server which issued them
+// and until it restarts.
+var confirmationKey = func() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Errorf("generate confirmation key: %w", err))
+	}
+	return key
+}()
+
+// confirmationMAC returns the signature of a confirmation token for an
+// action on a resource, expiring at expires. It covers the Grafana instance,
+// organization and credentials of the context, so that tokens can't be used
+// by other users.
+func confirmationMAC(ctx context.Context, expires int64, action string, subject []string) string {
+	cfg := GrafanaConfigFromContext(ctx)
+	mac := hmac.New(sha256.New, confirmationKey)
+	for _, s := range append([]string{
+		strconv.FormatInt(expires, 10),
+		action,
+		cfg.URL,
+		strconv.FormatInt(cfg.OrgID, 10),
+		hashSecret(cfg.APIKey),
+		hashSecret(cfg.AccessToken + cfg.IDToken),
+	}, subject...) {
+		// Lengths are written so that the fields can't be shifted.
+		fmt.Fprintf(mac, "%d:%s;", len(s), s)
+	}
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// IssueConfirmationToken returns a token which confirms an action, e.g.
+// "delete_dashboard", on a resource described by subject, such as its UID
+// and version, and when it expires. Destructive tools return it from a
+// first call, with a summary of what the action would do, and only act when
+// called again with the token, so that a single mistaken call can't cause
+// irreversible damage.
+func IssueConfirmationToken(ctx context.Context, action string, subject ...string) (string, time.Time) {
+	return issueConfirmationToken(ctx, time.Now(), action, subject...)
+}
+
+func issueConfirmationToken(ctx context.Context, now time.Time, action string, subject ...string) (string, time.Time) {
+	expires := now.Add(ConfirmationTTL).Truncate(time.Second)
+	return fmt.Sprintf("%d.%s", expires.Unix(), confirmationMAC(ctx, expires.Unix(), action, subject)), expires
+}
+
+// VerifyConfirmationToken checks that token was issued by
+// IssueConfirmationToken for the same action and subject, with the
+// credentials of the context, and hasn't expired. Since the subject
+// describes the state of the resource, tokens are no longer valid once the
+// resource changed.
+func VerifyConfirmationToken(ctx context.Context, token, action string, subject ...string) error {
+	return verifyConfirmationToken(ctx, time.Now(), token, action, subject...)
+}
+
+func verifyConfirmationToken(ctx context.Context, now time.Time, token, action string, subject ...string) error {
+	expiresStr, mac, ok := strings.Cut(token, ".")
+	expires, err := strconv.ParseInt(expiresStr, 10, 64)
+	if !ok || err != nil {
+		return fmt.Errorf("%w: malformed token", ErrInvalidConfirmationToken)
+	}
+	if !hmac.Equal([]byte(mac), []byte(confirmationMAC(ctx, expires, action, subject))) {
+		return fmt.Errorf("%w: the token was issued for another resource or action, or the resource changed since", ErrInvalidConfirmationToken)
+	}
+	if now.Unix() > expires {
+		return fmt.Errorf("%w: the token expired", ErrInvalidConfirmationToken)
+	}
+	return nil
+}
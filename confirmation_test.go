@@ -0,0 +1,42 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfirmationToken(t *testing.T) {
+	ctx := WithGrafanaConfig(context.Background(), GrafanaConfig{URL: "http://grafana", APIKey: "key"})
+
+	token, expires := IssueConfirmationToken(ctx, "delete_dashboard", "abc", "3")
+	assert.WithinDuration(t, time.Now().Add(ConfirmationTTL), expires, 2*time.Second)
+	assert.NoError(t, VerifyConfirmationToken(ctx, token, "delete_dashboard", "abc", "3"))
+
+	t.Run("other action or resource", func(t *testing.T) {
+		assert.ErrorIs(t, VerifyConfirmationToken(ctx, token, "revoke_public_dashboard", "abc", "3"), ErrInvalidConfirmationToken)
+		assert.ErrorIs(t, VerifyConfirmationToken(ctx, token, "delete_dashboard", "abc", "4"), ErrInvalidConfirmationToken)
+		assert.ErrorIs(t, VerifyConfirmationToken(ctx, token, "delete_dashboard", "ab", "c3"), ErrInvalidConfirmationToken)
+	})
+
+	t.Run("other credentials", func(t *testing.T) {
+		other := WithGrafanaConfig(context.Background(), GrafanaConfig{URL: "http://grafana", APIKey: "other"})
+		assert.ErrorIs(t, VerifyConfirmationToken(other, token, "delete_dashboard", "abc", "3"), ErrInvalidConfirmationToken)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		err := verifyConfirmationToken(ctx, time.Now().Add(ConfirmationTTL+time.Minute), token, "delete_dashboard", "abc", "3")
+		assert.ErrorIs(t, err, ErrInvalidConfirmationToken)
+		assert.ErrorContains(t, err, "expired")
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		assert.ErrorIs(t, VerifyConfirmationToken(ctx, "", "delete_dashboard", "abc", "3"), ErrInvalidConfirmationToken)
+		assert.ErrorIs(t, VerifyConfirmationToken(ctx, "soon.abc", "delete_dashboard", "abc", "3"), ErrInvalidConfirmationToken)
+	})
+}
@@ -0,0 +1,40 @@
+package mcpgrafana
+
+import (
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+)
+
+// DebugHandler returns an HTTP handler serving the pprof profiles of the
+// server under /debug/pprof/ and its expvar variables, such as memory
+// statistics, under /debug/vars.
+func DebugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	return mux
+}
+
+// ValidateDebugAddress checks that the debug endpoints would only listen on
+// a loopback interface, since profiles and the command line of the server
+// may reveal secrets.
+func ValidateDebugAddress(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid debug address %q: %w", addr, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("invalid debug address %q: the host must be localhost or a loopback IP address, e.g. localhost:6060", addr)
+	}
+	return nil
+}
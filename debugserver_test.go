@@ -0,0 +1,33 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateDebugAddress(t *testing.T) {
+	for _, addr := range []string{"localhost:6060", "127.0.0.1:6060", "[::1]:6060"} {
+		assert.NoError(t, ValidateDebugAddress(addr), addr)
+	}
+	for _, addr := range []string{":6060", "0.0.0.0:6060", "10.0.0.1:6060", "example.com:6060", "localhost"} {
+		assert.Error(t, ValidateDebugAddress(addr), addr)
+	}
+}
+
+func TestDebugHandler(t *testing.T) {
+	handler := DebugHandler()
+	for _, path := range []string{"/debug/vars", "/debug/pprof/", "/debug/pprof/goroutine?debug=1"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, rec.Code, path)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	assert.Contains(t, rec.Body.String(), `"memstats"`)
+}
@@ -0,0 +1,209 @@
+package mcpgrafana
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDiskCacheMaxBytes is the default maximum size of the disk cache.
+const DefaultDiskCacheMaxBytes = 512 << 20
+
+// diskCacheExt is the extension of cached artifact files. Other files in the
+// cache directory are left alone.
+const diskCacheExt = ".json"
+
+// DiskCacheConfig configures the disk cache of immutable artifacts, such as
+// dashboard versions and finished Sift analyses.
+type DiskCacheConfig struct {
+	// Dir is the directory holding the cache. An empty directory disables
+	// the cache.
+	Dir string
+	// MaxBytes is the maximum total size of cached artifacts. The least
+	// recently used artifacts are removed when the cache is full. Defaults
+	// to DefaultDiskCacheMaxBytes if not set.
+	MaxBytes int64
+}
+
+// Enabled returns true if the disk cache is enabled.
+func (c DiskCacheConfig) Enabled() bool {
+	return c.Dir != ""
+}
+
+// DiskCache caches immutable artifacts fetched from Grafana on disk, so that
+// they are not downloaded again by later sessions or server restarts.
+// Artifacts are keyed by their kind, a content identifier which changes
+// whenever the content would, and the identity of the caller, so that
+// artifacts fetched by one identity are never returned to another.
+//
+// A nil *DiskCache is valid and caches nothing.
+type DiskCache struct {
+	dir      string
+	maxBytes int64
+
+	// mu serializes writes and eviction.
+	mu sync.Mutex
+}
+
+// NewDiskCache creates a disk cache, creating its directory if needed.
+func NewDiskCache(config DiskCacheConfig) (*DiskCache, error) {
+	maxBytes := config.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultDiskCacheMaxBytes
+	}
+	if err := os.MkdirAll(config.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create disk cache directory: %w", err)
+	}
+	return &DiskCache{dir: config.Dir, maxBytes: maxBytes}, nil
+}
+
+func (c *DiskCache) path(ctx context.Context, kind, id string) string {
+	h := sha256.New()
+	for _, s := range []string{kind, id, identityHash(ctx)} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return filepath.Join(c.dir, kind+"-"+hex.EncodeToString(h.Sum(nil))+diskCacheExt)
+}
+
+// Get decodes the cached artifact of the given kind and ID into v, returning
+// false if it is not cached.
+func (c *DiskCache) Get(ctx context.Context, kind, id string, v any) bool {
+	if c == nil {
+		return false
+	}
+	path := c.path(ctx, kind, id)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		slog.WarnContext(ctx, "Ignoring corrupt disk cache entry", "path", path, "error", err)
+		_ = os.Remove(path)
+		return false
+	}
+	// Mark the artifact as recently used.
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return true
+}
+
+// Put caches an artifact of the given kind and ID. Failures are logged, since
+// the artifact can always be fetched again.
+func (c *DiskCache) Put(ctx context.Context, kind, id string, v any) {
+	if c == nil {
+		return
+	}
+	if err := c.put(c.path(ctx, kind, id), v); err != nil {
+		slog.WarnContext(ctx, "Failed to write disk cache entry", "kind", kind, "error", err)
+	}
+}
+
+func (c *DiskCache) put(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > c.maxBytes {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Write to a temporary file first so that readers never see a partial
+	// artifact.
+	f, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+	return c.evict()
+}
+
+// evict removes the least recently used artifacts until the cache fits
+// within its maximum size. It must be called with c.mu held.
+func (c *DiskCache) evict() error {
+	type entry struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var entries []entry
+	var total int64
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != c.dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, diskCacheExt) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			// The file was removed concurrently.
+			return nil
+		}
+		entries = append(entries, entry{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(entries, func(a, b entry) int { return a.modTime.Compare(b.modTime) })
+	for _, e := range entries {
+		if total <= c.maxBytes {
+			break
+		}
+		if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		total -= e.size
+	}
+	return nil
+}
+
+// CachedArtifact returns the artifact of the given kind and ID from the disk
+// cache of the current request's configuration, calling fetch on a miss.
+// fetch also returns whether the artifact is immutable; only immutable
+// artifacts are cached, so that e.g. a running analysis is fetched again
+// until it has finished.
+func CachedArtifact[T any](ctx context.Context, kind, id string, fetch func() (T, bool, error)) (T, error) {
+	cache := GrafanaConfigFromContext(ctx).DiskCache
+	var v T
+	if cache.Get(ctx, kind, id, &v) {
+		return v, nil
+	}
+	v, immutable, err := fetch()
+	if err != nil {
+		return v, err
+	}
+	if immutable {
+		cache.Put(ctx, kind, id, v)
+	}
+	return v, nil
+}
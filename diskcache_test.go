@@ -0,0 +1,115 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testArtifact struct {
+	Name string `json:"name"`
+	Data string `json:"data"`
+}
+
+func TestDiskCache(t *testing.T) {
+	cache, err := NewDiskCache(DiskCacheConfig{Dir: filepath.Join(t.TempDir(), "cache")})
+	require.NoError(t, err)
+	alice := WithGrafanaConfig(context.Background(), GrafanaConfig{URL: "http://grafana", APIKey: "alice", DiskCache: cache})
+	bob := WithGrafanaConfig(context.Background(), GrafanaConfig{URL: "http://grafana", APIKey: "bob", DiskCache: cache})
+
+	fetches := 0
+	fetch := func(immutable bool) func() (*testArtifact, bool, error) {
+		return func() (*testArtifact, bool, error) {
+			fetches++
+			return &testArtifact{Name: "v1"}, immutable, nil
+		}
+	}
+
+	t.Run("caches immutable artifacts", func(t *testing.T) {
+		fetches = 0
+		v, err := CachedArtifact(alice, "test", "a", fetch(true))
+		require.NoError(t, err)
+		assert.Equal(t, &testArtifact{Name: "v1"}, v)
+		v, err = CachedArtifact(alice, "test", "a", fetch(true))
+		require.NoError(t, err)
+		assert.Equal(t, &testArtifact{Name: "v1"}, v)
+		assert.Equal(t, 1, fetches)
+	})
+
+	t.Run("does not share artifacts between identities", func(t *testing.T) {
+		fetches = 0
+		_, err := CachedArtifact(bob, "test", "a", fetch(true))
+		require.NoError(t, err)
+		assert.Equal(t, 1, fetches)
+	})
+
+	t.Run("does not cache mutable artifacts", func(t *testing.T) {
+		fetches = 0
+		for range 2 {
+			_, err := CachedArtifact(alice, "test", "b", fetch(false))
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 2, fetches)
+	})
+
+	t.Run("does not cache errors", func(t *testing.T) {
+		_, err := CachedArtifact(alice, "test", "c", func() (*testArtifact, bool, error) {
+			return nil, true, errors.New("boom")
+		})
+		assert.Error(t, err)
+		var v *testArtifact
+		assert.False(t, cache.Get(alice, "test", "c", &v))
+	})
+
+	t.Run("ignores corrupt entries", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(cache.path(alice, "test", "d"), []byte("{"), 0o600))
+		var v *testArtifact
+		assert.False(t, cache.Get(alice, "test", "d", &v))
+		assert.NoFileExists(t, cache.path(alice, "test", "d"))
+	})
+}
+
+func TestDiskCacheEviction(t *testing.T) {
+	cache, err := NewDiskCache(DiskCacheConfig{Dir: t.TempDir(), MaxBytes: 250})
+	require.NoError(t, err)
+	ctx := context.Background()
+	data := strings.Repeat("x", 80)
+
+	// Each artifact is about 100 bytes, so only two fit.
+	start := time.Now().Add(-time.Hour)
+	for i, id := range []string{"a", "b"} {
+		cache.Put(ctx, "test", id, testArtifact{Name: id, Data: data})
+		mtime := start.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, os.Chtimes(cache.path(ctx, "test", id), mtime, mtime))
+	}
+	// Reading a marks it as recently used, so b is evicted next.
+	var v testArtifact
+	require.True(t, cache.Get(ctx, "test", "a", &v))
+	cache.Put(ctx, "test", "c", testArtifact{Name: "c", Data: data})
+
+	assert.True(t, cache.Get(ctx, "test", "a", &v))
+	assert.False(t, cache.Get(ctx, "test", "b", &v))
+	assert.True(t, cache.Get(ctx, "test", "c", &v))
+
+	// Artifacts larger than the cache are not stored.
+	cache.Put(ctx, "test", "big", testArtifact{Data: strings.Repeat("x", 300)})
+	assert.False(t, cache.Get(ctx, "test", "big", &v))
+	assert.True(t, cache.Get(ctx, "test", "c", &v))
+}
+
+func TestNilDiskCache(t *testing.T) {
+	var cache *DiskCache
+	cache.Put(context.Background(), "test", "a", testArtifact{})
+	var v testArtifact
+	assert.False(t, cache.Get(context.Background(), "test", "a", &v))
+}
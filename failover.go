@@ -0,0 +1,273 @@
+package mcpgrafana
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// DefaultFailoverCheckInterval is the default interval between health
+	// checks of the primary and secondary Grafana instances.
+	DefaultFailoverCheckInterval = 10 * time.Second
+	// DefaultFailoverThreshold is the default number of consecutive failed
+	// health checks of the primary instance before failing over, and of
+	// consecutive successful ones before failing back.
+	DefaultFailoverThreshold = 3
+
+	// healthCheckTimeout is the timeout of a single health check.
+	healthCheckTimeout = 5 * time.Second
+)
+
+// FailoverConfig configures failover to a warm standby Grafana instance.
+type FailoverConfig struct {
+	// PrimaryURL is the URL of the primary Grafana instance. Defaults to the
+	// GRAFANA_URL environment variable.
+	PrimaryURL string
+	// SecondaryURL is the URL of the standby Grafana instance. It must
+	// accept the same credentials as the primary, e.g. by sharing its
+	// database.
+	SecondaryURL string
+	// CheckInterval is the interval between health checks. Defaults to
+	// DefaultFailoverCheckInterval if not set.
+	CheckInterval time.Duration
+	// Threshold is the number of consecutive failed health checks of the
+	// primary instance before failing over, and of consecutive successful
+	// ones before failing back. Defaults to DefaultFailoverThreshold if not
+	// set.
+	Threshold int
+	// Headers are extra headers sent with health checks, e.g. for reverse
+	// proxies in front of the instances.
+	Headers http.Header
+}
+
+// InstanceHealth is the result of the health checks of a Grafana instance.
+type InstanceHealth struct {
+	URL                  string    `json:"url"`
+	Healthy              bool      `json:"healthy"`
+	ConsecutiveFailures  int       `json:"consecutiveFailures"`
+	ConsecutiveSuccesses int       `json:"consecutiveSuccesses"`
+	LastCheck            time.Time `json:"lastCheck,omitzero"`
+	LastError            string    `json:"lastError,omitempty"`
+}
+
+func (h *InstanceHealth) record(err error, now time.Time) {
+	h.LastCheck = now
+	h.Healthy = err == nil
+	if err != nil {
+		h.ConsecutiveFailures++
+		h.ConsecutiveSuccesses = 0
+		h.LastError = err.Error()
+		return
+	}
+	h.ConsecutiveFailures = 0
+	h.ConsecutiveSuccesses++
+	h.LastError = ""
+}
+
+// FailoverStatus describes which Grafana instance is in use and the health
+// of both instances.
+type FailoverStatus struct {
+	// Active is "primary" or "secondary".
+	Active    string         `json:"active"`
+	Primary   InstanceHealth `json:"primary"`
+	Secondary InstanceHealth `json:"secondary"`
+}
+
+// Failover checks the health of a primary and a secondary Grafana instance,
+// and redirects tool calls to the secondary one while the primary is
+// unhealthy.
+//
+// A nil *Failover is valid and always uses the primary instance.
+type Failover struct {
+	primaryURL    string
+	secondaryURL  string
+	checkInterval time.Duration
+	threshold     int
+	httpClient    *http.Client
+	now           func() time.Time
+
+	mu     sync.Mutex
+	status FailoverStatus
+}
+
+// NewFailover creates a Failover. Health checks only start once Run is
+// called.
+func NewFailover(config FailoverConfig, tlsConfig *TLSConfig) (*Failover, error) {
+	if config.SecondaryURL == "" {
+		return nil, fmt.Errorf("a secondary Grafana URL is required for failover")
+	}
+	if config.PrimaryURL == "" {
+		config.PrimaryURL, _ = urlAndAPIKeyFromEnv()
+	}
+	if config.PrimaryURL == "" {
+		config.PrimaryURL = defaultGrafanaURL
+	}
+	transport, err := tlsConfig.HTTPTransport(http.DefaultTransport.(*http.Transport))
+	if err != nil {
+		return nil, fmt.Errorf("create health check transport: %w", err)
+	}
+	f := &Failover{
+		primaryURL:    strings.TrimRight(config.PrimaryURL, "/"),
+		secondaryURL:  strings.TrimRight(config.SecondaryURL, "/"),
+		checkInterval: config.CheckInterval,
+		threshold:     config.Threshold,
+		httpClient:    &http.Client{Transport: WithExtraHeaders(transport, config.Headers), Timeout: healthCheckTimeout},
+		now:           time.Now,
+	}
+	if f.checkInterval <= 0 {
+		f.checkInterval = DefaultFailoverCheckInterval
+	}
+	if f.threshold <= 0 {
+		f.threshold = DefaultFailoverThreshold
+	}
+	f.status = FailoverStatus{
+		Active:    "primary",
+		Primary:   InstanceHealth{URL: f.primaryURL},
+		Secondary: InstanceHealth{URL: f.secondaryURL},
+	}
+	return f, nil
+}
+
+// Run checks the health of both instances at the configured interval until
+// the context is done.
+func (f *Failover) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.checkInterval)
+	defer ticker.Stop()
+	for {
+		f.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkURL checks the health of the Grafana instance at u using its
+// unauthenticated health endpoint.
+func (f *Failover) checkURL(ctx context.Context, u string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u+"/api/health", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check returned status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// check checks the health of both instances once, failing over or back if
+// needed.
+func (f *Failover) check(ctx context.Context) {
+	primaryErr := f.checkURL(ctx, f.primaryURL)
+	secondaryErr := f.checkURL(ctx, f.secondaryURL)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	f.status.Primary.record(primaryErr, now)
+	f.status.Secondary.record(secondaryErr, now)
+	switch f.status.Active {
+	case "primary":
+		// Failing over to an instance which is down too would not help.
+		if f.status.Primary.ConsecutiveFailures >= f.threshold && f.status.Secondary.Healthy {
+			f.status.Active = "secondary"
+			slog.WarnContext(ctx, "Primary Grafana instance is unhealthy, failing over to the secondary instance", "primary", f.primaryURL, "secondary", f.secondaryURL, "error", primaryErr)
+		}
+	case "secondary":
+		if f.status.Primary.ConsecutiveSuccesses >= f.threshold {
+			f.status.Active = "primary"
+			slog.InfoContext(ctx, "Primary Grafana instance is healthy again, failing back", "primary", f.primaryURL)
+		}
+	}
+}
+
+// Status returns the current failover status.
+func (f *Failover) Status() FailoverStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.status
+}
+
+// ActiveURL returns the URL to use in place of the Grafana URL u: the
+// secondary instance's URL if u is the primary's and the server has failed
+// over, otherwise u itself. URLs of other instances, e.g. chosen by clients
+// with the X-Grafana-URL header, are never replaced.
+func (f *Failover) ActiveURL(u string) string {
+	if f == nil || strings.TrimRight(u, "/") != f.primaryURL {
+		return u
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.status.Active == "secondary" {
+		return f.secondaryURL
+	}
+	return u
+}
+
+// FailoverMiddleware returns a tool handler middleware sending the requests
+// of tool calls to the secondary Grafana instance while the server has
+// failed over.
+func FailoverMiddleware(f *Failover) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			cfg := GrafanaConfigFromContext(ctx)
+			if u := f.ActiveURL(cfg.URL); u != cfg.URL {
+				cfg.URL = u
+				ctx = WithGrafanaConfig(ctx, cfg)
+				ctx = WithGrafanaClient(ctx, NewGrafanaClient(ctx, u, cfg.APIKey))
+				ctx = WithIncidentClient(ctx, newIncidentClient(ctx, u, cfg.APIKey))
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// healthResponse is the body of the health endpoint.
+type healthResponse struct {
+	// Status is "ok", "degraded" while failed over, or "unavailable" if no
+	// Grafana instance is healthy.
+	Status  string          `json:"status"`
+	Grafana *FailoverStatus `json:"grafana,omitempty"`
+}
+
+// HealthHandler returns an HTTP handler reporting the health of the server
+// and, if failover is configured, which Grafana instance is in use. It
+// responds with 503 Service Unavailable if the instance in use is known to
+// be unhealthy.
+func HealthHandler(f *Failover) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := healthResponse{Status: "ok"}
+		code := http.StatusOK
+		if f != nil {
+			status := f.Status()
+			resp.Grafana = &status
+			active := status.Primary
+			if status.Active == "secondary" {
+				active = status.Secondary
+				resp.Status = "degraded"
+			}
+			if !active.LastCheck.IsZero() && !active.Healthy {
+				resp.Status = "unavailable"
+				code = http.StatusServiceUnavailable
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+}
@@ -0,0 +1,131 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newHealthServer returns a server whose health endpoint succeeds while
+// healthy is true.
+func newHealthServer(t *testing.T, healthy *atomic.Bool) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/health" || !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"database":"ok"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFailover(t *testing.T) {
+	var primaryHealthy, secondaryHealthy atomic.Bool
+	primaryHealthy.Store(true)
+	secondaryHealthy.Store(true)
+	primary := newHealthServer(t, &primaryHealthy)
+	secondary := newHealthServer(t, &secondaryHealthy)
+
+	f, err := NewFailover(FailoverConfig{PrimaryURL: primary.URL + "/", SecondaryURL: secondary.URL, Threshold: 2}, nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	f.check(ctx)
+	assert.Equal(t, "primary", f.Status().Active)
+	assert.Equal(t, primary.URL, f.ActiveURL(primary.URL))
+
+	// A single failure doesn't trigger failover.
+	primaryHealthy.Store(false)
+	f.check(ctx)
+	assert.Equal(t, "primary", f.Status().Active)
+
+	f.check(ctx)
+	status := f.Status()
+	assert.Equal(t, "secondary", status.Active)
+	assert.False(t, status.Primary.Healthy)
+	assert.Equal(t, 2, status.Primary.ConsecutiveFailures)
+	assert.Contains(t, status.Primary.LastError, "503")
+	assert.Equal(t, secondary.URL, f.ActiveURL(primary.URL))
+	assert.Equal(t, secondary.URL, f.ActiveURL(primary.URL+"/"))
+	assert.Equal(t, "http://other:3000", f.ActiveURL("http://other:3000"), "other instances are not replaced")
+
+	// Fail back once the primary has been healthy for long enough.
+	primaryHealthy.Store(true)
+	f.check(ctx)
+	assert.Equal(t, "secondary", f.Status().Active)
+	f.check(ctx)
+	assert.Equal(t, "primary", f.Status().Active)
+
+	// Don't fail over to an unhealthy secondary.
+	primaryHealthy.Store(false)
+	secondaryHealthy.Store(false)
+	f.check(ctx)
+	f.check(ctx)
+	assert.Equal(t, "primary", f.Status().Active)
+}
+
+func TestFailoverMiddleware(t *testing.T) {
+	var primaryHealthy, secondaryHealthy atomic.Bool
+	secondaryHealthy.Store(true)
+	primary := newHealthServer(t, &primaryHealthy)
+	secondary := newHealthServer(t, &secondaryHealthy)
+	f, err := NewFailover(FailoverConfig{PrimaryURL: primary.URL, SecondaryURL: secondary.URL, Threshold: 1}, nil)
+	require.NoError(t, err)
+
+	var gotURL string
+	handler := FailoverMiddleware(f)(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		gotURL = GrafanaConfigFromContext(ctx).URL
+		assert.NotNil(t, GrafanaClientFromContext(ctx))
+		return mcp.NewToolResultText("ok"), nil
+	})
+	ctx := WithGrafanaConfig(context.Background(), GrafanaConfig{URL: primary.URL, APIKey: "key"})
+
+	f.check(context.Background())
+	_, err = handler(ctx, mcp.CallToolRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, secondary.URL, gotURL)
+}
+
+func TestHealthHandler(t *testing.T) {
+	get := func(h http.Handler) (int, map[string]any) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		return rec.Code, body
+	}
+
+	code, body := get(HealthHandler(nil))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]any{"status": "ok"}, body)
+
+	var primaryHealthy, secondaryHealthy atomic.Bool
+	secondaryHealthy.Store(true)
+	primary := newHealthServer(t, &primaryHealthy)
+	secondary := newHealthServer(t, &secondaryHealthy)
+	f, err := NewFailover(FailoverConfig{PrimaryURL: primary.URL, SecondaryURL: secondary.URL, Threshold: 1}, nil)
+	require.NoError(t, err)
+
+	f.check(context.Background())
+	code, body = get(HealthHandler(f))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "degraded", body["status"])
+	assert.Equal(t, "secondary", body["grafana"].(map[string]any)["active"])
+
+	secondaryHealthy.Store(false)
+	f.check(context.Background())
+	code, body = get(HealthHandler(f))
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "unavailable", body["status"])
+}
@@ -0,0 +1,91 @@
+package mcpgrafana
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"os"
+	"strings"
+)
+
+// isHeaderName returns true if s is a valid header name, i.e. an HTTP token.
+func isHeaderName(s string) bool {
+	return s != "" && !strings.ContainsFunc(s, func(r rune) bool {
+		return r <= ' ' || r >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r)
+	})
+}
+
+// ParseHeader parses a header in the "Name: value" format. Errors don't
+// include the header, whose value may be a secret.
+func ParseHeader(s string) (string, string, error) {
+	name, value, ok := strings.Cut(s, ":")
+	name = strings.TrimSpace(name)
+	if !ok || !isHeaderName(name) {
+		return "", "", errors.New("invalid header, must be in the format 'Name: value'")
+	}
+	value = strings.TrimSpace(value)
+	if strings.ContainsAny(value, "\r\n\x00") {
+		return "", "", fmt.Errorf("invalid value of header %s", name)
+	}
+	return textproto.CanonicalMIMEHeaderKey(name), value, nil
+}
+
+// ReadHeadersFile reads headers from a file with one header per line in the
+// "Name: value" format. Blank lines and lines starting with # are ignored.
+func ReadHeadersFile(path string) (http.Header, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open headers file: %w", err)
+	}
+	defer f.Close()
+	headers := http.Header{}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		name, value, err := ParseHeader(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		headers.Add(name, value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read headers file: %w", err)
+	}
+	return headers, nil
+}
+
+// extraHeadersRoundTripper adds static headers to every request.
+type extraHeadersRoundTripper struct {
+	headers    http.Header
+	underlying http.RoundTripper
+}
+
+func (rt *extraHeadersRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, values := range rt.headers {
+		// Headers set by the client, e.g. its credentials, take precedence.
+		if _, ok := req.Header[name]; ok {
+			continue
+		}
+		req.Header[name] = values
+	}
+	return rt.underlying.RoundTrip(req)
+}
+
+// WithExtraHeaders wraps rt so that the given headers are added to every
+// request, e.g. for reverse proxies requiring their own authentication or
+// tenancy headers. Headers already set on a request are not replaced.
+func WithExtraHeaders(rt http.RoundTripper, headers http.Header) http.RoundTripper {
+	if len(headers) == 0 {
+		return rt
+	}
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return &extraHeadersRoundTripper{headers: headers, underlying: rt}
+}
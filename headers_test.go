@@ -0,0 +1,76 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHeader(t *testing.T) {
+	name, value, err := ParseHeader("x-scope-orgid:  42 ")
+	require.NoError(t, err)
+	assert.Equal(t, "X-Scope-Orgid", name)
+	assert.Equal(t, "42", value)
+
+	name, value, err = ParseHeader("Authorization: Basic a2V5OnNlY3JldA==")
+	require.NoError(t, err)
+	assert.Equal(t, "Authorization", name)
+	assert.Equal(t, "Basic a2V5OnNlY3JldA==", value)
+
+	for _, s := range []string{"no-colon", ": value", "Bad Name: value", "X-Ok: bad\nvalue"} {
+		_, _, err := ParseHeader(s)
+		assert.Error(t, err, s)
+	}
+	_, _, err = ParseHeader("secret-token")
+	assert.NotContains(t, err.Error(), "secret-token")
+}
+
+func TestReadHeadersFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "headers")
+	require.NoError(t, os.WriteFile(path, []byte("# Gateway headers\nX-Scope-OrgID: 42\n\nX-Gateway-Key: secret\nX-Scope-OrgID: 43\n"), 0o600))
+	headers, err := ReadHeadersFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"42", "43"}, headers.Values("X-Scope-OrgID"))
+	assert.Equal(t, "secret", headers.Get("X-Gateway-Key"))
+
+	require.NoError(t, os.WriteFile(path, []byte("X-Ok: 1\nsecret-token\n"), 0o600))
+	_, err = ReadHeadersFile(path)
+	assert.ErrorContains(t, err, "headers:2:")
+	assert.NotContains(t, err.Error(), "secret-token")
+}
+
+func TestWithExtraHeaders(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+	}))
+	defer srv.Close()
+
+	headers := http.Header{}
+	headers.Set("X-Scope-OrgID", "42")
+	headers.Set("Authorization", "Basic proxy")
+	client := &http.Client{Transport: WithExtraHeaders(nil, headers)}
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer grafana")
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, "42", got.Get("X-Scope-OrgID"))
+	// Headers set by the client are not replaced.
+	assert.Equal(t, "Bearer grafana", got.Get("Authorization"))
+	assert.Empty(t, req.Header.Get("X-Scope-OrgID"), "the original request must not be modified")
+
+	rt := http.DefaultTransport
+	assert.Equal(t, rt, WithExtraHeaders(rt, nil))
+}
@@ -0,0 +1,188 @@
+package mcpgrafana
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocaleHeader is the header used by clients of the MCP server to choose the
+// locale of numbers, durations and dates in rendered text, overriding the
+// server's locale.
+const LocaleHeader = "X-Grafana-Locale"
+
+// Locale formats numbers, durations and dates in text rendered for users,
+// such as the messages of summaries and markdown output. Structured fields,
+// e.g. JSON timestamps, are never localized.
+//
+// The zero Locale formats like "en", with ISO 8601 dates.
+type Locale struct {
+	tag string
+	// decimal and group are the decimal and digit group separators.
+	decimal, group string
+	// dateTime is the time layout of dates with a time of day.
+	dateTime string
+}
+
+// locales are the supported locales, by lower case BCP 47 tag. Regional
+// variants fall back to their language, e.g. "de-AT" to "de". Digit groups
+// are separated by non-breaking spaces where locales use spaces.
+var locales = map[string]Locale{
+	"en":    {tag: "en", decimal: ".", group: ",", dateTime: "2006-01-02 15:04 MST"},
+	"en-us": {tag: "en-US", decimal: ".", group: ",", dateTime: "Jan 2, 2006 3:04 PM MST"},
+	"en-gb": {tag: "en-GB", decimal: ".", group: ",", dateTime: "2 Jan 2006 15:04 MST"},
+	"de":    {tag: "de", decimal: ",", group: ".", dateTime: "02.01.2006 15:04 MST"},
+	"de-ch": {tag: "de-CH", decimal: ".", group: "\u2019", dateTime: "02.01.2006 15:04 MST"},
+	"es":    {tag: "es", decimal: ",", group: ".", dateTime: "02/01/2006 15:04 MST"},
+	"fr":    {tag: "fr", decimal: ",", group: "\u202f", dateTime: "02/01/2006 15:04 MST"},
+	"it":    {tag: "it", decimal: ",", group: ".", dateTime: "02/01/2006 15:04 MST"},
+	"ja":    {tag: "ja", decimal: ".", group: ",", dateTime: "2006/01/02 15:04 MST"},
+	"ko":    {tag: "ko", decimal: ".", group: ",", dateTime: "2006. 01. 02. 15:04 MST"},
+	"nl":    {tag: "nl", decimal: ",", group: ".", dateTime: "02-01-2006 15:04 MST"},
+	"pl":    {tag: "pl", decimal: ",", group: "\u00a0", dateTime: "02.01.2006 15:04 MST"},
+	"pt":    {tag: "pt", decimal: ",", group: ".", dateTime: "02/01/2006 15:04 MST"},
+	"sv":    {tag: "sv", decimal: ",", group: "\u00a0", dateTime: "2006-01-02 15:04 MST"},
+	"zh":    {tag: "zh", decimal: ".", group: ",", dateTime: "2006/01/02 15:04 MST"},
+}
+
+// SupportedLocales returns the tags of the supported locales, sorted.
+func SupportedLocales() []string {
+	tags := make([]string, 0, len(locales))
+	for _, l := range locales {
+		tags = append(tags, l.tag)
+	}
+	slices.Sort(tags)
+	return tags
+}
+
+// ParseLocale returns the locale with the given BCP 47 tag, e.g. "de" or
+// "en-GB", falling back to the tag's language if the region is not
+// supported. An empty tag returns the zero Locale.
+func ParseLocale(tag string) (Locale, error) {
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	if tag == "" {
+		return Locale{}, nil
+	}
+	if l, ok := locales[tag]; ok {
+		return l, nil
+	}
+	language, _, _ := strings.Cut(tag, "-")
+	if l, ok := locales[language]; ok {
+		return l, nil
+	}
+	return Locale{}, fmt.Errorf("unsupported locale %q, must be one of %s", tag, strings.Join(SupportedLocales(), ", "))
+}
+
+func localeFromHeaders(req *http.Request) (Locale, bool) {
+	tag := req.Header.Get(LocaleHeader)
+	if tag == "" {
+		return Locale{}, false
+	}
+	l, err := ParseLocale(tag)
+	if err != nil {
+		slog.Warn("Ignoring invalid locale", "source", LocaleHeader, "error", err)
+		return Locale{}, false
+	}
+	return l, true
+}
+
+// String returns the locale's BCP 47 tag.
+func (l Locale) String() string {
+	if l.tag == "" {
+		return "en"
+	}
+	return l.tag
+}
+
+func (l Locale) orDefault() Locale {
+	if l.tag == "" {
+		return locales["en"]
+	}
+	return l
+}
+
+// FormatInt formats an integer with digit grouping, e.g. "1,234,567" in
+// "en" and "1.234.567" in "de".
+func (l Locale) FormatInt(n int64) string {
+	l = l.orDefault()
+	s := strconv.FormatInt(n, 10)
+	sign := ""
+	if n < 0 {
+		sign, s = "-", s[1:]
+	}
+	return sign + groupDigits(s, l.group)
+}
+
+// FormatFloat formats a number with the given number of decimals and digit
+// grouping, e.g. "1,234.5" in "en" and "1.234,5" in "de".
+func (l Locale) FormatFloat(f float64, decimals int) string {
+	l = l.orDefault()
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	s := strconv.FormatFloat(math.Abs(f), 'f', decimals, 64)
+	sign := ""
+	if f < 0 && strings.Trim(s, "0.") != "" {
+		sign = "-"
+	}
+	integer, fraction, ok := strings.Cut(s, ".")
+	s = groupDigits(integer, l.group)
+	if ok {
+		s += l.decimal + fraction
+	}
+	return sign + s
+}
+
+// groupDigits inserts sep between groups of three digits.
+func groupDigits(digits, sep string) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	first := len(digits) % 3
+	if first == 0 {
+		first = 3
+	}
+	b.WriteString(digits[:first])
+	for i := first; i < len(digits); i += 3 {
+		b.WriteString(sep)
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
+
+// FormatDuration formats a duration using the largest fitting unit of
+// seconds, minutes and hours, e.g. "45 s", "12 min" or "26.5 h" in "en" and
+// "26,5 h" in "de". Unit symbols are the same in all locales.
+func (l Locale) FormatDuration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	var value float64
+	var unit string
+	switch {
+	case d < time.Minute:
+		value, unit = d.Seconds(), "s"
+	case d < time.Hour:
+		value, unit = d.Minutes(), "min"
+	default:
+		value, unit = d.Hours(), "h"
+	}
+	// Whole values are shown without decimals.
+	decimals := 1
+	if math.Round(value*10) == math.Round(value)*10 {
+		decimals = 0
+	}
+	return sign + l.FormatFloat(value, decimals) + " " + unit
+}
+
+// FormatTime formats a date with its time of day in the time's location.
+func (l Locale) FormatTime(t time.Time) string {
+	return t.Format(l.orDefault().dateTime)
+}
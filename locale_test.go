@@ -0,0 +1,79 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLocale(t *testing.T) {
+	for tag, want := range map[string]string{
+		"":      "en",
+		"de":    "de",
+		"de-AT": "de",
+		"de_CH": "de-CH",
+		"EN-gb": "en-GB",
+	} {
+		l, err := ParseLocale(tag)
+		require.NoError(t, err, tag)
+		assert.Equal(t, want, l.String(), tag)
+	}
+	_, err := ParseLocale("tlh")
+	assert.ErrorContains(t, err, "unsupported locale")
+}
+
+func TestLocaleFormat(t *testing.T) {
+	de, err := ParseLocale("de")
+	require.NoError(t, err)
+	fr, err := ParseLocale("fr")
+	require.NoError(t, err)
+	var en Locale
+
+	assert.Equal(t, "1,234,567", en.FormatInt(1234567))
+	assert.Equal(t, "-1.234", de.FormatInt(-1234))
+	assert.Equal(t, "999", de.FormatInt(999))
+	assert.Equal(t, "1,234.50", en.FormatFloat(1234.5, 2))
+	assert.Equal(t, "1.234,5", de.FormatFloat(1234.5, 1))
+	assert.Equal(t, "1\u202f234\u202f567,9", fr.FormatFloat(1234567.89, 1))
+	assert.Equal(t, "0", de.FormatFloat(-0.01, 0))
+	assert.Equal(t, "NaN", de.FormatFloat(math.NaN(), 1))
+
+	assert.Equal(t, "45 s", en.FormatDuration(45*time.Second))
+	assert.Equal(t, "12 min", en.FormatDuration(12*time.Minute))
+	assert.Equal(t, "26.5 h", en.FormatDuration(26*time.Hour+30*time.Minute))
+	assert.Equal(t, "26,5 h", de.FormatDuration(26*time.Hour+30*time.Minute))
+	assert.Equal(t, "-2 h", de.FormatDuration(-2*time.Hour))
+
+	ts := time.Date(2025, 3, 7, 14, 5, 0, 0, time.UTC)
+	assert.Equal(t, "2025-03-07 14:05 UTC", en.FormatTime(ts))
+	assert.Equal(t, "07.03.2025 14:05 UTC", de.FormatTime(ts))
+	us, err := ParseLocale("en-US")
+	require.NoError(t, err)
+	assert.Equal(t, "Mar 7, 2025 2:05 PM UTC", us.FormatTime(ts))
+}
+
+func TestLocaleFromHeaders(t *testing.T) {
+	ctx := WithGrafanaConfig(context.Background(), GrafanaConfig{Locale: locales["fr"]})
+
+	req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	require.NoError(t, err)
+	got := GrafanaConfigFromContext(ExtractGrafanaInfoFromHeaders(ctx, req)).Locale
+	assert.Equal(t, "fr", got.String())
+
+	req.Header.Set(LocaleHeader, "de-DE")
+	got = GrafanaConfigFromContext(ExtractGrafanaInfoFromHeaders(ctx, req)).Locale
+	assert.Equal(t, "de", got.String())
+
+	// Invalid locales are ignored.
+	req.Header.Set(LocaleHeader, "xx")
+	got = GrafanaConfigFromContext(ExtractGrafanaInfoFromHeaders(ctx, req)).Locale
+	assert.Equal(t, "fr", got.String())
+}
@@ -0,0 +1,114 @@
+package mcpgrafana
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// LogFormatText writes human readable `key=value` log lines.
+	LogFormatText = "text"
+	// LogFormatJSON writes one JSON object per log line, for ingestion into
+	// log aggregation systems such as Loki.
+	LogFormatJSON = "json"
+)
+
+type toolCallKey struct{}
+
+// toolCall identifies the tool call a context belongs to in log lines.
+type toolCall struct {
+	requestID string
+	tool      string
+}
+
+// newRequestID returns a random ID for a tool call.
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// RequestIDFromContext returns the ID of the tool call the context belongs
+// to, or an empty string outside of tool calls.
+func RequestIDFromContext(ctx context.Context) string {
+	if call, ok := ctx.Value(toolCallKey{}).(toolCall); ok {
+		return call.requestID
+	}
+	return ""
+}
+
+// LogContextMiddleware returns a tool handler middleware giving each tool
+// call a request ID and recording it, along with the tool name, in the
+// context. Log lines written with the context, e.g. using slog.InfoContext,
+// then carry these as attributes when using a handler from NewLogHandler.
+func LogContextMiddleware() server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			ctx = context.WithValue(ctx, toolCallKey{}, toolCall{requestID: newRequestID(), tool: request.Params.Name})
+			return next(ctx, request)
+		}
+	}
+}
+
+// contextHandler adds the session ID, request ID and tool name found in the
+// context of each record to the records passed to the wrapped handler, and
+// redacts the queries logged as attributes if a query redactor is set.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if queryRedactionEnabled() {
+		redacted := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+		r.Attrs(func(a slog.Attr) bool {
+			redacted.AddAttrs(redactQueryAttr(a))
+			return true
+		})
+		r = redacted
+	}
+	if ctx != nil {
+		if id := sessionIDFromContext(ctx); id != "" {
+			r.AddAttrs(slog.String("sessionId", id))
+		}
+		if call, ok := ctx.Value(toolCallKey{}).(toolCall); ok {
+			r.AddAttrs(slog.String("requestId", call.requestID), slog.String("tool", call.tool))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if queryRedactionEnabled() {
+		redacted := make([]slog.Attr, len(attrs))
+		for i, a := range attrs {
+			redacted[i] = redactQueryAttr(a)
+		}
+		attrs = redacted
+	}
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// NewLogHandler returns a log handler writing to w in the given format,
+// LogFormatText or LogFormatJSON. Records logged with the context of a tool
+// call carry its session ID, request ID and tool name.
+func NewLogHandler(w io.Writer, format string, level slog.Level) (slog.Handler, error) {
+	opts := &slog.HandlerOptions{Level: level}
+	switch format {
+	case LogFormatText, "":
+		return contextHandler{slog.NewTextHandler(w, opts)}, nil
+	case LogFormatJSON:
+		return contextHandler{slog.NewJSONHandler(w, opts)}, nil
+	}
+	return nil, fmt.Errorf("unknown log format %q: must be %q or %q", format, LogFormatText, LogFormatJSON)
+}
@@ -0,0 +1,56 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	handler, err := NewLogHandler(&buf, LogFormatJSON, slog.LevelInfo)
+	require.NoError(t, err)
+	logger := slog.New(handler).With("component", "test")
+
+	var requestID string
+	tool := LogContextMiddleware()(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		requestID = RequestIDFromContext(ctx)
+		logger.InfoContext(ctx, "inside tool call")
+		logger.DebugContext(ctx, "below the log level")
+		return mcp.NewToolResultText("ok"), nil
+	})
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "grafana_search_dashboards"
+	_, err = tool(context.Background(), request)
+	require.NoError(t, err)
+	logger.Info("outside tool call")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(lines[0], &record))
+	assert.Equal(t, "inside tool call", record["msg"])
+	assert.Equal(t, "test", record["component"])
+	assert.Equal(t, "grafana_search_dashboards", record["tool"])
+	assert.Len(t, requestID, 16)
+	assert.Equal(t, requestID, record["requestId"])
+
+	record = nil
+	require.NoError(t, json.Unmarshal(lines[1], &record))
+	assert.Equal(t, "outside tool call", record["msg"])
+	assert.NotContains(t, record, "requestId")
+	assert.NotContains(t, record, "tool")
+
+	_, err = NewLogHandler(&buf, "xml", slog.LevelInfo)
+	assert.Error(t, err)
+}
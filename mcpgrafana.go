@@ -9,8 +9,10 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 
+	httptransport "github.com/go-openapi/runtime/client"
 	"github.com/go-openapi/strfmt"
 	"github.com/grafana/grafana-openapi-client-go/client"
 	"github.com/grafana/incident-go"
@@ -21,16 +23,25 @@ const (
 	defaultGrafanaHost = "localhost:3000"
 	defaultGrafanaURL  = "http://" + defaultGrafanaHost
 
-	grafanaURLEnvVar = "GRAFANA_URL"
-	grafanaAPIEnvVar = "GRAFANA_API_KEY"
+	grafanaURLEnvVar   = "GRAFANA_URL"
+	grafanaAPIEnvVar   = "GRAFANA_API_KEY"
+	grafanaOrgIDEnvVar = "GRAFANA_ORG_ID"
 
 	grafanaURLHeader    = "X-Grafana-URL"
 	grafanaAPIKeyHeader = "X-Grafana-API-Key"
+
+	// OrgIDHeader is the header used to select the Grafana organization a
+	// request applies to, both by clients of the MCP server and in requests
+	// made to Grafana.
+	OrgIDHeader = "X-Grafana-Org-Id"
 )
 
 func urlAndAPIKeyFromEnv() (string, string) {
 	u := strings.TrimRight(os.Getenv(grafanaURLEnvVar), "/")
 	apiKey := os.Getenv(grafanaAPIEnvVar)
+	if apiKey == "" {
+		apiKey = apiKeyFromFile()
+	}
 	return u, apiKey
 }
 
@@ -40,6 +51,52 @@ func urlAndAPIKeyFromHeaders(req *http.Request) (string, string) {
 	return u, apiKey
 }
 
+// parseOrgID parses an organization ID, returning 0 (the default
+// organization of the credentials) if it is empty or invalid.
+func parseOrgID(s, source string) int64 {
+	if s == "" {
+		return 0
+	}
+	orgID, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || orgID <= 0 {
+		slog.Warn("Ignoring invalid Grafana organization ID", "source", source, "value", s)
+		return 0
+	}
+	return orgID
+}
+
+func orgIDFromEnv() int64 {
+	return parseOrgID(os.Getenv(grafanaOrgIDEnvVar), grafanaOrgIDEnvVar)
+}
+
+func orgIDFromHeaders(req *http.Request) int64 {
+	return parseOrgID(req.Header.Get(OrgIDHeader), OrgIDHeader)
+}
+
+// orgIDRoundTripper sets the organization header on every request.
+type orgIDRoundTripper struct {
+	orgID      string
+	underlying http.RoundTripper
+}
+
+func (rt *orgIDRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set(OrgIDHeader, rt.orgID)
+	return rt.underlying.RoundTrip(req)
+}
+
+// withOrgID wraps rt so that requests are made in the given organization,
+// if one is set.
+func withOrgID(rt http.RoundTripper, orgID int64) http.RoundTripper {
+	if orgID == 0 {
+		return rt
+	}
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return &orgIDRoundTripper{orgID: strconv.FormatInt(orgID, 10), underlying: rt}
+}
+
 // grafanaConfigKey is the context key for Grafana configuration.
 type grafanaConfigKey struct{}
 
@@ -70,8 +127,89 @@ type GrafanaConfig struct {
 	// It is used for on-behalf-of auth in Grafana Cloud.
 	IDToken string
 
+	// OrgID is the ID of the Grafana organization requests are made in. It is
+	// sent as the `X-Grafana-Org-Id` header by all clients. Zero means the
+	// default organization of the credentials.
+	OrgID int64
+
+	// ExtraHeaders are static headers sent with every request by all
+	// clients, e.g. for reverse proxies requiring their own authentication
+	// or tenancy headers.
+	ExtraHeaders http.Header
+
 	// TLSConfig holds TLS configuration for all Grafana clients.
 	TLSConfig *TLSConfig
+
+	// OAuth enables forwarding user access tokens sent by clients of the
+	// HTTP transports to Grafana, if set.
+	OAuth *OAuthConfig
+
+	// Retry configures retries of requests failing with 429 or transient
+	// 5xx responses for all Grafana clients. Requests are not retried if nil.
+	Retry *RetryConfig
+	// DiskCache caches immutable artifacts fetched from Grafana on disk, if
+	// set.
+	DiskCache *DiskCache
+
+	// Failover sends requests to a standby Grafana instance while the
+	// primary one is unhealthy, if set.
+	Failover *Failover
+
+	// APIKeyFile is the file the Grafana token is read from, if set with
+	// GRAFANA_API_KEY_FILE. Tool calls use its current token after a
+	// rotation.
+	APIKeyFile *TokenFile
+
+	// SelfMetrics records the metrics of the server and pushes them to a
+	// datasource, if set.
+	SelfMetrics *SelfMetrics
+
+	// Locale is the locale of numbers, durations and dates in rendered text.
+	// It can be overridden per request with the `X-Grafana-Locale` header.
+	Locale Locale
+
+	// Scope is the default scope of tools which list or search resources,
+	// e.g. a team's alert rules and dashboards.
+	Scope Scope
+
+	// DSQuery lists the types of datasources, e.g. "loki", whose queries are
+	// run through Grafana's /api/ds/query endpoint instead of the datasource
+	// proxy, so that datasource features of Grafana such as LBAC rules,
+	// query caching and per-user permissions apply to them.
+	DSQuery []string
+
+	// LokiLimits are the maximum sizes of the results of Loki queries.
+	LokiLimits LokiLimits
+
+	// SeverityRubric is the rubric used to suggest the severity of
+	// incidents. DefaultSeverityRubric is used if it is nil.
+	SeverityRubric *SeverityRubric
+
+	// MigrationTarget is a second Grafana instance, e.g. the destination of
+	// a migration, which the migration tools compare this instance with.
+	MigrationTarget *MigrationTarget
+}
+
+// LokiLimits are the maximum sizes of the results of Loki queries, which
+// differ between log queries and metric queries. Zero values mean the
+// defaults of the tools.
+type LokiLimits struct {
+	// MaxLogLines is the maximum number of lines returned by a log query.
+	MaxLogLines int
+	// MaxMetricSamples is the maximum number of samples returned by a
+	// metric query, across all its series.
+	MaxMetricSamples int
+}
+
+// MigrationTarget is the Grafana instance compared with the configured one by
+// the migration tools. Requests to it use the TLS and retry configuration of
+// the configured instance, but not its extra headers.
+type MigrationTarget struct {
+	URL    string
+	APIKey string
+	// OrgID is the organization to compare with, or 0 for the default
+	// organization of the API key.
+	OrgID int64
 }
 
 // WithGrafanaConfig adds Grafana configuration to the context.
@@ -149,13 +287,18 @@ var ExtractGrafanaInfoFromEnv server.StdioContextFunc = func(ctx context.Context
 	if err != nil {
 		panic(fmt.Errorf("invalid Grafana URL %s: %w", u, err))
 	}
-	slog.Info("Using Grafana configuration", "url", parsedURL.Redacted(), "api_key_set", apiKey != "")
+	slog.InfoContext(ctx, "Using Grafana configuration", "url", parsedURL.Redacted(), "api_key_set", apiKey != "")
 
 	// Get existing config or create a new one.
 	// This will respect the existing debug flag, if set.
 	config := GrafanaConfigFromContext(ctx)
 	config.URL = u
 	config.APIKey = apiKey
+	// An organization set explicitly in the configuration, e.g. with the
+	// --org-id flag, takes precedence over the environment.
+	if config.OrgID == 0 {
+		config.OrgID = orgIDFromEnv()
+	}
 	return WithGrafanaConfig(ctx, config)
 }
 
@@ -168,6 +311,9 @@ type httpContextFunc func(ctx context.Context, req *http.Request) context.Contex
 // from request headers and injects a configured client into the context.
 var ExtractGrafanaInfoFromHeaders httpContextFunc = func(ctx context.Context, req *http.Request) context.Context {
 	u, apiKey := urlAndAPIKeyFromHeaders(req)
+	if apiKey == "" {
+		apiKey = forwardedToken(ctx, req)
+	}
 	uEnv, apiKeyEnv := urlAndAPIKeyFromEnv()
 	if u == "" {
 		u = uEnv
@@ -184,6 +330,16 @@ var ExtractGrafanaInfoFromHeaders httpContextFunc = func(ctx context.Context, re
 	config := GrafanaConfigFromContext(ctx)
 	config.URL = u
 	config.APIKey = apiKey
+	// The organization can be chosen per request with a header, falling back
+	// to the configuration and then the environment.
+	if orgID := orgIDFromHeaders(req); orgID != 0 {
+		config.OrgID = orgID
+	} else if config.OrgID == 0 {
+		config.OrgID = orgIDFromEnv()
+	}
+	if locale, ok := localeFromHeaders(req); ok {
+		config.Locale = locale
+	}
 	return WithGrafanaConfig(ctx, config)
 }
 
@@ -246,6 +402,7 @@ func NewGrafanaClient(ctx context.Context, grafanaURL, apiKey string) *client.Gr
 
 	config := GrafanaConfigFromContext(ctx)
 	cfg.Debug = config.Debug
+	cfg.OrgID = config.OrgID
 
 	// Configure TLS if custom TLS configuration is provided
 	if tlsConfig := config.TLSConfig; tlsConfig != nil {
@@ -254,14 +411,20 @@ func NewGrafanaClient(ctx context.Context, grafanaURL, apiKey string) *client.Gr
 			panic(fmt.Errorf("failed to create TLS config: %w", err))
 		}
 		cfg.TLSConfig = tlsCfg
-		slog.Debug("Using custom TLS configuration",
+		slog.DebugContext(ctx, "Using custom TLS configuration",
 			"cert_file", tlsConfig.CertFile,
 			"ca_file", tlsConfig.CAFile,
 			"skip_verify", tlsConfig.SkipVerify)
 	}
 
-	slog.Debug("Creating Grafana client", "url", parsedURL.Redacted(), "api_key_set", apiKey != "")
-	return client.NewHTTPClientWithConfig(strfmt.Default, cfg)
+	slog.DebugContext(ctx, "Creating Grafana client", "url", parsedURL.Redacted(), "api_key_set", apiKey != "")
+	c := client.NewHTTPClientWithConfig(strfmt.Default, cfg)
+	// The runtime is shared by all the API's subclients, so wrapping its
+	// transport applies retries and extra headers to all of them.
+	if rt, ok := c.Transport.(*httptransport.Runtime); ok {
+		rt.Transport = config.Retry.RoundTripper(WithExtraHeaders(rt.Transport, config.ExtraHeaders))
+	}
+	return c
 }
 
 // ExtractGrafanaClientFromEnv is a StdioContextFunc that extracts Grafana configuration
@@ -272,7 +435,7 @@ var ExtractGrafanaClientFromEnv server.StdioContextFunc = func(ctx context.Conte
 	if !ok {
 		grafanaURL = defaultGrafanaURL
 	}
-	apiKey := os.Getenv(grafanaAPIEnvVar)
+	_, apiKey := urlAndAPIKeyFromEnv()
 
 	grafanaClient := NewGrafanaClient(ctx, grafanaURL, apiKey)
 	return context.WithValue(ctx, grafanaClientKey{}, grafanaClient)
@@ -283,6 +446,9 @@ var ExtractGrafanaClientFromEnv server.StdioContextFunc = func(ctx context.Conte
 var ExtractGrafanaClientFromHeaders httpContextFunc = func(ctx context.Context, req *http.Request) context.Context {
 	// Extract transport config from request headers, and set it on the context.
 	u, apiKey := urlAndAPIKeyFromHeaders(req)
+	if apiKey == "" {
+		apiKey = forwardedToken(ctx, req)
+	}
 	uEnv, apiKeyEnv := urlAndAPIKeyFromEnv()
 	if u == "" {
 		u = uEnv
@@ -316,38 +482,49 @@ func GrafanaClientFromContext(ctx context.Context) *client.GrafanaHTTPAPI {
 
 type incidentClientKey struct{}
 
-var ExtractIncidentClientFromEnv server.StdioContextFunc = func(ctx context.Context) context.Context {
-	grafanaURL, apiKey := urlAndAPIKeyFromEnv()
-	if grafanaURL == "" {
-		grafanaURL = defaultGrafanaURL
-	}
+// newIncidentClient creates a Grafana Incident client for the Grafana
+// instance at grafanaURL, configured with the TLS configuration and
+// organization found in the context.
+func newIncidentClient(ctx context.Context, grafanaURL, apiKey string) *incident.Client {
 	incidentURL := fmt.Sprintf("%s/api/plugins/grafana-irm-app/resources/api/v1/", grafanaURL)
 	parsedURL, err := url.Parse(incidentURL)
 	if err != nil {
 		panic(fmt.Errorf("invalid incident URL %s: %w", incidentURL, err))
 	}
-	slog.Debug("Creating Incident client", "url", parsedURL.Redacted(), "api_key_set", apiKey != "")
+	slog.DebugContext(ctx, "Creating Incident client", "url", parsedURL.Redacted(), "api_key_set", apiKey != "")
 	client := incident.NewClient(incidentURL, apiKey)
 
 	// Configure custom TLS if available
 	if tlsConfig := GrafanaConfigFromContext(ctx).TLSConfig; tlsConfig != nil {
 		transport, err := tlsConfig.HTTPTransport(http.DefaultTransport.(*http.Transport))
 		if err != nil {
-			slog.Error("Failed to create custom transport for incident client, using default", "error", err)
+			slog.ErrorContext(ctx, "Failed to create custom transport for incident client, using default", "error", err)
 		} else {
 			client.HTTPClient.Transport = transport
-			slog.Debug("Using custom TLS configuration for incident client",
+			slog.DebugContext(ctx, "Using custom TLS configuration for incident client",
 				"cert_file", tlsConfig.CertFile,
 				"ca_file", tlsConfig.CAFile,
 				"skip_verify", tlsConfig.SkipVerify)
 		}
 	}
+	config := GrafanaConfigFromContext(ctx)
+	client.HTTPClient.Transport = WithExtraHeaders(withOrgID(client.HTTPClient.Transport, config.OrgID), config.ExtraHeaders)
+	return client
+}
 
-	return context.WithValue(ctx, incidentClientKey{}, client)
+var ExtractIncidentClientFromEnv server.StdioContextFunc = func(ctx context.Context) context.Context {
+	grafanaURL, apiKey := urlAndAPIKeyFromEnv()
+	if grafanaURL == "" {
+		grafanaURL = defaultGrafanaURL
+	}
+	return context.WithValue(ctx, incidentClientKey{}, newIncidentClient(ctx, grafanaURL, apiKey))
 }
 
 var ExtractIncidentClientFromHeaders httpContextFunc = func(ctx context.Context, req *http.Request) context.Context {
 	grafanaURL, apiKey := urlAndAPIKeyFromHeaders(req)
+	if apiKey == "" {
+		apiKey = forwardedToken(ctx, req)
+	}
 	grafanaURLEnv, apiKeyEnv := urlAndAPIKeyFromEnv()
 	if grafanaURL == "" {
 		grafanaURL = grafanaURLEnv
@@ -358,24 +535,7 @@ var ExtractIncidentClientFromHeaders httpContextFunc = func(ctx context.Context,
 	if apiKey == "" {
 		apiKey = apiKeyEnv
 	}
-	incidentURL := fmt.Sprintf("%s/api/plugins/grafana-irm-app/resources/api/v1/", grafanaURL)
-	client := incident.NewClient(incidentURL, apiKey)
-
-	// Configure custom TLS if available
-	if tlsConfig := GrafanaConfigFromContext(ctx).TLSConfig; tlsConfig != nil {
-		transport, err := tlsConfig.HTTPTransport(http.DefaultTransport.(*http.Transport))
-		if err != nil {
-			slog.Error("Failed to create custom transport for incident client, using default", "error", err)
-		} else {
-			client.HTTPClient.Transport = transport
-			slog.Debug("Using custom TLS configuration for incident client",
-				"cert_file", tlsConfig.CertFile,
-				"ca_file", tlsConfig.CAFile,
-				"skip_verify", tlsConfig.SkipVerify)
-		}
-	}
-
-	return context.WithValue(ctx, incidentClientKey{}, client)
+	return context.WithValue(ctx, incidentClientKey{}, newIncidentClient(ctx, grafanaURL, apiKey))
 }
 
 func WithIncidentClient(ctx context.Context, client *incident.Client) context.Context {
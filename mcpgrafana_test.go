@@ -6,6 +6,7 @@ package mcpgrafana
 import (
 	"context"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/go-openapi/runtime/client"
@@ -210,3 +211,62 @@ func TestExtractGrafanaClientFromHeaders(t *testing.T) {
 		assert.Equal(t, "/api", url.basePath)
 	})
 }
+
+func TestOrgID(t *testing.T) {
+	t.Run("from env", func(t *testing.T) {
+		t.Setenv("GRAFANA_ORG_ID", "2")
+		ctx := ExtractGrafanaInfoFromEnv(context.Background())
+		assert.Equal(t, int64(2), GrafanaConfigFromContext(ctx).OrgID)
+	})
+
+	t.Run("config takes precedence over env", func(t *testing.T) {
+		t.Setenv("GRAFANA_ORG_ID", "2")
+		ctx := WithGrafanaConfig(context.Background(), GrafanaConfig{OrgID: 3})
+		ctx = ExtractGrafanaInfoFromEnv(ctx)
+		assert.Equal(t, int64(3), GrafanaConfigFromContext(ctx).OrgID)
+	})
+
+	t.Run("invalid env", func(t *testing.T) {
+		t.Setenv("GRAFANA_ORG_ID", "main")
+		ctx := ExtractGrafanaInfoFromEnv(context.Background())
+		assert.Zero(t, GrafanaConfigFromContext(ctx).OrgID)
+	})
+
+	t.Run("header takes precedence over config", func(t *testing.T) {
+		t.Setenv("GRAFANA_ORG_ID", "2")
+		req, err := http.NewRequest("GET", "http://example.com", nil)
+		require.NoError(t, err)
+		req.Header.Set(OrgIDHeader, "4")
+		ctx := WithGrafanaConfig(context.Background(), GrafanaConfig{OrgID: 3})
+		ctx = ExtractGrafanaInfoFromHeaders(ctx, req)
+		assert.Equal(t, int64(4), GrafanaConfigFromContext(ctx).OrgID)
+	})
+
+	t.Run("no header", func(t *testing.T) {
+		t.Setenv("GRAFANA_ORG_ID", "2")
+		req, err := http.NewRequest("GET", "http://example.com", nil)
+		require.NoError(t, err)
+		ctx := ExtractGrafanaInfoFromHeaders(context.Background(), req)
+		assert.Equal(t, int64(2), GrafanaConfigFromContext(ctx).OrgID)
+	})
+
+	t.Run("propagated to clients", func(t *testing.T) {
+		var orgIDs []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			orgIDs = append(orgIDs, r.Header.Get(OrgIDHeader))
+			_, _ = w.Write([]byte(`{}`))
+		}))
+		defer server.Close()
+
+		ctx := WithGrafanaConfig(context.Background(), GrafanaConfig{OrgID: 5})
+		_, _ = NewGrafanaClient(ctx, server.URL, "").Health.GetHealth()
+
+		t.Setenv("GRAFANA_URL", server.URL)
+		ctx = ExtractIncidentClientFromEnv(ctx)
+		resp, err := IncidentClientFromContext(ctx).HTTPClient.Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, []string{"5", "5"}, orgIDs)
+	})
+}
@@ -0,0 +1,218 @@
+package mcpgrafana
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+const (
+	// jwksMinRefreshInterval is the minimum time between two fetches of the
+	// issuer's keys, which are refreshed when a token is signed with an
+	// unknown key.
+	jwksMinRefreshInterval = time.Minute
+	// tokenLeeway is the clock skew allowed when validating token expiry.
+	tokenLeeway = 30 * time.Second
+)
+
+// tokenSignatureAlgorithms are the signature algorithms accepted for
+// forwarded tokens. Symmetric algorithms are not accepted since the keys are
+// public.
+var tokenSignatureAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.EdDSA,
+}
+
+// OAuthConfig configures the forwarding of user access tokens, sent by
+// clients of the HTTP transports in the `Authorization: Bearer` header, to
+// Grafana. Forwarded tokens are used in place of the API key, so that tools
+// run with the permissions of the user rather than those of a shared service
+// account.
+type OAuthConfig struct {
+	// Issuer is the URL of an OIDC issuer. If set, forwarded tokens must be
+	// JWTs issued and signed by it, and requests without a valid token are
+	// rejected. The issuer's keys are found using OIDC discovery.
+	Issuer string
+	// Audience, if set, must be one of the audiences of forwarded tokens.
+	Audience string
+}
+
+// bearerToken returns the token in the Authorization header of a request, or
+// an empty string if there is none.
+func bearerToken(req *http.Request) string {
+	scheme, token, ok := strings.Cut(req.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// forwardedToken returns the user access token to forward to Grafana, if
+// token passthrough is enabled and the request carries one.
+func forwardedToken(ctx context.Context, req *http.Request) string {
+	if GrafanaConfigFromContext(ctx).OAuth == nil {
+		return ""
+	}
+	return bearerToken(req)
+}
+
+// tokenValidator validates JWTs against the keys of an OIDC issuer.
+type tokenValidator struct {
+	audience   string
+	httpClient *http.Client
+	now        func() time.Time
+
+	mu sync.Mutex
+	// issuer is matched exactly against the iss claim of tokens: it is the
+	// issuer as configured until discovery returns it as the issuer spells
+	// it, e.g. with a trailing slash.
+	issuer    string
+	keys      jose.JSONWebKeySet
+	fetchedAt time.Time
+}
+
+func newTokenValidator(config OAuthConfig) *tokenValidator {
+	return &tokenValidator{
+		issuer:     config.Issuer,
+		audience:   config.Audience,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		now:        time.Now,
+	}
+}
+
+func (v *tokenValidator) getJSON(ctx context.Context, u string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, u)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// fetchKeys fetches the issuer's signing keys using OIDC discovery. It must
+// be called with v.mu held.
+func (v *tokenValidator) fetchKeys(ctx context.Context) error {
+	v.fetchedAt = v.now()
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	base := strings.TrimRight(v.issuer, "/")
+	if err := v.getJSON(ctx, base+"/.well-known/openid-configuration", &discovery); err != nil {
+		return fmt.Errorf("discover OIDC configuration: %w", err)
+	}
+	if strings.TrimRight(discovery.Issuer, "/") != base {
+		return fmt.Errorf("discovered issuer %q does not match %q", discovery.Issuer, v.issuer)
+	}
+	var keys jose.JSONWebKeySet
+	if err := v.getJSON(ctx, discovery.JWKSURI, &keys); err != nil {
+		return fmt.Errorf("fetch signing keys: %w", err)
+	}
+	v.keys = keys
+	v.issuer = discovery.Issuer
+	return nil
+}
+
+// keysFor returns the issuer's keys with the given ID, refreshing the keys
+// if none is found and they were not fetched recently, along with the
+// issuer.
+func (v *tokenValidator) keysFor(ctx context.Context, kid string) ([]jose.JSONWebKey, string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if keys := v.keys.Key(kid); len(keys) > 0 {
+		return keys, v.issuer, nil
+	}
+	if !v.fetchedAt.IsZero() && v.now().Sub(v.fetchedAt) < jwksMinRefreshInterval {
+		return nil, "", fmt.Errorf("unknown signing key %q", kid)
+	}
+	if err := v.fetchKeys(ctx); err != nil {
+		return nil, "", err
+	}
+	if keys := v.keys.Key(kid); len(keys) > 0 {
+		return keys, v.issuer, nil
+	}
+	return nil, "", fmt.Errorf("unknown signing key %q", kid)
+}
+
+// validate checks that token is a JWT signed by the issuer, and that its
+// issuer, audience and validity period are as expected.
+func (v *tokenValidator) validate(ctx context.Context, token string) error {
+	tok, err := jwt.ParseSigned(token, tokenSignatureAlgorithms)
+	if err != nil {
+		return fmt.Errorf("parse token: %w", err)
+	}
+	if len(tok.Headers) != 1 {
+		return errors.New("token must have exactly one signature")
+	}
+	keys, issuer, err := v.keysFor(ctx, tok.Headers[0].KeyID)
+	if err != nil {
+		return err
+	}
+	var claims jwt.Claims
+	var verifyErr error
+	for _, key := range keys {
+		if verifyErr = tok.Claims(key.Key, &claims); verifyErr == nil {
+			break
+		}
+	}
+	if verifyErr != nil {
+		return fmt.Errorf("verify token signature: %w", verifyErr)
+	}
+	expected := jwt.Expected{Issuer: issuer, Time: v.now()}
+	if v.audience != "" {
+		expected.AnyAudience = jwt.Audience{v.audience}
+	}
+	if claims.Expiry == nil {
+		return errors.New("token has no expiry")
+	}
+	if err := claims.ValidateWithLeeway(expected, tokenLeeway); err != nil {
+		return fmt.Errorf("validate token claims: %w", err)
+	}
+	return nil
+}
+
+// OAuthMiddleware returns an HTTP middleware enforcing the token validation
+// configured in config. If no issuer is configured, requests are passed
+// through unchanged. Otherwise requests without a valid bearer token are
+// rejected with a 401 response.
+func OAuthMiddleware(config OAuthConfig) func(http.Handler) http.Handler {
+	if config.Issuer == "" {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	validator := newTokenValidator(config)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := bearerToken(r)
+			if token == "" {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "missing bearer token", http.StatusUnauthorized)
+				return
+			}
+			if err := validator.validate(r.Context(), token); err != nil {
+				slog.DebugContext(r.Context(), "Rejecting request with invalid bearer token", "error", err)
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, "invalid bearer token", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,154 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testIssuer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+	// suffix is appended to the URL of the server in the issuer it
+	// advertises, e.g. a trailing slash.
+	suffix string
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	issuer := &testIssuer{key: key}
+	issuer.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			_ = json.NewEncoder(w).Encode(map[string]string{"issuer": issuer.URL + issuer.suffix, "jwks_uri": issuer.URL + "/keys"})
+		case "/keys":
+			_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &key.PublicKey, KeyID: "key-1", Algorithm: string(jose.RS256), Use: "sig"}}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(issuer.Close)
+	return issuer
+}
+
+func (i *testIssuer) token(t *testing.T, kid string, claims jwt.Claims) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: i.key}, (&jose.SignerOptions{}).WithHeader("kid", kid))
+	require.NoError(t, err)
+	token, err := jwt.Signed(signer).Claims(claims).Serialize()
+	require.NoError(t, err)
+	return token
+}
+
+func TestTokenValidatorIssuerTrailingSlash(t *testing.T) {
+	issuer := newTestIssuer(t)
+	issuer.suffix = "/"
+	expiry := jwt.NewNumericDate(time.Now().Add(time.Hour))
+	for _, configured := range []string{issuer.URL, issuer.URL + "/"} {
+		validator := newTokenValidator(OAuthConfig{Issuer: configured})
+		token := issuer.token(t, "key-1", jwt.Claims{Issuer: issuer.URL + "/", Expiry: expiry})
+		assert.NoError(t, validator.validate(context.Background(), token), configured)
+		token = issuer.token(t, "key-1", jwt.Claims{Issuer: issuer.URL, Expiry: expiry})
+		assert.Error(t, validator.validate(context.Background(), token), "the iss claim must match the issuer exactly")
+	}
+}
+
+func TestTokenValidator(t *testing.T) {
+	issuer := newTestIssuer(t)
+	validator := newTokenValidator(OAuthConfig{Issuer: issuer.URL, Audience: "mcp-grafana"})
+	expiry := jwt.NewNumericDate(time.Now().Add(time.Hour))
+
+	t.Run("valid", func(t *testing.T) {
+		token := issuer.token(t, "key-1", jwt.Claims{Issuer: issuer.URL, Subject: "alice", Audience: jwt.Audience{"mcp-grafana"}, Expiry: expiry})
+		assert.NoError(t, validator.validate(context.Background(), token))
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		token := issuer.token(t, "key-1", jwt.Claims{Issuer: issuer.URL, Audience: jwt.Audience{"mcp-grafana"}, Expiry: jwt.NewNumericDate(time.Now().Add(-time.Hour))})
+		assert.ErrorContains(t, validator.validate(context.Background(), token), "expired")
+	})
+
+	t.Run("wrong audience", func(t *testing.T) {
+		token := issuer.token(t, "key-1", jwt.Claims{Issuer: issuer.URL, Audience: jwt.Audience{"other"}, Expiry: expiry})
+		assert.Error(t, validator.validate(context.Background(), token))
+	})
+
+	t.Run("wrong issuer", func(t *testing.T) {
+		token := issuer.token(t, "key-1", jwt.Claims{Issuer: "https://evil.example.com", Audience: jwt.Audience{"mcp-grafana"}, Expiry: expiry})
+		assert.Error(t, validator.validate(context.Background(), token))
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		token := issuer.token(t, "key-2", jwt.Claims{Issuer: issuer.URL, Audience: jwt.Audience{"mcp-grafana"}, Expiry: expiry})
+		assert.ErrorContains(t, validator.validate(context.Background(), token), "unknown signing key")
+	})
+
+	t.Run("not a JWT", func(t *testing.T) {
+		assert.Error(t, validator.validate(context.Background(), "glsa_service_account_token"))
+	})
+}
+
+func TestOAuthMiddleware(t *testing.T) {
+	issuer := newTestIssuer(t)
+	handler := OAuthMiddleware(OAuthConfig{Issuer: issuer.URL})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+
+	rec = serve("Bearer not-a-jwt")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Header().Get("WWW-Authenticate"), "invalid_token")
+
+	token := issuer.token(t, "key-1", jwt.Claims{Issuer: issuer.URL, Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))})
+	assert.Equal(t, http.StatusOK, serve("Bearer "+token).Code)
+}
+
+func TestForwardedToken(t *testing.T) {
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer user-token")
+
+	t.Run("passthrough disabled", func(t *testing.T) {
+		ctx := ExtractGrafanaInfoFromHeaders(context.Background(), req)
+		assert.Equal(t, "", GrafanaConfigFromContext(ctx).APIKey)
+	})
+
+	t.Run("passthrough enabled", func(t *testing.T) {
+		ctx := WithGrafanaConfig(context.Background(), GrafanaConfig{OAuth: &OAuthConfig{}})
+		ctx = ExtractGrafanaInfoFromHeaders(ctx, req)
+		assert.Equal(t, "user-token", GrafanaConfigFromContext(ctx).APIKey)
+	})
+
+	t.Run("API key header takes precedence", func(t *testing.T) {
+		req := req.Clone(context.Background())
+		req.Header.Set(grafanaAPIKeyHeader, "api-key")
+		ctx := WithGrafanaConfig(context.Background(), GrafanaConfig{OAuth: &OAuthConfig{}})
+		ctx = ExtractGrafanaInfoFromHeaders(ctx, req)
+		assert.Equal(t, "api-key", GrafanaConfigFromContext(ctx).APIKey)
+	})
+}
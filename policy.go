@@ -0,0 +1,256 @@
+package mcpgrafana
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"gopkg.in/yaml.v3"
+)
+
+// Policy maps the identities of callers to the tools they may call and
+// their rate limits. It is loaded from a YAML file with LoadPolicy.
+type Policy struct {
+	// Rules are matched against the caller in order, and the first matching
+	// rule applies.
+	Rules []PolicyRule `yaml:"rules"`
+	// Default applies to callers matching no rule. If it is not set, such
+	// callers cannot call any tool.
+	Default *PolicyRule `yaml:"default"`
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// PolicyRule is the part of a policy applying to some callers.
+type PolicyRule struct {
+	// Name identifies the rule in errors.
+	Name string `yaml:"name"`
+
+	// APIKeyHash and Subject identify the callers the rule applies to: the
+	// hash of their API key or forwarded token, as recorded in the audit
+	// log, and the subject of their JWT, e.g. a forwarded OAuth token or ID
+	// token. A caller must match all of those set.
+	APIKeyHash string `yaml:"apiKeyHash"`
+	Subject    string `yaml:"subject"`
+
+	// Tools and Categories are the tools the callers may call, by name, with
+	// * as a wildcard, and by category. DeniedTools are excluded from them.
+	Tools       []string `yaml:"tools"`
+	Categories  []string `yaml:"categories"`
+	DeniedTools []string `yaml:"deniedTools"`
+
+	// RateLimit is the maximum number of tool calls per second of each
+	// caller, and RateLimitBurst the number of calls allowed in a burst
+	// above it (defaults to the rate). Zero disables the limit.
+	RateLimit      float64 `yaml:"rateLimit"`
+	RateLimitBurst int     `yaml:"rateLimitBurst"`
+}
+
+// LoadPolicy reads and validates a policy file.
+func LoadPolicy(file string) (*Policy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read policy file: %w", err)
+	}
+	return ParsePolicy(data)
+}
+
+// ParsePolicy parses and validates a policy in YAML or JSON.
+func ParsePolicy(data []byte) (*Policy, error) {
+	p := &Policy{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parse policy: %w", err)
+	}
+	names := map[string]bool{}
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if r.Name == "" {
+			return nil, fmt.Errorf("policy rule %d has no name", i+1)
+		}
+		if names[r.Name] || r.Name == "default" {
+			return nil, fmt.Errorf("policy rule name %q is used more than once", r.Name)
+		}
+		names[r.Name] = true
+		if r.APIKeyHash == "" && r.Subject == "" {
+			return nil, fmt.Errorf("policy rule %q must set apiKeyHash or subject", r.Name)
+		}
+		if err := r.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if p.Default != nil {
+		if p.Default.APIKeyHash != "" || p.Default.Subject != "" {
+			return nil, errors.New("the default policy rule applies to every other caller and cannot set apiKeyHash or subject")
+		}
+		p.Default.Name = "default"
+		if err := p.Default.validate(); err != nil {
+			return nil, err
+		}
+	}
+	p.buckets = map[string]*tokenBucket{}
+	p.now = time.Now
+	return p, nil
+}
+
+func (r *PolicyRule) validate() error {
+	for _, pattern := range slices.Concat(r.Tools, r.DeniedTools) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("policy rule %q has an invalid tool pattern %q", r.Name, pattern)
+		}
+	}
+	if r.RateLimit < 0 || r.RateLimitBurst < 0 {
+		return fmt.Errorf("policy rule %q has a negative rate limit", r.Name)
+	}
+	return nil
+}
+
+// policyCaller identifies the caller of a tool.
+type policyCaller struct {
+	apiKeyHash string
+	subject    string
+}
+
+func policyCallerFromContext(ctx context.Context) policyCaller {
+	cfg := GrafanaConfigFromContext(ctx)
+	var c policyCaller
+	if cfg.APIKey != "" {
+		c.apiKeyHash = hashSecret(cfg.APIKey)
+		c.subject = jwtSubject(cfg.APIKey)
+	}
+	if c.subject == "" && cfg.IDToken != "" {
+		c.subject = jwtSubject(cfg.IDToken)
+	}
+	return c
+}
+
+// key identifies the caller for its rate limit.
+func (c policyCaller) key() string {
+	if c.subject != "" {
+		return "sub:" + c.subject
+	}
+	return c.apiKeyHash
+}
+
+// rule returns the rule applying to a caller, or nil if there is none.
+func (p *Policy) rule(c policyCaller) *PolicyRule {
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if (r.APIKeyHash == "" || r.APIKeyHash == c.apiKeyHash) && (r.Subject == "" || r.Subject == c.subject) {
+			return r
+		}
+	}
+	return p.Default
+}
+
+// allows returns true if the rule allows calling the named tool. A
+// deprecated alias is allowed like the tool it aliases, and denied if either
+// name is denied.
+func (r *PolicyRule) allows(tool string) bool {
+	names := []string{tool}
+	if info, ok := LookupTool(tool); ok && info.AliasOf != "" {
+		tool = info.AliasOf
+		names = append(names, tool)
+	}
+	matches := func(patterns []string) bool {
+		return slices.ContainsFunc(patterns, func(pattern string) bool {
+			return slices.ContainsFunc(names, func(name string) bool {
+				ok, _ := path.Match(pattern, name)
+				return ok
+			})
+		})
+	}
+	if matches(r.DeniedTools) {
+		return false
+	}
+	if matches(r.Tools) {
+		return true
+	}
+	category, ok := ToolCategory(tool)
+	return ok && slices.Contains(r.Categories, category)
+}
+
+// Allowed returns nil if the caller of the current request may call the
+// named tool, and an error saying why not otherwise.
+func (p *Policy) Allowed(ctx context.Context, tool string) error {
+	c := policyCallerFromContext(ctx)
+	r := p.rule(c)
+	if r == nil {
+		return errors.New("denied by the tool policy: the caller matches no policy rule")
+	}
+	if !r.allows(tool) {
+		return fmt.Errorf("denied by the tool policy: rule %q does not allow this tool", r.Name)
+	}
+	if r.RateLimit > 0 {
+		now := p.now()
+		if ok, wait := p.bucket(r, c, now).take(now); !ok {
+			return fmt.Errorf("rate limit of %g tool calls per second of policy rule %q exceeded, retry in %s", r.RateLimit, r.Name, wait.Round(time.Millisecond))
+		}
+	}
+	return nil
+}
+
+// bucket returns the token bucket of a caller under a rule.
+func (p *Policy) bucket(r *PolicyRule, c policyCaller, now time.Time) *tokenBucket {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if now.Sub(p.lastPrune) > sessionLimiterIdleTimeout {
+		for key, b := range p.buckets {
+			b.mu.Lock()
+			idle := now.Sub(b.lastUsed) > sessionLimiterIdleTimeout
+			b.mu.Unlock()
+			if idle {
+				delete(p.buckets, key)
+			}
+		}
+		p.lastPrune = now
+	}
+	key := r.Name + "\x00" + c.key()
+	b, ok := p.buckets[key]
+	if !ok {
+		b = newTokenBucket(r.RateLimit, r.RateLimitBurst, now)
+		p.buckets[key] = b
+	}
+	return b
+}
+
+// PolicyMiddleware returns a tool handler middleware rejecting the tool
+// calls which the policy doesn't allow for their caller.
+func PolicyMiddleware(p *Policy) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			if err := p.Allowed(ctx, request.Params.Name); err != nil {
+				return nil, fmt.Errorf("%s: %w", request.Params.Name, err)
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// PolicyToolFilter returns a filter of listed tools hiding the tools which
+// the policy doesn't allow for the caller.
+func PolicyToolFilter(p *Policy) server.ToolFilterFunc {
+	return func(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+		r := p.rule(policyCallerFromContext(ctx))
+		allowed := []mcp.Tool{}
+		for _, t := range tools {
+			if r != nil && r.allows(t.Name) {
+				allowed = append(allowed, t)
+			}
+		}
+		return allowed
+	}
+}
@@ -0,0 +1,121 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePolicy(t *testing.T) {
+	_, err := ParsePolicy([]byte("rules:\n  - name: bot\n    tools: ['*']\n"))
+	assert.ErrorContains(t, err, "must set apiKeyHash or subject")
+
+	_, err = ParsePolicy([]byte("rules:\n  - name: bot\n    subject: bot\n    tool: ['*']\n"))
+	assert.ErrorContains(t, err, "field tool not found")
+
+	_, err = ParsePolicy([]byte("default:\n  subject: bot\n"))
+	assert.Error(t, err)
+
+	p, err := ParsePolicy(nil)
+	require.NoError(t, err)
+	assert.Error(t, p.Allowed(context.Background(), "any_tool"), "callers matching no rule are denied without a default rule")
+}
+
+func TestPolicyAllowed(t *testing.T) {
+	s := server.NewMCPServer("test", "0.0.0")
+	read := MustTool("test_policy_read", "A tool", emptyToolHandler)
+	other := MustTool("test_policy_other", "A tool", emptyToolHandler)
+	write := MustTool("test_policy_write", "A tool", emptyToolHandler)
+	RegisterCategory(s, "test-policy-read", true, func(s *server.MCPServer) {
+		read.Register(s)
+		other.Register(s)
+	})
+	RegisterCategory(s, "test-policy-write", true, func(s *server.MCPServer) {
+		write.Register(s)
+	})
+
+	p, err := ParsePolicy([]byte(`
+rules:
+  - name: ci
+    apiKeyHash: ` + hashSecret("glsa_ci") + `
+    tools: ["test_policy_write"]
+    rateLimit: 1
+  - name: alice
+    subject: alice
+    categories: [test-policy-read, test-policy-write]
+    deniedTools: ["*_other"]
+default:
+  categories: [test-policy-read]
+`))
+	require.NoError(t, err)
+	now := time.Unix(0, 0)
+	p.now = func() time.Time { return now }
+	ctxWithKey := func(key string) context.Context {
+		return WithGrafanaConfig(context.Background(), GrafanaConfig{APIKey: key})
+	}
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"alice"}`))
+	alice := ctxWithKey("header." + payload + ".signature")
+	ci := ctxWithKey("glsa_ci")
+	unknown := ctxWithKey("glsa_other")
+
+	assert.NoError(t, p.Allowed(alice, "test_policy_read"))
+	assert.NoError(t, p.Allowed(alice, "test_policy_write"))
+	assert.ErrorContains(t, p.Allowed(alice, "test_policy_other"), `rule "alice" does not allow`)
+
+	assert.NoError(t, p.Allowed(ci, "test_policy_write"))
+	assert.ErrorContains(t, p.Allowed(ci, "test_policy_write"), "rate limit")
+	assert.Error(t, p.Allowed(ci, "test_policy_read"))
+	now = now.Add(time.Second)
+	assert.NoError(t, p.Allowed(ci, "test_policy_write"))
+
+	assert.NoError(t, p.Allowed(unknown, "test_policy_other"))
+	assert.ErrorContains(t, p.Allowed(unknown, "test_policy_write"), `rule "default" does not allow`)
+
+	tools := PolicyToolFilter(p)(alice, []mcp.Tool{{Name: "test_policy_read"}, {Name: "test_policy_other"}, {Name: "test_policy_write"}})
+	assert.Equal(t, []mcp.Tool{{Name: "test_policy_read"}, {Name: "test_policy_write"}}, tools)
+
+	next := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	}
+	_, err = PolicyMiddleware(p)(next)(unknown, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: "test_policy_write"}})
+	assert.ErrorContains(t, err, "test_policy_write: denied by the tool policy")
+}
+
+func TestPolicyAllowedAlias(t *testing.T) {
+	SetToolAliases(map[string]string{"test_policy_old_update": "test_policy_update"})
+	t.Cleanup(func() { SetToolAliases(nil) })
+	s := server.NewMCPServer("test", "0.0.0")
+	update := MustTool("test_policy_update", "A tool", emptyToolHandler)
+	get := MustTool("test_policy_get", "A tool", emptyToolHandler)
+	RegisterCategory(s, "test-policy-alias", true, func(s *server.MCPServer) {
+		update.Register(s)
+		get.Register(s)
+	})
+
+	p, err := ParsePolicy([]byte(`
+rules:
+  - name: bot
+    subject: bot
+    tools: ["test_policy_update"]
+default:
+  categories: [test-policy-alias]
+  deniedTools: ["test_policy_update"]
+`))
+	require.NoError(t, err)
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"bot"}`))
+	bot := WithGrafanaConfig(context.Background(), GrafanaConfig{APIKey: "header." + payload + ".signature"})
+
+	assert.Error(t, p.Allowed(context.Background(), "test_policy_update"))
+	assert.ErrorContains(t, p.Allowed(context.Background(), "test_policy_old_update"), "does not allow", "the alias of a denied tool is denied")
+	assert.NoError(t, p.Allowed(context.Background(), "test_policy_get"))
+	assert.NoError(t, p.Allowed(bot, "test_policy_old_update"), "the alias of an allowed tool is allowed")
+}
@@ -0,0 +1,58 @@
+package mcpgrafana
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// policyNotesPrefix introduces the notes on the server's policy appended to
+// the descriptions of tools.
+const policyNotesPrefix = "\n\nServer policy: "
+
+// policyNotes returns notes on how the configuration of the server changes
+// the behavior of a tool of the given category, such as disabled write
+// tools, truncated results and timeouts, so that models can account for
+// them when planning tool calls.
+func policyNotes(tool mcp.Tool, category string) []string {
+	readOnly := tool.Annotations.ReadOnlyHint != nil && *tool.Annotations.ReadOnlyHint
+	idempotent := tool.Annotations.IdempotentHint != nil && *tool.Annotations.IdempotentHint
+	limits := Limits()
+	var notes []string
+	if write, ok := CategoryFeature(category, FeatureWrite); ok && !write && readOnly {
+		notes = append(notes, fmt.Sprintf("Tools which create, modify or delete %s resources are disabled on this server, which is read-only for them.", category))
+	}
+	if maxBytes, ok := limits["maxOutputBytes"].(int); ok && maxBytes > 0 {
+		notes = append(notes, fmt.Sprintf("Results larger than %d bytes are truncated, so prefer narrow requests.", maxBytes))
+	}
+	if timeouts, ok := limits["toolTimeoutSeconds"].(map[string]float64); ok {
+		seconds, ok := timeouts[category]
+		if !ok {
+			seconds = timeouts["default"]
+		}
+		if seconds > 0 {
+			notes = append(notes, fmt.Sprintf("Calls time out after %s.", time.Duration(seconds*float64(time.Second))))
+		}
+	}
+	if ttl, ok := limits["cacheTTLSeconds"].(float64); ok && ttl > 0 && readOnly && idempotent {
+		notes = append(notes, fmt.Sprintf("Results may be cached for up to %s, so they can be that old.", time.Duration(ttl*float64(time.Second))))
+	}
+	return notes
+}
+
+// withPolicyNotes returns a copy of the tool whose description ends with
+// notes on the server's policy, if any apply to it. It is applied when the
+// tool is registered, after the limits have been recorded.
+func withPolicyNotes(tool mcp.Tool) mcp.Tool {
+	registry.mu.RLock()
+	category := registry.current
+	registry.mu.RUnlock()
+	notes := policyNotes(tool, category)
+	if len(notes) == 0 {
+		return tool
+	}
+	tool.Description = strings.TrimRight(tool.Description, " ") + policyNotesPrefix + strings.Join(notes, " ")
+	return tool
+}
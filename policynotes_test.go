@@ -0,0 +1,95 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listToolDescriptions returns the descriptions of the tools of a server by
+// name, as listed to clients.
+func listToolDescriptions(t *testing.T, s *server.MCPServer) map[string]string {
+	response := s.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	resp, ok := response.(mcp.JSONRPCResponse)
+	require.True(t, ok, "unexpected response %#v", response)
+	result, ok := resp.Result.(mcp.ListToolsResult)
+	require.True(t, ok)
+	descriptions := map[string]string{}
+	for _, tool := range result.Tools {
+		descriptions[tool.Name] = tool.Description
+	}
+	return descriptions
+}
+
+func TestPolicyNotes(t *testing.T) {
+	// Other tests record limits as well, so the test starts without any.
+	registry.mu.Lock()
+	limits := registry.limits
+	registry.limits = map[string]any{}
+	registry.mu.Unlock()
+	t.Cleanup(func() {
+		registry.mu.Lock()
+		registry.limits = limits
+		registry.mu.Unlock()
+	})
+
+	read := MustTool("test_policy_read", "Reads things.", emptyToolHandler, mcp.WithReadOnlyHintAnnotation(true), mcp.WithIdempotentHintAnnotation(true))
+	write := MustTool("test_policy_write", "Writes things.", emptyToolHandler, mcp.WithDestructiveHintAnnotation(true))
+
+	t.Run("no policy", func(t *testing.T) {
+		s := server.NewMCPServer("test", "0.0.0")
+		RegisterCategory(s, "test-policy-none", true, func(s *server.MCPServer) {
+			read.Register(s)
+		})
+		assert.Equal(t, "Reads things.", listToolDescriptions(t, s)["test_policy_read"])
+	})
+
+	t.Run("limits and read-only category", func(t *testing.T) {
+		RecordLimit("maxOutputBytes", 4096)
+		RecordLimit("toolTimeoutSeconds", map[string]float64{"default": 120, "test-policy": 30})
+		RecordLimit("cacheTTLSeconds", 15.0)
+		SetCategoryFeature("test-policy", FeatureWrite, false)
+
+		s := server.NewMCPServer("test", "0.0.0")
+		RegisterCategory(s, "test-policy", true, func(s *server.MCPServer) {
+			read.Register(s)
+		})
+		RegisterCategory(s, "test-policy-writable", true, func(s *server.MCPServer) {
+			write.Register(s)
+		})
+		descriptions := listToolDescriptions(t, s)
+		assert.Equal(t, "Reads things.\n\nServer policy: "+
+			"Tools which create, modify or delete test-policy resources are disabled on this server, which is read-only for them. "+
+			"Results larger than 4096 bytes are truncated, so prefer narrow requests. "+
+			"Calls time out after 30s. "+
+			"Results may be cached for up to 15s, so they can be that old.", descriptions["test_policy_read"])
+		assert.Equal(t, "Writes things.\n\nServer policy: "+
+			"Results larger than 4096 bytes are truncated, so prefer narrow requests. "+
+			"Calls time out after "+(2*time.Minute).String()+".", descriptions["test_policy_write"])
+
+		// The tools themselves are unchanged, so that they can be registered
+		// with other servers.
+		assert.Equal(t, "Reads things.", read.Tool.Description)
+
+		var category *CategoryInfo
+		for _, c := range Categories() {
+			if c.Name == "test-policy" {
+				category = &c
+			}
+		}
+		require.NotNil(t, category)
+		assert.Equal(t, map[string]bool{FeatureWrite: false}, category.Features)
+		enabled, ok := CategoryFeature("test-policy", FeatureWrite)
+		assert.True(t, ok)
+		assert.False(t, enabled)
+	})
+}
@@ -0,0 +1,116 @@
+package mcpgrafana
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sync"
+)
+
+// queryParamPattern matches the names of tool parameters and log attributes
+// holding query expressions, such as PromQL and LogQL queries, which are
+// redacted by the query redactor.
+var queryParamPattern = regexp.MustCompile(`(?i)(expr|query|queries|logql|promql|traceql|sql|target|selector|matche[rs])`)
+
+// QueryRedactor redacts sensitive identifiers, such as customer IDs, from a
+// query expression before it is written to the audit log or to logs.
+type QueryRedactor func(query string) string
+
+var (
+	queryRedactorMu sync.RWMutex
+	queryRedactor   QueryRedactor
+)
+
+// SetQueryRedactor sets the hook redacting query expressions before they
+// are written to the audit log and to logs. The queries themselves are run
+// unchanged. A nil redactor disables the redaction.
+func SetQueryRedactor(r QueryRedactor) {
+	queryRedactorMu.Lock()
+	defer queryRedactorMu.Unlock()
+	queryRedactor = r
+}
+
+// RedactQuery returns the query redacted by the hook set with
+// SetQueryRedactor, or the query itself if there is none.
+func RedactQuery(query string) string {
+	queryRedactorMu.RLock()
+	r := queryRedactor
+	queryRedactorMu.RUnlock()
+	if r == nil {
+		return query
+	}
+	return r(query)
+}
+
+// queryRedactionEnabled returns true if a query redactor is set.
+func queryRedactionEnabled() bool {
+	queryRedactorMu.RLock()
+	defer queryRedactorMu.RUnlock()
+	return queryRedactor != nil
+}
+
+// QueryRedactionConfig configures the query redactor created by
+// NewQueryRedactor.
+type QueryRedactionConfig struct {
+	// Labels are the names of labels whose values in label matchers, such
+	// as `customer_id="42"`, are redacted. They are matched case
+	// insensitively.
+	Labels []string
+	// Patterns are regular expressions whose matches are redacted, e.g.
+	// `\b[0-9]{16}\b` for card numbers embedded in line filters.
+	Patterns []string
+	// Mode is RedactionModeHash or RedactionModeMask. It defaults to
+	// RedactionModeHash.
+	Mode string
+}
+
+// Enabled returns true if anything is redacted from queries.
+func (c QueryRedactionConfig) Enabled() bool {
+	return len(c.Labels) > 0 || len(c.Patterns) > 0
+}
+
+// NewQueryRedactor returns a QueryRedactor redacting the values of the
+// configured labels and the matches of the configured patterns. Like the
+// redaction of tool results, values are replaced by a keyed hash, stable
+// while the server runs, or by a fixed marker.
+func NewQueryRedactor(config QueryRedactionConfig) (QueryRedactor, error) {
+	if err := (RedactionConfig{Mode: config.Mode}).Validate(); err != nil {
+		return nil, err
+	}
+	r := newRedactor(RedactionConfig{Labels: config.Labels, Mode: config.Mode})
+	patterns := make([]*regexp.Regexp, 0, len(config.Patterns))
+	for _, p := range config.Patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query redaction pattern %q: %w", p, err)
+		}
+		patterns = append(patterns, re)
+	}
+	return func(query string) string {
+		if len(r.labels) > 0 {
+			query = r.redactString(query)
+		}
+		for _, re := range patterns {
+			query = re.ReplaceAllStringFunc(query, r.redactValue)
+		}
+		return query
+	}, nil
+}
+
+// redactQueryAttr redacts the value of a log attribute holding a query.
+func redactQueryAttr(a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindString:
+		if queryParamPattern.MatchString(a.Key) {
+			return slog.String(a.Key, RedactQuery(a.Value.String()))
+		}
+	case slog.KindGroup:
+		attrs := a.Value.Group()
+		redacted := make([]any, len(attrs))
+		for i, child := range attrs {
+			redacted[i] = redactQueryAttr(child)
+		}
+		return slog.Group(a.Key, redacted...)
+	}
+	return a
+}
@@ -0,0 +1,104 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setQueryRedactor(t *testing.T, config QueryRedactionConfig) {
+	t.Helper()
+	redactor, err := NewQueryRedactor(config)
+	require.NoError(t, err)
+	SetQueryRedactor(redactor)
+	t.Cleanup(func() { SetQueryRedactor(nil) })
+}
+
+func TestNewQueryRedactor(t *testing.T) {
+	redactor, err := NewQueryRedactor(QueryRedactionConfig{
+		Labels:   []string{"customer_id"},
+		Patterns: []string{`acct-[0-9]+`},
+		Mode:     RedactionModeMask,
+	})
+	require.NoError(t, err)
+	assert.Equal(t,
+		`sum(rate(http_requests_total{customer_id="`+redactedLabelValue+`", job="api"}[5m]))`,
+		redactor(`sum(rate(http_requests_total{customer_id="42", job="api"}[5m]))`))
+	assert.Equal(t,
+		`{app="billing"} |= "`+redactedLabelValue+`"`,
+		redactor(`{app="billing"} |= "acct-1234"`))
+
+	t.Run("hash", func(t *testing.T) {
+		redactor, err := NewQueryRedactor(QueryRedactionConfig{Labels: []string{"customer_id"}})
+		require.NoError(t, err)
+		redacted := redactor(`up{customer_id="42"}`)
+		assert.NotContains(t, redacted, `"42"`)
+		assert.Contains(t, redacted, `customer_id="redacted:`)
+		// Hashes are stable, so that queries can be correlated.
+		assert.Equal(t, redacted, redactor(`up{customer_id="42"}`))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := NewQueryRedactor(QueryRedactionConfig{Patterns: []string{"("}})
+		assert.ErrorContains(t, err, "invalid query redaction pattern")
+		_, err = NewQueryRedactor(QueryRedactionConfig{Labels: []string{"a"}, Mode: "scramble"})
+		assert.Error(t, err)
+	})
+}
+
+func TestAuditMiddlewareRedactsQueries(t *testing.T) {
+	setQueryRedactor(t, QueryRedactionConfig{Labels: []string{"customer_id"}, Mode: RedactionModeMask})
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "test_audit"
+	request.Params.Arguments = map[string]any{
+		"expr":    `up{customer_id="42"}`,
+		"matches": []any{`{customer_id="42"}`},
+		"uid":     `customer_id="42"`,
+	}
+	handler := AuditMiddleware(logger)(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return nil, errors.New(`bad query up{customer_id="42"}`)
+	})
+	_, err := handler(context.Background(), request)
+	require.Error(t, err)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, map[string]any{
+		"expr":    `up{customer_id="` + redactedLabelValue + `"}`,
+		"matches": []any{`{customer_id="` + redactedLabelValue + `"}`},
+		// Only query parameters are redacted.
+		"uid": `customer_id="42"`,
+	}, record["params"])
+	assert.Equal(t, `bad query up{customer_id="`+redactedLabelValue+`"}`, record["error"])
+	// The tool is called with the query unchanged.
+	assert.Equal(t, `up{customer_id="42"}`, request.Params.Arguments.(map[string]any)["expr"])
+}
+
+func TestLogHandlerRedactsQueries(t *testing.T) {
+	setQueryRedactor(t, QueryRedactionConfig{Patterns: []string{`acct-[0-9]+`}, Mode: RedactionModeMask})
+
+	var buf bytes.Buffer
+	handler, err := NewLogHandler(&buf, LogFormatJSON, slog.LevelInfo)
+	require.NoError(t, err)
+	logger := slog.New(handler).With("logql", `{app="billing"} |= "acct-1"`)
+	logger.Info("query failed", "query", `{app="billing"} |= "acct-2"`, "account", "acct-3")
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, `{app="billing"} |= "`+redactedLabelValue+`"`, record["logql"])
+	assert.Equal(t, `{app="billing"} |= "`+redactedLabelValue+`"`, record["query"])
+	assert.Equal(t, "acct-3", record["account"])
+}
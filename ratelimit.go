@@ -0,0 +1,272 @@
+package mcpgrafana
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// sessionLimiterIdleTimeout is how long a per-session limiter is kept after
+// its last use before being discarded.
+const sessionLimiterIdleTimeout = 30 * time.Minute
+
+// RateLimitConfig configures the rate limits and concurrency guard applied
+// to tool calls. A zero value for any field disables that limit.
+type RateLimitConfig struct {
+	// GlobalRate is the maximum number of tool calls per second across all
+	// sessions.
+	GlobalRate float64
+	// GlobalBurst is the number of tool calls allowed in a burst above
+	// GlobalRate. Defaults to the rate rounded up if not set.
+	GlobalBurst int
+
+	// SessionRate is the maximum number of tool calls per second for a
+	// single client session. With the stateless streamable HTTP transport,
+	// it applies to each caller, identified by its credentials.
+	SessionRate float64
+	// SessionBurst is the number of tool calls allowed in a burst above
+	// SessionRate for a single session. Defaults to the rate rounded up if
+	// not set.
+	SessionBurst int
+
+	// MaxConcurrent is the maximum number of tool calls that may execute at
+	// the same time. Further calls wait until a slot is free or their
+	// context is cancelled.
+	MaxConcurrent int
+}
+
+// Enabled returns true if any limit is configured.
+func (c RateLimitConfig) Enabled() bool {
+	return c.GlobalRate > 0 || c.SessionRate > 0 || c.MaxConcurrent > 0
+}
+
+// tokenBucket is a simple token bucket rate limiter.
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     float64
+	burst    float64
+	tokens   float64
+	last     time.Time
+	lastUsed time.Time
+}
+
+func newTokenBucket(rate float64, burst int, now time.Time) *tokenBucket {
+	b := float64(burst)
+	if b <= 0 {
+		b = math.Max(1, math.Ceil(rate))
+	}
+	return &tokenBucket{
+		rate:     rate,
+		burst:    b,
+		tokens:   b,
+		last:     now,
+		lastUsed: now,
+	}
+}
+
+// refill adds the tokens accumulated since the last refill. b.mu must be
+// held.
+func (b *tokenBucket) refill(now time.Time) {
+	b.lastUsed = now
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed.Seconds()*b.rate)
+		b.last = now
+	}
+}
+
+// wait returns the time until the next token is available. b.mu must be
+// held.
+func (b *tokenBucket) wait() time.Duration {
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// take attempts to take a token from the bucket. If no token is available it
+// returns false along with the time until the next token will be available.
+func (b *tokenBucket) take(now time.Time) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, b.wait()
+}
+
+// takeAll takes a token from each bucket only if all of them have one, so
+// that a call rejected by one limit doesn't spend the budget of another. If
+// a bucket has no token, it returns its index along with the time until it
+// has one, and -1 otherwise. Callers must pass buckets in a consistent
+// order.
+func takeAll(now time.Time, buckets ...*tokenBucket) (int, time.Duration) {
+	for _, b := range buckets {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+	}
+	for i, b := range buckets {
+		b.refill(now)
+		if b.tokens < 1 {
+			return i, b.wait()
+		}
+	}
+	for _, b := range buckets {
+		b.tokens--
+	}
+	return -1, 0
+}
+
+// rateLimiter enforces a RateLimitConfig.
+type rateLimiter struct {
+	config RateLimitConfig
+	now    func() time.Time
+
+	global *tokenBucket
+
+	mu        sync.Mutex
+	sessions  map[string]*tokenBucket
+	lastPrune time.Time
+
+	slots chan struct{}
+}
+
+func newRateLimiter(config RateLimitConfig) *rateLimiter {
+	rl := &rateLimiter{
+		config:   config,
+		now:      time.Now,
+		sessions: map[string]*tokenBucket{},
+	}
+	if config.GlobalRate > 0 {
+		rl.global = newTokenBucket(config.GlobalRate, config.GlobalBurst, rl.now())
+	}
+	if config.MaxConcurrent > 0 {
+		rl.slots = make(chan struct{}, config.MaxConcurrent)
+	}
+	return rl
+}
+
+// sessionBucket returns the token bucket for the given session, creating it
+// if necessary and discarding buckets for sessions that have gone idle.
+func (rl *rateLimiter) sessionBucket(sessionKey string, now time.Time) *tokenBucket {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if now.Sub(rl.lastPrune) > sessionLimiterIdleTimeout {
+		for key, b := range rl.sessions {
+			b.mu.Lock()
+			idle := now.Sub(b.lastUsed) > sessionLimiterIdleTimeout
+			b.mu.Unlock()
+			if idle {
+				delete(rl.sessions, key)
+			}
+		}
+		rl.lastPrune = now
+	}
+	b, ok := rl.sessions[sessionKey]
+	if !ok {
+		b = newTokenBucket(rl.config.SessionRate, rl.config.SessionBurst, now)
+		rl.sessions[sessionKey] = b
+	}
+	return b
+}
+
+// allow checks the global and per-session rate limits for a tool call,
+// taking a token from each only if both allow it.
+func (rl *rateLimiter) allow(sessionKey string) error {
+	now := rl.now()
+	var session *tokenBucket
+	if rl.config.SessionRate > 0 {
+		session = rl.sessionBucket(sessionKey, now)
+	}
+	switch {
+	case session != nil && rl.global != nil:
+		switch i, wait := takeAll(now, session, rl.global); i {
+		case 0:
+			return sessionRateLimitError(rl.config.SessionRate, wait)
+		case 1:
+			return globalRateLimitError(rl.config.GlobalRate, wait)
+		}
+	case session != nil:
+		if ok, wait := session.take(now); !ok {
+			return sessionRateLimitError(rl.config.SessionRate, wait)
+		}
+	case rl.global != nil:
+		if ok, wait := rl.global.take(now); !ok {
+			return globalRateLimitError(rl.config.GlobalRate, wait)
+		}
+	}
+	return nil
+}
+
+func sessionRateLimitError(rate float64, wait time.Duration) error {
+	return fmt.Errorf("session rate limit of %g tool calls per second exceeded, retry in %s", rate, wait.Round(time.Millisecond))
+}
+
+func globalRateLimitError(rate float64, wait time.Duration) error {
+	return fmt.Errorf("server rate limit of %g tool calls per second exceeded, retry in %s", rate, wait.Round(time.Millisecond))
+}
+
+// acquire waits for a concurrency slot, returning a function that releases it.
+func (rl *rateLimiter) acquire(ctx context.Context) (func(), error) {
+	if rl.slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case rl.slots <- struct{}{}:
+		return func() { <-rl.slots }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for a free tool call slot (max %d concurrent calls): %w", rl.config.MaxConcurrent, ctx.Err())
+	}
+}
+
+// sessionIDFromContext returns the ID of the client session making the
+// current request, or an empty string if there is none.
+func sessionIDFromContext(ctx context.Context) string {
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return session.SessionID()
+	}
+	return ""
+}
+
+// rateLimitSessionKey identifies the client whose per-session rate limit
+// applies to the current request: its session, or its credentials with the
+// stateless streamable HTTP transport, whose requests carry no session ID.
+func rateLimitSessionKey(ctx context.Context) string {
+	if id := sessionIDFromContext(ctx); id != "" {
+		return "session:" + id
+	}
+	return "caller:" + policyCallerFromContext(ctx).key()
+}
+
+// RateLimitMiddleware returns a tool handler middleware enforcing the given
+// rate limits and concurrency guard. Calls exceeding a rate limit fail
+// immediately with an error telling the client when to retry; calls beyond
+// the concurrency limit wait for a free slot.
+func RateLimitMiddleware(config RateLimitConfig) server.ToolHandlerMiddleware {
+	if config.GlobalRate > 0 {
+		RecordLimit("globalRateLimit", config.GlobalRate)
+	}
+	if config.SessionRate > 0 {
+		RecordLimit("sessionRateLimit", config.SessionRate)
+	}
+	if config.MaxConcurrent > 0 {
+		RecordLimit("maxConcurrentToolCalls", config.MaxConcurrent)
+	}
+	rl := newRateLimiter(config)
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			if err := rl.allow(rateLimitSessionKey(ctx)); err != nil {
+				return nil, fmt.Errorf("%s: %w", request.Params.Name, err)
+			}
+			release, err := rl.acquire(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", request.Params.Name, err)
+			}
+			defer release()
+			return next(ctx, request)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	b := newTokenBucket(2, 2, now)
+
+	ok, _ := b.take(now)
+	assert.True(t, ok)
+	ok, _ = b.take(now)
+	assert.True(t, ok)
+	ok, wait := b.take(now)
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	// After half a second one more token is available.
+	ok, _ = b.take(now.Add(500 * time.Millisecond))
+	assert.True(t, ok)
+}
+
+func TestRateLimiterAllow(t *testing.T) {
+	now := time.Now()
+	rl := newRateLimiter(RateLimitConfig{SessionRate: 1})
+	rl.now = func() time.Time { return now }
+
+	require.NoError(t, rl.allow("a"))
+	err := rl.allow("a")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "session rate limit")
+
+	// Other sessions have their own budget.
+	require.NoError(t, rl.allow("b"))
+
+	rl = newRateLimiter(RateLimitConfig{GlobalRate: 1})
+	rl.now = func() time.Time { return now }
+	require.NoError(t, rl.allow("a"))
+	err = rl.allow("b")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "server rate limit")
+
+	// Calls rejected by the server limit don't spend the session's budget.
+	rl = newRateLimiter(RateLimitConfig{GlobalRate: 1, SessionRate: 1, SessionBurst: 2})
+	rl.now = func() time.Time { return now }
+	require.NoError(t, rl.allow("a"))
+	assert.ErrorContains(t, rl.allow("a"), "server rate limit")
+	assert.ErrorContains(t, rl.allow("a"), "server rate limit")
+	assert.Equal(t, 1.0, rl.sessions["a"].tokens, "the session still has a token")
+}
+
+func TestRateLimitSessionKey(t *testing.T) {
+	alice := WithGrafanaConfig(context.Background(), GrafanaConfig{APIKey: "glsa_alice"})
+	bob := WithGrafanaConfig(context.Background(), GrafanaConfig{APIKey: "glsa_bob"})
+	assert.NotEqual(t, rateLimitSessionKey(alice), rateLimitSessionKey(bob), "callers without a session are told apart by their credentials")
+	assert.Equal(t, rateLimitSessionKey(alice), rateLimitSessionKey(WithGrafanaConfig(context.Background(), GrafanaConfig{APIKey: "glsa_alice"})))
+	assert.NotContains(t, rateLimitSessionKey(alice), "glsa_alice")
+}
+
+func TestRateLimitMiddlewareConcurrency(t *testing.T) {
+	mw := RateLimitMiddleware(RateLimitConfig{MaxConcurrent: 1})
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	handler := mw(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		started <- struct{}{}
+		<-unblock
+		return mcp.NewToolResultText("ok"), nil
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := handler(context.Background(), mcp.CallToolRequest{})
+		assert.NoError(t, err)
+	}()
+	<-started
+
+	// A second call cannot start while the first is running.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := handler(ctx, mcp.CallToolRequest{})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(unblock)
+	wg.Wait()
+}
@@ -0,0 +1,277 @@
+package mcpgrafana
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// RedactionModeHash replaces sensitive label values with a keyed hash,
+	// so that equal values can still be correlated across results.
+	RedactionModeHash = "hash"
+	// RedactionModeMask replaces sensitive label values with a fixed marker.
+	RedactionModeMask = "mask"
+)
+
+// redactedLabelValue replaces sensitive label values in masked results.
+const redactedLabelValue = "[REDACTED]"
+
+// labelNameParams are the parameters naming the label whose values a tool
+// returns, by the name of the tool.
+var labelNameParams = map[string]string{
+	"grafana_list_prometheus_label_values": "labelName",
+	"grafana_list_loki_label_values":       "labelName",
+	"grafana_list_pyroscope_label_values":  "name",
+}
+
+// labelNameParam returns the parameter naming the label whose values the
+// named tool returns, if it is such a tool. Deprecated aliases are resolved
+// to the tool they alias.
+func labelNameParam(tool string) (string, bool) {
+	if info, ok := LookupTool(tool); ok && info.AliasOf != "" {
+		tool = info.AliasOf
+	}
+	param, ok := labelNameParams[tool]
+	return param, ok
+}
+
+// redactedError is an error whose message has been redacted.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// RedactionConfig configures the redaction of the values of sensitive
+// labels, such as `email` or `customer_id`, in tool results.
+type RedactionConfig struct {
+	// Labels are the names of the sensitive labels. They are matched case
+	// insensitively.
+	Labels []string
+	// Mode is RedactionModeHash or RedactionModeMask. It defaults to
+	// RedactionModeHash.
+	Mode string
+}
+
+// Enabled returns true if any labels are redacted.
+func (c RedactionConfig) Enabled() bool {
+	return len(c.Labels) > 0
+}
+
+// Validate checks that the redaction mode is known.
+func (c RedactionConfig) Validate() error {
+	switch c.Mode {
+	case "", RedactionModeHash, RedactionModeMask:
+		return nil
+	}
+	return fmt.Errorf("unknown redaction mode %q: must be %q or %q", c.Mode, RedactionModeHash, RedactionModeMask)
+}
+
+type redactor struct {
+	labels []string
+	mask   bool
+	// key is the key of the hash of redacted values. It is random so that
+	// hashes of guessable values, such as email addresses, can't be reversed
+	// by hashing candidates. Hashes are therefore only stable for the
+	// lifetime of the server.
+	key []byte
+	// selector matches label matchers, such as `email="a@example.com"`, in
+	// queries and series names.
+	selector *regexp.Regexp
+}
+
+func newRedactor(config RedactionConfig) *redactor {
+	r := &redactor{mask: config.Mode == RedactionModeMask, key: make([]byte, 32)}
+	_, _ = rand.Read(r.key)
+	quoted := make([]string, len(config.Labels))
+	for i, label := range config.Labels {
+		r.labels = append(r.labels, strings.ToLower(label))
+		quoted[i] = regexp.QuoteMeta(label)
+	}
+	r.selector = regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") + `)(\s*(?:=~|!~|!=|=)\s*)"((?:[^"\\]|\\.)*)"`)
+	return r
+}
+
+func (r *redactor) sensitive(label string) bool {
+	return slices.Contains(r.labels, strings.ToLower(label))
+}
+
+func (r *redactor) redactValue(v string) string {
+	if r.mask {
+		return redactedLabelValue
+	}
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(v))
+	return "redacted:" + hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// redactString redacts the values of sensitive label matchers within s.
+func (r *redactor) redactString(s string) string {
+	return r.selector.ReplaceAllStringFunc(s, func(m string) string {
+		parts := r.selector.FindStringSubmatch(m)
+		return parts[1] + parts[2] + `"` + r.redactValue(parts[3]) + `"`
+	})
+}
+
+// redactJSON redacts a decoded JSON value in place, returning the redacted
+// value. Values of sensitive keys are redacted, as are the values of
+// name/value pairs such as `{"name": "email", "value": "..."}` and of
+// summaries such as `{"name": "email", "values": [{"value": "..."}]}`. If
+// all is set, all strings are redacted. changed is set if anything was
+// redacted.
+func (r *redactor) redactJSON(v any, all bool, changed *bool) any {
+	switch v := v.(type) {
+	case map[string]any:
+		named := false
+		for _, nameKey := range []string{"name", "key", "label"} {
+			if name, ok := v[nameKey].(string); ok && r.sensitive(name) {
+				named = true
+			}
+		}
+		for k, child := range v {
+			v[k] = r.redactJSON(child, all || r.sensitive(k) || (named && (k == "value" || k == "values")), changed)
+		}
+		return v
+	case []any:
+		for i, child := range v {
+			v[i] = r.redactJSON(child, all, changed)
+		}
+		return v
+	case string:
+		if all {
+			*changed = true
+			return r.redactValue(v)
+		}
+		redacted := r.redactString(v)
+		*changed = *changed || redacted != v
+		return redacted
+	}
+	return v
+}
+
+// mentions returns true if text contains the name of a sensitive label.
+func (r *redactor) mentions(text string) bool {
+	text = strings.ToLower(text)
+	return slices.ContainsFunc(r.labels, func(label string) bool { return strings.Contains(text, label) })
+}
+
+// redactCSV redacts the columns of sensitive labels in CSV results, such as
+// query results in the CSV format, returning false if text is not CSV with a
+// header row naming a sensitive label.
+func (r *redactor) redactCSV(text string) (string, bool) {
+	records, err := csv.NewReader(strings.NewReader(text)).ReadAll()
+	if err != nil || len(records) == 0 || len(records[0]) < 2 {
+		return "", false
+	}
+	var columns []int
+	for i, name := range records[0] {
+		if r.sensitive(name) {
+			columns = append(columns, i)
+		}
+	}
+	if len(columns) == 0 {
+		return "", false
+	}
+	for _, record := range records[1:] {
+		for i, v := range record {
+			if slices.Contains(columns, i) {
+				if v != "" {
+					record[i] = r.redactValue(v)
+				}
+			} else {
+				record[i] = r.redactString(v)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(records); err != nil {
+		return "", false
+	}
+	return buf.String(), true
+}
+
+// redactText redacts a text result, keeping JSON and CSV results valid.
+func (r *redactor) redactText(text string, all bool) string {
+	if !all && !r.mentions(text) {
+		return text
+	}
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		if all {
+			return r.redactValue(text)
+		}
+		if redacted, ok := r.redactCSV(text); ok {
+			return redacted
+		}
+		return r.redactString(text)
+	}
+	changed := false
+	v = r.redactJSON(v, all, &changed)
+	if !changed {
+		return text
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return redactedLabelValue
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// RedactionMiddleware returns a tool handler middleware which redacts the
+// values of the configured sensitive labels in the text content of tool
+// results and in the errors of tool calls. Values are redacted wherever the
+// label appears as a JSON key, as a name/value pair, or in a label matcher
+// such as `email="..."`. The results of tools listing the values of a
+// sensitive label are redacted entirely.
+func RedactionMiddleware(config RedactionConfig) server.ToolHandlerMiddleware {
+	r := newRedactor(config)
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			result, err := next(ctx, request)
+			if err != nil {
+				// Error messages are not label values, but may quote queries.
+				if msg := err.Error(); r.mentions(msg) {
+					if redacted := r.redactString(msg); redacted != msg {
+						return nil, &redactedError{msg: redacted, err: err}
+					}
+				}
+				return nil, err
+			}
+			if result == nil {
+				return nil, nil
+			}
+			all := false
+			if p, ok := labelNameParam(request.Params.Name); ok && !result.IsError {
+				name, _ := request.GetArguments()[p].(string)
+				all = r.sensitive(name)
+			}
+			for i, c := range result.Content {
+				if text, ok := c.(mcp.TextContent); ok {
+					text.Text = r.redactText(text.Text, all)
+					result.Content[i] = text
+				}
+			}
+			return result, nil
+		}
+	}
+}
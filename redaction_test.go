@@ -0,0 +1,107 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactor(t *testing.T) {
+	r := newRedactor(RedactionConfig{Labels: []string{"email", "Customer_ID"}})
+	hashed := r.redactValue("alice@example.com")
+	assert.True(t, strings.HasPrefix(hashed, "redacted:"))
+	assert.Equal(t, hashed, r.redactValue("alice@example.com"), "hashes are stable")
+	assert.NotEqual(t, hashed, r.redactValue("bob@example.com"))
+
+	t.Run("JSON keys", func(t *testing.T) {
+		text := `[{"metric":{"__name__":"logins_total","email":"alice@example.com","customer_id":"42"},"value":[1700000000,"3"]}]`
+		var got []struct {
+			Metric map[string]any `json:"metric"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(r.redactText(text, false)), &got))
+		assert.Equal(t, map[string]any{"__name__": "logins_total", "email": hashed, "customer_id": r.redactValue("42")}, got[0].Metric)
+	})
+
+	t.Run("name/value pairs", func(t *testing.T) {
+		text := `{"labels":[{"name":"email","value":"alice@example.com"},{"name":"job","value":"api"}]}`
+		assert.Equal(t, `{"labels":[{"name":"email","value":"`+hashed+`"},{"name":"job","value":"api"}]}`, r.redactText(text, false))
+	})
+
+	t.Run("label summaries", func(t *testing.T) {
+		text := `{"labels":[{"name":"email","valueCount":2,"values":[{"value":"alice@example.com","series":3}]},{"name":"job","values":[{"value":"api","series":1}]}]}`
+		assert.Equal(t, `{"labels":[{"name":"email","valueCount":2,"values":[{"series":3,"value":"`+hashed+`"}]},{"name":"job","values":[{"series":1,"value":"api"}]}]}`, r.redactText(text, false))
+	})
+
+	t.Run("label matchers", func(t *testing.T) {
+		text := `{"query":"sum(rate(logins_total{job=\"api\", EMAIL=~\"alice@example.com\"}[5m]))"}`
+		assert.Equal(t, `{"query":"sum(rate(logins_total{job=\"api\", EMAIL=~\"`+hashed+`\"}[5m]))"}`, r.redactText(text, false))
+		assert.Equal(t, `logins_total{email="`+hashed+`"} 3`, r.redactText(`logins_total{email="alice@example.com"} 3`, false))
+	})
+
+	t.Run("CSV columns", func(t *testing.T) {
+		text := "timestamp,value,email,job\n2025-01-06T00:00:00Z,3,alice@example.com,api\n2025-01-06T00:01:00Z,4,,api\n"
+		assert.Equal(t, "timestamp,value,email,job\n2025-01-06T00:00:00Z,3,"+hashed+",api\n2025-01-06T00:01:00Z,4,,api\n", r.redactText(text, false))
+	})
+
+	t.Run("unrelated results are unchanged", func(t *testing.T) {
+		text := `{"z": 1, "a": "emailing <b>"}`
+		assert.Equal(t, text, r.redactText(text, false))
+	})
+
+	t.Run("label values", func(t *testing.T) {
+		assert.Equal(t, `["`+hashed+`"]`, r.redactText(`["alice@example.com"]`, true))
+	})
+}
+
+func TestRedactionMiddleware(t *testing.T) {
+	handler := RedactionMiddleware(RedactionConfig{Labels: []string{"email"}, Mode: RedactionModeMask})(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if request.Params.Name == "grafana_query_loki_logs" {
+			return nil, fmt.Errorf(`query {email="alice@example.com"} failed: %w`, context.DeadlineExceeded)
+		}
+		return mcp.NewToolResultText(`["alice@example.com","bob@example.com"]`), nil
+	})
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "grafana_list_prometheus_label_values"
+	request.Params.Arguments = map[string]any{"labelName": "job"}
+	result, err := handler(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, `["alice@example.com","bob@example.com"]`, result.Content[0].(mcp.TextContent).Text)
+
+	request.Params.Arguments = map[string]any{"labelName": "email"}
+	result, err = handler(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, `["[REDACTED]","[REDACTED]"]`, result.Content[0].(mcp.TextContent).Text)
+
+	// Pyroscope names the label with its name parameter.
+	request.Params.Name = "grafana_list_pyroscope_label_values"
+	request.Params.Arguments = map[string]any{"name": "email"}
+	result, err = handler(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, `["[REDACTED]","[REDACTED]"]`, result.Content[0].(mcp.TextContent).Text)
+	request.Params.Arguments = map[string]any{"name": "job", "labelName": "email"}
+	result, err = handler(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, `["alice@example.com","bob@example.com"]`, result.Content[0].(mcp.TextContent).Text)
+
+	// Errors are redacted too, and still wrap the original error.
+	request.Params.Name = "grafana_query_loki_logs"
+	_, err = handler(context.Background(), request)
+	assert.EqualError(t, err, `query {email="[REDACTED]"} failed: context deadline exceeded`)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRedactionConfigValidate(t *testing.T) {
+	assert.NoError(t, RedactionConfig{}.Validate())
+	assert.NoError(t, RedactionConfig{Mode: RedactionModeMask}.Validate())
+	assert.Error(t, RedactionConfig{Mode: "scramble"}.Validate())
+}
@@ -0,0 +1,91 @@
+package mcpgrafana
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+type resultMetaKey struct{}
+
+// resultMeta collects the fields of the _meta of the result of a tool call
+// and the notes added to its content by its handler.
+type resultMeta struct {
+	mu     sync.Mutex
+	fields map[string]any
+	// notes are keyed so that they can be replaced, and noteKeys keeps them
+	// in the order they were first set.
+	notes    map[string]string
+	noteKeys []string
+}
+
+func withResultMeta(ctx context.Context) (context.Context, *resultMeta) {
+	m := &resultMeta{}
+	return context.WithValue(ctx, resultMetaKey{}, m), m
+}
+
+// SetResultMeta sets a field of the _meta of the result of the current tool
+// call, e.g. statistics about the queries it ran, which clients can show or
+// learn from without it taking space in the result itself. It does nothing
+// outside of tools created with ConvertTool or MustTool.
+func SetResultMeta(ctx context.Context, key string, value any) {
+	UpdateResultMeta(ctx, key, func(any) any { return value })
+}
+
+// UpdateResultMeta sets a field of the _meta of the result of the current
+// tool call to the result of update, which is called with its current value,
+// or nil if it is not set. Use it to combine values set by concurrent
+// requests of a tool call, e.g. to sum their statistics.
+func UpdateResultMeta(ctx context.Context, key string, update func(old any) any) {
+	m, ok := ctx.Value(resultMetaKey{}).(*resultMeta)
+	if !ok {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.fields == nil {
+		m.fields = map[string]any{}
+	}
+	m.fields[key] = update(m.fields[key])
+}
+
+// SetResultNote sets a note added to the content of the result of the
+// current tool call, after the result itself, e.g. a warning that it is
+// incomplete. Unlike _meta fields, notes are seen by models. Setting a note
+// with the same key again replaces it. It does nothing outside of tools
+// created with ConvertTool or MustTool.
+func SetResultNote(ctx context.Context, key, text string) {
+	m, ok := ctx.Value(resultMetaKey{}).(*resultMeta)
+	if !ok {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.notes == nil {
+		m.notes = map[string]string{}
+	}
+	if _, ok := m.notes[key]; !ok {
+		m.noteKeys = append(m.noteKeys, key)
+	}
+	m.notes[key] = text
+}
+
+// apply adds the collected fields to the _meta of a result, and the notes
+// to its content.
+func (m *resultMeta) apply(result *mcp.CallToolResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range m.noteKeys {
+		result.Content = append(result.Content, mcp.NewTextContent(m.notes[key]))
+	}
+	if len(m.fields) == 0 {
+		return
+	}
+	if result.Meta == nil {
+		result.Meta = map[string]any{}
+	}
+	for k, v := range m.fields {
+		result.Meta[k] = v
+	}
+}
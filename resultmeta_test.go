@@ -0,0 +1,42 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type resultMetaTestParams struct{}
+
+func TestResultMeta(t *testing.T) {
+	tool := MustTool("test_result_meta", "A tool setting result metadata", func(ctx context.Context, args resultMetaTestParams) (map[string]int, error) {
+		SetResultMeta(ctx, "source", "test")
+		SetResultNote(ctx, "a", "first note")
+		SetResultNote(ctx, "b", "second note")
+		SetResultNote(ctx, "a", "replaced first note")
+		for range 3 {
+			UpdateResultMeta(ctx, "calls", func(old any) any {
+				n, _ := old.(int)
+				return n + 1
+			})
+		}
+		return map[string]int{"value": 1}, nil
+	})
+	result, err := tool.Handler(context.Background(), mcp.CallToolRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"source": "test", "calls": 3}, result.Meta)
+	// Notes follow the result, in the order they were first set.
+	require.Len(t, result.Content, 3)
+	assert.Equal(t, `{"value":1}`, result.Content[0].(mcp.TextContent).Text)
+	assert.Equal(t, "replaced first note", result.Content[1].(mcp.TextContent).Text)
+	assert.Equal(t, "second note", result.Content[2].(mcp.TextContent).Text)
+
+	// Setting metadata outside of a tool call does nothing.
+	SetResultMeta(context.Background(), "source", "test")
+}
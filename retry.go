@@ -0,0 +1,213 @@
+package mcpgrafana
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// DefaultMaxRetries is the default number of times a failed request to
+	// Grafana is retried.
+	DefaultMaxRetries = 3
+	// DefaultRetryInitialBackoff is the default time waited before the first
+	// retry. It doubles after each retry.
+	DefaultRetryInitialBackoff = 500 * time.Millisecond
+	// DefaultRetryMaxBackoff is the default maximum time waited before a
+	// retry.
+	DefaultRetryMaxBackoff = 10 * time.Second
+)
+
+// MaxRetriesArgument is the optional argument of tool calls overriding the
+// maximum number of retries of their requests to Grafana, up to
+// RetryConfig.MaxCallRetries.
+const MaxRetriesArgument = "maxRetries"
+
+// RetryConfig configures the retries of requests to Grafana which fail with
+// a 429 (Too Many Requests) or transient 5xx response.
+type RetryConfig struct {
+	// MaxRetries is the maximum number of retries of a request.
+	MaxRetries int
+	// InitialBackoff is the time waited before the first retry. It doubles
+	// after each retry, with random jitter.
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum time waited before a retry. Responses asking
+	// to retry after a longer time with a Retry-After header are returned
+	// without retrying.
+	MaxBackoff time.Duration
+	// MaxCallRetries is the maximum number of retries tool calls may request
+	// with the maxRetries argument, overriding MaxRetries. Zero means the
+	// argument is ignored.
+	MaxCallRetries int
+}
+
+// RoundTripper wraps rt so that failed requests are retried according to the
+// configuration. It returns rt unchanged if the configuration is nil or
+// disables retries.
+func (c *RetryConfig) RoundTripper(rt http.RoundTripper) http.RoundTripper {
+	if c == nil || (c.MaxRetries <= 0 && c.MaxCallRetries <= 0) {
+		return rt
+	}
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return &retryRoundTripper{config: *c, underlying: rt, sleep: sleepContext}
+}
+
+type maxRetriesKey struct{}
+
+// WithMaxRetries returns a context overriding the maximum number of retries
+// of requests made with it.
+func WithMaxRetries(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, maxRetriesKey{}, n)
+}
+
+func maxRetriesFromContext(ctx context.Context) (int, bool) {
+	n, ok := ctx.Value(maxRetriesKey{}).(int)
+	return n, ok
+}
+
+// MaxRetriesMiddleware returns a tool handler middleware applying the
+// maxRetries argument of tool calls to their requests to Grafana, so that
+// e.g. an investigation can retry a query against an overloaded datasource
+// more often than usual. Values above config.MaxCallRetries are rejected.
+func MaxRetriesMiddleware(config RetryConfig) server.ToolHandlerMiddleware {
+	RecordLimit("maxCallRetries", config.MaxCallRetries)
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			if config.MaxCallRetries <= 0 {
+				return next(ctx, request)
+			}
+			n, ok, err := intArgument(request, MaxRetriesArgument)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return next(ctx, request)
+			}
+			if n > config.MaxCallRetries {
+				return nil, fmt.Errorf("%s must be at most %d", MaxRetriesArgument, config.MaxCallRetries)
+			}
+			return next(WithMaxRetries(ctx, n), request)
+		}
+	}
+}
+
+type retryRoundTripper struct {
+	config     RetryConfig
+	underlying http.RoundTripper
+	// sleep waits for the given duration, returning early with an error if
+	// the context is done.
+	sleep func(context.Context, time.Duration) error
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// isIdempotent returns true if requests with the given method can safely be
+// sent more than once.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// shouldRetry returns true if a request which got the given response should
+// be retried. Requests rejected by rate limits were not processed and can
+// always be retried, while server errors are only retried for idempotent
+// requests.
+func shouldRetry(req *http.Request, resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return isIdempotent(req.Method)
+	}
+	return false
+}
+
+// retryAfter returns the delay requested by the Retry-After header of a
+// response, if any.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(0, t.Sub(now)), true
+	}
+	return 0, false
+}
+
+// backoff returns the time to wait before the given retry, starting at 1,
+// using exponential backoff with jitter.
+func (rt *retryRoundTripper) backoff(retry int) time.Duration {
+	d := rt.config.InitialBackoff
+	for i := 1; i < retry && d < rt.config.MaxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, rt.config.MaxBackoff)
+	// Wait between half and all of the backoff, so that clients which failed
+	// together don't retry together.
+	return d/2 + rand.N(d/2+1)
+}
+
+func (rt *retryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Requests with a body can only be retried if it can be read again.
+	rewindable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	maxRetries := rt.config.MaxRetries
+	if n, ok := maxRetriesFromContext(req.Context()); ok {
+		maxRetries = n
+	}
+	for retry := 1; ; retry++ {
+		resp, err := rt.underlying.RoundTrip(req)
+		if err != nil || !rewindable || retry > maxRetries || !shouldRetry(req, resp) {
+			return resp, err
+		}
+		wait := rt.backoff(retry)
+		if d, ok := retryAfter(resp, time.Now()); ok {
+			if d > rt.config.MaxBackoff {
+				return resp, nil
+			}
+			wait = d
+		}
+		slog.DebugContext(req.Context(), "Retrying request to Grafana", "method", req.Method, "url", req.URL.Redacted(), "status", resp.StatusCode, "retry", retry, "wait", wait)
+
+		// Drain the body so that the connection can be reused.
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+		resp.Body.Close()
+
+		if err := rt.sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
@@ -0,0 +1,211 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type retryTest struct {
+	client   *http.Client
+	url      string
+	attempts atomic.Int32
+	// waits are the waits between attempts, which are recorded instead of
+	// slept.
+	waits []time.Duration
+}
+
+// newRetryTest returns a client retrying requests to a server which responds
+// with the given statuses in turn, and then with 200 and the request body.
+func newRetryTest(t *testing.T, config RetryConfig, statuses []int, retryAfter string) *retryTest {
+	rt := &retryTest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		n := int(rt.attempts.Add(1))
+		if n <= len(statuses) {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+
+	transport := config.RoundTripper(http.DefaultTransport).(*retryRoundTripper)
+	transport.sleep = func(ctx context.Context, d time.Duration) error {
+		rt.waits = append(rt.waits, d)
+		return ctx.Err()
+	}
+	rt.client = &http.Client{Transport: transport}
+	rt.url = server.URL
+	return rt
+}
+
+func TestRetryRoundTripper(t *testing.T) {
+	config := RetryConfig{MaxRetries: 3, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	t.Run("retries rate limited requests with backoff", func(t *testing.T) {
+		rt := newRetryTest(t, config, []int{429, 429, 429}, "")
+		resp, err := rt.client.Get(rt.url + "/api/search")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, int32(4), rt.attempts.Load())
+		require.Len(t, rt.waits, 3)
+		for i, want := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond} {
+			assert.GreaterOrEqual(t, rt.waits[i], want/2)
+			assert.LessOrEqual(t, rt.waits[i], want)
+		}
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		rt := newRetryTest(t, config, []int{503, 503, 503, 503}, "")
+		resp, err := rt.client.Get(rt.url + "/api/search")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, int32(4), rt.attempts.Load())
+	})
+
+	t.Run("honors Retry-After", func(t *testing.T) {
+		rt := newRetryTest(t, config, []int{429}, "1")
+		resp, err := rt.client.Get(rt.url + "/api/search")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, int32(2), rt.attempts.Load())
+		assert.Equal(t, []time.Duration{time.Second}, rt.waits)
+	})
+
+	t.Run("does not wait longer than max backoff", func(t *testing.T) {
+		rt := newRetryTest(t, config, []int{429}, "120")
+		resp, err := rt.client.Get(rt.url + "/api/search")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, int32(1), rt.attempts.Load())
+	})
+
+	t.Run("does not retry server errors for POST requests", func(t *testing.T) {
+		rt := newRetryTest(t, config, []int{502}, "")
+		resp, err := rt.client.Post(rt.url+"/api/ds/query", "application/json", strings.NewReader(`{}`))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+		assert.Equal(t, int32(1), rt.attempts.Load())
+	})
+
+	t.Run("replays the body of rate limited POST requests", func(t *testing.T) {
+		rt := newRetryTest(t, config, []int{429}, "")
+		resp, err := rt.client.Post(rt.url+"/api/ds/query", "application/json", strings.NewReader(`{"queries":[]}`))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"queries":[]}`, string(body))
+		assert.Equal(t, int32(2), rt.attempts.Load())
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		rt := newRetryTest(t, config, []int{404}, "")
+		resp, err := rt.client.Get(rt.url + "/api/search")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, int32(1), rt.attempts.Load())
+	})
+
+	t.Run("stops waiting when the context is done", func(t *testing.T) {
+		rt := newRetryTest(t, config, []int{429}, "1")
+		rt.client.Transport.(*retryRoundTripper).sleep = sleepContext
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rt.url+"/api/search", nil)
+		require.NoError(t, err)
+		_, err = rt.client.Do(req)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, int32(1), rt.attempts.Load())
+	})
+
+	t.Run("honors the max retries of the context", func(t *testing.T) {
+		rt := newRetryTest(t, config, []int{503, 503, 503, 503, 503}, "")
+		req, err := http.NewRequestWithContext(WithMaxRetries(context.Background(), 5), http.MethodGet, rt.url+"/api/search", nil)
+		require.NoError(t, err)
+		resp, err := rt.client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, int32(6), rt.attempts.Load())
+	})
+}
+
+func TestMaxRetriesMiddleware(t *testing.T) {
+	var got []int
+	handler := MaxRetriesMiddleware(RetryConfig{MaxRetries: 3, MaxCallRetries: 5})(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		n, ok := maxRetriesFromContext(ctx)
+		if !ok {
+			n = -1
+		}
+		got = append(got, n)
+		return mcp.NewToolResultText("ok"), nil
+	})
+	call := func(args map[string]any) error {
+		request := mcp.CallToolRequest{}
+		request.Params.Name = "test_tool"
+		request.Params.Arguments = args
+		_, err := handler(context.Background(), request)
+		return err
+	}
+
+	require.NoError(t, call(nil))
+	require.NoError(t, call(map[string]any{"maxRetries": float64(0)}))
+	require.NoError(t, call(map[string]any{"maxRetries": float64(5)}))
+	assert.Equal(t, []int{-1, 0, 5}, got)
+
+	err := call(map[string]any{"maxRetries": float64(6)})
+	assert.EqualError(t, err, "maxRetries must be at most 5")
+	err = call(map[string]any{"maxRetries": "lots"})
+	assert.EqualError(t, err, "maxRetries must be a non-negative integer")
+	assert.Len(t, got, 3)
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second, true},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+		{"soon", 0, false},
+	} {
+		resp := &http.Response{Header: http.Header{"Retry-After": []string{tc.header}}}
+		got, ok := retryAfter(resp, now)
+		assert.Equal(t, tc.ok, ok, tc.header)
+		assert.Equal(t, tc.want, got, tc.header)
+	}
+}
+
+func TestRetryConfigDisabled(t *testing.T) {
+	var config *RetryConfig
+	assert.Equal(t, http.DefaultTransport, config.RoundTripper(http.DefaultTransport))
+	assert.Equal(t, http.DefaultTransport, (&RetryConfig{}).RoundTripper(http.DefaultTransport))
+	assert.NotEqual(t, http.DefaultTransport, (&RetryConfig{MaxCallRetries: 1}).RoundTripper(http.DefaultTransport))
+}
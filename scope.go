@@ -0,0 +1,39 @@
+package mcpgrafana
+
+import (
+	"errors"
+	"strings"
+)
+
+// Scope is a default scope applied by tools which list or search resources,
+// so that deployments dedicated to a single team return the team's
+// resources by default, e.g. only alert rules with the label team=payments.
+// Tool calls can ignore it by setting their unscoped argument.
+type Scope struct {
+	// Labels are the label values alert rules must have, by label name.
+	// Label selectors of a tool call replace the scope's matcher for the
+	// labels they select on.
+	Labels map[string]string
+	// DashboardTags are the tags dashboards must have to be found by
+	// dashboard searches.
+	DashboardTags []string
+	// OnCallTeamID is the ID of the OnCall team whose schedules are listed,
+	// unless a tool call asks for another team.
+	OnCallTeamID string
+}
+
+// IsZero returns true if the scope doesn't restrict any tool.
+func (s Scope) IsZero() bool {
+	return len(s.Labels) == 0 && len(s.DashboardTags) == 0 && s.OnCallTeamID == ""
+}
+
+// ParseScopeLabel parses a scope label in the "name=value" format, e.g.
+// "team=payments".
+func ParseScopeLabel(s string) (string, string, error) {
+	name, value, ok := strings.Cut(s, "=")
+	name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+	if !ok || name == "" || value == "" {
+		return "", "", errors.New("invalid scope label, must be in the format 'name=value'")
+	}
+	return name, value, nil
+}
@@ -0,0 +1,29 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScopeLabel(t *testing.T) {
+	name, value, err := ParseScopeLabel(" team = payments ")
+	require.NoError(t, err)
+	assert.Equal(t, "team", name)
+	assert.Equal(t, "payments", value)
+
+	for _, s := range []string{"team", "=payments", "team="} {
+		_, _, err := ParseScopeLabel(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestScopeIsZero(t *testing.T) {
+	assert.True(t, Scope{}.IsZero())
+	assert.False(t, Scope{DashboardTags: []string{"payments"}}.IsZero())
+	assert.False(t, Scope{OnCallTeamID: "T1"}.IsZero())
+}
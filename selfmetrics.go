@@ -0,0 +1,284 @@
+package mcpgrafana
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+const (
+	// DefaultSelfMetricsInterval is the default interval between pushes of
+	// the metrics of the server.
+	DefaultSelfMetricsInterval = time.Minute
+	// DefaultSelfMetricsWritePath is the default path of the remote-write
+	// endpoint of the datasource the metrics of the server are pushed to,
+	// which is the one of Mimir and Grafana Cloud Metrics. Prometheus with
+	// the remote-write receiver enabled uses /api/v1/write.
+	DefaultSelfMetricsWritePath = "/api/v1/push"
+
+	// selfMetricsPushTimeout is the timeout of a single push.
+	selfMetricsPushTimeout = 30 * time.Second
+)
+
+// SelfMetricsConfig configures pushing the metrics of the server, such as
+// the number and duration of tool calls, to a Prometheus or Mimir
+// datasource of Grafana using the remote-write protocol.
+type SelfMetricsConfig struct {
+	// DatasourceUID is the UID of the datasource the metrics are pushed to,
+	// through the datasource proxy of Grafana.
+	DatasourceUID string
+	// WritePath is the path of the remote-write endpoint of the datasource.
+	// Defaults to DefaultSelfMetricsWritePath if not set.
+	WritePath string
+	// Interval is the interval between pushes. Defaults to
+	// DefaultSelfMetricsInterval if not set.
+	Interval time.Duration
+	// URL and APIKey are the URL of Grafana and the credentials used to
+	// push the metrics. They default to the GRAFANA_URL and GRAFANA_API_KEY
+	// environment variables.
+	URL    string
+	APIKey string
+	// OrgID is the organization of the datasource. Defaults to the
+	// GRAFANA_ORG_ID environment variable, or the default organization of
+	// the credentials.
+	OrgID int64
+	// Headers are extra headers sent with every push.
+	Headers http.Header
+	// Instance is the value of the instance label of the metrics. Defaults
+	// to the host name.
+	Instance string
+}
+
+// SelfMetrics collects the metrics of the server and pushes them to a
+// datasource of Grafana.
+type SelfMetrics struct {
+	config     SelfMetricsConfig
+	pushURL    string
+	httpClient *http.Client
+	now        func() time.Time
+
+	registry  *prometheus.Registry
+	toolCalls *prometheus.CounterVec
+	durations *prometheus.HistogramVec
+}
+
+// NewSelfMetrics creates a SelfMetrics. Metrics are only pushed once Run is
+// called.
+func NewSelfMetrics(config SelfMetricsConfig, tlsConfig *TLSConfig) (*SelfMetrics, error) {
+	if config.DatasourceUID == "" {
+		return nil, fmt.Errorf("a datasource UID is required to push the metrics of the server")
+	}
+	envURL, envAPIKey := urlAndAPIKeyFromEnv()
+	if config.URL == "" {
+		config.URL = envURL
+	}
+	if config.URL == "" {
+		config.URL = defaultGrafanaURL
+	}
+	if config.APIKey == "" {
+		config.APIKey = envAPIKey
+	}
+	if config.OrgID == 0 {
+		config.OrgID = parseOrgID(os.Getenv(grafanaOrgIDEnvVar), grafanaOrgIDEnvVar)
+	}
+	if config.WritePath == "" {
+		config.WritePath = DefaultSelfMetricsWritePath
+	}
+	if config.Interval <= 0 {
+		config.Interval = DefaultSelfMetricsInterval
+	}
+	if config.Instance == "" {
+		config.Instance, _ = os.Hostname()
+	}
+	transport, err := tlsConfig.HTTPTransport(http.DefaultTransport.(*http.Transport))
+	if err != nil {
+		return nil, fmt.Errorf("create self-metrics transport: %w", err)
+	}
+
+	m := &SelfMetrics{
+		config:     config,
+		pushURL:    fmt.Sprintf("%s/api/datasources/proxy/uid/%s/%s", strings.TrimRight(config.URL, "/"), url.PathEscape(config.DatasourceUID), strings.TrimLeft(config.WritePath, "/")),
+		httpClient: &http.Client{Transport: WithExtraHeaders(transport, config.Headers), Timeout: selfMetricsPushTimeout},
+		now:        time.Now,
+		registry:   prometheus.NewRegistry(),
+		toolCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mcp_grafana_tool_calls_total",
+			Help: "Number of tool calls by tool, category and outcome (success, tool_error or error).",
+		}, []string{"tool", "category", "outcome"}),
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "mcp_grafana_tool_call_duration_seconds",
+			Help:    "Duration of tool calls by tool and category.",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+		}, []string{"tool", "category"}),
+	}
+	buildInfo := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:        "mcp_grafana_build_info",
+		Help:        "Version of the server, always 1.",
+		ConstLabels: prometheus.Labels{"version": Version()},
+	})
+	buildInfo.Set(1)
+	m.registry.MustRegister(m.toolCalls, m.durations, buildInfo)
+	return m, nil
+}
+
+// Middleware returns a tool handler middleware recording the number and
+// duration of tool calls.
+func (m *SelfMetrics) Middleware() server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			start := time.Now()
+			result, err := next(ctx, request)
+
+			tool := request.Params.Name
+			category, _ := ToolCategory(tool)
+			outcome := "success"
+			switch {
+			case err != nil:
+				outcome = "error"
+			case result != nil && result.IsError:
+				outcome = "tool_error"
+			}
+			m.toolCalls.WithLabelValues(tool, category, outcome).Inc()
+			m.durations.WithLabelValues(tool, category).Observe(time.Since(start).Seconds())
+			return result, err
+		}
+	}
+}
+
+// Run pushes the metrics at the configured interval until the context is
+// done. Failed pushes are logged and retried at the next interval.
+func (m *SelfMetrics) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := m.Push(ctx); err != nil {
+			slog.Warn("Failed to push the metrics of the server", "datasource", m.config.DatasourceUID, "error", err)
+		}
+	}
+}
+
+// Push pushes the current values of the metrics.
+func (m *SelfMetrics) Push(ctx context.Context) error {
+	families, err := m.registry.Gather()
+	if err != nil {
+		return fmt.Errorf("gather metrics: %w", err)
+	}
+	extra := map[string]string{"job": "mcp-grafana", "instance": m.config.Instance}
+	write := &prompb.WriteRequest{Timeseries: toTimeSeries(families, extra, m.now().UnixMilli())}
+	data, err := write.Marshal()
+	if err != nil {
+		return fmt.Errorf("encode metrics: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.pushURL, bytes.NewReader(snappy.Encode(nil, data)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	apiKey := m.config.APIKey
+	if f, _ := APIKeyFileFromEnv(); f != nil && f.issued(apiKey) {
+		// The token was read from a file, which may have been rotated.
+		apiKey = f.Token()
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	if m.config.OrgID != 0 {
+		req.Header.Set(OrgIDHeader, strconv.FormatInt(m.config.OrgID, 10))
+	}
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("datasource returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// toTimeSeries converts gathered metric families to remote-write series
+// with a sample at the given time, adding the extra labels to every series.
+// Histograms are converted to their _bucket, _sum and _count series.
+func toTimeSeries(families []*dto.MetricFamily, extra map[string]string, timestamp int64) []prompb.TimeSeries {
+	var series []prompb.TimeSeries
+	add := func(name string, metric *dto.Metric, value float64, more ...string) {
+		labels := []prompb.Label{{Name: "__name__", Value: name}}
+		for k, v := range extra {
+			labels = append(labels, prompb.Label{Name: k, Value: v})
+		}
+		for _, l := range metric.GetLabel() {
+			labels = append(labels, prompb.Label{Name: l.GetName(), Value: l.GetValue()})
+		}
+		for i := 0; i+1 < len(more); i += 2 {
+			labels = append(labels, prompb.Label{Name: more[i], Value: more[i+1]})
+		}
+		// Remote-write receivers require the labels of a series to be
+		// sorted by name.
+		sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+		series = append(series, prompb.TimeSeries{Labels: labels, Samples: []prompb.Sample{{Value: value, Timestamp: timestamp}}})
+	}
+	for _, f := range families {
+		name := f.GetName()
+		for _, metric := range f.GetMetric() {
+			switch f.GetType() {
+			case dto.MetricType_COUNTER:
+				add(name, metric, metric.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add(name, metric, metric.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add(name, metric, metric.GetUntyped().GetValue())
+			case dto.MetricType_HISTOGRAM:
+				h := metric.GetHistogram()
+				for _, b := range h.GetBucket() {
+					add(name+"_bucket", metric, float64(b.GetCumulativeCount()), "le", formatBound(b.GetUpperBound()))
+				}
+				add(name+"_bucket", metric, float64(h.GetSampleCount()), "le", "+Inf")
+				add(name+"_sum", metric, h.GetSampleSum())
+				add(name+"_count", metric, float64(h.GetSampleCount()))
+			case dto.MetricType_SUMMARY:
+				s := metric.GetSummary()
+				for _, q := range s.GetQuantile() {
+					add(name, metric, q.GetValue(), "quantile", formatBound(q.GetQuantile()))
+				}
+				add(name+"_sum", metric, s.GetSampleSum())
+				add(name+"_count", metric, float64(s.GetSampleCount()))
+			}
+		}
+	}
+	return series
+}
+
+// formatBound formats a bucket bound or quantile like the Prometheus
+// exposition format does.
+func formatBound(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
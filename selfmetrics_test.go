@@ -0,0 +1,108 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seriesLabels returns the labels of a remote-write series as a map.
+func seriesLabels(ts prompb.TimeSeries) map[string]string {
+	labels := map[string]string{}
+	for _, l := range ts.Labels {
+		labels[l.Name] = l.Value
+	}
+	return labels
+}
+
+func TestSelfMetrics(t *testing.T) {
+	var received *prompb.WriteRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/datasources/proxy/uid/mimir/api/v1/push", r.URL.Path)
+		assert.Equal(t, "Bearer test-api-key", r.Header.Get("Authorization"))
+		assert.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "2", r.Header.Get(OrgIDHeader))
+		body, err := io.ReadAll(r.Body)
+		if !assert.NoError(t, err) {
+			return
+		}
+		data, err := snappy.Decode(nil, body)
+		if !assert.NoError(t, err) {
+			return
+		}
+		received = &prompb.WriteRequest{}
+		assert.NoError(t, received.Unmarshal(data))
+	}))
+	defer server.Close()
+
+	m, err := NewSelfMetrics(SelfMetricsConfig{DatasourceUID: "mimir", URL: server.URL, APIKey: "test-api-key", OrgID: 2, Instance: "test"}, nil)
+	require.NoError(t, err)
+	m.now = func() time.Time { return time.UnixMilli(1736157600000) }
+
+	handler := m.Middleware()(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if request.Params.Name == "failing" {
+			return nil, errors.New("boom")
+		}
+		return mcp.NewToolResultText("ok"), nil
+	})
+	for _, name := range []string{"working", "working", "failing"} {
+		request := mcp.CallToolRequest{}
+		request.Params.Name = name
+		_, _ = handler(context.Background(), request)
+	}
+
+	require.NoError(t, m.Push(context.Background()))
+	require.NotNil(t, received)
+
+	calls := map[string]float64{}
+	var buckets int
+	for _, ts := range received.Timeseries {
+		labels := seriesLabels(ts)
+		assert.Equal(t, "mcp-grafana", labels["job"])
+		assert.Equal(t, "test", labels["instance"])
+		require.Len(t, ts.Samples, 1)
+		assert.Equal(t, int64(1736157600000), ts.Samples[0].Timestamp)
+		for i := 1; i < len(ts.Labels); i++ {
+			assert.Less(t, ts.Labels[i-1].Name, ts.Labels[i].Name, "labels must be sorted")
+		}
+		switch labels["__name__"] {
+		case "mcp_grafana_tool_calls_total":
+			calls[labels["tool"]+"/"+labels["outcome"]] = ts.Samples[0].Value
+		case "mcp_grafana_tool_call_duration_seconds_bucket":
+			buckets++
+		case "mcp_grafana_build_info":
+			assert.Equal(t, 1.0, ts.Samples[0].Value)
+		}
+	}
+	assert.Equal(t, map[string]float64{"working/success": 2, "failing/error": 1}, calls)
+	// Each tool has a series per bucket and the +Inf bucket.
+	assert.Equal(t, 2*12, buckets)
+}
+
+func TestSelfMetricsPushError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	m, err := NewSelfMetrics(SelfMetricsConfig{DatasourceUID: "prom", URL: server.URL, WritePath: "/api/v1/write"}, nil)
+	require.NoError(t, err)
+	err = m.Push(context.Background())
+	assert.ErrorContains(t, err, "status 400: out of order sample")
+
+	_, err = NewSelfMetrics(SelfMetricsConfig{}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,126 @@
+package mcpgrafana
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SeverityRubric is the rubric used to suggest the severity of incidents
+// from the firing alerts and the affected services. It is loaded from a
+// YAML file with LoadSeverityRubric, or is DefaultSeverityRubric.
+type SeverityRubric struct {
+	// Levels are the severities of incidents, from the most to the least
+	// severe.
+	Levels []string `yaml:"levels" json:"levels"`
+	// CustomerImpactLabels are labels of alerts and services indicating
+	// customer impact, with the values indicating it. An empty list of
+	// values means any value.
+	CustomerImpactLabels map[string][]string `yaml:"customerImpactLabels" json:"customerImpactLabels,omitempty"`
+	// ServiceLabels are the labels of alerts naming the affected service,
+	// in order of preference.
+	ServiceLabels []string `yaml:"serviceLabels" json:"serviceLabels,omitempty"`
+	// Rules are matched in order, and the first matching rule gives the
+	// severity.
+	Rules []SeverityRule `yaml:"rules" json:"rules"`
+	// Default is the severity if no rule matches. Defaults to the least
+	// severe level.
+	Default string `yaml:"default" json:"default"`
+}
+
+// SeverityRule gives a severity to incidents matching all of its conditions.
+// A rule without conditions matches every incident.
+type SeverityRule struct {
+	// Name identifies the rule in suggestions.
+	Name     string `yaml:"name" json:"name"`
+	Severity string `yaml:"severity" json:"severity"`
+
+	// CustomerImpact requires an alert or service indicating customer
+	// impact.
+	CustomerImpact bool `yaml:"customerImpact" json:"customerImpact,omitempty"`
+	// MinServices and MinAlerts are the minimum numbers of affected services
+	// and firing alerts.
+	MinServices int `yaml:"minServices" json:"minServices,omitempty"`
+	MinAlerts   int `yaml:"minAlerts" json:"minAlerts,omitempty"`
+	// AlertLabels and ServiceLabels require a firing alert or an affected
+	// service with all of these labels.
+	AlertLabels   map[string]string `yaml:"alertLabels" json:"alertLabels,omitempty"`
+	ServiceLabels map[string]string `yaml:"serviceLabels" json:"serviceLabels,omitempty"`
+}
+
+// DefaultSeverityRubric returns the rubric used if none is configured. It
+// uses the default severities of Grafana Incident.
+func DefaultSeverityRubric() *SeverityRubric {
+	return &SeverityRubric{
+		Levels: []string{"critical", "major", "minor"},
+		CustomerImpactLabels: map[string][]string{
+			"customer_impact": {"true", "yes", "high"},
+			"customer_facing": {"true", "yes"},
+		},
+		ServiceLabels: []string{"service", "service_name", "app", "job"},
+		Rules: []SeverityRule{
+			{Name: "customer impact across services", Severity: "critical", CustomerImpact: true, MinServices: 2},
+			{Name: "critical alert with customer impact", Severity: "critical", CustomerImpact: true, AlertLabels: map[string]string{"severity": "critical"}},
+			{Name: "customer impact", Severity: "major", CustomerImpact: true},
+			{Name: "many services", Severity: "major", MinServices: 3},
+			{Name: "critical alert", Severity: "major", AlertLabels: map[string]string{"severity": "critical"}},
+		},
+		Default: "minor",
+	}
+}
+
+// LoadSeverityRubric reads and validates a severity rubric file.
+func LoadSeverityRubric(file string) (*SeverityRubric, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read severity rubric file: %w", err)
+	}
+	return ParseSeverityRubric(data)
+}
+
+// ParseSeverityRubric parses and validates a severity rubric in YAML or
+// JSON.
+func ParseSeverityRubric(data []byte) (*SeverityRubric, error) {
+	r := &SeverityRubric{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(r); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parse severity rubric: %w", err)
+	}
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Validate checks that the rubric's severities are among its levels, and
+// sets the defaults of the rubric.
+func (r *SeverityRubric) Validate() error {
+	if len(r.Levels) == 0 {
+		return errors.New("the severity rubric has no levels")
+	}
+	if r.Default == "" {
+		r.Default = r.Levels[len(r.Levels)-1]
+	}
+	if !slices.Contains(r.Levels, r.Default) {
+		return fmt.Errorf("the default severity %q is not one of the levels %v", r.Default, r.Levels)
+	}
+	for i := range r.Rules {
+		rule := &r.Rules[i]
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if !slices.Contains(r.Levels, rule.Severity) {
+			return fmt.Errorf("severity rule %q has severity %q, which is not one of the levels %v", rule.Name, rule.Severity, r.Levels)
+		}
+		if rule.MinServices < 0 || rule.MinAlerts < 0 {
+			return fmt.Errorf("severity rule %q has a negative minimum", rule.Name)
+		}
+	}
+	return nil
+}
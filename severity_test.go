@@ -0,0 +1,40 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSeverityRubric(t *testing.T) {
+	r, err := ParseSeverityRubric([]byte(`
+levels: [sev1, sev2, sev3]
+customerImpactLabels:
+  impact: []
+rules:
+  - severity: sev1
+    customerImpact: true
+  - name: many alerts
+    severity: sev2
+    minAlerts: 10
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "sev3", r.Default, "the default should be the least severe level")
+	assert.Equal(t, "rule 1", r.Rules[0].Name)
+	assert.Equal(t, "many alerts", r.Rules[1].Name)
+
+	_, err = ParseSeverityRubric([]byte("levels: [sev1]\nrules:\n  - severity: sev2\n"))
+	assert.ErrorContains(t, err, `has severity "sev2"`)
+
+	_, err = ParseSeverityRubric([]byte("levels: [sev1]\nrule: []\n"))
+	assert.ErrorContains(t, err, "field rule not found")
+
+	_, err = ParseSeverityRubric(nil)
+	assert.ErrorContains(t, err, "no levels")
+
+	require.NoError(t, DefaultSeverityRubric().Validate())
+}
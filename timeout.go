@@ -0,0 +1,165 @@
+package mcpgrafana
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// TimeoutArgument is the optional argument of tool calls overriding their
+// timeout in seconds, up to TimeoutConfig.Max.
+const TimeoutArgument = "timeoutSeconds"
+
+// TimeoutConfig configures the maximum duration of tool calls.
+type TimeoutConfig struct {
+	// Default is the timeout of tool calls in categories without an
+	// override. Zero means no timeout.
+	Default time.Duration
+	// Categories overrides the timeout of tool calls by tool category, e.g.
+	// "loki". Zero means no timeout for the category.
+	Categories map[string]time.Duration
+	// Max is the maximum timeout tool calls may request with the
+	// timeoutSeconds argument, e.g. for queries which legitimately take
+	// longer than their default timeout. Zero means the argument is ignored.
+	Max time.Duration
+}
+
+// Enabled returns true if any tool calls have a timeout.
+func (c TimeoutConfig) Enabled() bool {
+	if c.Default > 0 || c.Max > 0 {
+		return true
+	}
+	for _, t := range c.Categories {
+		if t > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Timeout returns the timeout of calls to the given tool, or zero if they
+// have none.
+func (c TimeoutConfig) Timeout(tool string) time.Duration {
+	if category, ok := ToolCategory(tool); ok {
+		if t, ok := c.Categories[category]; ok {
+			return t
+		}
+	}
+	return c.Default
+}
+
+// TimeoutMiddleware returns a tool handler middleware cancelling the context
+// of tool calls which exceed their timeout, so that e.g. slow Loki queries
+// don't hang forever. Tool calls which time out fail with an error
+// suggesting a narrower request.
+//
+// Tool calls may override their timeout with the timeoutSeconds argument, up
+// to the configured maximum.
+func TimeoutMiddleware(config TimeoutConfig) server.ToolHandlerMiddleware {
+	limits := map[string]float64{"default": config.Default.Seconds()}
+	for category, t := range config.Categories {
+		limits[category] = t.Seconds()
+	}
+	RecordLimit("toolTimeoutSeconds", limits)
+	RecordLimit("maxCallTimeoutSeconds", config.Max.Seconds())
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			timeout := config.Timeout(request.Params.Name)
+			if config.Max > 0 {
+				seconds, ok, err := intArgument(request, TimeoutArgument)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					if seconds == 0 || time.Duration(seconds)*time.Second > config.Max {
+						return nil, fmt.Errorf("%s must be between 1 and %d", TimeoutArgument, int(config.Max.Seconds()))
+					}
+					timeout = time.Duration(seconds) * time.Second
+				}
+			}
+			if timeout <= 0 {
+				return next(ctx, request)
+			}
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			result, err := next(ctx, request)
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) && (err != nil || result == nil || result.IsError) {
+				return nil, fmt.Errorf("%s timed out after %s; try a narrower request, e.g. a shorter time range or a lower limit: %w", request.Params.Name, timeout, context.DeadlineExceeded)
+			}
+			return result, err
+		}
+	}
+}
+
+const (
+	// ClientTimeoutMetaField is the field of the _meta of tool calls in which
+	// clients may send how long they wait for the result, in milliseconds.
+	ClientTimeoutMetaField = "timeoutMs"
+	// ClientDeadlineMetaField is the field of the _meta of tool calls in which
+	// clients may send the time at which they stop waiting for the result, in
+	// RFC 3339 format.
+	ClientDeadlineMetaField = "deadline"
+)
+
+// clientDeadline returns the deadline of a tool call requested by the
+// client in its _meta, if any.
+func clientDeadline(request mcp.CallToolRequest, now time.Time) (time.Time, bool, error) {
+	if request.Params.Meta == nil {
+		return time.Time{}, false, nil
+	}
+	fields := request.Params.Meta.AdditionalFields
+	var deadline time.Time
+	if v, ok := fields[ClientTimeoutMetaField]; ok && v != nil {
+		ms, ok := v.(float64)
+		if !ok || ms <= 0 {
+			return time.Time{}, false, fmt.Errorf("_meta.%s must be a positive number of milliseconds", ClientTimeoutMetaField)
+		}
+		deadline = now.Add(time.Duration(ms * float64(time.Millisecond)))
+	}
+	if v, ok := fields[ClientDeadlineMetaField]; ok && v != nil {
+		s, _ := v.(string)
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("_meta.%s must be a time in RFC 3339 format", ClientDeadlineMetaField)
+		}
+		if deadline.IsZero() || t.Before(deadline) {
+			deadline = t
+		}
+	}
+	return deadline, !deadline.IsZero(), nil
+}
+
+// ClientDeadlineMiddleware returns a tool handler middleware deriving the
+// deadline of the context of tool calls from the timeout requested by the
+// client in their _meta, so that the server stops working on calls as soon
+// as the client has given up on them. The deadline only ever shortens the
+// server's own timeout.
+func ClientDeadlineMiddleware() server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			deadline, ok, err := clientDeadline(request, time.Now())
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return next(ctx, request)
+			}
+			if time.Until(deadline) <= 0 {
+				return nil, fmt.Errorf("%s was not run because the client's deadline has already passed: %w", request.Params.Name, context.DeadlineExceeded)
+			}
+			ctx, cancel := context.WithDeadline(ctx, deadline)
+			defer cancel()
+			result, err := next(ctx, request)
+			// If the server's own, shorter timeout expired, ctx isn't done and
+			// its error is kept.
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) && (err != nil || result == nil || result.IsError) {
+				return nil, fmt.Errorf("%s was cancelled at the client's deadline of %s: %w", request.Params.Name, deadline.UTC().Format(time.RFC3339), context.DeadlineExceeded)
+			}
+			return result, err
+		}
+	}
+}
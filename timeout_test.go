@@ -0,0 +1,164 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type timeoutTestParams struct{}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	s := server.NewMCPServer("test", "0.0.0")
+	RegisterCategory(s, "timeouttest", true, func(s *server.MCPServer) {
+		tool := MustTool("test_timeout_slow", "A slow tool", func(ctx context.Context, args timeoutTestParams) (string, error) {
+			return "", nil
+		})
+		tool.Register(s)
+	})
+
+	config := TimeoutConfig{Default: time.Hour, Categories: map[string]time.Duration{"timeouttest": 20 * time.Millisecond}}
+	assert.True(t, config.Enabled())
+	assert.Equal(t, 20*time.Millisecond, config.Timeout("test_timeout_slow"))
+	assert.Equal(t, time.Hour, config.Timeout("unknown_tool"))
+
+	// A handler which blocks until its context is done, like a hanging query.
+	handler := TimeoutMiddleware(config)(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "test_timeout_slow"
+	start := time.Now()
+	_, err := handler(context.Background(), request)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "test_timeout_slow timed out after 20ms")
+	assert.Less(t, time.Since(start), time.Second)
+
+	// Fast tool calls are unaffected.
+	handler = TimeoutMiddleware(config)(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		_, ok := ctx.Deadline()
+		assert.True(t, ok)
+		return mcp.NewToolResultText("ok"), nil
+	})
+	result, err := handler(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result.Content[0].(mcp.TextContent).Text)
+}
+
+func TestTimeoutConfigDisabled(t *testing.T) {
+	assert.False(t, TimeoutConfig{}.Enabled())
+	assert.False(t, TimeoutConfig{Categories: map[string]time.Duration{"loki": 0}}.Enabled())
+}
+
+func TestTimeoutMiddlewareCallOverride(t *testing.T) {
+	config := TimeoutConfig{Default: time.Minute, Max: 10 * time.Minute}
+	assert.True(t, TimeoutConfig{Max: time.Minute}.Enabled())
+
+	var remaining time.Duration
+	handler := TimeoutMiddleware(config)(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		deadline, _ := ctx.Deadline()
+		remaining = time.Until(deadline)
+		return mcp.NewToolResultText("ok"), nil
+	})
+	call := func(args map[string]any) error {
+		request := mcp.CallToolRequest{}
+		request.Params.Name = "test_tool"
+		request.Params.Arguments = args
+		_, err := handler(context.Background(), request)
+		return err
+	}
+
+	require.NoError(t, call(nil))
+	assert.InDelta(t, time.Minute, remaining, float64(time.Second))
+	require.NoError(t, call(map[string]any{"timeoutSeconds": float64(300)}))
+	assert.InDelta(t, 5*time.Minute, remaining, float64(time.Second))
+
+	assert.EqualError(t, call(map[string]any{"timeoutSeconds": float64(601)}), "timeoutSeconds must be between 1 and 600")
+	assert.EqualError(t, call(map[string]any{"timeoutSeconds": float64(0)}), "timeoutSeconds must be between 1 and 600")
+	assert.EqualError(t, call(map[string]any{"timeoutSeconds": 1.5}), "timeoutSeconds must be a non-negative integer")
+
+	// The argument is ignored if overrides are disabled.
+	config.Max = 0
+	handler = TimeoutMiddleware(config)(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		deadline, _ := ctx.Deadline()
+		remaining = time.Until(deadline)
+		return mcp.NewToolResultText("ok"), nil
+	})
+	require.NoError(t, call(map[string]any{"timeoutSeconds": float64(300)}))
+	assert.InDelta(t, time.Minute, remaining, float64(time.Second))
+}
+
+func TestClientDeadlineMiddleware(t *testing.T) {
+	call := func(meta map[string]any, handler server.ToolHandlerFunc) error {
+		request := mcp.CallToolRequest{}
+		request.Params.Name = "test_tool"
+		if meta != nil {
+			request.Params.Meta = &mcp.Meta{AdditionalFields: meta}
+		}
+		_, err := ClientDeadlineMiddleware()(handler)(context.Background(), request)
+		return err
+	}
+	blocking := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	// The call is cancelled when the client gives up.
+	start := time.Now()
+	err := call(map[string]any{ClientTimeoutMetaField: float64(20)}, blocking)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "test_tool was cancelled at the client's deadline")
+	assert.Less(t, time.Since(start), time.Second)
+
+	// An absolute deadline in the past fails without calling the tool.
+	called := false
+	err = call(map[string]any{ClientDeadlineMetaField: time.Now().Add(-time.Second).Format(time.RFC3339Nano)}, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		called = true
+		return nil, nil
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, called)
+
+	// The earliest of both fields applies.
+	var remaining time.Duration
+	record := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		deadline, ok := ctx.Deadline()
+		assert.True(t, ok)
+		remaining = time.Until(deadline)
+		return mcp.NewToolResultText("ok"), nil
+	}
+	require.NoError(t, call(map[string]any{
+		ClientTimeoutMetaField:  float64(60_000),
+		ClientDeadlineMetaField: time.Now().Add(time.Hour).Format(time.RFC3339),
+	}, record))
+	assert.InDelta(t, time.Minute.Seconds(), remaining.Seconds(), 5)
+
+	// The client's deadline doesn't extend the server's timeout.
+	handler := ClientDeadlineMiddleware()(TimeoutMiddleware(TimeoutConfig{Default: 20 * time.Millisecond})(blocking))
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "test_tool"
+	request.Params.Meta = &mcp.Meta{AdditionalFields: map[string]any{ClientTimeoutMetaField: float64(60_000)}}
+	_, err = handler(context.Background(), request)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "test_tool timed out after 20ms")
+
+	// Calls without a deadline have none, and invalid fields are rejected.
+	require.NoError(t, call(nil, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		_, ok := ctx.Deadline()
+		assert.False(t, ok)
+		return mcp.NewToolResultText("ok"), nil
+	}))
+	assert.ErrorContains(t, call(map[string]any{ClientTimeoutMetaField: "soon"}, record), "_meta.timeoutMs must be a positive number")
+	assert.ErrorContains(t, call(map[string]any{ClientDeadlineMetaField: "tomorrow"}, record), "_meta.deadline must be a time in RFC 3339 format")
+}
@@ -0,0 +1,162 @@
+package mcpgrafana
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	grafanaAPIKeyFileEnvVar = "GRAFANA_API_KEY_FILE"
+
+	// maxPreviousTokens is the number of rotated tokens a TokenFile
+	// remembers, to recognize contexts created before the rotations.
+	maxPreviousTokens = 4
+)
+
+// TokenFile is a file containing the Grafana token, such as a projected
+// Kubernetes service account token, which is replaced when the token is
+// rotated. The file is read again whenever it changes, so that the new token
+// is used without restarting the server.
+type TokenFile struct {
+	path string
+
+	mu       sync.Mutex
+	token    string
+	modTime  time.Time
+	size     int64
+	previous []string
+}
+
+// NewTokenFile creates a TokenFile, reading the token from the file at path.
+func NewTokenFile(path string) (*TokenFile, error) {
+	f := &TokenFile{path: path}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("read Grafana token file: %w", err)
+	}
+	if err := f.read(info); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// read reads the token from the file, whose current state is info. f.mu
+// must be held, except by NewTokenFile.
+func (f *TokenFile) read(info os.FileInfo) error {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("read Grafana token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return fmt.Errorf("Grafana token file %s is empty", f.path)
+	}
+	if f.token != "" && token != f.token {
+		f.previous = append(f.previous, f.token)
+		if len(f.previous) > maxPreviousTokens {
+			f.previous = f.previous[1:]
+		}
+		slog.Info("Grafana token file changed, using the new token", "path", f.path)
+	}
+	f.token = token
+	f.modTime = info.ModTime()
+	f.size = info.Size()
+	return nil
+}
+
+// Token returns the current token, reading the file again if it changed
+// since it was last read. Projected tokens are replaced by swapping a
+// symbolic link, which is followed to find the current file. If the file
+// can't be read, e.g. while it is being replaced, the last token is
+// returned.
+func (f *TokenFile) Token() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	info, err := os.Stat(f.path)
+	if err == nil && (!info.ModTime().Equal(f.modTime) || info.Size() != f.size) {
+		err = f.read(info)
+	}
+	if err != nil {
+		slog.Warn("Failed to read the Grafana token file, using the last token", "path", f.path, "error", err)
+	}
+	return f.token
+}
+
+// issued reports whether token was read from the file, now or before a
+// rotation.
+func (f *TokenFile) issued(token string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return token != "" && (token == f.token || slices.Contains(f.previous, token))
+}
+
+var (
+	tokenFilesMu sync.Mutex
+	// tokenFiles are the token files read so far, by path.
+	tokenFiles = map[string]*TokenFile{}
+)
+
+// APIKeyFileFromEnv returns the token file set with the
+// GRAFANA_API_KEY_FILE environment variable, or nil if it isn't set. Its
+// token is used when GRAFANA_API_KEY is not set.
+func APIKeyFileFromEnv() (*TokenFile, error) {
+	path := os.Getenv(grafanaAPIKeyFileEnvVar)
+	if path == "" {
+		return nil, nil
+	}
+	tokenFilesMu.Lock()
+	defer tokenFilesMu.Unlock()
+	if f, ok := tokenFiles[path]; ok {
+		return f, nil
+	}
+	f, err := NewTokenFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tokenFiles[path] = f
+	return f, nil
+}
+
+// apiKeyFromFile returns the current token of the token file set in the
+// environment, or an empty string if there is none.
+func apiKeyFromFile() string {
+	f, err := APIKeyFileFromEnv()
+	if err != nil {
+		slog.Error("Failed to read the Grafana token file", "error", err)
+		return ""
+	}
+	if f == nil {
+		return ""
+	}
+	return f.Token()
+}
+
+// TokenFileMiddleware returns a tool handler middleware replacing tokens
+// read from f which were rotated since the context of the tool call was
+// created, e.g. at the start of a stdio session, by the current token.
+// Tokens given by clients, e.g. with the X-Grafana-API-Key header, are kept.
+func TokenFileMiddleware(f *TokenFile) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			cfg := GrafanaConfigFromContext(ctx)
+			if f.issued(cfg.APIKey) {
+				if token := f.Token(); token != cfg.APIKey {
+					cfg.APIKey = token
+					ctx = WithGrafanaConfig(ctx, cfg)
+					ctx = WithGrafanaClient(ctx, NewGrafanaClient(ctx, cfg.URL, token))
+					ctx = WithIncidentClient(ctx, newIncidentClient(ctx, cfg.URL, token))
+				}
+			}
+			return next(ctx, request)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeProjectedToken writes a token the way the kubelet updates projected
+// volumes: into a new directory, which the ..data link is swapped to.
+func writeProjectedToken(t *testing.T, dir, version, token string) {
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, version), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, version, "token"), []byte(token+"\n"), 0o600))
+	tmp := filepath.Join(dir, "..data_tmp")
+	require.NoError(t, os.Symlink(version, tmp))
+	require.NoError(t, os.Rename(tmp, filepath.Join(dir, "..data")))
+}
+
+func TestTokenFile(t *testing.T) {
+	dir := t.TempDir()
+	writeProjectedToken(t, dir, "v1", "first-token")
+	path := filepath.Join(dir, "token")
+	require.NoError(t, os.Symlink(filepath.Join("..data", "token"), path))
+
+	f, err := NewTokenFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "first-token", f.Token())
+
+	writeProjectedToken(t, dir, "v2", "second-rotated-token")
+	assert.Equal(t, "second-rotated-token", f.Token())
+	assert.True(t, f.issued("first-token"))
+	assert.True(t, f.issued("second-rotated-token"))
+	assert.False(t, f.issued("other-token"))
+	assert.False(t, f.issued(""))
+
+	// The last token is kept while the file can't be read.
+	require.NoError(t, os.Remove(filepath.Join(dir, "..data")))
+	assert.Equal(t, "second-rotated-token", f.Token())
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := NewTokenFile(filepath.Join(dir, "missing"))
+		assert.Error(t, err)
+		empty := filepath.Join(t.TempDir(), "token")
+		require.NoError(t, os.WriteFile(empty, []byte("\n"), 0o600))
+		_, err = NewTokenFile(empty)
+		assert.ErrorContains(t, err, "is empty")
+	})
+}
+
+func TestAPIKeyFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("file-token"), 0o600))
+	t.Setenv(grafanaURLEnvVar, "http://grafana:3000")
+	t.Setenv(grafanaAPIKeyFileEnvVar, path)
+
+	t.Setenv(grafanaAPIEnvVar, "")
+	_, apiKey := urlAndAPIKeyFromEnv()
+	assert.Equal(t, "file-token", apiKey)
+
+	t.Setenv(grafanaAPIEnvVar, "env-token")
+	_, apiKey = urlAndAPIKeyFromEnv()
+	assert.Equal(t, "env-token", apiKey)
+}
+
+func TestTokenFileMiddleware(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("old-token"), 0o600))
+	f, err := NewTokenFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, []byte("new-rotated-token"), 0o600))
+
+	var gotKey string
+	handler := TokenFileMiddleware(f)(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		gotKey = GrafanaConfigFromContext(ctx).APIKey
+		return mcp.NewToolResultText("ok"), nil
+	})
+
+	// A context created before the rotation uses the new token.
+	ctx := WithGrafanaConfig(context.Background(), GrafanaConfig{URL: "http://grafana:3000", APIKey: "old-token"})
+	_, err = handler(ctx, mcp.CallToolRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "new-rotated-token", gotKey)
+
+	// Tokens given by clients are kept.
+	ctx = WithGrafanaConfig(context.Background(), GrafanaConfig{URL: "http://grafana:3000", APIKey: "client-token"})
+	_, err = handler(ctx, mcp.CallToolRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "client-token", gotKey)
+}
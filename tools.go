@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
+	"math"
 	"reflect"
 
 	"github.com/invopop/jsonschema"
@@ -30,8 +32,56 @@ type Tool struct {
 // statement:
 //
 //	mcpgrafana.MustTool(name, description, toolHandler).Register(server)
+//
+// The tool is also registered under its deprecated aliases, if any were set
+// using SetToolAliases. Notes on the server's policy applying to the tool,
+// such as the truncation of large results, are added to its description.
 func (t *Tool) Register(mcp *server.MCPServer) {
-	mcp.AddTool(t.Tool, t.Handler)
+	tool := withCallArguments(withPolicyNotes(t.Tool))
+	mcp.AddTool(tool, t.Handler)
+	registry.record(tool, "")
+	t.registerAliases(mcp)
+}
+
+// withCallArguments returns a copy of the tool whose input schema declares
+// the optional arguments read by the middleware from all tool calls, such as
+// timeoutSeconds, if they are enabled. This lets clients discover them, and
+// strict clients accept them. It is applied when the tool is registered,
+// after the limits have been recorded.
+func withCallArguments(tool mcp.Tool) mcp.Tool {
+	limits := Limits()
+	arguments := map[string]any{}
+	if seconds, ok := limits["maxCallTimeoutSeconds"].(float64); ok && seconds >= 1 {
+		arguments[TimeoutArgument] = map[string]any{
+			"type":        "integer",
+			"minimum":     1,
+			"maximum":     int(seconds),
+			"description": fmt.Sprintf("Optionally, the timeout of this call in seconds, overriding the server's default, up to %d", int(seconds)),
+		}
+	}
+	if retries, ok := limits["maxCallRetries"].(int); ok && retries > 0 {
+		arguments[MaxRetriesArgument] = map[string]any{
+			"type":        "integer",
+			"minimum":     0,
+			"maximum":     retries,
+			"description": fmt.Sprintf("Optionally, how many times failed requests to Grafana made by this call are retried, overriding the server's default, up to %d", retries),
+		}
+	}
+	if len(arguments) == 0 {
+		return tool
+	}
+	properties := maps.Clone(tool.InputSchema.Properties)
+	if properties == nil {
+		properties = map[string]any{}
+	}
+	for name, schema := range arguments {
+		// Parameters of the tool itself take precedence.
+		if _, ok := properties[name]; !ok {
+			properties[name] = schema
+		}
+	}
+	tool.InputSchema.Properties = properties
+	return tool
 }
 
 // MustTool creates a new Tool from the given name, description, and toolHandler.
@@ -83,7 +133,7 @@ func ConvertTool[T any, R any](name, description string, toolHandler ToolHandler
 		return zero, nil, errors.New("tool handler second argument must be a struct")
 	}
 
-	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	call := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 
 		s, err := json.Marshal(request.Params.Arguments)
 		if err != nil {
@@ -175,6 +225,14 @@ func ConvertTool[T any, R any](name, description string, toolHandler ToolHandler
 
 		return mcp.NewToolResultText(string(jsonBytes)), nil
 	}
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ctx, meta := withResultMeta(ctx)
+		result, err := call(ctx, request)
+		if err == nil && result != nil {
+			meta.apply(result)
+		}
+		return result, err
+	}
 
 	jsonSchema := createJSONSchemaFromHandler(toolHandler)
 	properties := make(map[string]any, jsonSchema.Properties.Len())
@@ -226,3 +284,24 @@ var (
 		CommentMap:                 nil,
 	}
 )
+
+// intArgument returns the value of an optional integer argument of a tool
+// call which is handled by middleware rather than the tool, such as
+// timeoutSeconds.
+func intArgument(request mcp.CallToolRequest, name string) (int, bool, error) {
+	v, ok := request.GetArguments()[name]
+	if !ok || v == nil {
+		return 0, false, nil
+	}
+	switch v := v.(type) {
+	case float64:
+		if v >= 0 && v == math.Trunc(v) && v <= math.MaxInt32 {
+			return int(v), true, nil
+		}
+	case int:
+		if v >= 0 {
+			return v, true, nil
+		}
+	}
+	return 0, false, fmt.Errorf("%s must be a non-negative integer", name)
+}
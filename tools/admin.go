@@ -2,10 +2,16 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 
+	"github.com/grafana/grafana-openapi-client-go/client/get_current_org"
+	"github.com/grafana/grafana-openapi-client-go/client/orgs"
 	"github.com/grafana/grafana-openapi-client-go/client/teams"
 	"github.com/grafana/grafana-openapi-client-go/models"
 	mcpgrafana "github.com/grafana/mcp-grafana"
@@ -34,6 +40,83 @@ var ListTeams = mcpgrafana.MustTool(
 	listTeams,
 )
 
+type GetOrgQuotasParams struct {
+	OrgID int64 `json:"orgId,omitempty" jsonschema:"description=The ID of the organization. Defaults to the current organization. Other organizations require Grafana server admin permissions"`
+}
+
+// OrgQuota is a quota of an organization with its current usage.
+type OrgQuota struct {
+	// Target is the kind of resource limited by the quota, e.g. "dashboard"
+	// or "data_source".
+	Target string `json:"target"`
+	// Limit is the maximum number of resources, or -1 if unlimited.
+	Limit int64 `json:"limit"`
+	Used  int64 `json:"used"`
+	// Remaining is the number of resources which can still be created, or
+	// nil if unlimited.
+	Remaining *int64 `json:"remaining,omitempty"`
+	// Reached is true if no more resources can be created.
+	Reached bool `json:"reached"`
+}
+
+func newOrgQuotas(dtos []*models.QuotaDTO) []OrgQuota {
+	quotas := make([]OrgQuota, 0, len(dtos))
+	for _, q := range dtos {
+		quota := OrgQuota{Target: q.Target, Limit: q.Limit, Used: q.Used}
+		if q.Limit >= 0 {
+			remaining := max(0, q.Limit-q.Used)
+			quota.Remaining = &remaining
+			quota.Reached = remaining == 0
+		}
+		quotas = append(quotas, quota)
+	}
+	slices.SortFunc(quotas, func(a, b OrgQuota) int { return strings.Compare(a.Target, b.Target) })
+	return quotas
+}
+
+func getOrgQuotas(ctx context.Context, args GetOrgQuotasParams) ([]OrgQuota, error) {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	var dtos []*models.QuotaDTO
+	var err error
+	if args.OrgID != 0 {
+		var resp *orgs.GetOrgQuotaOK
+		if resp, err = c.Orgs.GetOrgQuota(args.OrgID); err == nil {
+			dtos = resp.Payload
+		}
+	} else {
+		var resp *get_current_org.GetCurrentOrgQuotaOK
+		if resp, err = c.GetCurrentOrg.GetCurrentOrgQuota(); err == nil {
+			dtos = resp.Payload
+		}
+	}
+	if err != nil {
+		// Grafana responds with 404 if quotas are disabled.
+		if err := classifyAPIError(err); errors.Is(err, errNotFound) {
+			return nil, fmt.Errorf("quotas are not enabled on this Grafana instance, so organizations have no quota limits: %w", err)
+		}
+		return nil, fmt.Errorf("get org quotas: %w", err)
+	}
+	return newOrgQuotas(dtos), nil
+}
+
+var GetOrgQuotas = mcpgrafana.MustTool(
+	"grafana_get_org_quotas",
+	"Get the quotas of a Grafana organization and its current usage of them, such as the number of dashboards, data sources, users and alert rules. Returns each quota's target, limit (-1 if unlimited), usage, the number of resources which can still be created and whether the quota is reached. Use it to explain failures to create resources, e.g. \"why can't I create another data source\". Requires organization admin permissions.",
+	getOrgQuotas,
+	mcp.WithTitleAnnotation("Get organization quotas"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// AddAdminTools registers the admin tools. Tools which mint tokens or change
+// permissions are only registered if write tools are enabled, see
+// mcpgrafana.WriteToolsEnabled.
 func AddAdminTools(mcp *server.MCPServer) {
 	ListTeams.Register(mcp)
+	GetOrgQuotas.Register(mcp)
+	ListPermissions.Register(mcp)
+	if mcpgrafana.WriteToolsEnabled() {
+		MintScopedToken.Register(mcp)
+		SetPermissions.Register(mcp)
+	}
 }
@@ -0,0 +1,95 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetOrgQuotas(t *testing.T) {
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/org/quotas": jsonBody(`[
+				{"org_id":1,"target":"user","limit":-1,"used":12},
+				{"org_id":1,"target":"data_source","limit":10,"used":10},
+				{"org_id":1,"target":"dashboard","limit":100,"used":42}
+			]`),
+		"/api/orgs/2/quotas": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Quotas not enabled"}`))
+		},
+	})
+	ctx := fakeGrafanaContext(server)
+
+	quotas, err := getOrgQuotas(ctx, GetOrgQuotasParams{})
+	require.NoError(t, err)
+	require.Len(t, quotas, 3)
+	assert.Equal(t, "dashboard", quotas[0].Target)
+	assert.Equal(t, int64(58), *quotas[0].Remaining)
+	assert.False(t, quotas[0].Reached)
+	assert.Equal(t, "data_source", quotas[1].Target)
+	assert.True(t, quotas[1].Reached)
+	assert.Equal(t, OrgQuota{Target: "user", Limit: -1, Used: 12}, quotas[2])
+
+	_, err = getOrgQuotas(ctx, GetOrgQuotasParams{OrgID: 2})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "quotas are not enabled")
+}
+
+func TestPermissions(t *testing.T) {
+	var updated string
+	ctx := newFakeGrafanaContext(t, fakeRoutes{
+		"POST /api/dashboards/uid/prod/permissions": func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			updated = string(body)
+			_, _ = w.Write([]byte(`{"message":"Dashboard permissions updated"}`))
+		},
+		"/api/dashboards/uid/prod/permissions": jsonBody(`[
+			{"role":"Viewer","permission":1,"permissionName":"View","inherited":true},
+			{"teamId":3,"team":"SRE","permission":4,"permissionName":"Admin"},
+			{"userId":7,"userLogin":"alice","permission":2,"permissionName":"Edit"}
+		]`),
+		"/api/folders/missing/permissions": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Folder not found"}`))
+		},
+	})
+
+	t.Run("list", func(t *testing.T) {
+		permissions, err := listPermissions(ctx, ListPermissionsParams{DashboardUID: "prod"})
+		require.NoError(t, err)
+		assert.Equal(t, []Permission{
+			{Permission: "View", Role: "Viewer", Inherited: true},
+			{Permission: "Admin", TeamID: 3, Team: "SRE"},
+			{Permission: "Edit", UserID: 7, UserLogin: "alice"},
+		}, permissions)
+
+		_, err = listPermissions(ctx, ListPermissionsParams{FolderUID: "missing"})
+		assert.ErrorIs(t, err, errNotFound)
+		_, err = listPermissions(ctx, ListPermissionsParams{})
+		assert.ErrorContains(t, err, "exactly one of")
+	})
+
+	t.Run("set", func(t *testing.T) {
+		_, err := setPermissions(ctx, SetPermissionsParams{DashboardUID: "prod", Permissions: []PermissionItem{
+			{Permission: "Admin", TeamID: 3},
+			{Permission: "Edit", UserID: 7},
+		}})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"items":[{"permission":4,"teamId":3},{"permission":2,"userId":7}]}`, updated)
+	})
+
+	t.Run("invalid permissions", func(t *testing.T) {
+		_, err := setPermissions(ctx, SetPermissionsParams{DashboardUID: "prod", Permissions: []PermissionItem{{Permission: "Owner", Role: "Viewer"}}})
+		assert.ErrorContains(t, err, "invalid permission")
+		_, err = setPermissions(ctx, SetPermissionsParams{DashboardUID: "prod", Permissions: []PermissionItem{{Permission: "View", Role: "Viewer", TeamID: 3}}})
+		assert.ErrorContains(t, err, "exactly one of role")
+		_, err = setPermissions(ctx, SetPermissionsParams{DashboardUID: "prod", Permissions: []PermissionItem{{Permission: "View", Role: "Admin"}}})
+		assert.ErrorContains(t, err, "invalid role")
+	})
+}
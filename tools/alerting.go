@@ -1,8 +1,11 @@
 package tools
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/grafana/grafana-openapi-client-go/client/provisioning"
 	"github.com/grafana/grafana-openapi-client-go/models"
@@ -15,12 +18,23 @@ import (
 const (
 	DefaultListAlertRulesLimit    = 100
 	DefaultListContactPointsLimit = 100
+
+	// alertRuleGroupsPageSize is the number of rule groups fetched per
+	// request when listing alert rules.
+	alertRuleGroupsPageSize = 100
 )
 
+// alertRuleStates are the states alert rules can be filtered by.
+var alertRuleStates = []string{"firing", "pending", "inactive", "recovering"}
+
 type ListAlertRulesParams struct {
 	Limit          int        `json:"limit,omitempty" jsonschema:"description=The maximum number of results to return. Default is 100."`
 	Page           int        `json:"page,omitempty" jsonschema:"description=The page number to return."`
 	LabelSelectors []Selector `json:"label_selectors,omitempty" jsonschema:"description=Optionally\\, a list of matchers to filter alert rules by labels"`
+	Unscoped       bool       `json:"unscoped,omitempty" jsonschema:"description=Set to true to ignore the server's default scope\\, e.g. a team label\\, and list all alert rules"`
+	FolderUID      string     `json:"folder_uid,omitempty" jsonschema:"description=Optionally\\, only list the alert rules of the folder with this UID"`
+	RuleGroup      string     `json:"rule_group,omitempty" jsonschema:"description=Optionally\\, only list the alert rules of the rule group with this name"`
+	States         []string   `json:"states,omitempty" jsonschema:"description=Optionally\\, only list the alert rules in these states: firing\\, pending\\, inactive or recovering"`
 }
 
 func (p ListAlertRulesParams) validate() error {
@@ -30,6 +44,11 @@ func (p ListAlertRulesParams) validate() error {
 	if p.Page < 0 {
 		return fmt.Errorf("invalid page: %d, must be greater than 0", p.Page)
 	}
+	for _, state := range p.States {
+		if !slices.Contains(alertRuleStates, state) {
+			return fmt.Errorf("invalid state %q: must be one of %v", state, alertRuleStates)
+		}
+	}
 
 	return nil
 }
@@ -52,27 +71,95 @@ func listAlertRules(ctx context.Context, args ListAlertRulesParams) ([]alertRule
 	if err != nil {
 		return nil, fmt.Errorf("list alert rules: %w", err)
 	}
-	response, err := c.GetRules(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("list alert rules: %w", err)
+
+	selectors := args.LabelSelectors
+	if !args.Unscoped {
+		selectors = scopeSelectors(mcpgrafana.GrafanaConfigFromContext(ctx).Scope, selectors)
 	}
 
+	// Rule groups are fetched page by page, filtered as they come, until the
+	// requested page of rules is complete, so that only the rules of this
+	// page are held in memory. The alert instances of rules aren't needed
+	// for their summaries and are left out.
+	limit := cmp.Or(args.Limit, DefaultListAlertRulesLimit)
+	start := (max(args.Page, 1) - 1) * limit
+	query := rulesQuery{
+		FolderUID:     args.FolderUID,
+		RuleGroup:     args.RuleGroup,
+		States:        args.States,
+		GroupLimit:    alertRuleGroupsPageSize,
+		ExcludeAlerts: true,
+	}
 	alertRules := []alertingRule{}
-	for _, group := range response.Data.RuleGroups {
-		alertRules = append(alertRules, group.Rules...)
+	matched, more := 0, false
+	for !more {
+		response, err := c.GetRules(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("list alert rules: %w", err)
+		}
+		for _, group := range response.Data.RuleGroups {
+			// The filters are applied again for Grafana versions which
+			// ignore them.
+			if (args.FolderUID != "" && group.FolderUID != args.FolderUID) || (args.RuleGroup != "" && group.Name != args.RuleGroup) {
+				continue
+			}
+			rules, err := filterAlertRules(filterAlertRuleStates(group.Rules, args.States), selectors)
+			if err != nil {
+				return nil, fmt.Errorf("list alert rules: %w", err)
+			}
+			for _, rule := range rules {
+				switch {
+				case matched < start:
+				case len(alertRules) < limit:
+					alertRules = append(alertRules, rule)
+				default:
+					more = true
+				}
+				matched++
+			}
+		}
+		if response.Data.NextToken == "" {
+			break
+		}
+		query.GroupNextToken = response.Data.NextToken
 	}
-
-	alertRules, err = filterAlertRules(alertRules, args.LabelSelectors)
-	if err != nil {
-		return nil, fmt.Errorf("list alert rules: %w", err)
+	if more {
+		mcpgrafana.SetResultNote(ctx, "alertRulesNextPage", fmt.Sprintf("More alert rules match; call again with page %d to list them.", max(args.Page, 1)+1))
 	}
 
-	alertRules, err = applyPagination(alertRules, args.Limit, args.Page)
-	if err != nil {
-		return nil, fmt.Errorf("list alert rules: %w", err)
+	return summarizeAlertRules(alertRules), nil
+}
+
+// filterAlertRuleStates returns the rules in one of the given states, or all
+// of them if no states are given.
+func filterAlertRuleStates(rules []alertingRule, states []string) []alertingRule {
+	if len(states) == 0 {
+		return rules
 	}
+	// Rules in the normal state are reported as inactive.
+	return slices.DeleteFunc(slices.Clone(rules), func(r alertingRule) bool { return !slices.Contains(states, r.State) })
+}
 
-	return summarizeAlertRules(alertRules), nil
+// scopeSelectors adds a selector matching the labels of the scope to the
+// given selectors, except for labels they already select on.
+func scopeSelectors(scope mcpgrafana.Scope, selectors []Selector) []Selector {
+	selected := map[string]bool{}
+	for _, s := range selectors {
+		for _, f := range s.Filters {
+			selected[f.Name] = true
+		}
+	}
+	var scoped Selector
+	for name, value := range scope.Labels {
+		if !selected[name] {
+			scoped.Filters = append(scoped.Filters, LabelMatcher{Name: name, Value: value, Type: "="})
+		}
+	}
+	if len(scoped.Filters) == 0 {
+		return selectors
+	}
+	slices.SortFunc(scoped.Filters, func(a, b LabelMatcher) int { return strings.Compare(a.Name, b.Name) })
+	return append(slices.Clone(selectors), scoped)
 }
 
 // filterAlertRules filters a list of alert rules based on label selectors
@@ -123,31 +210,9 @@ func summarizeAlertRules(alertRules []alertingRule) []alertRuleSummary {
 	return result
 }
 
-// applyPagination applies pagination to the list of alert rules.
-// It doesn't sort the items and relies on the order returned by the API.
-func applyPagination(items []alertingRule, limit, page int) ([]alertingRule, error) {
-	if limit == 0 {
-		limit = DefaultListAlertRulesLimit
-	}
-	if page == 0 {
-		page = 1
-	}
-
-	start := (page - 1) * limit
-	end := start + limit
-
-	if start >= len(items) {
-		return nil, nil
-	} else if end > len(items) {
-		return items[start:], nil
-	}
-
-	return items[start:end], nil
-}
-
 var ListAlertRules = mcpgrafana.MustTool(
 	"grafana_list_alert_rules",
-	"Lists Grafana alert rules, returning a summary including UID, title, current state (e.g., 'pending', 'firing', 'inactive'), and labels. Supports filtering by labels using selectors and pagination. Example label selector: `[{'name': 'severity', 'type': '=', 'value': 'critical'}]`. Inactive state means the alert state is normal, not firing",
+	"Lists Grafana alert rules, returning a summary including UID, title, current state (e.g., 'pending', 'firing', 'inactive'), and labels. Supports filtering by folder, rule group, state and labels using selectors, and pagination; a note tells when more rules are on the next page. Example label selector: `[{'name': 'severity', 'type': '=', 'value': 'critical'}]`. Inactive state means the alert state is normal, not firing. If the server has a default scope, e.g. a team label, only matching rules are returned unless `unscoped` is set",
 	listAlertRules,
 	mcp.WithTitleAnnotation("List alert rules"),
 	mcp.WithIdempotentHintAnnotation(true),
@@ -264,8 +329,36 @@ var ListContactPoints = mcpgrafana.MustTool(
 	mcp.WithReadOnlyHintAnnotation(true),
 )
 
+// AddAlertingTools registers the alerting tools. Tools which modify the
+// alerting configuration are only registered if write tools are enabled, see
+// mcpgrafana.WriteToolsEnabled.
 func AddAlertingTools(mcp *server.MCPServer) {
 	ListAlertRules.Register(mcp)
 	GetAlertRuleByUID.Register(mcp)
 	ListContactPoints.Register(mcp)
+	DiffAlertRule.Register(mcp)
+	ExportAlertingBundle.Register(mcp)
+	GenerateGoldenSignalAlertRules.Register(mcp)
+	ListSilences.Register(mcp)
+	ListMuteTimings.Register(mcp)
+	GetAlertStateHistory.Register(mcp)
+	ListAlertGroups.Register(mcp)
+	GetAlertRulePanel.Register(mcp)
+	GetServiceHealth.Register(mcp)
+	if mcpgrafana.WriteToolsEnabled() {
+		ImportAlertingBundle.Register(mcp)
+		CreateAlertRule.Register(mcp)
+		UpdateAlertRule.Register(mcp)
+		DeleteAlertRule.Register(mcp)
+		PauseAlertRule.Register(mcp)
+		CreateSilence.Register(mcp)
+		DeleteSilence.Register(mcp)
+		CreateMuteTiming.Register(mcp)
+		DeleteMuteTiming.Register(mcp)
+		CreateContactPoint.Register(mcp)
+		UpdateContactPoint.Register(mcp)
+		DeleteContactPoint.Register(mcp)
+		TestContactPoint.Register(mcp)
+		TestContactPoints.Register(mcp)
+	}
 }
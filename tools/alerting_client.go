@@ -1,12 +1,14 @@
 package tools
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -26,6 +28,13 @@ type alertingClient struct {
 	idToken     string
 	apiKey      string
 	httpClient  *http.Client
+
+	// orgID is the Grafana organization requests are made in, or 0 for the
+	// default organization of the credentials.
+	orgID int64
+	// disableProvenance makes resources created or updated through the
+	// provisioning API editable in the Grafana UI.
+	disableProvenance bool
 }
 
 func newAlertingClientFromContext(ctx context.Context) (*alertingClient, error) {
@@ -41,6 +50,7 @@ func newAlertingClientFromContext(ctx context.Context) (*alertingClient, error)
 		accessToken: cfg.AccessToken,
 		idToken:     cfg.IDToken,
 		apiKey:      cfg.APIKey,
+		orgID:       cfg.OrgID,
 		httpClient: &http.Client{
 			Timeout: defaultTimeout,
 		},
@@ -53,20 +63,37 @@ func newAlertingClientFromContext(ctx context.Context) (*alertingClient, error)
 			return nil, fmt.Errorf("failed to create custom transport: %w", err)
 		}
 	}
+	client.httpClient.Transport = cfg.Retry.RoundTripper(mcpgrafana.WithCompression(mcpgrafana.WithExtraHeaders(client.httpClient.Transport, cfg.ExtraHeaders)))
 
 	return client, nil
 }
 
 func (c *alertingClient) makeRequest(ctx context.Context, path string) (*http.Response, error) {
-	p := c.baseURL.JoinPath(path).String()
+	return c.doRequest(ctx, http.MethodGet, path, nil, nil)
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p, nil)
+// doRequest makes a request to the Grafana API with the given method, path,
+// query parameters and body, returning an error for non-2xx responses.
+func (c *alertingClient) doRequest(ctx context.Context, method, path string, params url.Values, body io.Reader) (*http.Response, error) {
+	u := c.baseURL.JoinPath(path)
+	if len(params) > 0 {
+		u.RawQuery = params.Encode()
+	}
+	p := u.String()
+
+	req, err := http.NewRequestWithContext(ctx, method, p, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request to %s: %w", p, err)
 	}
 
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
+	if c.orgID != 0 {
+		req.Header.Set(mcpgrafana.OrgIDHeader, strconv.FormatInt(c.orgID, 10))
+	}
+	if c.disableProvenance && method != http.MethodGet {
+		req.Header.Set("X-Disable-Provenance", "true")
+	}
 
 	// If accessToken is set we use that first and fall back to normal Authorization.
 	if c.accessToken != "" && c.idToken != "" {
@@ -80,25 +107,113 @@ func (c *alertingClient) makeRequest(ctx context.Context, path string) (*http.Re
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request to %s: %w", p, err)
 	}
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
 		resp.Body.Close()
-		return nil, fmt.Errorf("Grafana API returned status code %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, &alertingAPIError{statusCode: resp.StatusCode, body: string(bodyBytes)}
 	}
 
 	return resp, nil
 }
 
-func (c *alertingClient) GetRules(ctx context.Context) (*rulesResponse, error) {
-	resp, err := c.makeRequest(ctx, rulesEndpointPath)
+// alertingAPIError is returned for non-2xx responses of the Grafana API.
+type alertingAPIError struct {
+	statusCode int
+	body       string
+}
+
+func (e *alertingAPIError) Error() string {
+	return fmt.Sprintf("Grafana API returned status code %d: %s", e.statusCode, e.body)
+}
+
+// IsCode returns true if the response had the given status code, like the
+// errors of the Grafana OpenAPI client.
+func (e *alertingAPIError) IsCode(code int) bool {
+	return e.statusCode == code
+}
+
+// getJSON makes a GET request to the given path with the given query
+// parameters and decodes the JSON response into out.
+func (c *alertingClient) getJSON(ctx context.Context, path string, params url.Values, out any) error {
+	resp, err := c.doRequest(ctx, http.MethodGet, path, params, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := decodeJSONResponse(resp.Body, out); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %w", path, err)
+	}
+	return nil
+}
+
+// sendJSON makes a request with the given method to the given path with body
+// encoded as JSON, decoding the JSON response into out if it is not nil.
+func (c *alertingClient) sendJSON(ctx context.Context, method, path string, body, out any) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request body for %s: %w", path, err)
+	}
+	resp, err := c.doRequest(ctx, method, path, nil, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	if err := decodeJSONResponse(resp.Body, out); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %w", path, err)
+	}
+	return nil
+}
+
+// rulesQuery filters and paginates the rule groups returned by the rules
+// API. Grafana versions without support for a parameter ignore it.
+type rulesQuery struct {
+	FolderUID string
+	RuleGroup string
+	// States are the states of the rules to return, e.g. firing.
+	States []string
+	// GroupLimit is the maximum number of rule groups returned, or 0 for
+	// all of them. The next groups are fetched with GroupNextToken.
+	GroupLimit     int
+	GroupNextToken string
+	// ExcludeAlerts leaves out the alert instances of the rules.
+	ExcludeAlerts bool
+}
+
+func (q rulesQuery) values() url.Values {
+	params := url.Values{}
+	if q.FolderUID != "" {
+		params.Set("folder_uid", q.FolderUID)
+	}
+	if q.RuleGroup != "" {
+		params.Set("rule_group", q.RuleGroup)
+	}
+	for _, s := range q.States {
+		params.Add("state", s)
+	}
+	if q.GroupLimit > 0 {
+		params.Set("group_limit", strconv.Itoa(q.GroupLimit))
+	}
+	if q.GroupNextToken != "" {
+		params.Set("group_next_token", q.GroupNextToken)
+	}
+	if q.ExcludeAlerts {
+		params.Set("limit_alerts", "0")
+	}
+	return params
+}
+
+func (c *alertingClient) GetRules(ctx context.Context, q rulesQuery) (*rulesResponse, error) {
+	resp, err := c.doRequest(ctx, http.MethodGet, rulesEndpointPath, q.values(), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get alert rules from Grafana API: %w", err)
 	}
 	defer resp.Body.Close()
 
 	var rulesResponse rulesResponse
-	decoder := json.NewDecoder(resp.Body)
-	if err := decoder.Decode(&rulesResponse); err != nil {
+	if err := decodeJSONResponse(resp.Body, &rulesResponse); err != nil {
 		return nil, fmt.Errorf("failed to decode rules response from %s: %w", rulesEndpointPath, err)
 	}
 
@@ -68,7 +68,7 @@ func TestAlertingClient_GetRules(t *testing.T) {
 	})
 	defer server.Close()
 
-	rules, err := client.GetRules(context.Background())
+	rules, err := client.GetRules(context.Background(), rulesQuery{})
 	require.NoError(t, err)
 	require.NotNil(t, rules)
 	require.ElementsMatch(t, rules.Data.RuleGroups, []ruleGroup{fakeruleGroup})
@@ -83,7 +83,7 @@ func TestAlertingClient_GetRules_Error(t *testing.T) {
 		})
 		defer server.Close()
 
-		rules, err := client.GetRules(context.Background())
+		rules, err := client.GetRules(context.Background(), rulesQuery{})
 		require.Error(t, err)
 		require.Nil(t, rules)
 		require.ErrorContains(t, err, "Grafana API returned status code 500: internal server error")
@@ -93,7 +93,7 @@ func TestAlertingClient_GetRules_Error(t *testing.T) {
 		server, client := setupMockServer(func(w http.ResponseWriter, r *http.Request) {})
 		server.Close()
 
-		rules, err := client.GetRules(context.Background())
+		rules, err := client.GetRules(context.Background(), rulesQuery{})
 
 		require.Error(t, err)
 		require.Nil(t, rules)
@@ -115,3 +115,16 @@ func TestNewAlertingClientFromContext(t *testing.T) {
 	require.Equal(t, "test-api-key", client.apiKey)
 	require.NotNil(t, client.httpClient)
 }
+
+func TestAlertingClient_OrgID(t *testing.T) {
+	server, client := setupMockServer(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "2", r.Header.Get(mcpgrafana.OrgIDHeader))
+		err := json.NewEncoder(w).Encode(mockrulesResponse())
+		require.NoError(t, err)
+	})
+	defer server.Close()
+	client.orgID = 2
+
+	_, err := client.GetRules(context.Background(), rulesQuery{})
+	require.NoError(t, err)
+}
@@ -0,0 +1,229 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const contactPointsPath = "/api/v1/provisioning/contact-points"
+
+// ContactPoint is an integration of a contact point, as managed by the
+// provisioning API. Integrations with the same name form a single contact
+// point, which notifies through all of them. Secure settings, such as
+// tokens, are redacted.
+type ContactPoint struct {
+	UID                   string         `json:"uid"`
+	Name                  string         `json:"name"`
+	Type                  string         `json:"type"`
+	Settings              map[string]any `json:"settings"`
+	DisableResolveMessage bool           `json:"disableResolveMessage"`
+	Provenance            string         `json:"provenance,omitempty"`
+}
+
+// getContactPoint returns the integration with a UID. The provisioning API
+// can only list contact points, so they are listed and searched.
+func getContactPoint(ctx context.Context, c *alertingClient, uid string) (*ContactPoint, error) {
+	var contactPoints []ContactPoint
+	if err := c.getJSON(ctx, contactPointsPath, nil, &contactPoints); err != nil {
+		return nil, err
+	}
+	for _, cp := range contactPoints {
+		if cp.UID == uid {
+			return &cp, nil
+		}
+	}
+	return nil, fmt.Errorf("contact point %s: %w", uid, errNotFound)
+}
+
+type CreateContactPointParams struct {
+	Name                  string         `json:"name" jsonschema:"required,description=The name of the contact point. Integrations with the same name form a single contact point"`
+	Type                  string         `json:"type" jsonschema:"required,description=The type of the integration\\, e.g. email\\, slack\\, webhook\\, pagerduty\\, opsgenie\\, teams or oncall"`
+	Settings              map[string]any `json:"settings" jsonschema:"required,description=The settings of the integration\\, which depend on its type\\, e.g. addresses for email\\, url or token and recipient for slack\\, url for webhook and integrationKey for pagerduty"`
+	DisableResolveMessage bool           `json:"disableResolveMessage,omitempty" jsonschema:"description=Whether to not notify when alerts are resolved"`
+	DisableProvenance     bool           `json:"disableProvenance,omitempty" jsonschema:"description=If true\\, the contact point remains editable in the Grafana UI. Otherwise it is marked as provisioned"`
+}
+
+func createContactPoint(ctx context.Context, args CreateContactPointParams) (*ContactPoint, error) {
+	if args.Name == "" || args.Type == "" || args.Settings == nil {
+		return nil, fmt.Errorf("create contact point: name, type and settings are required")
+	}
+	c, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create contact point %s: %w", args.Name, err)
+	}
+	c.disableProvenance = args.DisableProvenance
+	body := ContactPoint{Name: args.Name, Type: args.Type, Settings: args.Settings, DisableResolveMessage: args.DisableResolveMessage}
+	var created ContactPoint
+	if err := c.sendJSON(ctx, http.MethodPost, contactPointsPath, body, &created); err != nil {
+		return nil, fmt.Errorf("create contact point %s: %w", args.Name, err)
+	}
+	return &created, nil
+}
+
+var CreateContactPoint = mcpgrafana.MustTool(
+	"grafana_create_contact_point",
+	"Create a notification contact point of Grafana Alerting, or add an integration to an existing one by giving its name. Check the settings first with `grafana_test_contact_point`, which sends a test notification without saving anything. Notification policies must route alerts to the contact point for it to be notified.",
+	createContactPoint,
+	mcp.WithTitleAnnotation("Create contact point"),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithIdempotentHintAnnotation(false),
+)
+
+type UpdateContactPointParams struct {
+	UID                   string         `json:"uid" jsonschema:"required,description=The UID of the integration to update\\, as listed by grafana_list_contact_points"`
+	Name                  string         `json:"name,omitempty" jsonschema:"description=Optionally\\, the new name of the contact point"`
+	Settings              map[string]any `json:"settings,omitempty" jsonschema:"description=Optionally\\, the settings to change. Settings which are not given are kept\\, including secure ones"`
+	DisableResolveMessage *bool          `json:"disableResolveMessage,omitempty" jsonschema:"description=Optionally\\, whether to not notify when alerts are resolved"`
+	DisableProvenance     bool           `json:"disableProvenance,omitempty" jsonschema:"description=If true\\, the contact point remains editable in the Grafana UI. Otherwise it is marked as provisioned"`
+}
+
+func updateContactPoint(ctx context.Context, args UpdateContactPointParams) (*ContactPoint, error) {
+	if args.UID == "" {
+		return nil, fmt.Errorf("update contact point: uid is required")
+	}
+	c, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("update contact point %s: %w", args.UID, err)
+	}
+	c.disableProvenance = args.DisableProvenance
+	cp, err := getContactPoint(ctx, c, args.UID)
+	if err != nil {
+		return nil, fmt.Errorf("update contact point: %w", err)
+	}
+	// Secure settings are returned redacted, and Grafana keeps the stored
+	// value of settings sent back redacted.
+	settings := maps.Clone(cp.Settings)
+	if settings == nil {
+		settings = map[string]any{}
+	}
+	maps.Copy(settings, args.Settings)
+	cp.Settings = settings
+	cp.Name = cmp.Or(args.Name, cp.Name)
+	if args.DisableResolveMessage != nil {
+		cp.DisableResolveMessage = *args.DisableResolveMessage
+	}
+	cp.Provenance = ""
+	if err := c.sendJSON(ctx, http.MethodPut, contactPointsPath+"/"+url.PathEscape(args.UID), cp, nil); err != nil {
+		return nil, fmt.Errorf("update contact point %s: %w", args.UID, err)
+	}
+	return getContactPoint(ctx, c, args.UID)
+}
+
+var UpdateContactPoint = mcpgrafana.MustTool(
+	"grafana_update_contact_point",
+	"Update an integration of a notification contact point of Grafana Alerting: rename the contact point, or change some of its settings, such as email addresses or a Slack channel. Settings which are not given are kept, including secure settings such as tokens, which are returned redacted. Returns the integration after the update.",
+	updateContactPoint,
+	mcp.WithTitleAnnotation("Update contact point"),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithIdempotentHintAnnotation(true),
+)
+
+type DeleteContactPointParams struct {
+	UID               string `json:"uid" jsonschema:"required,description=The UID of the integration to delete\\, as listed by grafana_list_contact_points"`
+	ConfirmationToken string `json:"confirmationToken,omitempty" jsonschema:"description=The token returned by a previous call for this integration. Without it nothing is deleted and the impact of the deletion is returned with a token"`
+}
+
+// DeleteContactPointResult describes an integration which was deleted, or
+// would be deleted if the deletion was confirmed.
+type DeleteContactPointResult struct {
+	Deleted      bool          `json:"deleted"`
+	ContactPoint *ContactPoint `json:"contactPoint"`
+	Confirmation *Confirmation `json:"confirmation,omitempty"`
+}
+
+func deleteContactPoint(ctx context.Context, args DeleteContactPointParams) (*DeleteContactPointResult, error) {
+	if args.UID == "" {
+		return nil, fmt.Errorf("delete contact point: uid is required")
+	}
+	c, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("delete contact point %s: %w", args.UID, err)
+	}
+	cp, err := getContactPoint(ctx, c, args.UID)
+	if err != nil {
+		return nil, fmt.Errorf("delete contact point: %w", err)
+	}
+	if args.ConfirmationToken == "" {
+		impact := []string{
+			fmt.Sprintf("Deletes the %s integration of the contact point %q with its settings, including secure ones which can't be recovered.", cp.Type, cp.Name),
+			"Grafana refuses to delete the last integration of a contact point used by notification policies or alert rules.",
+		}
+		return &DeleteContactPointResult{
+			ContactPoint: cp,
+			Confirmation: newConfirmation(ctx, deleteContactPointAction, impact, cp.UID, cp.Name, cp.Type),
+		}, nil
+	}
+	if err := checkConfirmation(ctx, args.ConfirmationToken, deleteContactPointAction, cp.UID, cp.Name, cp.Type); err != nil {
+		return nil, err
+	}
+	resp, err := c.doRequest(ctx, http.MethodDelete, contactPointsPath+"/"+url.PathEscape(args.UID), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("delete contact point %s: %w", args.UID, err)
+	}
+	resp.Body.Close()
+	return &DeleteContactPointResult{Deleted: true, ContactPoint: cp}, nil
+}
+
+var DeleteContactPoint = mcpgrafana.MustTool(
+	"grafana_delete_contact_point",
+	"Delete an integration of a notification contact point of Grafana Alerting; the contact point is gone once its last integration is deleted. "+deleteContactPointAction.description(),
+	deleteContactPoint,
+	mcp.WithTitleAnnotation("Delete contact point"),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithIdempotentHintAnnotation(true),
+)
+
+type TestContactPointParams struct {
+	UID      string         `json:"uid,omitempty" jsonschema:"description=The UID of an existing integration to test. Either uid or type and settings are required"`
+	Type     string         `json:"type,omitempty" jsonschema:"description=The type of an integration which is not saved yet\\, e.g. slack"`
+	Settings map[string]any `json:"settings,omitempty" jsonschema:"description=The settings of an integration which is not saved yet\\, as for grafana_create_contact_point"`
+}
+
+func testContactPoint(ctx context.Context, args TestContactPointParams) (*contactPointTestResult, error) {
+	c, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("test contact point: %w", err)
+	}
+	var name string
+	var integration receiverIntegration
+	switch {
+	case args.UID != "" && args.Type != "":
+		return nil, fmt.Errorf("test contact point: give either uid or type and settings")
+	case args.UID != "":
+		cp, err := getContactPoint(ctx, c, args.UID)
+		if err != nil {
+			return nil, fmt.Errorf("test contact point: %w", err)
+		}
+		// Grafana fills in the secure settings of integrations tested with
+		// their UID.
+		name = cp.Name
+		integration = receiverIntegration{UID: cp.UID, Name: cp.Name, Type: cp.Type, Settings: cp.Settings}
+	case args.Type != "" && args.Settings != nil:
+		name = "test"
+		integration = receiverIntegration{Name: name, Type: args.Type, Settings: args.Settings}
+	default:
+		return nil, fmt.Errorf("test contact point: uid or type and settings are required")
+	}
+	result := &contactPointTestResult{ContactPoint: name, UID: integration.UID, Type: integration.Type, Status: contactPointTestOK}
+	if msg := testIntegration(ctx, c, name, integration); msg != "" {
+		result.Status = contactPointTestFailed
+		result.Error = msg
+	}
+	return result, nil
+}
+
+var TestContactPoint = mcpgrafana.MustTool(
+	"grafana_test_contact_point",
+	"Send a test notification through an existing integration of a contact point, by UID, or through an integration which is not saved yet, by type and settings, e.g. to check the settings of a new Slack integration before creating it. Returns whether Grafana could deliver it and its error otherwise. Since this notifies the people behind the contact point, only send after confirmation from the user. To check many contact points at once, use `grafana_test_contact_points`.",
+	testContactPoint,
+	mcp.WithTitleAnnotation("Send a test notification"),
+	mcp.WithDestructiveHintAnnotation(false),
+)
@@ -0,0 +1,124 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+func TestContactPointTools(t *testing.T) {
+	contactPoints := map[string]map[string]any{
+		"cp1": {"uid": "cp1", "name": "ops", "type": "slack", "settings": map[string]any{"recipient": "#ops", "token": "[REDACTED]"}, "provenance": "api"},
+	}
+	var header http.Header
+	var tested map[string]any
+	ctx := newFakeGrafanaContext(t, fakeRoutes{
+		"GET /api/v1/provisioning/contact-points": func(w http.ResponseWriter, r *http.Request) {
+			list := []any{}
+			for _, cp := range contactPoints {
+				list = append(list, cp)
+			}
+			_ = json.NewEncoder(w).Encode(list)
+		},
+		"POST /api/v1/provisioning/contact-points": func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]any
+			if !decodeJSONBody(t, w, r, &body) {
+				return
+			}
+			header = r.Header
+			body["uid"] = "cp2"
+			contactPoints["cp2"] = body
+			_ = json.NewEncoder(w).Encode(body)
+		},
+		"PUT /api/v1/provisioning/contact-points/cp1": func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]any
+			if !decodeJSONBody(t, w, r, &body) {
+				return
+			}
+			contactPoints["cp1"] = body
+			w.WriteHeader(http.StatusAccepted)
+		},
+		"DELETE /api/v1/provisioning/contact-points/cp1": func(w http.ResponseWriter, r *http.Request) {
+			delete(contactPoints, "cp1")
+			w.WriteHeader(http.StatusAccepted)
+		},
+		"/api/alertmanager/grafana/config/api/v1/receivers/test": func(w http.ResponseWriter, r *http.Request) {
+			if !decodeJSONBody(t, w, r, &tested) {
+				return
+			}
+			_, _ = w.Write([]byte(`{"receivers": [{"grafana_managed_receiver_configs": [{"status": "failed", "error": "invalid webhook url"}]}]}`))
+		},
+	})
+
+	t.Run("create", func(t *testing.T) {
+		created, err := createContactPoint(ctx, CreateContactPointParams{
+			Name:              "ops",
+			Type:              "email",
+			Settings:          map[string]any{"addresses": "ops@example.com"},
+			DisableProvenance: true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "cp2", created.UID)
+		assert.Equal(t, "email", created.Type)
+		assert.Equal(t, "true", header.Get("X-Disable-Provenance"))
+
+		_, err = createContactPoint(ctx, CreateContactPointParams{Name: "ops", Type: "email"})
+		assert.ErrorContains(t, err, "are required")
+	})
+
+	t.Run("update", func(t *testing.T) {
+		disable := true
+		updated, err := updateContactPoint(ctx, UpdateContactPointParams{
+			UID:                   "cp1",
+			Settings:              map[string]any{"recipient": "#alerts"},
+			DisableResolveMessage: &disable,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "ops", updated.Name)
+		assert.True(t, updated.DisableResolveMessage)
+		assert.Equal(t, map[string]any{"recipient": "#alerts", "token": "[REDACTED]"}, updated.Settings, "keeps the other settings")
+		assert.NotContains(t, contactPoints["cp1"], "provenance")
+
+		_, err = updateContactPoint(ctx, UpdateContactPointParams{UID: "missing"})
+		assert.ErrorIs(t, err, errNotFound)
+	})
+
+	t.Run("test", func(t *testing.T) {
+		result, err := testContactPoint(ctx, TestContactPointParams{Type: "webhook", Settings: map[string]any{"url": "nope"}})
+		require.NoError(t, err)
+		assert.Equal(t, contactPointTestFailed, result.Status)
+		assert.Equal(t, "invalid webhook url", result.Error)
+		receivers := tested["receivers"].([]any)
+		integration := receivers[0].(map[string]any)["grafana_managed_receiver_configs"].([]any)[0].(map[string]any)
+		assert.Equal(t, "webhook", integration["type"])
+
+		_, err = testContactPoint(ctx, TestContactPointParams{UID: "cp1", Type: "webhook"})
+		assert.ErrorContains(t, err, "either uid or type")
+		_, err = testContactPoint(ctx, TestContactPointParams{Type: "webhook"})
+		assert.ErrorContains(t, err, "are required")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		result, err := deleteContactPoint(ctx, DeleteContactPointParams{UID: "cp1"})
+		require.NoError(t, err)
+		assert.False(t, result.Deleted)
+		require.NotNil(t, result.Confirmation)
+		assert.Contains(t, contactPoints, "cp1")
+
+		_, err = deleteContactPoint(ctx, DeleteContactPointParams{UID: "cp1", ConfirmationToken: "1.abc"})
+		assert.ErrorIs(t, err, mcpgrafana.ErrInvalidConfirmationToken)
+
+		result, err = deleteContactPoint(ctx, DeleteContactPointParams{UID: "cp1", ConfirmationToken: result.Confirmation.Token})
+		require.NoError(t, err)
+		assert.True(t, result.Deleted)
+		assert.NotContains(t, contactPoints, "cp1")
+	})
+}
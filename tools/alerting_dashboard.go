@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/common/model"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// Annotations linking an alert rule to a dashboard panel.
+const (
+	dashboardUIDAnnotation = "__dashboardUid__"
+	panelIDAnnotation      = "__panelId__"
+)
+
+type GetAlertRulePanelParams struct {
+	UID    string            `json:"uid" jsonschema:"required,description=The UID of the alert rule"`
+	Values map[string]string `json:"values,omitempty" jsonschema:"description=Optionally\\, values of the dashboard's variables to use in the panel queries instead of their current values\\, by variable name\\, e.g. the labels of a firing alert instance. Separate multiple values with commas"`
+}
+
+// AlertRulePanel is the dashboard panel an alert rule is linked to, with
+// the queries of the panel and of the rule.
+type AlertRulePanel struct {
+	RuleUID        string `json:"ruleUid"`
+	RuleTitle      string `json:"ruleTitle"`
+	DashboardUID   string `json:"dashboardUid"`
+	DashboardTitle string `json:"dashboardTitle"`
+	PanelID        int    `json:"panelId"`
+	PanelTitle     string `json:"panelTitle"`
+	PanelType      string `json:"panelType,omitempty"`
+	URL            string `json:"url,omitempty"`
+	// Variables are the values of the dashboard's variables used in the
+	// panel queries, by name.
+	Variables    map[string][]string `json:"variables"`
+	PanelQueries []PanelTarget       `json:"panelQueries"`
+	RuleQueries  []AlertRuleQuery    `json:"ruleQueries"`
+}
+
+func getAlertRulePanel(ctx context.Context, args GetAlertRulePanelParams) (*AlertRulePanel, error) {
+	rule, err := getAlertRuleByUID(ctx, GetAlertRuleByUIDParams{UID: args.UID})
+	if err != nil {
+		return nil, classifyAPIError(err)
+	}
+	dashboardUID := rule.Annotations[dashboardUIDAnnotation]
+	if dashboardUID == "" {
+		return nil, fmt.Errorf("alert rule %s is not linked to a dashboard: it has no %s annotation", args.UID, dashboardUIDAnnotation)
+	}
+	panelID, err := strconv.Atoi(rule.Annotations[panelIDAnnotation])
+	if err != nil {
+		return nil, fmt.Errorf("alert rule %s is linked to dashboard %s but not to a panel: use grafana_get_dashboard_summary to find its panels", args.UID, dashboardUID)
+	}
+
+	dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: dashboardUID})
+	if err != nil {
+		return nil, fmt.Errorf("get dashboard %s of alert rule %s: %w", dashboardUID, args.UID, classifyAPIError(err))
+	}
+	db, ok := dashboard.Dashboard.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("dashboard is not a JSON object")
+	}
+	panels, _ := db["panels"].([]any)
+	panel, ok := findPanel(panels, panelID)
+	if !ok {
+		return nil, fmt.Errorf("panel %d of alert rule %s not found in dashboard %s, which may have changed since the rule was linked", panelID, args.UID, dashboardUID)
+	}
+
+	// The variables are resolved over the default time range of the
+	// dashboard, as when opening the panel.
+	timeRange, _ := db["time"].(map[string]any)
+	dashboardFrom, _ := timeRange["from"].(string)
+	dashboardTo, _ := timeRange["to"].(string)
+	from, err := parseTime(cmp.Or(dashboardFrom, "now-1h"))
+	if err != nil {
+		return nil, fmt.Errorf("parsing the time range of dashboard %s: %w", dashboardUID, err)
+	}
+	to, err := parseTime(cmp.Or(dashboardTo, "now"))
+	if err != nil {
+		return nil, fmt.Errorf("parsing the time range of dashboard %s: %w", dashboardUID, err)
+	}
+	r := newVariableResolver(args.Values, from, to, 0)
+	variables := r.resolveAll(ctx, db)
+
+	result := &AlertRulePanel{
+		RuleUID:      args.UID,
+		DashboardUID: dashboardUID,
+		PanelID:      panelID,
+		Variables:    make(map[string][]string, len(variables)),
+		PanelQueries: panelTargets(ctx, r, panel),
+		RuleQueries:  []AlertRuleQuery{},
+	}
+	if rule.Title != nil {
+		result.RuleTitle = *rule.Title
+	}
+	result.DashboardTitle, _ = db["title"].(string)
+	result.PanelTitle, _ = panel["title"].(string)
+	result.PanelType, _ = panel["type"].(string)
+	for _, v := range variables {
+		result.Variables[v.Name] = v.Current
+	}
+	if link, err := dashboardDeeplink(ctx, GenerateDeeplinkParams{Kind: deeplinkPanel, DashboardUID: dashboardUID, PanelID: panelID, Variables: args.Values}, "", ""); err == nil {
+		result.URL = link.URL
+	}
+	for _, q := range rule.Data {
+		if q == nil {
+			continue
+		}
+		query := AlertRuleQuery{RefID: q.RefID, DatasourceUID: q.DatasourceUID}
+		query.Model, _ = q.Model.(map[string]any)
+		if q.RelativeTimeRange != nil && q.RelativeTimeRange.From > 0 {
+			query.From = model.Duration(time.Duration(q.RelativeTimeRange.From) * time.Second).String()
+		}
+		result.RuleQueries = append(result.RuleQueries, query)
+	}
+	return result, nil
+}
+
+var GetAlertRulePanel = mcpgrafana.MustTool(
+	"grafana_get_alert_rule_panel",
+	"Get the dashboard panel an alert rule is linked to by its __dashboardUid__ and __panelId__ annotations, to pivot from a firing alert to the underlying data. Returns the dashboard and panel with a link to the panel, the panel queries with the dashboard's variables replaced by their current values or those given in `values`, and the queries of the rule for comparison. Run the panel queries with `grafana_query_dashboard_panel`, or the ones of a datasource with its query tool.",
+	getAlertRulePanel,
+	mcp.WithTitleAnnotation("Get alert rule panel"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
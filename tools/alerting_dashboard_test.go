@@ -0,0 +1,89 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAlertRulePanel(t *testing.T) {
+	rules := map[string]map[string]any{
+		"linked": {
+			"uid": "linked", "title": "High error rate", "folderUID": "infra", "ruleGroup": "errors", "condition": "B",
+			"annotations": map[string]string{"__dashboardUid__": "service", "__panelId__": "2"},
+			"data": []any{
+				map[string]any{"refId": "A", "datasourceUid": "metrics", "relativeTimeRange": map[string]any{"from": 600, "to": 0}, "model": map[string]any{"expr": "sum(rate(errors_total[5m]))"}},
+				map[string]any{"refId": "B", "datasourceUid": "__expr__", "model": map[string]any{"type": "threshold", "expression": "A"}},
+			},
+		},
+		"unlinked": {"uid": "unlinked", "title": "Disk full", "folderUID": "infra", "ruleGroup": "disk", "condition": "A"},
+		"stale": {
+			"uid": "stale", "title": "Old", "folderUID": "infra", "ruleGroup": "errors", "condition": "A",
+			"annotations": map[string]string{"__dashboardUid__": "service", "__panelId__": "42"},
+		},
+	}
+	routes := fakeRoutes{
+		"/api/dashboards/uid/service": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"meta": map[string]any{"url": "/d/service/service"},
+				"dashboard": map[string]any{
+					"uid":   "service",
+					"title": "Service",
+					"templating": map[string]any{"list": []any{
+						map[string]any{"name": "env", "type": "custom", "query": "prod,staging", "current": map[string]any{"value": "prod"}},
+					}},
+					"panels": []any{
+						map[string]any{
+							"id":         2,
+							"title":      "Errors",
+							"type":       "timeseries",
+							"datasource": map[string]any{"type": "prometheus", "uid": "metrics"},
+							"targets": []any{
+								map[string]any{"refId": "A", "expr": `sum(rate(errors_total{env="$env"}[5m]))`},
+							},
+						},
+					},
+				},
+			})
+		},
+		"/api/datasources": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode([]map[string]any{
+				{"id": 1, "uid": "metrics", "name": "Metrics", "type": "prometheus", "isDefault": true},
+			})
+		},
+	}
+	for uid, rule := range rules {
+		routes["/api/v1/provisioning/alert-rules/"+uid] = func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(rule)
+		}
+	}
+	server := newFakeGrafana(t, routes)
+	ctx := fakeGrafanaContext(server)
+
+	result, err := getAlertRulePanel(ctx, GetAlertRulePanelParams{UID: "linked", Values: map[string]string{"env": "staging"}})
+	require.NoError(t, err)
+	assert.Equal(t, "High error rate", result.RuleTitle)
+	assert.Equal(t, "Service", result.DashboardTitle)
+	assert.Equal(t, "Errors", result.PanelTitle)
+	assert.Equal(t, "timeseries", result.PanelType)
+	assert.Equal(t, server.URL+"/d/service/service?viewPanel=2&var-env=staging", result.URL)
+	assert.Equal(t, []string{"staging"}, result.Variables["env"])
+	require.Len(t, result.PanelQueries, 1)
+	assert.Equal(t, datasourceInfo{UID: "metrics", Type: "prometheus"}, result.PanelQueries[0].Datasource)
+	assert.Equal(t, `sum(rate(errors_total{env="staging"}[5m]))`, result.PanelQueries[0].Query["expr"])
+	require.Len(t, result.RuleQueries, 2)
+	assert.Equal(t, "10m", result.RuleQueries[0].From)
+	assert.Equal(t, "threshold", result.RuleQueries[1].Model["type"])
+
+	_, err = getAlertRulePanel(ctx, GetAlertRulePanelParams{UID: "unlinked"})
+	assert.ErrorContains(t, err, "not linked to a dashboard")
+
+	_, err = getAlertRulePanel(ctx, GetAlertRulePanelParams{UID: "stale"})
+	assert.ErrorContains(t, err, "panel 42 of alert rule stale not found")
+}
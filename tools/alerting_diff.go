@@ -0,0 +1,306 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v3"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// ignoredAlertRuleFields are fields managed by Grafana rather than by the
+// provisioning source, which are never reported as drift.
+var ignoredAlertRuleFields = map[string]bool{
+	"id":         true,
+	"orgID":      true,
+	"orgId":      true,
+	"updated":    true,
+	"provenance": true,
+}
+
+// durationAlertRuleFields are fields holding durations, which are compared
+// by value so that e.g. "5m" and "300s" are considered equal.
+var durationAlertRuleFields = map[string]bool{
+	"for":             true,
+	"interval":        true,
+	"keepFiringFor":   true,
+	"keep_firing_for": true,
+}
+
+type DiffAlertRuleParams struct {
+	Definition string `json:"definition" jsonschema:"required,description=The provisioning definition of the alert rule as YAML or JSON. Either a file provisioning document with 'groups' (as produced by exporting alert rules)\\, a single rule from such a document\\, or a rule in the format of the alerting provisioning HTTP API"`
+	UID        string `json:"uid,omitempty" jsonschema:"description=The UID of the alert rule to compare. Defaults to the UID in the definition. Required if the definition contains more than one rule"`
+}
+
+// alertRuleFieldDiff describes a single field that differs between the
+// provisioning definition and the rule in Grafana.
+type alertRuleFieldDiff struct {
+	Path string `json:"path"`
+	// Kind is one of: changed, missing_in_grafana, extra_in_grafana.
+	Kind       string `json:"kind"`
+	Definition any    `json:"definition,omitempty"`
+	Grafana    any    `json:"grafana,omitempty"`
+}
+
+type alertRuleDiff struct {
+	UID         string               `json:"uid"`
+	Format      string               `json:"format"`
+	Drifted     bool                 `json:"drifted"`
+	Differences []alertRuleFieldDiff `json:"differences"`
+}
+
+// parseDefinition parses a YAML or JSON document into plain JSON values, so
+// that it can be compared with JSON returned by Grafana.
+func parseDefinition(definition string) (map[string]any, error) {
+	var raw any
+	if err := yaml.Unmarshal([]byte(definition), &raw); err != nil {
+		return nil, fmt.Errorf("parse definition: %w", err)
+	}
+	// Round-trip through JSON to normalize numbers and map types.
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse definition: %w", err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("parse definition: definition must be an object: %w", err)
+	}
+	return doc, nil
+}
+
+// findRuleInGroups returns the group and rule with the given UID from a file
+// provisioning document. If uid is empty the document must contain exactly
+// one rule.
+func findRuleInGroups(doc map[string]any, uid string) (map[string]any, map[string]any, error) {
+	groups, _ := doc["groups"].([]any)
+	var foundGroup, foundRule map[string]any
+	count := 0
+	for _, g := range groups {
+		group, ok := g.(map[string]any)
+		if !ok {
+			continue
+		}
+		rules, _ := group["rules"].([]any)
+		for _, r := range rules {
+			rule, ok := r.(map[string]any)
+			if !ok {
+				continue
+			}
+			count++
+			if uid == "" || rule["uid"] == uid {
+				foundGroup, foundRule = group, rule
+			}
+		}
+	}
+	switch {
+	case uid == "" && count > 1:
+		return nil, nil, fmt.Errorf("definition contains %d rules, uid is required", count)
+	case foundRule == nil && uid != "":
+		return nil, nil, fmt.Errorf("rule %s not found in definition", uid)
+	case foundRule == nil:
+		return nil, nil, fmt.Errorf("definition contains no rules")
+	}
+	return foundGroup, foundRule, nil
+}
+
+// withoutRules returns a copy of a rule group without its rules.
+func withoutRules(group map[string]any) map[string]any {
+	result := make(map[string]any, len(group))
+	for k, v := range group {
+		if k != "rules" {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+func isZeroValue(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice:
+		return rv.Len() == 0
+	}
+	return rv.IsZero()
+}
+
+func parseAnyDuration(s string) (time.Duration, bool) {
+	if d, err := model.ParseDuration(s); err == nil {
+		return time.Duration(d), true
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, true
+	}
+	return 0, false
+}
+
+func equalDurations(a, b any) bool {
+	as, aok := a.(string)
+	bs, bok := b.(string)
+	if !aok || !bok {
+		return false
+	}
+	ad, aok := parseAnyDuration(as)
+	bd, bok := parseAnyDuration(bs)
+	return aok && bok && ad == bd
+}
+
+// diffJSON appends the differences between a value from the definition and
+// the corresponding value from Grafana to diffs. Missing fields are treated
+// as equal to zero values, since Grafana omits defaults.
+func diffJSON(path, key string, definition, grafana any, diffs *[]alertRuleFieldDiff) {
+	if ignoredAlertRuleFields[key] {
+		return
+	}
+	if isZeroValue(definition) && isZeroValue(grafana) {
+		return
+	}
+	if durationAlertRuleFields[key] && equalDurations(definition, grafana) {
+		return
+	}
+	switch {
+	case isZeroValue(grafana):
+		*diffs = append(*diffs, alertRuleFieldDiff{Path: path, Kind: "missing_in_grafana", Definition: definition})
+		return
+	case isZeroValue(definition):
+		*diffs = append(*diffs, alertRuleFieldDiff{Path: path, Kind: "extra_in_grafana", Grafana: grafana})
+		return
+	}
+
+	dm, dok := definition.(map[string]any)
+	gm, gok := grafana.(map[string]any)
+	if dok && gok {
+		keys := make([]string, 0, len(dm)+len(gm))
+		for k := range dm {
+			keys = append(keys, k)
+		}
+		for k := range gm {
+			if _, ok := dm[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			diffJSON(joinPath(path, k), k, dm[k], gm[k], diffs)
+		}
+		return
+	}
+
+	ds, dok := definition.([]any)
+	gs, gok := grafana.([]any)
+	if dok && gok && len(ds) == len(gs) {
+		for i := range ds {
+			diffJSON(fmt.Sprintf("%s[%d]", path, i), "", ds[i], gs[i], diffs)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(definition, grafana) {
+		*diffs = append(*diffs, alertRuleFieldDiff{Path: path, Kind: "changed", Definition: definition, Grafana: grafana})
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func diffAlertRule(ctx context.Context, args DiffAlertRuleParams) (*alertRuleDiff, error) {
+	doc, err := parseDefinition(args.Definition)
+	if err != nil {
+		return nil, fmt.Errorf("diff alert rule: %w", err)
+	}
+
+	c, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("diff alert rule: %w", err)
+	}
+
+	result := &alertRuleDiff{Differences: []alertRuleFieldDiff{}}
+	_, isFile := doc["groups"]
+	_, hasRuleGroup := doc["ruleGroup"]
+	_, hasFolderUID := doc["folderUID"]
+	switch {
+	case isFile:
+		group, rule, err := findRuleInGroups(doc, args.UID)
+		if err != nil {
+			return nil, fmt.Errorf("diff alert rule: %w", err)
+		}
+		uid, _ := rule["uid"].(string)
+		if uid == "" {
+			return nil, fmt.Errorf("diff alert rule: rule in definition has no uid")
+		}
+		liveGroup, liveRule, err := fetchExportedAlertRule(ctx, c, uid)
+		if err != nil {
+			return nil, fmt.Errorf("diff alert rule: %w", err)
+		}
+		result.UID, result.Format = uid, "file"
+		diffJSON("group", "", withoutRules(group), withoutRules(liveGroup), &result.Differences)
+		diffJSON("rule", "", rule, liveRule, &result.Differences)
+	case hasRuleGroup || hasFolderUID:
+		uid := args.UID
+		if uid == "" {
+			uid, _ = doc["uid"].(string)
+		}
+		if uid == "" {
+			return nil, fmt.Errorf("diff alert rule: uid is required")
+		}
+		var live map[string]any
+		if err := c.getJSON(ctx, alertRulePath(uid), nil, &live); err != nil {
+			return nil, fmt.Errorf("diff alert rule: %w", err)
+		}
+		result.UID, result.Format = uid, "api"
+		diffJSON("", "", doc, live, &result.Differences)
+	default:
+		// A single rule in file provisioning format.
+		uid := args.UID
+		if uid == "" {
+			uid, _ = doc["uid"].(string)
+		}
+		if uid == "" {
+			return nil, fmt.Errorf("diff alert rule: uid is required")
+		}
+		_, liveRule, err := fetchExportedAlertRule(ctx, c, uid)
+		if err != nil {
+			return nil, fmt.Errorf("diff alert rule: %w", err)
+		}
+		result.UID, result.Format = uid, "file"
+		diffJSON("rule", "", doc, liveRule, &result.Differences)
+	}
+	result.Drifted = len(result.Differences) > 0
+	return result, nil
+}
+
+// fetchExportedAlertRule fetches a rule in file provisioning format,
+// returning the rule and the group containing it.
+func fetchExportedAlertRule(ctx context.Context, c *alertingClient, uid string) (map[string]any, map[string]any, error) {
+	var export map[string]any
+	if err := c.getJSON(ctx, alertRulePath(uid)+"/export", nil, &export); err != nil {
+		return nil, nil, err
+	}
+	group, rule, err := findRuleInGroups(export, uid)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unexpected export of rule %s: %w", uid, err)
+	}
+	return group, rule, nil
+}
+
+var DiffAlertRule = mcpgrafana.MustTool(
+	"grafana_diff_alert_rule",
+	"Compares a Grafana-managed alert rule against its provisioning definition (for example from a Git repository) and reports drift. The definition may be YAML or JSON in the file provisioning format (as produced by exporting alert rules) or in the format of the alerting provisioning HTTP API. Returns whether the rule has drifted and a list of differing fields, each with its path, the kind of difference (changed, missing_in_grafana or extra_in_grafana) and the values in the definition and in Grafana. Fields managed by Grafana such as id and updated are ignored, durations are compared by value, and missing fields are treated as equal to their zero value.",
+	diffAlertRule,
+	mcp.WithTitleAnnotation("Diff alert rule against provisioning definition"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
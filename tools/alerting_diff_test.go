@@ -0,0 +1,120 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const exportedRuleJSON = `{
+  "apiVersion": 1,
+  "groups": [{
+    "orgId": 1,
+    "name": "cpu",
+    "folder": "Infra",
+    "interval": "1m",
+    "rules": [{
+      "uid": "rule-1",
+      "title": "High CPU",
+      "condition": "B",
+      "data": [{"refId": "A", "datasourceUid": "prom", "model": {"expr": "rate(cpu[5m])", "refId": "A"}}],
+      "noDataState": "NoData",
+      "execErrState": "Error",
+      "for": "5m",
+      "labels": {"severity": "critical"},
+      "isPaused": false
+    }]
+  }]
+}`
+
+func newDiffTestContext(t *testing.T) context.Context {
+	return newFakeGrafanaContext(t, fakeRoutes{
+		"/api/v1/provisioning/alert-rules/rule-1/export": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(exportedRuleJSON))
+		},
+		"/api/v1/provisioning/alert-rules/rule-1": jsonBody(`{"id": 7, "uid": "rule-1", "orgID": 1, "folderUID": "infra", "ruleGroup": "cpu", "title": "High CPU", "for": "5m", "updated": "2025-01-01T00:00:00Z", "labels": {"severity": "critical"}}`),
+	})
+}
+
+func TestDiffAlertRule(t *testing.T) {
+	ctx := newDiffTestContext(t)
+
+	t.Run("file format without drift", func(t *testing.T) {
+		definition := `
+apiVersion: 1
+groups:
+  - orgId: 1
+    name: cpu
+    folder: Infra
+    interval: 60s
+    rules:
+      - uid: rule-1
+        title: High CPU
+        condition: B
+        data:
+          - refId: A
+            datasourceUid: prom
+            model:
+              expr: rate(cpu[5m])
+              refId: A
+        noDataState: NoData
+        execErrState: Error
+        for: 300s
+        labels:
+          severity: critical
+`
+		result, err := diffAlertRule(ctx, DiffAlertRuleParams{Definition: definition})
+		require.NoError(t, err)
+		assert.Equal(t, "rule-1", result.UID)
+		assert.Equal(t, "file", result.Format)
+		assert.False(t, result.Drifted)
+		assert.Empty(t, result.Differences)
+	})
+
+	t.Run("single rule with drift", func(t *testing.T) {
+		definition := `
+uid: rule-1
+title: High CPU usage
+condition: B
+data:
+  - refId: A
+    datasourceUid: prom
+    model:
+      expr: rate(cpu[1m])
+      refId: A
+noDataState: NoData
+execErrState: Error
+for: 5m
+annotations:
+  summary: CPU is high
+`
+		result, err := diffAlertRule(ctx, DiffAlertRuleParams{Definition: definition})
+		require.NoError(t, err)
+		assert.True(t, result.Drifted)
+		assert.Equal(t, []alertRuleFieldDiff{
+			{Path: "rule.annotations", Kind: "missing_in_grafana", Definition: map[string]any{"summary": "CPU is high"}},
+			{Path: "rule.data[0].model.expr", Kind: "changed", Definition: "rate(cpu[1m])", Grafana: "rate(cpu[5m])"},
+			{Path: "rule.labels", Kind: "extra_in_grafana", Grafana: map[string]any{"severity": "critical"}},
+			{Path: "rule.title", Kind: "changed", Definition: "High CPU usage", Grafana: "High CPU"},
+		}, result.Differences)
+	})
+
+	t.Run("api format ignores managed fields", func(t *testing.T) {
+		definition := `{"uid": "rule-1", "folderUID": "infra", "ruleGroup": "cpu", "title": "High CPU", "for": "5m", "labels": {"severity": "critical"}}`
+		result, err := diffAlertRule(ctx, DiffAlertRuleParams{Definition: definition})
+		require.NoError(t, err)
+		assert.Equal(t, "api", result.Format)
+		assert.False(t, result.Drifted)
+	})
+
+	t.Run("rule not found in definition", func(t *testing.T) {
+		_, err := diffAlertRule(ctx, DiffAlertRuleParams{Definition: exportedRuleJSON, UID: "other"})
+		require.Error(t, err)
+	})
+}
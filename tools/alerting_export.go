@@ -0,0 +1,155 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"gopkg.in/yaml.v3"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// Sections of an alerting provisioning bundle.
+const (
+	bundleSectionRules         = "rules"
+	bundleSectionContactPoints = "contactPoints"
+	bundleSectionPolicies      = "policies"
+	bundleSectionMuteTimings   = "muteTimings"
+	bundleSectionTemplates     = "templates"
+)
+
+var allBundleSections = []string{
+	bundleSectionRules,
+	bundleSectionContactPoints,
+	bundleSectionPolicies,
+	bundleSectionMuteTimings,
+	bundleSectionTemplates,
+}
+
+// alertingBundle is a complete alerting configuration in Grafana's file
+// provisioning format. It can be written to a provisioning file as-is.
+type alertingBundle struct {
+	APIVersion    int              `json:"apiVersion" yaml:"apiVersion"`
+	Groups        []any            `json:"groups,omitempty" yaml:"groups,omitempty"`
+	ContactPoints []any            `json:"contactPoints,omitempty" yaml:"contactPoints,omitempty"`
+	Policies      []any            `json:"policies,omitempty" yaml:"policies,omitempty"`
+	MuteTimes     []any            `json:"muteTimes,omitempty" yaml:"muteTimes,omitempty"`
+	Templates     []bundleTemplate `json:"templates,omitempty" yaml:"templates,omitempty"`
+}
+
+type bundleTemplate struct {
+	Name     string `json:"name" yaml:"name"`
+	Template string `json:"template" yaml:"template"`
+}
+
+type ExportAlertingBundleParams struct {
+	Sections       []string `json:"sections,omitempty" jsonschema:"description=The sections to export. Any of: rules\\, contactPoints\\, policies\\, muteTimings\\, templates. Defaults to all sections"`
+	Format         string   `json:"format,omitempty" jsonschema:"description=The format of the bundle: json or yaml. Defaults to json"`
+	DecryptSecrets bool     `json:"decryptSecrets,omitempty" jsonschema:"description=Whether to include the decrypted secure settings of contact points. Requires admin permissions. Only use this when the bundle is needed for a restore. Defaults to false\\, in which case secrets are redacted"`
+}
+
+func (p ExportAlertingBundleParams) validate() error {
+	for _, s := range p.Sections {
+		if !slices.Contains(allBundleSections, s) {
+			return fmt.Errorf("invalid section %q, must be one of %v", s, allBundleSections)
+		}
+	}
+	switch p.Format {
+	case "", "json", "yaml":
+	default:
+		return fmt.Errorf("invalid format %q, must be json or yaml", p.Format)
+	}
+	return nil
+}
+
+// fetchAlertingBundle fetches the requested sections of the alerting
+// configuration using the provisioning export APIs.
+func fetchAlertingBundle(ctx context.Context, c *alertingClient, sections []string, decrypt bool) (*alertingBundle, error) {
+	if len(sections) == 0 {
+		sections = allBundleSections
+	}
+	bundle := &alertingBundle{APIVersion: 1}
+
+	var export struct {
+		Groups        []any `json:"groups"`
+		ContactPoints []any `json:"contactPoints"`
+		Policies      []any `json:"policies"`
+		MuteTimes     []any `json:"muteTimes"`
+	}
+	if slices.Contains(sections, bundleSectionRules) {
+		if err := c.getJSON(ctx, "/api/v1/provisioning/alert-rules/export", nil, &export); err != nil {
+			return nil, fmt.Errorf("export alert rules: %w", err)
+		}
+		bundle.Groups = export.Groups
+	}
+	if slices.Contains(sections, bundleSectionContactPoints) {
+		params := url.Values{}
+		if decrypt {
+			params.Set("decrypt", "true")
+		}
+		if err := c.getJSON(ctx, "/api/v1/provisioning/contact-points/export", params, &export); err != nil {
+			return nil, fmt.Errorf("export contact points: %w", err)
+		}
+		bundle.ContactPoints = export.ContactPoints
+	}
+	if slices.Contains(sections, bundleSectionPolicies) {
+		if err := c.getJSON(ctx, "/api/v1/provisioning/policies/export", nil, &export); err != nil {
+			return nil, fmt.Errorf("export notification policies: %w", err)
+		}
+		bundle.Policies = export.Policies
+	}
+	if slices.Contains(sections, bundleSectionMuteTimings) {
+		if err := c.getJSON(ctx, "/api/v1/provisioning/mute-timings/export", nil, &export); err != nil {
+			return nil, fmt.Errorf("export mute timings: %w", err)
+		}
+		bundle.MuteTimes = export.MuteTimes
+	}
+	if slices.Contains(sections, bundleSectionTemplates) {
+		// There is no export endpoint for templates, but the provisioning
+		// API returns the fields used in provisioning files.
+		var templates []bundleTemplate
+		if err := c.getJSON(ctx, "/api/v1/provisioning/templates", nil, &templates); err != nil {
+			return nil, fmt.Errorf("export notification templates: %w", err)
+		}
+		bundle.Templates = templates
+	}
+	return bundle, nil
+}
+
+func exportAlertingBundle(ctx context.Context, args ExportAlertingBundleParams) (any, error) {
+	if err := args.validate(); err != nil {
+		return nil, fmt.Errorf("export alerting bundle: %w", err)
+	}
+	c, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("export alerting bundle: %w", err)
+	}
+	if args.DecryptSecrets {
+		// Decrypted secrets must not outlive the call in the result cache.
+		mcpgrafana.DisableResultCache(ctx)
+	}
+	bundle, err := fetchAlertingBundle(ctx, c, args.Sections, args.DecryptSecrets)
+	if err != nil {
+		return nil, fmt.Errorf("export alerting bundle: %w", err)
+	}
+	if args.Format == "yaml" {
+		b, err := yaml.Marshal(bundle)
+		if err != nil {
+			return nil, fmt.Errorf("export alerting bundle: marshal yaml: %w", err)
+		}
+		return string(b), nil
+	}
+	return bundle, nil
+}
+
+var ExportAlertingBundle = mcpgrafana.MustTool(
+	"grafana_export_alerting_bundle",
+	"Exports the complete alerting configuration - alert rules, contact points, notification policies, mute timings and notification templates - as a single bundle in Grafana's file provisioning format (JSON or YAML). The bundle can be committed to Git, used as a backup, or imported into another Grafana instance to promote configuration between environments. Secure settings of contact points are redacted unless decryptSecrets is set.",
+	exportAlertingBundle,
+	mcp.WithTitleAnnotation("Export alerting configuration bundle"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,68 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestExportAlertingBundle(t *testing.T) {
+	var requests []string
+	exported := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.URL.String())
+			_, _ = w.Write([]byte(body))
+		}
+	}
+	ctx := newFakeGrafanaContext(t, fakeRoutes{
+		"/api/v1/provisioning/alert-rules/export":    exported(`{"apiVersion": 1, "groups": [{"name": "cpu", "folder": "Infra", "interval": "1m", "rules": [{"uid": "rule-1", "title": "High CPU"}]}]}`),
+		"/api/v1/provisioning/contact-points/export": exported(`{"apiVersion": 1, "contactPoints": [{"name": "email", "receivers": [{"uid": "cp-1", "type": "email"}]}]}`),
+		"/api/v1/provisioning/policies/export":       exported(`{"apiVersion": 1, "policies": [{"receiver": "email"}]}`),
+		"/api/v1/provisioning/mute-timings/export":   exported(`{"apiVersion": 1, "muteTimes": [{"name": "weekends"}]}`),
+		"/api/v1/provisioning/templates":             exported(`[{"name": "custom", "template": "{{ define \"custom\" }}{{ end }}", "provenance": "api"}]`),
+	})
+
+	t.Run("all sections", func(t *testing.T) {
+		requests = nil
+		result, err := exportAlertingBundle(ctx, ExportAlertingBundleParams{})
+		require.NoError(t, err)
+		bundle, ok := result.(*alertingBundle)
+		require.True(t, ok)
+		assert.Equal(t, 1, bundle.APIVersion)
+		assert.Len(t, bundle.Groups, 1)
+		assert.Len(t, bundle.ContactPoints, 1)
+		assert.Len(t, bundle.Policies, 1)
+		assert.Len(t, bundle.MuteTimes, 1)
+		assert.Equal(t, []bundleTemplate{{Name: "custom", Template: `{{ define "custom" }}{{ end }}`}}, bundle.Templates)
+		assert.Contains(t, requests, "/api/v1/provisioning/contact-points/export")
+	})
+
+	t.Run("selected sections as yaml", func(t *testing.T) {
+		requests = nil
+		result, err := exportAlertingBundle(ctx, ExportAlertingBundleParams{
+			Sections:       []string{"contactPoints"},
+			Format:         "yaml",
+			DecryptSecrets: true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"/api/v1/provisioning/contact-points/export?decrypt=true"}, requests)
+
+		var bundle map[string]any
+		require.NoError(t, yaml.Unmarshal([]byte(result.(string)), &bundle))
+		assert.Equal(t, 1, bundle["apiVersion"])
+		assert.Contains(t, bundle, "contactPoints")
+		assert.NotContains(t, bundle, "groups")
+	})
+
+	t.Run("invalid section", func(t *testing.T) {
+		_, err := exportAlertingBundle(context.Background(), ExportAlertingBundleParams{Sections: []string{"dashboards"}})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,424 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"gopkg.in/yaml.v3"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// Golden signals alert rules are generated for.
+const (
+	goldenSignalLatency    = "latency"
+	goldenSignalErrors     = "errors"
+	goldenSignalSaturation = "saturation"
+)
+
+var allGoldenSignals = []string{goldenSignalLatency, goldenSignalErrors, goldenSignalSaturation}
+
+const (
+	// goldenSignalRateWindow is the range of the rates of the generated
+	// rules.
+	goldenSignalRateWindow = "5m"
+
+	// Default thresholds of the generated rules.
+	defaultLatencyPercentile       = 0.99
+	defaultLatencyThresholdSeconds = 1
+	defaultErrorRatioThreshold     = 0.05
+	defaultSaturationThreshold     = 0.9
+)
+
+// latencyHistograms are the histograms of request durations, in order of
+// preference, by the naming conventions of OpenTelemetry, the Prometheus
+// client libraries, Micrometer and gRPC.
+var latencyHistograms = []string{
+	"http_server_request_duration_seconds_bucket",
+	"http_request_duration_seconds_bucket",
+	"http_server_requests_seconds_bucket",
+	"grpc_server_handling_seconds_bucket",
+}
+
+// errorCounter is a counter of requests with a label of their status.
+type errorCounter struct {
+	metric string
+	// statusLabels are the possible labels of the status, in order of
+	// preference.
+	statusLabels []string
+	// errors matches the statuses of failed requests.
+	errors string
+}
+
+// errorCounters are the counters of requests, in order of preference.
+var errorCounters = []errorCounter{
+	{"http_server_request_duration_seconds_count", []string{"http_response_status_code"}, "5.."},
+	{"http_requests_total", []string{"code", "status", "status_code"}, "5.."},
+	{"http_server_requests_seconds_count", []string{"status", "outcome"}, "5..|SERVER_ERROR"},
+	{"grpc_server_handled_total", []string{"grpc_code"}, "Unknown|DeadlineExceeded|Internal|Unavailable|DataLoss"},
+}
+
+// saturationRatio is a resource whose usage is measured as a ratio of its
+// capacity.
+type saturationRatio struct {
+	name    string
+	metrics []string
+	expr    func(selector string) string
+}
+
+var saturationRatios = []saturationRatio{
+	{"memory", []string{"container_memory_working_set_bytes", "container_spec_memory_limit_bytes"}, func(s string) string {
+		return fmt.Sprintf(`max(container_memory_working_set_bytes{%[1]s} / on(namespace, pod, container) (container_spec_memory_limit_bytes{%[1]s} > 0))`, joinMatchers(s, `container!=""`))
+	}},
+	{"file descriptor", []string{"process_open_fds", "process_max_fds"}, func(s string) string {
+		return fmt.Sprintf(`max(process_open_fds{%[1]s} / process_max_fds{%[1]s})`, s)
+	}},
+}
+
+type GenerateGoldenSignalAlertRulesParams struct {
+	DatasourceUID           string            `json:"datasourceUid" jsonschema:"required,description=The UID of the Prometheus datasource the metrics of the service are in"`
+	Service                 string            `json:"service" jsonschema:"required,description=The name of the service\\, used in the titles and labels of the rules"`
+	Selector                *Selector         `json:"selector,omitempty" jsonschema:"description=Optionally\\, the selector of the series of the service\\, e.g. on namespace and job. Defaults to the job label matching the service name"`
+	Signals                 []string          `json:"signals,omitempty" jsonschema:"description=Optionally\\, the golden signals to generate rules for: latency\\, errors or saturation. Defaults to all of them"`
+	LatencyPercentile       float64           `json:"latencyPercentile,omitempty" jsonschema:"description=Optionally\\, the percentile of the request duration alerted on (default 0.99)"`
+	LatencyThresholdSeconds float64           `json:"latencyThresholdSeconds,omitempty" jsonschema:"description=Optionally\\, the request duration in seconds above which the latency rule fires (default 1)"`
+	ErrorRatioThreshold     float64           `json:"errorRatioThreshold,omitempty" jsonschema:"description=Optionally\\, the ratio of failed requests above which the error rule fires (default 0.05)"`
+	SaturationThreshold     float64           `json:"saturationThreshold,omitempty" jsonschema:"description=Optionally\\, the ratio of used capacity above which saturation rules fire (default 0.9)"`
+	For                     string            `json:"for,omitempty" jsonschema:"description=Optionally\\, how long the condition must hold before a rule fires (default 5m)"`
+	Folder                  string            `json:"folder,omitempty" jsonschema:"description=Optionally\\, the title of the folder of the rule group (default 'Golden signals')"`
+	Group                   string            `json:"group,omitempty" jsonschema:"description=Optionally\\, the name of the rule group. Defaults to the service name"`
+	Interval                string            `json:"interval,omitempty" jsonschema:"description=Optionally\\, the evaluation interval of the rule group (default 1m)"`
+	Labels                  map[string]string `json:"labels,omitempty" jsonschema:"description=Optionally\\, labels added to every rule\\, e.g. team or severity\\, for routing notifications"`
+	Format                  string            `json:"format,omitempty" jsonschema:"description=The format of the bundle: json or yaml. Defaults to json"`
+}
+
+func (p GenerateGoldenSignalAlertRulesParams) validate() error {
+	if p.Service == "" {
+		return fmt.Errorf("service is required")
+	}
+	for _, s := range p.Signals {
+		if !slices.Contains(allGoldenSignals, s) {
+			return fmt.Errorf("invalid signal %q, must be one of %v", s, allGoldenSignals)
+		}
+	}
+	if p.LatencyPercentile < 0 || p.LatencyPercentile >= 1 {
+		return fmt.Errorf("latencyPercentile must be between 0 and 1, got %v", p.LatencyPercentile)
+	}
+	for _, d := range []string{p.For, p.Interval} {
+		if _, ok := parseAnyDuration(d); d != "" && !ok {
+			return fmt.Errorf("invalid duration %q", d)
+		}
+	}
+	switch p.Format {
+	case "", "json", "yaml":
+	default:
+		return fmt.Errorf("invalid format %q, must be json or yaml", p.Format)
+	}
+	return nil
+}
+
+// GeneratedAlertRule describes an alert rule generated for a golden signal.
+type GeneratedAlertRule struct {
+	Signal    string   `json:"signal"`
+	Title     string   `json:"title"`
+	UID       string   `json:"uid"`
+	Metrics   []string `json:"metrics"`
+	Expr      string   `json:"expr"`
+	Threshold float64  `json:"threshold"`
+}
+
+// GoldenSignalAlertRules are the alert rules generated for a service, as a
+// provisioning bundle with one rule group.
+type GoldenSignalAlertRules struct {
+	Rules []GeneratedAlertRule `json:"rules"`
+	// Skipped explains why no rule was generated for some signals.
+	Skipped []string `json:"skipped,omitempty"`
+	// Bundle is the provisioning bundle, as an object or a YAML string.
+	Bundle any `json:"bundle"`
+}
+
+// joinMatchers joins non-empty label matchers with commas.
+func joinMatchers(matchers ...string) string {
+	var nonEmpty []string
+	for _, m := range matchers {
+		if m != "" {
+			nonEmpty = append(nonEmpty, m)
+		}
+	}
+	return strings.Join(nonEmpty, ", ")
+}
+
+// goldenSignalRuleUID returns a stable UID for the rule of a signal of a
+// service, so that importing the generated rules again updates them.
+func goldenSignalRuleUID(service, signal, name string) string {
+	sum := sha256.Sum256([]byte(service + "\x00" + signal + "\x00" + name))
+	return "golden-" + hex.EncodeToString(sum[:8])
+}
+
+// goldenSignalGenerator discovers the metrics of a service and generates
+// the rules of its golden signals.
+type goldenSignalGenerator struct {
+	args     GenerateGoldenSignalAlertRulesParams
+	selector string
+	metrics  map[string]bool
+	// labelNames returns the label names of the series of a metric of the
+	// service.
+	labelNames func(metric string) ([]string, error)
+}
+
+func (g *goldenSignalGenerator) has(metrics ...string) bool {
+	for _, m := range metrics {
+		if !g.metrics[m] {
+			return false
+		}
+	}
+	return true
+}
+
+// latency returns the rule of the latency signal, using the preferred
+// request duration histogram of the service.
+func (g *goldenSignalGenerator) latency() (*GeneratedAlertRule, string) {
+	i := slices.IndexFunc(latencyHistograms, func(m string) bool { return g.has(m) })
+	if i < 0 {
+		return nil, fmt.Sprintf("latency: none of the request duration histograms %v was found", latencyHistograms)
+	}
+	metric := latencyHistograms[i]
+	percentile := cmp.Or(g.args.LatencyPercentile, defaultLatencyPercentile)
+	return &GeneratedAlertRule{
+		Signal:    goldenSignalLatency,
+		Title:     fmt.Sprintf("%s high latency", g.args.Service),
+		Metrics:   []string{metric},
+		Expr:      fmt.Sprintf("histogram_quantile(%g, sum by (le) (rate(%s{%s}[%s])))", percentile, metric, g.selector, goldenSignalRateWindow),
+		Threshold: cmp.Or(g.args.LatencyThresholdSeconds, defaultLatencyThresholdSeconds),
+	}, ""
+}
+
+// errors returns the rule of the errors signal, using the preferred request
+// counter of the service which has a status label.
+func (g *goldenSignalGenerator) errors() (*GeneratedAlertRule, string, error) {
+	for _, c := range errorCounters {
+		if !g.has(c.metric) {
+			continue
+		}
+		names, err := g.labelNames(c.metric)
+		if err != nil {
+			return nil, "", err
+		}
+		i := slices.IndexFunc(c.statusLabels, func(l string) bool { return slices.Contains(names, l) })
+		if i < 0 {
+			continue
+		}
+		failed := joinMatchers(g.selector, fmt.Sprintf(`%s=~"%s"`, c.statusLabels[i], c.errors))
+		return &GeneratedAlertRule{
+			Signal:    goldenSignalErrors,
+			Title:     fmt.Sprintf("%s high error rate", g.args.Service),
+			Metrics:   []string{c.metric},
+			Expr:      fmt.Sprintf("sum(rate(%[1]s{%[2]s}[%[4]s])) / sum(rate(%[1]s{%[3]s}[%[4]s]))", c.metric, failed, g.selector, goldenSignalRateWindow),
+			Threshold: cmp.Or(g.args.ErrorRatioThreshold, defaultErrorRatioThreshold),
+		}, "", nil
+	}
+	metrics := make([]string, len(errorCounters))
+	for i, c := range errorCounters {
+		metrics[i] = c.metric
+	}
+	return nil, fmt.Sprintf("errors: none of the request counters %v was found with a status label", metrics), nil
+}
+
+// saturation returns the rules of the saturation signal, one for each
+// resource whose usage and capacity are both measured.
+func (g *goldenSignalGenerator) saturation() ([]GeneratedAlertRule, string) {
+	var rules []GeneratedAlertRule
+	for _, r := range saturationRatios {
+		if !g.has(r.metrics...) {
+			continue
+		}
+		rules = append(rules, GeneratedAlertRule{
+			Signal:    goldenSignalSaturation,
+			Title:     fmt.Sprintf("%s %s saturation", g.args.Service, r.name),
+			Metrics:   r.metrics,
+			Expr:      r.expr(g.selector),
+			Threshold: cmp.Or(g.args.SaturationThreshold, defaultSaturationThreshold),
+		})
+	}
+	if len(rules) == 0 {
+		return nil, "saturation: no usage and capacity metrics of memory (cAdvisor) or file descriptors (process) were found"
+	}
+	return rules, ""
+}
+
+// provisioningRule returns a generated rule in Grafana's file provisioning
+// format: the query of the signal, and a threshold expression as the
+// condition.
+func (g *goldenSignalGenerator) provisioningRule(r GeneratedAlertRule) map[string]any {
+	labels := map[string]any{"service": g.args.Service, "signal": r.Signal}
+	for k, v := range g.args.Labels {
+		labels[k] = v
+	}
+	return map[string]any{
+		"uid":       r.UID,
+		"title":     r.Title,
+		"condition": "B",
+		"data": []any{
+			map[string]any{
+				"refId":             "A",
+				"relativeTimeRange": map[string]any{"from": 600, "to": 0},
+				"datasourceUid":     g.args.DatasourceUID,
+				"model": map[string]any{
+					"refId":   "A",
+					"expr":    r.Expr,
+					"instant": true,
+				},
+			},
+			map[string]any{
+				"refId":         "B",
+				"datasourceUid": "__expr__",
+				"model": map[string]any{
+					"refId":      "B",
+					"type":       "threshold",
+					"expression": "A",
+					"conditions": []any{map[string]any{
+						"evaluator": map[string]any{"type": "gt", "params": []any{r.Threshold}},
+					}},
+				},
+			},
+		},
+		"noDataState":  "OK",
+		"execErrState": "Error",
+		"for":          cmp.Or(g.args.For, "5m"),
+		"annotations": map[string]any{
+			"summary":     fmt.Sprintf("%s of %s is above %g", r.Signal, g.args.Service, r.Threshold),
+			"description": fmt.Sprintf("%s: {{ $values.A }}, above the threshold of %g.", r.Expr, r.Threshold),
+		},
+		"labels":   labels,
+		"isPaused": false,
+	}
+}
+
+// generate generates the rules of the requested signals from the metrics
+// of the service.
+func (g *goldenSignalGenerator) generate() (*GoldenSignalAlertRules, error) {
+	signals := g.args.Signals
+	if len(signals) == 0 {
+		signals = allGoldenSignals
+	}
+	result := &GoldenSignalAlertRules{Rules: []GeneratedAlertRule{}}
+	for _, signal := range allGoldenSignals {
+		if !slices.Contains(signals, signal) {
+			continue
+		}
+		var skipped string
+		switch signal {
+		case goldenSignalLatency:
+			var rule *GeneratedAlertRule
+			if rule, skipped = g.latency(); rule != nil {
+				result.Rules = append(result.Rules, *rule)
+			}
+		case goldenSignalErrors:
+			rule, reason, err := g.errors()
+			if err != nil {
+				return nil, err
+			}
+			if skipped = reason; rule != nil {
+				result.Rules = append(result.Rules, *rule)
+			}
+		case goldenSignalSaturation:
+			var rules []GeneratedAlertRule
+			rules, skipped = g.saturation()
+			result.Rules = append(result.Rules, rules...)
+		}
+		if skipped != "" {
+			result.Skipped = append(result.Skipped, skipped)
+		}
+	}
+
+	rules := make([]any, len(result.Rules))
+	for i := range result.Rules {
+		r := &result.Rules[i]
+		r.UID = goldenSignalRuleUID(g.args.Service, r.Signal, r.Title)
+		rules[i] = g.provisioningRule(*r)
+	}
+	bundle := alertingBundle{APIVersion: 1}
+	if len(rules) > 0 {
+		bundle.Groups = []any{map[string]any{
+			"orgId":    1,
+			"name":     cmp.Or(g.args.Group, g.args.Service),
+			"folder":   cmp.Or(g.args.Folder, "Golden signals"),
+			"interval": cmp.Or(g.args.Interval, "1m"),
+			"rules":    rules,
+		}}
+	}
+	result.Bundle = bundle
+	if g.args.Format == "yaml" {
+		b, err := yaml.Marshal(bundle)
+		if err != nil {
+			return nil, fmt.Errorf("marshal yaml: %w", err)
+		}
+		result.Bundle = string(b)
+	}
+	return result, nil
+}
+
+func generateGoldenSignalAlertRules(ctx context.Context, args GenerateGoldenSignalAlertRulesParams) (*GoldenSignalAlertRules, error) {
+	if err := args.validate(); err != nil {
+		return nil, fmt.Errorf("generate golden signal alert rules: %w", err)
+	}
+	promClient, err := promClientFromContext(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+	sel := Selector{Filters: []LabelMatcher{{Name: "job", Value: args.Service, Type: "="}}}
+	if args.Selector != nil && len(args.Selector.Filters) > 0 {
+		sel = *args.Selector
+	}
+	// The matchers are used within the selectors of the rules' queries.
+	selector := strings.TrimSuffix(strings.TrimPrefix(sel.String(), "{"), "}")
+
+	end := time.Now()
+	start := end.Add(-time.Hour)
+	names, warnings, err := promClient.LabelValues(ctx, "__name__", []string{"{" + selector + "}"}, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("listing the metrics of %s: %w", args.Service, err)
+	}
+	recordPrometheusWarnings(ctx, warnings)
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no metrics match {%s} in the last hour; set selector to the label matchers of the series of %s", selector, args.Service)
+	}
+
+	g := &goldenSignalGenerator{
+		args:     args,
+		selector: selector,
+		metrics:  map[string]bool{},
+		labelNames: func(metric string) ([]string, error) {
+			names, warnings, err := promClient.LabelNames(ctx, []string{fmt.Sprintf("%s{%s}", metric, selector)}, start, end)
+			if err != nil {
+				return nil, fmt.Errorf("listing the labels of %s: %w", metric, err)
+			}
+			recordPrometheusWarnings(ctx, warnings)
+			return names, nil
+		},
+	}
+	for _, name := range names {
+		g.metrics[string(name)] = true
+	}
+	result, err := g.generate()
+	if err != nil {
+		return nil, fmt.Errorf("generate golden signal alert rules: %w", err)
+	}
+	return result, nil
+}
+
+var GenerateGoldenSignalAlertRules = mcpgrafana.MustTool(
+	"grafana_generate_golden_signal_alert_rules",
+	"Generate recommended alert rules for the latency, errors and saturation golden signals of a service, from the metrics of the service found in a Prometheus datasource and their usual naming conventions (OpenTelemetry, Prometheus client libraries, Micrometer, gRPC, cAdvisor). Returns the generated rules with their queries and thresholds, the signals no metrics were found for, and a provisioning bundle with one rule group, to be reviewed and then applied with `grafana_import_alerting_bundle`. Nothing is created in Grafana. Rule UIDs are stable, so applying the rules again updates them.",
+	generateGoldenSignalAlertRules,
+	mcp.WithTitleAnnotation("Generate golden signal alert rules"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,89 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateGoldenSignalAlertRules(t *testing.T) {
+	ctx := newFakeGrafanaContext(t, fakeRoutes{
+		"/api/datasources/uid/prom": jsonBody(`{"uid": "prom", "type": "prometheus"}`),
+		"/api/datasources/proxy/uid/prom/api/v1/label/__name__/values": func(w http.ResponseWriter, r *http.Request) {
+			assert.NoError(t, r.ParseForm())
+			assert.Equal(t, []string{"{job='checkout'}"}, r.Form["match[]"])
+			_, _ = w.Write([]byte(`{"status": "success", "data": ["http_request_duration_seconds_bucket", "http_requests_total", "process_open_fds", "process_max_fds", "up"]}`))
+		},
+		"/api/datasources/proxy/uid/prom/api/v1/labels": func(w http.ResponseWriter, r *http.Request) {
+			assert.NoError(t, r.ParseForm())
+			assert.Equal(t, []string{"http_requests_total{job='checkout'}"}, r.Form["match[]"])
+			_, _ = w.Write([]byte(`{"status": "success", "data": ["__name__", "code", "instance", "job"]}`))
+		},
+	})
+
+	result, err := generateGoldenSignalAlertRules(ctx, GenerateGoldenSignalAlertRulesParams{
+		DatasourceUID:       "prom",
+		Service:             "checkout",
+		ErrorRatioThreshold: 0.01,
+		Labels:              map[string]string{"team": "payments"},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Rules, 3)
+	assert.Empty(t, result.Skipped)
+
+	latency := result.Rules[0]
+	assert.Equal(t, "latency", latency.Signal)
+	assert.Equal(t, "histogram_quantile(0.99, sum by (le) (rate(http_request_duration_seconds_bucket{job='checkout'}[5m])))", latency.Expr)
+	assert.Equal(t, float64(1), latency.Threshold)
+	errors := result.Rules[1]
+	assert.Equal(t, `sum(rate(http_requests_total{job='checkout', code=~"5.."}[5m])) / sum(rate(http_requests_total{job='checkout'}[5m]))`, errors.Expr)
+	assert.Equal(t, 0.01, errors.Threshold)
+	assert.Equal(t, "checkout file descriptor saturation", result.Rules[2].Title)
+
+	// The UIDs are stable across calls.
+	again, err := generateGoldenSignalAlertRules(ctx, GenerateGoldenSignalAlertRulesParams{DatasourceUID: "prom", Service: "checkout", Signals: []string{"latency"}})
+	require.NoError(t, err)
+	require.Len(t, again.Rules, 1)
+	assert.Equal(t, latency.UID, again.Rules[0].UID)
+
+	bundle := result.Bundle.(alertingBundle)
+	require.Len(t, bundle.Groups, 1)
+	group := bundle.Groups[0].(map[string]any)
+	assert.Equal(t, "Golden signals", group["folder"])
+	assert.Equal(t, "checkout", group["name"])
+	rules := group["rules"].([]any)
+	require.Len(t, rules, 3)
+	rule := rules[1].(map[string]any)
+	assert.Equal(t, errors.UID, rule["uid"])
+	assert.Equal(t, "B", rule["condition"])
+	assert.Equal(t, map[string]any{"service": "checkout", "signal": "errors", "team": "payments"}, rule["labels"])
+	query := rule["data"].([]any)[0].(map[string]any)
+	assert.Equal(t, "prom", query["datasourceUid"])
+	assert.Equal(t, errors.Expr, query["model"].(map[string]any)["expr"])
+
+	t.Run("invalid signal", func(t *testing.T) {
+		_, err := generateGoldenSignalAlertRules(ctx, GenerateGoldenSignalAlertRulesParams{DatasourceUID: "prom", Service: "checkout", Signals: []string{"traffic"}})
+		assert.ErrorContains(t, err, "invalid signal")
+	})
+}
+
+func TestGoldenSignalGeneratorSkipsMissingSignals(t *testing.T) {
+	g := &goldenSignalGenerator{
+		args:       GenerateGoldenSignalAlertRulesParams{Service: "worker"},
+		selector:   "job='worker'",
+		metrics:    map[string]bool{"grpc_server_handled_total": true},
+		labelNames: func(string) ([]string, error) { return []string{"grpc_code"}, nil },
+	}
+	result, err := g.generate()
+	require.NoError(t, err)
+	require.Len(t, result.Rules, 1)
+	assert.Contains(t, result.Rules[0].Expr, `grpc_code=~"Unknown|DeadlineExceeded|Internal|Unavailable|DataLoss"`)
+	require.Len(t, result.Skipped, 2)
+	assert.Contains(t, result.Skipped[0], "latency")
+	assert.Contains(t, result.Skipped[1], "saturation")
+}
@@ -0,0 +1,138 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	alertGroupsPath = "/api/alertmanager/grafana/api/v2/alerts/groups"
+
+	// DefaultMaxAlertsPerGroup is the default maximum number of alerts
+	// returned per alert group.
+	DefaultMaxAlertsPerGroup = 20
+)
+
+// alertStateActive is the state of alerts which are neither silenced nor
+// inhibited.
+const alertStateActive = "active"
+
+type ListAlertGroupsParams struct {
+	Matchers          []SilenceMatcher `json:"matchers,omitempty" jsonschema:"description=Optionally\\, only list the alerts with these labels"`
+	Receiver          string           `json:"receiver,omitempty" jsonschema:"description=Optionally\\, a regular expression matching the contact points notified of the alerts"`
+	ExcludeSilenced   bool             `json:"excludeSilenced,omitempty" jsonschema:"description=Whether to leave out silenced alerts"`
+	ExcludeInhibited  bool             `json:"excludeInhibited,omitempty" jsonschema:"description=Whether to leave out inhibited alerts"`
+	MaxAlertsPerGroup int              `json:"maxAlertsPerGroup,omitempty" jsonschema:"description=Optionally\\, the maximum number of alerts returned per group (default 20). Groups are always fully counted"`
+}
+
+// AlertInstance is an alert of Grafana Alerting, i.e. an instance of an alert
+// rule with its labels.
+type AlertInstance struct {
+	Fingerprint string            `json:"fingerprint"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// State is active, suppressed when silenced or inhibited, or
+	// unprocessed.
+	State        string    `json:"state"`
+	SilencedBy   []string  `json:"silencedBy,omitempty"`
+	InhibitedBy  []string  `json:"inhibitedBy,omitempty"`
+	StartsAt     time.Time `json:"startsAt"`
+	GeneratorURL string    `json:"generatorURL,omitempty"`
+}
+
+// AlertGroup is a group of alerts notified together by the Alertmanager, as
+// set up by the group_by of the notification policy routing them.
+type AlertGroup struct {
+	Labels    map[string]string `json:"labels"`
+	Receiver  string            `json:"receiver"`
+	Total     int               `json:"total"`
+	Active    int               `json:"active"`
+	Silenced  int               `json:"silenced"`
+	Inhibited int               `json:"inhibited"`
+	Alerts    []AlertInstance   `json:"alerts"`
+}
+
+// gettableAlertGroup is an alert group returned by the Alertmanager API.
+type gettableAlertGroup struct {
+	Labels   map[string]string `json:"labels"`
+	Receiver struct {
+		Name string `json:"name"`
+	} `json:"receiver"`
+	Alerts []struct {
+		AlertInstance
+		Status struct {
+			State       string   `json:"state"`
+			SilencedBy  []string `json:"silencedBy"`
+			InhibitedBy []string `json:"inhibitedBy"`
+		} `json:"status"`
+	} `json:"alerts"`
+}
+
+func listAlertGroups(ctx context.Context, args ListAlertGroupsParams) ([]AlertGroup, error) {
+	params := url.Values{}
+	params.Set("active", "true")
+	params.Set("silenced", strconv.FormatBool(!args.ExcludeSilenced))
+	params.Set("inhibited", strconv.FormatBool(!args.ExcludeInhibited))
+	for _, m := range args.Matchers {
+		params.Add("filter", m.filter())
+	}
+	if args.Receiver != "" {
+		params.Set("receiver", args.Receiver)
+	}
+	c, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list alert groups: %w", err)
+	}
+	var groups []gettableAlertGroup
+	if err := c.getJSON(ctx, alertGroupsPath, params, &groups); err != nil {
+		return nil, fmt.Errorf("list alert groups: %w", err)
+	}
+
+	limit := cmp.Or(args.MaxAlertsPerGroup, DefaultMaxAlertsPerGroup)
+	truncated := false
+	result := make([]AlertGroup, 0, len(groups))
+	for _, g := range groups {
+		group := AlertGroup{Labels: g.Labels, Receiver: g.Receiver.Name, Total: len(g.Alerts), Alerts: []AlertInstance{}}
+		for _, a := range g.Alerts {
+			switch {
+			case len(a.Status.SilencedBy) > 0:
+				group.Silenced++
+			case len(a.Status.InhibitedBy) > 0:
+				group.Inhibited++
+			case a.Status.State == alertStateActive:
+				group.Active++
+			}
+			if len(group.Alerts) == limit {
+				truncated = true
+				continue
+			}
+			alert := a.AlertInstance
+			alert.State = a.Status.State
+			alert.SilencedBy = a.Status.SilencedBy
+			alert.InhibitedBy = a.Status.InhibitedBy
+			group.Alerts = append(group.Alerts, alert)
+		}
+		result = append(result, group)
+	}
+	if truncated {
+		mcpgrafana.SetResultNote(ctx, "alertGroupsLimit", fmt.Sprintf("Some groups have more than %d alerts, which were left out; narrow down the matchers or raise maxAlertsPerGroup.", limit))
+	}
+	return result, nil
+}
+
+var ListAlertGroups = mcpgrafana.MustTool(
+	"grafana_list_alert_groups",
+	"List the alerts currently firing in Grafana Alerting, grouped as the Alertmanager notifies them, with the labels of each group and its contact point. Each alert instance comes with its labels, annotations, start time and whether it is silenced or inhibited, with the IDs of the silences or the fingerprints of the alerts inhibiting it, and each group counts its active, silenced and inhibited alerts. Unlike `grafana_list_alert_rules`, which returns the state of rules, this returns their individual alert instances. Filter by label matchers or contact point.",
+	listAlertGroups,
+	mcp.WithTitleAnnotation("List alert groups"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,53 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListAlertGroups(t *testing.T) {
+	var query url.Values
+	ctx := newFakeGrafanaContext(t, fakeRoutes{
+		"/api/alertmanager/grafana/api/v2/alerts/groups": func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.Query()
+			_, _ = w.Write([]byte(`[{
+				"labels": {"alertname": "HighCPU"},
+				"receiver": {"name": "ops"},
+				"alerts": [
+					{"fingerprint": "a1", "labels": {"alertname": "HighCPU", "instance": "a"}, "startsAt": "2024-05-01T10:00:00Z", "status": {"state": "active", "silencedBy": [], "inhibitedBy": []}},
+					{"fingerprint": "a2", "labels": {"alertname": "HighCPU", "instance": "b"}, "startsAt": "2024-05-01T10:00:00Z", "status": {"state": "suppressed", "silencedBy": ["s1"], "inhibitedBy": []}},
+					{"fingerprint": "a3", "labels": {"alertname": "HighCPU", "instance": "c"}, "startsAt": "2024-05-01T10:00:00Z", "status": {"state": "suppressed", "silencedBy": [], "inhibitedBy": ["a9"]}}
+				]
+			}]`))
+		},
+	})
+
+	groups, err := listAlertGroups(ctx, ListAlertGroupsParams{
+		Matchers:          []SilenceMatcher{{Name: "alertname", Value: "HighCPU"}},
+		ExcludeInhibited:  true,
+		MaxAlertsPerGroup: 2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{`alertname="HighCPU"`}, query["filter"])
+	assert.Equal(t, "true", query.Get("silenced"))
+	assert.Equal(t, "false", query.Get("inhibited"))
+
+	require.Len(t, groups, 1)
+	group := groups[0]
+	assert.Equal(t, "ops", group.Receiver)
+	assert.Equal(t, 3, group.Total)
+	assert.Equal(t, 1, group.Active)
+	assert.Equal(t, 1, group.Silenced)
+	assert.Equal(t, 1, group.Inhibited)
+	require.Len(t, group.Alerts, 2, "limited to maxAlertsPerGroup")
+	assert.Equal(t, "active", group.Alerts[0].State)
+	assert.Equal(t, []string{"s1"}, group.Alerts[1].SilencedBy)
+	assert.Equal(t, "suppressed", group.Alerts[1].State)
+}
@@ -0,0 +1,228 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	stateHistoryPath = "/api/v1/rules/history"
+
+	// DefaultAlertStateHistoryLimit is the default maximum number of state
+	// transitions returned.
+	DefaultAlertStateHistoryLimit = 200
+)
+
+type GetAlertStateHistoryParams struct {
+	RuleUID string            `json:"ruleUid,omitempty" jsonschema:"description=Optionally\\, the UID of the alert rule. Otherwise the transitions of all rules are returned"`
+	Labels  map[string]string `json:"labels,omitempty" jsonschema:"description=Optionally\\, only return the transitions of alert instances with these labels"`
+	From    string            `json:"from,omitempty" jsonschema:"description=Optionally\\, the start of the time range\\, e.g. now-24h or RFC3339 (default now-24h)"`
+	To      string            `json:"to,omitempty" jsonschema:"description=Optionally\\, the end of the time range\\, e.g. now or RFC3339 (default now)"`
+	Limit   int               `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of transitions to return (default 200)"`
+}
+
+// AlertStateTransition is a change of the state of an alert instance.
+type AlertStateTransition struct {
+	Time      time.Time          `json:"time"`
+	RuleUID   string             `json:"ruleUid"`
+	RuleTitle string             `json:"ruleTitle,omitempty"`
+	Previous  string             `json:"previous"`
+	Current   string             `json:"current"`
+	Labels    map[string]string  `json:"labels,omitempty"`
+	Values    map[string]float64 `json:"values,omitempty"`
+	Error     string             `json:"error,omitempty"`
+}
+
+// AlertInstanceHistory summarizes the transitions of an alert instance, to
+// spot flapping instances at a glance.
+type AlertInstanceHistory struct {
+	RuleUID     string            `json:"ruleUid"`
+	RuleTitle   string            `json:"ruleTitle,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Transitions int               `json:"transitions"`
+	// Firings is the number of transitions to the Alerting state.
+	Firings   int    `json:"firings"`
+	LastState string `json:"lastState"`
+}
+
+// AlertStateHistory is the state history of alert rules in a time range,
+// oldest transition first.
+type AlertStateHistory struct {
+	Start       time.Time              `json:"start"`
+	End         time.Time              `json:"end"`
+	Transitions []AlertStateTransition `json:"transitions"`
+	// Instances are sorted by number of transitions, most first.
+	Instances []AlertInstanceHistory `json:"instances"`
+}
+
+// stateHistoryFrame is the data frame returned by the state history API,
+// with a time, a line and a labels field.
+type stateHistoryFrame struct {
+	Schema struct {
+		Fields []struct {
+			Name string `json:"name"`
+		} `json:"fields"`
+	} `json:"schema"`
+	Data struct {
+		Values []json.RawMessage `json:"values"`
+	} `json:"data"`
+}
+
+// stateHistoryLine is the line of a state transition.
+type stateHistoryLine struct {
+	Previous  string             `json:"previous"`
+	Current   string             `json:"current"`
+	Error     string             `json:"error"`
+	Values    map[string]float64 `json:"values"`
+	RuleTitle string             `json:"ruleTitle"`
+	RuleUID   string             `json:"ruleUID"`
+	Labels    map[string]string  `json:"labels"`
+}
+
+// field decodes the values of the field of the frame with the given name.
+func (f stateHistoryFrame) field(name string, out any) error {
+	for i, field := range f.Schema.Fields {
+		if field.Name == name && i < len(f.Data.Values) {
+			return json.Unmarshal(f.Data.Values[i], out)
+		}
+	}
+	return fmt.Errorf("the state history has no %s field", name)
+}
+
+// transitions returns the state transitions of the frame.
+func (f stateHistoryFrame) transitions() ([]AlertStateTransition, error) {
+	var times []int64
+	var lines []stateHistoryLine
+	if err := f.field("time", &times); err != nil {
+		return nil, err
+	}
+	if err := f.field("line", &lines); err != nil {
+		return nil, err
+	}
+	// The labels of the frame identify the rule, e.g. by folder and
+	// group, and are only used when lines have no labels.
+	var streamLabels []map[string]string
+	_ = f.field("labels", &streamLabels)
+	transitions := make([]AlertStateTransition, 0, len(lines))
+	for i, line := range lines {
+		if i >= len(times) {
+			break
+		}
+		labels := line.Labels
+		if labels == nil && i < len(streamLabels) {
+			labels = streamLabels[i]
+		}
+		transitions = append(transitions, AlertStateTransition{
+			Time:      time.UnixMilli(times[i]).UTC(),
+			RuleUID:   line.RuleUID,
+			RuleTitle: line.RuleTitle,
+			Previous:  line.Previous,
+			Current:   line.Current,
+			Labels:    labels,
+			Values:    line.Values,
+			Error:     line.Error,
+		})
+	}
+	return transitions, nil
+}
+
+// summarizeAlertInstances counts the transitions of each alert instance,
+// identified by its rule and labels.
+func summarizeAlertInstances(transitions []AlertStateTransition) []AlertInstanceHistory {
+	byKey := map[string]*AlertInstanceHistory{}
+	var keys []string
+	for _, t := range transitions {
+		var key strings.Builder
+		key.WriteString(t.RuleUID)
+		for _, name := range slices.Sorted(maps.Keys(t.Labels)) {
+			fmt.Fprintf(&key, "\x00%s=%s", name, t.Labels[name])
+		}
+		instance, ok := byKey[key.String()]
+		if !ok {
+			instance = &AlertInstanceHistory{RuleUID: t.RuleUID, RuleTitle: t.RuleTitle, Labels: t.Labels}
+			byKey[key.String()] = instance
+			keys = append(keys, key.String())
+		}
+		instance.Transitions++
+		// States may have a reason, e.g. "Alerting (Error)".
+		if strings.HasPrefix(t.Current, "Alerting") {
+			instance.Firings++
+		}
+		instance.LastState = t.Current
+	}
+	instances := make([]AlertInstanceHistory, 0, len(keys))
+	for _, key := range keys {
+		instances = append(instances, *byKey[key])
+	}
+	slices.SortStableFunc(instances, func(a, b AlertInstanceHistory) int { return cmp.Compare(b.Transitions, a.Transitions) })
+	return instances
+}
+
+func getAlertStateHistory(ctx context.Context, args GetAlertStateHistoryParams) (*AlertStateHistory, error) {
+	start, err := parseTime(cmp.Or(args.From, "now-24h"))
+	if err != nil {
+		return nil, fmt.Errorf("get alert state history: invalid from: %w", err)
+	}
+	end, err := parseTime(cmp.Or(args.To, "now"))
+	if err != nil {
+		return nil, fmt.Errorf("get alert state history: invalid to: %w", err)
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("get alert state history: the end of the time range must be after its start")
+	}
+	limit := cmp.Or(args.Limit, DefaultAlertStateHistoryLimit)
+	params := url.Values{}
+	params.Set("from", strconv.FormatInt(start.Unix(), 10))
+	params.Set("to", strconv.FormatInt(end.Unix(), 10))
+	params.Set("limit", strconv.Itoa(limit))
+	if args.RuleUID != "" {
+		params.Set("ruleUID", args.RuleUID)
+	}
+	for name, value := range args.Labels {
+		params.Set("labels_"+name, value)
+	}
+
+	c, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get alert state history: %w", err)
+	}
+	var frame stateHistoryFrame
+	if err := c.getJSON(ctx, stateHistoryPath, params, &frame); err != nil {
+		return nil, fmt.Errorf("get alert state history: %w", err)
+	}
+	transitions, err := frame.transitions()
+	if err != nil {
+		return nil, fmt.Errorf("get alert state history: %w", err)
+	}
+	slices.SortStableFunc(transitions, func(a, b AlertStateTransition) int { return a.Time.Compare(b.Time) })
+	if len(transitions) == limit {
+		mcpgrafana.SetResultNote(ctx, "alertStateHistoryLimit", fmt.Sprintf("The state history has at least %d transitions, so older ones may be missing; narrow down the time range or raise the limit.", limit))
+	}
+	return &AlertStateHistory{
+		Start:       start.UTC(),
+		End:         end.UTC(),
+		Transitions: transitions,
+		Instances:   summarizeAlertInstances(transitions),
+	}, nil
+}
+
+var GetAlertStateHistory = mcpgrafana.MustTool(
+	"grafana_get_alert_state_history",
+	"Get the state history of Grafana-managed alert rules in a time range: when their alert instances changed between Normal, Pending, Alerting, NoData and Error, with the values of their queries at the time. Filter by rule UID and instance labels. The transitions come with a summary per alert instance, with its number of transitions and firings, most transitions first, which makes flapping alerts stand out. Useful for flapping analysis and postmortems.",
+	getAlertStateHistory,
+	mcp.WithTitleAnnotation("Get alert state history"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
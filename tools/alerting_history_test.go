@@ -0,0 +1,66 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stateHistoryTestFrame is a state history frame with three transitions of
+// an instance, newest first, and one of another instance.
+const stateHistoryTestFrame = `{
+	"schema": {"fields": [{"name": "time", "type": "time"}, {"name": "line", "type": "other"}, {"name": "labels", "type": "other"}]},
+	"data": {"values": [
+		[1714557600000, 1714557300000, 1714557000000, 1714556000000],
+		[
+			{"previous": "Alerting", "current": "Normal", "ruleUID": "cpu", "ruleTitle": "High CPU", "labels": {"instance": "a"}},
+			{"previous": "Pending", "current": "Alerting", "ruleUID": "cpu", "ruleTitle": "High CPU", "labels": {"instance": "a"}, "values": {"A": 0.97}},
+			{"previous": "Normal", "current": "Pending", "ruleUID": "cpu", "ruleTitle": "High CPU", "labels": {"instance": "a"}},
+			{"previous": "Normal", "current": "Alerting (Error)", "ruleUID": "cpu", "ruleTitle": "High CPU", "error": "timeout"}
+		],
+		[{"folderUID": "infra"}, {"folderUID": "infra"}, {"folderUID": "infra"}, {"folderUID": "infra"}]
+	]}
+}`
+
+func TestGetAlertStateHistory(t *testing.T) {
+	var query url.Values
+	ctx := newFakeGrafanaContext(t, fakeRoutes{
+		"/api/v1/rules/history": func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.Query()
+			_, _ = w.Write([]byte(stateHistoryTestFrame))
+		},
+	})
+
+	history, err := getAlertStateHistory(ctx, GetAlertStateHistoryParams{
+		RuleUID: "cpu",
+		Labels:  map[string]string{"instance": "a"},
+		From:    "2024-05-01T00:00:00Z",
+		To:      "2024-05-02T00:00:00Z",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "cpu", query.Get("ruleUID"))
+	assert.Equal(t, "a", query.Get("labels_instance"))
+	assert.Equal(t, "1714521600", query.Get("from"))
+	assert.Equal(t, "200", query.Get("limit"))
+
+	require.Len(t, history.Transitions, 4)
+	assert.Equal(t, time.UnixMilli(1714556000000).UTC(), history.Transitions[0].Time, "oldest first")
+	assert.Equal(t, map[string]string{"folderUID": "infra"}, history.Transitions[0].Labels, "falls back to the frame labels")
+	assert.Equal(t, "timeout", history.Transitions[0].Error)
+	assert.Equal(t, map[string]float64{"A": 0.97}, history.Transitions[2].Values)
+
+	assert.Equal(t, []AlertInstanceHistory{
+		{RuleUID: "cpu", RuleTitle: "High CPU", Labels: map[string]string{"instance": "a"}, Transitions: 3, Firings: 1, LastState: "Normal"},
+		{RuleUID: "cpu", RuleTitle: "High CPU", Labels: map[string]string{"folderUID": "infra"}, Transitions: 1, Firings: 1, LastState: "Alerting (Error)"},
+	}, history.Instances)
+
+	_, err = getAlertStateHistory(ctx, GetAlertStateHistoryParams{From: "now", To: "now-1h"})
+	assert.ErrorContains(t, err, "must be after its start")
+}
@@ -0,0 +1,502 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// redactedValue is the value Grafana substitutes for secure settings of
+// contact points when they are exported without decryption.
+const redactedValue = "[REDACTED]"
+
+// Actions reported for each resource in an import plan.
+const (
+	importActionCreate    = "create"
+	importActionUpdate    = "update"
+	importActionDelete    = "delete"
+	importActionUnchanged = "unchanged"
+)
+
+type ImportAlertingBundleParams struct {
+	Bundle            string `json:"bundle" jsonschema:"required,description=The alerting provisioning bundle to import as YAML or JSON\\, as produced by grafana_export_alerting_bundle"`
+	DryRun            bool   `json:"dryRun,omitempty" jsonschema:"description=If true\\, only report the changes that would be made without applying them. Always do a dry run first and review the changes"`
+	DisableProvenance bool   `json:"disableProvenance,omitempty" jsonschema:"description=If true\\, imported resources remain editable in the Grafana UI. Otherwise they are marked as provisioned"`
+}
+
+// importChange describes a change to a single resource made by an import.
+type importChange struct {
+	Section     string               `json:"section"`
+	Name        string               `json:"name"`
+	Action      string               `json:"action"`
+	Differences []alertRuleFieldDiff `json:"differences,omitempty"`
+}
+
+type importResult struct {
+	DryRun   bool           `json:"dryRun"`
+	Applied  int            `json:"applied"`
+	Changes  []importChange `json:"changes"`
+	Warnings []string       `json:"warnings,omitempty"`
+}
+
+// importStep applies a set of changes to Grafana.
+type importStep struct {
+	changes int
+	apply   func(ctx context.Context) error
+}
+
+// importPlan is the set of changes needed to apply a bundle, and the steps
+// applying them in dependency order.
+type importPlan struct {
+	result importResult
+	steps  []importStep
+}
+
+func (p *importPlan) add(section, name string, differences []alertRuleFieldDiff, exists bool) string {
+	action := importActionUnchanged
+	switch {
+	case !exists:
+		action = importActionCreate
+	case len(differences) > 0:
+		action = importActionUpdate
+	}
+	p.result.Changes = append(p.result.Changes, importChange{Section: section, Name: name, Action: action, Differences: differences})
+	return action
+}
+
+func asObject(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+func asString(m map[string]any, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+// without returns a copy of m without the given keys.
+func without(m map[string]any, keys ...string) map[string]any {
+	result := make(map[string]any, len(m))
+	for k, v := range m {
+		result[k] = v
+	}
+	for _, k := range keys {
+		delete(result, k)
+	}
+	return result
+}
+
+func diffObjects(definition, grafana map[string]any) []alertRuleFieldDiff {
+	diffs := []alertRuleFieldDiff{}
+	diffJSON("", "", definition, grafana, &diffs)
+	return diffs
+}
+
+// containsRedacted returns true if any of the settings of a contact point
+// were redacted when it was exported.
+func containsRedacted(settings map[string]any) bool {
+	for _, v := range settings {
+		switch v := v.(type) {
+		case string:
+			if v == redactedValue {
+				return true
+			}
+		case map[string]any:
+			if containsRedacted(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// exportRuleToAPI converts a rule from the file provisioning format to the
+// format of the provisioning HTTP API.
+func exportRuleToAPI(rule map[string]any, folderUID, group string) map[string]any {
+	result := without(rule, "dashboardUid", "panelId")
+	annotations := map[string]any{}
+	for k, v := range asObject(rule["annotations"]) {
+		annotations[k] = v
+	}
+	if dashboardUID := asString(rule, "dashboardUid"); dashboardUID != "" {
+		annotations["__dashboardUid__"] = dashboardUID
+	}
+	if panelID, ok := rule["panelId"].(float64); ok {
+		annotations["__panelId__"] = strconv.FormatInt(int64(panelID), 10)
+	}
+	if len(annotations) > 0 {
+		result["annotations"] = annotations
+	}
+	result["folderUID"] = folderUID
+	result["ruleGroup"] = group
+	return result
+}
+
+func groupIntervalSeconds(group map[string]any) (int64, error) {
+	switch v := group["interval"].(type) {
+	case float64:
+		return int64(v), nil
+	case string:
+		d, ok := parseAnyDuration(v)
+		if !ok {
+			return 0, fmt.Errorf("invalid interval %q for rule group %s", v, asString(group, "name"))
+		}
+		return int64(d / time.Second), nil
+	}
+	return 0, fmt.Errorf("missing interval for rule group %s", asString(group, "name"))
+}
+
+// folderResolver resolves folder titles to UIDs, creating folders which
+// don't exist yet.
+type folderResolver struct {
+	c    *alertingClient
+	uids map[string]string
+	// created are the titles of the folders which were created.
+	created map[string]bool
+}
+
+func (r *folderResolver) resolve(ctx context.Context, title string) (string, error) {
+	if r.uids == nil {
+		var folders []struct {
+			UID   string `json:"uid"`
+			Title string `json:"title"`
+		}
+		if err := r.c.getJSON(ctx, "/api/folders", url.Values{"limit": []string{"1000"}}, &folders); err != nil {
+			return "", fmt.Errorf("list folders: %w", err)
+		}
+		r.uids = make(map[string]string, len(folders))
+		for _, f := range folders {
+			r.uids[f.Title] = f.UID
+		}
+	}
+	if uid, ok := r.uids[title]; ok {
+		return uid, nil
+	}
+	var created struct {
+		UID string `json:"uid"`
+	}
+	if err := r.c.sendJSON(ctx, http.MethodPost, "/api/folders", map[string]any{"title": title}, &created); err != nil {
+		return "", fmt.Errorf("create folder %s: %w", title, err)
+	}
+	r.uids[title] = created.UID
+	if r.created == nil {
+		r.created = map[string]bool{}
+	}
+	r.created[title] = true
+	return created.UID, nil
+}
+
+func (p *importPlan) planTemplates(c *alertingClient, templates, live []bundleTemplate) {
+	existing := make(map[string]string, len(live))
+	for _, t := range live {
+		existing[t.Name] = t.Template
+	}
+	for _, t := range templates {
+		current, exists := existing[t.Name]
+		var diffs []alertRuleFieldDiff
+		if exists && current != t.Template {
+			diffs = []alertRuleFieldDiff{{Path: "template", Kind: "changed", Definition: t.Template, Grafana: current}}
+		}
+		if p.add("templates", t.Name, diffs, exists) == importActionUnchanged {
+			continue
+		}
+		p.steps = append(p.steps, importStep{changes: 1, apply: func(ctx context.Context) error {
+			if err := c.sendJSON(ctx, http.MethodPut, "/api/v1/provisioning/templates/"+url.PathEscape(t.Name), map[string]any{"template": t.Template}, nil); err != nil {
+				return fmt.Errorf("import template %s: %w", t.Name, err)
+			}
+			return nil
+		}})
+	}
+}
+
+func (p *importPlan) planMuteTimings(c *alertingClient, muteTimes, live []any) {
+	existing := map[string]map[string]any{}
+	for _, m := range live {
+		mt := asObject(m)
+		existing[asString(mt, "name")] = mt
+	}
+	for _, m := range muteTimes {
+		mt := without(asObject(m), "orgId", "orgID")
+		name := asString(mt, "name")
+		current, exists := existing[name]
+		var diffs []alertRuleFieldDiff
+		if exists {
+			diffs = diffObjects(mt, current)
+		}
+		action := p.add("muteTimings", name, diffs, exists)
+		if action == importActionUnchanged {
+			continue
+		}
+		p.steps = append(p.steps, importStep{changes: 1, apply: func(ctx context.Context) error {
+			var err error
+			if action == importActionCreate {
+				err = c.sendJSON(ctx, http.MethodPost, muteTimingsPath, mt, nil)
+			} else {
+				err = c.sendJSON(ctx, http.MethodPut, muteTimingsPath+"/"+url.PathEscape(name), mt, nil)
+			}
+			if err != nil {
+				return fmt.Errorf("import mute timing %s: %w", name, err)
+			}
+			return nil
+		}})
+	}
+}
+
+func (p *importPlan) planContactPoints(c *alertingClient, contactPoints, live []any) {
+	existing := map[string]map[string]any{}
+	for _, cp := range live {
+		receivers, _ := asObject(cp)["receivers"].([]any)
+		for _, r := range receivers {
+			receiver := asObject(r)
+			existing[asString(receiver, "uid")] = receiver
+		}
+	}
+	for _, cp := range contactPoints {
+		contactPoint := asObject(cp)
+		name := asString(contactPoint, "name")
+		receivers, _ := contactPoint["receivers"].([]any)
+		for _, r := range receivers {
+			receiver := asObject(r)
+			uid := asString(receiver, "uid")
+			current, exists := existing[uid]
+			if uid == "" {
+				exists = false
+			}
+			var diffs []alertRuleFieldDiff
+			if exists {
+				diffs = diffObjects(receiver, current)
+			}
+			label := name
+			if uid != "" {
+				label = fmt.Sprintf("%s (%s)", name, uid)
+			}
+			action := p.add("contactPoints", label, diffs, exists)
+			if action == importActionUnchanged {
+				continue
+			}
+			if action == importActionCreate && containsRedacted(asObject(receiver["settings"])) {
+				p.result.Warnings = append(p.result.Warnings, fmt.Sprintf("contact point %s contains redacted secrets which will be imported as %q; export the bundle with decryptSecrets to import them", label, redactedValue))
+			}
+			body := without(receiver)
+			body["name"] = name
+			p.steps = append(p.steps, importStep{changes: 1, apply: func(ctx context.Context) error {
+				var err error
+				if action == importActionCreate {
+					err = c.sendJSON(ctx, http.MethodPost, "/api/v1/provisioning/contact-points", body, nil)
+				} else {
+					err = c.sendJSON(ctx, http.MethodPut, contactPointsPath+"/"+url.PathEscape(uid), body, nil)
+				}
+				if err != nil {
+					return fmt.Errorf("import contact point %s: %w", label, err)
+				}
+				return nil
+			}})
+		}
+	}
+}
+
+func (p *importPlan) planPolicies(c *alertingClient, policies, live []any) {
+	if len(policies) == 0 {
+		return
+	}
+	if len(policies) > 1 {
+		p.result.Warnings = append(p.result.Warnings, fmt.Sprintf("bundle contains %d notification policy trees, only the first is imported", len(policies)))
+	}
+	tree := without(asObject(policies[0]), "orgId", "orgID")
+	var current map[string]any
+	if len(live) > 0 {
+		current = asObject(live[0])
+	}
+	if p.add("policies", "notification policy tree", diffObjects(tree, current), true) == importActionUnchanged {
+		return
+	}
+	p.steps = append(p.steps, importStep{changes: 1, apply: func(ctx context.Context) error {
+		if err := c.sendJSON(ctx, http.MethodPut, "/api/v1/provisioning/policies", tree, nil); err != nil {
+			return fmt.Errorf("import notification policies: %w", err)
+		}
+		return nil
+	}})
+}
+
+func (p *importPlan) planRuleGroups(c *alertingClient, folders *folderResolver, groups, live []any) error {
+	type groupKey struct{ folder, name string }
+	existing := map[groupKey]map[string]any{}
+	for _, g := range live {
+		group := asObject(g)
+		existing[groupKey{asString(group, "folder"), asString(group, "name")}] = group
+	}
+
+	for _, g := range groups {
+		group := asObject(g)
+		folder, name := asString(group, "folder"), asString(group, "name")
+		if folder == "" || name == "" {
+			return fmt.Errorf("rule groups must have a folder and a name")
+		}
+		interval, err := groupIntervalSeconds(group)
+		if err != nil {
+			return err
+		}
+		groupLabel := fmt.Sprintf("%s/%s", folder, name)
+
+		current, groupExists := existing[groupKey{folder, name}]
+		liveRules := map[string]map[string]any{}
+		var groupDiffs []alertRuleFieldDiff
+		if groupExists {
+			groupDiffs = diffObjects(withoutRules(group), withoutRules(current))
+			rules, _ := current["rules"].([]any)
+			for _, r := range rules {
+				rule := asObject(r)
+				liveRules[asString(rule, "uid")] = rule
+			}
+		}
+
+		changed := p.add("ruleGroups", groupLabel, groupDiffs, groupExists) != importActionUnchanged
+		rules, _ := group["rules"].([]any)
+		apiRules := make([]any, 0, len(rules))
+		seen := map[string]bool{}
+		for _, r := range rules {
+			rule := asObject(r)
+			uid := asString(rule, "uid")
+			if uid == "" {
+				return fmt.Errorf("rule %q in group %s has no uid; rules must have a uid to be imported idempotently", asString(rule, "title"), groupLabel)
+			}
+			seen[uid] = true
+			liveRule, exists := liveRules[uid]
+			var diffs []alertRuleFieldDiff
+			if exists {
+				diffs = diffObjects(rule, liveRule)
+			}
+			if p.add("rules", fmt.Sprintf("%s (%s)", asString(rule, "title"), uid), diffs, exists) != importActionUnchanged {
+				changed = true
+			}
+			apiRules = append(apiRules, exportRuleToAPI(rule, "", name))
+		}
+		// Updating a rule group replaces all of its rules, so rules in
+		// Grafana which are missing from the bundle are deleted.
+		for uid, rule := range liveRules {
+			if !seen[uid] {
+				p.result.Changes = append(p.result.Changes, importChange{Section: "rules", Name: fmt.Sprintf("%s (%s)", asString(rule, "title"), uid), Action: importActionDelete})
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		p.steps = append(p.steps, importStep{changes: 1, apply: func(ctx context.Context) error {
+			folderUID, err := folders.resolve(ctx, folder)
+			if err != nil {
+				return fmt.Errorf("import rule group %s: %w", groupLabel, err)
+			}
+			for _, r := range apiRules {
+				asObject(r)["folderUID"] = folderUID
+			}
+			body := map[string]any{
+				"title":     name,
+				"folderUid": folderUID,
+				"interval":  interval,
+				"rules":     apiRules,
+			}
+			path := ruleGroupPath(folderUID, name)
+			if err := c.sendJSON(ctx, http.MethodPut, path, body, nil); err != nil {
+				return fmt.Errorf("import rule group %s: %w", groupLabel, err)
+			}
+			return nil
+		}})
+	}
+	return nil
+}
+
+// parseBundle parses a YAML or JSON alerting provisioning bundle.
+func parseBundle(s string) (*alertingBundle, error) {
+	doc, err := parseDefinition(s)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var bundle alertingBundle
+	if err := json.Unmarshal(b, &bundle); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	return &bundle, nil
+}
+
+func importAlertingBundle(ctx context.Context, args ImportAlertingBundleParams) (*importResult, error) {
+	bundle, err := parseBundle(args.Bundle)
+	if err != nil {
+		return nil, fmt.Errorf("import alerting bundle: %w", err)
+	}
+	c, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("import alerting bundle: %w", err)
+	}
+	c.disableProvenance = args.DisableProvenance
+
+	var sections []string
+	if len(bundle.Templates) > 0 {
+		sections = append(sections, bundleSectionTemplates)
+	}
+	if len(bundle.MuteTimes) > 0 {
+		sections = append(sections, bundleSectionMuteTimings)
+	}
+	if len(bundle.ContactPoints) > 0 {
+		sections = append(sections, bundleSectionContactPoints)
+	}
+	if len(bundle.Policies) > 0 {
+		sections = append(sections, bundleSectionPolicies)
+	}
+	if len(bundle.Groups) > 0 {
+		sections = append(sections, bundleSectionRules)
+	}
+	if len(sections) == 0 {
+		return nil, fmt.Errorf("import alerting bundle: bundle is empty")
+	}
+	live, err := fetchAlertingBundle(ctx, c, sections, false)
+	if err != nil {
+		return nil, fmt.Errorf("import alerting bundle: %w", err)
+	}
+
+	// Resources are planned and applied in dependency order: templates and
+	// mute timings are used by contact points and policies, contact points
+	// by policies and rules.
+	plan := &importPlan{result: importResult{DryRun: args.DryRun, Changes: []importChange{}}}
+	plan.planTemplates(c, bundle.Templates, live.Templates)
+	plan.planMuteTimings(c, bundle.MuteTimes, live.MuteTimes)
+	plan.planContactPoints(c, bundle.ContactPoints, live.ContactPoints)
+	plan.planPolicies(c, bundle.Policies, live.Policies)
+	if err := plan.planRuleGroups(c, &folderResolver{c: c}, bundle.Groups, live.Groups); err != nil {
+		return nil, fmt.Errorf("import alerting bundle: %w", err)
+	}
+
+	if args.DryRun {
+		return &plan.result, nil
+	}
+	for _, step := range plan.steps {
+		if err := step.apply(ctx); err != nil {
+			if plan.result.Applied > 0 {
+				return nil, fmt.Errorf("import alerting bundle: %w (%d changes were applied before the failure)", err, plan.result.Applied)
+			}
+			return nil, fmt.Errorf("import alerting bundle: %w", err)
+		}
+		plan.result.Applied += step.changes
+	}
+	return &plan.result, nil
+}
+
+var ImportAlertingBundle = mcpgrafana.MustTool(
+	"grafana_import_alerting_bundle",
+	"Imports an alerting provisioning bundle, as produced by grafana_export_alerting_bundle, into the Grafana instance: alert rule groups, contact points, notification policies, mute timings and notification templates. Resources are matched by UID (rules and contact points) or name, and created or updated as needed. Importing a rule group replaces all of its rules, so rules in Grafana missing from the bundle are deleted, and importing policies replaces the whole notification policy tree. Folders referenced by rule groups are created if they don't exist. Use dryRun first to review the changes, which are reported per resource with the differing fields.",
+	importAlertingBundle,
+	mcp.WithTitleAnnotation("Import alerting configuration bundle"),
+	mcp.WithDestructiveHintAnnotation(true),
+)
@@ -0,0 +1,137 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const importTestBundle = `
+apiVersion: 1
+templates:
+  - name: custom/v2
+    template: '{{ define "custom" }}new{{ end }}'
+muteTimes:
+  - orgId: 1
+    name: weekends
+    time_intervals:
+      - weekdays: [saturday, sunday]
+contactPoints:
+  - orgId: 1
+    name: email
+    receivers:
+      - uid: cp-1
+        type: email
+        settings:
+          addresses: oncall@example.com
+groups:
+  - orgId: 1
+    name: cpu
+    folder: Infra
+    interval: 1m
+    rules:
+      - uid: rule-1
+        title: High CPU
+        condition: B
+        for: 10m
+        dashboardUid: dash
+        panelId: 2
+      - uid: rule-3
+        title: New rule
+        condition: A
+`
+
+type importTestServer struct {
+	mu     sync.Mutex
+	writes map[string]map[string]any
+	header http.Header
+}
+
+// routes returns the routes of a fake Grafana with the alerting
+// configuration the bundle is imported into, recording the writes to ts.
+func (ts *importTestServer) routes() fakeRoutes {
+	write := func(response string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ts.mu.Lock()
+			defer ts.mu.Unlock()
+			body, _ := io.ReadAll(r.Body)
+			var v map[string]any
+			_ = json.Unmarshal(body, &v)
+			ts.writes[r.Method+" "+r.URL.EscapedPath()] = v
+			ts.header = r.Header
+			_, _ = w.Write([]byte(response))
+		}
+	}
+	return fakeRoutes{
+		"GET /api/v1/provisioning/templates":             jsonBody(`[{"name": "custom/v2", "template": "{{ define \"custom\" }}old{{ end }}"}]`),
+		"GET /api/v1/provisioning/mute-timings/export":   jsonBody(`{"muteTimes": [{"orgId": 1, "name": "weekends", "time_intervals": [{"weekdays": ["saturday", "sunday"]}]}]}`),
+		"GET /api/v1/provisioning/contact-points/export": jsonBody(`{"contactPoints": []}`),
+		"GET /api/v1/provisioning/alert-rules/export": jsonBody(`{"groups": [{"name": "cpu", "folder": "Infra", "interval": "1m", "rules": [
+			{"uid": "rule-1", "title": "High CPU", "condition": "B", "for": "5m", "dashboardUid": "dash", "panelId": 2},
+			{"uid": "rule-2", "title": "Old rule", "condition": "A"}
+		]}]}`),
+		"GET /api/folders":                                           jsonBody(`[]`),
+		"POST /api/folders":                                          write(`{"uid": "new-folder"}`),
+		"PUT /api/v1/provisioning/templates/custom/v2":               write(`{}`),
+		"POST /api/v1/provisioning/contact-points":                   write(`{}`),
+		"PUT /api/v1/provisioning/folder/new-folder/rule-groups/cpu": write(`{}`),
+	}
+}
+
+func TestImportAlertingBundle(t *testing.T) {
+	t.Run("dry run", func(t *testing.T) {
+		ts := &importTestServer{writes: map[string]map[string]any{}}
+		ctx := newFakeGrafanaContext(t, ts.routes())
+		result, err := importAlertingBundle(ctx, ImportAlertingBundleParams{Bundle: importTestBundle, DryRun: true})
+		require.NoError(t, err)
+		assert.True(t, result.DryRun)
+		assert.Zero(t, result.Applied)
+		assert.Empty(t, ts.writes)
+
+		actions := map[string]string{}
+		for _, c := range result.Changes {
+			actions[c.Section+" "+c.Name] = c.Action
+		}
+		assert.Equal(t, map[string]string{
+			"templates custom/v2":        "update",
+			"muteTimings weekends":       "unchanged",
+			"contactPoints email (cp-1)": "create",
+			"ruleGroups Infra/cpu":       "unchanged",
+			"rules High CPU (rule-1)":    "update",
+			"rules New rule (rule-3)":    "create",
+			"rules Old rule (rule-2)":    "delete",
+		}, actions)
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		ts := &importTestServer{writes: map[string]map[string]any{}}
+		ctx := newFakeGrafanaContext(t, ts.routes())
+		result, err := importAlertingBundle(ctx, ImportAlertingBundleParams{Bundle: importTestBundle, DisableProvenance: true})
+		require.NoError(t, err)
+		assert.Equal(t, 3, result.Applied)
+		assert.Equal(t, "true", ts.header.Get("X-Disable-Provenance"))
+
+		assert.Contains(t, ts.writes, "PUT /api/v1/provisioning/templates/custom%2Fv2", "template names are escaped")
+		assert.Equal(t, map[string]any{"name": "email", "uid": "cp-1", "type": "email", "settings": map[string]any{"addresses": "oncall@example.com"}}, ts.writes["POST /api/v1/provisioning/contact-points"])
+		assert.Equal(t, map[string]any{"title": "Infra"}, ts.writes["POST /api/folders"])
+
+		group := ts.writes["PUT /api/v1/provisioning/folder/new-folder/rule-groups/cpu"]
+		require.NotNil(t, group)
+		assert.Equal(t, float64(60), group["interval"])
+		rules := group["rules"].([]any)
+		require.Len(t, rules, 2)
+		rule := rules[0].(map[string]any)
+		assert.Equal(t, "new-folder", rule["folderUID"])
+		assert.Equal(t, "cpu", rule["ruleGroup"])
+		assert.Equal(t, map[string]any{"__dashboardUid__": "dash", "__panelId__": "2"}, rule["annotations"])
+		assert.NotContains(t, rule, "dashboardUid")
+	})
+}
@@ -0,0 +1,157 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const muteTimingsPath = "/api/v1/provisioning/mute-timings"
+
+// MuteTimeRange is a range of times of day, e.g. 22:00 to 06:00.
+type MuteTimeRange struct {
+	StartTime string `json:"start_time" jsonschema:"required,description=The start of the range\\, e.g. 22:00"`
+	EndTime   string `json:"end_time" jsonschema:"required,description=The end of the range\\, e.g. 23:59"`
+}
+
+// MuteTimeInterval is a recurring period of a mute timing. Fields which are
+// not set match any time.
+type MuteTimeInterval struct {
+	Times       []MuteTimeRange `json:"times,omitempty" jsonschema:"description=Optionally\\, the times of day"`
+	Weekdays    []string        `json:"weekdays,omitempty" jsonschema:"description=Optionally\\, the days of the week or ranges of them\\, e.g. saturday or monday:friday"`
+	DaysOfMonth []string        `json:"days_of_month,omitempty" jsonschema:"description=Optionally\\, the days of the month or ranges of them\\, e.g. 1:7 or -1 for the last day"`
+	Months      []string        `json:"months,omitempty" jsonschema:"description=Optionally\\, the months or ranges of them\\, e.g. december or 1:3"`
+	Years       []string        `json:"years,omitempty" jsonschema:"description=Optionally\\, the years or ranges of them\\, e.g. 2025:2026"`
+	Location    string          `json:"location,omitempty" jsonschema:"description=Optionally\\, the time zone of the times\\, e.g. Europe/Paris (default UTC)"`
+}
+
+// MuteTiming is a named set of recurring periods during which the
+// notification policies using it don't send notifications, e.g. weekends or
+// maintenance windows.
+type MuteTiming struct {
+	Name          string             `json:"name"`
+	TimeIntervals []MuteTimeInterval `json:"time_intervals"`
+	Provenance    string             `json:"provenance,omitempty"`
+}
+
+type ListMuteTimingsParams struct{}
+
+func listMuteTimings(ctx context.Context, args ListMuteTimingsParams) ([]MuteTiming, error) {
+	c, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list mute timings: %w", err)
+	}
+	muteTimings := []MuteTiming{}
+	if err := c.getJSON(ctx, muteTimingsPath, nil, &muteTimings); err != nil {
+		return nil, fmt.Errorf("list mute timings: %w", err)
+	}
+	return muteTimings, nil
+}
+
+var ListMuteTimings = mcpgrafana.MustTool(
+	"grafana_list_mute_timings",
+	"List the mute timings of Grafana Alerting: named recurring periods, such as weekends or maintenance windows, during which the notification policies using them don't send notifications. To mute alerts once, e.g. during an incident, use silences instead.",
+	listMuteTimings,
+	mcp.WithTitleAnnotation("List mute timings"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type CreateMuteTimingParams struct {
+	Name              string             `json:"name" jsonschema:"required,description=The name of the mute timing"`
+	TimeIntervals     []MuteTimeInterval `json:"timeIntervals" jsonschema:"required,description=The recurring periods of the mute timing. Notifications are muted during any of them"`
+	DisableProvenance bool               `json:"disableProvenance,omitempty" jsonschema:"description=If true\\, the mute timing remains editable in the Grafana UI. Otherwise it is marked as provisioned"`
+}
+
+func createMuteTiming(ctx context.Context, args CreateMuteTimingParams) (*MuteTiming, error) {
+	if args.Name == "" {
+		return nil, fmt.Errorf("create mute timing: a name is required")
+	}
+	if len(args.TimeIntervals) == 0 {
+		return nil, fmt.Errorf("create mute timing: at least one time interval is required")
+	}
+	c, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create mute timing %s: %w", args.Name, err)
+	}
+	c.disableProvenance = args.DisableProvenance
+	var created MuteTiming
+	body := MuteTiming{Name: args.Name, TimeIntervals: args.TimeIntervals}
+	if err := c.sendJSON(ctx, http.MethodPost, muteTimingsPath, body, &created); err != nil {
+		return nil, fmt.Errorf("create mute timing %s: %w", args.Name, err)
+	}
+	return &created, nil
+}
+
+var CreateMuteTiming = mcpgrafana.MustTool(
+	"grafana_create_mute_timing",
+	"Create a mute timing of Grafana Alerting: a named set of recurring periods, e.g. weekends or a weekly maintenance window, during which notifications are muted. It only takes effect once notification policies use it.",
+	createMuteTiming,
+	mcp.WithTitleAnnotation("Create mute timing"),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithIdempotentHintAnnotation(false),
+)
+
+type DeleteMuteTimingParams struct {
+	Name              string `json:"name" jsonschema:"required,description=The name of the mute timing to delete"`
+	ConfirmationToken string `json:"confirmationToken,omitempty" jsonschema:"description=The token returned by a previous call for this mute timing. Without it nothing is deleted and the impact of the deletion is returned with a token"`
+}
+
+// DeleteMuteTimingResult describes a mute timing which was deleted, or would
+// be deleted if the deletion was confirmed.
+type DeleteMuteTimingResult struct {
+	Deleted      bool          `json:"deleted"`
+	MuteTiming   *MuteTiming   `json:"muteTiming"`
+	Confirmation *Confirmation `json:"confirmation,omitempty"`
+}
+
+func deleteMuteTiming(ctx context.Context, args DeleteMuteTimingParams) (*DeleteMuteTimingResult, error) {
+	if args.Name == "" {
+		return nil, fmt.Errorf("delete mute timing: a name is required")
+	}
+	c, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("delete mute timing %s: %w", args.Name, err)
+	}
+	path := muteTimingsPath + "/" + url.PathEscape(args.Name)
+	var muteTiming struct {
+		MuteTiming
+		Version string `json:"version"`
+	}
+	if err := c.getJSON(ctx, path, nil, &muteTiming); err != nil {
+		return nil, fmt.Errorf("delete mute timing %s: %w", args.Name, err)
+	}
+	if args.ConfirmationToken == "" {
+		impact := []string{
+			fmt.Sprintf("Deletes the mute timing %s with its %d time intervals.", args.Name, len(muteTiming.TimeIntervals)),
+			"Grafana refuses to delete mute timings used by notification policies; remove it from them first.",
+		}
+		return &DeleteMuteTimingResult{
+			MuteTiming:   &muteTiming.MuteTiming,
+			Confirmation: newConfirmation(ctx, deleteMuteTimingAction, impact, args.Name, muteTiming.Version),
+		}, nil
+	}
+	if err := checkConfirmation(ctx, args.ConfirmationToken, deleteMuteTimingAction, args.Name, muteTiming.Version); err != nil {
+		return nil, err
+	}
+	resp, err := c.doRequest(ctx, http.MethodDelete, path, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("delete mute timing %s: %w", args.Name, err)
+	}
+	resp.Body.Close()
+	return &DeleteMuteTimingResult{Deleted: true, MuteTiming: &muteTiming.MuteTiming}, nil
+}
+
+var DeleteMuteTiming = mcpgrafana.MustTool(
+	"grafana_delete_mute_timing",
+	"Delete a mute timing of Grafana Alerting. "+deleteMuteTimingAction.description()+" Mute timings used by notification policies can't be deleted.",
+	deleteMuteTiming,
+	mcp.WithTitleAnnotation("Delete mute timing"),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithIdempotentHintAnnotation(true),
+)
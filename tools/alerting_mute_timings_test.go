@@ -0,0 +1,75 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+func TestMuteTimings(t *testing.T) {
+	muteTimings := map[string]map[string]any{
+		"weekends": {"name": "weekends", "time_intervals": []any{map[string]any{"weekdays": []any{"saturday", "sunday"}}}, "version": "v1"},
+	}
+	var header http.Header
+	ctx := newFakeGrafanaContext(t, fakeRoutes{
+		"GET /api/v1/provisioning/mute-timings": func(w http.ResponseWriter, r *http.Request) {
+			list := []any{}
+			for _, mt := range muteTimings {
+				list = append(list, mt)
+			}
+			_ = json.NewEncoder(w).Encode(list)
+		},
+		"POST /api/v1/provisioning/mute-timings": func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]any
+			if !decodeJSONBody(t, w, r, &body) {
+				return
+			}
+			header = r.Header
+			muteTimings[body["name"].(string)] = body
+			_ = json.NewEncoder(w).Encode(body)
+		},
+		"GET /api/v1/provisioning/mute-timings/weekends": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(muteTimings["weekends"])
+		},
+		"DELETE /api/v1/provisioning/mute-timings/weekends": func(w http.ResponseWriter, r *http.Request) {
+			delete(muteTimings, "weekends")
+			w.WriteHeader(http.StatusNoContent)
+		},
+	})
+
+	list, err := listMuteTimings(ctx, ListMuteTimingsParams{})
+	require.NoError(t, err)
+	assert.Equal(t, []MuteTiming{{Name: "weekends", TimeIntervals: []MuteTimeInterval{{Weekdays: []string{"saturday", "sunday"}}}}}, list)
+
+	created, err := createMuteTiming(ctx, CreateMuteTimingParams{
+		Name:              "maintenance",
+		TimeIntervals:     []MuteTimeInterval{{Weekdays: []string{"tuesday"}, Times: []MuteTimeRange{{StartTime: "22:00", EndTime: "23:00"}}, Location: "Europe/Paris"}},
+		DisableProvenance: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "maintenance", created.Name)
+	assert.Equal(t, "22:00", created.TimeIntervals[0].Times[0].StartTime)
+	assert.Equal(t, "true", header.Get("X-Disable-Provenance"))
+
+	result, err := deleteMuteTiming(ctx, DeleteMuteTimingParams{Name: "weekends"})
+	require.NoError(t, err)
+	assert.False(t, result.Deleted)
+	require.NotNil(t, result.Confirmation)
+	assert.Contains(t, muteTimings, "weekends")
+
+	_, err = deleteMuteTiming(ctx, DeleteMuteTimingParams{Name: "weekends", ConfirmationToken: "1.abc"})
+	assert.ErrorIs(t, err, mcpgrafana.ErrInvalidConfirmationToken)
+
+	result, err = deleteMuteTiming(ctx, DeleteMuteTimingParams{Name: "weekends", ConfirmationToken: result.Confirmation.Token})
+	require.NoError(t, err)
+	assert.True(t, result.Deleted)
+	assert.NotContains(t, muteTimings, "weekends")
+}
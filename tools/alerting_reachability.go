@@ -0,0 +1,189 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	// DefaultContactPointTestIntervalSeconds is the default time waited
+	// between two test notifications.
+	DefaultContactPointTestIntervalSeconds = 2
+	// MaxContactPointTests is the maximum number of integrations tested by a
+	// single call, to bound the time taken and the notifications sent.
+	MaxContactPointTests = 50
+
+	alertmanagerConfigPath = "/api/alertmanager/grafana/config/api/v1/alerts"
+	testReceiversPath      = "/api/alertmanager/grafana/config/api/v1/receivers/test"
+)
+
+// Statuses of contact point integration tests.
+const (
+	contactPointTestOK      = "ok"
+	contactPointTestFailed  = "failed"
+	contactPointTestPending = "not_sent"
+	contactPointTestSkipped = "skipped"
+)
+
+type TestContactPointsParams struct {
+	Names           []string `json:"names,omitempty" jsonschema:"description=Only test the contact points with these names. Defaults to all contact points"`
+	Send            bool     `json:"send,omitempty" jsonschema:"description=Must be true to send test notifications. Otherwise the integrations which would be tested are only listed"`
+	IntervalSeconds int      `json:"intervalSeconds,omitempty" jsonschema:"description=The number of seconds to wait between two test notifications. Defaults to 2; at least 1"`
+}
+
+// receiverIntegration is a Grafana managed integration of a contact point, as
+// found in the Alertmanager configuration. Secure settings are not returned
+// by the API, but are filled in by Grafana from the stored configuration
+// when testing an integration with its UID.
+type receiverIntegration struct {
+	UID                   string          `json:"uid"`
+	Name                  string          `json:"name"`
+	Type                  string          `json:"type"`
+	DisableResolveMessage bool            `json:"disableResolveMessage"`
+	Settings              map[string]any  `json:"settings"`
+	SecureFields          map[string]bool `json:"secureFields,omitempty"`
+}
+
+type receiverConfig struct {
+	Name         string                `json:"name"`
+	Integrations []receiverIntegration `json:"grafana_managed_receiver_configs"`
+}
+
+type alertmanagerConfigResponse struct {
+	AlertmanagerConfig struct {
+		Receivers []receiverConfig `json:"receivers"`
+	} `json:"alertmanager_config"`
+}
+
+type testReceiversResponse struct {
+	Receivers []struct {
+		Name    string `json:"name"`
+		Configs []struct {
+			UID    string `json:"uid"`
+			Status string `json:"status"`
+			Error  string `json:"error"`
+		} `json:"grafana_managed_receiver_configs"`
+	} `json:"receivers"`
+}
+
+type contactPointTestResult struct {
+	ContactPoint string `json:"contactPoint"`
+	UID          string `json:"uid"`
+	Type         string `json:"type"`
+	Status       string `json:"status"`
+	Error        string `json:"error,omitempty"`
+}
+
+type contactPointTestReport struct {
+	DryRun  bool                     `json:"dryRun"`
+	Tested  int                      `json:"tested"`
+	Failed  int                      `json:"failed"`
+	Results []contactPointTestResult `json:"results"`
+}
+
+// testIntegration sends a test notification through a single integration of
+// a contact point, returning the error reported by Grafana if it failed.
+func testIntegration(ctx context.Context, c *alertingClient, receiver string, integration receiverIntegration) string {
+	body := map[string]any{
+		"receivers": []receiverConfig{{Name: receiver, Integrations: []receiverIntegration{integration}}},
+		"alert": map[string]any{
+			"labels": map[string]string{"alertname": "TestContactPoint"},
+			"annotations": map[string]string{
+				"summary": "Test notification sent by the Grafana MCP server to check that this contact point is reachable.",
+			},
+		},
+	}
+	var resp testReceiversResponse
+	if err := c.sendJSON(ctx, http.MethodPost, testReceiversPath, body, &resp); err != nil {
+		// Grafana responds with an error status if every tested integration
+		// failed, which is always the case here since they are tested one by
+		// one.
+		return err.Error()
+	}
+	for _, r := range resp.Receivers {
+		for _, cfg := range r.Configs {
+			if cfg.Status != contactPointTestOK {
+				return cfg.Error
+			}
+		}
+	}
+	return ""
+}
+
+// testContactPoints tests every integration of the given receivers, waiting
+// interval between two notifications.
+func testContactPoints(ctx context.Context, c *alertingClient, receivers []receiverConfig, send bool, interval time.Duration) (*contactPointTestReport, error) {
+	report := &contactPointTestReport{DryRun: !send, Results: []contactPointTestResult{}}
+	for _, r := range receivers {
+		for _, integration := range r.Integrations {
+			result := contactPointTestResult{ContactPoint: r.Name, UID: integration.UID, Type: integration.Type, Status: contactPointTestPending}
+			switch {
+			case !send:
+			case report.Tested >= MaxContactPointTests:
+				result.Status = contactPointTestSkipped
+				result.Error = fmt.Sprintf("only %d integrations are tested per call; use names to test the others", MaxContactPointTests)
+			default:
+				if report.Tested > 0 {
+					select {
+					case <-ctx.Done():
+						return nil, ctx.Err()
+					case <-time.After(interval):
+					}
+				}
+				report.Tested++
+				result.Status = contactPointTestOK
+				if msg := testIntegration(ctx, c, r.Name, integration); msg != "" {
+					result.Status = contactPointTestFailed
+					result.Error = msg
+					report.Failed++
+				}
+			}
+			report.Results = append(report.Results, result)
+		}
+	}
+	return report, nil
+}
+
+func testContactPointsTool(ctx context.Context, args TestContactPointsParams) (*contactPointTestReport, error) {
+	interval := args.IntervalSeconds
+	if interval <= 0 {
+		interval = DefaultContactPointTestIntervalSeconds
+	}
+
+	c, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("test contact points: %w", err)
+	}
+	var config alertmanagerConfigResponse
+	if err := c.getJSON(ctx, alertmanagerConfigPath, nil, &config); err != nil {
+		return nil, fmt.Errorf("test contact points: get Alertmanager configuration: %w", err)
+	}
+
+	receivers := config.AlertmanagerConfig.Receivers
+	if len(args.Names) > 0 {
+		receivers = slices.DeleteFunc(receivers, func(r receiverConfig) bool {
+			return !slices.Contains(args.Names, r.Name)
+		})
+		for _, name := range args.Names {
+			if !slices.ContainsFunc(receivers, func(r receiverConfig) bool { return r.Name == name }) {
+				return nil, fmt.Errorf("test contact points: contact point %q not found", name)
+			}
+		}
+	}
+	return testContactPoints(ctx, c, receivers, args.Send, time.Duration(interval)*time.Second)
+}
+
+var TestContactPoints = mcpgrafana.MustTool(
+	"grafana_test_contact_points",
+	"Checks that notification contact points are reachable by sending a test notification through each of their integrations, one at a time and with a pause between notifications. Returns which integrations failed and the error reported by Grafana. Test notifications are only sent when send is true, otherwise the integrations which would be tested are listed. Since this notifies the people behind each contact point, only send after confirmation from the user.",
+	testContactPointsTool,
+	mcp.WithTitleAnnotation("Test contact point reachability"),
+	mcp.WithDestructiveHintAnnotation(false),
+)
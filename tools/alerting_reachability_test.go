@@ -0,0 +1,91 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newContactPointTestContext(t *testing.T, tested *[]receiverConfig) context.Context {
+	var mu sync.Mutex
+	server := newFakeGrafana(t, fakeRoutes{
+		alertmanagerConfigPath: jsonBody(`{"alertmanager_config": {"receivers": [
+				{"name": "email", "grafana_managed_receiver_configs": [{"uid": "cp-1", "name": "email", "type": "email", "settings": {"addresses": "oncall@example.com"}}]},
+				{"name": "slack", "grafana_managed_receiver_configs": [
+					{"uid": "cp-2", "name": "slack", "type": "slack", "settings": {"recipient": "#alerts"}, "secureFields": {"token": true}},
+					{"uid": "cp-3", "name": "slack", "type": "webhook", "settings": {"url": "http://unreachable"}}
+				]}
+			]}}`),
+		testReceiversPath: func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Receivers []receiverConfig `json:"receivers"`
+			}
+			if !decodeJSONBody(t, w, r, &body) {
+				return
+			}
+			mu.Lock()
+			*tested = append(*tested, body.Receivers...)
+			mu.Unlock()
+			if body.Receivers[0].Integrations[0].UID == "cp-3" {
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte(`{"receivers": [{"name": "slack", "grafana_managed_receiver_configs": [{"uid": "cp-3", "status": "failed", "error": "connection refused"}]}]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"receivers": [{"name": "` + body.Receivers[0].Name + `", "grafana_managed_receiver_configs": [{"uid": "` + body.Receivers[0].Integrations[0].UID + `", "status": "ok"}]}]}`))
+		},
+	})
+	return fakeGrafanaContext(server)
+}
+
+func TestTestContactPoints(t *testing.T) {
+	t.Run("dry run", func(t *testing.T) {
+		var tested []receiverConfig
+		ctx := newContactPointTestContext(t, &tested)
+		report, err := testContactPointsTool(ctx, TestContactPointsParams{Names: []string{"slack"}})
+		require.NoError(t, err)
+		assert.True(t, report.DryRun)
+		assert.Empty(t, tested)
+		require.Len(t, report.Results, 2)
+		assert.Equal(t, contactPointTestPending, report.Results[0].Status)
+	})
+
+	t.Run("send", func(t *testing.T) {
+		var tested []receiverConfig
+		ctx := newContactPointTestContext(t, &tested)
+		c, err := newAlertingClientFromContext(ctx)
+		require.NoError(t, err)
+		var config alertmanagerConfigResponse
+		require.NoError(t, c.getJSON(ctx, alertmanagerConfigPath, nil, &config))
+
+		report, err := testContactPoints(ctx, c, config.AlertmanagerConfig.Receivers, true, time.Millisecond)
+		require.NoError(t, err)
+		assert.Equal(t, 3, report.Tested)
+		assert.Equal(t, 1, report.Failed)
+		assert.Equal(t, []contactPointTestResult{
+			{ContactPoint: "email", UID: "cp-1", Type: "email", Status: contactPointTestOK},
+			{ContactPoint: "slack", UID: "cp-2", Type: "slack", Status: contactPointTestOK},
+			{ContactPoint: "slack", UID: "cp-3", Type: "webhook", Status: contactPointTestFailed, Error: report.Results[2].Error},
+		}, report.Results)
+		assert.Contains(t, report.Results[2].Error, "connection refused")
+
+		// Integrations are tested one at a time, keeping their secure fields
+		// so that Grafana uses the stored secrets.
+		require.Len(t, tested, 3)
+		assert.Equal(t, map[string]bool{"token": true}, tested[1].Integrations[0].SecureFields)
+	})
+
+	t.Run("unknown contact point", func(t *testing.T) {
+		var tested []receiverConfig
+		ctx := newContactPointTestContext(t, &tested)
+		_, err := testContactPointsTool(ctx, TestContactPointsParams{Names: []string{"pagerduty"}, Send: true})
+		assert.ErrorContains(t, err, `contact point "pagerduty" not found`)
+	})
+}
@@ -0,0 +1,466 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/common/model"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	// defaultAlertQueryFrom is how far back the queries of alert rules look
+	// if no time range is given.
+	defaultAlertQueryFrom = 10 * time.Minute
+
+	// expressionDatasourceUID is the UID of server-side expressions, such
+	// as the thresholds and reductions used as conditions of alert rules.
+	expressionDatasourceUID = "__expr__"
+)
+
+var (
+	alertRuleNoDataStates  = []string{"NoData", "Alerting", "OK"}
+	alertRuleExecErrStates = []string{"Error", "Alerting", "OK"}
+)
+
+// AlertRuleQuery is a query or expression of an alert rule.
+type AlertRuleQuery struct {
+	RefID         string         `json:"refId" jsonschema:"required,description=The reference of the query\\, e.g. A\\, used by expressions and the condition"`
+	DatasourceUID string         `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource\\, or __expr__ for server-side expressions such as reduce\\, math and threshold"`
+	Model         map[string]any `json:"model" jsonschema:"required,description=The query model. For Prometheus: expr and instant set to true. For a threshold expression: type threshold\\, expression with the refId of its input and conditions with an evaluator whose type is gt or lt and whose params are the threshold"`
+	From          string         `json:"from,omitempty" jsonschema:"description=Optionally\\, how far back the query looks\\, e.g. 10m (default 10m). Ignored for expressions"`
+}
+
+// SavedAlertRule is an alert rule which was created or updated.
+type SavedAlertRule struct {
+	UID       string `json:"uid"`
+	Title     string `json:"title"`
+	FolderUID string `json:"folderUid"`
+	RuleGroup string `json:"ruleGroup"`
+	// Interval is the evaluation interval of the rule group.
+	Interval string `json:"interval,omitempty"`
+	URL      string `json:"url"`
+}
+
+// alertRuleData converts the queries of an alert rule to the format of the
+// provisioning API, checking that the condition is one of them.
+func alertRuleData(queries []AlertRuleQuery, condition string) ([]any, error) {
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("at least one query is required")
+	}
+	var refIDs []string
+	data := make([]any, 0, len(queries))
+	for i, q := range queries {
+		if q.RefID == "" || q.DatasourceUID == "" || q.Model == nil {
+			return nil, fmt.Errorf("query %d: refId, datasourceUid and model are required", i)
+		}
+		if slices.Contains(refIDs, q.RefID) {
+			return nil, fmt.Errorf("query %d: the refId %s is used by another query", i, q.RefID)
+		}
+		refIDs = append(refIDs, q.RefID)
+		from := time.Duration(0)
+		if q.DatasourceUID != expressionDatasourceUID {
+			from = defaultAlertQueryFrom
+			if q.From != "" {
+				d, ok := parseAnyDuration(q.From)
+				if !ok || d <= 0 {
+					return nil, fmt.Errorf("query %d: invalid from %q: must be a duration such as 10m", i, q.From)
+				}
+				from = d
+			}
+		}
+		model := maps.Clone(q.Model)
+		model["refId"] = q.RefID
+		data = append(data, map[string]any{
+			"refId":             q.RefID,
+			"datasourceUid":     q.DatasourceUID,
+			"relativeTimeRange": map[string]any{"from": int64(from / time.Second), "to": 0},
+			"model":             model,
+		})
+	}
+	if !slices.Contains(refIDs, condition) {
+		return nil, fmt.Errorf("the condition %q must be the refId of one of the queries: %v", condition, refIDs)
+	}
+	return data, nil
+}
+
+// alertRuleFields are the fields of alert rules which can be set when
+// creating and updating them.
+type alertRuleFields struct {
+	For          string
+	Labels       map[string]string
+	Annotations  map[string]string
+	NoDataState  string
+	ExecErrState string
+}
+
+// apply validates the fields which are set and sets them in rule.
+func (f alertRuleFields) apply(rule map[string]any) error {
+	if f.For != "" {
+		if _, ok := parseAnyDuration(f.For); !ok {
+			return fmt.Errorf("invalid for %q: must be a duration such as 5m", f.For)
+		}
+		rule["for"] = f.For
+	}
+	if f.NoDataState != "" {
+		if !slices.Contains(alertRuleNoDataStates, f.NoDataState) {
+			return fmt.Errorf("invalid noDataState %q: must be one of %v", f.NoDataState, alertRuleNoDataStates)
+		}
+		rule["noDataState"] = f.NoDataState
+	}
+	if f.ExecErrState != "" {
+		if !slices.Contains(alertRuleExecErrStates, f.ExecErrState) {
+			return fmt.Errorf("invalid execErrState %q: must be one of %v", f.ExecErrState, alertRuleExecErrStates)
+		}
+		rule["execErrState"] = f.ExecErrState
+	}
+	if f.Labels != nil {
+		rule["labels"] = f.Labels
+	}
+	if f.Annotations != nil {
+		rule["annotations"] = f.Annotations
+	}
+	return nil
+}
+
+// parseRuleGroupInterval parses the evaluation interval of a rule group,
+// which must be a multiple of 10 seconds, in seconds.
+func parseRuleGroupInterval(interval string) (int64, error) {
+	d, ok := parseAnyDuration(interval)
+	if !ok || d <= 0 || d%(10*time.Second) != 0 {
+		return 0, fmt.Errorf("invalid interval %q: must be a duration which is a multiple of 10s, such as 1m", interval)
+	}
+	return int64(d / time.Second), nil
+}
+
+// alertRulePath returns the provisioning API path of an alert rule.
+func alertRulePath(uid string) string {
+	return "/api/v1/provisioning/alert-rules/" + url.PathEscape(uid)
+}
+
+// ruleGroupPath returns the provisioning API path of a rule group.
+func ruleGroupPath(folderUID, group string) string {
+	return fmt.Sprintf("/api/v1/provisioning/folder/%s/rule-groups/%s", url.PathEscape(folderUID), url.PathEscape(group))
+}
+
+// setRuleGroupInterval sets the evaluation interval of a rule group, if
+// seconds is not 0, and returns it. The interval applies to all the rules of
+// the group.
+func setRuleGroupInterval(ctx context.Context, c *alertingClient, folderUID, group string, seconds int64) (string, error) {
+	var g map[string]any
+	if err := c.getJSON(ctx, ruleGroupPath(folderUID, group), nil, &g); err != nil {
+		return "", fmt.Errorf("get rule group %s: %w", group, err)
+	}
+	current, _ := g["interval"].(float64)
+	if seconds != 0 && int64(current) != seconds {
+		g["interval"] = seconds
+		if err := c.sendJSON(ctx, http.MethodPut, ruleGroupPath(folderUID, group), g, nil); err != nil {
+			return "", fmt.Errorf("set the interval of rule group %s: %w", group, err)
+		}
+		current = float64(seconds)
+	}
+	return model.Duration(time.Duration(current) * time.Second).String(), nil
+}
+
+// savedAlertRule summarizes a rule returned by the provisioning API.
+func savedAlertRule(ctx context.Context, rule map[string]any, interval string) *SavedAlertRule {
+	base, _ := deeplinkBase(ctx)
+	uid := asString(rule, "uid")
+	return &SavedAlertRule{
+		UID:       uid,
+		Title:     asString(rule, "title"),
+		FolderUID: asString(rule, "folderUID"),
+		RuleGroup: asString(rule, "ruleGroup"),
+		Interval:  interval,
+		URL:       fmt.Sprintf("%s/alerting/grafana/%s/view", base, uid),
+	}
+}
+
+type CreateAlertRuleParams struct {
+	Title             string            `json:"title" jsonschema:"required,description=The title of the rule"`
+	FolderUID         string            `json:"folderUid" jsonschema:"required,description=The UID of the folder of the rule"`
+	RuleGroup         string            `json:"ruleGroup" jsonschema:"required,description=The name of the rule group of the rule. It is created if it doesn't exist"`
+	Queries           []AlertRuleQuery  `json:"queries" jsonschema:"required,description=The queries and expressions of the rule"`
+	Condition         string            `json:"condition" jsonschema:"required,description=The refId of the query or expression which fires the alert when it is non-zero\\, usually a threshold expression"`
+	Interval          string            `json:"interval,omitempty" jsonschema:"description=Optionally\\, the evaluation interval of the rule group\\, e.g. 1m. It applies to all the rules of the group"`
+	UID               string            `json:"uid,omitempty" jsonschema:"description=Optionally\\, the UID of the rule. Grafana generates one if not given"`
+	DisableProvenance bool              `json:"disableProvenance,omitempty" jsonschema:"description=If true\\, the rule remains editable in the Grafana UI. Otherwise it is marked as provisioned"`
+	For               string            `json:"for,omitempty" jsonschema:"description=Optionally\\, how long the condition must hold before the alert fires\\, e.g. 5m"`
+	Labels            map[string]string `json:"labels,omitempty" jsonschema:"description=Optionally\\, the labels of the rule\\, used to route its notifications"`
+	Annotations       map[string]string `json:"annotations,omitempty" jsonschema:"description=Optionally\\, the annotations of the rule\\, e.g. summary\\, description and runbook_url"`
+	NoDataState       string            `json:"noDataState,omitempty" jsonschema:"enum=NoData,enum=Alerting,enum=OK,description=Optionally\\, the state of the rule when its queries return no data (default NoData)"`
+	ExecErrState      string            `json:"execErrState,omitempty" jsonschema:"enum=Error,enum=Alerting,enum=OK,description=Optionally\\, the state of the rule when its queries fail (default Error)"`
+}
+
+func (p CreateAlertRuleParams) fields() alertRuleFields {
+	return alertRuleFields{For: p.For, Labels: p.Labels, Annotations: p.Annotations, NoDataState: p.NoDataState, ExecErrState: p.ExecErrState}
+}
+
+func createAlertRule(ctx context.Context, args CreateAlertRuleParams) (*SavedAlertRule, error) {
+	if args.Title == "" || args.FolderUID == "" || args.RuleGroup == "" {
+		return nil, fmt.Errorf("create alert rule: title, folderUid and ruleGroup are required")
+	}
+	var interval int64
+	if args.Interval != "" {
+		var err error
+		if interval, err = parseRuleGroupInterval(args.Interval); err != nil {
+			return nil, fmt.Errorf("create alert rule: %w", err)
+		}
+	}
+	data, err := alertRuleData(args.Queries, args.Condition)
+	if err != nil {
+		return nil, fmt.Errorf("create alert rule: %w", err)
+	}
+	rule := map[string]any{
+		"title":        args.Title,
+		"folderUID":    args.FolderUID,
+		"ruleGroup":    args.RuleGroup,
+		"condition":    args.Condition,
+		"data":         data,
+		"noDataState":  "NoData",
+		"execErrState": "Error",
+		"for":          "0s",
+	}
+	if args.UID != "" {
+		rule["uid"] = args.UID
+	}
+	if err := args.fields().apply(rule); err != nil {
+		return nil, fmt.Errorf("create alert rule: %w", err)
+	}
+
+	c, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create alert rule: %w", err)
+	}
+	c.disableProvenance = args.DisableProvenance
+	var created map[string]any
+	if err := c.sendJSON(ctx, http.MethodPost, "/api/v1/provisioning/alert-rules", rule, &created); err != nil {
+		return nil, fmt.Errorf("create alert rule: %w", err)
+	}
+	groupInterval, err := setRuleGroupInterval(ctx, c, args.FolderUID, args.RuleGroup, interval)
+	if err != nil {
+		return nil, fmt.Errorf("alert rule %s was created, but: %w", asString(created, "uid"), err)
+	}
+	return savedAlertRule(ctx, created, groupInterval), nil
+}
+
+var CreateAlertRule = mcpgrafana.MustTool(
+	"grafana_create_alert_rule",
+	"Create a Grafana-managed alert rule with the provisioning API. A rule has queries against datasources and server-side expressions (datasource UID __expr__), and fires when its condition, usually a threshold expression on a query, is non-zero. For example, query A with the PromQL expression and `instant` set, and expression B of type threshold on A, with B as the condition. The rule is added to the rule group, which is created if it doesn't exist; its evaluation interval applies to all of its rules. Returns the rule with its URL in Grafana.",
+	createAlertRule,
+	mcp.WithTitleAnnotation("Create alert rule"),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithIdempotentHintAnnotation(false),
+)
+
+type UpdateAlertRuleParams struct {
+	UID               string            `json:"uid" jsonschema:"required,description=The UID of the rule to update"`
+	Title             string            `json:"title,omitempty" jsonschema:"description=Optionally\\, the new title of the rule"`
+	Queries           []AlertRuleQuery  `json:"queries,omitempty" jsonschema:"description=Optionally\\, the new queries and expressions of the rule\\, replacing all of them"`
+	Condition         string            `json:"condition,omitempty" jsonschema:"description=Optionally\\, the refId of the new condition. Required if the queries are replaced"`
+	Interval          string            `json:"interval,omitempty" jsonschema:"description=Optionally\\, the new evaluation interval of the rule group\\, e.g. 1m. It applies to all the rules of the group"`
+	DisableProvenance bool              `json:"disableProvenance,omitempty" jsonschema:"description=If true\\, the rule remains editable in the Grafana UI. Otherwise it is marked as provisioned"`
+	For               string            `json:"for,omitempty" jsonschema:"description=Optionally\\, how long the condition must hold before the alert fires\\, e.g. 5m"`
+	Labels            map[string]string `json:"labels,omitempty" jsonschema:"description=Optionally\\, the labels of the rule\\, used to route its notifications"`
+	Annotations       map[string]string `json:"annotations,omitempty" jsonschema:"description=Optionally\\, the annotations of the rule\\, e.g. summary\\, description and runbook_url"`
+	NoDataState       string            `json:"noDataState,omitempty" jsonschema:"enum=NoData,enum=Alerting,enum=OK,description=Optionally\\, the state of the rule when its queries return no data (default NoData)"`
+	ExecErrState      string            `json:"execErrState,omitempty" jsonschema:"enum=Error,enum=Alerting,enum=OK,description=Optionally\\, the state of the rule when its queries fail (default Error)"`
+}
+
+func (p UpdateAlertRuleParams) fields() alertRuleFields {
+	return alertRuleFields{For: p.For, Labels: p.Labels, Annotations: p.Annotations, NoDataState: p.NoDataState, ExecErrState: p.ExecErrState}
+}
+
+func updateAlertRule(ctx context.Context, args UpdateAlertRuleParams) (*SavedAlertRule, error) {
+	if args.UID == "" {
+		return nil, fmt.Errorf("update alert rule: uid is required")
+	}
+	var interval int64
+	if args.Interval != "" {
+		var err error
+		if interval, err = parseRuleGroupInterval(args.Interval); err != nil {
+			return nil, fmt.Errorf("update alert rule %s: %w", args.UID, err)
+		}
+	}
+	c, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("update alert rule %s: %w", args.UID, err)
+	}
+	c.disableProvenance = args.DisableProvenance
+
+	// The rule is fetched and sent back with the changes, so that the
+	// fields which aren't given, including those unknown to this tool, are
+	// kept.
+	path := alertRulePath(args.UID)
+	var rule map[string]any
+	if err := c.getJSON(ctx, path, nil, &rule); err != nil {
+		return nil, fmt.Errorf("update alert rule %s: %w", args.UID, err)
+	}
+	switch {
+	case len(args.Queries) > 0:
+		data, err := alertRuleData(args.Queries, args.Condition)
+		if err != nil {
+			return nil, fmt.Errorf("update alert rule %s: %w", args.UID, err)
+		}
+		rule["data"], rule["condition"] = data, args.Condition
+	case args.Condition != "":
+		data, ok := rule["data"].([]any)
+		if !ok {
+			return nil, fmt.Errorf("update alert rule %s: the rule has no queries to set the condition to", args.UID)
+		}
+		refIDs := []string{}
+		for _, q := range data {
+			refIDs = append(refIDs, asString(asObject(q), "refId"))
+		}
+		if !slices.Contains(refIDs, args.Condition) {
+			return nil, fmt.Errorf("update alert rule %s: the condition %q must be the refId of one of the queries: %v", args.UID, args.Condition, refIDs)
+		}
+		rule["condition"] = args.Condition
+	}
+	if args.Title != "" {
+		rule["title"] = args.Title
+	}
+	if err := args.fields().apply(rule); err != nil {
+		return nil, fmt.Errorf("update alert rule %s: %w", args.UID, err)
+	}
+
+	var updated map[string]any
+	if err := c.sendJSON(ctx, http.MethodPut, path, rule, &updated); err != nil {
+		return nil, fmt.Errorf("update alert rule %s: %w", args.UID, err)
+	}
+	if updated == nil {
+		updated = rule
+	}
+	groupInterval, err := setRuleGroupInterval(ctx, c, asString(updated, "folderUID"), asString(updated, "ruleGroup"), interval)
+	if err != nil {
+		return nil, fmt.Errorf("alert rule %s was updated, but: %w", args.UID, err)
+	}
+	return savedAlertRule(ctx, updated, groupInterval), nil
+}
+
+var UpdateAlertRule = mcpgrafana.MustTool(
+	"grafana_update_alert_rule",
+	"Update a Grafana-managed alert rule with the provisioning API: its title, queries and condition, pending period, labels, annotations, no data and error states, or the evaluation interval of its rule group. Fields which are not given are kept; labels and annotations, when given, replace all of the current ones. Use `grafana_get_alert_rule_by_uid` to see the current rule first. Returns the rule with its URL in Grafana.",
+	updateAlertRule,
+	mcp.WithTitleAnnotation("Update alert rule"),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithIdempotentHintAnnotation(true),
+)
+
+type DeleteAlertRuleParams struct {
+	UID               string `json:"uid" jsonschema:"required,description=The UID of the rule to delete"`
+	ConfirmationToken string `json:"confirmationToken,omitempty" jsonschema:"description=The token returned by a previous call for this rule. Without it nothing is deleted and the impact of the deletion is returned with a token"`
+}
+
+// DeleteAlertRuleResult describes an alert rule which was deleted, or would
+// be deleted if the deletion was confirmed.
+type DeleteAlertRuleResult struct {
+	Deleted      bool            `json:"deleted"`
+	Rule         *SavedAlertRule `json:"rule"`
+	Confirmation *Confirmation   `json:"confirmation,omitempty"`
+}
+
+// deleteAlertRule deletes an alert rule, but only if the call has a
+// confirmation token. Calls without a token return the impact of the
+// deletion and a token, which is only valid until the rule is updated.
+func deleteAlertRule(ctx context.Context, args DeleteAlertRuleParams) (*DeleteAlertRuleResult, error) {
+	if args.UID == "" {
+		return nil, fmt.Errorf("delete alert rule: uid is required")
+	}
+	c, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("delete alert rule %s: %w", args.UID, err)
+	}
+	var rule map[string]any
+	if err := c.getJSON(ctx, alertRulePath(args.UID), nil, &rule); err != nil {
+		return nil, fmt.Errorf("delete alert rule %s: %w", args.UID, err)
+	}
+	saved := savedAlertRule(ctx, rule, "")
+	updated := asString(rule, "updated")
+	if args.ConfirmationToken == "" {
+		impact := []string{
+			fmt.Sprintf("Deletes the alert rule %q of the rule group %q in the folder %s.", saved.Title, saved.RuleGroup, saved.FolderUID),
+			"Its alerts stop being evaluated, and firing alerts are resolved, which sends resolved notifications.",
+		}
+		if provenance := asString(rule, "provenance"); provenance != "" {
+			impact = append(impact, fmt.Sprintf("The rule is provisioned (%s), so it is recreated if its provisioning source still has it.", provenance))
+		}
+		return &DeleteAlertRuleResult{
+			Rule:         saved,
+			Confirmation: newConfirmation(ctx, deleteAlertRuleAction, impact, args.UID, updated),
+		}, nil
+	}
+	if err := checkConfirmation(ctx, args.ConfirmationToken, deleteAlertRuleAction, args.UID, updated); err != nil {
+		return nil, err
+	}
+	resp, err := c.doRequest(ctx, http.MethodDelete, alertRulePath(args.UID), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("delete alert rule %s: %w", args.UID, err)
+	}
+	resp.Body.Close()
+	return &DeleteAlertRuleResult{Deleted: true, Rule: saved}, nil
+}
+
+var DeleteAlertRule = mcpgrafana.MustTool(
+	"grafana_delete_alert_rule",
+	"Delete a Grafana-managed alert rule, e.g. when cleaning up stale or noisy rules. "+deleteAlertRuleAction.description()+" Tokens are no longer valid if the rule was updated. To stop a rule temporarily, pause it with `grafana_pause_alert_rule` instead.",
+	deleteAlertRule,
+	mcp.WithTitleAnnotation("Delete alert rule"),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithIdempotentHintAnnotation(true),
+)
+
+type PauseAlertRuleParams struct {
+	UID string `json:"uid" jsonschema:"required,description=The UID of the rule to pause or resume"`
+	// Paused is a pointer so that it can default to true.
+	Paused            *bool `json:"paused,omitempty" jsonschema:"description=Whether to pause the rule (default true). Set to false to resume it"`
+	DisableProvenance bool  `json:"disableProvenance,omitempty" jsonschema:"description=If true\\, the rule becomes editable in the Grafana UI if it was provisioned. Otherwise its provenance is kept"`
+}
+
+// PausedAlertRule is an alert rule which was paused or resumed.
+type PausedAlertRule struct {
+	SavedAlertRule
+	Paused bool `json:"paused"`
+}
+
+func pauseAlertRule(ctx context.Context, args PauseAlertRuleParams) (*PausedAlertRule, error) {
+	if args.UID == "" {
+		return nil, fmt.Errorf("pause alert rule: uid is required")
+	}
+	paused := args.Paused == nil || *args.Paused
+	c, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("pause alert rule %s: %w", args.UID, err)
+	}
+	var rule map[string]any
+	if err := c.getJSON(ctx, alertRulePath(args.UID), nil, &rule); err != nil {
+		return nil, fmt.Errorf("pause alert rule %s: %w", args.UID, err)
+	}
+	// Rules without provenance are updated without it as well, so that
+	// pausing a rule created in the UI doesn't lock it.
+	provenance, _ := rule["provenance"].(string)
+	c.disableProvenance = args.DisableProvenance || provenance == ""
+	if current, _ := rule["isPaused"].(bool); current != paused {
+		rule["isPaused"] = paused
+		if err := c.sendJSON(ctx, http.MethodPut, alertRulePath(args.UID), rule, nil); err != nil {
+			return nil, fmt.Errorf("pause alert rule %s: %w", args.UID, err)
+		}
+	}
+	return &PausedAlertRule{SavedAlertRule: *savedAlertRule(ctx, rule, ""), Paused: paused}, nil
+}
+
+var PauseAlertRule = mcpgrafana.MustTool(
+	"grafana_pause_alert_rule",
+	"Pause a Grafana-managed alert rule, so that it is no longer evaluated and doesn't notify, e.g. during maintenance or while fixing a noisy rule, or resume it with `paused` set to false. The rule is kept with its configuration.",
+	pauseAlertRule,
+	mcp.WithTitleAnnotation("Pause or resume alert rule"),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithIdempotentHintAnnotation(true),
+)
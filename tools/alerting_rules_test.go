@@ -0,0 +1,221 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// alertRulesTestServer is a fake provisioning API holding rules by UID and
+// the interval of a single rule group.
+type alertRulesTestServer struct {
+	rules         map[string]map[string]any
+	groupInterval float64
+	headers       []http.Header
+}
+
+// alertRulesTestUIDs are the UIDs of the rules the tests create and update.
+var alertRulesTestUIDs = []string{"generated", "rule-1", "rule-2"}
+
+// routes returns the routes of a fake Grafana serving the provisioning API.
+func (ts *alertRulesTestServer) routes(t *testing.T) fakeRoutes {
+	// decode decodes the body of a write, recording its headers.
+	decode := func(w http.ResponseWriter, r *http.Request, body *map[string]any) bool {
+		if !decodeJSONBody(t, w, r, body) {
+			return false
+		}
+		ts.headers = append(ts.headers, r.Header)
+		return true
+	}
+	routes := fakeRoutes{
+		"POST /api/v1/provisioning/alert-rules": func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]any
+			if !decode(w, r, &body) {
+				return
+			}
+			if body["uid"] == nil {
+				body["uid"] = "generated"
+			}
+			ts.rules[body["uid"].(string)] = body
+			_ = json.NewEncoder(w).Encode(body)
+		},
+		"/api/v1/provisioning/folder/infra/rule-groups/cpu": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPut {
+				var body map[string]any
+				if !decode(w, r, &body) {
+					return
+				}
+				ts.groupInterval = body["interval"].(float64)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"title": "cpu", "folderUid": "infra", "interval": ts.groupInterval, "rules": []any{}})
+		},
+	}
+	for _, uid := range alertRulesTestUIDs {
+		routes["/api/v1/provisioning/alert-rules/"+uid] = func(w http.ResponseWriter, r *http.Request) {
+			rule, ok := ts.rules[uid]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"message":"rule not found"}`))
+				return
+			}
+			switch r.Method {
+			case http.MethodPut:
+				var body map[string]any
+				if !decode(w, r, &body) {
+					return
+				}
+				ts.rules[uid] = body
+				rule = body
+			case http.MethodDelete:
+				delete(ts.rules, uid)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(rule)
+		}
+	}
+	return routes
+}
+
+var testAlertRuleQueries = []AlertRuleQuery{
+	{RefID: "A", DatasourceUID: "prom", Model: map[string]any{"expr": "avg(cpu)", "instant": true}, From: "5m"},
+	{RefID: "B", DatasourceUID: "__expr__", Model: map[string]any{"type": "threshold", "expression": "A"}},
+}
+
+func TestCreateAlertRule(t *testing.T) {
+	ts := &alertRulesTestServer{rules: map[string]map[string]any{}, groupInterval: 60}
+	ctx := newFakeGrafanaContext(t, ts.routes(t))
+
+	rule, err := createAlertRule(ctx, CreateAlertRuleParams{
+		Title:       "High CPU",
+		FolderUID:   "infra",
+		RuleGroup:   "cpu",
+		Queries:     testAlertRuleQueries,
+		Condition:   "B",
+		Interval:    "2m",
+		For:         "5m",
+		Labels:      map[string]string{"severity": "warning"},
+		Annotations: map[string]string{"summary": "CPU is high"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "generated", rule.UID)
+	assert.Equal(t, "2m", rule.Interval)
+	assert.True(t, strings.HasSuffix(rule.URL, "/alerting/grafana/generated/view"))
+	assert.Equal(t, float64(120), ts.groupInterval)
+	assert.Empty(t, ts.headers[0].Get("X-Disable-Provenance"))
+
+	saved := ts.rules["generated"]
+	assert.Equal(t, "5m", saved["for"])
+	assert.Equal(t, "NoData", saved["noDataState"])
+	data := saved["data"].([]any)
+	require.Len(t, data, 2)
+	assert.Equal(t, map[string]any{"from": float64(300), "to": float64(0)}, data[0].(map[string]any)["relativeTimeRange"])
+	assert.Equal(t, map[string]any{"from": float64(0), "to": float64(0)}, data[1].(map[string]any)["relativeTimeRange"])
+	assert.Equal(t, "B", data[1].(map[string]any)["model"].(map[string]any)["refId"])
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := createAlertRule(ctx, CreateAlertRuleParams{Title: "x", FolderUID: "infra", RuleGroup: "cpu", Queries: testAlertRuleQueries, Condition: "C"})
+		assert.ErrorContains(t, err, `the condition "C" must be the refId of one of the queries`)
+		_, err = createAlertRule(ctx, CreateAlertRuleParams{Title: "x", FolderUID: "infra", RuleGroup: "cpu", Queries: testAlertRuleQueries, Condition: "B", Interval: "15s"})
+		assert.ErrorContains(t, err, "multiple of 10s")
+		_, err = createAlertRule(ctx, CreateAlertRuleParams{Title: "x", FolderUID: "infra", RuleGroup: "cpu", Queries: testAlertRuleQueries, Condition: "B", NoDataState: "Firing"})
+		assert.ErrorContains(t, err, "invalid noDataState")
+	})
+}
+
+func TestUpdateAlertRule(t *testing.T) {
+	ts := &alertRulesTestServer{rules: map[string]map[string]any{}, groupInterval: 60}
+	ctx := newFakeGrafanaContext(t, ts.routes(t))
+	ts.rules["rule-1"] = map[string]any{
+		"uid": "rule-1", "title": "High CPU", "folderUID": "infra", "ruleGroup": "cpu", "condition": "B",
+		"data":   []any{map[string]any{"refId": "A"}, map[string]any{"refId": "B"}},
+		"labels": map[string]any{"severity": "warning"}, "notification_settings": map[string]any{"receiver": "email"},
+	}
+
+	rule, err := updateAlertRule(ctx, UpdateAlertRuleParams{UID: "rule-1", Title: "Very high CPU", Condition: "A", DisableProvenance: true})
+	require.NoError(t, err)
+	assert.Equal(t, "Very high CPU", rule.Title)
+	assert.Equal(t, "1m", rule.Interval)
+	updated := ts.rules["rule-1"]
+	assert.Equal(t, "A", updated["condition"])
+	assert.Equal(t, map[string]any{"severity": "warning"}, updated["labels"])
+	assert.Equal(t, map[string]any{"receiver": "email"}, updated["notification_settings"])
+	assert.Equal(t, "true", ts.headers[0].Get("X-Disable-Provenance"))
+
+	_, err = updateAlertRule(ctx, UpdateAlertRuleParams{UID: "rule-1", Condition: "C"})
+	assert.ErrorContains(t, err, `the condition "C" must be the refId of one of the queries`)
+
+	ts.rules["rule-2"] = map[string]any{"uid": "rule-2", "title": "No queries", "folderUID": "infra", "ruleGroup": "cpu"}
+	_, err = updateAlertRule(ctx, UpdateAlertRuleParams{UID: "rule-2", Condition: "A"})
+	assert.ErrorContains(t, err, "the rule has no queries")
+
+	_, err = updateAlertRule(ctx, UpdateAlertRuleParams{UID: "missing", Title: "x"})
+	assert.ErrorContains(t, err, "404")
+}
+
+func TestDeleteAlertRule(t *testing.T) {
+	ts := &alertRulesTestServer{rules: map[string]map[string]any{}, groupInterval: 60}
+	ctx := newFakeGrafanaContext(t, ts.routes(t))
+	ts.rules["rule-1"] = map[string]any{"uid": "rule-1", "title": "High CPU", "folderUID": "infra", "ruleGroup": "cpu", "updated": "2024-05-01T10:00:00Z", "provenance": "api"}
+
+	result, err := deleteAlertRule(ctx, DeleteAlertRuleParams{UID: "rule-1"})
+	require.NoError(t, err)
+	assert.False(t, result.Deleted)
+	assert.Equal(t, "High CPU", result.Rule.Title)
+	require.NotNil(t, result.Confirmation)
+	assert.Len(t, result.Confirmation.Impact, 3)
+	assert.Contains(t, ts.rules, "rule-1")
+
+	// The token is no longer valid once the rule was updated.
+	token := result.Confirmation.Token
+	ts.rules["rule-1"]["updated"] = "2024-05-02T10:00:00Z"
+	_, err = deleteAlertRule(ctx, DeleteAlertRuleParams{UID: "rule-1", ConfirmationToken: token})
+	assert.ErrorIs(t, err, mcpgrafana.ErrInvalidConfirmationToken)
+	assert.Contains(t, ts.rules, "rule-1")
+
+	result, err = deleteAlertRule(ctx, DeleteAlertRuleParams{UID: "rule-1"})
+	require.NoError(t, err)
+	result, err = deleteAlertRule(ctx, DeleteAlertRuleParams{UID: "rule-1", ConfirmationToken: result.Confirmation.Token})
+	require.NoError(t, err)
+	assert.True(t, result.Deleted)
+	assert.NotContains(t, ts.rules, "rule-1")
+}
+
+func TestPauseAlertRule(t *testing.T) {
+	ts := &alertRulesTestServer{rules: map[string]map[string]any{}, groupInterval: 60}
+	ctx := newFakeGrafanaContext(t, ts.routes(t))
+	ts.rules["rule-1"] = map[string]any{"uid": "rule-1", "title": "High CPU", "folderUID": "infra", "ruleGroup": "cpu", "condition": "B"}
+
+	rule, err := pauseAlertRule(ctx, PauseAlertRuleParams{UID: "rule-1"})
+	require.NoError(t, err)
+	assert.True(t, rule.Paused)
+	assert.Equal(t, true, ts.rules["rule-1"]["isPaused"])
+	assert.Equal(t, "B", ts.rules["rule-1"]["condition"])
+	assert.Equal(t, "true", ts.headers[0].Get("X-Disable-Provenance"), "rules without provenance are kept without it")
+
+	// Pausing a paused rule changes nothing.
+	_, err = pauseAlertRule(ctx, PauseAlertRuleParams{UID: "rule-1"})
+	require.NoError(t, err)
+	assert.Len(t, ts.headers, 1)
+
+	paused := false
+	rule, err = pauseAlertRule(ctx, PauseAlertRuleParams{UID: "rule-1", Paused: &paused})
+	require.NoError(t, err)
+	assert.False(t, rule.Paused)
+	assert.Equal(t, false, ts.rules["rule-1"]["isPaused"])
+
+	// Provisioned rules stay provisioned.
+	ts.rules["rule-2"] = map[string]any{"uid": "rule-2", "title": "High memory", "provenance": "api"}
+	_, err = pauseAlertRule(ctx, PauseAlertRuleParams{UID: "rule-2"})
+	require.NoError(t, err)
+	assert.Empty(t, ts.headers[len(ts.headers)-1].Get("X-Disable-Provenance"))
+}
@@ -0,0 +1,283 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	silencesPath = "/api/alertmanager/grafana/api/v2/silences"
+
+	// defaultSilenceCreator is the creator of silences when none is given.
+	defaultSilenceCreator = "Grafana MCP server"
+)
+
+var silenceStates = []string{"active", "pending", "expired"}
+
+// SilenceMatcher selects the alerts a silence applies to by one of their
+// labels.
+type SilenceMatcher struct {
+	Name    string `json:"name" jsonschema:"required,description=The name of the label"`
+	Value   string `json:"value" jsonschema:"required,description=The value of the label\\, or a regular expression if isRegex is true"`
+	IsRegex bool   `json:"isRegex,omitempty" jsonschema:"description=Whether the value is a regular expression"`
+	// IsEqual is a pointer so that it can default to true.
+	IsEqual *bool `json:"isEqual,omitempty" jsonschema:"description=Whether the label must match the value (default true). Set to false to select alerts whose label doesn't match"`
+}
+
+// filter returns the matcher in the filter syntax of the Alertmanager API,
+// e.g. alertname="HighCPU".
+func (m SilenceMatcher) filter() string {
+	op := "="
+	if m.IsEqual != nil && !*m.IsEqual {
+		op = "!="
+	}
+	if m.IsRegex {
+		// =~ or !~
+		op = op[:1] + "~"
+	}
+	return m.Name + op + strconv.Quote(m.Value)
+}
+
+// Silence is a silence of Grafana Alerting, which mutes the notifications of
+// the alerts it matches from its start until its end.
+type Silence struct {
+	ID        string           `json:"id"`
+	State     string           `json:"state"`
+	Matchers  []SilenceMatcher `json:"matchers"`
+	StartsAt  time.Time        `json:"startsAt"`
+	EndsAt    time.Time        `json:"endsAt"`
+	CreatedBy string           `json:"createdBy"`
+	Comment   string           `json:"comment"`
+	URL       string           `json:"url,omitempty"`
+}
+
+// gettableSilence is a silence returned by the Alertmanager API.
+type gettableSilence struct {
+	Silence
+	Status struct {
+		State string `json:"state"`
+	} `json:"status"`
+}
+
+// silenceURL returns the URL of the page of a silence in Grafana.
+func silenceURL(ctx context.Context, id string) string {
+	base, _ := deeplinkBase(ctx)
+	return fmt.Sprintf("%s/alerting/silence/%s/edit", base, id)
+}
+
+type ListSilencesParams struct {
+	States   []string         `json:"states,omitempty" jsonschema:"description=Optionally\\, the states of the silences to list: active\\, pending or expired (default active and pending)"`
+	Matchers []SilenceMatcher `json:"matchers,omitempty" jsonschema:"description=Optionally\\, only list the silences with these matchers"`
+}
+
+func listSilences(ctx context.Context, args ListSilencesParams) ([]Silence, error) {
+	states := args.States
+	if len(states) == 0 {
+		states = []string{"active", "pending"}
+	}
+	for _, s := range states {
+		if !slices.Contains(silenceStates, s) {
+			return nil, fmt.Errorf("invalid state %q: must be one of %v", s, silenceStates)
+		}
+	}
+	c, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list silences: %w", err)
+	}
+	params := url.Values{}
+	for _, m := range args.Matchers {
+		params.Add("filter", m.filter())
+	}
+	var silences []gettableSilence
+	if err := c.getJSON(ctx, silencesPath, params, &silences); err != nil {
+		return nil, fmt.Errorf("list silences: %w", err)
+	}
+	result := []Silence{}
+	for _, s := range silences {
+		if !slices.Contains(states, s.Status.State) {
+			continue
+		}
+		silence := s.Silence
+		silence.State = s.Status.State
+		silence.URL = silenceURL(ctx, silence.ID)
+		result = append(result, silence)
+	}
+	slices.SortFunc(result, func(a, b Silence) int { return b.StartsAt.Compare(a.StartsAt) })
+	return result, nil
+}
+
+var ListSilences = mcpgrafana.MustTool(
+	"grafana_list_silences",
+	"List the silences of Grafana Alerting, which mute the notifications of the alerts they match, with their matchers, start and end, creator and comment. By default only active and pending silences are listed, most recent first.",
+	listSilences,
+	mcp.WithTitleAnnotation("List silences"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type CreateSilenceParams struct {
+	Matchers  []SilenceMatcher `json:"matchers" jsonschema:"required,description=The matchers selecting the alerts to silence\\, e.g. alertname equal to HighCPU. Alerts must match all of them"`
+	Duration  string           `json:"duration,omitempty" jsonschema:"description=How long the silence lasts from its start\\, e.g. 2h. Either duration or endsAt is required"`
+	StartsAt  string           `json:"startsAt,omitempty" jsonschema:"description=Optionally\\, when the silence starts\\, as RFC3339 or relative to now\\, e.g. now+1h (default now)"`
+	EndsAt    string           `json:"endsAt,omitempty" jsonschema:"description=When the silence ends\\, as RFC3339 or relative to now\\, e.g. now+2h"`
+	Comment   string           `json:"comment" jsonschema:"required,description=Why the alerts are silenced\\, e.g. the incident or maintenance"`
+	CreatedBy string           `json:"createdBy,omitempty" jsonschema:"description=Optionally\\, who the silence is created for (default Grafana MCP server)"`
+}
+
+// CreatedSilence is a silence which was created, with the number of firing
+// alerts it silenced which were not silenced yet, unless they could not be
+// listed.
+type CreatedSilence struct {
+	Silence
+	SilencedAlerts *int `json:"silencedAlerts,omitempty"`
+}
+
+// silencePeriod returns the start and end of a silence.
+func silencePeriod(args CreateSilenceParams) (time.Time, time.Time, error) {
+	startsAt := time.Now()
+	if args.StartsAt != "" {
+		t, err := parseTime(args.StartsAt)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid startsAt: %w", err)
+		}
+		startsAt = t
+	}
+	var endsAt time.Time
+	switch {
+	case args.Duration != "" && args.EndsAt != "":
+		return time.Time{}, time.Time{}, fmt.Errorf("only one of duration and endsAt can be given")
+	case args.Duration != "":
+		d, ok := parseAnyDuration(args.Duration)
+		if !ok || d <= 0 {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid duration %q: must be a duration such as 2h", args.Duration)
+		}
+		endsAt = startsAt.Add(d)
+	case args.EndsAt != "":
+		t, err := parseTime(args.EndsAt)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid endsAt: %w", err)
+		}
+		endsAt = t
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("duration or endsAt is required")
+	}
+	if !endsAt.After(startsAt) || !endsAt.After(time.Now()) {
+		return time.Time{}, time.Time{}, fmt.Errorf("the silence must end after its start and in the future")
+	}
+	return startsAt, endsAt, nil
+}
+
+func createSilence(ctx context.Context, args CreateSilenceParams) (*CreatedSilence, error) {
+	if len(args.Matchers) == 0 {
+		return nil, fmt.Errorf("create silence: at least one matcher is required")
+	}
+	for i, m := range args.Matchers {
+		if m.Name == "" {
+			return nil, fmt.Errorf("create silence: matcher %d: a label name is required", i)
+		}
+	}
+	if args.Comment == "" {
+		return nil, fmt.Errorf("create silence: a comment is required")
+	}
+	startsAt, endsAt, err := silencePeriod(args)
+	if err != nil {
+		return nil, fmt.Errorf("create silence: %w", err)
+	}
+
+	// The alerts which are silenced are counted before the silence is
+	// created, while they are not silenced yet.
+	filters := make([]string, len(args.Matchers))
+	matchers := make([]map[string]any, len(args.Matchers))
+	for i, m := range args.Matchers {
+		filters[i] = m.filter()
+		matchers[i] = map[string]any{"name": m.Name, "value": m.Value, "isRegex": m.IsRegex, "isEqual": m.IsEqual == nil || *m.IsEqual}
+	}
+	var silenced *int
+	if firing, err := fetchFiringAlerts(ctx, filters); err == nil {
+		n := len(firing)
+		silenced = &n
+	}
+
+	c, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create silence: %w", err)
+	}
+	body := map[string]any{
+		"matchers":  matchers,
+		"startsAt":  startsAt.UTC().Format(time.RFC3339),
+		"endsAt":    endsAt.UTC().Format(time.RFC3339),
+		"comment":   args.Comment,
+		"createdBy": cmp.Or(args.CreatedBy, defaultSilenceCreator),
+	}
+	var created struct {
+		SilenceID string `json:"silenceID"`
+	}
+	if err := c.sendJSON(ctx, http.MethodPost, silencesPath, body, &created); err != nil {
+		return nil, fmt.Errorf("create silence: %w", err)
+	}
+	state := "active"
+	if startsAt.After(time.Now()) {
+		state = "pending"
+	}
+	return &CreatedSilence{
+		Silence: Silence{
+			ID:        created.SilenceID,
+			State:     state,
+			Matchers:  args.Matchers,
+			StartsAt:  startsAt.UTC().Truncate(time.Second),
+			EndsAt:    endsAt.UTC().Truncate(time.Second),
+			CreatedBy: body["createdBy"].(string),
+			Comment:   args.Comment,
+			URL:       silenceURL(ctx, created.SilenceID),
+		},
+		SilencedAlerts: silenced,
+	}, nil
+}
+
+var CreateSilence = mcpgrafana.MustTool(
+	"grafana_create_silence",
+	"Silence the notifications of the alerts of Grafana Alerting matching label matchers for a period, e.g. to silence an alert for 2 hours during an incident. Alerts keep being evaluated and shown as silenced. Returns the silence with its URL and the number of firing alerts it silenced which were not silenced yet; check the matchers with the user when it is 0.",
+	createSilence,
+	mcp.WithTitleAnnotation("Create silence"),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithIdempotentHintAnnotation(false),
+)
+
+type DeleteSilenceParams struct {
+	ID string `json:"id" jsonschema:"required,description=The ID of the silence to expire"`
+}
+
+func deleteSilence(ctx context.Context, args DeleteSilenceParams) (string, error) {
+	if args.ID == "" {
+		return "", fmt.Errorf("delete silence: id is required")
+	}
+	c, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return "", fmt.Errorf("delete silence %s: %w", args.ID, err)
+	}
+	resp, err := c.doRequest(ctx, http.MethodDelete, "/api/alertmanager/grafana/api/v2/silence/"+url.PathEscape(args.ID), nil, nil)
+	if err != nil {
+		return "", fmt.Errorf("delete silence %s: %w", args.ID, err)
+	}
+	resp.Body.Close()
+	return fmt.Sprintf("Silence %s expired; the alerts it matched notify again.", args.ID), nil
+}
+
+var DeleteSilence = mcpgrafana.MustTool(
+	"grafana_delete_silence",
+	"Expire a silence of Grafana Alerting, so that the alerts it matched notify again, e.g. when an incident is resolved before the silence ends. Expired silences are kept in the list of silences.",
+	deleteSilence,
+	mcp.WithTitleAnnotation("Expire silence"),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithIdempotentHintAnnotation(true),
+)
@@ -0,0 +1,97 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSilenceMatcherFilter(t *testing.T) {
+	notEqual := false
+	assert.Equal(t, `alertname="HighCPU"`, SilenceMatcher{Name: "alertname", Value: "HighCPU"}.filter())
+	assert.Equal(t, `env!="dev"`, SilenceMatcher{Name: "env", Value: "dev", IsEqual: &notEqual}.filter())
+	assert.Equal(t, `pod=~"api-.*"`, SilenceMatcher{Name: "pod", Value: "api-.*", IsRegex: true}.filter())
+	assert.Equal(t, `pod!~"api-.*"`, SilenceMatcher{Name: "pod", Value: "api-.*", IsRegex: true, IsEqual: &notEqual}.filter())
+}
+
+func TestSilences(t *testing.T) {
+	var created map[string]any
+	var filters []string
+	var expired string
+	server := newFakeGrafana(t, fakeRoutes{
+		"GET /api/alertmanager/grafana/api/v2/silences": func(w http.ResponseWriter, r *http.Request) {
+			filters = r.URL.Query()["filter"]
+			_, _ = w.Write([]byte(`[
+				{"id": "s1", "status": {"state": "expired"}, "matchers": [{"name": "alertname", "value": "Old", "isEqual": true}], "startsAt": "2024-05-01T10:00:00Z", "endsAt": "2024-05-01T12:00:00Z", "createdBy": "alice", "comment": "old"},
+				{"id": "s2", "status": {"state": "active"}, "matchers": [{"name": "alertname", "value": "HighCPU", "isEqual": true}], "startsAt": "2024-05-02T10:00:00Z", "endsAt": "2024-05-02T12:00:00Z", "createdBy": "bob", "comment": "incident"}
+			]`))
+		},
+		"POST /api/alertmanager/grafana/api/v2/silences": func(w http.ResponseWriter, r *http.Request) {
+			if !decodeJSONBody(t, w, r, &created) {
+				return
+			}
+			_, _ = w.Write([]byte(`{"silenceID": "s3"}`))
+		},
+		"/api/alertmanager/grafana/api/v2/alerts": jsonBody(`[{"labels": {"alertname": "HighCPU", "instance": "a"}}, {"labels": {"alertname": "HighCPU", "instance": "b"}}]`),
+		"DELETE /api/alertmanager/grafana/api/v2/silence/s2": func(w http.ResponseWriter, r *http.Request) {
+			expired = "s2"
+		},
+	})
+	ctx := fakeGrafanaContext(server)
+
+	t.Run("list", func(t *testing.T) {
+		silences, err := listSilences(ctx, ListSilencesParams{Matchers: []SilenceMatcher{{Name: "alertname", Value: "HighCPU"}}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{`alertname="HighCPU"`}, filters)
+		require.Len(t, silences, 1)
+		assert.Equal(t, "s2", silences[0].ID)
+		assert.Equal(t, "active", silences[0].State)
+		assert.Equal(t, server.URL+"/alerting/silence/s2/edit", silences[0].URL)
+
+		silences, err = listSilences(ctx, ListSilencesParams{States: []string{"active", "expired"}})
+		require.NoError(t, err)
+		require.Len(t, silences, 2)
+		assert.Equal(t, "s2", silences[0].ID, "most recent first")
+
+		_, err = listSilences(ctx, ListSilencesParams{States: []string{"firing"}})
+		assert.ErrorContains(t, err, "invalid state")
+	})
+
+	t.Run("create", func(t *testing.T) {
+		silence, err := createSilence(ctx, CreateSilenceParams{
+			Matchers: []SilenceMatcher{{Name: "alertname", Value: "HighCPU"}},
+			Duration: "2h",
+			Comment:  "INC-42",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "s3", silence.ID)
+		assert.Equal(t, "active", silence.State)
+		require.NotNil(t, silence.SilencedAlerts)
+		assert.Equal(t, 2, *silence.SilencedAlerts)
+		assert.WithinDuration(t, silence.StartsAt.Add(2*time.Hour), silence.EndsAt, time.Second)
+		assert.Equal(t, "Grafana MCP server", created["createdBy"])
+		assert.Equal(t, []any{map[string]any{"name": "alertname", "value": "HighCPU", "isRegex": false, "isEqual": true}}, created["matchers"])
+	})
+
+	t.Run("invalid period", func(t *testing.T) {
+		matchers := []SilenceMatcher{{Name: "alertname", Value: "HighCPU"}}
+		_, err := createSilence(ctx, CreateSilenceParams{Matchers: matchers, Comment: "x"})
+		assert.ErrorContains(t, err, "duration or endsAt is required")
+		_, err = createSilence(ctx, CreateSilenceParams{Matchers: matchers, Comment: "x", Duration: "1h", EndsAt: "now+2h"})
+		assert.ErrorContains(t, err, "only one of duration and endsAt")
+		_, err = createSilence(ctx, CreateSilenceParams{Matchers: matchers, Comment: "x", EndsAt: "now-1h"})
+		assert.ErrorContains(t, err, "must end after its start")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		_, err := deleteSilence(ctx, DeleteSilenceParams{ID: "s2"})
+		require.NoError(t, err)
+		assert.Equal(t, "s2", expired)
+	})
+}
@@ -0,0 +1,102 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+func TestScopeSelectors(t *testing.T) {
+	scope := mcpgrafana.Scope{Labels: map[string]string{"team": "payments", "env": "prod"}}
+	rules := []alertingRule{
+		{UID: "payments-prod", Labels: labels.FromStrings("team", "payments", "env", "prod")},
+		{UID: "payments-dev", Labels: labels.FromStrings("team", "payments", "env", "dev")},
+		{UID: "search-prod", Labels: labels.FromStrings("team", "search", "env", "prod")},
+	}
+	uids := func(selectors []Selector) []string {
+		filtered, err := filterAlertRules(rules, selectors)
+		require.NoError(t, err)
+		var out []string
+		for _, r := range filtered {
+			out = append(out, r.UID)
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{"payments-prod"}, uids(scopeSelectors(scope, nil)))
+
+	// Selectors on a scope label replace the scope's matcher for it.
+	devSelector := Selector{Filters: []LabelMatcher{{Name: "env", Value: "dev", Type: "="}}}
+	selectors := []Selector{devSelector}
+	assert.Equal(t, []string{"payments-dev"}, uids(scopeSelectors(scope, selectors)))
+	assert.Equal(t, []Selector{devSelector}, selectors, "the given selectors must not be modified")
+
+	assert.Empty(t, scopeSelectors(mcpgrafana.Scope{}, nil))
+}
+
+func TestListAlertRulesPagination(t *testing.T) {
+	// Two pages of one rule group each, with three and two rules.
+	pages := map[string]rulesResponse{}
+	for token, group := range map[string]ruleGroup{
+		"": {Name: "cpu", FolderUID: "infra", Rules: []alertingRule{
+			{UID: "r1", State: "firing"}, {UID: "r2", State: "inactive"}, {UID: "r3", State: "firing"},
+		}},
+		"next": {Name: "disk", FolderUID: "infra", Rules: []alertingRule{
+			{UID: "r4", State: "firing"}, {UID: "r5", State: "pending"},
+		}},
+	} {
+		var resp rulesResponse
+		resp.Data.RuleGroups = []ruleGroup{group}
+		if token == "" {
+			resp.Data.NextToken = "next"
+		}
+		pages[token] = resp
+	}
+	var queries []url.Values
+	ctx := newFakeGrafanaContext(t, fakeRoutes{
+		rulesEndpointPath: func(w http.ResponseWriter, r *http.Request) {
+			queries = append(queries, r.URL.Query())
+			_ = json.NewEncoder(w).Encode(pages[r.URL.Query().Get("group_next_token")])
+		},
+	})
+	uids := func(rules []alertRuleSummary) []string {
+		var out []string
+		for _, r := range rules {
+			out = append(out, r.UID)
+		}
+		return out
+	}
+
+	rules, err := listAlertRules(ctx, ListAlertRulesParams{Limit: 2, Page: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"r1", "r2"}, uids(rules))
+	require.Len(t, queries, 1, "the second page of groups isn't needed")
+	assert.Equal(t, "0", queries[0].Get("limit_alerts"))
+	assert.Equal(t, "100", queries[0].Get("group_limit"))
+
+	queries = nil
+	rules, err = listAlertRules(ctx, ListAlertRulesParams{Limit: 2, Page: 2, FolderUID: "infra", States: []string{"firing", "pending"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"r4", "r5"}, uids(rules), "filtered by state when the server ignores the filter")
+	require.Len(t, queries, 2)
+	assert.Equal(t, "infra", queries[0].Get("folder_uid"))
+	assert.Equal(t, []string{"firing", "pending"}, queries[0]["state"])
+	assert.Equal(t, "next", queries[1].Get("group_next_token"))
+
+	rules, err = listAlertRules(ctx, ListAlertRulesParams{RuleGroup: "disk"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"r4", "r5"}, uids(rules))
+
+	_, err = listAlertRules(ctx, ListAlertRulesParams{States: []string{"normal"}})
+	assert.ErrorContains(t, err, "invalid state")
+}
@@ -0,0 +1,49 @@
+package tools
+
+// DeprecatedToolNames maps the names tools had before they were prefixed with
+// "grafana_" to their current names. The old names are registered as
+// deprecated aliases, so that client configurations referring to them keep
+// working, unless the server is started with --disable-deprecated-aliases.
+//
+// When renaming a tool, add its old name here.
+var DeprecatedToolNames = map[string]string{
+	"add_activity_to_incident":        "grafana_add_activity_to_incident",
+	"create_incident":                 "grafana_create_incident",
+	"fetch_pyroscope_profile":         "grafana_fetch_pyroscope_profile",
+	"find_error_pattern_logs":         "grafana_find_error_pattern_logs",
+	"find_slow_requests":              "grafana_find_slow_requests",
+	"get_alert_rule_by_uid":           "grafana_get_alert_rule_by_uid",
+	"get_assertions":                  "grafana_get_assertions",
+	"get_current_oncall_users":        "grafana_get_current_oncall_users",
+	"get_dashboard_by_uid":            "grafana_get_dashboard_by_uid",
+	"get_dashboard_panel_queries":     "grafana_get_dashboard_panel_queries",
+	"get_datasource_by_name":          "grafana_get_datasource_by_name",
+	"get_datasource_by_uid":           "grafana_get_datasource_by_uid",
+	"get_incident":                    "grafana_get_incident",
+	"get_oncall_shift":                "grafana_get_oncall_shift",
+	"get_sift_analysis":               "grafana_get_sift_analysis",
+	"get_sift_investigation":          "grafana_get_sift_investigation",
+	"list_alert_rules":                "grafana_list_alert_rules",
+	"list_contact_points":             "grafana_list_contact_points",
+	"list_datasources":                "grafana_list_datasources",
+	"list_incidents":                  "grafana_list_incidents",
+	"list_loki_label_names":           "grafana_list_loki_label_names",
+	"list_loki_label_values":          "grafana_list_loki_label_values",
+	"list_oncall_schedules":           "grafana_list_oncall_schedules",
+	"list_oncall_teams":               "grafana_list_oncall_teams",
+	"list_oncall_users":               "grafana_list_oncall_users",
+	"list_prometheus_label_names":     "grafana_list_prometheus_label_names",
+	"list_prometheus_label_values":    "grafana_list_prometheus_label_values",
+	"list_prometheus_metric_metadata": "grafana_list_prometheus_metric_metadata",
+	"list_prometheus_metric_names":    "grafana_list_prometheus_metric_names",
+	"list_pyroscope_label_names":      "grafana_list_pyroscope_label_names",
+	"list_pyroscope_label_values":     "grafana_list_pyroscope_label_values",
+	"list_pyroscope_profile_types":    "grafana_list_pyroscope_profile_types",
+	"list_sift_investigations":        "grafana_list_sift_investigations",
+	"list_teams":                      "grafana_list_teams",
+	"query_loki_logs":                 "grafana_query_loki_logs",
+	"query_loki_stats":                "grafana_query_loki_stats",
+	"query_prometheus":                "grafana_query_prometheus",
+	"search_dashboards":               "grafana_search_dashboards",
+	"update_dashboard":                "grafana_update_dashboard",
+}
@@ -0,0 +1,32 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"testing"
+
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+func TestDeprecatedToolNames(t *testing.T) {
+	s := server.NewMCPServer("test", "0.0.0")
+	for _, add := range []func(*server.MCPServer){
+		AddAssertsTools, AddDatasourceTools, AddLokiTools,
+		AddPrometheusTools, AddPyroscopeTools, AddSearchTools, AddSiftTools,
+		AddAdminTools, AddAlertingTools, AddDashboardTools, AddIncidentTools,
+		AddOnCallTools,
+	} {
+		add(s)
+	}
+	// Every alias must refer to an existing tool and must not shadow one.
+	for alias, name := range DeprecatedToolNames {
+		_, ok := mcpgrafana.LookupTool(name)
+		assert.True(t, ok, "alias %s refers to unknown tool %s", alias, name)
+		_, ok = mcpgrafana.LookupTool(alias)
+		assert.False(t, ok, "alias %s is the name of a tool", alias)
+	}
+}
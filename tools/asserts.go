@@ -19,23 +19,9 @@ func newAssertsClient(ctx context.Context) (*Client, error) {
 	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
 	url := fmt.Sprintf("%s/api/plugins/grafana-asserts-app/resources/asserts/api-server", strings.TrimRight(cfg.URL, "/"))
 
-	// Create custom transport with TLS configuration if available
-	var transport http.RoundTripper = http.DefaultTransport
-	if tlsConfig := cfg.TLSConfig; tlsConfig != nil {
-		var err error
-		transport, err = tlsConfig.HTTPTransport(transport.(*http.Transport))
-		if err != nil {
-			return nil, fmt.Errorf("failed to create custom transport: %w", err)
-		}
-	}
-
-	client := &http.Client{
-		Transport: &authRoundTripper{
-			apiKey:      cfg.APIKey,
-			accessToken: cfg.AccessToken,
-			idToken:     cfg.IDToken,
-			underlying:  transport,
-		},
+	client, err := newGrafanaHTTPClient(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	return &Client{
@@ -92,7 +78,7 @@ func (c *Client) fetchAssertsData(ctx context.Context, urlPath string, method st
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(mcpgrafana.LimitResponseBody(resp.Body, mcpgrafana.MaxResponseSize))
 	if err != nil {
 		return "", fmt.Errorf("failed to read response body: %w", err)
 	}
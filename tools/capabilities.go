@@ -0,0 +1,307 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/grafana/grafana-openapi-client-go/client/datasources"
+	"github.com/grafana/grafana-openapi-client-go/client/search"
+	"github.com/grafana/grafana-openapi-client-go/client/teams"
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// errPermissionDenied is returned by category probes when the configured
+// credentials are not allowed to use the category's APIs.
+var errPermissionDenied = errors.New("permission denied")
+
+// errNotFound is returned by category probes when an API or plugin that the
+// category depends on does not exist on the Grafana instance.
+var errNotFound = errors.New("not found")
+
+// classifyAPIError maps errors returned by the Grafana OpenAPI client onto
+// errPermissionDenied or errNotFound, where possible.
+func classifyAPIError(err error) error {
+	var coded interface{ IsCode(int) bool }
+	if !errors.As(err, &coded) {
+		return err
+	}
+	switch {
+	case coded.IsCode(http.StatusUnauthorized), coded.IsCode(http.StatusForbidden):
+		return fmt.Errorf("%w: %s", errPermissionDenied, err)
+	case coded.IsCode(http.StatusNotFound):
+		return fmt.Errorf("%w: %s", errNotFound, err)
+	}
+	return err
+}
+
+// categoryProbe checks whether the tools in a category are expected to work
+// against the configured Grafana instance. It returns nil if they are.
+type categoryProbe func(ctx context.Context) error
+
+// probePlugin returns a probe that checks that the given app plugin is
+// installed and accessible.
+func probePlugin(pluginID string) categoryProbe {
+	return func(ctx context.Context) error {
+		cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+		settingsURL := fmt.Sprintf("%s/api/plugins/%s/settings", strings.TrimRight(cfg.URL, "/"), pluginID)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, settingsURL, nil)
+		if err != nil {
+			return fmt.Errorf("creating plugin settings request: %w", err)
+		}
+
+		client, err := newGrafanaHTTPClient(ctx)
+		if err != nil {
+			return err
+		}
+		client.Timeout = defaultTimeout
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("fetching plugin settings: %w", err)
+		}
+		defer resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			return nil
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return fmt.Errorf("%w: cannot read settings of plugin %s", errPermissionDenied, pluginID)
+		case http.StatusNotFound:
+			return fmt.Errorf("%w: plugin %s is not installed", errNotFound, pluginID)
+		}
+		return fmt.Errorf("unexpected status code %d fetching settings of plugin %s", resp.StatusCode, pluginID)
+	}
+}
+
+// probeDatasourceType returns a probe that checks that at least one
+// datasource of the given type is configured.
+func probeDatasourceType(dsType string) categoryProbe {
+	return func(ctx context.Context) error {
+		c := mcpgrafana.GrafanaClientFromContext(ctx)
+		resp, err := c.Datasources.GetDataSourcesWithParams(datasources.NewGetDataSourcesParamsWithContext(ctx))
+		if err != nil {
+			return classifyAPIError(err)
+		}
+		if len(filterDatasources(resp.Payload, dsType)) == 0 {
+			return fmt.Errorf("%w: no %s datasources are configured", errNotFound, dsType)
+		}
+		return nil
+	}
+}
+
+func probeSearch(ctx context.Context) error {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	limit := int64(1)
+	_, err := c.Search.Search(search.NewSearchParamsWithContext(ctx).WithLimit(&limit))
+	return classifyAPIError(err)
+}
+
+// probeMigrationTarget checks that a migration target is configured and can
+// be searched.
+func probeMigrationTarget(ctx context.Context) error {
+	if mcpgrafana.GrafanaConfigFromContext(ctx).MigrationTarget == nil {
+		return fmt.Errorf("%w: no migration target is configured", errNotFound)
+	}
+	targetCtx, err := migrationTargetContext(ctx)
+	if err != nil {
+		return err
+	}
+	return probeSearch(targetCtx)
+}
+
+func probeDatasources(ctx context.Context) error {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	_, err := c.Datasources.GetDataSourcesWithParams(datasources.NewGetDataSourcesParamsWithContext(ctx))
+	return classifyAPIError(err)
+}
+
+func probeTeams(ctx context.Context) error {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	perPage := int64(1)
+	_, err := c.Teams.SearchTeams(teams.NewSearchTeamsParamsWithContext(ctx).WithPerpage(&perPage))
+	return classifyAPIError(err)
+}
+
+func probeAlerting(ctx context.Context) error {
+	c, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	resp, err := c.makeRequest(ctx, rulesEndpointPath)
+	if err != nil {
+		return classifyAPIError(err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// categoryProbes maps each category to the probe used to detect whether it
+// is degraded. Categories without a probe are never reported as degraded.
+var categoryProbes = map[string]categoryProbe{
+	"search":        probeSearch,
+	"dashboard":     probeSearch,
+	"datasource":    probeDatasources,
+	"prometheus":    probeDatasourceType("prometheus"),
+	"loki":          probeDatasourceType("loki"),
+	"pyroscope":     probeDatasourceType("pyroscope"),
+	"tempo":         probeDatasourceType("tempo"),
+	"elasticsearch": probeDatasourceType("elasticsearch"),
+	"graphite":      probeDatasourceType("graphite"),
+	"migration":     probeMigrationTarget,
+	"alerting":      probeAlerting,
+	"incident":      probePlugin("grafana-irm-app"),
+	"oncall":        probePlugin("grafana-irm-app"),
+	"sift":          probePlugin("grafana-ml-app"),
+	"asserts":       probePlugin("grafana-asserts-app"),
+	"admin":         probeTeams,
+}
+
+// CategoryStatus describes a category of tools, whether it is enabled and
+// whether it is expected to work against the configured Grafana instance.
+type CategoryStatus struct {
+	Name     string   `json:"name"`
+	Enabled  bool     `json:"enabled"`
+	Degraded bool     `json:"degraded,omitempty"`
+	Reason   string   `json:"reason,omitempty"`
+	Tools    []string `json:"tools,omitempty"`
+	// Features are the features of the category, such as write, and
+	// whether they are enabled.
+	Features map[string]bool `json:"features,omitempty"`
+}
+
+// Capabilities describes what the server can do for the current client.
+type Capabilities struct {
+	Categories []CategoryStatus      `json:"categories"`
+	Tools      []mcpgrafana.ToolInfo `json:"tools"`
+	Limits     map[string]any        `json:"limits"`
+}
+
+type ListCapabilitiesParams struct {
+	SkipChecks bool `json:"skipChecks,omitempty" jsonschema:"description=Optionally\\, skip checking enabled categories against Grafana. Degraded categories will not be reported."`
+}
+
+// defaultLimits returns the limits built in to the tools themselves.
+func defaultLimits() map[string]any {
+	return map[string]any{
+		"lokiDefaultLogLimit":       DefaultLokiLogLimit,
+		"lokiMaxLogLimit":           MaxLokiLogLimit,
+		"lokiDefaultMetricLimit":    DefaultLokiMetricLimit,
+		"lokiMaxMetricLimit":        MaxLokiMetricLimit,
+		"alertRulesDefaultLimit":    DefaultListAlertRulesLimit,
+		"contactPointsDefaultLimit": DefaultListContactPointsLimit,
+	}
+}
+
+func listCapabilities(ctx context.Context, args ListCapabilitiesParams) (*Capabilities, error) {
+	categories := mcpgrafana.Categories()
+	result := &Capabilities{
+		Categories: make([]CategoryStatus, 0, len(categories)),
+		Tools:      mcpgrafana.RegisteredTools(),
+		Limits:     defaultLimits(),
+	}
+	for k, v := range mcpgrafana.Limits() {
+		result.Limits[k] = v
+	}
+
+	for _, c := range categories {
+		status := CategoryStatus{
+			Name:     c.Name,
+			Enabled:  c.Enabled,
+			Tools:    c.Tools,
+			Features: c.Features,
+		}
+		probe, ok := categoryProbes[c.Name]
+		if c.Enabled && ok && !args.SkipChecks {
+			if err := probe(ctx); err != nil {
+				status.Degraded = true
+				switch {
+				case errors.Is(err, errPermissionDenied):
+					status.Reason = fmt.Sprintf("permission missing: %s", err)
+				case errors.Is(err, errNotFound):
+					status.Reason = fmt.Sprintf("dependency missing: %s", err)
+				default:
+					status.Reason = err.Error()
+				}
+			}
+		}
+		result.Categories = append(result.Categories, status)
+	}
+	return result, nil
+}
+
+var ListCapabilities = mcpgrafana.MustTool(
+	"grafana_list_capabilities",
+	"Lists the categories of tools provided by this server, whether each is enabled, and whether enabled categories are degraded against the configured Grafana instance (for example because a required plugin is not installed or the credentials lack permission). Also returns the category of each registered tool and the server's configuration limits. Call this first to plan which tools to use instead of discovering failures tool-by-tool.",
+	listCapabilities,
+	mcp.WithTitleAnnotation("List server capabilities"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// Toolset describes the tools of an enabled category. Its version changes
+// whenever a tool of the category is added, removed or renamed, or its
+// parameters change.
+type Toolset struct {
+	Category string `json:"category"`
+	Version  string `json:"version"`
+	Tools    int    `json:"tools"`
+}
+
+// ServerVersion describes the build of the server and the tools it provides.
+type ServerVersion struct {
+	mcpgrafana.BuildInfo
+	ProtocolVersion   string    `json:"protocolVersion"`
+	EnabledCategories []string  `json:"enabledCategories"`
+	Toolsets          []Toolset `json:"toolsets"`
+}
+
+// toolsetVersion returns a short hash of the names and parameters of tools.
+func toolsetVersion(tools []string) string {
+	h := sha256.New()
+	for _, tool := range tools {
+		fmt.Fprintf(h, "%s(%s)\n", tool, strings.Join(mcpgrafana.ToolParameters(tool), ","))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+type GetVersionParams struct{}
+
+func getVersion(ctx context.Context, args GetVersionParams) (*ServerVersion, error) {
+	result := &ServerVersion{
+		BuildInfo:         mcpgrafana.ReadBuildInfo(),
+		ProtocolVersion:   mcp.LATEST_PROTOCOL_VERSION,
+		EnabledCategories: []string{},
+		Toolsets:          []Toolset{},
+	}
+	for _, c := range mcpgrafana.Categories() {
+		if !c.Enabled {
+			continue
+		}
+		result.EnabledCategories = append(result.EnabledCategories, c.Name)
+		result.Toolsets = append(result.Toolsets, Toolset{Category: c.Name, Version: toolsetVersion(c.Tools), Tools: len(c.Tools)})
+	}
+	return result, nil
+}
+
+var GetVersion = mcpgrafana.MustTool(
+	"grafana_get_version",
+	"Returns the version of this server and the details of its build (Go version, VCS revision and time, and the versions of the MCP and Grafana client libraries), the latest MCP protocol version it supports, its enabled tool categories, and a version of the toolset of each enabled category which changes whenever its tools or their parameters change. Use it to check which server build and capabilities a client is talking to, e.g. before relying on a recently added tool.",
+	getVersion,
+	mcp.WithTitleAnnotation("Get server version"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// AddCapabilitiesTools registers the tools describing the server itself.
+func AddCapabilitiesTools(mcp *server.MCPServer) {
+	ListCapabilities.Register(mcp)
+	GetVersion.Register(mcp)
+}
@@ -0,0 +1,120 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+func TestProbePlugin(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		status int
+		target error
+	}{
+		{name: "installed", status: http.StatusOK},
+		{name: "not installed", status: http.StatusNotFound, target: errNotFound},
+		{name: "forbidden", status: http.StatusForbidden, target: errPermissionDenied},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := newFakeGrafanaContext(t, fakeRoutes{
+				"/api/plugins/grafana-irm-app/settings": func(w http.ResponseWriter, r *http.Request) {
+					assert.Equal(t, "Bearer test-api-key", r.Header.Get("Authorization"))
+					w.WriteHeader(tc.status)
+				},
+			})
+			err := probePlugin("grafana-irm-app")(ctx)
+			if tc.target == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tc.target)
+		})
+	}
+}
+
+func TestProbeAlerting(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		status int
+		target error
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "unauthorized", status: http.StatusUnauthorized, target: errPermissionDenied},
+		{name: "forbidden", status: http.StatusForbidden, target: errPermissionDenied},
+		{name: "not found", status: http.StatusNotFound, target: errNotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := newFakeGrafanaContext(t, fakeRoutes{
+				rulesEndpointPath: func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(tc.status)
+					_, _ = w.Write([]byte(`{}`))
+				},
+			})
+			err := probeAlerting(ctx)
+			if tc.target == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tc.target)
+		})
+	}
+
+	t.Run("server error", func(t *testing.T) {
+		ctx := newFakeGrafanaContext(t, fakeRoutes{
+			rulesEndpointPath: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+		})
+		err := probeAlerting(ctx)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, errPermissionDenied)
+		assert.NotErrorIs(t, err, errNotFound)
+	})
+}
+
+func TestListCapabilitiesSkipChecks(t *testing.T) {
+	mcpgrafana.RecordLimit("testCapabilitiesLimit", "1s")
+	result, err := listCapabilities(context.Background(), ListCapabilitiesParams{SkipChecks: true})
+	require.NoError(t, err)
+	assert.Equal(t, MaxLokiLogLimit, result.Limits["lokiMaxLogLimit"])
+	assert.Equal(t, "1s", result.Limits["testCapabilitiesLimit"])
+	for _, c := range result.Categories {
+		assert.False(t, c.Degraded)
+	}
+}
+
+func TestGetVersion(t *testing.T) {
+	s := server.NewMCPServer("test", "1.0.0")
+	mcpgrafana.RegisterCategory(s, "versiontest", true, AddCapabilitiesTools)
+	mcpgrafana.RegisterCategory(s, "versiontestdisabled", false, AddCapabilitiesTools)
+
+	result, err := getVersion(context.Background(), GetVersionParams{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.Version)
+	assert.Equal(t, mcp.LATEST_PROTOCOL_VERSION, result.ProtocolVersion)
+	assert.Contains(t, result.EnabledCategories, "versiontest")
+	assert.NotContains(t, result.EnabledCategories, "versiontestdisabled")
+
+	var toolset *Toolset
+	for i := range result.Toolsets {
+		if result.Toolsets[i].Category == "versiontest" {
+			toolset = &result.Toolsets[i]
+		}
+	}
+	require.NotNil(t, toolset)
+	assert.Equal(t, 2, toolset.Tools)
+	assert.Len(t, toolset.Version, 12)
+	assert.Equal(t, toolset.Version, toolsetVersion([]string{"grafana_get_version", "grafana_list_capabilities"}))
+	assert.NotEqual(t, toolset.Version, toolsetVersion([]string{"grafana_list_capabilities"}))
+}
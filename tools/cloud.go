@@ -0,0 +1,487 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// Types of cloud provider datasources.
+const (
+	cloudWatchType      = "cloudwatch"
+	azureMonitorType    = "grafana-azure-monitor-datasource"
+	cloudMonitoringType = "stackdriver"
+
+	// azureMonitorPath is the prefix of the Azure Resource Manager API in the
+	// resources of Azure Monitor datasources.
+	azureMonitorPath = "azuremonitor"
+)
+
+// cloudDatasource is a CloudWatch, Azure Monitor or Google Cloud Monitoring
+// datasource, whose metadata is read through its resource API.
+type cloudDatasource struct {
+	uid      string
+	typ      string
+	jsonData map[string]any
+	client   *dsQueryClient
+}
+
+func newCloudDatasource(ctx context.Context, uid string) (*cloudDatasource, error) {
+	ds, err := getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: uid})
+	if err != nil {
+		return nil, err
+	}
+	switch ds.Type {
+	case cloudWatchType, azureMonitorType, cloudMonitoringType:
+	default:
+		return nil, fmt.Errorf("datasource %s is a %s datasource, not a CloudWatch, Azure Monitor or Google Cloud Monitoring datasource", uid, ds.Type)
+	}
+	client, err := newDSQueryClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	jsonData, _ := ds.JSONData.(map[string]any)
+	return &cloudDatasource{uid: ds.UID, typ: ds.Type, jsonData: jsonData, client: client}, nil
+}
+
+func (d *cloudDatasource) resource(ctx context.Context, path string, params url.Values, v any) error {
+	return d.client.resource(ctx, d.uid, path, params, v)
+}
+
+// CloudMetric is a metric of a cloud provider.
+type CloudMetric struct {
+	Name         string   `json:"name"`
+	Namespace    string   `json:"namespace,omitempty"`
+	Unit         string   `json:"unit,omitempty"`
+	Description  string   `json:"description,omitempty"`
+	Aggregations []string `json:"aggregations,omitempty"`
+	Dimensions   []string `json:"dimensions,omitempty"`
+}
+
+// CloudResource is a region, subscription, project or resource of a cloud
+// provider.
+type CloudResource struct {
+	ID       string `json:"id"`
+	Name     string `json:"name,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Location string `json:"location,omitempty"`
+}
+
+// cloudOption is an option returned by the CloudWatch resource API, whose
+// value is a string or an object with a name.
+type cloudOption struct {
+	Text  string          `json:"text"`
+	Value json.RawMessage `json:"value"`
+}
+
+func (o cloudOption) name() string {
+	var s string
+	if err := json.Unmarshal(o.Value, &s); err == nil {
+		return s
+	}
+	var v struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(o.Value, &v); err == nil && v.Name != "" {
+		return v.Name
+	}
+	return o.Text
+}
+
+// cloudWatchOptions fetches a CloudWatch resource listing options, returning
+// their names. An empty region selects the datasource's default region.
+func (d *cloudDatasource) cloudWatchOptions(ctx context.Context, path string, params url.Values) ([]string, error) {
+	if params.Get("region") == "" {
+		params.Set("region", "default")
+	}
+	var options []cloudOption
+	if err := d.resource(ctx, path, params, &options); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(options))
+	for _, o := range options {
+		names = append(names, o.name())
+	}
+	return names, nil
+}
+
+// azureMetricDefinition is a metric definition of the Azure Monitor API.
+type azureMetricDefinition struct {
+	Name struct {
+		Value string `json:"value"`
+	} `json:"name"`
+	Namespace                 string   `json:"namespace"`
+	Unit                      string   `json:"unit"`
+	PrimaryAggregationType    string   `json:"primaryAggregationType"`
+	SupportedAggregationTypes []string `json:"supportedAggregationTypes"`
+	Dimensions                []struct {
+		Value string `json:"value"`
+	} `json:"dimensions"`
+}
+
+func (m azureMetricDefinition) metric() CloudMetric {
+	c := CloudMetric{Name: m.Name.Value, Namespace: m.Namespace, Unit: m.Unit, Aggregations: m.SupportedAggregationTypes}
+	if len(c.Aggregations) == 0 && m.PrimaryAggregationType != "" {
+		c.Aggregations = []string{m.PrimaryAggregationType}
+	}
+	for _, d := range m.Dimensions {
+		c.Dimensions = append(c.Dimensions, d.Value)
+	}
+	return c
+}
+
+// azureResourcePath returns the resource path of an Azure Resource Manager
+// URI, which must be given.
+func azureResourcePath(resourceURI, suffix string) (string, error) {
+	if resourceURI == "" {
+		return "", fmt.Errorf("resourceUri is required for Azure Monitor datasources, e.g. /subscriptions/<id>/resourceGroups/<group>/providers/Microsoft.Compute/virtualMachines/<name>; use grafana_list_cloud_resources to find it")
+	}
+	return azureMonitorPath + "/" + strings.Trim(resourceURI, "/") + suffix, nil
+}
+
+func (d *cloudDatasource) azureMetricDefinitions(ctx context.Context, resourceURI, namespace string) ([]azureMetricDefinition, error) {
+	path, err := azureResourcePath(resourceURI, "/providers/microsoft.insights/metricdefinitions")
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{"api-version": {"2018-01-01"}}
+	if namespace != "" {
+		params.Set("metricnamespace", namespace)
+	}
+	var resp struct {
+		Value []azureMetricDefinition `json:"value"`
+	}
+	if err := d.resource(ctx, path, params, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Value, nil
+}
+
+// gcpMetricDescriptor is a metric descriptor of the Cloud Monitoring API.
+type gcpMetricDescriptor struct {
+	Type        string `json:"type"`
+	Service     string `json:"service"`
+	Description string `json:"description"`
+	Unit        string `json:"unit"`
+	Labels      []struct {
+		Key string `json:"key"`
+	} `json:"labels"`
+}
+
+func (m gcpMetricDescriptor) metric() CloudMetric {
+	c := CloudMetric{Name: m.Type, Namespace: m.service(), Unit: m.Unit, Description: m.Description}
+	for _, l := range m.Labels {
+		c.Dimensions = append(c.Dimensions, l.Key)
+	}
+	return c
+}
+
+// service returns the service of the metric, e.g. compute.googleapis.com.
+func (m gcpMetricDescriptor) service() string {
+	if m.Service != "" {
+		return m.Service
+	}
+	service, _, _ := strings.Cut(m.Type, "/")
+	return service
+}
+
+// gcpProject returns the given project, or the datasource's default project.
+func (d *cloudDatasource) gcpProject(ctx context.Context, project string) (string, error) {
+	if project != "" {
+		return project, nil
+	}
+	if p, ok := d.jsonData["defaultProject"].(string); ok && p != "" {
+		return p, nil
+	}
+	if err := d.resource(ctx, "gceDefaultProject", nil, &project); err != nil || project == "" {
+		return "", fmt.Errorf("the datasource has no default project, so a project is required")
+	}
+	return project, nil
+}
+
+func (d *cloudDatasource) gcpMetricDescriptors(ctx context.Context, project string) ([]gcpMetricDescriptor, error) {
+	project, err := d.gcpProject(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	var raw json.RawMessage
+	if err := d.resource(ctx, fmt.Sprintf("metricDescriptors/v3/projects/%s/metricDescriptors", url.PathEscape(project)), nil, &raw); err != nil {
+		return nil, err
+	}
+	// Grafana returns the descriptors as an array, while the API itself
+	// wraps them in an object.
+	var descriptors []gcpMetricDescriptor
+	if err := json.Unmarshal(raw, &descriptors); err != nil {
+		var resp struct {
+			MetricDescriptors []gcpMetricDescriptor `json:"metricDescriptors"`
+		}
+		if err := json.Unmarshal(raw, &resp); err != nil {
+			return nil, fmt.Errorf("unmarshalling metric descriptors: %w", err)
+		}
+		descriptors = resp.MetricDescriptors
+	}
+	return descriptors, nil
+}
+
+type ListCloudMetricNamespacesParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the CloudWatch\\, Azure Monitor or Google Cloud Monitoring datasource"`
+	Region        string `json:"region,omitempty" jsonschema:"description=CloudWatch only: the AWS region. Defaults to the datasource's default region"`
+	ResourceURI   string `json:"resourceUri,omitempty" jsonschema:"description=Azure Monitor only (required): the URI of the resource\\, e.g. /subscriptions/<id>/resourceGroups/<group>/providers/Microsoft.Compute/virtualMachines/<name>"`
+	Project       string `json:"project,omitempty" jsonschema:"description=Google Cloud Monitoring only: the project ID. Defaults to the datasource's default project"`
+}
+
+func listCloudMetricNamespaces(ctx context.Context, args ListCloudMetricNamespacesParams) ([]string, error) {
+	d, err := newCloudDatasource(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, err
+	}
+	switch d.typ {
+	case cloudWatchType:
+		return d.cloudWatchOptions(ctx, "namespaces", url.Values{"region": {args.Region}})
+	case azureMonitorType:
+		path, err := azureResourcePath(args.ResourceURI, "/providers/microsoft.insights/metricNamespaces")
+		if err != nil {
+			return nil, err
+		}
+		var resp struct {
+			Value []struct {
+				Properties struct {
+					MetricNamespaceName string `json:"metricNamespaceName"`
+				} `json:"properties"`
+			} `json:"value"`
+		}
+		if err := d.resource(ctx, path, url.Values{"api-version": {"2017-12-01-preview"}}, &resp); err != nil {
+			return nil, err
+		}
+		namespaces := make([]string, 0, len(resp.Value))
+		for _, v := range resp.Value {
+			namespaces = append(namespaces, v.Properties.MetricNamespaceName)
+		}
+		return namespaces, nil
+	}
+	descriptors, err := d.gcpMetricDescriptors(ctx, args.Project)
+	if err != nil {
+		return nil, err
+	}
+	var services []string
+	for _, m := range descriptors {
+		services = append(services, m.service())
+	}
+	slices.Sort(services)
+	return slices.Compact(services), nil
+}
+
+var ListCloudMetricNamespaces = mcpgrafana.MustTool(
+	"grafana_list_cloud_metric_namespaces",
+	"List the metric namespaces of a cloud provider datasource: the namespaces of CloudWatch (e.g. AWS/EC2), the metric namespaces of an Azure resource (e.g. Microsoft.Compute/virtualMachines) or the services of Google Cloud Monitoring (e.g. compute.googleapis.com). Use `grafana_list_cloud_metrics` to list the metrics of a namespace.",
+	listCloudMetricNamespaces,
+	mcp.WithTitleAnnotation("List cloud metric namespaces"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type ListCloudMetricsParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the CloudWatch\\, Azure Monitor or Google Cloud Monitoring datasource"`
+	Namespace     string `json:"namespace,omitempty" jsonschema:"description=The namespace to list metrics of. Required for CloudWatch; optional for Azure Monitor and Google Cloud Monitoring (where it is a service such as compute.googleapis.com)"`
+	Region        string `json:"region,omitempty" jsonschema:"description=CloudWatch only: the AWS region. Defaults to the datasource's default region"`
+	ResourceURI   string `json:"resourceUri,omitempty" jsonschema:"description=Azure Monitor only (required): the URI of the resource"`
+	Project       string `json:"project,omitempty" jsonschema:"description=Google Cloud Monitoring only: the project ID. Defaults to the datasource's default project"`
+}
+
+func listCloudMetrics(ctx context.Context, args ListCloudMetricsParams) ([]CloudMetric, error) {
+	d, err := newCloudDatasource(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, err
+	}
+	var metrics []CloudMetric
+	switch d.typ {
+	case cloudWatchType:
+		if args.Namespace == "" {
+			return nil, fmt.Errorf("namespace is required for CloudWatch datasources")
+		}
+		names, err := d.cloudWatchOptions(ctx, "metrics", url.Values{"region": {args.Region}, "namespace": {args.Namespace}})
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			metrics = append(metrics, CloudMetric{Name: name, Namespace: args.Namespace})
+		}
+	case azureMonitorType:
+		definitions, err := d.azureMetricDefinitions(ctx, args.ResourceURI, args.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range definitions {
+			metrics = append(metrics, m.metric())
+		}
+	default:
+		descriptors, err := d.gcpMetricDescriptors(ctx, args.Project)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range descriptors {
+			if args.Namespace == "" || m.service() == args.Namespace {
+				metrics = append(metrics, m.metric())
+			}
+		}
+	}
+	return metrics, nil
+}
+
+var ListCloudMetrics = mcpgrafana.MustTool(
+	"grafana_list_cloud_metrics",
+	"List the metrics of a cloud provider datasource (CloudWatch, Azure Monitor or Google Cloud Monitoring), optionally in a namespace. Azure Monitor and Google Cloud Monitoring metrics include their unit and dimensions (labels); use `grafana_list_cloud_dimensions` for the dimensions of CloudWatch metrics.",
+	listCloudMetrics,
+	mcp.WithTitleAnnotation("List cloud metrics"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type ListCloudDimensionsParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the CloudWatch\\, Azure Monitor or Google Cloud Monitoring datasource"`
+	Namespace     string `json:"namespace,omitempty" jsonschema:"description=The namespace of the metric. Required for CloudWatch"`
+	MetricName    string `json:"metricName" jsonschema:"required,description=The name of the metric\\, e.g. CPUUtilization or compute.googleapis.com/instance/cpu/utilization"`
+	Dimension     string `json:"dimension,omitempty" jsonschema:"description=CloudWatch only: list the values of this dimension instead of the dimension names"`
+	Region        string `json:"region,omitempty" jsonschema:"description=CloudWatch only: the AWS region. Defaults to the datasource's default region"`
+	ResourceURI   string `json:"resourceUri,omitempty" jsonschema:"description=Azure Monitor only (required): the URI of the resource"`
+	Project       string `json:"project,omitempty" jsonschema:"description=Google Cloud Monitoring only: the project ID. Defaults to the datasource's default project"`
+}
+
+func listCloudDimensions(ctx context.Context, args ListCloudDimensionsParams) ([]string, error) {
+	if args.MetricName == "" {
+		return nil, fmt.Errorf("metricName is required")
+	}
+	d, err := newCloudDatasource(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, err
+	}
+	if args.Dimension != "" && d.typ != cloudWatchType {
+		return nil, fmt.Errorf("listing dimension values is only supported for CloudWatch datasources")
+	}
+	switch d.typ {
+	case cloudWatchType:
+		if args.Namespace == "" {
+			return nil, fmt.Errorf("namespace is required for CloudWatch datasources")
+		}
+		params := url.Values{
+			"region":           {args.Region},
+			"namespace":        {args.Namespace},
+			"metricName":       {args.MetricName},
+			"dimensionFilters": {"{}"},
+		}
+		if args.Dimension != "" {
+			params.Set("dimensionKey", args.Dimension)
+			return d.cloudWatchOptions(ctx, "dimension-values", params)
+		}
+		return d.cloudWatchOptions(ctx, "dimension-keys", params)
+	case azureMonitorType:
+		definitions, err := d.azureMetricDefinitions(ctx, args.ResourceURI, args.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range definitions {
+			if strings.EqualFold(m.Name.Value, args.MetricName) {
+				return m.metric().Dimensions, nil
+			}
+		}
+	default:
+		descriptors, err := d.gcpMetricDescriptors(ctx, args.Project)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range descriptors {
+			if m.Type == args.MetricName {
+				return m.metric().Dimensions, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("metric %s not found", args.MetricName)
+}
+
+var ListCloudDimensions = mcpgrafana.MustTool(
+	"grafana_list_cloud_dimensions",
+	"List the dimensions (labels) of a metric of a cloud provider datasource (CloudWatch, Azure Monitor or Google Cloud Monitoring). For CloudWatch, set `dimension` to list the values of a dimension instead, e.g. the instance IDs of InstanceId.",
+	listCloudDimensions,
+	mcp.WithTitleAnnotation("List cloud metric dimensions"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type ListCloudResourcesParams struct {
+	DatasourceUID  string `json:"datasourceUid" jsonschema:"required,description=The UID of the CloudWatch\\, Azure Monitor or Google Cloud Monitoring datasource"`
+	SubscriptionID string `json:"subscriptionId,omitempty" jsonschema:"description=Azure Monitor only: list the resources of this subscription instead of the subscriptions"`
+	ResourceType   string `json:"resourceType,omitempty" jsonschema:"description=Azure Monitor only: the type of resources to list\\, e.g. Microsoft.Compute/virtualMachines"`
+}
+
+func listCloudResources(ctx context.Context, args ListCloudResourcesParams) ([]CloudResource, error) {
+	d, err := newCloudDatasource(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, err
+	}
+	var resources []CloudResource
+	switch d.typ {
+	case cloudWatchType:
+		regions, err := d.cloudWatchOptions(ctx, "regions", url.Values{})
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range regions {
+			resources = append(resources, CloudResource{ID: r, Type: "region"})
+		}
+	case azureMonitorType:
+		if args.SubscriptionID == "" {
+			var resp struct {
+				Value []struct {
+					SubscriptionID string `json:"subscriptionId"`
+					DisplayName    string `json:"displayName"`
+				} `json:"value"`
+			}
+			if err := d.resource(ctx, azureMonitorPath+"/subscriptions", url.Values{"api-version": {"2019-03-01"}}, &resp); err != nil {
+				return nil, err
+			}
+			for _, s := range resp.Value {
+				resources = append(resources, CloudResource{ID: s.SubscriptionID, Name: s.DisplayName, Type: "subscription"})
+			}
+			break
+		}
+		params := url.Values{"api-version": {"2021-04-01"}}
+		if args.ResourceType != "" {
+			params.Set("$filter", "resourceType eq "+sqlString(args.ResourceType))
+		}
+		var resp struct {
+			Value []CloudResource `json:"value"`
+		}
+		if err := d.resource(ctx, fmt.Sprintf("%s/subscriptions/%s/resources", azureMonitorPath, url.PathEscape(args.SubscriptionID)), params, &resp); err != nil {
+			return nil, err
+		}
+		resources = resp.Value
+	default:
+		var projects []struct {
+			Label string `json:"label"`
+			Value string `json:"value"`
+		}
+		if err := d.resource(ctx, "projects", nil, &projects); err != nil {
+			return nil, err
+		}
+		for _, p := range projects {
+			resources = append(resources, CloudResource{ID: p.Value, Name: p.Label, Type: "project"})
+		}
+	}
+	return resources, nil
+}
+
+var ListCloudResources = mcpgrafana.MustTool(
+	"grafana_list_cloud_resources",
+	"List the resources metrics can be queried for in a cloud provider datasource: the regions of CloudWatch, the projects of Google Cloud Monitoring, and the subscriptions of Azure Monitor or, given a subscription ID, its resources with their URI (the `id`), type and location.",
+	listCloudResources,
+	mcp.WithTitleAnnotation("List cloud resources"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
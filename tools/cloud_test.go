@@ -0,0 +1,120 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCloudTestContext(t *testing.T) context.Context {
+	return newFakeGrafanaContext(t, fakeRoutes{
+		"/api/datasources/uid/cw":                      jsonBody(`{"uid": "cw", "type": "cloudwatch"}`),
+		"/api/datasources/uid/azure":                   jsonBody(`{"uid": "azure", "type": "grafana-azure-monitor-datasource"}`),
+		"/api/datasources/uid/gcp":                     jsonBody(`{"uid": "gcp", "type": "stackdriver", "jsonData": {"defaultProject": "my-project"}}`),
+		"/api/datasources/uid/loki":                    jsonBody(`{"uid": "loki", "type": "loki"}`),
+		"/api/datasources/uid/cw/resources/namespaces": jsonBody(`[{"value": "AWS/EC2"}, {"text": "AWS/RDS", "value": "AWS/RDS"}]`),
+		"/api/datasources/uid/cw/resources/metrics": func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			assert.Equal(t, "default", q.Get("region"))
+			assert.Equal(t, "AWS/EC2", q.Get("namespace"))
+			_, _ = w.Write([]byte(`[{"value": {"name": "CPUUtilization", "namespace": "AWS/EC2"}}]`))
+		},
+		"/api/datasources/uid/cw/resources/dimension-values": func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			assert.Equal(t, "eu-west-1", q.Get("region"))
+			assert.Equal(t, "InstanceId", q.Get("dimensionKey"))
+			_, _ = w.Write([]byte(`[{"value": "i-1"}, {"value": "i-2"}]`))
+		},
+		"/api/datasources/uid/azure/resources/azuremonitor/subscriptions/s1/resourceGroups/g/providers/Microsoft.Compute/virtualMachines/vm/providers/microsoft.insights/metricdefinitions": func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			assert.Equal(t, "2018-01-01", q.Get("api-version"))
+			_, _ = w.Write([]byte(`{"value": [{
+				"name": {"value": "Percentage CPU", "localizedValue": "Percentage CPU"},
+				"namespace": "Microsoft.Compute/virtualMachines", "unit": "Percent",
+				"primaryAggregationType": "Average", "supportedAggregationTypes": ["Average", "Maximum"],
+				"dimensions": [{"value": "VMName"}]
+			}]}`))
+		},
+		"/api/datasources/uid/azure/resources/azuremonitor/subscriptions/s1/resources": func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			assert.Equal(t, "resourceType eq 'Microsoft.Compute/virtualMachines'", q.Get("$filter"))
+			_, _ = w.Write([]byte(`{"value": [{"id": "/subscriptions/s1/resourceGroups/g/providers/Microsoft.Compute/virtualMachines/vm", "name": "vm", "type": "Microsoft.Compute/virtualMachines", "location": "westeurope"}]}`))
+		},
+		"/api/datasources/uid/gcp/resources/metricDescriptors/v3/projects/my-project/metricDescriptors": jsonBody(`[
+				{"type": "compute.googleapis.com/instance/cpu/utilization", "unit": "10^2.%", "labels": [{"key": "instance_name"}]},
+				{"type": "pubsub.googleapis.com/topic/send_request_count", "service": "pubsub.googleapis.com"},
+				{"type": "compute.googleapis.com/instance/uptime"}
+			]`),
+	})
+}
+
+func TestCloudWatchDiscovery(t *testing.T) {
+	ctx := newCloudTestContext(t)
+
+	namespaces, err := listCloudMetricNamespaces(ctx, ListCloudMetricNamespacesParams{DatasourceUID: "cw"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"AWS/EC2", "AWS/RDS"}, namespaces)
+
+	metrics, err := listCloudMetrics(ctx, ListCloudMetricsParams{DatasourceUID: "cw", Namespace: "AWS/EC2"})
+	require.NoError(t, err)
+	assert.Equal(t, []CloudMetric{{Name: "CPUUtilization", Namespace: "AWS/EC2"}}, metrics)
+
+	values, err := listCloudDimensions(ctx, ListCloudDimensionsParams{DatasourceUID: "cw", Namespace: "AWS/EC2", MetricName: "CPUUtilization", Dimension: "InstanceId", Region: "eu-west-1"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"i-1", "i-2"}, values)
+
+	_, err = listCloudMetrics(ctx, ListCloudMetricsParams{DatasourceUID: "cw"})
+	assert.EqualError(t, err, "namespace is required for CloudWatch datasources")
+	_, err = listCloudMetrics(ctx, ListCloudMetricsParams{DatasourceUID: "loki"})
+	assert.ErrorContains(t, err, "not a CloudWatch, Azure Monitor or Google Cloud Monitoring datasource")
+}
+
+func TestAzureMonitorDiscovery(t *testing.T) {
+	ctx := newCloudTestContext(t)
+	uri := "/subscriptions/s1/resourceGroups/g/providers/Microsoft.Compute/virtualMachines/vm"
+
+	metrics, err := listCloudMetrics(ctx, ListCloudMetricsParams{DatasourceUID: "azure", ResourceURI: uri})
+	require.NoError(t, err)
+	assert.Equal(t, []CloudMetric{{
+		Name:         "Percentage CPU",
+		Namespace:    "Microsoft.Compute/virtualMachines",
+		Unit:         "Percent",
+		Aggregations: []string{"Average", "Maximum"},
+		Dimensions:   []string{"VMName"},
+	}}, metrics)
+
+	dimensions, err := listCloudDimensions(ctx, ListCloudDimensionsParams{DatasourceUID: "azure", ResourceURI: uri, MetricName: "percentage cpu"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"VMName"}, dimensions)
+
+	resources, err := listCloudResources(ctx, ListCloudResourcesParams{DatasourceUID: "azure", SubscriptionID: "s1", ResourceType: "Microsoft.Compute/virtualMachines"})
+	require.NoError(t, err)
+	assert.Equal(t, []CloudResource{{ID: uri, Name: "vm", Type: "Microsoft.Compute/virtualMachines", Location: "westeurope"}}, resources)
+
+	_, err = listCloudMetrics(ctx, ListCloudMetricsParams{DatasourceUID: "azure"})
+	assert.ErrorContains(t, err, "resourceUri is required")
+}
+
+func TestCloudMonitoringDiscovery(t *testing.T) {
+	ctx := newCloudTestContext(t)
+
+	namespaces, err := listCloudMetricNamespaces(ctx, ListCloudMetricNamespacesParams{DatasourceUID: "gcp"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"compute.googleapis.com", "pubsub.googleapis.com"}, namespaces)
+
+	metrics, err := listCloudMetrics(ctx, ListCloudMetricsParams{DatasourceUID: "gcp", Namespace: "compute.googleapis.com"})
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, CloudMetric{Name: "compute.googleapis.com/instance/cpu/utilization", Namespace: "compute.googleapis.com", Unit: "10^2.%", Dimensions: []string{"instance_name"}}, metrics[0])
+
+	_, err = listCloudDimensions(ctx, ListCloudDimensionsParams{DatasourceUID: "gcp", MetricName: "missing"})
+	assert.EqualError(t, err, "metric missing not found")
+	_, err = listCloudDimensions(ctx, ListCloudDimensionsParams{DatasourceUID: "gcp", MetricName: "compute.googleapis.com/instance/uptime", Dimension: "x"})
+	assert.ErrorContains(t, err, "only supported for CloudWatch")
+}
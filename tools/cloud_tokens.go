@@ -0,0 +1,252 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	// defaultScopedTokenTTL and maxScopedTokenTTL are the default and
+	// maximum lifetime of scoped tokens.
+	defaultScopedTokenTTL = 15 * time.Minute
+	maxScopedTokenTTL     = time.Hour
+
+	// scopedTokenPrefix prefixes the names of the access policies and tokens
+	// created for scoped tokens, which are followed by the Unix time at which
+	// the token expires and a unique suffix.
+	scopedTokenPrefix = "mcp-grafana-scoped-"
+)
+
+// grafanaCloudAPIURL is the URL of the Grafana Cloud API, which is a
+// variable for tests.
+var grafanaCloudAPIURL = "https://grafana.com"
+
+// scopedTokenScopes are the access policy scopes which scoped tokens may
+// have. They are read-only, so that a leaked scoped token can't be used to
+// change anything.
+var scopedTokenScopes = []string{
+	"metrics:read",
+	"logs:read",
+	"traces:read",
+	"profiles:read",
+	"alerts:read",
+	"rules:read",
+}
+
+type MintScopedTokenParams struct {
+	Scopes []string `json:"scopes" jsonschema:"required,description=The access policy scopes of the token\\, among metrics:read\\, logs:read\\, traces:read\\, profiles:read\\, alerts:read and rules:read"`
+	TTL    string   `json:"ttl,omitempty" jsonschema:"description=Optionally\\, the lifetime of the token as a duration\\, e.g. '30m' (default 15m\\, max 1h)"`
+}
+
+type MintScopedTokenResult struct {
+	Token     string    `json:"token"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	// AccessPolicyID is the ID of the access policy created for the token,
+	// which is deleted the next time a token is minted after it has expired.
+	AccessPolicyID string `json:"accessPolicyId"`
+}
+
+// grafanaCloudStackSlug returns the slug of the Grafana Cloud stack at the
+// given URL, e.g. "mystack" for https://mystack.grafana.net.
+func grafanaCloudStackSlug(grafanaURL string) (string, bool) {
+	u, err := url.Parse(grafanaURL)
+	if err != nil {
+		return "", false
+	}
+	slug, ok := strings.CutSuffix(u.Hostname(), ".grafana.net")
+	if !ok || slug == "" || strings.Contains(slug, ".") {
+		return "", false
+	}
+	return slug, true
+}
+
+// cloudAPIClient makes requests to the Grafana Cloud API with an access
+// policy token.
+type cloudAPIClient struct {
+	httpClient *http.Client
+	token      string
+}
+
+func (c *cloudAPIClient) do(ctx context.Context, method, path string, params url.Values, body, v any) error {
+	u := grafanaCloudAPIURL + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshalling request body: %w", err)
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("executing request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close() //nolint:errcheck
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+		return fmt.Errorf("Grafana Cloud API returned status code %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if v == nil {
+		return nil
+	}
+	return decodeJSONResponse(resp.Body, v)
+}
+
+// scopedTokenPolicyExpired returns true if the access policy with the given
+// name was created for a scoped token which has expired.
+func scopedTokenPolicyExpired(name string, now time.Time) bool {
+	rest, ok := strings.CutPrefix(name, scopedTokenPrefix)
+	if !ok {
+		return false
+	}
+	expiry, _, ok := strings.Cut(rest, "-")
+	if !ok {
+		return false
+	}
+	n, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return false
+	}
+	return now.After(time.Unix(n, 0))
+}
+
+// deleteExpiredScopedTokenPolicies deletes the access policies of scoped
+// tokens which have expired, so that minting tokens doesn't pile up access
+// policies until the quota of the organization is reached.
+func (c *cloudAPIClient) deleteExpiredScopedTokenPolicies(ctx context.Context, region url.Values) error {
+	var policies struct {
+		Items []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"items"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/accesspolicies", region, nil, &policies); err != nil {
+		return fmt.Errorf("list access policies: %w", err)
+	}
+	now := time.Now()
+	var errs []error
+	for _, policy := range policies.Items {
+		if !scopedTokenPolicyExpired(policy.Name, now) {
+			continue
+		}
+		if err := c.do(ctx, http.MethodDelete, "/api/v1/accesspolicies/"+url.PathEscape(policy.ID), region, nil, nil); err != nil {
+			errs = append(errs, fmt.Errorf("delete access policy %s: %w", policy.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func mintScopedToken(ctx context.Context, args MintScopedTokenParams) (*MintScopedTokenResult, error) {
+	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+	slug, ok := grafanaCloudStackSlug(cfg.URL)
+	if !ok || cfg.AccessToken == "" {
+		return nil, errors.New("scoped tokens can only be minted for Grafana Cloud stacks with on-behalf-of authentication using an access policy token")
+	}
+	if len(args.Scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	for _, scope := range args.Scopes {
+		if !slices.Contains(scopedTokenScopes, scope) {
+			return nil, fmt.Errorf("scope %q is not allowed, expected one of %s", scope, strings.Join(scopedTokenScopes, ", "))
+		}
+	}
+	ttl := defaultScopedTokenTTL
+	if args.TTL != "" {
+		d, err := time.ParseDuration(args.TTL)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid ttl %q, expected a positive duration such as '30m'", args.TTL)
+		}
+		if d > maxScopedTokenTTL {
+			return nil, fmt.Errorf("ttl %s is longer than the maximum of %s", d, maxScopedTokenTTL)
+		}
+		ttl = d
+	}
+
+	c := &cloudAPIClient{
+		httpClient: &http.Client{Transport: cfg.Retry.RoundTripper(mcpgrafana.WithCompression(http.DefaultTransport))},
+		token:      cfg.AccessToken,
+	}
+	var stack struct {
+		ID         int64  `json:"id"`
+		RegionSlug string `json:"regionSlug"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/instances/"+url.PathEscape(slug), nil, nil, &stack); err != nil {
+		return nil, fmt.Errorf("get Grafana Cloud stack %s: %w", slug, err)
+	}
+	region := url.Values{"region": {stack.RegionSlug}}
+
+	if err := c.deleteExpiredScopedTokenPolicies(ctx, region); err != nil {
+		mcpgrafana.SetResultNote(ctx, "scopedTokenCleanup", fmt.Sprintf("The access policies of expired scoped tokens could not be cleaned up: %s", err))
+	}
+
+	expiresAt := time.Now().Add(ttl).UTC().Truncate(time.Second)
+	name := fmt.Sprintf("%s%d-%d", scopedTokenPrefix, expiresAt.Unix(), time.Now().UnixNano())
+	var policy struct {
+		ID string `json:"id"`
+	}
+	err := c.do(ctx, http.MethodPost, "/api/v1/accesspolicies", region, map[string]any{
+		"name":        name,
+		"displayName": "Scoped token minted by mcp-grafana",
+		"scopes":      args.Scopes,
+		"realms":      []map[string]any{{"type": "stack", "identifier": fmt.Sprint(stack.ID)}},
+	}, &policy)
+	if err != nil {
+		return nil, fmt.Errorf("create access policy: %w", err)
+	}
+
+	var token struct {
+		Token string `json:"token"`
+	}
+	err = c.do(ctx, http.MethodPost, "/api/v1/tokens", region, map[string]any{
+		"accessPolicyId": policy.ID,
+		"name":           name,
+		"expiresAt":      expiresAt.Format(time.RFC3339),
+	}, &token)
+	if err != nil {
+		// Don't leave an access policy without a token behind.
+		_ = c.do(context.WithoutCancel(ctx), http.MethodDelete, "/api/v1/accesspolicies/"+url.PathEscape(policy.ID), region, nil, nil)
+		return nil, fmt.Errorf("create token: %w", err)
+	}
+	return &MintScopedTokenResult{
+		Token:          token.Token,
+		Scopes:         args.Scopes,
+		ExpiresAt:      expiresAt,
+		AccessPolicyID: policy.ID,
+	}, nil
+}
+
+var MintScopedToken = mcpgrafana.MustTool(
+	"grafana_mint_scoped_token",
+	"Mint a short-lived Grafana Cloud access policy token with narrow, read-only scopes, such as metrics:read or logs:read, limited to the current stack. Use it to hand a session or a sub-agent a credential which expires on its own, rather than the long-lived credentials of the server. Only available for Grafana Cloud stacks with on-behalf-of authentication, and the configured access policy token must be allowed to manage access policies. Each token gets its own access policy, named `mcp-grafana-scoped-...`, and the access policies of expired tokens are deleted when minting a new one. The token is only returned once.",
+	mintScopedToken,
+	mcp.WithTitleAnnotation("Mint scoped token"),
+	mcp.WithDestructiveHintAnnotation(false),
+)
@@ -0,0 +1,110 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrafanaCloudStackSlug(t *testing.T) {
+	slug, ok := grafanaCloudStackSlug("https://mystack.grafana.net/")
+	assert.True(t, ok)
+	assert.Equal(t, "mystack", slug)
+	for _, u := range []string{"http://localhost:3000", "https://grafana.net", "https://a.b.grafana.net", "https://mystack.grafana.net.evil.com"} {
+		_, ok := grafanaCloudStackSlug(u)
+		assert.False(t, ok, u)
+	}
+}
+
+func TestMintScopedToken(t *testing.T) {
+	var policy, token map[string]any
+	var deleted []string
+	failToken := false
+	// cloudAPI checks that requests to the Grafana Cloud API are made with the
+	// access policy token of the caller.
+	cloudAPI := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "Bearer access-policy-token", r.Header.Get("Authorization"))
+			h(w, r)
+		}
+	}
+	routes := fakeRoutes{
+		"GET /api/instances/mystack": cloudAPI(jsonBody(`{"id": 42, "slug": "mystack", "regionSlug": "prod-eu-west-0"}`)),
+		"GET /api/v1/accesspolicies": cloudAPI(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{"items": []any{
+				map[string]any{"id": "expired", "name": fmt.Sprintf("mcp-grafana-scoped-%d-1", time.Now().Add(-time.Minute).Unix())},
+				map[string]any{"id": "valid", "name": fmt.Sprintf("mcp-grafana-scoped-%d-1", time.Now().Add(time.Minute).Unix())},
+				map[string]any{"id": "other", "name": "ci"},
+			}})
+		}),
+		"POST /api/v1/accesspolicies": cloudAPI(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "prod-eu-west-0", r.URL.Query().Get("region"))
+			if !decodeJSONBody(t, w, r, &policy) {
+				return
+			}
+			_, _ = w.Write([]byte(`{"id": "policy-1"}`))
+		}),
+		"POST /api/v1/tokens": cloudAPI(func(w http.ResponseWriter, r *http.Request) {
+			if failToken {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"message": "forbidden"}`))
+				return
+			}
+			if !decodeJSONBody(t, w, r, &token) {
+				return
+			}
+			_, _ = w.Write([]byte(`{"id": "token-1", "token": "glc_secret"}`))
+		}),
+	}
+	for _, id := range []string{"expired", "valid", "other", "policy-1"} {
+		routes["DELETE /api/v1/accesspolicies/"+id] = cloudAPI(func(w http.ResponseWriter, r *http.Request) {
+			deleted = append(deleted, id)
+		})
+	}
+	server := newFakeGrafana(t, routes)
+	grafanaCloudAPIURL = server.URL
+	t.Cleanup(func() { grafanaCloudAPIURL = "https://grafana.com" })
+
+	ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{
+		URL:         "https://mystack.grafana.net",
+		AccessToken: "access-policy-token",
+		IDToken:     "id-token",
+	})
+	result, err := mintScopedToken(ctx, MintScopedTokenParams{Scopes: []string{"metrics:read", "logs:read"}, TTL: "30m"})
+	require.NoError(t, err)
+	assert.Equal(t, "glc_secret", result.Token)
+	assert.Equal(t, "policy-1", result.AccessPolicyID)
+	assert.WithinDuration(t, time.Now().Add(30*time.Minute), result.ExpiresAt, 5*time.Second)
+	assert.Equal(t, []any{"metrics:read", "logs:read"}, policy["scopes"])
+	assert.Equal(t, []any{map[string]any{"type": "stack", "identifier": "42"}}, policy["realms"])
+	assert.Equal(t, "policy-1", token["accessPolicyId"])
+	assert.Equal(t, result.ExpiresAt.Format(time.RFC3339), token["expiresAt"])
+	assert.Equal(t, fmt.Sprintf("mcp-grafana-scoped-%d-", result.ExpiresAt.Unix()), policy["name"].(string)[:len("mcp-grafana-scoped-")+11])
+	assert.Equal(t, []string{"expired"}, deleted, "the access policies of expired tokens are deleted")
+	deleted = nil
+
+	// The access policy is deleted if the token can't be created.
+	failToken = true
+	_, err = mintScopedToken(ctx, MintScopedTokenParams{Scopes: []string{"logs:read"}})
+	assert.ErrorContains(t, err, "create token: Grafana Cloud API returned status code 403")
+	assert.Equal(t, []string{"expired", "policy-1"}, deleted)
+
+	_, err = mintScopedToken(ctx, MintScopedTokenParams{Scopes: []string{"metrics:write"}})
+	assert.ErrorContains(t, err, `scope "metrics:write" is not allowed`)
+	_, err = mintScopedToken(ctx, MintScopedTokenParams{Scopes: []string{"logs:read"}, TTL: "2h"})
+	assert.ErrorContains(t, err, "longer than the maximum of 1h0m0s")
+
+	ctx = mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{URL: "http://localhost:3000", APIKey: "key"})
+	_, err = mintScopedToken(ctx, MintScopedTokenParams{Scopes: []string{"logs:read"}})
+	assert.ErrorContains(t, err, "only be minted for Grafana Cloud stacks")
+}
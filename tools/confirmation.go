@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// Confirmation is returned by destructive tools called without a
+// confirmation token, instead of acting: what the action would do, and the
+// token to call the tool again with to do it.
+type Confirmation struct {
+	Token     string   `json:"token"`
+	ExpiresAt string   `json:"expiresAt"`
+	Impact    []string `json:"impact"`
+	Message   string   `json:"message"`
+}
+
+// confirmedAction is an action of destructive tools which is only done when
+// they are called again with a confirmation token.
+type confirmedAction struct {
+	// name identifies the action in tokens, e.g. "delete_dashboard".
+	name string
+	// verb, past and noun are the forms of the action, e.g. "delete",
+	// "deleted" and "deletion".
+	verb, past, noun string
+	// resource is what the action applies to, e.g. "dashboard".
+	resource string
+}
+
+var (
+	deleteDashboardAction       = confirmedAction{"delete_dashboard", "delete", "deleted", "deletion", "dashboard"}
+	deleteAlertRuleAction       = confirmedAction{"delete_alert_rule", "delete", "deleted", "deletion", "rule"}
+	deleteMuteTimingAction      = confirmedAction{"delete_mute_timing", "delete", "deleted", "deletion", "mute timing"}
+	deleteContactPointAction    = confirmedAction{"delete_contact_point", "delete", "deleted", "deletion", "integration"}
+	revokePublicDashboardAction = confirmedAction{"revoke_public_dashboard", "revoke", "revoked", "revocation", "public dashboard"}
+)
+
+// description explains the two calls doing the action to clients, for the
+// descriptions of tools.
+func (a confirmedAction) description() string {
+	return fmt.Sprintf("%s%s takes two calls: without `confirmationToken`, nothing is %s and the %s, the impact of its %s and a confirmation token are returned. "+
+		"Show the impact to the user and only call again with the token after they agreed. Tokens expire after %d minutes.",
+		strings.ToUpper(a.noun[:1]), a.noun[1:], a.past, a.resource, a.noun, int(mcpgrafana.ConfirmationTTL.Minutes()))
+}
+
+// newConfirmation issues a token confirming an action on a resource in the
+// state described by subject, e.g. its UID and version.
+func newConfirmation(ctx context.Context, action confirmedAction, impact []string, subject ...string) *Confirmation {
+	token, expires := mcpgrafana.IssueConfirmationToken(ctx, action.name, subject...)
+	return &Confirmation{
+		Token:     token,
+		ExpiresAt: expires.UTC().Format(time.RFC3339),
+		Impact:    impact,
+		Message:   fmt.Sprintf("The %s was not %s. Check with the user that this is the %s to %s, then call again with the confirmation token.", action.resource, action.past, action.resource, action.verb),
+	}
+}
+
+// checkConfirmation checks the confirmation token of a call of a
+// destructive tool.
+func checkConfirmation(ctx context.Context, token string, action confirmedAction, subject ...string) error {
+	if err := mcpgrafana.VerifyConfirmationToken(ctx, token, action.name, subject...); err != nil {
+		return fmt.Errorf("%w; call again without confirmationToken to review the impact and get a new token", err)
+	}
+	return nil
+}
@@ -1,12 +1,15 @@
 package tools
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"strconv"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 
+	"github.com/grafana/grafana-openapi-client-go/client/search"
 	"github.com/grafana/grafana-openapi-client-go/models"
 	mcpgrafana "github.com/grafana/mcp-grafana"
 )
@@ -24,6 +27,25 @@ func getDashboardByUID(ctx context.Context, args GetDashboardByUIDParams) (*mode
 	return dashboard.Payload, nil
 }
 
+type GetDashboardVersionParams struct {
+	UID     string `json:"uid" jsonschema:"required,description=The UID of the dashboard"`
+	Version int64  `json:"version" jsonschema:"required,description=The version of the dashboard to retrieve"`
+}
+
+// getDashboardVersion fetches a past version of a dashboard. Versions never
+// change once saved, so they are kept in the disk cache if it is enabled.
+func getDashboardVersion(ctx context.Context, args GetDashboardVersionParams) (*models.DashboardVersionMeta, error) {
+	id := fmt.Sprintf("%s/%d", args.UID, args.Version)
+	return mcpgrafana.CachedArtifact(ctx, "dashboard-version", id, func() (*models.DashboardVersionMeta, bool, error) {
+		c := mcpgrafana.GrafanaClientFromContext(ctx)
+		version, err := c.DashboardVersions.GetDashboardVersionByUID(args.UID, args.Version)
+		if err != nil {
+			return nil, false, fmt.Errorf("get dashboard %s version %d: %w", args.UID, args.Version, err)
+		}
+		return version.Payload, true, nil
+	})
+}
+
 type UpdateDashboardParams struct {
 	Dashboard map[string]interface{} `json:"dashboard" jsonschema:"required,description=The full dashboard JSON"`
 	FolderUID string                 `json:"folderUid" jsonschema:"optional,description=The UID of the dashboard's folder"`
@@ -51,6 +73,59 @@ func updateDashboard(ctx context.Context, args UpdateDashboardParams) (*models.P
 	return dashboard.Payload, nil
 }
 
+type DeleteDashboardByUIDParams struct {
+	UID               string `json:"uid" jsonschema:"required,description=The UID of the dashboard to delete"`
+	ConfirmationToken string `json:"confirmationToken,omitempty" jsonschema:"description=The token returned by a previous call for this dashboard. Without it nothing is deleted and the impact of the deletion is returned with a token"`
+}
+
+// DeleteDashboardResult describes a dashboard which was deleted, or would be
+// deleted if the deletion was confirmed.
+type DeleteDashboardResult struct {
+	Deleted      bool             `json:"deleted"`
+	Dashboard    dashboardSummary `json:"dashboard"`
+	Confirmation *Confirmation    `json:"confirmation,omitempty"`
+}
+
+// deleteDashboardImpact describes what deleting a dashboard does.
+func deleteDashboardImpact(ctx context.Context, summary dashboardSummary) []string {
+	folder := cmp.Or(summary.FolderTitle, "General")
+	impact := []string{
+		fmt.Sprintf("Deletes the dashboard %q (version %d) in the folder %q, with its %d panels and its version history.", summary.Title, summary.Version, folder, len(summary.Panels)),
+	}
+	if p, err := getPublicDashboard(ctx, summary.UID); err == nil && p != nil {
+		impact = append(impact, "Revokes its public dashboard, whose URL stops working.")
+	}
+	return append(impact, "Alert rules and library panels used by the dashboard are kept.")
+}
+
+// deleteDashboardByUID deletes a dashboard, but only if the call has a
+// confirmation token. Calls without a token return the impact of the
+// deletion and a token instead, so that the user can check that it is the
+// right dashboard before it is gone. The token is only valid for the
+// version of the dashboard it was issued for.
+func deleteDashboardByUID(ctx context.Context, args DeleteDashboardByUIDParams) (*DeleteDashboardResult, error) {
+	dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: args.UID})
+	if err != nil {
+		return nil, classifyAPIError(err)
+	}
+	summary := summarizeDashboard(args.UID, dashboard)
+	version := strconv.FormatInt(summary.Version, 10)
+	if args.ConfirmationToken == "" {
+		return &DeleteDashboardResult{
+			Dashboard:    summary,
+			Confirmation: newConfirmation(ctx, deleteDashboardAction, deleteDashboardImpact(ctx, summary), args.UID, version),
+		}, nil
+	}
+	if err := checkConfirmation(ctx, args.ConfirmationToken, deleteDashboardAction, args.UID, version); err != nil {
+		return nil, err
+	}
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	if _, err := c.Dashboards.DeleteDashboardByUID(args.UID); err != nil {
+		return nil, fmt.Errorf("delete dashboard %s: %w", args.UID, classifyAPIError(err))
+	}
+	return &DeleteDashboardResult{Deleted: true, Dashboard: summary}, nil
+}
+
 var GetDashboardByUID = mcpgrafana.MustTool(
 	"grafana_get_dashboard_by_uid",
 	"Retrieves the complete dashboard, including panels, variables, and settings, for a specific dashboard identified by its UID.",
@@ -60,14 +135,32 @@ var GetDashboardByUID = mcpgrafana.MustTool(
 	mcp.WithReadOnlyHintAnnotation(true),
 )
 
+var GetDashboardVersion = mcpgrafana.MustTool(
+	"grafana_get_dashboard_version",
+	"Retrieves a past version of a dashboard identified by its UID and version number, including the full dashboard JSON of that version in the data field and the version's commit message, author and creation time. The current version number is in the version field of the dashboard.",
+	getDashboardVersion,
+	mcp.WithTitleAnnotation("Get dashboard version"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
 var UpdateDashboard = mcpgrafana.MustTool(
 	"grafana_update_dashboard",
-	"Create or update a dashboard",
+	"Create or update a dashboard. Check new or generated dashboards with `grafana_validate_dashboard_json` first, as the API reports invalid dashboards with opaque errors.",
 	updateDashboard,
 	mcp.WithTitleAnnotation("Create or update dashboard"),
 	mcp.WithDestructiveHintAnnotation(true),
 )
 
+var DeleteDashboardByUID = mcpgrafana.MustTool(
+	"grafana_delete_dashboard_by_uid",
+	"Delete a dashboard identified by its UID, e.g. to clean up generated or test dashboards. "+deleteDashboardAction.description()+" Tokens are no longer valid if the dashboard changed. Alert rules and library panels used by the dashboard are not deleted.",
+	deleteDashboardByUID,
+	mcp.WithTitleAnnotation("Delete dashboard"),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithIdempotentHintAnnotation(false),
+)
+
 type DashboardPanelQueriesParams struct {
 	UID string `json:"uid" jsonschema:"required,description=The UID of the dashboard"`
 }
@@ -95,6 +188,12 @@ func GetDashboardPanelQueriesTool(ctx context.Context, args DashboardPanelQuerie
 	if !ok {
 		return result, fmt.Errorf("dashboard is not a JSON object")
 	}
+	return dashboardPanelQueries(db)
+}
+
+// dashboardPanelQueries returns the queries of the panels of a dashboard.
+func dashboardPanelQueries(db map[string]any) ([]panelQuery, error) {
+	result := make([]panelQuery, 0)
 	panels, ok := db["panels"].([]any)
 	if !ok {
 		return result, fmt.Errorf("panels is not a JSON array")
@@ -151,8 +250,209 @@ var GetDashboardPanelQueries = mcpgrafana.MustTool(
 	mcp.WithReadOnlyHintAnnotation(true),
 )
 
+const (
+	// DefaultDashboardSummariesLimit is the default number of dashboards
+	// summarized when using a search filter.
+	DefaultDashboardSummariesLimit = 20
+	// MaxDashboardSummariesLimit is the maximum number of dashboards that can
+	// be summarized in a single call.
+	MaxDashboardSummariesLimit = 100
+	// dashboardSummaryWorkers is the number of dashboards fetched
+	// concurrently when building summaries.
+	dashboardSummaryWorkers = 8
+)
+
+type GetDashboardSummariesParams struct {
+	UIDs      []string `json:"uids,omitempty" jsonschema:"description=The UIDs of the dashboards to summarize. If empty\\, dashboards are found using the search filter instead"`
+	Query     string   `json:"query,omitempty" jsonschema:"description=Optionally\\, a query string used to search for dashboards to summarize"`
+	FolderUID string   `json:"folderUid,omitempty" jsonschema:"description=Optionally\\, only summarize dashboards in the folder with this UID"`
+	Tags      []string `json:"tags,omitempty" jsonschema:"description=Optionally\\, only summarize dashboards with all of these tags"`
+	Limit     int      `json:"limit,omitempty" jsonschema:"description=The maximum number of dashboards to summarize (default 20\\, max 100)"`
+}
+
+type panelSummary struct {
+	ID    int    `json:"id,omitempty"`
+	Title string `json:"title,omitempty"`
+	Type  string `json:"type,omitempty"`
+}
+
+type dashboardSummary struct {
+	UID         string         `json:"uid"`
+	Title       string         `json:"title,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Tags        []string       `json:"tags,omitempty"`
+	FolderUID   string         `json:"folderUid,omitempty"`
+	FolderTitle string         `json:"folderTitle,omitempty"`
+	URL         string         `json:"url,omitempty"`
+	Version     int64          `json:"version,omitempty"`
+	Panels      []panelSummary `json:"panels,omitempty"`
+	Variables   []string       `json:"variables,omitempty"`
+	Error       string         `json:"error,omitempty"`
+}
+
+// summarizePanels flattens the panels of a dashboard, including those nested
+// inside collapsed rows, into a list of panel summaries.
+func summarizePanels(panels []any) []panelSummary {
+	var result []panelSummary
+	for _, p := range panels {
+		panel, ok := p.(map[string]any)
+		if !ok {
+			continue
+		}
+		summary := panelSummary{}
+		if id, ok := panel["id"].(float64); ok {
+			summary.ID = int(id)
+		}
+		summary.Title, _ = panel["title"].(string)
+		summary.Type, _ = panel["type"].(string)
+		result = append(result, summary)
+		if nested, ok := panel["panels"].([]any); ok {
+			result = append(result, summarizePanels(nested)...)
+		}
+	}
+	return result
+}
+
+// summarizeDashboard extracts the most relevant details of a dashboard
+// without the full panel definitions.
+func summarizeDashboard(uid string, dashboard *models.DashboardFullWithMeta) dashboardSummary {
+	summary := dashboardSummary{UID: uid}
+	if dashboard.Meta != nil {
+		summary.FolderUID = dashboard.Meta.FolderUID
+		summary.FolderTitle = dashboard.Meta.FolderTitle
+		summary.URL = dashboard.Meta.URL
+		summary.Version = dashboard.Meta.Version
+	}
+	db, ok := dashboard.Dashboard.(map[string]any)
+	if !ok {
+		return summary
+	}
+	summary.Title, _ = db["title"].(string)
+	summary.Description, _ = db["description"].(string)
+	if tags, ok := db["tags"].([]any); ok {
+		for _, t := range tags {
+			if tag, ok := t.(string); ok {
+				summary.Tags = append(summary.Tags, tag)
+			}
+		}
+	}
+	if panels, ok := db["panels"].([]any); ok {
+		summary.Panels = summarizePanels(panels)
+	}
+	if templating, ok := db["templating"].(map[string]any); ok {
+		if list, ok := templating["list"].([]any); ok {
+			for _, v := range list {
+				if variable, ok := v.(map[string]any); ok {
+					if name, ok := variable["name"].(string); ok {
+						summary.Variables = append(summary.Variables, name)
+					}
+				}
+			}
+		}
+	}
+	return summary
+}
+
+// findDashboardUIDs returns the UIDs of the dashboards matching the search
+// filter in args.
+func findDashboardUIDs(ctx context.Context, args GetDashboardSummariesParams, limit int) ([]string, error) {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	params := search.NewSearchParamsWithContext(ctx).WithType(&dashboardTypeStr)
+	if args.Query != "" {
+		params.SetQuery(&args.Query)
+	}
+	if args.FolderUID != "" {
+		params.SetFolderUIDs([]string{args.FolderUID})
+	}
+	if len(args.Tags) > 0 {
+		params.SetTag(args.Tags)
+	}
+	l := int64(limit)
+	params.SetLimit(&l)
+	resp, err := c.Search.Search(params)
+	if err != nil {
+		return nil, fmt.Errorf("search dashboards: %w", err)
+	}
+	uids := make([]string, 0, len(resp.Payload))
+	for _, hit := range resp.Payload {
+		uids = append(uids, hit.UID)
+	}
+	return uids, nil
+}
+
+func getDashboardSummaries(ctx context.Context, args GetDashboardSummariesParams) ([]dashboardSummary, error) {
+	limit := args.Limit
+	if limit <= 0 {
+		limit = DefaultDashboardSummariesLimit
+	}
+	if limit > MaxDashboardSummariesLimit {
+		limit = MaxDashboardSummariesLimit
+	}
+
+	uids := args.UIDs
+	if len(uids) == 0 {
+		if args.Query == "" && args.FolderUID == "" && len(args.Tags) == 0 {
+			return nil, fmt.Errorf("either uids or a search filter (query, folderUid or tags) must be provided")
+		}
+		var err error
+		uids, err = findDashboardUIDs(ctx, args, limit)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(uids) > limit {
+		uids = uids[:limit]
+	}
+
+	// Dashboards which can't be fetched are returned with their error.
+	results := fanOut(ctx, "dashboards", uids, dashboardSummaryWorkers, func(ctx context.Context, uid string) (dashboardSummary, error) {
+		dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: uid})
+		if err != nil {
+			return dashboardSummary{UID: uid, Error: err.Error()}, err
+		}
+		return summarizeDashboard(uid, dashboard), nil
+	})
+	summaries := fanOutValues(results)
+	return summaries, nil
+}
+
+var GetDashboardSummaries = mcpgrafana.MustTool(
+	"grafana_get_dashboard_summaries",
+	"Get summaries of multiple dashboards in one call, either by a list of UIDs or by a search filter (query, folder UID and/or tags). Each summary includes the dashboard's title, description, tags, folder, URL, version, template variable names and the ID, title and type of each panel, without the full panel definitions. Dashboards are fetched concurrently; dashboards that cannot be fetched are returned with an error field instead of failing the whole call. Useful for reviewing all dashboards in a folder.",
+	getDashboardSummaries,
+	mcp.WithTitleAnnotation("Get dashboard summaries"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// AddDashboardTools registers the dashboard tools. Tools which modify
+// dashboards are only registered if write tools are enabled, see
+// mcpgrafana.WriteToolsEnabled.
 func AddDashboardTools(mcp *server.MCPServer) {
 	GetDashboardByUID.Register(mcp)
-	UpdateDashboard.Register(mcp)
+	GetDashboardVersion.Register(mcp)
+	DiffDashboards.Register(mcp)
+	if mcpgrafana.WriteToolsEnabled() {
+		UpdateDashboard.Register(mcp)
+		CreateDashboardFromSpec.Register(mcp)
+		DeleteDashboardByUID.Register(mcp)
+		EnablePublicDashboard.Register(mcp)
+		ConfigurePublicDashboard.Register(mcp)
+		RevokePublicDashboard.Register(mcp)
+		CreatePlaylist.Register(mcp)
+		UpdatePlaylist.Register(mcp)
+	}
+	ListPublicDashboards.Register(mcp)
+	ListPlaylists.Register(mcp)
+	ValidateDashboardJSON.Register(mcp)
 	GetDashboardPanelQueries.Register(mcp)
+	ResolveDashboardVariables.Register(mcp)
+	QueryDashboardPanel.Register(mcp)
+	GetDashboardSummary.Register(mcp)
+	GetDashboardSummaries.Register(mcp)
+	FindMetricUsages.Register(mcp)
+	GenerateDeeplink.Register(mcp)
+	RenderPanel.Register(mcp)
+	RenderPanelTimeline.Register(mcp)
+	ListLiveChannels.Register(mcp)
 }
@@ -0,0 +1,362 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// dashboardQueryFields are the fields holding the query text of targets of
+// common datasources, in order of preference.
+var dashboardQueryFields = []string{"expr", "query", "rawSql", "expression", "target"}
+
+// dashboardMetadataFields are top-level fields of dashboards which change
+// whenever they are saved or copied, and are not compared.
+var dashboardMetadataFields = []string{"id", "uid", "version", "iteration", "panels", "templating"}
+
+type DiffDashboardsParams struct {
+	UID           string `json:"uid" jsonschema:"required,description=The UID of the dashboard"`
+	Version       int64  `json:"version,omitempty" jsonschema:"description=Optionally\\, the version of the dashboard to compare. Defaults to the current version"`
+	OtherUID      string `json:"otherUid,omitempty" jsonschema:"description=Optionally\\, the UID of the dashboard to compare with. Defaults to uid"`
+	OtherVersion  int64  `json:"otherVersion,omitempty" jsonschema:"description=Optionally\\, the version of the other dashboard. Defaults to its current version"`
+	OtherOnTarget bool   `json:"otherOnTarget,omitempty" jsonschema:"description=Whether the other dashboard is on the second Grafana instance configured with --migration-target-url\\, e.g. to compare staging with production"`
+}
+
+func (p DiffDashboardsParams) validate() error {
+	if p.UID == "" {
+		return fmt.Errorf("uid is required")
+	}
+	if (p.OtherUID == "" || p.OtherUID == p.UID) && p.Version == p.OtherVersion && !p.OtherOnTarget {
+		return fmt.Errorf("nothing to compare: set a different otherUid, version or otherVersion, or otherOnTarget")
+	}
+	return nil
+}
+
+// DashboardRef identifies a version of a dashboard on an instance.
+type DashboardRef struct {
+	UID      string `json:"uid"`
+	Title    string `json:"title"`
+	Version  int64  `json:"version"`
+	Instance string `json:"instance"`
+}
+
+// DashboardFieldChange is a field whose value differs between the
+// dashboards. Old is missing for added fields and New for removed ones.
+type DashboardFieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old,omitempty"`
+	New   any    `json:"new,omitempty"`
+}
+
+type DashboardVariableChange struct {
+	Name    string                 `json:"name"`
+	Changes []DashboardFieldChange `json:"changes"`
+}
+
+type DashboardPanelRef struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	Type  string `json:"type"`
+}
+
+// DashboardQueryChange is a target of a panel which was added, removed or
+// changed. Old and New are the query texts if they differ.
+type DashboardQueryChange struct {
+	RefID   string                 `json:"refId"`
+	Status  string                 `json:"status"`
+	Old     string                 `json:"old,omitempty"`
+	New     string                 `json:"new,omitempty"`
+	Changes []DashboardFieldChange `json:"changes,omitempty"`
+}
+
+// DashboardPanelChange is a panel present in both dashboards, matched by ID
+// or else by title, with its changes.
+type DashboardPanelChange struct {
+	DashboardPanelRef
+	// Moved is set if the position or size of the panel changed.
+	Moved   bool                   `json:"moved,omitempty"`
+	Changes []DashboardFieldChange `json:"changes,omitempty"`
+	Queries []DashboardQueryChange `json:"queries,omitempty"`
+}
+
+// DashboardDiff is the structural difference between two dashboards, from
+// Base to Other.
+type DashboardDiff struct {
+	Base             DashboardRef              `json:"base"`
+	Other            DashboardRef              `json:"other"`
+	Identical        bool                      `json:"identical"`
+	Settings         []DashboardFieldChange    `json:"settings,omitempty"`
+	VariablesAdded   []string                  `json:"variablesAdded,omitempty"`
+	VariablesRemoved []string                  `json:"variablesRemoved,omitempty"`
+	VariablesChanged []DashboardVariableChange `json:"variablesChanged,omitempty"`
+	PanelsAdded      []DashboardPanelRef       `json:"panelsAdded,omitempty"`
+	PanelsRemoved    []DashboardPanelRef       `json:"panelsRemoved,omitempty"`
+	PanelsChanged    []DashboardPanelChange    `json:"panelsChanged,omitempty"`
+}
+
+// loadDashboardModel returns the JSON model of a version of a dashboard, or
+// of its current version if version is 0.
+func loadDashboardModel(ctx context.Context, uid string, version int64) (map[string]any, DashboardRef, error) {
+	ref := DashboardRef{UID: uid, Version: version, Instance: mcpgrafana.GrafanaConfigFromContext(ctx).URL}
+	var data any
+	if version == 0 {
+		dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: uid})
+		if err != nil {
+			return nil, ref, classifyAPIError(err)
+		}
+		data = dashboard.Dashboard
+		if dashboard.Meta != nil {
+			ref.Version = dashboard.Meta.Version
+		}
+	} else {
+		v, err := getDashboardVersion(ctx, GetDashboardVersionParams{UID: uid, Version: version})
+		if err != nil {
+			return nil, ref, classifyAPIError(err)
+		}
+		data = v.Data
+	}
+	value, err := toJSONValue(data)
+	if err != nil {
+		return nil, ref, fmt.Errorf("converting dashboard %s: %w", uid, err)
+	}
+	model, ok := value.(map[string]any)
+	if !ok {
+		return nil, ref, fmt.Errorf("dashboard %s is not a JSON object", uid)
+	}
+	ref.Title, _ = model["title"].(string)
+	return model, ref, nil
+}
+
+// fieldChanges returns the fields which differ between two JSON values,
+// with the given fields left out, at most maxMigrationDifferences of them.
+func fieldChanges(old, new map[string]any, skip ...string) []DashboardFieldChange {
+	var diffs []alertRuleFieldDiff
+	diffJSON("", "", without(old, skip...), without(new, skip...), &diffs)
+	changes := make([]DashboardFieldChange, 0, len(diffs))
+	for _, d := range diffs[:min(len(diffs), maxMigrationDifferences)] {
+		changes = append(changes, DashboardFieldChange{Field: d.Path, Old: d.Definition, New: d.Grafana})
+	}
+	if len(diffs) > maxMigrationDifferences {
+		changes = append(changes, DashboardFieldChange{Field: "...", New: fmt.Sprintf("%d more changes", len(diffs)-maxMigrationDifferences)})
+	}
+	return changes
+}
+
+// templateVariablesByName returns the template variables of a dashboard by name,
+// and their names in order.
+func templateVariablesByName(db map[string]any) (map[string]map[string]any, []string) {
+	variables := map[string]map[string]any{}
+	var names []string
+	list, _ := asObject(db["templating"])["list"].([]any)
+	for _, item := range list {
+		variable := asObject(item)
+		name := asString(variable, "name")
+		if name == "" {
+			continue
+		}
+		if _, ok := variables[name]; !ok {
+			names = append(names, name)
+		}
+		variables[name] = variable
+	}
+	return variables, names
+}
+
+// flattenPanels returns the panels of a dashboard, including those in rows.
+func flattenPanels(panels []any) []map[string]any {
+	var result []map[string]any
+	for _, item := range panels {
+		panel := asObject(item)
+		if panel == nil {
+			continue
+		}
+		result = append(result, panel)
+		if children, ok := panel["panels"].([]any); ok {
+			result = append(result, flattenPanels(children)...)
+		}
+	}
+	return result
+}
+
+func panelRef(panel map[string]any) DashboardPanelRef {
+	id, _ := panel["id"].(float64)
+	return DashboardPanelRef{ID: int(id), Title: asString(panel, "title"), Type: asString(panel, "type")}
+}
+
+// matchPanels pairs the panels of two dashboards by ID, and the remaining
+// ones by title and type. Unpaired panels were removed or added.
+func matchPanels(old, new []map[string]any) (pairs [][2]map[string]any, removed, added []map[string]any) {
+	used := make([]bool, len(new))
+	match := func(same func(a, b DashboardPanelRef) bool, panels []map[string]any) []map[string]any {
+		var unmatched []map[string]any
+	next:
+		for _, p := range panels {
+			ref := panelRef(p)
+			for i, q := range new {
+				if !used[i] && same(ref, panelRef(q)) {
+					used[i] = true
+					pairs = append(pairs, [2]map[string]any{p, q})
+					continue next
+				}
+			}
+			unmatched = append(unmatched, p)
+		}
+		return unmatched
+	}
+	removed = match(func(a, b DashboardPanelRef) bool { return a.ID != 0 && a.ID == b.ID }, old)
+	removed = match(func(a, b DashboardPanelRef) bool { return a.Title != "" && a.Title == b.Title && a.Type == b.Type }, removed)
+	for i, p := range new {
+		if !used[i] {
+			added = append(added, p)
+		}
+	}
+	return pairs, removed, added
+}
+
+// targetsByRefID returns the targets of a panel by refId, and the refIds in
+// order. Targets without a refId are named by their position, as Grafana
+// does.
+func targetsByRefID(panel map[string]any) (map[string]map[string]any, []string) {
+	targets := map[string]map[string]any{}
+	var refIDs []string
+	list, _ := panel["targets"].([]any)
+	for i, item := range list {
+		target := asObject(item)
+		refID := cmp.Or(asString(target, "refId"), string(rune('A'+i)))
+		if _, ok := targets[refID]; !ok {
+			refIDs = append(refIDs, refID)
+		}
+		targets[refID] = target
+	}
+	return targets, refIDs
+}
+
+// queryText returns the query of a target and the field holding it.
+func queryText(target map[string]any) (string, string) {
+	for _, field := range dashboardQueryFields {
+		if s := asString(target, field); s != "" {
+			return s, field
+		}
+	}
+	return "", ""
+}
+
+// diffTargets returns the changes of the targets of a panel.
+func diffTargets(old, new map[string]any) []DashboardQueryChange {
+	oldTargets, oldRefIDs := targetsByRefID(old)
+	newTargets, newRefIDs := targetsByRefID(new)
+	var changes []DashboardQueryChange
+	for _, refID := range oldRefIDs {
+		o := oldTargets[refID]
+		oldQuery, oldField := queryText(o)
+		n, ok := newTargets[refID]
+		if !ok {
+			changes = append(changes, DashboardQueryChange{RefID: refID, Status: "removed", Old: oldQuery})
+			continue
+		}
+		newQuery, newField := queryText(n)
+		change := DashboardQueryChange{RefID: refID, Status: "changed", Changes: fieldChanges(o, n, oldField, newField)}
+		if oldQuery != newQuery {
+			change.Old, change.New = oldQuery, newQuery
+		}
+		if change.Old != "" || change.New != "" || len(change.Changes) > 0 {
+			changes = append(changes, change)
+		}
+	}
+	for _, refID := range newRefIDs {
+		if _, ok := oldTargets[refID]; !ok {
+			query, _ := queryText(newTargets[refID])
+			changes = append(changes, DashboardQueryChange{RefID: refID, Status: "added", New: query})
+		}
+	}
+	return changes
+}
+
+// diffDashboardModels returns the structural difference between two
+// dashboard models.
+func diffDashboardModels(old, new map[string]any) DashboardDiff {
+	var diff DashboardDiff
+	diff.Settings = fieldChanges(old, new, dashboardMetadataFields...)
+
+	oldVars, oldNames := templateVariablesByName(old)
+	newVars, newNames := templateVariablesByName(new)
+	for _, name := range oldNames {
+		n, ok := newVars[name]
+		if !ok {
+			diff.VariablesRemoved = append(diff.VariablesRemoved, name)
+			continue
+		}
+		// The current values and resolved options change whenever the
+		// dashboard is saved with other values selected.
+		if changes := fieldChanges(oldVars[name], n, "current", "options"); len(changes) > 0 {
+			diff.VariablesChanged = append(diff.VariablesChanged, DashboardVariableChange{Name: name, Changes: changes})
+		}
+	}
+	for _, name := range newNames {
+		if _, ok := oldVars[name]; !ok {
+			diff.VariablesAdded = append(diff.VariablesAdded, name)
+		}
+	}
+
+	oldPanels, _ := old["panels"].([]any)
+	newPanels, _ := new["panels"].([]any)
+	pairs, removed, added := matchPanels(flattenPanels(oldPanels), flattenPanels(newPanels))
+	for _, p := range removed {
+		diff.PanelsRemoved = append(diff.PanelsRemoved, panelRef(p))
+	}
+	for _, p := range added {
+		diff.PanelsAdded = append(diff.PanelsAdded, panelRef(p))
+	}
+	for _, pair := range pairs {
+		o, n := pair[0], pair[1]
+		change := DashboardPanelChange{
+			DashboardPanelRef: panelRef(n),
+			Moved:             !reflect.DeepEqual(o["gridPos"], n["gridPos"]),
+			Changes:           fieldChanges(o, n, "id", "gridPos", "targets", "panels"),
+			Queries:           diffTargets(o, n),
+		}
+		if change.Moved || len(change.Changes) > 0 || len(change.Queries) > 0 {
+			diff.PanelsChanged = append(diff.PanelsChanged, change)
+		}
+	}
+	diff.Identical = len(diff.Settings) == 0 && len(diff.VariablesAdded) == 0 && len(diff.VariablesRemoved) == 0 &&
+		len(diff.VariablesChanged) == 0 && len(diff.PanelsAdded) == 0 && len(diff.PanelsRemoved) == 0 && len(diff.PanelsChanged) == 0
+	return diff
+}
+
+func diffDashboards(ctx context.Context, args DiffDashboardsParams) (*DashboardDiff, error) {
+	if err := args.validate(); err != nil {
+		return nil, fmt.Errorf("diff dashboards: %w", err)
+	}
+	base, baseRef, err := loadDashboardModel(ctx, args.UID, args.Version)
+	if err != nil {
+		return nil, err
+	}
+	otherCtx := ctx
+	if args.OtherOnTarget {
+		if otherCtx, err = migrationTargetContext(ctx); err != nil {
+			return nil, err
+		}
+	}
+	other, otherRef, err := loadDashboardModel(otherCtx, cmp.Or(args.OtherUID, args.UID), args.OtherVersion)
+	if err != nil {
+		return nil, fmt.Errorf("other dashboard: %w", err)
+	}
+	diff := diffDashboardModels(base, other)
+	diff.Base, diff.Other = baseRef, otherRef
+	return &diff, nil
+}
+
+var DiffDashboards = mcpgrafana.MustTool(
+	"grafana_diff_dashboards",
+	"Compare two dashboards structurally, for review: two versions of a dashboard, two dashboards, or a dashboard with its copy on the second instance configured with --migration-target-url. Reports the changed dashboard settings, the added, removed and changed template variables, and the added, removed, moved and changed panels, with the changed queries of each panel by refId, rather than a raw JSON diff. Panels are matched by ID, or else by title and type.",
+	diffDashboards,
+	mcp.WithTitleAnnotation("Diff dashboards"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
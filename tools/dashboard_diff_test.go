@@ -0,0 +1,98 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffDashboards(t *testing.T) {
+	source := newFakeGrafana(t, fakeRoutes{
+		"/api/dashboards/uid/svc": jsonBody(`{"dashboard": {"id": 1, "uid": "svc", "title": "Service", "version": 5, "tags": ["prod"],
+			"templating": {"list": [
+				{"name": "job", "type": "query", "query": "label_values(job)", "current": {"value": "api"}},
+				{"name": "env", "type": "custom", "query": "prod,dev"}
+			]},
+			"panels": [
+				{"id": 1, "type": "timeseries", "title": "Requests", "gridPos": {"x": 0, "y": 0, "w": 12, "h": 8},
+				 "targets": [{"refId": "A", "expr": "sum(rate(http_requests_total[5m]))"}, {"refId": "B", "expr": "up"}]},
+				{"id": 2, "type": "stat", "title": "Errors", "gridPos": {"x": 12, "y": 0, "w": 12, "h": 8}},
+				{"id": 3, "type": "row", "title": "Details", "collapsed": true, "panels": [
+					{"id": 4, "type": "table", "title": "Pods", "fieldConfig": {"defaults": {"unit": "short"}}}
+				]}
+			]}, "meta": {"version": 5}}`),
+		"/api/dashboards/uid/svc/versions/3": jsonBody(`{"version": 3, "data": {"id": 1, "uid": "svc", "title": "Service", "version": 3, "tags": ["prod"],
+			"templating": {"list": [
+				{"name": "job", "type": "query", "query": "label_values(job)", "current": {"value": "worker"}},
+				{"name": "env", "type": "custom", "query": "prod,staging"}
+			]},
+			"panels": [
+				{"id": 1, "type": "timeseries", "title": "Requests", "gridPos": {"x": 0, "y": 0, "w": 12, "h": 8},
+				 "targets": [{"refId": "A", "expr": "sum(rate(http_requests_total[1m]))"}, {"refId": "B", "expr": "up"}]},
+				{"id": 2, "type": "stat", "title": "Errors", "gridPos": {"x": 12, "y": 0, "w": 12, "h": 8}},
+				{"id": 3, "type": "row", "title": "Details", "collapsed": true, "panels": [
+					{"id": 4, "type": "table", "title": "Pods", "fieldConfig": {"defaults": {"unit": "short"}}}
+				]}
+			]}}`),
+	})
+	target := newFakeGrafana(t, fakeRoutes{
+		"/api/dashboards/uid/svc": jsonBody(`{"dashboard": {"id": 9, "uid": "svc", "title": "Service", "version": 2, "tags": ["staging"],
+			"templating": {"list": [{"name": "job", "type": "query", "query": "label_values(job)"}, {"name": "cluster", "type": "custom", "query": "a,b"}]},
+			"panels": [
+				{"id": 1, "type": "timeseries", "title": "Requests", "gridPos": {"x": 0, "y": 8, "w": 12, "h": 8},
+				 "targets": [{"refId": "A", "expr": "sum(rate(http_requests_total[5m]))", "legendFormat": "{{job}}"}, {"refId": "C", "expr": "down"}]},
+				{"id": 3, "type": "row", "title": "Details", "collapsed": true, "panels": [
+					{"id": 7, "type": "table", "title": "Pods", "fieldConfig": {"defaults": {"unit": "percent"}}}
+				]},
+				{"id": 5, "type": "logs", "title": "Logs"}
+			]}, "meta": {"version": 2}}`),
+	})
+	ctx := newMigrationTestContext(source, target)
+
+	t.Run("versions", func(t *testing.T) {
+		diff, err := diffDashboards(ctx, DiffDashboardsParams{UID: "svc", Version: 3})
+		require.NoError(t, err)
+		assert.False(t, diff.Identical)
+		assert.Equal(t, DashboardRef{UID: "svc", Title: "Service", Version: 3, Instance: source.URL}, diff.Base)
+		assert.Equal(t, int64(5), diff.Other.Version)
+		assert.Empty(t, diff.Settings)
+		// The current value of job is not a change of the variable.
+		assert.Equal(t, []DashboardVariableChange{{Name: "env", Changes: []DashboardFieldChange{{Field: "query", Old: "prod,staging", New: "prod,dev"}}}}, diff.VariablesChanged)
+		require.Len(t, diff.PanelsChanged, 1)
+		assert.Equal(t, []DashboardQueryChange{{RefID: "A", Status: "changed", Old: "sum(rate(http_requests_total[1m]))", New: "sum(rate(http_requests_total[5m]))", Changes: []DashboardFieldChange{}}}, diff.PanelsChanged[0].Queries)
+	})
+
+	t.Run("target", func(t *testing.T) {
+		diff, err := diffDashboards(ctx, DiffDashboardsParams{UID: "svc", OtherOnTarget: true})
+		require.NoError(t, err)
+		assert.Equal(t, target.URL, diff.Other.Instance)
+		assert.Equal(t, []DashboardFieldChange{{Field: "tags[0]", Old: "prod", New: "staging"}}, diff.Settings)
+		assert.Equal(t, []string{"cluster"}, diff.VariablesAdded)
+		assert.Equal(t, []string{"env"}, diff.VariablesRemoved)
+		assert.Equal(t, []DashboardPanelRef{{ID: 5, Title: "Logs", Type: "logs"}}, diff.PanelsAdded)
+		assert.Equal(t, []DashboardPanelRef{{ID: 2, Title: "Errors", Type: "stat"}}, diff.PanelsRemoved)
+
+		require.Len(t, diff.PanelsChanged, 2)
+		requests := diff.PanelsChanged[0]
+		assert.Equal(t, "Requests", requests.Title)
+		assert.True(t, requests.Moved)
+		assert.Equal(t, []DashboardQueryChange{
+			{RefID: "A", Status: "changed", Changes: []DashboardFieldChange{{Field: "legendFormat", New: "{{job}}"}}},
+			{RefID: "B", Status: "removed", Old: "up"},
+			{RefID: "C", Status: "added", New: "down"},
+		}, requests.Queries)
+		// Panels with another ID are matched by title.
+		pods := diff.PanelsChanged[1]
+		assert.Equal(t, DashboardPanelRef{ID: 7, Title: "Pods", Type: "table"}, pods.DashboardPanelRef)
+		assert.Equal(t, []DashboardFieldChange{{Field: "fieldConfig.defaults.unit", Old: "short", New: "percent"}}, pods.Changes)
+	})
+
+	t.Run("nothing to compare", func(t *testing.T) {
+		_, err := diffDashboards(ctx, DiffDashboardsParams{UID: "svc"})
+		assert.ErrorContains(t, err, "nothing to compare")
+	})
+}
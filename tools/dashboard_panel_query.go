@@ -0,0 +1,310 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	// DefaultPanelQueryRowsLimit is the default maximum number of rows
+	// returned for each frame.
+	DefaultPanelQueryRowsLimit = 100
+
+	// defaultPanelMaxDataPoints is the maximum number of data points of
+	// panels which don't set one, as for a panel about 1000 pixels wide.
+	defaultPanelMaxDataPoints = 1000
+)
+
+type QueryDashboardPanelParams struct {
+	DashboardUID string            `json:"dashboardUid" jsonschema:"required,description=The UID of the dashboard"`
+	PanelID      int               `json:"panelId" jsonschema:"required,description=The ID of the panel\\, including panels in collapsed rows"`
+	Values       map[string]string `json:"values,omitempty" jsonschema:"description=Optionally\\, values of variables to use instead of their current values\\, by variable name. Separate multiple values with commas"`
+	StartTime    string            `json:"startTime,omitempty" jsonschema:"description=Optionally\\, the start of the time range\\, in RFC3339 format or relative to now (e.g. 'now-6h'). Defaults to the start of the dashboard's time range"`
+	EndTime      string            `json:"endTime,omitempty" jsonschema:"description=Optionally\\, the end of the time range. Defaults to the end of the dashboard's time range"`
+	Limit        int               `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of rows returned for each frame (default 100). The last rows are returned\\, which are the most recent ones of time series"`
+}
+
+// PanelFrameField is a field of a frame returned by a panel query.
+type PanelFrameField struct {
+	Name   string            `json:"name"`
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// PanelFrame is a frame returned by a panel query, row by row. The values of
+// time fields are in RFC3339 format.
+type PanelFrame struct {
+	Name   string            `json:"name,omitempty"`
+	Fields []PanelFrameField `json:"fields"`
+	Rows   [][]any           `json:"rows"`
+	// TotalRows is the number of rows of the frame, of which only the last
+	// ones are returned if it is larger than the limit.
+	TotalRows int      `json:"totalRows"`
+	Notices   []string `json:"notices,omitempty"`
+}
+
+// PanelTarget is a target of a panel, ready to be run.
+type PanelTarget struct {
+	RefID      string         `json:"refId"`
+	Datasource datasourceInfo `json:"datasource"`
+	// Query is the target with the variables replaced by their values.
+	Query map[string]any `json:"query"`
+	Error string         `json:"error,omitempty"`
+}
+
+// PanelTargetResult is the result of a target of a panel.
+type PanelTargetResult struct {
+	PanelTarget
+	Frames []PanelFrame `json:"frames"`
+}
+
+// PanelQueryResult are the results of the targets of a panel.
+type PanelQueryResult struct {
+	PanelID int    `json:"panelId"`
+	Title   string `json:"title"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	// Variables are the values of the variables used, by name.
+	Variables map[string][]string `json:"variables"`
+	Targets   []PanelTargetResult `json:"targets"`
+}
+
+// findPanel returns the panel with the given ID, looking into rows.
+func findPanel(panels []any, id int) (map[string]any, bool) {
+	for _, p := range panels {
+		panel, ok := p.(map[string]any)
+		if !ok {
+			continue
+		}
+		if panelID, ok := panel["id"].(float64); ok && int(panelID) == id {
+			return panel, true
+		}
+		if children, ok := panel["panels"].([]any); ok {
+			if child, ok := findPanel(children, id); ok {
+				return child, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// interpolateTarget returns a copy of a target with the variables replaced
+// in all of its strings.
+func (r *variableResolver) interpolateTarget(v any, datasourceType string) any {
+	switch v := v.(type) {
+	case string:
+		return r.interpolate(v, datasourceType)
+	case map[string]any:
+		result := make(map[string]any, len(v))
+		for k, child := range v {
+			result[k] = r.interpolateTarget(child, datasourceType)
+		}
+		return result
+	case []any:
+		result := make([]any, len(v))
+		for i, child := range v {
+			result[i] = r.interpolateTarget(child, datasourceType)
+		}
+		return result
+	}
+	return v
+}
+
+// targetDatasource returns the datasource of a target, which defaults to
+// the datasource of its panel. Server-side expressions and the built-in
+// Grafana datasource are passed as they are.
+func (r *variableResolver) targetDatasource(ctx context.Context, target, panel map[string]any) (datasourceInfo, error) {
+	field := target["datasource"]
+	if field == nil {
+		field = panel["datasource"]
+	}
+	if ds, ok := field.(map[string]any); ok {
+		uid, _ := ds["uid"].(string)
+		dsType, _ := ds["type"].(string)
+		switch {
+		case uid == "__expr__" || dsType == "__expr__":
+			return datasourceInfo{UID: "__expr__", Type: "__expr__"}, nil
+		case uid == "grafana":
+			return datasourceInfo{UID: uid, Type: cmp.Or(dsType, "grafana")}, nil
+		case uid == "-- Dashboard --":
+			return datasourceInfo{}, fmt.Errorf("queries reusing the results of other panels are not supported")
+		case uid == "-- Mixed --":
+			return datasourceInfo{}, fmt.Errorf("the target has no datasource of its own in a panel with mixed datasources")
+		}
+	}
+	ds, err := r.variableDatasource(ctx, field)
+	if err != nil {
+		return datasourceInfo{}, err
+	}
+	return datasourceInfo{UID: ds.UID, Type: ds.Type}, nil
+}
+
+// panelFrame converts a frame to rows, keeping the last limit rows.
+func panelFrame(f dataFrame, limit int) PanelFrame {
+	frame := PanelFrame{Name: f.Schema.Name, Fields: make([]PanelFrameField, len(f.Schema.Fields))}
+	for i, field := range f.Schema.Fields {
+		frame.Fields[i] = PanelFrameField{Name: field.Name, Type: field.Type, Labels: field.Labels}
+	}
+	for _, n := range f.Schema.Meta.Notices {
+		frame.Notices = append(frame.Notices, fmt.Sprintf("%s: %s", n.Severity, n.Text))
+	}
+	rows := f.rows()
+	frame.TotalRows = len(rows)
+	if len(rows) > limit {
+		rows = rows[len(rows)-limit:]
+	}
+	for _, row := range rows {
+		for i, v := range row {
+			if ms, ok := v.(float64); ok && i < len(frame.Fields) && frame.Fields[i].Type == "time" {
+				row[i] = time.UnixMilli(int64(ms)).UTC().Format(time.RFC3339)
+			}
+		}
+	}
+	frame.Rows = rows
+	if frame.Rows == nil {
+		frame.Rows = [][]any{}
+	}
+	return frame
+}
+
+// panelTargets returns the targets of a panel which are not hidden, with the
+// variables replaced by the values of r and their datasource resolved.
+func panelTargets(ctx context.Context, r *variableResolver, panel map[string]any) []PanelTarget {
+	targets, _ := panel["targets"].([]any)
+	result := []PanelTarget{}
+	for i, t := range targets {
+		target, ok := t.(map[string]any)
+		if !ok {
+			continue
+		}
+		if hide, _ := target["hide"].(bool); hide {
+			continue
+		}
+		refID, _ := target["refId"].(string)
+		refID = cmp.Or(refID, string(rune('A'+i)))
+		ds, err := r.targetDatasource(ctx, target, panel)
+		if err != nil {
+			result = append(result, PanelTarget{RefID: refID, Error: err.Error()})
+			continue
+		}
+		query := r.interpolateTarget(target, ds.Type).(map[string]any)
+		query["refId"] = refID
+		query["datasource"] = map[string]string{"uid": ds.UID, "type": ds.Type}
+		result = append(result, PanelTarget{RefID: refID, Datasource: ds, Query: query})
+	}
+	return result
+}
+
+func queryDashboardPanel(ctx context.Context, args QueryDashboardPanelParams) (*PanelQueryResult, error) {
+	dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: args.DashboardUID})
+	if err != nil {
+		return nil, classifyAPIError(err)
+	}
+	db, ok := dashboard.Dashboard.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("dashboard is not a JSON object")
+	}
+	panels, _ := db["panels"].([]any)
+	panel, ok := findPanel(panels, args.PanelID)
+	if !ok {
+		return nil, fmt.Errorf("panel %d not found in dashboard %s", args.PanelID, args.DashboardUID)
+	}
+	if _, ok := panel["libraryPanel"]; ok && panel["targets"] == nil {
+		return nil, fmt.Errorf("panel %d is a library panel, whose queries are not stored in the dashboard", args.PanelID)
+	}
+	targets, _ := panel["targets"].([]any)
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("panel %d has no queries", args.PanelID)
+	}
+
+	timeRange, _ := db["time"].(map[string]any)
+	dashboardFrom, _ := timeRange["from"].(string)
+	dashboardTo, _ := timeRange["to"].(string)
+	from, err := parseTime(cmp.Or(args.StartTime, dashboardFrom, "now-1h"))
+	if err != nil {
+		return nil, fmt.Errorf("parsing start time: %w", err)
+	}
+	to, err := parseTime(cmp.Or(args.EndTime, dashboardTo, "now"))
+	if err != nil {
+		return nil, fmt.Errorf("parsing end time: %w", err)
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("the start time %s is not before the end time %s", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+
+	r := newVariableResolver(args.Values, from, to, 0)
+	variables := r.resolveAll(ctx, db)
+	result := &PanelQueryResult{
+		PanelID:   args.PanelID,
+		From:      from.UTC().Format(time.RFC3339),
+		To:        to.UTC().Format(time.RFC3339),
+		Variables: make(map[string][]string, len(variables)),
+		Targets:   []PanelTargetResult{},
+	}
+	result.Title, _ = panel["title"].(string)
+	for _, v := range variables {
+		result.Variables[v.Name] = v.Current
+	}
+
+	maxDataPoints := defaultPanelMaxDataPoints
+	if n, ok := panel["maxDataPoints"].(float64); ok && n > 0 {
+		maxDataPoints = int(n)
+	}
+	intervalMs, _ := strconv.ParseInt(r.values["__interval_ms"][0], 10, 64)
+	var queries []map[string]any
+	for _, target := range panelTargets(ctx, r, panel) {
+		if target.Query != nil {
+			target.Query["maxDataPoints"] = maxDataPoints
+			target.Query["intervalMs"] = intervalMs
+			queries = append(queries, target.Query)
+		}
+		result.Targets = append(result.Targets, PanelTargetResult{PanelTarget: target, Frames: []PanelFrame{}})
+	}
+	if len(queries) == 0 {
+		return result, nil
+	}
+
+	client, err := newDSQueryClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("creating query client: %w", err)
+	}
+	results, err := client.queries(ctx, strconv.FormatInt(from.UnixMilli(), 10), strconv.FormatInt(to.UnixMilli(), 10), queries)
+	if err != nil {
+		return nil, err
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = DefaultPanelQueryRowsLimit
+	}
+	for i, target := range result.Targets {
+		if target.Query == nil {
+			continue
+		}
+		res, ok := results[target.RefID]
+		if !ok {
+			result.Targets[i].Error = "no result returned for the query"
+			continue
+		}
+		result.Targets[i].Error = res.Error
+		for _, f := range res.Frames {
+			result.Targets[i].Frames = append(result.Targets[i].Frames, panelFrame(f, limit))
+		}
+	}
+	return result, nil
+}
+
+var QueryDashboardPanel = mcpgrafana.MustTool(
+	"grafana_query_dashboard_panel",
+	"Run the queries of a dashboard panel for any datasource type and return the resulting data frames. The template variables of the dashboard are resolved as with `grafana_resolve_dashboard_variables` and replaced in the panel's targets, using their current values or those given in `values`. The time range defaults to the dashboard's. Each target returns the query as it was run, its frames with their fields and rows, or its error. Hidden targets are skipped. Use `grafana_get_dashboard_summary` to find the IDs of the panels.",
+	queryDashboardPanel,
+	mcp.WithTitleAnnotation("Query dashboard panel"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
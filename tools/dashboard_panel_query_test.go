@@ -0,0 +1,108 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryDashboardPanel(t *testing.T) {
+	var request struct {
+		Queries []map[string]any `json:"queries"`
+		From    string           `json:"from"`
+		To      string           `json:"to"`
+	}
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/dashboards/uid/service": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"dashboard": map[string]any{
+					"uid":  "service",
+					"time": map[string]any{"from": "2024-01-01T00:00:00Z", "to": "2024-01-01T01:00:00Z"},
+					"templating": map[string]any{"list": []any{
+						map[string]any{"name": "ds", "type": "datasource", "query": "prometheus", "current": map[string]any{"value": "metrics"}},
+						map[string]any{"name": "env", "type": "custom", "query": "prod,staging", "current": map[string]any{"value": "prod"}},
+					}},
+					"panels": []any{
+						map[string]any{"id": 1, "type": "row", "collapsed": true, "panels": []any{
+							map[string]any{
+								"id":         2,
+								"title":      "Errors",
+								"datasource": map[string]any{"type": "prometheus", "uid": "${ds}"},
+								"targets": []any{
+									map[string]any{"refId": "A", "expr": `sum(rate(errors_total{env="$env"}[$__rate_interval]))`},
+									map[string]any{"refId": "B", "expr": "up", "hide": true},
+									map[string]any{"refId": "C", "datasource": map[string]any{"uid": "-- Dashboard --"}},
+									map[string]any{"refId": "D", "datasource": map[string]any{"type": "__expr__", "uid": "__expr__"}, "type": "math", "expression": "$A * 100"},
+								},
+							},
+						}},
+					},
+				},
+			})
+		},
+		"/api/datasources": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode([]map[string]any{
+				{"id": 1, "uid": "metrics", "name": "Metrics", "type": "prometheus", "isDefault": true},
+			})
+		},
+		"/api/ds/query": func(w http.ResponseWriter, r *http.Request) {
+			if !decodeJSONBody(t, w, r, &request) {
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"results": map[string]any{
+				"A": map[string]any{"frames": []any{map[string]any{
+					"schema": map[string]any{"fields": []any{
+						map[string]any{"name": "Time", "type": "time"},
+						map[string]any{"name": "Value", "type": "number", "labels": map[string]string{"env": "prod"}},
+					}},
+					"data": map[string]any{"values": []any{[]any{1704067200000, 1704067260000, 1704067320000}, []any{1, 2, 3}}},
+				}}},
+				"D": map[string]any{"error": "boom"},
+			}})
+		},
+	})
+
+	ctx := fakeGrafanaContext(server)
+
+	result, err := queryDashboardPanel(ctx, QueryDashboardPanelParams{
+		DashboardUID: "service",
+		PanelID:      2,
+		Values:       map[string]string{"env": "staging"},
+		Limit:        2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Errors", result.Title)
+	assert.Equal(t, "2024-01-01T00:00:00Z", result.From)
+	assert.Equal(t, []string{"staging"}, result.Variables["env"])
+
+	// The hidden and unsupported targets are not run.
+	require.Len(t, request.Queries, 2)
+	assert.Equal(t, "1704067200000", request.From)
+	assert.Equal(t, `sum(rate(errors_total{env="staging"}[60s]))`, request.Queries[0]["expr"])
+	assert.Equal(t, map[string]any{"uid": "metrics", "type": "prometheus"}, request.Queries[0]["datasource"])
+	assert.Equal(t, "$A * 100", request.Queries[1]["expression"])
+
+	require.Len(t, result.Targets, 3)
+	a := result.Targets[0]
+	assert.Equal(t, "A", a.RefID)
+	assert.Empty(t, a.Error)
+	require.Len(t, a.Frames, 1)
+	assert.Equal(t, 3, a.Frames[0].TotalRows)
+	assert.Equal(t, [][]any{{"2024-01-01T00:01:00Z", float64(2)}, {"2024-01-01T00:02:00Z", float64(3)}}, a.Frames[0].Rows)
+	assert.Equal(t, map[string]string{"env": "prod"}, a.Frames[0].Fields[1].Labels)
+	assert.Equal(t, "C", result.Targets[1].RefID)
+	assert.Contains(t, result.Targets[1].Error, "other panels")
+	assert.Equal(t, "D", result.Targets[2].RefID)
+	assert.Equal(t, "boom", result.Targets[2].Error)
+
+	t.Run("missing panel", func(t *testing.T) {
+		_, err := queryDashboardPanel(ctx, QueryDashboardPanelParams{DashboardUID: "service", PanelID: 42})
+		assert.ErrorContains(t, err, "panel 42 not found")
+	})
+}
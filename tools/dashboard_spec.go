@@ -0,0 +1,269 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// dashboardGridColumns is the number of columns of the dashboard grid.
+const dashboardGridColumns = 24
+
+// PanelSpec is a compact description of a panel, which is expanded into the
+// full panel JSON.
+type PanelSpec struct {
+	Title         string   `json:"title" jsonschema:"required,description=The title of the panel"`
+	Type          string   `json:"type,omitempty" jsonschema:"description=The visualization\\, e.g. timeseries (default)\\, stat\\, gauge\\, bargauge\\, table\\, barchart\\, piechart\\, heatmap\\, logs or text"`
+	DatasourceUID string   `json:"datasourceUid,omitempty" jsonschema:"description=The UID of the datasource the queries are run against. Required unless the panel is a text panel"`
+	Queries       []string `json:"queries,omitempty" jsonschema:"description=The queries of the panel in the language of the datasource\\, e.g. PromQL or LogQL. Their refIds are A\\, B and so on"`
+	Unit          string   `json:"unit,omitempty" jsonschema:"description=Optionally\\, the unit of the values\\, e.g. percent\\, s\\, bytes or reqps"`
+	Description   string   `json:"description,omitempty" jsonschema:"description=Optionally\\, the description of the panel"`
+	Content       string   `json:"content,omitempty" jsonschema:"description=The Markdown content of text panels"`
+	Row           string   `json:"row,omitempty" jsonschema:"description=Optionally\\, the title of the row the panel is grouped under. Rows are added in the order they first appear\\, after the panels without a row"`
+	Width         int      `json:"width,omitempty" jsonschema:"description=Optionally\\, the width of the panel in columns of the 24 column grid. Defaults to 6 for stat and gauge panels\\, 24 for tables and logs and 12 otherwise"`
+	Height        int      `json:"height,omitempty" jsonschema:"description=Optionally\\, the height of the panel in grid units of 30 pixels. Defaults to 5 for stat and gauge panels\\, 10 for tables and logs and 8 otherwise"`
+}
+
+type CreateDashboardFromSpecParams struct {
+	Title     string      `json:"title" jsonschema:"required,description=The title of the dashboard"`
+	UID       string      `json:"uid,omitempty" jsonschema:"description=Optionally\\, the UID of the dashboard. Grafana generates one if not given"`
+	FolderUID string      `json:"folderUid,omitempty" jsonschema:"description=Optionally\\, the UID of the folder of the dashboard"`
+	Tags      []string    `json:"tags,omitempty" jsonschema:"description=Optionally\\, the tags of the dashboard"`
+	From      string      `json:"from,omitempty" jsonschema:"description=Optionally\\, the start of the default time range\\, e.g. now-1h (default now-6h). It ends now"`
+	Refresh   string      `json:"refresh,omitempty" jsonschema:"description=Optionally\\, the refresh interval\\, e.g. 30s or 1m"`
+	Panels    []PanelSpec `json:"panels" jsonschema:"required,description=The panels of the dashboard\\, laid out from left to right and top to bottom"`
+	Message   string      `json:"message,omitempty" jsonschema:"description=Optionally\\, the commit message of the version history"`
+	Overwrite bool        `json:"overwrite,omitempty" jsonschema:"description=Whether to replace the dashboard with the same UID or title in the folder if there is one"`
+}
+
+// CreateDashboardFromSpecResult is the dashboard created from a spec.
+type CreateDashboardFromSpecResult struct {
+	UID     string `json:"uid"`
+	URL     string `json:"url"`
+	Version int64  `json:"version"`
+	Panels  int    `json:"panels"`
+}
+
+// defaultPanelSize returns the width and height of panels of a type.
+func defaultPanelSize(panelType string) (int, int) {
+	switch panelType {
+	case "stat", "gauge", "bargauge":
+		return 6, 5
+	case "table", "logs":
+		return dashboardGridColumns, 10
+	default:
+		return 12, 8
+	}
+}
+
+// gridLayout places panels on the dashboard grid from left to right,
+// starting a new line when a panel doesn't fit on the current one.
+type gridLayout struct {
+	x, y, lineHeight int
+}
+
+func (l *gridLayout) place(w, h int) map[string]int {
+	if l.x+w > dashboardGridColumns {
+		l.newLine()
+	}
+	pos := map[string]int{"x": l.x, "y": l.y, "w": w, "h": h}
+	l.x += w
+	l.lineHeight = max(l.lineHeight, h)
+	return pos
+}
+
+func (l *gridLayout) newLine() {
+	l.y += l.lineHeight
+	l.x, l.lineHeight = 0, 0
+}
+
+// specPanel expands the spec of a panel. Its datasource must be in
+// datasources.
+func specPanel(id int, spec PanelSpec, datasources map[string]*models.DataSource, layout *gridLayout) map[string]any {
+	panelType := cmp.Or(spec.Type, "timeseries")
+	w, h := defaultPanelSize(panelType)
+	panel := map[string]any{
+		"id":      id,
+		"type":    panelType,
+		"title":   spec.Title,
+		"gridPos": layout.place(min(cmp.Or(spec.Width, w), dashboardGridColumns), cmp.Or(spec.Height, h)),
+	}
+	if spec.Description != "" {
+		panel["description"] = spec.Description
+	}
+	if panelType == "text" {
+		panel["options"] = map[string]any{"mode": "markdown", "content": spec.Content}
+		return panel
+	}
+	ds := datasources[spec.DatasourceUID]
+	panel["datasource"] = map[string]string{"type": ds.Type, "uid": ds.UID}
+	targets := make([]any, len(spec.Queries))
+	for i, q := range spec.Queries {
+		targets[i] = newDatasourceQuery(ds, refIDForIndex(i), q)
+	}
+	panel["targets"] = targets
+	defaults := map[string]any{}
+	if spec.Unit != "" {
+		defaults["unit"] = spec.Unit
+	}
+	panel["fieldConfig"] = map[string]any{"defaults": defaults, "overrides": []any{}}
+	panel["options"] = map[string]any{}
+	return panel
+}
+
+// refIDForIndex returns the refId of the i-th query of a panel: A to Z, then
+// AA, AB and so on.
+func refIDForIndex(i int) string {
+	if i < 26 {
+		return string(rune('A' + i))
+	}
+	return refIDForIndex(i/26-1) + refIDForIndex(i%26)
+}
+
+// validatePanelSpecs checks the specs of the panels and returns the UIDs of
+// their datasources.
+func validatePanelSpecs(specs []PanelSpec) ([]string, error) {
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("at least one panel is required")
+	}
+	var uids []string
+	for i, spec := range specs {
+		if spec.Title == "" {
+			return nil, fmt.Errorf("panel %d: a title is required", i)
+		}
+		if spec.Width < 0 || spec.Width > dashboardGridColumns || spec.Height < 0 {
+			return nil, fmt.Errorf("panel %d: the width must be between 1 and %d columns and the height positive", i, dashboardGridColumns)
+		}
+		switch spec.Type {
+		case "row":
+			return nil, fmt.Errorf("panel %d: rows are created with the row field of panels", i)
+		case "text":
+			continue
+		}
+		if spec.DatasourceUID == "" || len(spec.Queries) == 0 {
+			return nil, fmt.Errorf("panel %d: a datasource UID and at least one query are required", i)
+		}
+		uids = append(uids, spec.DatasourceUID)
+	}
+	return uids, nil
+}
+
+// buildDashboardFromSpec expands the spec of a dashboard into its JSON. The
+// panels without a row come first, then the rows in the order they first
+// appear in the spec, each with its panels.
+func buildDashboardFromSpec(args CreateDashboardFromSpecParams, datasources map[string]*models.DataSource) map[string]any {
+	var rows []string
+	byRow := map[string][]PanelSpec{}
+	for _, spec := range args.Panels {
+		if _, ok := byRow[spec.Row]; !ok && spec.Row != "" {
+			rows = append(rows, spec.Row)
+		}
+		byRow[spec.Row] = append(byRow[spec.Row], spec)
+	}
+	layout := &gridLayout{}
+	panels := []any{}
+	id := 1
+	for _, spec := range byRow[""] {
+		panels = append(panels, specPanel(id, spec, datasources, layout))
+		id++
+	}
+	for _, row := range rows {
+		layout.newLine()
+		panels = append(panels, map[string]any{
+			"id":        id,
+			"type":      "row",
+			"title":     row,
+			"collapsed": false,
+			"panels":    []any{},
+			"gridPos":   layout.place(dashboardGridColumns, 1),
+		})
+		id++
+		layout.newLine()
+		for _, spec := range byRow[row] {
+			panels = append(panels, specPanel(id, spec, datasources, layout))
+			id++
+		}
+	}
+	tags := args.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	dashboard := map[string]any{
+		"title":         args.Title,
+		"tags":          tags,
+		"timezone":      "browser",
+		"editable":      true,
+		"schemaVersion": latestDashboardSchemaVersion,
+		"time":          map[string]string{"from": cmp.Or(args.From, "now-6h"), "to": "now"},
+		"templating":    map[string]any{"list": []any{}},
+		"panels":        panels,
+	}
+	if args.UID != "" {
+		dashboard["uid"] = args.UID
+	}
+	if args.Refresh != "" {
+		dashboard["refresh"] = args.Refresh
+	}
+	return dashboard
+}
+
+func createDashboardFromSpec(ctx context.Context, args CreateDashboardFromSpecParams) (*CreateDashboardFromSpecResult, error) {
+	if args.Title == "" {
+		return nil, fmt.Errorf("a title is required")
+	}
+	if args.UID != "" && !dashboardUIDPattern.MatchString(args.UID) {
+		return nil, fmt.Errorf("invalid UID %q: it must have at most 40 letters, digits, '-' and '_'", args.UID)
+	}
+	uids, err := validatePanelSpecs(args.Panels)
+	if err != nil {
+		return nil, err
+	}
+	datasources := map[string]*models.DataSource{}
+	for _, uid := range uids {
+		if _, ok := datasources[uid]; ok {
+			continue
+		}
+		ds, err := getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: uid})
+		if err != nil {
+			return nil, err
+		}
+		datasources[uid] = ds
+	}
+	dashboard := buildDashboardFromSpec(args, datasources)
+	saved, err := updateDashboard(ctx, UpdateDashboardParams{
+		Dashboard: dashboard,
+		FolderUID: args.FolderUID,
+		Message:   cmp.Or(args.Message, "Created from a spec"),
+		Overwrite: args.Overwrite,
+	})
+	if err != nil {
+		return nil, err
+	}
+	base, _ := deeplinkBase(ctx)
+	result := &CreateDashboardFromSpecResult{Panels: len(args.Panels)}
+	if saved.UID != nil {
+		result.UID = *saved.UID
+	}
+	if saved.URL != nil {
+		result.URL = base + *saved.URL
+	}
+	if saved.Version != nil {
+		result.Version = *saved.Version
+	}
+	return result, nil
+}
+
+var CreateDashboardFromSpec = mcpgrafana.MustTool(
+	"grafana_create_dashboard_from_spec",
+	"Create a dashboard from a compact spec rather than its full JSON: a title and a list of panels, each with a title, a visualization type, a datasource UID and queries, and optionally a unit and a row to group it under. The spec is expanded into valid dashboard JSON, with the panels laid out on the grid with sizes fitting their type and the query of each target in the field its datasource expects, and saved. Returns the UID, URL and version of the dashboard. Fine-tune the dashboard afterwards with `grafana_update_dashboard` if needed.",
+	createDashboardFromSpec,
+	mcp.WithTitleAnnotation("Create dashboard from spec"),
+	// With overwrite, an existing dashboard is replaced.
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithIdempotentHintAnnotation(false),
+)
@@ -0,0 +1,117 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefIDForIndex(t *testing.T) {
+	assert.Equal(t, "A", refIDForIndex(0))
+	assert.Equal(t, "Z", refIDForIndex(25))
+	assert.Equal(t, "AA", refIDForIndex(26))
+	assert.Equal(t, "BA", refIDForIndex(52))
+}
+
+func TestBuildDashboardFromSpec(t *testing.T) {
+	datasources := map[string]*models.DataSource{
+		"prom": {UID: "prom", Type: "prometheus"},
+		"loki": {UID: "loki", Type: "loki"},
+	}
+	dashboard := buildDashboardFromSpec(CreateDashboardFromSpecParams{
+		Title: "API",
+		UID:   "api",
+		Panels: []PanelSpec{
+			{Title: "Up", Type: "stat", DatasourceUID: "prom", Queries: []string{"up"}},
+			{Title: "Notes", Type: "text", Content: "# API"},
+			{Title: "Requests", DatasourceUID: "prom", Queries: []string{"rate(a[5m])", "rate(b[5m])"}, Unit: "reqps", Row: "Traffic"},
+			{Title: "Errors", Type: "logs", DatasourceUID: "loki", Queries: []string{`{job="api"} |= "error"`}, Row: "Logs"},
+			{Title: "Latency", DatasourceUID: "prom", Queries: []string{"histogram_quantile(0.99, x)"}, Row: "Traffic"},
+			{Title: "Saturation", DatasourceUID: "prom", Queries: []string{"y"}, Row: "Traffic"},
+		},
+	}, datasources)
+
+	assert.Equal(t, "api", dashboard["uid"])
+	assert.Equal(t, map[string]string{"from": "now-6h", "to": "now"}, dashboard["time"])
+	panels := dashboard["panels"].([]any)
+	require.Len(t, panels, 8)
+	var titles []string
+	var positions []map[string]int
+	for i, p := range panels {
+		panel := p.(map[string]any)
+		assert.Equal(t, i+1, panel["id"])
+		titles = append(titles, panel["title"].(string))
+		positions = append(positions, panel["gridPos"].(map[string]int))
+	}
+	assert.Equal(t, []string{"Up", "Notes", "Traffic", "Requests", "Latency", "Saturation", "Logs", "Errors"}, titles)
+	assert.Equal(t, []map[string]int{
+		{"x": 0, "y": 0, "w": 6, "h": 5},
+		{"x": 6, "y": 0, "w": 12, "h": 8},
+		{"x": 0, "y": 8, "w": 24, "h": 1},
+		{"x": 0, "y": 9, "w": 12, "h": 8},
+		{"x": 12, "y": 9, "w": 12, "h": 8},
+		{"x": 0, "y": 17, "w": 12, "h": 8},
+		{"x": 0, "y": 25, "w": 24, "h": 1},
+		{"x": 0, "y": 26, "w": 24, "h": 10},
+	}, positions)
+
+	requests := panels[3].(map[string]any)
+	assert.Equal(t, "timeseries", requests["type"])
+	assert.Equal(t, map[string]string{"type": "prometheus", "uid": "prom"}, requests["datasource"])
+	targets := requests["targets"].([]any)
+	require.Len(t, targets, 2)
+	assert.Equal(t, "B", targets[1].(map[string]any)["refId"])
+	assert.Equal(t, "rate(b[5m])", targets[1].(map[string]any)["expr"])
+	assert.Equal(t, "reqps", requests["fieldConfig"].(map[string]any)["defaults"].(map[string]any)["unit"])
+
+	// The generated dashboard passes the validation.
+	var generic map[string]any
+	data, err := json.Marshal(dashboard)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &generic))
+	v := &dashboardValidator{panelIDs: map[int]string{}}
+	v.validateTop(generic)
+	v.validatePanels("panels", generic["panels"], false)
+	assert.Empty(t, v.result.Errors)
+	assert.Empty(t, v.result.Warnings)
+}
+
+func TestCreateDashboardFromSpec(t *testing.T) {
+	var saved map[string]any
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/datasources/uid/prom": jsonBody(`{"uid":"prom","type":"prometheus","name":"Prometheus"}`),
+		"POST /api/dashboards/db": func(w http.ResponseWriter, r *http.Request) {
+			if !decodeJSONBody(t, w, r, &saved) {
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":1,"uid":"generated","url":"/d/generated/api","status":"success","version":1,"title":"API"}`))
+		},
+	})
+	ctx := fakeGrafanaContext(server)
+
+	result, err := createDashboardFromSpec(ctx, CreateDashboardFromSpecParams{
+		Title:     "API",
+		FolderUID: "ops",
+		Panels:    []PanelSpec{{Title: "Up", DatasourceUID: "prom", Queries: []string{"up"}}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &CreateDashboardFromSpecResult{UID: "generated", URL: server.URL + "/d/generated/api", Version: 1, Panels: 1}, result)
+	assert.Equal(t, "ops", saved["folderUid"])
+	assert.Equal(t, "API", saved["dashboard"].(map[string]any)["title"])
+
+	t.Run("invalid specs", func(t *testing.T) {
+		_, err := createDashboardFromSpec(ctx, CreateDashboardFromSpecParams{Title: "API"})
+		assert.ErrorContains(t, err, "at least one panel")
+		_, err = createDashboardFromSpec(ctx, CreateDashboardFromSpecParams{Title: "API", Panels: []PanelSpec{{Title: "Up", DatasourceUID: "prom"}}})
+		assert.ErrorContains(t, err, "at least one query")
+		_, err = createDashboardFromSpec(ctx, CreateDashboardFromSpecParams{Title: "API", Panels: []PanelSpec{{Title: "Up", DatasourceUID: "missing", Queries: []string{"up"}}}})
+		assert.ErrorContains(t, err, "not found")
+	})
+}
@@ -0,0 +1,208 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+type GetDashboardSummaryParams struct {
+	UID string `json:"uid" jsonschema:"required,description=The UID of the dashboard"`
+}
+
+// DashboardVariable is the definition of a template variable of a dashboard.
+type DashboardVariable struct {
+	Name       string          `json:"name"`
+	Type       string          `json:"type,omitempty"`
+	Label      string          `json:"label,omitempty"`
+	Query      string          `json:"query,omitempty"`
+	Datasource *datasourceInfo `json:"datasource,omitempty"`
+	// Current is the text of the current value of the variable, which may
+	// be a list for variables with several values selected.
+	Current    any  `json:"current,omitempty"`
+	Multi      bool `json:"multi,omitempty"`
+	IncludeAll bool `json:"includeAll,omitempty"`
+}
+
+// DashboardPanel is a panel of a dashboard, without its definition.
+type DashboardPanel struct {
+	ID    int    `json:"id,omitempty"`
+	Title string `json:"title,omitempty"`
+	Type  string `json:"type,omitempty"`
+	// DatasourceUIDs are the UIDs of the datasources of the panel and its
+	// queries, which may be template variables such as "$datasource".
+	DatasourceUIDs []string `json:"datasourceUids,omitempty"`
+}
+
+// DashboardRow is a row of a dashboard with the panels it contains.
+type DashboardRow struct {
+	ID        int              `json:"id,omitempty"`
+	Title     string           `json:"title,omitempty"`
+	Collapsed bool             `json:"collapsed,omitempty"`
+	Panels    []DashboardPanel `json:"panels"`
+}
+
+// DashboardSummary describes the structure of a dashboard without the full
+// definitions of its panels.
+type DashboardSummary struct {
+	UID         string              `json:"uid"`
+	Title       string              `json:"title,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	FolderUID   string              `json:"folderUid,omitempty"`
+	FolderTitle string              `json:"folderTitle,omitempty"`
+	URL         string              `json:"url,omitempty"`
+	Version     int64               `json:"version,omitempty"`
+	Variables   []DashboardVariable `json:"variables,omitempty"`
+	// Panels are the panels which are not in a row, above the first row.
+	Panels []DashboardPanel `json:"panels,omitempty"`
+	Rows   []DashboardRow   `json:"rows,omitempty"`
+}
+
+// dashboardPanel summarizes a panel, collecting the distinct datasources of
+// the panel and its targets.
+func dashboardPanel(panel map[string]any) DashboardPanel {
+	p := DashboardPanel{}
+	if id, ok := panel["id"].(float64); ok {
+		p.ID = int(id)
+	}
+	p.Title, _ = panel["title"].(string)
+	p.Type, _ = panel["type"].(string)
+	addUID := func(ds any) {
+		uid := panelDatasource(ds).UID
+		if s, ok := ds.(string); ok {
+			// Old dashboards reference datasources by name.
+			uid = s
+		}
+		if uid == "" || slices.Contains(p.DatasourceUIDs, uid) {
+			return
+		}
+		p.DatasourceUIDs = append(p.DatasourceUIDs, uid)
+	}
+	addUID(panel["datasource"])
+	targets, _ := panel["targets"].([]any)
+	for _, t := range targets {
+		if target, ok := t.(map[string]any); ok {
+			addUID(target["datasource"])
+		}
+	}
+	return p
+}
+
+// dashboardLayout splits the panels of a dashboard into those above the
+// first row and rows. The panels of expanded rows follow the row in the
+// list of panels, while those of collapsed rows are nested inside it.
+func dashboardLayout(panels []any) ([]DashboardPanel, []DashboardRow) {
+	top := []DashboardPanel{}
+	var rows []DashboardRow
+	for _, v := range panels {
+		panel, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		if panel["type"] != "row" {
+			if len(rows) == 0 {
+				top = append(top, dashboardPanel(panel))
+			} else {
+				rows[len(rows)-1].Panels = append(rows[len(rows)-1].Panels, dashboardPanel(panel))
+			}
+			continue
+		}
+		row := DashboardRow{Panels: []DashboardPanel{}}
+		if id, ok := panel["id"].(float64); ok {
+			row.ID = int(id)
+		}
+		row.Title, _ = panel["title"].(string)
+		row.Collapsed, _ = panel["collapsed"].(bool)
+		nested, _ := panel["panels"].([]any)
+		for _, n := range nested {
+			if p, ok := n.(map[string]any); ok {
+				row.Panels = append(row.Panels, dashboardPanel(p))
+			}
+		}
+		rows = append(rows, row)
+	}
+	return top, rows
+}
+
+// dashboardVariables returns the definitions of the template variables of a
+// dashboard.
+func dashboardVariables(db map[string]any) []DashboardVariable {
+	templating, _ := db["templating"].(map[string]any)
+	list, _ := templating["list"].([]any)
+	var variables []DashboardVariable
+	for _, v := range list {
+		variable, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		dv := DashboardVariable{}
+		dv.Name, _ = variable["name"].(string)
+		dv.Type, _ = variable["type"].(string)
+		dv.Label, _ = variable["label"].(string)
+		dv.Multi, _ = variable["multi"].(bool)
+		dv.IncludeAll, _ = variable["includeAll"].(bool)
+		// Queries of datasource variables are strings, while some
+		// datasources store them as objects with a query field.
+		switch q := variable["query"].(type) {
+		case string:
+			dv.Query = q
+		case map[string]any:
+			dv.Query, _ = q["query"].(string)
+		}
+		if ds := panelDatasource(variable["datasource"]); ds.UID != "" {
+			dv.Datasource = &ds
+		}
+		if current, ok := variable["current"].(map[string]any); ok {
+			dv.Current = current["text"]
+		}
+		variables = append(variables, dv)
+	}
+	return variables
+}
+
+// summarizeDashboardStructure describes the structure of a dashboard: its
+// metadata, variables, panels and rows.
+func summarizeDashboardStructure(uid string, dashboard *models.DashboardFullWithMeta) (*DashboardSummary, error) {
+	db, ok := dashboard.Dashboard.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("dashboard is not a JSON object")
+	}
+	base := summarizeDashboard(uid, dashboard)
+	summary := &DashboardSummary{
+		UID:         uid,
+		Title:       base.Title,
+		Description: base.Description,
+		Tags:        base.Tags,
+		FolderUID:   base.FolderUID,
+		FolderTitle: base.FolderTitle,
+		URL:         base.URL,
+		Version:     base.Version,
+		Variables:   dashboardVariables(db),
+	}
+	panels, _ := db["panels"].([]any)
+	summary.Panels, summary.Rows = dashboardLayout(panels)
+	return summary, nil
+}
+
+func getDashboardSummary(ctx context.Context, args GetDashboardSummaryParams) (*DashboardSummary, error) {
+	dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams(args))
+	if err != nil {
+		return nil, err
+	}
+	return summarizeDashboardStructure(args.UID, dashboard)
+}
+
+var GetDashboardSummary = mcpgrafana.MustTool(
+	"grafana_get_dashboard_summary",
+	"Get the structure of a dashboard without its full JSON, which often exceeds the context window for real dashboards: its title, description, tags, folder, URL and version, the definitions of its template variables (type, query, datasource and current value), and its panels (ID, title, type and datasource UIDs) grouped by row, with the panels above the first row in `panels`. Use `grafana_get_dashboard_by_uid` only when the full definition of the panels is needed, and `grafana_get_dashboard_panel_queries` for their queries.",
+	getDashboardSummary,
+	mcp.WithTitleAnnotation("Get dashboard summary"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -73,6 +73,21 @@ func TestDashboardTools(t *testing.T) {
 		require.Error(t, err)
 	})
 
+	t.Run("get dashboard version", func(t *testing.T) {
+		ctx := newTestContext()
+
+		dashboard := getExistingTestDashboard(t, ctx, "")
+		dashboardMap := getTestDashboardJSON(t, ctx, dashboard)
+
+		result, err := getDashboardVersion(ctx, GetDashboardVersionParams{
+			UID:     dashboard.UID,
+			Version: int64(dashboardMap["version"].(float64)),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, dashboard.UID, result.UID)
+		assert.NotNil(t, result.Data)
+	})
+
 	t.Run("update dashboard - create new", func(t *testing.T) {
 		ctx := newTestContext()
 
@@ -0,0 +1,207 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeDashboard(t *testing.T) {
+	dashboard := &models.DashboardFullWithMeta{
+		Meta: &models.DashboardMeta{
+			FolderUID:   "folder",
+			FolderTitle: "Folder",
+			URL:         "/d/abc/test",
+			Version:     3,
+		},
+		Dashboard: map[string]any{
+			"title":       "Test",
+			"description": "A test dashboard",
+			"tags":        []any{"a", "b"},
+			"panels": []any{
+				map[string]any{"id": float64(1), "title": "Requests", "type": "timeseries"},
+				map[string]any{"id": float64(2), "title": "Row", "type": "row", "panels": []any{
+					map[string]any{"id": float64(3), "title": "Errors", "type": "stat"},
+				}},
+			},
+			"templating": map[string]any{
+				"list": []any{map[string]any{"name": "datasource"}, map[string]any{"name": "job"}},
+			},
+		},
+	}
+
+	summary := summarizeDashboard("abc", dashboard)
+	assert.Equal(t, dashboardSummary{
+		UID:         "abc",
+		Title:       "Test",
+		Description: "A test dashboard",
+		Tags:        []string{"a", "b"},
+		FolderUID:   "folder",
+		FolderTitle: "Folder",
+		URL:         "/d/abc/test",
+		Version:     3,
+		Panels: []panelSummary{
+			{ID: 1, Title: "Requests", Type: "timeseries"},
+			{ID: 2, Title: "Row", Type: "row"},
+			{ID: 3, Title: "Errors", Type: "stat"},
+		},
+		Variables: []string{"datasource", "job"},
+	}, summary)
+}
+
+func TestGetDashboardSummaries(t *testing.T) {
+	routes := fakeRoutes{
+		"/api/search": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "dash-db", r.URL.Query().Get("type"))
+			assert.Equal(t, "folder", r.URL.Query().Get("folderUIDs"))
+			_ = json.NewEncoder(w).Encode([]map[string]any{{"uid": "one"}, {"uid": "missing"}, {"uid": "two"}})
+		},
+		"/api/dashboards/uid/missing": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Dashboard not found"}`))
+		},
+	}
+	for _, uid := range []string{"one", "two"} {
+		routes["/api/dashboards/uid/"+uid] = func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"dashboard": map[string]any{"uid": uid, "title": "Dashboard " + uid},
+				"meta":      map[string]any{"folderUid": "folder"},
+			})
+		}
+	}
+	ctx := newFakeGrafanaContext(t, routes)
+
+	t.Run("by search filter", func(t *testing.T) {
+		summaries, err := getDashboardSummaries(ctx, GetDashboardSummariesParams{FolderUID: "folder"})
+		require.NoError(t, err)
+		require.Len(t, summaries, 3)
+		assert.Equal(t, "Dashboard one", summaries[0].Title)
+		assert.Equal(t, "missing", summaries[1].UID)
+		assert.NotEmpty(t, summaries[1].Error)
+		assert.Equal(t, "Dashboard two", summaries[2].Title)
+	})
+
+	t.Run("by uids with limit", func(t *testing.T) {
+		summaries, err := getDashboardSummaries(ctx, GetDashboardSummariesParams{UIDs: []string{"two", "one"}, Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, summaries, 1)
+		assert.Equal(t, "Dashboard two", summaries[0].Title)
+		assert.Equal(t, "folder", summaries[0].FolderUID)
+	})
+
+	t.Run("no uids or filter", func(t *testing.T) {
+		_, err := getDashboardSummaries(ctx, GetDashboardSummariesParams{})
+		require.Error(t, err)
+	})
+}
+
+func TestSummarizeDashboardStructure(t *testing.T) {
+	dashboard := &models.DashboardFullWithMeta{
+		Meta: &models.DashboardMeta{FolderUID: "folder", FolderTitle: "Folder", Version: 7},
+		Dashboard: map[string]any{
+			"title": "API",
+			"tags":  []any{"api"},
+			"panels": []any{
+				map[string]any{"id": float64(1), "title": "Overview", "type": "text"},
+				map[string]any{"id": float64(2), "title": "Traffic", "type": "row", "collapsed": false, "panels": []any{}},
+				map[string]any{"id": float64(3), "title": "Requests", "type": "timeseries", "datasource": map[string]any{"uid": "$datasource"}, "targets": []any{
+					map[string]any{"refId": "A", "datasource": map[string]any{"uid": "$datasource"}},
+					map[string]any{"refId": "B", "datasource": map[string]any{"uid": "loki"}},
+				}},
+				map[string]any{"id": float64(4), "title": "Details", "type": "row", "collapsed": true, "panels": []any{
+					map[string]any{"id": float64(5), "title": "Logs", "type": "logs", "datasource": "Loki"},
+				}},
+			},
+			"templating": map[string]any{"list": []any{
+				map[string]any{"name": "datasource", "type": "datasource", "query": "prometheus", "current": map[string]any{"text": "Prometheus", "value": "prom"}},
+				map[string]any{"name": "job", "type": "query", "multi": true, "includeAll": true,
+					"datasource": map[string]any{"uid": "$datasource", "type": "prometheus"},
+					"query":      map[string]any{"query": "label_values(up, job)", "refId": "A"},
+					"current":    map[string]any{"text": []any{"api", "web"}}},
+			}},
+		},
+	}
+
+	summary, err := summarizeDashboardStructure("abc", dashboard)
+	require.NoError(t, err)
+	assert.Equal(t, &DashboardSummary{
+		UID:         "abc",
+		Title:       "API",
+		Tags:        []string{"api"},
+		FolderUID:   "folder",
+		FolderTitle: "Folder",
+		Version:     7,
+		Variables: []DashboardVariable{
+			{Name: "datasource", Type: "datasource", Query: "prometheus", Current: "Prometheus"},
+			{Name: "job", Type: "query", Query: "label_values(up, job)", Datasource: &datasourceInfo{UID: "$datasource", Type: "prometheus"}, Current: []any{"api", "web"}, Multi: true, IncludeAll: true},
+		},
+		Panels: []DashboardPanel{{ID: 1, Title: "Overview", Type: "text"}},
+		Rows: []DashboardRow{
+			{ID: 2, Title: "Traffic", Panels: []DashboardPanel{{ID: 3, Title: "Requests", Type: "timeseries", DatasourceUIDs: []string{"$datasource", "loki"}}}},
+			{ID: 4, Title: "Details", Collapsed: true, Panels: []DashboardPanel{{ID: 5, Title: "Logs", Type: "logs", DatasourceUIDs: []string{"Loki"}}}},
+		},
+	}, summary)
+}
+
+func TestDeleteDashboardByUID(t *testing.T) {
+	var deleted []string
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/dashboards/uid/missing": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Dashboard not found"}`))
+		},
+		"DELETE /api/dashboards/uid/test": func(w http.ResponseWriter, r *http.Request) {
+			deleted = append(deleted, "test")
+			_, _ = w.Write([]byte(`{"title":"Test","message":"Dashboard Test deleted","id":1}`))
+		},
+		"GET /api/dashboards/uid/test": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"dashboard": map[string]any{"uid": "test", "title": "Test", "panels": []any{map[string]any{"id": 1, "title": "Requests"}}},
+				"meta":      map[string]any{"folderTitle": "Scratch", "url": "/d/test/test", "version": 2},
+			})
+		},
+	})
+
+	ctx := fakeGrafanaContext(server)
+
+	var token string
+	t.Run("unconfirmed", func(t *testing.T) {
+		result, err := deleteDashboardByUID(ctx, DeleteDashboardByUIDParams{UID: "test"})
+		require.NoError(t, err)
+		assert.False(t, result.Deleted)
+		assert.Equal(t, "Test", result.Dashboard.Title)
+		assert.Equal(t, "Scratch", result.Dashboard.FolderTitle)
+		require.NotNil(t, result.Confirmation)
+		assert.Equal(t, "The dashboard was not deleted. Check with the user that this is the dashboard to delete, then call again with the confirmation token.", result.Confirmation.Message)
+		assert.Contains(t, result.Confirmation.Impact[0], `"Test" (version 2) in the folder "Scratch", with its 1 panels`)
+		token = result.Confirmation.Token
+		assert.Empty(t, deleted)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		_, err := deleteDashboardByUID(ctx, DeleteDashboardByUIDParams{UID: "test", ConfirmationToken: "1.abc"})
+		assert.ErrorIs(t, err, mcpgrafana.ErrInvalidConfirmationToken)
+		assert.Empty(t, deleted)
+	})
+
+	t.Run("confirmed", func(t *testing.T) {
+		result, err := deleteDashboardByUID(ctx, DeleteDashboardByUIDParams{UID: "test", ConfirmationToken: token})
+		require.NoError(t, err)
+		assert.True(t, result.Deleted)
+		assert.Equal(t, int64(2), result.Dashboard.Version)
+		assert.Equal(t, []string{"test"}, deleted)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := deleteDashboardByUID(ctx, DeleteDashboardByUIDParams{UID: "missing", ConfirmationToken: token})
+		assert.ErrorIs(t, err, errNotFound)
+	})
+}
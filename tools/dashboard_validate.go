@@ -0,0 +1,342 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// latestDashboardSchemaVersion is the latest schema version of dashboards
+// known to the validation. Grafana migrates dashboards with older versions
+// when it loads them.
+const latestDashboardSchemaVersion = 41
+
+// dashboardUIDPattern matches the UIDs Grafana accepts for dashboards.
+var dashboardUIDPattern = regexp.MustCompile(`^[a-zA-Z0-9\-_]{1,40}$`)
+
+// builtinDatasourceUIDs are the UIDs of the special datasources of Grafana,
+// which are not listed with the other datasources.
+var builtinDatasourceUIDs = []string{"-- Mixed --", "-- Grafana --", "grafana", "-- Dashboard --", "__expr__"}
+
+type ValidateDashboardJSONParams struct {
+	Dashboard map[string]any `json:"dashboard" jsonschema:"required,description=The dashboard JSON to validate\\, as it would be passed to grafana_update_dashboard"`
+	// CheckDatasources is a pointer so that it can default to true.
+	CheckDatasources *bool `json:"checkDatasources,omitempty" jsonschema:"description=Whether to check that the datasources referenced by panels exist in Grafana (default true)"`
+}
+
+// DashboardIssue is a problem found in a dashboard, at a path such as
+// panels[1].targets[0].datasource.
+type DashboardIssue struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// DashboardValidation is the result of validating a dashboard. Errors would
+// make saving the dashboard fail or break it, warnings are likely mistakes.
+type DashboardValidation struct {
+	Valid    bool             `json:"valid"`
+	Errors   []DashboardIssue `json:"errors"`
+	Warnings []DashboardIssue `json:"warnings"`
+}
+
+// dashboardValidator collects the issues of a dashboard.
+type dashboardValidator struct {
+	result DashboardValidation
+	// variables are the names of the template variables of the dashboard.
+	variables []string
+	// datasources are the datasources of Grafana by UID, or nil if they
+	// are not checked.
+	datasources map[string]dataSourceSummary
+	// panelIDs are the paths of the panels by ID, to find duplicates.
+	panelIDs map[int]string
+}
+
+func (v *dashboardValidator) errorf(path, format string, args ...any) {
+	v.result.Errors = append(v.result.Errors, DashboardIssue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *dashboardValidator) warnf(path, format string, args ...any) {
+	v.result.Warnings = append(v.result.Warnings, DashboardIssue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// validateTop checks the top-level fields of the dashboard.
+func (v *dashboardValidator) validateTop(db map[string]any) {
+	if title, ok := db["title"].(string); !ok || title == "" {
+		v.errorf("title", "the dashboard must have a non-empty title")
+	}
+	if uid, ok := db["uid"]; ok && uid != nil {
+		s, isString := uid.(string)
+		switch {
+		case !isString:
+			v.errorf("uid", "the UID must be a string")
+		case s != "" && !dashboardUIDPattern.MatchString(s):
+			v.errorf("uid", "the UID %q must have at most 40 letters, digits, '-' and '_'", s)
+		}
+	}
+	if id, ok := db["id"]; ok && id != nil {
+		v.warnf("id", "the dashboard has an ID; set it to null when creating a dashboard or copying one from another instance, or the save fails with a not found error")
+	}
+	switch version := db["schemaVersion"].(type) {
+	case nil:
+		v.warnf("schemaVersion", "the dashboard has no schemaVersion, so Grafana runs all migrations on it, which can change panels; set it to %d", latestDashboardSchemaVersion)
+	case float64:
+		if version > latestDashboardSchemaVersion {
+			v.warnf("schemaVersion", "schemaVersion %v is newer than the latest known version %d", version, latestDashboardSchemaVersion)
+		}
+	default:
+		v.errorf("schemaVersion", "schemaVersion must be a number")
+	}
+	if _, ok := db["rows"]; ok {
+		v.warnf("rows", "rows is the layout of dashboards older than schemaVersion 16; use panels with gridPos and row panels instead")
+	}
+	if tags, ok := db["tags"]; ok && tags != nil {
+		list, ok := tags.([]any)
+		if !ok || slices.ContainsFunc(list, func(t any) bool { _, ok := t.(string); return !ok }) {
+			v.errorf("tags", "tags must be an array of strings")
+		}
+	}
+	if tr, ok := db["time"]; ok && tr != nil {
+		m, ok := tr.(map[string]any)
+		_, fromOK := m["from"].(string)
+		_, toOK := m["to"].(string)
+		if !ok || !fromOK || !toOK {
+			v.errorf("time", "time must be an object with from and to strings, e.g. now-6h and now")
+		}
+	}
+}
+
+// validateTemplating checks the template variables and collects their
+// names.
+func (v *dashboardValidator) validateTemplating(db map[string]any) {
+	templating, ok := db["templating"]
+	if !ok || templating == nil {
+		return
+	}
+	t, ok := templating.(map[string]any)
+	list, listOK := t["list"].([]any)
+	if !ok || (t["list"] != nil && !listOK) {
+		v.errorf("templating", "templating must be an object with a list of variables")
+		return
+	}
+	for i, item := range list {
+		path := fmt.Sprintf("templating.list[%d]", i)
+		variable, ok := item.(map[string]any)
+		if !ok {
+			v.errorf(path, "variables must be objects")
+			continue
+		}
+		name, _ := variable["name"].(string)
+		if name == "" {
+			v.errorf(path, "the variable must have a name")
+			continue
+		}
+		if slices.Contains(v.variables, name) {
+			v.errorf(path, "the variable name %q is used by another variable", name)
+		}
+		v.variables = append(v.variables, name)
+		if typ, _ := variable["type"].(string); typ == "" {
+			v.errorf(path, "the variable %s must have a type, e.g. query, custom or datasource", name)
+		}
+		if typ, _ := variable["type"].(string); typ == "query" && variable["datasource"] != nil {
+			v.validateDatasource(path+".datasource", variable["datasource"])
+		}
+	}
+}
+
+// validateDatasource checks a datasource reference of a panel, target or
+// variable.
+func (v *dashboardValidator) validateDatasource(path string, ref any) {
+	var uid, dsType string
+	switch ds := ref.(type) {
+	case nil:
+		return
+	case string:
+		v.warnf(path, "the datasource is referenced by name (%q), as in old dashboards; use an object with uid and type", ds)
+		return
+	case map[string]any:
+		uid, _ = ds["uid"].(string)
+		dsType, _ = ds["type"].(string)
+	default:
+		v.errorf(path, "the datasource must be an object with uid and type")
+		return
+	}
+	if uid == "" || slices.Contains(builtinDatasourceUIDs, uid) {
+		return
+	}
+	if m := variableReferencePattern.FindStringSubmatch(uid); m != nil {
+		name := cmp.Or(m[1], m[2], m[4])
+		if !slices.Contains(v.variables, name) && name != "__all" {
+			v.errorf(path, "the datasource references the variable $%s, which is not defined in templating", name)
+		}
+		return
+	}
+	if v.datasources == nil {
+		return
+	}
+	ds, ok := v.datasources[uid]
+	if !ok {
+		v.errorf(path, "no datasource with UID %q exists in Grafana", uid)
+		return
+	}
+	if dsType != "" && dsType != ds.Type {
+		v.warnf(path, "the datasource %s has type %s, not %s", uid, ds.Type, dsType)
+	}
+}
+
+// validatePanels checks the panels of the dashboard or of a row.
+func (v *dashboardValidator) validatePanels(path string, value any, inRow bool) {
+	panels, ok := value.([]any)
+	if !ok {
+		v.errorf(path, "panels must be an array")
+		return
+	}
+	for i, item := range panels {
+		panelPath := fmt.Sprintf("%s[%d]", path, i)
+		panel, ok := item.(map[string]any)
+		if !ok {
+			v.errorf(panelPath, "panels must be objects")
+			continue
+		}
+		switch id := panel["id"].(type) {
+		case float64:
+			if other, ok := v.panelIDs[int(id)]; ok {
+				v.errorf(panelPath, "the panel ID %d is also used by %s; panel IDs must be unique", int(id), other)
+			} else {
+				v.panelIDs[int(id)] = panelPath
+			}
+		case nil:
+			v.warnf(panelPath, "the panel has no ID; Grafana assigns one, but links and alert rules to the panel need a stable ID")
+		default:
+			v.errorf(panelPath, "the panel ID must be a number")
+		}
+		panelType, _ := panel["type"].(string)
+		if panelType == "" {
+			v.errorf(panelPath, "the panel must have a type, e.g. timeseries, stat or row")
+		}
+		if panel["libraryPanel"] != nil {
+			continue
+		}
+		v.validateGridPos(panelPath, panel["gridPos"])
+		if panelType == "row" {
+			if inRow {
+				v.errorf(panelPath, "rows cannot be nested")
+			}
+			if children, ok := panel["panels"]; ok {
+				v.validatePanels(panelPath+".panels", children, true)
+				if collapsed, _ := panel["collapsed"].(bool); !collapsed {
+					if list, _ := children.([]any); len(list) > 0 {
+						v.warnf(panelPath, "the panels of an expanded row must follow the row in the dashboard's panels; those of the row itself are only shown when the row is collapsed")
+					}
+				}
+			}
+			continue
+		}
+		v.validateDatasource(panelPath+".datasource", panel["datasource"])
+		v.validateTargets(panelPath, panel)
+	}
+}
+
+// validateGridPos checks the position of a panel on the 24 column grid.
+func (v *dashboardValidator) validateGridPos(path string, value any) {
+	if value == nil {
+		v.warnf(path+".gridPos", "the panel has no gridPos, so Grafana places it with a default size")
+		return
+	}
+	pos, ok := value.(map[string]any)
+	if !ok {
+		v.errorf(path+".gridPos", "gridPos must be an object with x, y, w and h")
+		return
+	}
+	for _, key := range []string{"x", "y", "w", "h"} {
+		if n, ok := pos[key].(float64); !ok || n < 0 {
+			v.errorf(path+".gridPos", "gridPos.%s must be a non-negative number", key)
+			return
+		}
+	}
+	if x, w := pos["x"].(float64), pos["w"].(float64); x+w > 24 {
+		v.errorf(path+".gridPos", "the panel is wider than the grid: x %v plus w %v is more than 24 columns", x, w)
+	}
+}
+
+// validateTargets checks the queries of a panel.
+func (v *dashboardValidator) validateTargets(path string, panel map[string]any) {
+	value, ok := panel["targets"]
+	if !ok || value == nil {
+		return
+	}
+	targets, ok := value.([]any)
+	if !ok {
+		v.errorf(path+".targets", "targets must be an array")
+		return
+	}
+	mixed := false
+	if ds, ok := panel["datasource"].(map[string]any); ok && ds["uid"] == "-- Mixed --" {
+		mixed = true
+	}
+	var refIDs []string
+	for i, item := range targets {
+		targetPath := fmt.Sprintf("%s.targets[%d]", path, i)
+		target, ok := item.(map[string]any)
+		if !ok {
+			v.errorf(targetPath, "targets must be objects")
+			continue
+		}
+		if refID, _ := target["refId"].(string); refID != "" {
+			if slices.Contains(refIDs, refID) {
+				v.errorf(targetPath, "the refId %q is used by another target of the panel", refID)
+			}
+			refIDs = append(refIDs, refID)
+		}
+		if mixed && target["datasource"] == nil {
+			v.errorf(targetPath+".datasource", "targets of panels with mixed datasources must have their own datasource")
+		}
+		v.validateDatasource(targetPath+".datasource", target["datasource"])
+	}
+}
+
+func validateDashboardJSON(ctx context.Context, args ValidateDashboardJSONParams) (*DashboardValidation, error) {
+	if args.Dashboard == nil {
+		return nil, fmt.Errorf("dashboard is required")
+	}
+	v := &dashboardValidator{
+		result:   DashboardValidation{Errors: []DashboardIssue{}, Warnings: []DashboardIssue{}},
+		panelIDs: map[int]string{},
+	}
+	if args.CheckDatasources == nil || *args.CheckDatasources {
+		datasources, err := listDatasources(ctx, ListDatasourcesParams{})
+		if err != nil {
+			v.warnf("", "the datasources were not checked, because they could not be listed: %v", err)
+		} else {
+			v.datasources = make(map[string]dataSourceSummary, len(datasources))
+			for _, ds := range datasources {
+				v.datasources[ds.UID] = ds
+			}
+		}
+	}
+	if _, ok := args.Dashboard["dashboard"].(map[string]any); ok && args.Dashboard["title"] == nil {
+		v.errorf("dashboard", "the payload wraps the dashboard in a dashboard field; pass the dashboard model itself")
+	} else {
+		v.validateTop(args.Dashboard)
+		v.validateTemplating(args.Dashboard)
+		if panels, ok := args.Dashboard["panels"]; ok && panels != nil {
+			v.validatePanels("panels", panels, false)
+		}
+	}
+	v.result.Valid = len(v.result.Errors) == 0
+	return &v.result, nil
+}
+
+var ValidateDashboardJSON = mcpgrafana.MustTool(
+	"grafana_validate_dashboard_json",
+	"Validate a dashboard JSON before saving it with `grafana_update_dashboard`, to fix mistakes which make the save fail with opaque API errors or break the dashboard. Checks the required fields (title, UID format, schemaVersion), duplicate panel IDs, the grid positions of panels, rows, duplicate target refIds and variable names, and that the datasources referenced by panels, targets and variables exist in Grafana or are defined template variables. Returns the errors, which must be fixed, and warnings, each with the path of the offending field. Nothing is saved.",
+	validateDashboardJSON,
+	mcp.WithTitleAnnotation("Validate dashboard JSON"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
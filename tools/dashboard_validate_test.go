@@ -0,0 +1,89 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// issuePaths returns the paths of issues.
+func issuePaths(issues []DashboardIssue) []string {
+	paths := make([]string, len(issues))
+	for i, issue := range issues {
+		paths[i] = issue.Path
+	}
+	return paths
+}
+
+func TestValidateDashboardJSON(t *testing.T) {
+	ctx := newFakeGrafanaContext(t, fakeRoutes{
+		"/api/datasources": jsonBody(`[{"id": 1, "uid": "metrics", "name": "Metrics", "type": "prometheus", "isDefault": true}]`),
+	})
+
+	validate := func(t *testing.T, dashboard string) *DashboardValidation {
+		t.Helper()
+		var db map[string]any
+		require.NoError(t, json.Unmarshal([]byte(dashboard), &db))
+		result, err := validateDashboardJSON(ctx, ValidateDashboardJSONParams{Dashboard: db})
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		result := validate(t, `{
+			"title": "Service", "uid": "service", "schemaVersion": 39,
+			"templating": {"list": [{"name": "ds", "type": "datasource", "query": "prometheus"}]},
+			"panels": [
+				{"id": 1, "type": "timeseries", "gridPos": {"x": 0, "y": 0, "w": 12, "h": 8},
+				 "datasource": {"type": "prometheus", "uid": "${ds}"},
+				 "targets": [{"refId": "A", "expr": "up"}, {"refId": "B", "datasource": {"type": "prometheus", "uid": "metrics"}}]},
+				{"id": 2, "type": "row", "collapsed": true, "gridPos": {"x": 0, "y": 8, "w": 24, "h": 1}, "panels": [
+					{"id": 3, "type": "stat", "gridPos": {"x": 0, "y": 9, "w": 6, "h": 4}, "datasource": {"uid": "metrics"}}
+				]}
+			]
+		}`)
+		assert.True(t, result.Valid)
+		assert.Empty(t, result.Errors)
+		assert.Empty(t, result.Warnings)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		result := validate(t, `{
+			"uid": "not a valid uid", "id": 12,
+			"templating": {"list": [{"name": "env", "type": "custom"}, {"name": "env", "type": "custom"}]},
+			"panels": [
+				{"id": 1, "type": "timeseries", "gridPos": {"x": 18, "y": 0, "w": 12, "h": 8},
+				 "datasource": {"type": "loki", "uid": "metrics"},
+				 "targets": [{"refId": "A"}, {"refId": "A"}]},
+				{"id": 1, "gridPos": {"x": 0, "y": 8, "w": 12, "h": 8}, "datasource": {"uid": "$cluster"}},
+				{"id": 4, "type": "table", "gridPos": {"x": 0, "y": 16, "w": 12, "h": 8},
+				 "datasource": {"uid": "-- Mixed --"}, "targets": [{"refId": "A", "datasource": {"uid": "missing"}}, {"refId": "B"}]}
+			]
+		}`)
+		assert.False(t, result.Valid)
+		assert.Equal(t, []string{
+			"title",
+			"uid",
+			"templating.list[1]",
+			"panels[0].gridPos",
+			"panels[0].targets[1]",
+			"panels[1]",
+			"panels[1]",
+			"panels[1].datasource",
+			"panels[2].targets[0].datasource",
+			"panels[2].targets[1].datasource",
+		}, issuePaths(result.Errors))
+		assert.Equal(t, []string{"id", "schemaVersion", "panels[0].datasource"}, issuePaths(result.Warnings))
+	})
+
+	t.Run("wrapped", func(t *testing.T) {
+		result := validate(t, `{"dashboard": {"title": "Service"}, "overwrite": true}`)
+		assert.False(t, result.Valid)
+		assert.Equal(t, []string{"dashboard"}, issuePaths(result.Errors))
+	})
+}
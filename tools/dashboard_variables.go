@@ -0,0 +1,644 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/common/model"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	// DefaultDashboardVariableOptionsLimit is the default maximum number of
+	// options returned for each variable.
+	DefaultDashboardVariableOptionsLimit = 100
+
+	// allValue is the value of variables set to all of their options.
+	allValue = "$__all"
+)
+
+var (
+	// variableReferencePattern matches references to template variables in
+	// the $var, ${var}, ${var:format} and [[var]] syntaxes.
+	variableReferencePattern = regexp.MustCompile(`\$(\w+)|\$\{(\w+)(?::(\w+))?\}|\[\[(\w+)(?::(\w+))?\]\]`)
+
+	// Functions of the queries of Prometheus and Loki query variables.
+	labelNamesFunction  = regexp.MustCompile(`^label_names\(\s*(.*?)\s*\)$`)
+	labelValuesFunction = regexp.MustCompile(`^label_values\(\s*(?:(.*?)\s*,\s*)?([a-zA-Z_][a-zA-Z0-9_.]*)\s*\)$`)
+	metricsFunction     = regexp.MustCompile(`^metrics\(\s*(.*?)\s*\)$`)
+	queryResultFunction = regexp.MustCompile(`^query_result\(\s*(.*?)\s*\)$`)
+)
+
+type ResolveDashboardVariablesParams struct {
+	DashboardUID       string            `json:"dashboardUid" jsonschema:"required,description=The UID of the dashboard"`
+	Values             map[string]string `json:"values,omitempty" jsonschema:"description=Optionally\\, values of variables to use instead of their current values\\, by variable name. Separate multiple values with commas. Variables depending on them are resolved with these values"`
+	StartTime          string            `json:"startTime,omitempty" jsonschema:"description=Optionally\\, the start of the time range of the variable queries\\, in RFC3339 format or relative to now (e.g. 'now-6h'). Defaults to 'now-1h'"`
+	EndTime            string            `json:"endTime,omitempty" jsonschema:"description=Optionally\\, the end of the time range of the variable queries. Defaults to 'now'"`
+	Limit              int               `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of options returned for each variable (default 100)"`
+	InterpolateQueries bool              `json:"interpolateQueries,omitempty" jsonschema:"description=Optionally\\, also return the queries of the dashboard's panels with the variables replaced by their values\\, ready to be run"`
+}
+
+// ResolvedVariable is a template variable of a dashboard with its resolved
+// options and current values.
+type ResolvedVariable struct {
+	Name       string          `json:"name"`
+	Label      string          `json:"label,omitempty"`
+	Type       string          `json:"type"`
+	Query      string          `json:"query,omitempty"`
+	Datasource *datasourceInfo `json:"datasource,omitempty"`
+	Multi      bool            `json:"multi,omitempty"`
+	IncludeAll bool            `json:"includeAll,omitempty"`
+	// Current are the values the variable is set to, which are its options
+	// if it is set to all of them.
+	Current []string `json:"current"`
+	Options []string `json:"options,omitempty"`
+	// Truncated is set if the variable has more options than the limit.
+	Truncated bool `json:"truncated,omitempty"`
+	// Error is set if the options of the variable could not be resolved.
+	Error string `json:"error,omitempty"`
+}
+
+// DashboardVariables are the resolved template variables of a dashboard.
+type DashboardVariables struct {
+	Variables []ResolvedVariable `json:"variables"`
+	// Queries are the queries of the dashboard's panels with the variables
+	// replaced by their values, if requested.
+	Queries []panelQuery `json:"queries,omitempty"`
+}
+
+// variableResolver resolves the template variables of a dashboard in order,
+// so that variables can depend on the values of the previous ones.
+type variableResolver struct {
+	overrides map[string]string
+	from, to  time.Time
+	limit     int
+
+	// values are the values of the variables resolved so far, and of the
+	// built-in variables, by name.
+	values map[string][]string
+	// datasources are the datasources of Grafana, listed once.
+	datasources []dataSourceSummary
+}
+
+// builtinVariables returns the values of the built-in variables of Grafana
+// for a time range. The intervals are computed the way Grafana does for
+// Prometheus with the default scrape interval of 15s.
+func builtinVariables(from, to time.Time) map[string][]string {
+	rangeSeconds := int64(to.Sub(from).Seconds())
+	const scrapeInterval = 15
+	interval := max(rangeSeconds/1000, scrapeInterval)
+	rateInterval := max(interval+scrapeInterval, 4*scrapeInterval)
+	return map[string][]string{
+		"__from":          {strconv.FormatInt(from.UnixMilli(), 10)},
+		"__to":            {strconv.FormatInt(to.UnixMilli(), 10)},
+		"__range":         {fmt.Sprintf("%ds", rangeSeconds)},
+		"__range_s":       {strconv.FormatInt(rangeSeconds, 10)},
+		"__range_ms":      {strconv.FormatInt(rangeSeconds*1000, 10)},
+		"__interval":      {fmt.Sprintf("%ds", interval)},
+		"__interval_ms":   {strconv.FormatInt(interval*1000, 10)},
+		"__rate_interval": {fmt.Sprintf("%ds", rateInterval)},
+	}
+}
+
+// formatVariableValues formats the values of a variable for a query, using
+// the format of the reference if it has one. Multiple values default to the
+// regex format for Prometheus and Loki, and are separated by commas for
+// other datasources.
+func formatVariableValues(values []string, format, datasourceType string) string {
+	if format == "" {
+		if len(values) == 1 {
+			return values[0]
+		}
+		switch datasourceType {
+		case "prometheus", "loki":
+			format = "regex"
+		default:
+			format = "csv"
+		}
+	}
+	switch format {
+	case "regex":
+		escaped := make([]string, len(values))
+		for i, v := range values {
+			escaped[i] = regexp.QuoteMeta(v)
+		}
+		if len(escaped) == 1 {
+			return escaped[0]
+		}
+		return "(" + strings.Join(escaped, "|") + ")"
+	case "pipe":
+		return strings.Join(values, "|")
+	case "glob":
+		if len(values) == 1 {
+			return values[0]
+		}
+		return "{" + strings.Join(values, ",") + "}"
+	case "json":
+		data, _ := json.Marshal(values)
+		return string(data)
+	case "singlequote", "doublequote":
+		quote := "'"
+		if format == "doublequote" {
+			quote = `"`
+		}
+		quoted := make([]string, len(values))
+		for i, v := range values {
+			quoted[i] = quote + strings.ReplaceAll(v, quote, `\`+quote) + quote
+		}
+		return strings.Join(quoted, ",")
+	}
+	return strings.Join(values, ",")
+}
+
+// interpolate replaces the references to variables with known values in s.
+// References to unknown variables are kept.
+func (r *variableResolver) interpolate(s, datasourceType string) string {
+	return variableReferencePattern.ReplaceAllStringFunc(s, func(ref string) string {
+		m := variableReferencePattern.FindStringSubmatch(ref)
+		name := cmp.Or(m[1], m[2], m[4])
+		format := cmp.Or(m[3], m[5])
+		values, ok := r.values[name]
+		if !ok {
+			return ref
+		}
+		return formatVariableValues(values, format, datasourceType)
+	})
+}
+
+// listDatasources returns the datasources of Grafana, listing them once.
+func (r *variableResolver) listDatasources(ctx context.Context) ([]dataSourceSummary, error) {
+	if r.datasources != nil {
+		return r.datasources, nil
+	}
+	datasources, err := listDatasources(ctx, ListDatasourcesParams{})
+	if err != nil {
+		return nil, err
+	}
+	r.datasources = datasources
+	return datasources, nil
+}
+
+// lookupDatasource returns the datasource with the given UID or name, which
+// may reference variables, or the default datasource if ref is empty.
+func (r *variableResolver) lookupDatasource(ctx context.Context, ref string) (*dataSourceSummary, error) {
+	ref = r.interpolate(ref, "")
+	datasources, err := r.listDatasources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i, ds := range datasources {
+		isDefault := (ref == "" || ref == "default") && ds.IsDefault
+		if isDefault || (ref != "" && (ds.UID == ref || ds.Name == ref)) {
+			return &datasources[i], nil
+		}
+	}
+	if ref == "" {
+		return nil, fmt.Errorf("no default datasource")
+	}
+	return nil, fmt.Errorf("datasource %s not found", ref)
+}
+
+// variableDatasource returns the datasource referenced by the datasource
+// field of a variable or panel, which is either an object with a UID and a
+// type or, in older dashboards, a UID or name.
+func (r *variableResolver) variableDatasource(ctx context.Context, field any) (*dataSourceSummary, error) {
+	switch ds := field.(type) {
+	case map[string]any:
+		uid, _ := ds["uid"].(string)
+		return r.lookupDatasource(ctx, uid)
+	case string:
+		return r.lookupDatasource(ctx, ds)
+	}
+	return r.lookupDatasource(ctx, "")
+}
+
+// variableQuery returns the query of a variable, which is a string or, for
+// newer versions of some datasources, an object with the query.
+func variableQuery(variable map[string]any) (string, map[string]any) {
+	switch q := variable["query"].(type) {
+	case string:
+		return q, nil
+	case map[string]any:
+		s, _ := q["query"].(string)
+		return s, q
+	}
+	return "", nil
+}
+
+// resolvePrometheusQuery returns the options of a Prometheus query
+// variable.
+func (r *variableResolver) resolvePrometheusQuery(ctx context.Context, uid, query string) ([]string, error) {
+	client, err := promClientFromContext(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	var matchers []string
+	switch {
+	case labelNamesFunction.MatchString(query):
+		if m := labelNamesFunction.FindStringSubmatch(query); m[1] != "" {
+			matchers = []string{m[1]}
+		}
+		names, warnings, err := client.LabelNames(ctx, matchers, r.from, r.to)
+		if err != nil {
+			return nil, fmt.Errorf("listing label names: %w", err)
+		}
+		recordPrometheusWarnings(ctx, warnings)
+		return names, nil
+	case labelValuesFunction.MatchString(query):
+		m := labelValuesFunction.FindStringSubmatch(query)
+		if m[1] != "" {
+			matchers = []string{m[1]}
+		}
+		values, warnings, err := client.LabelValues(ctx, m[2], matchers, r.from, r.to)
+		if err != nil {
+			return nil, fmt.Errorf("listing label values: %w", err)
+		}
+		recordPrometheusWarnings(ctx, warnings)
+		return labelValueStrings(values), nil
+	case metricsFunction.MatchString(query):
+		pattern, err := regexp.Compile(metricsFunction.FindStringSubmatch(query)[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid metrics regex: %w", err)
+		}
+		values, warnings, err := client.LabelValues(ctx, model.MetricNameLabel, nil, r.from, r.to)
+		if err != nil {
+			return nil, fmt.Errorf("listing metric names: %w", err)
+		}
+		recordPrometheusWarnings(ctx, warnings)
+		var names []string
+		for _, name := range labelValueStrings(values) {
+			if pattern.MatchString(name) {
+				names = append(names, name)
+			}
+		}
+		return names, nil
+	case queryResultFunction.MatchString(query):
+		expr := queryResultFunction.FindStringSubmatch(query)[1]
+		value, warnings, err := client.Query(ctx, expr, r.to)
+		if err != nil {
+			return nil, fmt.Errorf("querying Prometheus: %w", err)
+		}
+		recordPrometheusWarnings(ctx, warnings)
+		var results []string
+		switch v := value.(type) {
+		case model.Vector:
+			for _, s := range v {
+				results = append(results, fmt.Sprintf("%s %s %d", s.Metric, s.Value, s.Timestamp.Time().UnixMilli()))
+			}
+		case *model.Scalar:
+			results = append(results, v.Value.String())
+		}
+		return results, nil
+	}
+	return nil, fmt.Errorf("unsupported Prometheus variable query %q: expected label_names(), label_values(), metrics() or query_result()", query)
+}
+
+func labelValueStrings(values model.LabelValues) []string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = string(v)
+	}
+	return s
+}
+
+// resolveLokiQuery returns the options of a Loki query variable, whose
+// query is either the label_names() or label_values() function or, for
+// newer versions, an object with the type of query, the label and the
+// stream selector.
+func (r *variableResolver) resolveLokiQuery(ctx context.Context, uid, query string, object map[string]any) ([]string, error) {
+	client, err := newLokiClient(ctx, uid)
+	if err != nil {
+		return nil, fmt.Errorf("creating Loki client: %w", err)
+	}
+	var label, stream string
+	labelNames := false
+	switch {
+	case object != nil && query == "":
+		// The type is 0 for label names and 1 for label values.
+		queryType, _ := object["type"].(float64)
+		labelNames = queryType == 0
+		label, _ = object["label"].(string)
+		stream, _ = object["stream"].(string)
+		stream = r.interpolate(stream, "loki")
+	case labelNamesFunction.MatchString(query):
+		labelNames = true
+	case labelValuesFunction.MatchString(query):
+		m := labelValuesFunction.FindStringSubmatch(query)
+		stream, label = m[1], m[2]
+	default:
+		return nil, fmt.Errorf("unsupported Loki variable query %q: expected label_names() or label_values()", query)
+	}
+
+	params := url.Values{}
+	params.Set("start", r.from.Format(time.RFC3339))
+	params.Set("end", r.to.Format(time.RFC3339))
+	if stream != "" {
+		params.Set("query", stream)
+	}
+	urlPath := "/loki/api/v1/labels"
+	if !labelNames {
+		if label == "" {
+			return nil, fmt.Errorf("the Loki variable query has no label")
+		}
+		urlPath = fmt.Sprintf("/loki/api/v1/label/%s/values", url.PathEscape(label))
+	}
+	var response LabelResponse
+	if err := client.makeRequest(ctx, "GET", urlPath, params, &response); err != nil {
+		return nil, err
+	}
+	if response.Status != "success" {
+		return nil, fmt.Errorf("Loki API returned unexpected status %q", response.Status)
+	}
+	return response.Data, nil
+}
+
+// filterOptions applies the regex of a variable to its options, keeping the
+// options matching it. Options are replaced by the named group "value" or
+// the first capturing group of the regex, if it has one.
+func filterOptions(options []string, regex string) ([]string, error) {
+	if regex == "" {
+		return options, nil
+	}
+	// Grafana writes regexes between slashes, optionally followed by flags.
+	if strings.HasPrefix(regex, "/") {
+		if end := strings.LastIndex(regex, "/"); end > 0 {
+			flags := regex[end+1:]
+			regex = regex[1:end]
+			if strings.Contains(flags, "i") {
+				regex = "(?i)" + regex
+			}
+		}
+	}
+	pattern, err := regexp.Compile(regex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex %q: %w", regex, err)
+	}
+	group := 0
+	if i := pattern.SubexpIndex("value"); i > 0 {
+		group = i
+	} else if pattern.NumSubexp() > 0 {
+		group = 1
+	}
+	var filtered []string
+	for _, option := range options {
+		m := pattern.FindStringSubmatch(option)
+		if m == nil {
+			continue
+		}
+		filtered = append(filtered, m[group])
+	}
+	return filtered, nil
+}
+
+// sortOptions sorts options by the sort order of a variable: 1 and 2 are
+// alphabetical, 3 and 4 numerical and 5 and 6 case-insensitive
+// alphabetical, with even numbers for descending orders.
+func sortOptions(options []string, order int) {
+	var compare func(a, b string) int
+	switch order {
+	case 1, 2:
+		compare = strings.Compare
+	case 3, 4:
+		compare = func(a, b string) int {
+			x, _ := strconv.ParseFloat(a, 64)
+			y, _ := strconv.ParseFloat(b, 64)
+			return cmp.Compare(x, y)
+		}
+	case 5, 6:
+		compare = func(a, b string) int { return strings.Compare(strings.ToLower(a), strings.ToLower(b)) }
+	default:
+		return
+	}
+	slices.SortStableFunc(options, compare)
+	if order%2 == 0 {
+		slices.Reverse(options)
+	}
+}
+
+// splitCustomOptions splits the query of a custom or interval variable into
+// its options. Options may be written "text : value", in which case the
+// value is used.
+func splitCustomOptions(query string) []string {
+	var options []string
+	for option := range strings.SplitSeq(query, ",") {
+		if _, value, ok := strings.Cut(option, " : "); ok {
+			option = value
+		}
+		if option = strings.TrimSpace(option); option != "" {
+			options = append(options, option)
+		}
+	}
+	return options
+}
+
+// resolveOptions returns the options of a variable. Datasource variables
+// are resolved to the UIDs of the datasources.
+func (r *variableResolver) resolveOptions(ctx context.Context, variable map[string]any, result *ResolvedVariable) ([]string, error) {
+	query, object := variableQuery(variable)
+	switch result.Type {
+	case "custom", "interval":
+		return splitCustomOptions(r.interpolate(query, "")), nil
+	case "constant", "textbox":
+		return []string{r.interpolate(query, "")}, nil
+	case "datasource":
+		datasources, err := r.listDatasources(ctx)
+		if err != nil {
+			return nil, err
+		}
+		regex, _ := variable["regex"].(string)
+		var names []string
+		for _, ds := range datasources {
+			if ds.Type == query {
+				names = append(names, ds.Name)
+			}
+		}
+		names, err = filterOptions(names, r.interpolate(regex, ""))
+		if err != nil {
+			return nil, err
+		}
+		var uids []string
+		for _, ds := range datasources {
+			if ds.Type == query && slices.Contains(names, ds.Name) {
+				uids = append(uids, ds.UID)
+			}
+		}
+		return uids, nil
+	case "query":
+		ds, err := r.variableDatasource(ctx, variable["datasource"])
+		if err != nil {
+			return nil, err
+		}
+		result.Datasource = &datasourceInfo{UID: ds.UID, Type: ds.Type}
+		query = r.interpolate(query, ds.Type)
+		var options []string
+		switch ds.Type {
+		case "prometheus":
+			options, err = r.resolvePrometheusQuery(ctx, ds.UID, query)
+		case "loki":
+			options, err = r.resolveLokiQuery(ctx, ds.UID, query, object)
+		default:
+			return nil, fmt.Errorf("resolving query variables of %s datasources is not supported", ds.Type)
+		}
+		if err != nil {
+			return nil, err
+		}
+		regex, _ := variable["regex"].(string)
+		options, err = filterOptions(options, r.interpolate(regex, ds.Type))
+		if err != nil {
+			return nil, err
+		}
+		order, _ := variable["sort"].(float64)
+		sortOptions(options, int(order))
+		return options, nil
+	}
+	return nil, fmt.Errorf("variables of type %s are not resolved", result.Type)
+}
+
+// currentValues returns the values a variable is set to, given its options.
+func (r *variableResolver) currentValues(ctx context.Context, variable map[string]any, result ResolvedVariable, options []string) []string {
+	var values []string
+	if override, ok := r.overrides[result.Name]; ok {
+		for v := range strings.SplitSeq(override, ",") {
+			values = append(values, strings.TrimSpace(v))
+		}
+	} else if current, ok := variable["current"].(map[string]any); ok {
+		switch v := current["value"].(type) {
+		case string:
+			values = []string{v}
+		case []any:
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					values = append(values, s)
+				}
+			}
+		}
+	}
+	if slices.Contains(values, allValue) {
+		if custom, _ := variable["allValue"].(string); custom != "" {
+			return []string{custom}
+		}
+		return options
+	}
+	if result.Type == "datasource" {
+		// Datasource variables of older dashboards are set to the names of
+		// datasources, or to default.
+		for i, v := range values {
+			if ds, err := r.lookupDatasource(ctx, v); err == nil && ds.Type == result.Query {
+				values[i] = ds.UID
+			}
+		}
+	}
+	if len(values) == 0 || len(values) == 1 && values[0] == "" {
+		// Like Grafana, variables without a value use their first option.
+		if len(options) == 0 {
+			return []string{}
+		}
+		return options[:1]
+	}
+	return values
+}
+
+func (r *variableResolver) resolve(ctx context.Context, variable map[string]any) ResolvedVariable {
+	result := ResolvedVariable{}
+	result.Name, _ = variable["name"].(string)
+	result.Label, _ = variable["label"].(string)
+	result.Type, _ = variable["type"].(string)
+	result.Query, _ = variableQuery(variable)
+	result.Multi, _ = variable["multi"].(bool)
+	result.IncludeAll, _ = variable["includeAll"].(bool)
+
+	options, err := r.resolveOptions(ctx, variable, &result)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	options = slices.Compact(options)
+	result.Current = r.currentValues(ctx, variable, result, options)
+	if len(options) > r.limit {
+		options = options[:r.limit]
+		result.Truncated = true
+	}
+	result.Options = options
+	r.values[result.Name] = result.Current
+	return result
+}
+
+func newVariableResolver(overrides map[string]string, from, to time.Time, limit int) *variableResolver {
+	if limit <= 0 {
+		limit = DefaultDashboardVariableOptionsLimit
+	}
+	return &variableResolver{
+		overrides: overrides,
+		from:      from,
+		to:        to,
+		limit:     limit,
+		values:    builtinVariables(from, to),
+	}
+}
+
+// resolveAll resolves the template variables of a dashboard in order.
+func (r *variableResolver) resolveAll(ctx context.Context, db map[string]any) []ResolvedVariable {
+	variables := []ResolvedVariable{}
+	if templating, ok := db["templating"].(map[string]any); ok {
+		list, _ := templating["list"].([]any)
+		for _, v := range list {
+			if variable, ok := v.(map[string]any); ok {
+				variables = append(variables, r.resolve(ctx, variable))
+			}
+		}
+	}
+	return variables
+}
+
+func resolveDashboardVariables(ctx context.Context, args ResolveDashboardVariablesParams) (*DashboardVariables, error) {
+	from, err := parseTime(cmp.Or(args.StartTime, "now-1h"))
+	if err != nil {
+		return nil, fmt.Errorf("parsing start time: %w", err)
+	}
+	to, err := parseTime(cmp.Or(args.EndTime, "now"))
+	if err != nil {
+		return nil, fmt.Errorf("parsing end time: %w", err)
+	}
+	dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: args.DashboardUID})
+	if err != nil {
+		return nil, classifyAPIError(err)
+	}
+	db, ok := dashboard.Dashboard.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("dashboard is not a JSON object")
+	}
+
+	r := newVariableResolver(args.Values, from, to, args.Limit)
+	result := &DashboardVariables{Variables: r.resolveAll(ctx, db)}
+
+	if args.InterpolateQueries {
+		queries, err := dashboardPanelQueries(db)
+		if err != nil {
+			return nil, err
+		}
+		for i, q := range queries {
+			queries[i].Datasource.UID = r.interpolate(q.Datasource.UID, "")
+			queries[i].Query = r.interpolate(q.Query, q.Datasource.Type)
+		}
+		result.Queries = queries
+	}
+	return result, nil
+}
+
+var ResolveDashboardVariables = mcpgrafana.MustTool(
+	"grafana_resolve_dashboard_variables",
+	"List the template variables of a dashboard and resolve their options and current values, by running the label_values(), label_names(), metrics() and query_result() queries of query variables against their Prometheus or Loki datasources. Variables are resolved in order, so that variables depending on previous ones use their values, which can be overridden with `values`. Datasource variables resolve to datasource UIDs. Set `interpolateQueries` to also get the panel queries of `grafana_get_dashboard_panel_queries` with variables such as `$job` and `$__rate_interval` replaced, so that they can be run with the query tools.",
+	resolveDashboardVariables,
+	mcp.WithTitleAnnotation("Resolve dashboard variables"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,130 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveDashboardVariables(t *testing.T) {
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/dashboards/uid/service": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"dashboard": map[string]any{
+					"uid": "service",
+					"templating": map[string]any{"list": []any{
+						map[string]any{"name": "ds", "type": "datasource", "query": "prometheus", "current": map[string]any{"value": "Metrics"}},
+						map[string]any{"name": "env", "type": "custom", "query": "Production : prod,staging", "current": map[string]any{"value": "staging"}},
+						map[string]any{
+							"name": "job", "type": "query", "multi": true, "includeAll": true,
+							"datasource": map[string]any{"type": "prometheus", "uid": "${ds}"},
+							"query":      map[string]any{"query": `label_values(up{env="$env"}, job)`, "refId": "A"},
+							"regex":      "/api-(.*)/",
+							"sort":       1,
+							"current":    map[string]any{"value": []any{"$__all"}},
+						},
+						map[string]any{"name": "app", "type": "query", "datasource": "Logs", "query": "label_values(app)"},
+						map[string]any{"name": "trace", "type": "query", "datasource": map[string]any{"uid": "traces"}, "query": "anything"},
+					}},
+					"panels": []any{
+						map[string]any{
+							"title":      "Requests",
+							"datasource": map[string]any{"type": "prometheus", "uid": "${ds}"},
+							"targets": []any{map[string]any{
+								"expr": `sum(rate(http_requests_total{job=~"$job", env="$env"}[$__rate_interval]))`,
+							}},
+						},
+					},
+				},
+			})
+		},
+		"/api/datasources": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode([]map[string]any{
+				{"id": 1, "uid": "metrics", "name": "Metrics", "type": "prometheus", "isDefault": true},
+				{"id": 2, "uid": "logs", "name": "Logs", "type": "loki"},
+				{"id": 3, "uid": "traces", "name": "Traces", "type": "tempo"},
+			})
+		},
+		"/api/datasources/uid/metrics": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": 1, "uid": "metrics", "name": "Metrics", "type": "prometheus"})
+		},
+		"/api/datasources/uid/logs": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": 2, "uid": "logs", "name": "Logs", "type": "loki"})
+		},
+		"/api/datasources/proxy/uid/metrics/api/v1/label/job/values": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, []string{`up{env="staging"}`}, r.URL.Query()["match[]"])
+			_ = json.NewEncoder(w).Encode(map[string]any{"status": "success", "data": []string{"worker", "api-orders", "api-checkout"}})
+		},
+		"/api/datasources/proxy/uid/logs/loki/api/v1/label/app/values": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{"status": "success", "data": []string{"checkout", "orders"}})
+		},
+	})
+
+	ctx := fakeGrafanaContext(server)
+
+	result, err := resolveDashboardVariables(ctx, ResolveDashboardVariablesParams{DashboardUID: "service", InterpolateQueries: true})
+	require.NoError(t, err)
+	require.Len(t, result.Variables, 5)
+
+	ds := result.Variables[0]
+	assert.Equal(t, []string{"metrics"}, ds.Options)
+	assert.Equal(t, []string{"metrics"}, ds.Current, "datasource names should resolve to UIDs")
+
+	env := result.Variables[1]
+	assert.Equal(t, []string{"prod", "staging"}, env.Options)
+	assert.Equal(t, []string{"staging"}, env.Current)
+
+	job := result.Variables[2]
+	assert.Empty(t, job.Error)
+	assert.Equal(t, &datasourceInfo{UID: "metrics", Type: "prometheus"}, job.Datasource)
+	assert.Equal(t, []string{"checkout", "orders"}, job.Options)
+	assert.Equal(t, []string{"checkout", "orders"}, job.Current, "all should select every option")
+
+	app := result.Variables[3]
+	assert.Empty(t, app.Error)
+	assert.Equal(t, []string{"checkout", "orders"}, app.Options)
+	assert.Equal(t, []string{"checkout"}, app.Current, "variables without a value should use their first option")
+
+	assert.Contains(t, result.Variables[4].Error, "tempo datasources is not supported")
+
+	require.Len(t, result.Queries, 1)
+	assert.Equal(t, "metrics", result.Queries[0].Datasource.UID)
+	assert.Equal(t, `sum(rate(http_requests_total{job=~"(checkout|orders)", env="staging"}[60s]))`, result.Queries[0].Query)
+
+	t.Run("overridden values", func(t *testing.T) {
+		result, err := resolveDashboardVariables(ctx, ResolveDashboardVariablesParams{
+			DashboardUID: "service",
+			Values:       map[string]string{"env": "staging", "job": "orders"},
+			Limit:        1,
+		})
+		require.NoError(t, err)
+		job := result.Variables[2]
+		assert.Equal(t, []string{"orders"}, job.Current)
+		assert.Equal(t, []string{"checkout"}, job.Options)
+		assert.True(t, job.Truncated)
+		assert.Empty(t, result.Queries)
+	})
+}
+
+func TestFormatVariableValues(t *testing.T) {
+	values := []string{"a.b", "c"}
+	for format, expected := range map[string]string{
+		"":            `(a\.b|c)`,
+		"regex":       `(a\.b|c)`,
+		"csv":         "a.b,c",
+		"pipe":        "a.b|c",
+		"glob":        "{a.b,c}",
+		"json":        `["a.b","c"]`,
+		"singlequote": "'a.b','c'",
+	} {
+		assert.Equal(t, expected, formatVariableValues(values, format, "prometheus"), format)
+	}
+	assert.Equal(t, "a.b,c", formatVariableValues(values, "", "mysql"))
+	assert.Equal(t, "a.b", formatVariableValues([]string{"a.b"}, "", "prometheus"))
+}
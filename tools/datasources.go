@@ -125,4 +125,11 @@ func AddDatasourceTools(mcp *server.MCPServer) {
 	ListDatasources.Register(mcp)
 	GetDatasourceByUID.Register(mcp)
 	GetDatasourceByName.Register(mcp)
+	ListSQLDatabases.Register(mcp)
+	ListSQLTables.Register(mcp)
+	ListSQLColumns.Register(mcp)
+	ListCloudMetricNamespaces.Register(mcp)
+	ListCloudMetrics.Register(mcp)
+	ListCloudDimensions.Register(mcp)
+	ListCloudResources.Register(mcp)
 }
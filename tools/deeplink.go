@@ -0,0 +1,203 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// Kinds of deep links.
+const (
+	deeplinkDashboard = "dashboard"
+	deeplinkPanel     = "panel"
+	deeplinkExplore   = "explore"
+)
+
+type GenerateDeeplinkParams struct {
+	Kind          string            `json:"kind" jsonschema:"required,enum=dashboard,enum=panel,enum=explore,description=The kind of link: a dashboard\\, a single panel of a dashboard\\, or Explore with a query"`
+	DashboardUID  string            `json:"dashboardUid,omitempty" jsonschema:"description=The UID of the dashboard. Required for dashboard and panel links"`
+	PanelID       int               `json:"panelId,omitempty" jsonschema:"description=The ID of the panel. Required for panel links"`
+	DatasourceUID string            `json:"datasourceUid,omitempty" jsonschema:"description=The UID of the datasource to query. Required for Explore links"`
+	Query         string            `json:"query,omitempty" jsonschema:"description=The query to fill in Explore\\, e.g. a PromQL or LogQL expression. Required for Explore links"`
+	StartTime     string            `json:"startTime,omitempty" jsonschema:"description=The start of the time range\\, in RFC3339 format or relative to now (e.g. 'now-6h'). Relative times are kept relative in the link"`
+	EndTime       string            `json:"endTime,omitempty" jsonschema:"description=The end of the time range\\, in RFC3339 format or relative to now (e.g. 'now')"`
+	Variables     map[string]string `json:"variables,omitempty" jsonschema:"description=Values of the dashboard's template variables by variable name\\, for dashboard and panel links"`
+}
+
+// Deeplink is a link to Grafana, with the name of what it shows.
+type Deeplink struct {
+	URL   string `json:"url"`
+	Title string `json:"title,omitempty"`
+}
+
+// deeplinkTime converts a time of the arguments to the format of Grafana
+// URLs: relative times such as "now-6h" are kept, and absolute ones are
+// converted to milliseconds since the epoch.
+func deeplinkTime(s string) (string, error) {
+	if s == "" || strings.HasPrefix(s, "now") {
+		return s, nil
+	}
+	t, err := parseTime(s)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(t.UnixMilli(), 10), nil
+}
+
+// deeplinkBase returns the URL of Grafana that links are relative to, with
+// the organization of the request.
+func deeplinkBase(ctx context.Context) (string, url.Values) {
+	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+	params := url.Values{}
+	if cfg.OrgID != 0 {
+		params.Set("orgId", strconv.FormatInt(cfg.OrgID, 10))
+	}
+	return strings.TrimRight(cfg.URL, "/"), params
+}
+
+// encodeDeeplinkParams encodes the parameters of a link with the template
+// variables last, sorted by name, which is the order Grafana uses.
+func encodeDeeplinkParams(params url.Values, variables map[string]string) string {
+	s := params.Encode()
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if s != "" {
+			s += "&"
+		}
+		s += url.QueryEscape("var-"+name) + "=" + url.QueryEscape(variables[name])
+	}
+	return s
+}
+
+func dashboardDeeplink(ctx context.Context, args GenerateDeeplinkParams, from, to string) (*Deeplink, error) {
+	if args.DashboardUID == "" {
+		return nil, fmt.Errorf("dashboardUid is required for %s links", args.Kind)
+	}
+	// The dashboard is fetched to check that it exists, and for its URL,
+	// which includes its slug and the sub path Grafana is served from.
+	dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: args.DashboardUID})
+	if err != nil {
+		return nil, classifyAPIError(err)
+	}
+	summary := summarizeDashboard(args.DashboardUID, dashboard)
+	base, params := deeplinkBase(ctx)
+	path := summary.URL
+	if path == "" {
+		path = "/d/" + url.PathEscape(args.DashboardUID)
+	}
+	// The dashboard URL contains the sub path, which the configured URL
+	// may contain as well.
+	if u, err := url.Parse(base); err == nil && u.Path != "" && strings.HasPrefix(path, u.Path+"/") {
+		path = strings.TrimPrefix(path, u.Path)
+	}
+	title := summary.Title
+	if args.Kind == deeplinkPanel {
+		if args.PanelID == 0 {
+			return nil, fmt.Errorf("panelId is required for panel links")
+		}
+		panels, err := timelinePanels(summary, []int{args.PanelID})
+		if err != nil {
+			return nil, err
+		}
+		params.Set("viewPanel", strconv.Itoa(args.PanelID))
+		title = fmt.Sprintf("%s / %s", summary.Title, panels[0].Title)
+	}
+	if from != "" {
+		params.Set("from", from)
+	}
+	if to != "" {
+		params.Set("to", to)
+	}
+	link := base + path
+	if query := encodeDeeplinkParams(params, args.Variables); query != "" {
+		link += "?" + query
+	}
+	return &Deeplink{URL: link, Title: title}, nil
+}
+
+// newDatasourceQuery returns a query of a datasource, in the field of its
+// target which holds the query text for its type.
+func newDatasourceQuery(ds *models.DataSource, refID, text string) map[string]any {
+	query := map[string]any{"refId": refID, "datasource": map[string]string{"type": ds.Type, "uid": ds.UID}}
+	switch ds.Type {
+	case "prometheus", "loki":
+		query["expr"] = text
+	case "tempo":
+		query["queryType"] = "traceql"
+		query["query"] = text
+	case "graphite":
+		query["target"] = text
+	case "elasticsearch":
+		query["query"] = text
+	default:
+		// SQL and most other datasources take the raw query text.
+		query["rawSql"] = text
+		query["rawQuery"] = true
+	}
+	return query
+}
+
+func exploreDeeplink(ctx context.Context, args GenerateDeeplinkParams, from, to string) (*Deeplink, error) {
+	if args.DatasourceUID == "" || args.Query == "" {
+		return nil, fmt.Errorf("datasourceUid and query are required for Explore links")
+	}
+	ds, err := getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: args.DatasourceUID})
+	if err != nil {
+		return nil, err
+	}
+	query := newDatasourceQuery(ds, "A", args.Query)
+	pane := map[string]any{
+		"datasource": ds.UID,
+		"queries":    []any{query},
+		"range":      map[string]string{"from": cmp.Or(from, "now-1h"), "to": cmp.Or(to, "now")},
+	}
+	panes, err := json.Marshal(map[string]any{"a": pane})
+	if err != nil {
+		return nil, err
+	}
+	base, params := deeplinkBase(ctx)
+	params.Set("schemaVersion", "1")
+	params.Set("panes", string(panes))
+	return &Deeplink{URL: base + "/explore?" + params.Encode(), Title: fmt.Sprintf("Explore %s", ds.Name)}, nil
+}
+
+func generateDeeplink(ctx context.Context, args GenerateDeeplinkParams) (*Deeplink, error) {
+	from, err := deeplinkTime(args.StartTime)
+	if err != nil {
+		return nil, fmt.Errorf("parsing start time: %w", err)
+	}
+	to, err := deeplinkTime(args.EndTime)
+	if err != nil {
+		return nil, fmt.Errorf("parsing end time: %w", err)
+	}
+	switch args.Kind {
+	case deeplinkDashboard, deeplinkPanel:
+		return dashboardDeeplink(ctx, args, from, to)
+	case deeplinkExplore:
+		return exploreDeeplink(ctx, args, from, to)
+	}
+	return nil, fmt.Errorf("invalid kind %q: must be dashboard, panel or explore", args.Kind)
+}
+
+var GenerateDeeplink = mcpgrafana.MustTool(
+	"grafana_generate_deeplink",
+	"Generate a working link to Grafana: to a dashboard with a time range and template variable values, to a single panel of a dashboard, or to Explore with a PromQL, LogQL or other query of a datasource filled in. The dashboard or datasource is looked up to check that it exists, and the link uses the URL of the configured Grafana instance. Always use this tool to give users links instead of writing Grafana URLs by hand.",
+	generateDeeplink,
+	mcp.WithTitleAnnotation("Generate Grafana link"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,87 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+func TestGenerateDeeplink(t *testing.T) {
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/dashboards/uid/checkout": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"dashboard": map[string]any{"uid": "checkout", "title": "Checkout", "panels": []any{
+					map[string]any{"id": 4, "title": "Errors", "type": "timeseries"},
+				}},
+				"meta": map[string]any{"url": "/d/checkout/checkout"},
+			})
+		},
+		"/api/datasources/uid/logs": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{"uid": "logs", "name": "Logs", "type": "loki"})
+		},
+	})
+
+	// Links don't repeat the trailing slash of the configured URL.
+	ctx := fakeGrafanaContext(server)
+	config := mcpgrafana.GrafanaConfigFromContext(ctx)
+	config.URL += "/"
+	ctx = mcpgrafana.WithGrafanaConfig(ctx, config)
+
+	t.Run("dashboard", func(t *testing.T) {
+		link, err := generateDeeplink(ctx, GenerateDeeplinkParams{
+			Kind:         deeplinkDashboard,
+			DashboardUID: "checkout",
+			StartTime:    "2025-01-06T09:00:00Z",
+			EndTime:      "now",
+			Variables:    map[string]string{"env": "prod"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, server.URL+"/d/checkout/checkout?from=1736154000000&to=now&var-env=prod", link.URL)
+		assert.Equal(t, "Checkout", link.Title)
+	})
+
+	t.Run("panel", func(t *testing.T) {
+		link, err := generateDeeplink(ctx, GenerateDeeplinkParams{Kind: deeplinkPanel, DashboardUID: "checkout", PanelID: 4, StartTime: "now-6h"})
+		require.NoError(t, err)
+		assert.Equal(t, server.URL+"/d/checkout/checkout?from=now-6h&viewPanel=4", link.URL)
+		assert.Equal(t, "Checkout / Errors", link.Title)
+
+		_, err = generateDeeplink(ctx, GenerateDeeplinkParams{Kind: deeplinkPanel, DashboardUID: "checkout", PanelID: 9})
+		assert.ErrorContains(t, err, "panel 9 not found")
+	})
+
+	t.Run("explore", func(t *testing.T) {
+		link, err := generateDeeplink(ctx, GenerateDeeplinkParams{Kind: deeplinkExplore, DatasourceUID: "logs", Query: `{app="checkout"} |= "error"`})
+		require.NoError(t, err)
+		u, err := url.Parse(link.URL)
+		require.NoError(t, err)
+		assert.Equal(t, "/explore", u.Path)
+		var panes map[string]struct {
+			Datasource string           `json:"datasource"`
+			Queries    []map[string]any `json:"queries"`
+			Range      map[string]string
+		}
+		require.NoError(t, json.Unmarshal([]byte(u.Query().Get("panes")), &panes))
+		require.Len(t, panes["a"].Queries, 1)
+		assert.Equal(t, "logs", panes["a"].Datasource)
+		assert.Equal(t, `{app="checkout"} |= "error"`, panes["a"].Queries[0]["expr"])
+		assert.Equal(t, map[string]string{"from": "now-1h", "to": "now"}, panes["a"].Range)
+		assert.Equal(t, "Explore Logs", link.Title)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := generateDeeplink(ctx, GenerateDeeplinkParams{Kind: deeplinkExplore, DatasourceUID: "logs"})
+		assert.Error(t, err)
+		_, err = generateDeeplink(ctx, GenerateDeeplinkParams{Kind: "alert"})
+		assert.ErrorContains(t, err, "invalid kind")
+	})
+}
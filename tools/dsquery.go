@@ -0,0 +1,174 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// dsQueryClient runs queries through Grafana's /api/ds/query endpoint and
+// fetches the resources of datasources, which supports backend datasources
+// without an HTTP API of their own, such as SQL databases and cloud
+// providers.
+type dsQueryClient struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+func newDSQueryClient(ctx context.Context) (*dsQueryClient, error) {
+	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+	httpClient, err := newGrafanaHTTPClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &dsQueryClient{
+		httpClient: httpClient,
+		baseURL:    strings.TrimRight(cfg.URL, "/"),
+	}, nil
+}
+
+// dataFrame is a data frame returned by /api/ds/query, with its values
+// stored column by column.
+type dataFrame struct {
+	Schema struct {
+		Name   string `json:"name"`
+		RefID  string `json:"refId"`
+		Fields []struct {
+			Name   string            `json:"name"`
+			Type   string            `json:"type"`
+			Labels map[string]string `json:"labels,omitempty"`
+		} `json:"fields"`
+		Meta struct {
+			// Notices are messages about the frame, such as the warnings
+			// of the datasource.
+			Notices []struct {
+				Severity string `json:"severity"`
+				Text     string `json:"text"`
+			} `json:"notices,omitempty"`
+		} `json:"meta"`
+	} `json:"schema"`
+	Data struct {
+		Values [][]any `json:"values"`
+		// Entities lists, for each field, the indices of the values which
+		// are special floats, encoded as null in Values.
+		Entities []*struct {
+			NaN    []int `json:"NaN,omitempty"`
+			Inf    []int `json:"Inf,omitempty"`
+			NegInf []int `json:"NegInf,omitempty"`
+		} `json:"entities,omitempty"`
+	} `json:"data"`
+}
+
+// rows returns the values of the frame row by row.
+func (f dataFrame) rows() [][]any {
+	if len(f.Data.Values) == 0 {
+		return nil
+	}
+	rows := make([][]any, len(f.Data.Values[0]))
+	for i := range rows {
+		rows[i] = make([]any, len(f.Data.Values))
+		for j, column := range f.Data.Values {
+			if i < len(column) {
+				rows[i][j] = column[i]
+			}
+		}
+	}
+	return rows
+}
+
+type dsQueryResult struct {
+	Status int         `json:"status"`
+	Error  string      `json:"error"`
+	Frames []dataFrame `json:"frames"`
+}
+
+// query runs a single query, which must include the datasource, over the
+// given time range, returning its frames.
+func (c *dsQueryClient) query(ctx context.Context, from, to string, query map[string]any) ([]dataFrame, error) {
+	query["refId"] = "A"
+	results, err := c.queries(ctx, from, to, []map[string]any{query})
+	if err != nil {
+		return nil, err
+	}
+	result := results["A"]
+	if result.Error != "" {
+		return nil, fmt.Errorf("query failed: %s", result.Error)
+	}
+	return result.Frames, nil
+}
+
+// queries runs several queries in one request, each with its own refId and
+// datasource, over the given time range, returning their results by refId.
+// Queries may reference the results of other queries, as server-side
+// expressions do. The errors of single queries are reported in their
+// results.
+func (c *dsQueryClient) queries(ctx context.Context, from, to string, queries []map[string]any) (map[string]dsQueryResult, error) {
+	body, err := json.Marshal(map[string]any{
+		"queries": queries,
+		"from":    from,
+		"to":      to,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshalling query: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/ds/query", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024*48)) // 48MB limit
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+	// Failed queries are reported with a 400 or 500 status and the error of
+	// each query in the results.
+	var response struct {
+		Results map[string]dsQueryResult `json:"results"`
+		Message string                   `json:"message"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil || (resp.StatusCode != http.StatusOK && len(response.Results) == 0) {
+		return nil, fmt.Errorf("query returned status code %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return response.Results, nil
+}
+
+// resource fetches a resource of a backend datasource, e.g. the metrics of a
+// cloud provider, unmarshalling the JSON response into v.
+func (c *dsQueryClient) resource(ctx context.Context, uid, path string, params url.Values, v any) error {
+	u := fmt.Sprintf("%s/api/datasources/uid/%s/resources/%s", c.baseURL, url.PathEscape(uid), strings.TrimPrefix(path, "/"))
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024*48)) // 48MB limit
+	if err != nil {
+		return fmt.Errorf("reading response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("datasource resource %s returned status code %d: %s", path, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("unmarshalling datasource resource %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,501 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	// DefaultElasticsearchLogLimit is the default number of documents
+	// returned by grafana_query_elasticsearch_logs.
+	DefaultElasticsearchLogLimit = 10
+	// MaxElasticsearchLogLimit is the maximum number of documents that can be
+	// requested.
+	MaxElasticsearchLogLimit = 100
+)
+
+// elasticsearchClient queries an Elasticsearch datasource through the
+// Grafana datasource proxy.
+type elasticsearchClient struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+func newElasticsearchClient(ctx context.Context, ds *models.DataSource) (*elasticsearchClient, error) {
+	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+	httpClient, err := newGrafanaHTTPClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &elasticsearchClient{
+		httpClient: httpClient,
+		baseURL:    fmt.Sprintf("%s/api/datasources/proxy/uid/%s", strings.TrimRight(cfg.URL, "/"), ds.UID),
+	}, nil
+}
+
+func (c *elasticsearchClient) do(ctx context.Context, method, path string, body []byte, v any) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-ndjson")
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024*48)) // 48MB limit
+	if err != nil {
+		return fmt.Errorf("reading response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Elasticsearch API returned status code %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("unmarshalling response: %w", err)
+	}
+	return nil
+}
+
+// search runs a search request against an index pattern. The Grafana proxy
+// only accepts POST requests to _msearch for Elasticsearch datasources, so a
+// single search is sent as a multi-search.
+func (c *elasticsearchClient) search(ctx context.Context, index string, request any) (*elasticsearchSearchResponse, error) {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	if err := enc.Encode(map[string]any{"index": index, "ignore_unavailable": true}); err != nil {
+		return nil, fmt.Errorf("marshalling search header: %w", err)
+	}
+	if err := enc.Encode(request); err != nil {
+		return nil, fmt.Errorf("marshalling search request: %w", err)
+	}
+	var response struct {
+		Responses []elasticsearchSearchResponse `json:"responses"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/_msearch", body.Bytes(), &response); err != nil {
+		return nil, err
+	}
+	if len(response.Responses) == 0 {
+		return nil, fmt.Errorf("Elasticsearch returned no search response")
+	}
+	r := &response.Responses[0]
+	if r.Error != nil {
+		reason := r.Error.Reason
+		for _, cause := range r.Error.RootCause {
+			if cause.Reason != "" && cause.Reason != reason {
+				reason += ": " + cause.Reason
+				break
+			}
+		}
+		return nil, fmt.Errorf("search failed: %s", reason)
+	}
+	return r, nil
+}
+
+// mapping fetches the field mappings of the indices matching a pattern,
+// keyed by index.
+func (c *elasticsearchClient) mapping(ctx context.Context, index string) (map[string]elasticsearchIndexMapping, error) {
+	var mappings map[string]elasticsearchIndexMapping
+	if err := c.do(ctx, http.MethodGet, "/"+url.PathEscape(index)+"/_mapping", nil, &mappings); err != nil {
+		return nil, err
+	}
+	return mappings, nil
+}
+
+type elasticsearchSearchResponse struct {
+	Hits struct {
+		Total elasticsearchTotal `json:"total"`
+		Hits  []struct {
+			Index  string         `json:"_index"`
+			ID     string         `json:"_id"`
+			Source map[string]any `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+	Error *struct {
+		Reason    string `json:"reason"`
+		RootCause []struct {
+			Reason string `json:"reason"`
+		} `json:"root_cause"`
+	} `json:"error"`
+}
+
+// elasticsearchTotal is the number of hits of a search, which is a number in
+// Elasticsearch 6 and an object with a relation since Elasticsearch 7.
+type elasticsearchTotal struct {
+	Value int `json:"value"`
+	// Relation is "gte" if the value is a lower bound.
+	Relation string `json:"relation"`
+}
+
+func (t *elasticsearchTotal) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '{' {
+		type total elasticsearchTotal
+		return json.Unmarshal(data, (*total)(t))
+	}
+	return json.Unmarshal(data, &t.Value)
+}
+
+// elasticsearchIndexMapping is the mapping of an index. Elasticsearch 6 and
+// earlier nest the properties under the name of a mapping type.
+type elasticsearchIndexMapping struct {
+	Mappings map[string]json.RawMessage `json:"mappings"`
+}
+
+type elasticsearchProperty struct {
+	Type       string                           `json:"type"`
+	Properties map[string]elasticsearchProperty `json:"properties"`
+	// Fields are the multi-fields of a field, e.g. message.keyword.
+	Fields map[string]elasticsearchProperty `json:"fields"`
+}
+
+// properties returns the top-level properties of an index mapping.
+func (m elasticsearchIndexMapping) properties() (map[string]elasticsearchProperty, error) {
+	var props map[string]elasticsearchProperty
+	if raw, ok := m.Mappings["properties"]; ok {
+		if err := json.Unmarshal(raw, &props); err != nil {
+			return nil, fmt.Errorf("unmarshalling mapping: %w", err)
+		}
+		return props, nil
+	}
+	props = map[string]elasticsearchProperty{}
+	for _, raw := range m.Mappings {
+		var typed struct {
+			Properties map[string]elasticsearchProperty `json:"properties"`
+		}
+		if err := json.Unmarshal(raw, &typed); err != nil {
+			continue
+		}
+		maps.Copy(props, typed.Properties)
+	}
+	return props, nil
+}
+
+// flattenProperties adds the fields of a mapping to fields with their dotted
+// names, as used in queries.
+func flattenProperties(prefix string, props map[string]elasticsearchProperty, fields map[string]string) {
+	for name, p := range props {
+		name = prefix + name
+		if len(p.Properties) > 0 {
+			flattenProperties(name+".", p.Properties, fields)
+		}
+		if p.Type != "" {
+			fields[name] = p.Type
+		} else if len(p.Properties) == 0 {
+			fields[name] = "object"
+		}
+		flattenProperties(name+".", p.Fields, fields)
+	}
+}
+
+// elasticsearchSettings are the settings of an Elasticsearch datasource used
+// to query its logs.
+type elasticsearchSettings struct {
+	index           string
+	timeField       string
+	logMessageField string
+	logLevelField   string
+}
+
+func parseElasticsearchSettings(ds *models.DataSource) elasticsearchSettings {
+	jsonData, _ := ds.JSONData.(map[string]any)
+	str := func(key string) string {
+		s, _ := jsonData[key].(string)
+		return s
+	}
+	s := elasticsearchSettings{
+		index:           str("index"),
+		timeField:       str("timeField"),
+		logMessageField: str("logMessageField"),
+		logLevelField:   str("logLevelField"),
+	}
+	if s.index == "" {
+		// Older versions of Grafana store the index in the database field.
+		s.index = ds.Database
+	}
+	if interval := str("interval"); interval != "" && interval != "none" {
+		s.index = wildcardIndexPattern(s.index)
+	}
+	if s.timeField == "" {
+		s.timeField = "@timestamp"
+	}
+	return s
+}
+
+// wildcardIndexPattern turns an index pattern with a date interval, such as
+// [logs-]YYYY.MM.DD, into a wildcard pattern matching every index, such as
+// logs-*. Text in brackets is literal.
+func wildcardIndexPattern(pattern string) string {
+	var b strings.Builder
+	literal, wildcard := false, false
+	for _, r := range pattern {
+		switch {
+		case r == '[':
+			literal = true
+		case r == ']':
+			literal = false
+		case literal:
+			b.WriteRune(r)
+			wildcard = false
+		case !wildcard:
+			b.WriteRune('*')
+			wildcard = true
+		}
+	}
+	return b.String()
+}
+
+// getElasticsearchDatasource fetches an Elasticsearch datasource, returning
+// a client for it and its settings, with the index overridden by index if
+// set.
+func getElasticsearchDatasource(ctx context.Context, uid, index string) (*elasticsearchClient, elasticsearchSettings, error) {
+	ds, err := getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: uid})
+	if err != nil {
+		return nil, elasticsearchSettings{}, err
+	}
+	if ds.Type != "elasticsearch" {
+		return nil, elasticsearchSettings{}, fmt.Errorf("datasource %s is a %s datasource, not an Elasticsearch datasource", uid, ds.Type)
+	}
+	settings := parseElasticsearchSettings(ds)
+	if index != "" {
+		settings.index = index
+	}
+	if settings.index == "" {
+		return nil, elasticsearchSettings{}, fmt.Errorf("datasource %s has no index pattern; set index", uid)
+	}
+	client, err := newElasticsearchClient(ctx, ds)
+	if err != nil {
+		return nil, elasticsearchSettings{}, fmt.Errorf("creating Elasticsearch client: %w", err)
+	}
+	return client, settings, nil
+}
+
+type QueryElasticsearchLogsParams struct {
+	DatasourceUID string   `json:"datasourceUid" jsonschema:"required,description=The UID of the Elasticsearch datasource"`
+	Query         string   `json:"query,omitempty" jsonschema:"description=A Lucene query string\\, e.g. 'level:error AND service.name:api'. Defaults to matching every document"`
+	DSL           string   `json:"dsl,omitempty" jsonschema:"description=Instead of query\\, an Elasticsearch Query DSL clause as JSON\\, such as a bool or term query"`
+	Index         string   `json:"index,omitempty" jsonschema:"description=Optionally\\, the index pattern to search instead of the datasource's"`
+	StartRFC3339  string   `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the query in RFC3339 format (defaults to 1 hour ago)"`
+	EndRFC3339    string   `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the query in RFC3339 format (defaults to now)"`
+	Limit         int      `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of documents to return (default 10\\, max 100)"`
+	Direction     string   `json:"direction,omitempty" jsonschema:"description=Optionally\\, the direction of the query: 'forward' (oldest first) or 'backward' (newest first\\, default)"`
+	Fields        []string `json:"fields,omitempty" jsonschema:"description=Optionally\\, the fields to return for each document. Defaults to every field"`
+}
+
+// ElasticsearchLogEntry is a document returned by an Elasticsearch log
+// query.
+type ElasticsearchLogEntry struct {
+	Timestamp string `json:"timestamp,omitempty"`
+	Index     string `json:"index"`
+	ID        string `json:"id"`
+	// Message and Level are the values of the datasource's log message and
+	// log level fields, if configured.
+	Message string `json:"message,omitempty"`
+	Level   string `json:"level,omitempty"`
+	// Fields are the other fields of the document, with the fields of nested
+	// objects flattened to dotted names.
+	Fields map[string]any `json:"fields,omitempty"`
+}
+
+type QueryElasticsearchLogsResult struct {
+	// Total is the number of matching documents, which is a lower bound if
+	// TotalIsLowerBound is set.
+	Total             int                     `json:"total"`
+	TotalIsLowerBound bool                    `json:"totalIsLowerBound,omitempty"`
+	Entries           []ElasticsearchLogEntry `json:"entries"`
+}
+
+// flattenDocument adds the fields of a document to fields with their dotted
+// names.
+func flattenDocument(prefix string, doc map[string]any, fields map[string]any) {
+	for k, v := range doc {
+		if nested, ok := v.(map[string]any); ok {
+			flattenDocument(prefix+k+".", nested, fields)
+			continue
+		}
+		fields[prefix+k] = v
+	}
+}
+
+func queryElasticsearchLogs(ctx context.Context, args QueryElasticsearchLogsParams) (*QueryElasticsearchLogsResult, error) {
+	if args.Query != "" && args.DSL != "" {
+		return nil, fmt.Errorf("query and dsl are mutually exclusive")
+	}
+	client, settings, err := getElasticsearchDatasource(ctx, args.DatasourceUID, args.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := args.Limit
+	if limit <= 0 {
+		limit = DefaultElasticsearchLogLimit
+	}
+	limit = min(limit, MaxElasticsearchLogLimit)
+	order := "desc"
+	switch args.Direction {
+	case "", "backward":
+	case "forward":
+		order = "asc"
+	default:
+		return nil, fmt.Errorf("invalid direction %q: must be 'forward' or 'backward'", args.Direction)
+	}
+
+	startRFC3339, endRFC3339 := getDefaultTimeRange(args.StartRFC3339, args.EndRFC3339)
+	start, err := time.Parse(time.RFC3339, startRFC3339)
+	if err != nil {
+		return nil, fmt.Errorf("parsing start time: %w", err)
+	}
+	end, err := time.Parse(time.RFC3339, endRFC3339)
+	if err != nil {
+		return nil, fmt.Errorf("parsing end time: %w", err)
+	}
+	filters := []any{map[string]any{"range": map[string]any{settings.timeField: map[string]any{
+		"gte":    start.UnixMilli(),
+		"lte":    end.UnixMilli(),
+		"format": "epoch_millis",
+	}}}}
+	switch {
+	case args.DSL != "":
+		var clause map[string]any
+		if err := json.Unmarshal([]byte(args.DSL), &clause); err != nil {
+			return nil, fmt.Errorf("parsing dsl: %w", err)
+		}
+		filters = append(filters, clause)
+	case args.Query != "" && args.Query != "*":
+		filters = append(filters, map[string]any{"query_string": map[string]any{"query": args.Query, "analyze_wildcard": true}})
+	}
+	request := map[string]any{
+		"size":             limit,
+		"track_total_hits": true,
+		"query":            map[string]any{"bool": map[string]any{"filter": filters}},
+		"sort":             []any{map[string]any{settings.timeField: map[string]any{"order": order, "unmapped_type": "boolean"}}},
+	}
+	if len(args.Fields) > 0 {
+		includes := append([]string{settings.timeField}, args.Fields...)
+		for _, f := range []string{settings.logMessageField, settings.logLevelField} {
+			if f != "" {
+				includes = append(includes, f)
+			}
+		}
+		request["_source"] = map[string]any{"includes": includes}
+	}
+
+	response, err := client.search(ctx, settings.index, request)
+	if err != nil {
+		return nil, err
+	}
+	result := &QueryElasticsearchLogsResult{
+		Total:             response.Hits.Total.Value,
+		TotalIsLowerBound: response.Hits.Total.Relation == "gte",
+		Entries:           make([]ElasticsearchLogEntry, 0, len(response.Hits.Hits)),
+	}
+	for _, hit := range response.Hits.Hits {
+		fields := map[string]any{}
+		flattenDocument("", hit.Source, fields)
+		entry := ElasticsearchLogEntry{Index: hit.Index, ID: hit.ID}
+		take := func(field string) string {
+			v, ok := fields[field]
+			if !ok || field == "" {
+				return ""
+			}
+			delete(fields, field)
+			return fmt.Sprint(v)
+		}
+		entry.Timestamp = take(settings.timeField)
+		entry.Message = take(settings.logMessageField)
+		entry.Level = take(settings.logLevelField)
+		if len(fields) > 0 {
+			entry.Fields = fields
+		}
+		result.Entries = append(result.Entries, entry)
+	}
+	return result, nil
+}
+
+var QueryElasticsearchLogs = mcpgrafana.MustTool(
+	"grafana_query_elasticsearch_logs",
+	"Search the logs of an Elasticsearch datasource with a Lucene query string or a Query DSL clause, over a time range of the datasource's time field (default: the last hour). Returns the matching documents newest first with their message and level (if the datasource configures log message and level fields) and their other fields, flattened to dotted names, together with the total number of matches. Use `grafana_list_elasticsearch_fields` to find the fields to query.",
+	queryElasticsearchLogs,
+	mcp.WithTitleAnnotation("Query Elasticsearch logs"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type ListElasticsearchFieldsParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the Elasticsearch datasource"`
+	Index         string `json:"index,omitempty" jsonschema:"description=Optionally\\, the index pattern to list the fields of instead of the datasource's"`
+	Type          string `json:"type,omitempty" jsonschema:"description=Optionally\\, only list fields of this type\\, e.g. 'keyword'"`
+}
+
+// ElasticsearchField is a field of the documents of an Elasticsearch index.
+type ElasticsearchField struct {
+	Name string `json:"name"`
+	// Type is the type of the field, or its types separated by commas if
+	// it has different types in different indices.
+	Type string `json:"type"`
+}
+
+func listElasticsearchFields(ctx context.Context, args ListElasticsearchFieldsParams) ([]ElasticsearchField, error) {
+	client, settings, err := getElasticsearchDatasource(ctx, args.DatasourceUID, args.Index)
+	if err != nil {
+		return nil, err
+	}
+	mappings, err := client.mapping(ctx, settings.index)
+	if err != nil {
+		return nil, err
+	}
+	types := map[string][]string{}
+	for _, m := range mappings {
+		props, err := m.properties()
+		if err != nil {
+			return nil, err
+		}
+		fields := map[string]string{}
+		flattenProperties("", props, fields)
+		for name, t := range fields {
+			if !slices.Contains(types[name], t) {
+				types[name] = append(types[name], t)
+			}
+		}
+	}
+	fields := make([]ElasticsearchField, 0, len(types))
+	for _, name := range slices.Sorted(maps.Keys(types)) {
+		t := types[name]
+		if args.Type != "" && !slices.Contains(t, args.Type) {
+			continue
+		}
+		slices.Sort(t)
+		fields = append(fields, ElasticsearchField{Name: name, Type: strings.Join(t, ",")})
+	}
+	return fields, nil
+}
+
+var ListElasticsearchFields = mcpgrafana.MustTool(
+	"grafana_list_elasticsearch_fields",
+	"List the fields of the documents of an Elasticsearch datasource's indices with their types, from the index mappings, including multi-fields such as `message.keyword`. Use this before writing queries for `grafana_query_elasticsearch_logs`: `keyword` fields match exact values, while `text` fields match words.",
+	listElasticsearchFields,
+	mcp.WithTitleAnnotation("List Elasticsearch fields"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+func AddElasticsearchTools(mcp *server.MCPServer) {
+	QueryElasticsearchLogs.Register(mcp)
+	ListElasticsearchFields.Register(mcp)
+}
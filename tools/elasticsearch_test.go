@@ -0,0 +1,135 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newElasticsearchTestContext(t *testing.T, search func(header, request map[string]any) string) context.Context {
+	return newFakeGrafanaContext(t, fakeRoutes{
+		"/api/datasources/uid/es": jsonBody(`{"uid": "es", "type": "elasticsearch", "jsonData": {
+				"index": "[logs-]YYYY.MM.DD", "interval": "Daily", "timeField": "@timestamp",
+				"logMessageField": "message", "logLevelField": "log.level"
+			}}`),
+		"/api/datasources/uid/prom": jsonBody(`{"uid": "prom", "type": "prometheus"}`),
+		"/api/datasources/proxy/uid/es/_msearch": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			var header, request map[string]any
+			scanner := bufio.NewScanner(r.Body)
+			for _, v := range []any{&header, &request} {
+				if !assert.True(t, scanner.Scan()) || !assert.NoError(t, json.Unmarshal(scanner.Bytes(), v)) {
+					http.Error(w, "invalid multi-search body", http.StatusBadRequest)
+					return
+				}
+			}
+			_, _ = w.Write([]byte(search(header, request)))
+		},
+		"/api/datasources/proxy/uid/es/logs-*/_mapping": jsonBody(`{
+				"logs-2025.01.05": {"mappings": {"properties": {
+					"@timestamp": {"type": "date"},
+					"message": {"type": "text", "fields": {"keyword": {"type": "keyword"}}},
+					"log": {"properties": {"level": {"type": "keyword"}}},
+					"status": {"type": "long"}
+				}}},
+				"logs-2025.01.06": {"mappings": {"properties": {
+					"status": {"type": "keyword"}
+				}}}
+			}`),
+	})
+}
+
+func TestQueryElasticsearchLogs(t *testing.T) {
+	var gotHeader, gotRequest map[string]any
+	ctx := newElasticsearchTestContext(t, func(header, request map[string]any) string {
+		gotHeader, gotRequest = header, request
+		return `{"responses": [{"hits": {"total": {"value": 42, "relation": "eq"}, "hits": [
+			{"_index": "logs-2025.01.06", "_id": "1", "_source": {
+				"@timestamp": "2025-01-06T00:00:00Z", "message": "db timeout", "log": {"level": "error"}, "service": {"name": "api"}
+			}}
+		]}}]}`
+	})
+
+	result, err := queryElasticsearchLogs(ctx, QueryElasticsearchLogsParams{
+		DatasourceUID: "es",
+		Query:         "log.level:error",
+		StartRFC3339:  "2025-01-06T00:00:00Z",
+		EndRFC3339:    "2025-01-06T01:00:00Z",
+		Direction:     "forward",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "logs-*", gotHeader["index"])
+	assert.Equal(t, float64(DefaultElasticsearchLogLimit), gotRequest["size"])
+	assert.Equal(t, []any{map[string]any{"@timestamp": map[string]any{"order": "asc", "unmapped_type": "boolean"}}}, gotRequest["sort"])
+	filters := gotRequest["query"].(map[string]any)["bool"].(map[string]any)["filter"].([]any)
+	require.Len(t, filters, 2)
+	assert.Equal(t, map[string]any{"@timestamp": map[string]any{"gte": float64(1736121600000), "lte": float64(1736125200000), "format": "epoch_millis"}}, filters[0].(map[string]any)["range"])
+	assert.Equal(t, "log.level:error", filters[1].(map[string]any)["query_string"].(map[string]any)["query"])
+
+	assert.Equal(t, &QueryElasticsearchLogsResult{
+		Total: 42,
+		Entries: []ElasticsearchLogEntry{{
+			Timestamp: "2025-01-06T00:00:00Z",
+			Index:     "logs-2025.01.06",
+			ID:        "1",
+			Message:   "db timeout",
+			Level:     "error",
+			Fields:    map[string]any{"service.name": "api"},
+		}},
+	}, result)
+
+	t.Run("dsl", func(t *testing.T) {
+		_, err := queryElasticsearchLogs(ctx, QueryElasticsearchLogsParams{DatasourceUID: "es", DSL: `{"term": {"status": 500}}`, Index: "errors"})
+		require.NoError(t, err)
+		assert.Equal(t, "errors", gotHeader["index"])
+		filters := gotRequest["query"].(map[string]any)["bool"].(map[string]any)["filter"].([]any)
+		assert.Equal(t, map[string]any{"term": map[string]any{"status": float64(500)}}, filters[1])
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := queryElasticsearchLogs(ctx, QueryElasticsearchLogsParams{DatasourceUID: "es", Query: "a", DSL: "{}"})
+		assert.Error(t, err)
+		_, err = queryElasticsearchLogs(ctx, QueryElasticsearchLogsParams{DatasourceUID: "prom"})
+		assert.ErrorContains(t, err, "not an Elasticsearch datasource")
+	})
+}
+
+func TestQueryElasticsearchLogsSearchError(t *testing.T) {
+	ctx := newElasticsearchTestContext(t, func(_, _ map[string]any) string {
+		return `{"responses": [{"error": {"reason": "all shards failed", "root_cause": [{"reason": "Failed to parse query [level:]"}]}, "status": 400}]}`
+	})
+	_, err := queryElasticsearchLogs(ctx, QueryElasticsearchLogsParams{DatasourceUID: "es", Query: "level:"})
+	assert.EqualError(t, err, "search failed: all shards failed: Failed to parse query [level:]")
+}
+
+func TestListElasticsearchFields(t *testing.T) {
+	ctx := newElasticsearchTestContext(t, nil)
+
+	fields, err := listElasticsearchFields(ctx, ListElasticsearchFieldsParams{DatasourceUID: "es"})
+	require.NoError(t, err)
+	assert.Equal(t, []ElasticsearchField{
+		{Name: "@timestamp", Type: "date"},
+		{Name: "log.level", Type: "keyword"},
+		{Name: "message", Type: "text"},
+		{Name: "message.keyword", Type: "keyword"},
+		{Name: "status", Type: "keyword,long"},
+	}, fields)
+
+	fields, err = listElasticsearchFields(ctx, ListElasticsearchFieldsParams{DatasourceUID: "es", Type: "long"})
+	require.NoError(t, err)
+	assert.Equal(t, []ElasticsearchField{{Name: "status", Type: "keyword,long"}}, fields)
+}
+
+func TestWildcardIndexPattern(t *testing.T) {
+	assert.Equal(t, "logs-*", wildcardIndexPattern("[logs-]YYYY.MM.DD"))
+	assert.Equal(t, "*-logs", wildcardIndexPattern("YYYY.MM.DD[-logs]"))
+	assert.Equal(t, "app-*-logs", wildcardIndexPattern("[app-]GGGG.WW[-logs]"))
+}
@@ -0,0 +1,73 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// fakeRoutes are the handlers of a fake Grafana by "METHOD /path", or by
+// "/path" for any method.
+type fakeRoutes map[string]http.HandlerFunc
+
+// jsonBody returns a handler responding with a fixed JSON body.
+func jsonBody(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+// newFakeGrafana returns a server answering requests with the matching
+// route, with a JSON content type unless the route sets another. Requests
+// without a route are answered with 404 Not Found and a Grafana error
+// message.
+func newFakeGrafana(t *testing.T, routes fakeRoutes) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, ok := routes[r.Method+" "+r.URL.Path]
+		if !ok {
+			handler, ok = routes[r.URL.Path]
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "not found"}`))
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newFakeGrafanaContext returns a context with the configuration and the
+// client of a fake Grafana answering requests with routes.
+func newFakeGrafanaContext(t *testing.T, routes fakeRoutes) context.Context {
+	return fakeGrafanaContext(newFakeGrafana(t, routes))
+}
+
+// fakeGrafanaContext returns a context with the configuration and the
+// client of a fake Grafana.
+func fakeGrafanaContext(server *httptest.Server) context.Context {
+	ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{URL: server.URL, APIKey: "test-api-key"})
+	return mcpgrafana.WithGrafanaClient(ctx, mcpgrafana.NewGrafanaClient(ctx, server.URL, "test-api-key"))
+}
+
+// decodeJSONBody decodes the JSON body of a request to a fake Grafana into
+// v. Handlers run outside of the test's goroutine, so a body which can't be
+// decoded fails the test with t.Errorf and is answered with 400 Bad Request,
+// and the handler should return when false is returned.
+func decodeJSONBody(t *testing.T, w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		t.Errorf("decode body of %s %s: %v", r.Method, r.URL.Path, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// fanOutResult is the outcome of a branch of a fan-out: the value returned
+// by the branch and the error it failed with, if any. Branches may return a
+// value describing the failure along with the error.
+type fanOutResult[R any] struct {
+	value R
+	err   error
+}
+
+// PartialFailure is added to the _meta of the results of composite tools,
+// under partialFailure and by kind of branch, when some of their branches
+// failed.
+type PartialFailure struct {
+	Branches int `json:"branches"`
+	Failed   int `json:"failed"`
+}
+
+// fanOut runs fn for each of items using at most workers goroutines, but at
+// least one, and returns the outcomes in the order of the items. Branches are
+// independent: a failed branch doesn't stop the others, and its error is
+// captured in its outcome so that it can be reported along with the results
+// of the others.
+//
+// If some but not all of the branches fail, the result of the tool call is
+// marked as partial: its _meta counts the failed branches of each kind, e.g.
+// "dashboards", and a note tells the model that the results are incomplete.
+// Whether a call fails when all of its branches fail is up to the tool; see
+// allFailed.
+func fanOut[T, R any](ctx context.Context, kind string, items []T, workers int, fn func(ctx context.Context, item T) (R, error)) []fanOutResult[R] {
+	results := make([]fanOutResult[R], len(items))
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for range max(1, min(workers, len(items))) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				results[i].value, results[i].err = fn(ctx, items[i])
+			}
+		}()
+	}
+	for i := range items {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+	reportPartialFailure(ctx, kind, results)
+	return results
+}
+
+// reportPartialFailure marks the result of the tool call as partial if some
+// but not all of the branches failed.
+func reportPartialFailure[R any](ctx context.Context, kind string, results []fanOutResult[R]) {
+	failed := 0
+	for _, r := range results {
+		if r.err != nil {
+			failed++
+		}
+	}
+	if failed == 0 || failed == len(results) {
+		return
+	}
+	mcpgrafana.UpdateResultMeta(ctx, "partialFailure", func(old any) any {
+		m, _ := old.(map[string]PartialFailure)
+		if m == nil {
+			m = map[string]PartialFailure{}
+		}
+		m[kind] = PartialFailure{Branches: len(results), Failed: failed}
+		return m
+	})
+	mcpgrafana.SetResultNote(ctx, "partialFailure:"+kind, fmt.Sprintf("Partial results: %d of %d %s failed, so the results are incomplete.", failed, len(results), kind))
+}
+
+// allFailed returns an error if there was at least one branch and all of
+// them failed, for tools which fail when none of their branches succeeded.
+func allFailed[R any](kind string, results []fanOutResult[R]) error {
+	if len(results) == 0 {
+		return nil
+	}
+	for _, r := range results {
+		if r.err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("all %s failed, e.g.: %w", kind, results[0].err)
+}
+
+// fanOutValues returns the values of the outcomes of a fan-out, including
+// those of failed branches.
+func fanOutValues[R any](results []fanOutResult[R]) []R {
+	values := make([]R, len(results))
+	for i, r := range results {
+		values[i] = r.value
+	}
+	return values
+}
@@ -0,0 +1,80 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFanOut(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	items := []int{1, 2, 3, 4, 5, 6, 7, 8}
+	results := fanOut(context.Background(), "items", items, 3, func(ctx context.Context, n int) (int, error) {
+		r := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if r <= m || maxRunning.CompareAndSwap(m, r) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		if n%3 == 0 {
+			return -n, fmt.Errorf("item %d failed", n)
+		}
+		return n * 10, nil
+	})
+	assert.LessOrEqual(t, maxRunning.Load(), int32(3))
+	// The outcomes are in the order of the items, and failed branches keep
+	// their values.
+	assert.Equal(t, []int{10, 20, -3, 40, 50, -6, 70, 80}, fanOutValues(results))
+	assert.EqualError(t, results[2].err, "item 3 failed")
+	assert.NoError(t, results[3].err)
+	assert.NoError(t, allFailed("items", results))
+
+	failing := fanOut(context.Background(), "items", items[:2], 3, func(ctx context.Context, n int) (int, error) {
+		return 0, fmt.Errorf("item %d failed", n)
+	})
+	assert.EqualError(t, allFailed("items", failing), "all items failed, e.g.: item 1 failed")
+	assert.NoError(t, allFailed("items", fanOut(context.Background(), "items", nil, 3, func(ctx context.Context, n int) (int, error) { return n, nil })))
+
+	// Without workers, the items are still run one at a time.
+	sequential := fanOut(context.Background(), "items", items[:3], 0, func(ctx context.Context, n int) (int, error) { return n, nil })
+	assert.Equal(t, []int{1, 2, 3}, fanOutValues(sequential))
+}
+
+func TestFanOutPartialFailure(t *testing.T) {
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/dashboards/uid/a": jsonBody(`{"dashboard": {"uid": "a", "title": "A"}, "meta": {}}`),
+		"/api/dashboards/uid/b": jsonBody(`{"dashboard": {"uid": "b", "title": "B"}, "meta": {}}`),
+	})
+	ctx := fakeGrafanaContext(server)
+
+	call := func(uids ...string) *mcp.CallToolResult {
+		request := mcp.CallToolRequest{}
+		request.Params.Name = GetDashboardSummaries.Tool.Name
+		request.Params.Arguments = map[string]any{"uids": uids}
+		result, err := GetDashboardSummaries.Handler(ctx, request)
+		require.NoError(t, err)
+		return result
+	}
+
+	result := call("a", "missing", "b")
+	require.Len(t, result.Content, 2)
+	assert.Equal(t, "Partial results: 1 of 3 dashboards failed, so the results are incomplete.", result.Content[1].(mcp.TextContent).Text)
+	assert.Equal(t, map[string]PartialFailure{"dashboards": {Branches: 3, Failed: 1}}, result.Meta["partialFailure"])
+
+	// Complete results are not marked.
+	result = call("a", "b")
+	assert.Len(t, result.Content, 1)
+	assert.Nil(t, result.Meta)
+}
@@ -0,0 +1,211 @@
+package tools
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/csv"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// Formats of query results.
+const (
+	formatJSON     = "json"
+	formatCSV      = "csv"
+	formatMarkdown = "markdown-table"
+)
+
+// validateFormat checks that format is a known result format.
+func validateFormat(format string) error {
+	switch format {
+	case "", formatJSON, formatCSV, formatMarkdown:
+		return nil
+	}
+	return fmt.Errorf("unknown format %q: must be %q, %q or %q", format, formatJSON, formatCSV, formatMarkdown)
+}
+
+// resultFormat returns the requested format of a query result, given by the
+// outputFormat parameter or the older format parameter.
+func resultFormat(format, outputFormat string) (string, error) {
+	if format != "" && outputFormat != "" && format != outputFormat {
+		return "", fmt.Errorf("format %q and outputFormat %q differ; use outputFormat only", format, outputFormat)
+	}
+	f := cmp.Or(outputFormat, format, formatJSON)
+	if err := validateFormat(f); err != nil {
+		return "", err
+	}
+	return f, nil
+}
+
+// table is a tabular query result, with one column per field or label.
+type table struct {
+	columns []string
+	rows    [][]string
+}
+
+// newTable creates a table with the given leading columns followed by a
+// column for each of the given labels, sorted by name.
+func newTable(columns []string, labels map[string]struct{}) *table {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return &table{columns: append(columns, names...)}
+}
+
+// addRow adds a row with the given values of the leading columns, followed
+// by the values of the labels. Missing labels are left empty.
+func (t *table) addRow(values []string, labels func(string) string) {
+	row := make([]string, len(t.columns))
+	copy(row, values)
+	for i := len(values); i < len(t.columns); i++ {
+		row[i] = labels(t.columns[i])
+	}
+	t.rows = append(t.rows, row)
+}
+
+// csv renders the table as CSV with a header row.
+func (t *table) csv() (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(t.columns); err != nil {
+		return "", err
+	}
+	if err := w.WriteAll(t.rows); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// markdown renders the table as a Markdown table.
+func (t *table) markdown() string {
+	var b strings.Builder
+	writeRow := func(cells []string) {
+		b.WriteString("|")
+		for _, c := range cells {
+			b.WriteString(" ")
+			b.WriteString(markdownCellReplacer.Replace(c))
+			b.WriteString(" |")
+		}
+		b.WriteString("\n")
+	}
+	writeRow(t.columns)
+	b.WriteString("|")
+	b.WriteString(strings.Repeat(" --- |", len(t.columns)))
+	b.WriteString("\n")
+	for _, row := range t.rows {
+		writeRow(row)
+	}
+	return b.String()
+}
+
+// markdownCellReplacer escapes the characters which would break a cell of a
+// Markdown table.
+var markdownCellReplacer = strings.NewReplacer("|", "\\|", "\r\n", "<br>", "\n", "<br>")
+
+// render renders the table in the given tabular format.
+func (t *table) render(format string) (string, error) {
+	if format == formatMarkdown {
+		return t.markdown(), nil
+	}
+	return t.csv()
+}
+
+func formatSampleTime(t model.Time) string {
+	return t.Time().UTC().Format(time.RFC3339Nano)
+}
+
+func formatSampleValue(v model.SampleValue) string {
+	return strconv.FormatFloat(float64(v), 'f', -1, 64)
+}
+
+// prometheusTable converts a Prometheus query result to a table with one row
+// per sample and a column per label.
+func prometheusTable(v model.Value) *table {
+	columns := []string{"timestamp", "value"}
+	labels := map[string]struct{}{}
+	switch v := v.(type) {
+	case model.Matrix:
+		for _, s := range v {
+			for name := range s.Metric {
+				labels[string(name)] = struct{}{}
+			}
+		}
+		t := newTable(columns, labels)
+		for _, s := range v {
+			metric := func(name string) string { return string(s.Metric[model.LabelName(name)]) }
+			for _, p := range s.Values {
+				t.addRow([]string{formatSampleTime(p.Timestamp), formatSampleValue(p.Value)}, metric)
+			}
+		}
+		return t
+	case model.Vector:
+		for _, s := range v {
+			for name := range s.Metric {
+				labels[string(name)] = struct{}{}
+			}
+		}
+		t := newTable(columns, labels)
+		for _, s := range v {
+			metric := func(name string) string { return string(s.Metric[model.LabelName(name)]) }
+			t.addRow([]string{formatSampleTime(s.Timestamp), formatSampleValue(s.Value)}, metric)
+		}
+		return t
+	case *model.Scalar:
+		t := newTable(columns, nil)
+		t.addRow([]string{formatSampleTime(v.Timestamp), formatSampleValue(v.Value)}, nil)
+		return t
+	case *model.String:
+		t := newTable(columns, nil)
+		t.addRow([]string{formatSampleTime(v.Timestamp), v.Value}, nil)
+		return t
+	}
+	return newTable(columns, nil)
+}
+
+// lokiTable converts Loki log entries or metric samples to a table with one
+// row per entry and a column per label, structured metadata or parsed field.
+func lokiTable(entries []LogEntry) *table {
+	metrics := slices.ContainsFunc(entries, func(e LogEntry) bool { return e.Value != nil })
+	columns := []string{"timestamp", "line"}
+	if metrics {
+		columns[1] = "value"
+	}
+	labels := map[string]struct{}{}
+	for _, e := range entries {
+		for _, m := range []map[string]string{e.Labels, e.StructuredMetadata, e.Parsed} {
+			for name := range m {
+				labels[name] = struct{}{}
+			}
+		}
+	}
+	t := newTable(columns, labels)
+	for _, e := range entries {
+		value := e.Line
+		if metrics && e.Value != nil {
+			value = strconv.FormatFloat(*e.Value, 'f', -1, 64)
+		}
+		t.addRow([]string{lokiTimestamp(e.Timestamp, metrics), value}, e.field)
+	}
+	return t
+}
+
+// lokiTimestamp converts the timestamp of a Loki entry, in nanoseconds since
+// the epoch for log lines and in seconds for metric samples, to RFC 3339.
+func lokiTimestamp(ts string, metric bool) string {
+	ts = strings.Trim(ts, `"`)
+	if metric {
+		if s, err := strconv.ParseFloat(ts, 64); err == nil {
+			return time.UnixMilli(int64(s * 1000)).UTC().Format(time.RFC3339Nano)
+		}
+	} else if ns, err := strconv.ParseInt(ts, 10, 64); err == nil {
+		return time.Unix(0, ns).UTC().Format(time.RFC3339Nano)
+	}
+	return ts
+}
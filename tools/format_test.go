@@ -0,0 +1,84 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrometheusTableCSV(t *testing.T) {
+	ts := model.TimeFromUnix(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC).Unix())
+
+	t.Run("matrix", func(t *testing.T) {
+		matrix := model.Matrix{
+			{Metric: model.Metric{"__name__": "up", "job": "api"}, Values: []model.SamplePair{{Timestamp: ts, Value: 1}, {Timestamp: ts + 60000, Value: 0.5}}},
+			{Metric: model.Metric{"__name__": "up", "instance": "db:9090"}, Values: []model.SamplePair{{Timestamp: ts, Value: 0}}},
+		}
+		got, err := prometheusTable(matrix).csv()
+		require.NoError(t, err)
+		assert.Equal(t, "timestamp,value,__name__,instance,job\n"+
+			"2025-01-06T00:00:00Z,1,up,,api\n"+
+			"2025-01-06T00:01:00Z,0.5,up,,api\n"+
+			"2025-01-06T00:00:00Z,0,up,db:9090,\n", got)
+	})
+
+	t.Run("scalar", func(t *testing.T) {
+		got, err := prometheusTable(&model.Scalar{Timestamp: ts, Value: 42}).csv()
+		require.NoError(t, err)
+		assert.Equal(t, "timestamp,value\n2025-01-06T00:00:00Z,42\n", got)
+	})
+}
+
+func TestLokiTableCSV(t *testing.T) {
+	got, err := lokiTable([]LogEntry{
+		{Timestamp: `"1736121600000000000"`, Line: `level=error msg="db, timeout"`, Labels: map[string]string{"app": "api"}},
+		{Timestamp: `"1736121601000000000"`, Line: "ok", Labels: map[string]string{"app": "web", "env": "prod"}},
+	}).csv()
+	require.NoError(t, err)
+	assert.Equal(t, "timestamp,line,app,env\n"+
+		"2025-01-06T00:00:00Z,\"level=error msg=\"\"db, timeout\"\"\",api,\n"+
+		"2025-01-06T00:00:01Z,ok,web,prod\n", got)
+
+	v := 2.5
+	got, err = lokiTable([]LogEntry{{Timestamp: "1736121600", Value: &v, Labels: map[string]string{"app": "api"}}}).csv()
+	require.NoError(t, err)
+	assert.Equal(t, "timestamp,value,app\n2025-01-06T00:00:00Z,2.5,api\n", got)
+
+	assert.Error(t, validateFormat("xml"))
+}
+
+func TestTableMarkdown(t *testing.T) {
+	got, err := lokiTable([]LogEntry{
+		{Timestamp: `"1736121600000000000"`, Line: "a | b\nc", Labels: map[string]string{"app": "api"}},
+	}).render(formatMarkdown)
+	require.NoError(t, err)
+	assert.Equal(t, "| timestamp | line | app |\n"+
+		"| --- | --- | --- |\n"+
+		"| 2025-01-06T00:00:00Z | a \\| b<br>c | api |\n", got)
+}
+
+func TestResultFormat(t *testing.T) {
+	for _, tc := range []struct {
+		format, outputFormat, want string
+	}{
+		{"", "", formatJSON},
+		{"csv", "", formatCSV},
+		{"", "markdown-table", formatMarkdown},
+		{"csv", "csv", formatCSV},
+	} {
+		got, err := resultFormat(tc.format, tc.outputFormat)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, got)
+	}
+
+	_, err := resultFormat("csv", "markdown-table")
+	assert.Error(t, err)
+	_, err = resultFormat("", "xml")
+	assert.Error(t, err)
+}
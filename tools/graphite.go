@@ -0,0 +1,370 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	// DefaultGraphiteMaxDataPoints is the default maximum number of data
+	// points per series returned by grafana_query_graphite.
+	DefaultGraphiteMaxDataPoints = 100
+	// MaxGraphiteMaxDataPoints is the highest maximum number of data points
+	// that can be requested.
+	MaxGraphiteMaxDataPoints = 10000
+)
+
+// graphiteFunctions are the functions of Graphite 1.1, including aliases.
+var graphiteFunctions = func() map[string]bool {
+	functions := map[string]bool{}
+	for _, f := range strings.Fields(`
+		absolute add aggregate aggregateLine aggregateSeriesLists aggregateWithWildcards alias
+		aliasByMetric aliasByNode aliasByTags aliasQuery aliasSub alpha applyByNode areaBetween
+		asPercent averageAbove averageBelow averageOutsidePercentile averageSeries
+		averageSeriesWithWildcards avg cactiStyle changed color consolidateBy constantLine
+		countSeries cumulative currentAbove currentBelow dashed delay derivative diffSeries
+		diffSeriesLists divideSeries divideSeriesLists drawAsInfinite events exclude exp
+		exponentialMovingAverage fallbackSeries filterSeries grep group groupByNode groupByNodes
+		groupByTags highest highestAverage highestCurrent highestMax hitcount
+		holtWintersAberration holtWintersConfidenceArea holtWintersConfidenceBands
+		holtWintersForecast identity integral integralByInterval interpolate invert isNonNull
+		keepLastValue legendValue limit lineWidth linearRegression linearRegressionAnalysis log
+		logarithm logit lower lowest lowestAverage lowestCurrent map mapSeries max maxSeries
+		maximumAbove maximumBelow min minMax minSeries minimumAbove minimumBelow mostDeviant
+		movingAverage movingMax movingMedian movingMin movingSum movingWindow multiplySeries
+		multiplySeriesLists multiplySeriesWithWildcards nPercentile nonNegativeDerivative offset
+		offsetToZero pct perSecond percentileOfSeries pow powSeries randomWalk
+		randomWalkFunction rangeOfSeries reduce reduceSeries removeAbovePercentile
+		removeAboveValue removeBelowPercentile removeBelowValue removeBetweenPercentile
+		removeEmptySeries round scale scaleToSeconds secondYAxis seriesByTag setXFilesFactor
+		sigmoid sin sinFunction smartSummarize sortBy sortByMaxima sortByMinima sortByName
+		sortByTotal squareRoot stacked stddevSeries stdev substr sum sumSeries
+		sumSeriesLists sumSeriesWithWildcards summarize threshold time timeFunction timeShift
+		timeSlice timeStack toLowerCase toUpperCase transformNull unique upper
+		useSeriesAbove verticalLine weightedAverage xFilesFactor
+	`) {
+		functions[f] = true
+	}
+	return functions
+}()
+
+// graphiteError is a syntax error in a Graphite target at a byte offset.
+type graphiteError struct {
+	pos int
+	msg string
+}
+
+func (e *graphiteError) Error() string {
+	return fmt.Sprintf("invalid target at position %d: %s", e.pos+1, e.msg)
+}
+
+// graphiteParser checks the syntax of a Graphite target, following the
+// grammar of graphite-web: an expression is a function call, a series path,
+// a string, a number or a boolean, and function arguments may be named.
+type graphiteParser struct {
+	s   string
+	pos int
+}
+
+// validateGraphiteTarget checks that target is a syntactically valid
+// Graphite expression which only calls known functions.
+func validateGraphiteTarget(target string) error {
+	p := &graphiteParser{s: target}
+	p.skipSpace()
+	if p.pos == len(p.s) {
+		return fmt.Errorf("target is empty")
+	}
+	if err := p.expression(); err != nil {
+		return err
+	}
+	p.skipSpace()
+	if p.pos < len(p.s) {
+		return p.errorf("unexpected %q", p.s[p.pos:p.pos+1])
+	}
+	return nil
+}
+
+func (p *graphiteParser) errorf(format string, args ...any) error {
+	return &graphiteError{pos: p.pos, msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *graphiteParser) skipSpace() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t' || p.s[p.pos] == '\n') {
+		p.pos++
+	}
+}
+
+func (p *graphiteParser) expression() error {
+	p.skipSpace()
+	if p.pos == len(p.s) {
+		return p.errorf("unexpected end of target")
+	}
+	switch c := p.s[p.pos]; {
+	case c == '"' || c == '\'':
+		return p.str()
+	case c == '(' || c == ')' || c == ',':
+		return p.errorf("unexpected %q", string(c))
+	}
+	start := p.pos
+	word := p.word()
+	p.skipSpace()
+	if p.pos < len(p.s) && p.s[p.pos] == '(' {
+		if !graphiteFunctions[word] {
+			p.pos = start
+			return p.errorf("unknown function %q", word)
+		}
+		return p.args()
+	}
+	if word == "" {
+		return p.errorf("unexpected %q", p.s[p.pos:p.pos+1])
+	}
+	return nil
+}
+
+// word consumes a series path, number, boolean or function name. Commas are
+// part of a path inside braces, e.g. servers.{a,b}.cpu.
+func (p *graphiteParser) word() string {
+	start, braces := p.pos, 0
+	for ; p.pos < len(p.s); p.pos++ {
+		switch c := p.s[p.pos]; c {
+		case '{':
+			braces++
+		case '}':
+			braces = max(braces-1, 0)
+		case ',':
+			if braces == 0 {
+				return p.s[start:p.pos]
+			}
+		case '(', ')', ' ', '\t', '\n', '"', '\'':
+			return p.s[start:p.pos]
+		}
+	}
+	return p.s[start:p.pos]
+}
+
+func (p *graphiteParser) str() error {
+	quote, start := p.s[p.pos], p.pos
+	for p.pos++; p.pos < len(p.s); p.pos++ {
+		switch p.s[p.pos] {
+		case '\\':
+			p.pos++
+		case quote:
+			p.pos++
+			return nil
+		}
+	}
+	p.pos = start
+	return p.errorf("unterminated string")
+}
+
+// args consumes the arguments of a function call, starting at the opening
+// parenthesis.
+func (p *graphiteParser) args() error {
+	p.pos++
+	p.skipSpace()
+	if p.pos < len(p.s) && p.s[p.pos] == ')' {
+		p.pos++
+		return nil
+	}
+	for {
+		if err := p.arg(); err != nil {
+			return err
+		}
+		p.skipSpace()
+		if p.pos == len(p.s) {
+			return p.errorf("missing closing parenthesis")
+		}
+		switch p.s[p.pos] {
+		case ')':
+			p.pos++
+			return nil
+		case ',':
+			p.pos++
+		default:
+			return p.errorf("unexpected %q, expected ',' or ')'", p.s[p.pos:p.pos+1])
+		}
+	}
+}
+
+// arg consumes an argument, which may be named, e.g. func='sum'.
+func (p *graphiteParser) arg() error {
+	p.skipSpace()
+	end := p.pos
+	for end < len(p.s) && (isGraphiteIdentChar(p.s[end])) {
+		end++
+	}
+	if end > p.pos && end < len(p.s) && p.s[end] == '=' {
+		p.pos = end + 1
+	}
+	return p.expression()
+}
+
+func isGraphiteIdentChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// graphiteClient queries a Graphite datasource through the Grafana
+// datasource proxy.
+type graphiteClient struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+func newGraphiteClient(ctx context.Context, ds *models.DataSource) (*graphiteClient, error) {
+	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+	httpClient, err := newGrafanaHTTPClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &graphiteClient{
+		httpClient: httpClient,
+		baseURL:    fmt.Sprintf("%s/api/datasources/proxy/uid/%s", strings.TrimRight(cfg.URL, "/"), ds.UID),
+	}, nil
+}
+
+type graphiteRenderSeries struct {
+	Target     string            `json:"target"`
+	Tags       map[string]any    `json:"tags"`
+	Datapoints [][2]*json.Number `json:"datapoints"`
+}
+
+// render evaluates a target over a time range with the render API.
+func (c *graphiteClient) render(ctx context.Context, target string, from, until time.Time, maxDataPoints int) ([]graphiteRenderSeries, error) {
+	form := url.Values{}
+	form.Set("target", target)
+	form.Set("from", strconv.FormatInt(from.Unix(), 10))
+	form.Set("until", strconv.FormatInt(until.Unix(), 10))
+	form.Set("maxDataPoints", strconv.Itoa(maxDataPoints))
+	form.Set("format", "json")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/render", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024*48)) // 48MB limit
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Graphite API returned status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var series []graphiteRenderSeries
+	if err := json.Unmarshal(body, &series); err != nil {
+		return nil, fmt.Errorf("unmarshalling response: %w", err)
+	}
+	return series, nil
+}
+
+type QueryGraphiteParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the Graphite datasource"`
+	Target        string `json:"target" jsonschema:"required,description=The target expression\\, e.g. 'sumSeries(servers.*.cpu.user)'"`
+	StartTime     string `json:"startTime,omitempty" jsonschema:"description=The start time. Supported formats are RFC3339 or relative to now (e.g. 'now'\\, 'now-1.5h'). Defaults to 1 hour ago"`
+	EndTime       string `json:"endTime,omitempty" jsonschema:"description=The end time. Supported formats are RFC3339 or relative to now. Defaults to now"`
+	MaxDataPoints int    `json:"maxDataPoints,omitempty" jsonschema:"description=The maximum number of data points per series; Graphite consolidates series with more points (default 100\\, max 10000)"`
+}
+
+// GraphiteSeries is a series returned by a Graphite query.
+type GraphiteSeries struct {
+	Target     string              `json:"target"`
+	Tags       map[string]any      `json:"tags,omitempty"`
+	Datapoints []GraphiteDatapoint `json:"datapoints"`
+}
+
+// GraphiteDatapoint is a data point of a Graphite series. Value is null if
+// the series has no value at that time.
+type GraphiteDatapoint struct {
+	Timestamp string   `json:"timestamp"`
+	Value     *float64 `json:"value"`
+}
+
+func queryGraphite(ctx context.Context, args QueryGraphiteParams) ([]GraphiteSeries, error) {
+	if err := validateGraphiteTarget(args.Target); err != nil {
+		return nil, err
+	}
+	maxDataPoints := args.MaxDataPoints
+	if maxDataPoints <= 0 {
+		maxDataPoints = DefaultGraphiteMaxDataPoints
+	}
+	maxDataPoints = min(maxDataPoints, MaxGraphiteMaxDataPoints)
+	from, err := parseTime(cmp.Or(args.StartTime, "now-1h"))
+	if err != nil {
+		return nil, fmt.Errorf("parsing start time: %w", err)
+	}
+	until, err := parseTime(cmp.Or(args.EndTime, "now"))
+	if err != nil {
+		return nil, fmt.Errorf("parsing end time: %w", err)
+	}
+
+	ds, err := getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: args.DatasourceUID})
+	if err != nil {
+		return nil, err
+	}
+	if ds.Type != "graphite" {
+		return nil, fmt.Errorf("datasource %s is a %s datasource, not a Graphite datasource", args.DatasourceUID, ds.Type)
+	}
+	client, err := newGraphiteClient(ctx, ds)
+	if err != nil {
+		return nil, fmt.Errorf("creating Graphite client: %w", err)
+	}
+	response, err := client.render(ctx, args.Target, from, until, maxDataPoints)
+	if err != nil {
+		return nil, err
+	}
+
+	series := make([]GraphiteSeries, 0, len(response))
+	for _, s := range response {
+		gs := GraphiteSeries{Target: s.Target, Tags: s.Tags, Datapoints: make([]GraphiteDatapoint, 0, len(s.Datapoints))}
+		for _, dp := range s.Datapoints {
+			if dp[1] == nil {
+				continue
+			}
+			ts, err := dp[1].Int64()
+			if err != nil {
+				return nil, fmt.Errorf("parsing timestamp %s: %w", dp[1], err)
+			}
+			point := GraphiteDatapoint{Timestamp: time.Unix(ts, 0).UTC().Format(time.RFC3339)}
+			if dp[0] != nil {
+				v, err := dp[0].Float64()
+				if err != nil {
+					return nil, fmt.Errorf("parsing value %s: %w", dp[0], err)
+				}
+				point.Value = &v
+			}
+			gs.Datapoints = append(gs.Datapoints, point)
+		}
+		series = append(series, gs)
+	}
+	return series, nil
+}
+
+var QueryGraphite = mcpgrafana.MustTool(
+	"grafana_query_graphite",
+	"Evaluate a Graphite target expression against a Graphite datasource over a time range (default: the last hour), returning each resulting series with its data points. The target is checked before it is sent: it must be a valid Graphite expression calling only known Graphite functions, and syntax errors are reported with their position. Use maxDataPoints to control how much Graphite consolidates long ranges.",
+	queryGraphite,
+	mcp.WithTitleAnnotation("Query Graphite"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+func AddGraphiteTools(mcp *server.MCPServer) {
+	QueryGraphite.Register(mcp)
+}
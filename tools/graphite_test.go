@@ -0,0 +1,78 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateGraphiteTarget(t *testing.T) {
+	for _, target := range []string{
+		"servers.web01.cpu.user",
+		"servers.{web01,web02}.cpu.*",
+		"sumSeries(servers.*.cpu.user)",
+		"alias(scale(servers.web01.cpu.user, 0.5), 'half CPU')",
+		"aggregate(servers.*.cpu.user, func='sum')",
+		`seriesByTag('name=cpu.user', "dc=eu")`,
+		"summarize(app.requests, '1h', 'sum', true)",
+		"countSeries()",
+	} {
+		assert.NoError(t, validateGraphiteTarget(target), target)
+	}
+
+	for target, want := range map[string]string{
+		"":                            "target is empty",
+		"sumSeries(a.b":               "invalid target at position 14: missing closing parenthesis",
+		"sumSeriez(a.b)":              `invalid target at position 1: unknown function "sumSeriez"`,
+		"alias(a.b, 'x)":              "invalid target at position 12: unterminated string",
+		"sumSeries(a.b))":             `invalid target at position 15: unexpected ")"`,
+		"sumSeries(a.b,)":             `invalid target at position 15: unexpected ")"`,
+		"scale(a.b 2)":                `invalid target at position 11: unexpected "2", expected ',' or ')'`,
+		"sumSeries(nosuch(a.b), c.d)": `invalid target at position 11: unknown function "nosuch"`,
+	} {
+		assert.EqualError(t, validateGraphiteTarget(target), want, target)
+	}
+}
+
+func TestQueryGraphite(t *testing.T) {
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/datasources/uid/graphite": jsonBody(`{"uid": "graphite", "type": "graphite"}`),
+		"/api/datasources/proxy/uid/graphite/render": func(w http.ResponseWriter, r *http.Request) {
+			assert.NoError(t, r.ParseForm())
+			assert.Equal(t, "sumSeries(servers.*.cpu)", r.PostForm.Get("target"))
+			assert.Equal(t, "1736121600", r.PostForm.Get("from"))
+			assert.Equal(t, "1736125200", r.PostForm.Get("until"))
+			assert.Equal(t, "50", r.PostForm.Get("maxDataPoints"))
+			assert.Equal(t, "json", r.PostForm.Get("format"))
+			_, _ = w.Write([]byte(`[{"target": "sumSeries(servers.*.cpu)", "tags": {"name": "sumSeries(servers.*.cpu)"},
+				"datapoints": [[1.5, 1736121600], [null, 1736121660]]}]`))
+		},
+	})
+	ctx := fakeGrafanaContext(server)
+
+	result, err := queryGraphite(ctx, QueryGraphiteParams{
+		DatasourceUID: "graphite",
+		Target:        "sumSeries(servers.*.cpu)",
+		StartTime:     "2025-01-06T00:00:00Z",
+		EndTime:       "2025-01-06T01:00:00Z",
+		MaxDataPoints: 50,
+	})
+	require.NoError(t, err)
+	value := 1.5
+	assert.Equal(t, []GraphiteSeries{{
+		Target: "sumSeries(servers.*.cpu)",
+		Tags:   map[string]any{"name": "sumSeries(servers.*.cpu)"},
+		Datapoints: []GraphiteDatapoint{
+			{Timestamp: "2025-01-06T00:00:00Z", Value: &value},
+			{Timestamp: "2025-01-06T00:01:00Z"},
+		},
+	}}, result)
+
+	_, err = queryGraphite(ctx, QueryGraphiteParams{DatasourceUID: "graphite", Target: "nosuch(a.b)"})
+	assert.ErrorContains(t, err, "unknown function")
+}
@@ -120,11 +120,18 @@ var AddActivityToIncident = mcpgrafana.MustTool(
 	mcp.WithTitleAnnotation("Add activity to incident"),
 )
 
+// AddIncidentTools registers the incident tools. Tools which create or
+// modify incidents are only registered if write tools are enabled, see
+// mcpgrafana.WriteToolsEnabled.
 func AddIncidentTools(mcp *server.MCPServer) {
 	ListIncidents.Register(mcp)
-	CreateIncident.Register(mcp)
-	AddActivityToIncident.Register(mcp)
+	if mcpgrafana.WriteToolsEnabled() {
+		CreateIncident.Register(mcp)
+		AddActivityToIncident.Register(mcp)
+	}
 	GetIncident.Register(mcp)
+	GetTimeline.Register(mcp)
+	SuggestIncidentSeverity.Register(mcp)
 }
 
 type GetIncidentParams struct {
@@ -0,0 +1,255 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+type SeverityAlert struct {
+	Labels map[string]string `json:"labels" jsonschema:"required,description=The labels of the firing alert"`
+}
+
+type AffectedService struct {
+	Name   string            `json:"name" jsonschema:"required,description=The name of the service"`
+	Labels map[string]string `json:"labels,omitempty" jsonschema:"description=Metadata of the service as labels\\, e.g. tier or customer_facing"`
+}
+
+type SuggestIncidentSeverityParams struct {
+	Alerts            []SeverityAlert   `json:"alerts,omitempty" jsonschema:"description=The firing alerts of the incident"`
+	FetchFiringAlerts bool              `json:"fetchFiringAlerts,omitempty" jsonschema:"description=Optionally\\, also use the alerts currently firing in Grafana Alerting\\, excluding silenced and inhibited ones"`
+	AlertFilters      []string          `json:"alertFilters,omitempty" jsonschema:"description=Optionally\\, label matchers selecting the fetched firing alerts\\, e.g. 'service=checkout' or 'team=~payments|orders'"`
+	Services          []AffectedService `json:"services,omitempty" jsonschema:"description=The affected services with their metadata. Services named by the labels of the alerts are added to them"`
+}
+
+// SeveritySuggestion is a suggested severity of an incident, with the rule
+// of the rubric giving it and the facts it was based on.
+type SeveritySuggestion struct {
+	Severity string `json:"severity"`
+	// Rule is the name of the matching rule, or "default" if no rule
+	// matched.
+	Rule             string                     `json:"rule"`
+	Reasons          []string                   `json:"reasons"`
+	CustomerImpact   bool                       `json:"customerImpact"`
+	AffectedServices []string                   `json:"affectedServices"`
+	FiringAlerts     int                        `json:"firingAlerts"`
+	Rubric           *mcpgrafana.SeverityRubric `json:"rubric"`
+}
+
+// severityFacts are the facts about an incident which severity rules are
+// matched against.
+type severityFacts struct {
+	alerts   []map[string]string
+	services map[string]map[string]string
+	// impact describes the alert or service indicating customer impact, if
+	// any.
+	impact string
+}
+
+// matchesLabels returns true if labels include all of want.
+func matchesLabels(labels, want map[string]string) bool {
+	for k, v := range want {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// hasCustomerImpact returns true if labels indicate customer impact by the
+// rubric, with the label indicating it.
+func hasCustomerImpact(rubric *mcpgrafana.SeverityRubric, labels map[string]string) (string, bool) {
+	names := make([]string, 0, len(rubric.CustomerImpactLabels))
+	for name := range rubric.CustomerImpactLabels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, ok := labels[name]
+		if !ok {
+			continue
+		}
+		values := rubric.CustomerImpactLabels[name]
+		if len(values) == 0 || slices.Contains(values, value) {
+			return fmt.Sprintf("%s=%s", name, value), true
+		}
+	}
+	return "", false
+}
+
+// newSeverityFacts collects the facts of an incident: the affected services,
+// including those named by the labels of the alerts, and the first alert or
+// service indicating customer impact.
+func newSeverityFacts(rubric *mcpgrafana.SeverityRubric, alerts []map[string]string, services []AffectedService) severityFacts {
+	facts := severityFacts{alerts: alerts, services: map[string]map[string]string{}}
+	for _, s := range services {
+		facts.services[s.Name] = s.Labels
+		if label, ok := hasCustomerImpact(rubric, s.Labels); ok && facts.impact == "" {
+			facts.impact = fmt.Sprintf("service %s has %s", s.Name, label)
+		}
+	}
+	for _, labels := range alerts {
+		for _, name := range rubric.ServiceLabels {
+			if service := labels[name]; service != "" {
+				if _, ok := facts.services[service]; !ok {
+					facts.services[service] = nil
+				}
+				break
+			}
+		}
+		if label, ok := hasCustomerImpact(rubric, labels); ok && facts.impact == "" {
+			facts.impact = fmt.Sprintf("alert %s has %s", alertName(labels), label)
+		}
+	}
+	return facts
+}
+
+// alertName returns the name of an alert for reasons.
+func alertName(labels map[string]string) string {
+	if labels["alertname"] == "" {
+		return "(unnamed)"
+	}
+	return labels["alertname"]
+}
+
+// match returns the reasons a rule matches the facts, or false if it does
+// not match.
+func (f severityFacts) match(rule mcpgrafana.SeverityRule) ([]string, bool) {
+	var reasons []string
+	if rule.CustomerImpact {
+		if f.impact == "" {
+			return nil, false
+		}
+		reasons = append(reasons, "customer impact: "+f.impact)
+	}
+	if rule.MinServices > 0 {
+		if len(f.services) < rule.MinServices {
+			return nil, false
+		}
+		reasons = append(reasons, fmt.Sprintf("%d affected services, at least %d", len(f.services), rule.MinServices))
+	}
+	if rule.MinAlerts > 0 {
+		if len(f.alerts) < rule.MinAlerts {
+			return nil, false
+		}
+		reasons = append(reasons, fmt.Sprintf("%d firing alerts, at least %d", len(f.alerts), rule.MinAlerts))
+	}
+	if len(rule.AlertLabels) > 0 {
+		i := slices.IndexFunc(f.alerts, func(labels map[string]string) bool { return matchesLabels(labels, rule.AlertLabels) })
+		if i < 0 {
+			return nil, false
+		}
+		reasons = append(reasons, fmt.Sprintf("alert %s has labels %v", alertName(f.alerts[i]), rule.AlertLabels))
+	}
+	if len(rule.ServiceLabels) > 0 {
+		var matched string
+		for name, labels := range f.services {
+			if matchesLabels(labels, rule.ServiceLabels) && (matched == "" || name < matched) {
+				matched = name
+			}
+		}
+		if matched == "" {
+			return nil, false
+		}
+		reasons = append(reasons, fmt.Sprintf("service %s has labels %v", matched, rule.ServiceLabels))
+	}
+	return reasons, true
+}
+
+// suggestSeverity matches the rules of the rubric in order against the
+// facts of an incident.
+func suggestSeverity(rubric *mcpgrafana.SeverityRubric, facts severityFacts) SeveritySuggestion {
+	suggestion := SeveritySuggestion{
+		Severity:         rubric.Default,
+		Rule:             "default",
+		Reasons:          []string{"no rule of the rubric matched"},
+		CustomerImpact:   facts.impact != "",
+		AffectedServices: []string{},
+		FiringAlerts:     len(facts.alerts),
+		Rubric:           rubric,
+	}
+	for name := range facts.services {
+		suggestion.AffectedServices = append(suggestion.AffectedServices, name)
+	}
+	sort.Strings(suggestion.AffectedServices)
+	for _, rule := range rubric.Rules {
+		if reasons, ok := facts.match(rule); ok {
+			suggestion.Severity = rule.Severity
+			suggestion.Rule = rule.Name
+			suggestion.Reasons = reasons
+			if len(reasons) == 0 {
+				suggestion.Reasons = []string{"the rule has no conditions"}
+			}
+			break
+		}
+	}
+	return suggestion
+}
+
+// gettableAlert is an alert of the Alertmanager API of Grafana.
+type gettableAlert struct {
+	Labels map[string]string `json:"labels"`
+}
+
+// fetchFiringAlerts returns the labels of the active alerts of Grafana
+// Alerting matching the filters.
+func fetchFiringAlerts(ctx context.Context, filters []string) ([]map[string]string, error) {
+	c, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{}
+	params.Set("active", "true")
+	params.Set("silenced", "false")
+	params.Set("inhibited", "false")
+	for _, f := range filters {
+		params.Add("filter", f)
+	}
+	var alerts []gettableAlert
+	if err := c.getJSON(ctx, "/api/alertmanager/grafana/api/v2/alerts", params, &alerts); err != nil {
+		return nil, fmt.Errorf("list firing alerts: %w", err)
+	}
+	labels := make([]map[string]string, 0, len(alerts))
+	for _, a := range alerts {
+		labels = append(labels, a.Labels)
+	}
+	return labels, nil
+}
+
+func suggestIncidentSeverity(ctx context.Context, args SuggestIncidentSeverityParams) (*SeveritySuggestion, error) {
+	rubric := mcpgrafana.GrafanaConfigFromContext(ctx).SeverityRubric
+	if rubric == nil {
+		rubric = mcpgrafana.DefaultSeverityRubric()
+	}
+	var alerts []map[string]string
+	for _, a := range args.Alerts {
+		alerts = append(alerts, a.Labels)
+	}
+	if args.FetchFiringAlerts {
+		firing, err := fetchFiringAlerts(ctx, args.AlertFilters)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, firing...)
+	}
+	if len(alerts) == 0 && len(args.Services) == 0 {
+		return nil, fmt.Errorf("no alerts or services: provide the firing alerts or the affected services, or set fetchFiringAlerts")
+	}
+	suggestion := suggestSeverity(rubric, newSeverityFacts(rubric, alerts, args.Services))
+	return &suggestion, nil
+}
+
+var SuggestIncidentSeverity = mcpgrafana.MustTool(
+	"grafana_suggest_incident_severity",
+	"Suggest the severity of an incident from its firing alerts and affected services, using the severity rubric of the server: rules matched in order on customer impact labels, the number of affected services and firing alerts, and the labels of alerts and services. Returns the suggested severity, the matching rule and the reasons, to be confirmed with the user before declaring an incident with `grafana_create_incident`. Alerts can be passed in or fetched from Grafana Alerting.",
+	suggestIncidentSeverity,
+	mcp.WithTitleAnnotation("Suggest incident severity"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,110 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+func TestSuggestIncidentSeverity(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("default rubric", func(t *testing.T) {
+		for _, tc := range []struct {
+			name     string
+			args     SuggestIncidentSeverityParams
+			severity string
+			rule     string
+		}{
+			{
+				name: "single warning",
+				args: SuggestIncidentSeverityParams{Alerts: []SeverityAlert{
+					{Labels: map[string]string{"alertname": "HighLatency", "service": "checkout", "severity": "warning"}},
+				}},
+				severity: "minor",
+				rule:     "default",
+			},
+			{
+				name: "critical alert",
+				args: SuggestIncidentSeverityParams{Alerts: []SeverityAlert{
+					{Labels: map[string]string{"alertname": "Down", "service": "checkout", "severity": "critical"}},
+				}},
+				severity: "major",
+				rule:     "critical alert",
+			},
+			{
+				name: "customer facing services",
+				args: SuggestIncidentSeverityParams{
+					Alerts: []SeverityAlert{
+						{Labels: map[string]string{"alertname": "Errors", "job": "payments"}},
+					},
+					Services: []AffectedService{{Name: "checkout", Labels: map[string]string{"customer_facing": "true"}}},
+				},
+				severity: "critical",
+				rule:     "customer impact across services",
+			},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				suggestion, err := suggestIncidentSeverity(ctx, tc.args)
+				require.NoError(t, err)
+				assert.Equal(t, tc.severity, suggestion.Severity)
+				assert.Equal(t, tc.rule, suggestion.Rule)
+				assert.NotEmpty(t, suggestion.Reasons)
+			})
+		}
+
+		suggestion, err := suggestIncidentSeverity(ctx, SuggestIncidentSeverityParams{
+			Alerts:   []SeverityAlert{{Labels: map[string]string{"alertname": "Errors", "job": "payments"}}},
+			Services: []AffectedService{{Name: "checkout", Labels: map[string]string{"customer_facing": "true"}}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"checkout", "payments"}, suggestion.AffectedServices)
+		assert.True(t, suggestion.CustomerImpact)
+		assert.Equal(t, []string{"customer impact: service checkout has customer_facing=true", "2 affected services, at least 2"}, suggestion.Reasons)
+
+		_, err = suggestIncidentSeverity(ctx, SuggestIncidentSeverityParams{})
+		assert.Error(t, err)
+	})
+
+	t.Run("configured rubric and firing alerts", func(t *testing.T) {
+		ctx := newFakeGrafanaContext(t, fakeRoutes{
+			"/api/alertmanager/grafana/api/v2/alerts": func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "true", r.URL.Query().Get("active"))
+				assert.Equal(t, []string{"team=payments"}, r.URL.Query()["filter"])
+				_ = json.NewEncoder(w).Encode([]map[string]any{
+					{"labels": map[string]string{"alertname": "A", "service": "ledger"}},
+					{"labels": map[string]string{"alertname": "B", "service": "ledger"}},
+					{"labels": map[string]string{"alertname": "C", "app": "billing"}},
+				})
+			},
+		})
+
+		rubric, err := mcpgrafana.ParseSeverityRubric([]byte(`
+levels: [sev1, sev2, sev3]
+serviceLabels: [service, app]
+rules:
+  - name: alert storm
+    severity: sev2
+    minAlerts: 3
+`))
+		require.NoError(t, err)
+		config := mcpgrafana.GrafanaConfigFromContext(ctx)
+		config.SeverityRubric = rubric
+		ctx = mcpgrafana.WithGrafanaConfig(ctx, config)
+		suggestion, err := suggestIncidentSeverity(ctx, SuggestIncidentSeverityParams{FetchFiringAlerts: true, AlertFilters: []string{"team=payments"}})
+		require.NoError(t, err)
+		assert.Equal(t, "sev2", suggestion.Severity)
+		assert.Equal(t, "alert storm", suggestion.Rule)
+		assert.Equal(t, 3, suggestion.FiringAlerts)
+		assert.Equal(t, []string{"billing", "ledger"}, suggestion.AffectedServices)
+	})
+}
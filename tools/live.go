@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+type ListLiveChannelsParams struct {
+	Prefix string `json:"prefix,omitempty" jsonschema:"description=Optionally\\, only list channels starting with this prefix\\, e.g. 'stream/' or 'plugin/testdata/'"`
+}
+
+// LiveChannel is a channel of Grafana Live which data is being published to.
+type LiveChannel struct {
+	// Channel is the address of the channel, made of its scope, namespace
+	// and path, e.g. stream/telegraf/cpu.
+	Channel   string `json:"channel"`
+	Scope     string `json:"scope"`
+	Namespace string `json:"namespace,omitempty"`
+	Path      string `json:"path,omitempty"`
+	// MinuteRate is the number of messages published to the channel in the
+	// last minute.
+	MinuteRate int64 `json:"minuteRate"`
+	// Fields are the fields of the data frames published to the channel,
+	// from the last message.
+	Fields []string `json:"fields,omitempty"`
+}
+
+type ListLiveChannelsResult struct {
+	// Enabled is false if Grafana Live is disabled, e.g. with
+	// max_connections = 0 in the [live] section of the Grafana configuration.
+	Enabled  bool          `json:"enabled"`
+	Channels []LiveChannel `json:"channels"`
+}
+
+// parseLiveChannel splits the address of a channel into its scope, namespace
+// and path.
+func parseLiveChannel(channel string) LiveChannel {
+	c := LiveChannel{Channel: channel}
+	parts := strings.SplitN(channel, "/", 3)
+	c.Scope = parts[0]
+	if len(parts) > 1 {
+		c.Namespace = parts[1]
+	}
+	if len(parts) > 2 {
+		c.Path = parts[2]
+	}
+	return c
+}
+
+func listLiveChannels(ctx context.Context, args ListLiveChannelsParams) (*ListLiveChannelsResult, error) {
+	c, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("creating Grafana client: %w", err)
+	}
+	var settings struct {
+		LiveEnabled bool `json:"liveEnabled"`
+	}
+	if err := c.getJSON(ctx, "/api/frontend/settings", nil, &settings); err != nil {
+		return nil, fmt.Errorf("get frontend settings: %w", err)
+	}
+	result := &ListLiveChannelsResult{Enabled: settings.LiveEnabled, Channels: []LiveChannel{}}
+	if !result.Enabled {
+		return result, nil
+	}
+
+	var response struct {
+		Channels []struct {
+			Channel    string          `json:"channel"`
+			MinuteRate int64           `json:"minute_rate"`
+			Data       json.RawMessage `json:"data"`
+		} `json:"channels"`
+	}
+	if err := c.getJSON(ctx, "/api/live/list", nil, &response); err != nil {
+		return nil, fmt.Errorf("list live channels: %w", err)
+	}
+	for _, ch := range response.Channels {
+		if !strings.HasPrefix(ch.Channel, args.Prefix) {
+			continue
+		}
+		channel := parseLiveChannel(ch.Channel)
+		channel.MinuteRate = ch.MinuteRate
+		var frame dataFrame
+		if len(ch.Data) > 0 && json.Unmarshal(ch.Data, &frame) == nil {
+			for _, f := range frame.Schema.Fields {
+				channel.Fields = append(channel.Fields, f.Name)
+			}
+		}
+		result.Channels = append(result.Channels, channel)
+	}
+	slices.SortFunc(result.Channels, func(a, b LiveChannel) int { return strings.Compare(a.Channel, b.Channel) })
+	return result, nil
+}
+
+var ListLiveChannels = mcpgrafana.MustTool(
+	"grafana_list_live_channels",
+	"List the Grafana Live channels which data is being published to in the current organization, such as the streams of datasource plugins and data pushed with `/api/live/push`, with the number of messages published in the last minute and the fields of the published data. Use it to debug streaming panels which don't update: a channel missing from the list or with a rate of 0 receives no data, and `enabled` is false if Grafana Live is disabled altogether. Grafana doesn't expose the number of subscribers of each channel through its API, so they are not included.",
+	listLiveChannels,
+	mcp.WithTitleAnnotation("List Grafana Live channels"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
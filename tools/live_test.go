@@ -0,0 +1,45 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"testing"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListLiveChannels(t *testing.T) {
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/frontend/settings": jsonBody(`{"liveEnabled": true}`),
+		"/api/live/list": jsonBody(`{"channels": [
+			{"channel": "stream/telegraf/cpu", "minute_rate": 60, "data": {"schema": {"fields": [{"name": "time", "type": "time"}, {"name": "usage_idle", "type": "number"}]}}},
+			{"channel": "plugin/testdata/random-2s-stream", "minute_rate": 0, "data": null}
+		]}`),
+	})
+	ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{URL: server.URL})
+
+	result, err := listLiveChannels(ctx, ListLiveChannelsParams{})
+	require.NoError(t, err)
+	assert.True(t, result.Enabled)
+	assert.Equal(t, []LiveChannel{
+		{Channel: "plugin/testdata/random-2s-stream", Scope: "plugin", Namespace: "testdata", Path: "random-2s-stream"},
+		{Channel: "stream/telegraf/cpu", Scope: "stream", Namespace: "telegraf", Path: "cpu", MinuteRate: 60, Fields: []string{"time", "usage_idle"}},
+	}, result.Channels)
+
+	result, err = listLiveChannels(ctx, ListLiveChannelsParams{Prefix: "stream/"})
+	require.NoError(t, err)
+	require.Len(t, result.Channels, 1)
+	assert.Equal(t, "stream/telegraf/cpu", result.Channels[0].Channel)
+
+	t.Run("disabled", func(t *testing.T) {
+		server := newFakeGrafana(t, fakeRoutes{"/api/frontend/settings": jsonBody(`{"liveEnabled": false}`)})
+		ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{URL: server.URL})
+		result, err := listLiveChannels(ctx, ListLiveChannelsParams{})
+		require.NoError(t, err)
+		assert.Equal(t, &ListLiveChannelsResult{Channels: []LiveChannel{}}, result)
+	})
+}
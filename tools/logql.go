@@ -0,0 +1,796 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/common/model"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// This file implements a parser for LogQL which checks the syntax of queries
+// without sending them to Loki. It follows the grammar of Loki's parser but
+// only builds what is needed to describe a query, i.e. its type and stream
+// selectors.
+
+type logqlTokenKind int
+
+const (
+	logqlEOF logqlTokenKind = iota
+	logqlIdent
+	logqlString
+	// logqlNumber is a number, duration or byte size, e.g. "5", "1h30m" or
+	// "10KB".
+	logqlNumber
+	logqlPunct
+)
+
+type logqlToken struct {
+	kind logqlTokenKind
+	// text is the unquoted value of strings, and the text of other tokens.
+	text string
+	pos  int
+}
+
+func (t logqlToken) String() string {
+	switch t.kind {
+	case logqlEOF:
+		return "end of query"
+	case logqlString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// logqlError is a syntax error at a byte offset of the query.
+type logqlError struct {
+	pos int
+	msg string
+}
+
+// in returns the error with its line and column in the query.
+func (e *logqlError) in(query string) error {
+	line := strings.Count(query[:e.pos], "\n") + 1
+	col := utf8.RuneCountInString(query[strings.LastIndex(query[:e.pos], "\n")+1:e.pos]) + 1
+	return fmt.Errorf("parse error at line %d, col %d: %s", line, col, e.msg)
+}
+
+// logqlPunctuation are the punctuation tokens, longest first.
+var logqlPunctuation = []string{
+	"!=", "=~", "!~", "|=", "|~", "|>", "!>", ">=", "<=", "==",
+	"{", "}", "(", ")", "[", "]", ",", "=", "|", ">", "<", "+", "-", "*", "/", "%", "^",
+}
+
+func lexLogQL(query string) ([]logqlToken, *logqlError) {
+	var tokens []logqlToken
+	for i := 0; i < len(query); {
+		r, size := utf8.DecodeRuneInString(query[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '#':
+			// Comments run to the end of the line.
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case r == '"' || r == '`':
+			end := i + 1
+			for end < len(query) && query[end] != byte(r) {
+				if r == '"' && query[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(query) {
+				return nil, &logqlError{i, "unterminated string"}
+			}
+			raw := query[i : end+1]
+			text := raw[1 : len(raw)-1]
+			if r == '"' {
+				var err error
+				if text, err = strconv.Unquote(raw); err != nil {
+					return nil, &logqlError{i, fmt.Sprintf("invalid string %s: %s", raw, err)}
+				}
+			}
+			tokens = append(tokens, logqlToken{logqlString, text, i})
+			i = end + 1
+		case r == '_' || unicode.IsLetter(r):
+			end := i
+			for end < len(query) && isLogQLIdentByte(query[end]) {
+				end++
+			}
+			tokens = append(tokens, logqlToken{logqlIdent, query[i:end], i})
+			i = end
+		case unicode.IsDigit(r):
+			// Numbers, durations and byte sizes are lexed together and told
+			// apart by the parser.
+			end := i
+			for end < len(query) && (isLogQLIdentByte(query[end]) || query[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, logqlToken{logqlNumber, query[i:end], i})
+			i = end
+		case strings.HasPrefix(query[i:], "--") && i+2 < len(query) && unicode.IsLetter(rune(query[i+2])):
+			// Parser flags, e.g. logfmt --strict.
+			end := i + 2
+			for end < len(query) && (isLogQLIdentByte(query[end]) || query[end] == '-') {
+				end++
+			}
+			tokens = append(tokens, logqlToken{logqlIdent, query[i:end], i})
+			i = end
+		default:
+			found := false
+			for _, p := range logqlPunctuation {
+				if strings.HasPrefix(query[i:], p) {
+					tokens = append(tokens, logqlToken{logqlPunct, p, i})
+					i += len(p)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, &logqlError{i, fmt.Sprintf("unexpected character %q", r)}
+			}
+		}
+	}
+	return append(tokens, logqlToken{kind: logqlEOF, pos: len(query)}), nil
+}
+
+func isLogQLIdentByte(b byte) bool {
+	return b == '_' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+var (
+	// logqlRangeAggregations are the functions aggregating a log range,
+	// mapped to whether they require an unwrap stage. Functions which accept
+	// either are not included.
+	logqlRangeAggregations = map[string]bool{
+		"count_over_time":    false,
+		"bytes_rate":         false,
+		"bytes_over_time":    false,
+		"absent_over_time":   false,
+		"sum_over_time":      true,
+		"avg_over_time":      true,
+		"max_over_time":      true,
+		"min_over_time":      true,
+		"stdvar_over_time":   true,
+		"stddev_over_time":   true,
+		"quantile_over_time": true,
+		"first_over_time":    true,
+		"last_over_time":     true,
+		"rate_counter":       true,
+	}
+	logqlVectorAggregations = []string{"sum", "avg", "min", "max", "count", "stddev", "stdvar", "topk", "bottomk", "approx_topk", "sort", "sort_desc"}
+	logqlBinaryPrecedence   = map[string]int{
+		"or": 1, "and": 2, "unless": 2,
+		"==": 3, "!=": 3, ">": 3, ">=": 3, "<": 3, "<=": 3,
+		"+": 4, "-": 4, "*": 5, "/": 5, "%": 5, "^": 6,
+	}
+	logqlLabelOps      = []string{"=", "!=", "=~", "!~"}
+	logqlLineFilterOps = []string{"|=", "!=", "|~", "!~", "|>", "!>"}
+	logqlComparisonOps = []string{"=", "!=", "=~", "!~", ">", ">=", "<", "<=", "=="}
+	logqlBytesPattern  = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?([KMGTPE]i?)?B$`)
+	logqlCapture       = regexp.MustCompile(`<[a-zA-Z_][a-zA-Z0-9_]*>`)
+)
+
+type logqlExprKind int
+
+const (
+	logqlLogExpr logqlExprKind = iota
+	logqlRangeExpr
+	logqlMetricExpr
+)
+
+// logqlExpr describes a parsed expression.
+type logqlExpr struct {
+	kind logqlExprKind
+	// unwrap is set for log ranges with an unwrap stage.
+	unwrap bool
+}
+
+type logqlParser struct {
+	query     string
+	tokens    []logqlToken
+	pos       int
+	selectors []string
+}
+
+// fail aborts parsing with an error at the given token.
+func (p *logqlParser) fail(t logqlToken, format string, args ...any) {
+	panic(&logqlError{t.pos, fmt.Sprintf(format, args...)})
+}
+
+func (p *logqlParser) peek() logqlToken {
+	return p.tokens[p.pos]
+}
+
+func (p *logqlParser) peekAt(n int) logqlToken {
+	return p.tokens[min(p.pos+n, len(p.tokens)-1)]
+}
+
+func (p *logqlParser) next() logqlToken {
+	t := p.tokens[p.pos]
+	if t.kind != logqlEOF {
+		p.pos++
+	}
+	return t
+}
+
+// is returns true if the next token has the given kind and one of the given
+// texts, if any.
+func (p *logqlParser) is(kind logqlTokenKind, texts ...string) bool {
+	t := p.peek()
+	return t.kind == kind && (len(texts) == 0 || slices.Contains(texts, t.text))
+}
+
+// expect consumes a token of the given kind, and text if not empty.
+func (p *logqlParser) expect(kind logqlTokenKind, text, what string) logqlToken {
+	if t := p.peek(); t.kind != kind || (text != "" && t.text != text) {
+		p.fail(p.peek(), "expected %s, found %s", what, p.peek())
+	}
+	return p.next()
+}
+
+func (p *logqlParser) expectPunct(text string) logqlToken {
+	return p.expect(logqlPunct, text, strconv.Quote(text))
+}
+
+func (p *logqlParser) expectString(what string) logqlToken {
+	return p.expect(logqlString, "", what)
+}
+
+func (p *logqlParser) expectIdent(what string) logqlToken {
+	return p.expect(logqlIdent, "", what)
+}
+
+// expectDuration consumes a duration such as "5m".
+func (p *logqlParser) expectDuration() {
+	t := p.next()
+	if t.kind != logqlNumber || !isLogQLDuration(t.text) {
+		p.fail(t, "expected a duration such as 5m, found %s", t)
+	}
+}
+
+func isLogQLDuration(s string) bool {
+	if _, err := model.ParseDuration(s); err == nil {
+		return true
+	}
+	_, err := time.ParseDuration(s)
+	return err == nil
+}
+
+func isLogQLNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// compileRegexp checks that the value of a regexp matcher or filter is a
+// valid regular expression.
+func (p *logqlParser) compileRegexp(t logqlToken, anchored bool) *regexp.Regexp {
+	expr := t.text
+	if anchored {
+		expr = "^(?:" + expr + ")$"
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		p.fail(t, "invalid regular expression %s: %s", t, err)
+	}
+	return re
+}
+
+// parseExpr parses an expression with binary operators of at least the
+// given precedence.
+func (p *logqlParser) parseExpr(minPrecedence int) logqlExpr {
+	left := p.parseUnary()
+	for {
+		t := p.peek()
+		precedence, ok := logqlBinaryPrecedence[t.text]
+		if !ok || t.kind == logqlString || t.kind == logqlNumber || precedence < minPrecedence {
+			return left
+		}
+		p.next()
+		p.checkOperand(left, t)
+		if p.is(logqlIdent, "bool") {
+			p.next()
+		}
+		if p.is(logqlIdent, "on", "ignoring") {
+			p.next()
+			p.parseLabelList()
+			if p.is(logqlIdent, "group_left", "group_right") {
+				p.next()
+				if p.is(logqlPunct, "(") {
+					p.parseLabelList()
+				}
+			}
+		}
+		// Exponentiation is right associative.
+		next := precedence + 1
+		if t.text == "^" {
+			next = precedence
+		}
+		right := p.parseExpr(next)
+		p.checkOperand(right, t)
+		left = logqlExpr{kind: logqlMetricExpr}
+	}
+}
+
+// checkOperand checks that an operand of a binary operator is a metric
+// query.
+func (p *logqlParser) checkOperand(e logqlExpr, op logqlToken) {
+	switch e.kind {
+	case logqlLogExpr:
+		p.fail(op, "log queries can't be used with the %s operator; use a metric query, e.g. count_over_time({app=\"foo\"}[5m])", op.text)
+	case logqlRangeExpr:
+		p.fail(op, "log ranges can't be used with the %s operator; aggregate them with a function such as rate or count_over_time", op.text)
+	}
+}
+
+func (p *logqlParser) parseUnary() logqlExpr {
+	t := p.peek()
+	switch {
+	case t.kind == logqlPunct && (t.text == "-" || t.text == "+"):
+		p.next()
+		e := p.parseUnary()
+		p.checkOperand(e, t)
+		return logqlExpr{kind: logqlMetricExpr}
+	case t.kind == logqlPunct && t.text == "{":
+		return p.parseLogExpr()
+	case t.kind == logqlPunct && t.text == "(":
+		p.next()
+		e := p.parseExpr(0)
+		p.expectPunct(")")
+		if p.is(logqlPunct, "[") {
+			if e.kind != logqlLogExpr {
+				p.fail(p.peek(), "only log queries can have a range")
+			}
+			p.parseRange()
+			return logqlExpr{kind: logqlRangeExpr, unwrap: e.unwrap}
+		}
+		return e
+	case t.kind == logqlNumber:
+		p.next()
+		if !isLogQLNumber(t.text) {
+			p.fail(t, "invalid number %s", t)
+		}
+		return logqlExpr{kind: logqlMetricExpr}
+	case t.kind == logqlIdent:
+		if _, ok := logqlRangeAggregations[t.text]; ok || t.text == "rate" {
+			return p.parseRangeAggregation()
+		}
+		if slices.Contains(logqlVectorAggregations, t.text) {
+			return p.parseVectorAggregation()
+		}
+		switch t.text {
+		case "label_replace":
+			p.next()
+			p.expectPunct("(")
+			p.parseMetricArgument(t)
+			for range 4 {
+				p.expectPunct(",")
+				p.expectString("a string")
+			}
+			p.expectPunct(")")
+			return logqlExpr{kind: logqlMetricExpr}
+		case "vector":
+			p.next()
+			p.expectPunct("(")
+			if n := p.next(); n.kind != logqlNumber || !isLogQLNumber(n.text) {
+				p.fail(n, "expected a number, found %s", n)
+			}
+			p.expectPunct(")")
+			return logqlExpr{kind: logqlMetricExpr}
+		}
+		p.fail(t, "unexpected %s; expected a stream selector such as {app=\"foo\"} or a function such as rate or sum", t)
+	case t.kind == logqlEOF:
+		p.fail(t, "unexpected end of query; expected a stream selector such as {app=\"foo\"}")
+	}
+	p.fail(t, "unexpected %s", t)
+	return logqlExpr{}
+}
+
+// parseLogExpr parses a stream selector followed by a pipeline, and
+// optionally a range.
+func (p *logqlParser) parseLogExpr() logqlExpr {
+	p.parseSelector()
+	e := logqlExpr{kind: logqlLogExpr, unwrap: p.parsePipeline()}
+	if p.is(logqlPunct, "[") {
+		p.parseRange()
+		e.kind = logqlRangeExpr
+		// The pipeline may also follow the range.
+		e.unwrap = p.parsePipeline() || e.unwrap
+	}
+	return e
+}
+
+func (p *logqlParser) parseRange() {
+	p.expectPunct("[")
+	p.expectDuration()
+	p.expectPunct("]")
+	if p.is(logqlIdent, "offset") {
+		p.next()
+		p.expectDuration()
+	}
+}
+
+func (p *logqlParser) parseSelector() {
+	start := p.expectPunct("{")
+	if p.is(logqlPunct, "}") {
+		p.fail(p.peek(), "stream selectors must have at least one label matcher, e.g. {app=\"foo\"}")
+	}
+	nonEmpty := false
+	for {
+		name := p.expectIdent("a label name")
+		op := p.next()
+		if op.kind != logqlPunct || !slices.Contains(logqlLabelOps, op.text) {
+			p.fail(op, "expected one of =, !=, =~ or !~ after label %s, found %s", name.text, op)
+		}
+		value := p.expectString("a quoted label value")
+		switch op.text {
+		case "=":
+			nonEmpty = nonEmpty || value.text != ""
+		case "=~":
+			nonEmpty = nonEmpty || !p.compileRegexp(value, true).MatchString("")
+		case "!~":
+			p.compileRegexp(value, true)
+		}
+		if !p.is(logqlPunct, ",") {
+			break
+		}
+		p.next()
+	}
+	end := p.expectPunct("}")
+	if !nonEmpty {
+		p.fail(start, "stream selectors must have at least one = or =~ matcher which doesn't match an empty value, e.g. {app=~\".+\"} instead of {app=~\".*\"}")
+	}
+	p.selectors = append(p.selectors, p.query[start.pos:end.pos+1])
+}
+
+// parsePipeline parses the stages of a log pipeline, returning true if it
+// has an unwrap stage.
+func (p *logqlParser) parsePipeline() bool {
+	unwrap := false
+	for {
+		switch {
+		case p.is(logqlPunct, logqlLineFilterOps...):
+			p.parseLineFilter()
+		case p.is(logqlPunct, "|"):
+			p.next()
+			if p.parseStage() {
+				unwrap = true
+			}
+		default:
+			return unwrap
+		}
+	}
+}
+
+func (p *logqlParser) parseLineFilter() {
+	op := p.next()
+	for {
+		if p.is(logqlIdent, "ip") {
+			p.next()
+			p.expectPunct("(")
+			p.expectString("a quoted IP address or range")
+			p.expectPunct(")")
+		} else {
+			value := p.expectString(fmt.Sprintf("a quoted string after %s", op.text))
+			if op.text == "|~" || op.text == "!~" {
+				p.compileRegexp(value, false)
+			}
+		}
+		if !p.is(logqlIdent, "or") {
+			return
+		}
+		p.next()
+	}
+}
+
+// parseStage parses a pipeline stage following a "|", returning true if it
+// is an unwrap stage.
+func (p *logqlParser) parseStage() bool {
+	t := p.peek()
+	if t.kind != logqlIdent && !(t.kind == logqlPunct && t.text == "(") {
+		p.fail(t, "expected a parser, formatter or label filter after |, found %s", t)
+	}
+	// Label filters start with a label name followed by an operator.
+	if t.kind == logqlIdent && p.peekAt(1).kind == logqlPunct && slices.Contains(logqlComparisonOps, p.peekAt(1).text) {
+		p.parseLabelFilter()
+		return false
+	}
+	switch t.text {
+	case "json", "logfmt":
+		p.next()
+		for t.text == "logfmt" && p.is(logqlIdent) && strings.HasPrefix(p.peek().text, "--") {
+			if flag := p.next(); flag.text != "--strict" && flag.text != "--keep-empty" {
+				p.fail(flag, "unknown logfmt flag %s", flag)
+			}
+		}
+		p.parseAssignments(false)
+	case "regexp":
+		p.next()
+		value := p.expectString("a quoted regular expression")
+		if !slices.ContainsFunc(p.compileRegexp(value, false).SubexpNames(), func(s string) bool { return s != "" }) {
+			p.fail(value, "the regexp parser requires at least one named capture group, e.g. (?P<status>\\d+)")
+		}
+	case "pattern":
+		p.next()
+		value := p.expectString("a quoted pattern")
+		if !logqlCapture.MatchString(value.text) {
+			p.fail(value, "the pattern parser requires at least one named capture, e.g. <status>")
+		}
+	case "unpack", "decolorize":
+		p.next()
+	case "line_format":
+		p.next()
+		p.expectString("a quoted template")
+	case "label_format":
+		p.next()
+		p.parseAssignments(true)
+	case "distinct":
+		p.next()
+		p.expectIdent("a label name")
+		for p.is(logqlPunct, ",") {
+			p.next()
+			p.expectIdent("a label name")
+		}
+	case "drop", "keep":
+		p.next()
+		for {
+			p.expectIdent("a label name")
+			if p.is(logqlPunct, logqlLabelOps...) {
+				p.next()
+				p.expectString("a quoted label value")
+			}
+			if !p.is(logqlPunct, ",") {
+				break
+			}
+			p.next()
+		}
+	case "unwrap":
+		p.next()
+		if p.is(logqlIdent, "bytes", "duration", "duration_seconds") && p.peekAt(1).text == "(" {
+			p.next()
+			p.next()
+			p.expectIdent("a label name")
+			p.expectPunct(")")
+		} else {
+			p.expectIdent("a label name to unwrap")
+		}
+		return true
+	case "(":
+		p.parseLabelFilter()
+	default:
+		p.fail(t, "unknown pipeline stage %s; expected a parser such as json or logfmt, a formatter such as line_format, or a label filter such as status>=500", t)
+	}
+	return false
+}
+
+// parseAssignments parses the optional name="value" parameters of the json
+// and logfmt parsers, or the required ones of label_format, where values
+// may also be label names.
+func (p *logqlParser) parseAssignments(required bool) {
+	if !required && !(p.is(logqlIdent) && p.peekAt(1).kind == logqlPunct && p.peekAt(1).text == "=") {
+		return
+	}
+	for {
+		p.expectIdent("a label name")
+		p.expectPunct("=")
+		if v := p.next(); v.kind != logqlString && !(required && v.kind == logqlIdent) {
+			p.fail(v, "expected a quoted value, found %s", v)
+		}
+		if !p.is(logqlPunct, ",") {
+			return
+		}
+		p.next()
+	}
+}
+
+// parseLabelFilter parses label filters combined with and, or and commas.
+func (p *logqlParser) parseLabelFilter() {
+	p.parseLabelFilterAnd()
+	for p.is(logqlIdent, "or") {
+		p.next()
+		p.parseLabelFilterAnd()
+	}
+}
+
+func (p *logqlParser) parseLabelFilterAnd() {
+	p.parseLabelFilterPrimary()
+	for {
+		switch {
+		case p.is(logqlIdent, "and") || p.is(logqlPunct, ","):
+			p.next()
+		case p.is(logqlIdent) && p.peekAt(1).kind == logqlPunct && slices.Contains(logqlComparisonOps, p.peekAt(1).text):
+			// Filters separated by spaces are combined with and.
+		default:
+			return
+		}
+		p.parseLabelFilterPrimary()
+	}
+}
+
+func (p *logqlParser) parseLabelFilterPrimary() {
+	if p.is(logqlPunct, "(") {
+		p.next()
+		p.parseLabelFilter()
+		p.expectPunct(")")
+		return
+	}
+	name := p.expectIdent("a label filter such as status>=500")
+	op := p.next()
+	if op.kind != logqlPunct || !slices.Contains(logqlComparisonOps, op.text) {
+		p.fail(op, "expected a comparison operator after label %s, found %s", name.text, op)
+	}
+	value := p.next()
+	switch {
+	case value.kind == logqlString:
+		if op.text == "=~" || op.text == "!~" {
+			p.compileRegexp(value, true)
+		} else if op.text != "=" && op.text != "!=" && op.text != "==" {
+			p.fail(value, "the %s operator compares numbers, durations or byte sizes, found %s", op.text, value)
+		}
+	case value.kind == logqlNumber:
+		if op.text == "=~" || op.text == "!~" {
+			p.fail(value, "the %s operator requires a quoted regular expression, found %s", op.text, value)
+		}
+		if !isLogQLNumber(value.text) && !isLogQLDuration(value.text) && !logqlBytesPattern.MatchString(value.text) {
+			p.fail(value, "invalid number, duration or byte size %s", value)
+		}
+	case value.kind == logqlIdent && value.text == "ip" && (op.text == "=" || op.text == "!="):
+		p.expectPunct("(")
+		p.expectString("a quoted IP address or range")
+		p.expectPunct(")")
+	default:
+		p.fail(value, "expected a value for label %s, found %s", name.text, value)
+	}
+}
+
+func (p *logqlParser) parseGrouping() {
+	if p.is(logqlIdent, "by", "without") {
+		p.next()
+		p.parseLabelList()
+	}
+}
+
+func (p *logqlParser) parseLabelList() {
+	p.expectPunct("(")
+	for !p.is(logqlPunct, ")") {
+		p.expectIdent("a label name")
+		if !p.is(logqlPunct, ",") {
+			break
+		}
+		p.next()
+	}
+	p.expectPunct(")")
+}
+
+func (p *logqlParser) parseRangeAggregation() logqlExpr {
+	fn := p.next()
+	p.expectPunct("(")
+	if fn.text == "quantile_over_time" {
+		if t := p.next(); t.kind != logqlNumber || !isLogQLNumber(t.text) {
+			p.fail(t, "quantile_over_time expects a quantile such as 0.99 as its first argument, found %s", t)
+		}
+		p.expectPunct(",")
+	}
+	arg := p.peek()
+	e := p.parseExpr(0)
+	if e.kind != logqlRangeExpr {
+		p.fail(arg, "%s expects a log range such as {app=\"foo\"}[5m]", fn.text)
+	}
+	if requiresUnwrap, ok := logqlRangeAggregations[fn.text]; ok {
+		if requiresUnwrap && !e.unwrap {
+			p.fail(fn, "%s requires an unwrap stage to aggregate a label's values, e.g. {app=\"foo\"} | logfmt | unwrap duration [5m]", fn.text)
+		}
+		if !requiresUnwrap && e.unwrap {
+			p.fail(fn, "%s counts log lines and can't be used with an unwrap stage", fn.text)
+		}
+	}
+	p.expectPunct(")")
+	p.parseGrouping()
+	return logqlExpr{kind: logqlMetricExpr}
+}
+
+func (p *logqlParser) parseVectorAggregation() logqlExpr {
+	fn := p.next()
+	p.parseGrouping()
+	p.expectPunct("(")
+	if fn.text == "topk" || fn.text == "bottomk" || fn.text == "approx_topk" {
+		if t := p.next(); t.kind != logqlNumber || !isLogQLNumber(t.text) {
+			p.fail(t, "%s expects a number as its first argument, found %s", fn.text, t)
+		}
+		p.expectPunct(",")
+	}
+	p.parseMetricArgument(fn)
+	p.expectPunct(")")
+	p.parseGrouping()
+	return logqlExpr{kind: logqlMetricExpr}
+}
+
+// parseMetricArgument parses an argument of fn which must be a metric query.
+func (p *logqlParser) parseMetricArgument(fn logqlToken) {
+	arg := p.peek()
+	if e := p.parseExpr(0); e.kind != logqlMetricExpr {
+		p.fail(arg, "%s expects a metric query, e.g. %s(rate({app=\"foo\"}[5m])); log queries must be aggregated with a function such as rate or count_over_time first", fn.text, fn.text)
+	}
+}
+
+// logqlQuery describes a syntactically valid LogQL query.
+type logqlQuery struct {
+	// metric is true for metric queries and false for log queries.
+	metric bool
+	// selectors are the stream selectors of the query, as written.
+	selectors []string
+}
+
+// parseLogQL checks the syntax of a LogQL query, returning an error with
+// the line and column of the first problem found.
+func parseLogQL(query string) (q logqlQuery, err error) {
+	tokens, lexErr := lexLogQL(query)
+	if lexErr != nil {
+		return logqlQuery{}, lexErr.in(query)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*logqlError)
+			if !ok {
+				panic(r)
+			}
+			err = e.in(query)
+		}
+	}()
+	p := &logqlParser{query: query, tokens: tokens}
+	e := p.parseExpr(0)
+	if t := p.peek(); t.kind != logqlEOF {
+		p.fail(t, "unexpected %s", t)
+	}
+	if e.kind == logqlRangeExpr {
+		p.fail(tokens[0], "log ranges must be aggregated with a function such as rate or count_over_time, e.g. count_over_time({app=\"foo\"}[5m])")
+	}
+	return logqlQuery{metric: e.kind == logqlMetricExpr, selectors: p.selectors}, nil
+}
+
+type ValidateLogQLParams struct {
+	LogQL string `json:"logql" jsonschema:"required,description=The LogQL query to validate"`
+}
+
+type ValidateLogQLResult struct {
+	Valid bool `json:"valid"`
+	// Error is the syntax error of an invalid query, with its position.
+	Error string `json:"error,omitempty"`
+	// QueryType is "log" for queries returning log lines and "metric" for
+	// queries returning samples.
+	QueryType string `json:"queryType,omitempty"`
+	// Selectors are the stream selectors of the query.
+	Selectors []string `json:"selectors,omitempty"`
+}
+
+func validateLogQL(ctx context.Context, args ValidateLogQLParams) (*ValidateLogQLResult, error) {
+	q, err := parseLogQL(args.LogQL)
+	if err != nil {
+		return &ValidateLogQLResult{Error: err.Error()}, nil
+	}
+	result := &ValidateLogQLResult{Valid: true, QueryType: "log", Selectors: q.selectors}
+	if q.metric {
+		result.QueryType = "metric"
+	}
+	return result, nil
+}
+
+var ValidateLogQL = mcpgrafana.MustTool(
+	"grafana_validate_logql",
+	"Check the syntax of a LogQL query locally, without querying a datasource. Returns whether the query is valid, the syntax error with its line and column if not, whether it is a log query (returning log lines) or a metric query (returning samples, e.g. `rate(...)`), and its stream selectors. Use it before `grafana_query_loki_logs` to avoid round-trips with malformed queries. Label names and values are not checked against the datasource.",
+	validateLogQL,
+	mcp.WithTitleAnnotation("Validate LogQL query"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,77 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLogQL(t *testing.T) {
+	for _, tc := range []struct {
+		query     string
+		metric    bool
+		selectors []string
+	}{
+		{query: `{app="api"}`, selectors: []string{`{app="api"}`}},
+		{query: `{app="api", env=~"prod|staging"} |= "error" != "timeout" |~ "(?i)fail"`, selectors: []string{`{app="api", env=~"prod|staging"}`}},
+		{query: `{app="api"} | json | status >= 500 and duration > 1s | line_format "{{.msg}}"`, selectors: []string{`{app="api"}`}},
+		{query: `{app="api"} | logfmt --strict | label_format lvl=level | drop trace_id`, selectors: []string{`{app="api"}`}},
+		{query: `{app="api"} | regexp "(?P<status>\\d+)" | pattern "<ip> <_>" | size > 10KB`, selectors: []string{`{app="api"}`}},
+		{query: `sum by (status) (rate({app="api"} |= "error" [5m]))`, metric: true, selectors: []string{`{app="api"}`}},
+		{query: `quantile_over_time(0.99, {app="api"} | logfmt | unwrap duration(latency) [5m]) by (route)`, metric: true, selectors: []string{`{app="api"}`}},
+		{query: `topk(10, count_over_time({app="api"}[1h] offset 1d)) / ignoring(x) 2`, metric: true, selectors: []string{`{app="api"}`}},
+		{query: `approx_topk(3, sum by (app) (rate({app="api"}[5m])))`, metric: true, selectors: []string{`{app="api"}`}},
+		{query: `{app="api"} | logfmt | distinct foo, bar`, selectors: []string{`{app="api"}`}},
+		{query: "sum(count_over_time({a=\"1\"}[5m])) / sum(count_over_time({b=\"2\"}[5m])) > 0.1 # ratio", metric: true, selectors: []string{`{a="1"}`, `{b="2"}`}},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			q, err := parseLogQL(tc.query)
+			require.NoError(t, err)
+			assert.Equal(t, tc.metric, q.metric)
+			assert.Equal(t, tc.selectors, q.selectors)
+		})
+	}
+}
+
+func TestParseLogQLErrors(t *testing.T) {
+	for _, tc := range []struct {
+		query string
+		err   string
+	}{
+		{query: `{app="api"`, err: `line 1, col 11: expected "}", found end of query`},
+		{query: `{app="api}`, err: "line 1, col 6: unterminated string"},
+		{query: `{}`, err: "at least one label matcher"},
+		{query: `{app=~".*"}`, err: "doesn't match an empty value"},
+		{query: `{app=~"("}`, err: "invalid regular expression"},
+		{query: "{app=\"api\"}\n  | jsn", err: "line 2, col 5: unknown pipeline stage"},
+		{query: `{app="api"}[5m]`, err: "must be aggregated"},
+		{query: `rate({app="api"})`, err: "rate expects a log range"},
+		{query: `sum_over_time({app="api"}[5m])`, err: "requires an unwrap stage"},
+		{query: `count_over_time({app="api"} | unwrap x [5m])`, err: "can't be used with an unwrap stage"},
+		{query: `sum({app="api"})`, err: "sum expects a metric query"},
+		{query: `{app="api"} > 1`, err: "log queries can't be used with the > operator"},
+		{query: `{app="api"} | regexp "\\d+"`, err: "named capture group"},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			_, err := parseLogQL(tc.query)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
+
+func TestValidateLogQL(t *testing.T) {
+	result, err := validateLogQL(context.Background(), ValidateLogQLParams{LogQL: `rate({app="api"}[5m])`})
+	require.NoError(t, err)
+	assert.Equal(t, &ValidateLogQLResult{Valid: true, QueryType: "metric", Selectors: []string{`{app="api"}`}}, result)
+
+	result, err = validateLogQL(context.Background(), ValidateLogQLParams{LogQL: `{app="api"} |= `})
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, `parse error at line 1, col 16: expected a quoted string after |=, found end of query`, result.Error)
+}
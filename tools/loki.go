@@ -1,13 +1,15 @@
 package tools
 
 import (
-	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -23,6 +25,20 @@ const (
 
 	// MaxLokiLogLimit is the maximum number of log lines that can be requested
 	MaxLokiLogLimit = 100
+
+	// DefaultLokiMetricLimit is the default number of samples of a metric
+	// query to return if not specified
+	DefaultLokiMetricLimit = 1000
+
+	// MaxLokiMetricLimit is the maximum number of samples of a metric query
+	// that can be requested
+	MaxLokiMetricLimit = 10000
+
+	// DefaultLokiSeriesLimit is the default number of series to return if not specified
+	DefaultLokiSeriesLimit = 100
+
+	// MaxLokiSeriesLimit is the maximum number of series that can be requested
+	MaxLokiSeriesLimit = 1000
 )
 
 type Client struct {
@@ -54,23 +70,9 @@ func newLokiClient(ctx context.Context, uid string) (*Client, error) {
 	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
 	url := fmt.Sprintf("%s/api/datasources/proxy/uid/%s", strings.TrimRight(cfg.URL, "/"), uid)
 
-	// Create custom transport with TLS configuration if available
-	var transport http.RoundTripper = http.DefaultTransport
-	if tlsConfig := cfg.TLSConfig; tlsConfig != nil {
-		var err error
-		transport, err = tlsConfig.HTTPTransport(transport.(*http.Transport))
-		if err != nil {
-			return nil, fmt.Errorf("failed to create custom transport: %w", err)
-		}
-	}
-
-	client := &http.Client{
-		Transport: &authRoundTripper{
-			accessToken: cfg.AccessToken,
-			idToken:     cfg.IDToken,
-			apiKey:      cfg.APIKey,
-			underlying:  transport,
-		},
+	client, err := newGrafanaHTTPClient(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	return &Client{
@@ -91,13 +93,14 @@ func (c *Client) buildURL(urlPath string) string {
 	return fullURL + urlPath
 }
 
-// makeRequest makes an HTTP request to the Loki API and returns the response body
-func (c *Client) makeRequest(ctx context.Context, method, urlPath string, params url.Values) ([]byte, error) {
+// makeRequest makes an HTTP request to the Loki API and decodes the JSON
+// response body into v
+func (c *Client) makeRequest(ctx context.Context, method, urlPath string, params url.Values, v any) error {
 	fullURL := c.buildURL(urlPath)
 
 	u, err := url.Parse(fullURL)
 	if err != nil {
-		return nil, fmt.Errorf("parsing URL: %w", err)
+		return fmt.Errorf("parsing URL: %w", err)
 	}
 
 	if params != nil {
@@ -106,35 +109,40 @@ func (c *Client) makeRequest(ctx context.Context, method, urlPath string, params
 
 	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+		return fmt.Errorf("creating request: %w", err)
 	}
+	// Ask Loki to return the structured metadata and parsed labels of each
+	// log line separately from the stream labels. Older versions ignore it.
+	req.Header.Set(lokiEncodingFlagsHeader, "categorize-labels")
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("executing request: %w", err)
+		return fmt.Errorf("executing request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Check for non-200 status code
 	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Loki API returned status code %d: %s", resp.StatusCode, string(bodyBytes))
+		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+		return fmt.Errorf("Loki API returned status code %d: %s", resp.StatusCode, string(bodyBytes))
 	}
 
-	// Read the response body with a limit to prevent memory issues
-	body := io.LimitReader(resp.Body, 1024*1024*48)
-	bodyBytes, err := io.ReadAll(body)
-	if err != nil {
-		return nil, fmt.Errorf("reading response body: %w", err)
-	}
+	return decodeJSONResponse(resp.Body, v)
+}
 
-	// Check if the response is empty
-	if len(bodyBytes) == 0 {
-		return nil, fmt.Errorf("empty response from Loki API")
+// decodeJSONResponse decodes a JSON response body into v while it is read,
+// failing if it is larger than mcpgrafana.MaxResponseSize.
+func decodeJSONResponse(body io.Reader, v any) error {
+	if err := json.NewDecoder(mcpgrafana.LimitResponseBody(body, mcpgrafana.MaxResponseSize)).Decode(v); err != nil {
+		if errors.Is(err, mcpgrafana.ErrResponseTooLarge) {
+			return fmt.Errorf("response is larger than %dMB, narrow down the query or its time range: %w", mcpgrafana.MaxResponseSize>>20, err)
+		}
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("empty response")
+		}
+		return fmt.Errorf("unmarshalling response: %w", err)
 	}
-
-	// Trim any whitespace that might cause JSON parsing issues
-	return bytes.TrimSpace(bodyBytes), nil
+	return nil
 }
 
 // fetchData is a generic method to fetch data from Loki API
@@ -147,19 +155,13 @@ func (c *Client) fetchData(ctx context.Context, urlPath string, startRFC3339, en
 		params.Add("end", endRFC3339)
 	}
 
-	bodyBytes, err := c.makeRequest(ctx, "GET", urlPath, params)
-	if err != nil {
-		return nil, err
-	}
-
 	var labelResponse LabelResponse
-	err = json.Unmarshal(bodyBytes, &labelResponse)
-	if err != nil {
-		return nil, fmt.Errorf("unmarshalling response (content: %s): %w", string(bodyBytes), err)
+	if err := c.makeRequest(ctx, "GET", urlPath, params, &labelResponse); err != nil {
+		return nil, err
 	}
 
 	if labelResponse.Status != "success" {
-		return nil, fmt.Errorf("Loki API returned unexpected response format: %s", string(bodyBytes))
+		return nil, fmt.Errorf("Loki API returned unexpected status %q", labelResponse.Status)
 	}
 
 	// Check if Data is nil or empty and handle it explicitly
@@ -179,7 +181,10 @@ type authRoundTripper struct {
 	accessToken string
 	idToken     string
 	apiKey      string
-	underlying  http.RoundTripper
+	// orgID is the Grafana organization requests are made in, or 0 for the
+	// default organization of the credentials.
+	orgID      int64
+	underlying http.RoundTripper
 }
 
 func (rt *authRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
@@ -189,6 +194,9 @@ func (rt *authRoundTripper) RoundTrip(req *http.Request) (*http.Response, error)
 	} else if rt.apiKey != "" {
 		req.Header.Set("Authorization", "Bearer "+rt.apiKey)
 	}
+	if rt.orgID != 0 {
+		req.Header.Set(mcpgrafana.OrgIDHeader, strconv.FormatInt(rt.orgID, 10))
+	}
 
 	resp, err := rt.underlying.RoundTrip(req)
 	if err != nil {
@@ -198,6 +206,34 @@ func (rt *authRoundTripper) RoundTrip(req *http.Request) (*http.Response, error)
 	return resp, nil
 }
 
+// newGrafanaHTTPClient returns a client for Grafana's HTTP API, such as its
+// datasource proxy, which uses the TLS configuration, credentials,
+// organization, extra headers and retries of the Grafana configuration in
+// ctx, and requests compressed responses.
+func newGrafanaHTTPClient(ctx context.Context) (*http.Client, error) {
+	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+
+	// Create custom transport with TLS configuration if available
+	var transport http.RoundTripper = http.DefaultTransport
+	if tlsConfig := cfg.TLSConfig; tlsConfig != nil {
+		var err error
+		transport, err = tlsConfig.HTTPTransport(transport.(*http.Transport))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create custom transport: %w", err)
+		}
+	}
+
+	return &http.Client{
+		Transport: &authRoundTripper{
+			accessToken: cfg.AccessToken,
+			idToken:     cfg.IDToken,
+			apiKey:      cfg.APIKey,
+			orgID:       cfg.OrgID,
+			underlying:  cfg.Retry.RoundTripper(mcpgrafana.WithCompression(mcpgrafana.WithExtraHeaders(transport, cfg.ExtraHeaders))),
+		},
+	}, nil
+}
+
 // ListLokiLabelNamesParams defines the parameters for listing Loki label names
 type ListLokiLabelNamesParams struct {
 	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
@@ -275,18 +311,134 @@ var ListLokiLabelValues = mcpgrafana.MustTool(
 	mcp.WithReadOnlyHintAnnotation(true),
 )
 
+// SeriesResponse represents the http json response to a series query
+type SeriesResponse struct {
+	Status string              `json:"status"`
+	Data   []map[string]string `json:"data,omitempty"`
+}
+
+// fetchSeries fetches the label sets of the streams matching any of the
+// given selectors
+func (c *Client) fetchSeries(ctx context.Context, matchers []string, startRFC3339, endRFC3339 string) ([]map[string]string, error) {
+	params := url.Values{}
+	for _, m := range matchers {
+		params.Add("match[]", m)
+	}
+	if err := addTimeRangeParams(params, startRFC3339, endRFC3339); err != nil {
+		return nil, err
+	}
+
+	var seriesResponse SeriesResponse
+	if err := c.makeRequest(ctx, "GET", "/loki/api/v1/series", params, &seriesResponse); err != nil {
+		return nil, err
+	}
+	if seriesResponse.Status != "success" {
+		return nil, fmt.Errorf("Loki API returned unexpected status %q", seriesResponse.Status)
+	}
+	return seriesResponse.Data, nil
+}
+
+// ListLokiSeriesParams defines the parameters for listing Loki series
+type ListLokiSeriesParams struct {
+	DatasourceUID string   `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	Matchers      []string `json:"matchers" jsonschema:"required,description=One or more stream selectors (e.g. '{app=\"api\"}'). Streams matching any of them are returned"`
+	StartRFC3339  string   `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the query in RFC3339 format (defaults to 1 hour ago)"`
+	EndRFC3339    string   `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the query in RFC3339 format (defaults to now)"`
+	Limit         int      `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of series to return (default 100\\, max 1000)"`
+}
+
+// ListLokiSeriesResult is the result of listing Loki series
+type ListLokiSeriesResult struct {
+	// Series are the label sets of the matching streams, sorted.
+	Series []map[string]string `json:"series"`
+	// Truncated is true if more series matched than the limit.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// seriesKey returns a canonical string form of a label set, used to sort
+// series
+func seriesKey(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+strconv.Quote(v))
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// listLokiSeries lists the label sets of the streams matching the given
+// selectors in a Loki datasource
+func listLokiSeries(ctx context.Context, args ListLokiSeriesParams) (*ListLokiSeriesResult, error) {
+	if len(args.Matchers) == 0 {
+		return nil, fmt.Errorf("at least one matcher is required")
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = DefaultLokiSeriesLimit
+	}
+	limit = min(limit, MaxLokiSeriesLimit)
+
+	client, err := newLokiClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("creating Loki client: %w", err)
+	}
+
+	startTime, endTime := getDefaultTimeRange(args.StartRFC3339, args.EndRFC3339)
+	series, err := client.fetchSeries(ctx, args.Matchers, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sort the series so that the same ones are returned when truncating.
+	slices.SortFunc(series, func(a, b map[string]string) int {
+		return strings.Compare(seriesKey(a), seriesKey(b))
+	})
+	result := &ListLokiSeriesResult{Series: []map[string]string{}}
+	if len(series) > limit {
+		series = series[:limit]
+		result.Truncated = true
+	}
+	result.Series = append(result.Series, series...)
+	return result, nil
+}
+
+// ListLokiSeries is a tool for listing Loki series
+var ListLokiSeries = mcpgrafana.MustTool(
+	"grafana_list_loki_series",
+	"Lists the full label sets of the log streams matching one or more stream selectors within a Loki datasource and time range. Returns each stream's labels (e.g., `{\"app\": \"api\", \"env\": \"prod\", \"pod\": \"api-7f9c\"}`), showing which label combinations actually exist so that selectors can be built without trial and error. `truncated` is true if more streams matched than the limit (default 100). Defaults to the last hour if the time range is omitted.",
+	listLokiSeries,
+	mcp.WithTitleAnnotation("List Loki series"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// lokiEncodingFlagsHeader is the header selecting the encoding of Loki
+// query responses.
+const lokiEncodingFlagsHeader = "X-Loki-Response-Encoding-Flags"
+
 // LogStream represents a stream of log entries from Loki
 type LogStream struct {
 	Stream map[string]string   `json:"stream"`
-	Values [][]json.RawMessage `json:"values"` // [timestamp, value] where value can be string or number
+	Values [][]json.RawMessage `json:"values"` // [timestamp, value, metadata] where value can be string or number
+	// Metric holds the labels of the series of metric queries, which Loki
+	// returns as a matrix instead of streams.
+	Metric map[string]string `json:"metric,omitempty"`
+}
+
+// entryMetadata is the metadata of a log line returned by Loki when labels
+// are categorized.
+type entryMetadata struct {
+	StructuredMetadata map[string]string `json:"structuredMetadata"`
+	Parsed             map[string]string `json:"parsed"`
 }
 
 // QueryRangeResponse represents the response from Loki's query_range API
 type QueryRangeResponse struct {
 	Status string `json:"status"`
 	Data   struct {
-		ResultType string      `json:"resultType"`
-		Result     []LogStream `json:"result"`
+		ResultType string            `json:"resultType"`
+		Result     []LogStream       `json:"result"`
+		Stats      lokiResponseStats `json:"stats"`
 	} `json:"data"`
 }
 
@@ -344,32 +496,118 @@ func (c *Client) fetchLogs(ctx context.Context, query, startRFC3339, endRFC3339
 		params.Add("direction", direction)
 	}
 
-	bodyBytes, err := c.makeRequest(ctx, "GET", "/loki/api/v1/query_range", params)
-	if err != nil {
+	var queryResponse QueryRangeResponse
+	if err := c.makeRequest(ctx, "GET", "/loki/api/v1/query_range", params, &queryResponse); err != nil {
 		return nil, err
 	}
 
-	var queryResponse QueryRangeResponse
-	err = json.Unmarshal(bodyBytes, &queryResponse)
-	if err != nil {
-		return nil, fmt.Errorf("unmarshalling response (content: %s): %w", string(bodyBytes), err)
+	if queryResponse.Status != "success" {
+		return nil, fmt.Errorf("Loki API returned unexpected status %q", queryResponse.Status)
 	}
+	recordLokiQueryStats(ctx, queryResponse.Data.Stats)
+
+	return queryResponse.Data.Result, nil
+}
 
+// instantQueryResponse represents the response from Loki's query API
+type instantQueryResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		ResultType string            `json:"resultType"`
+		Result     json.RawMessage   `json:"result"`
+		Stats      lokiResponseStats `json:"stats"`
+	} `json:"data"`
+}
+
+// parseSampleValue parses the value of a metric sample, which Loki encodes as
+// a string.
+func parseSampleValue(raw json.RawMessage) (float64, bool) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	return v, err == nil
+}
+
+// fetchInstant runs an instant query, evaluating it at a single point in
+// time. Metric queries return one sample per series, with timestamps in
+// seconds.
+func (c *Client) fetchInstant(ctx context.Context, query, timeRFC3339 string, limit int, direction string) ([]LogEntry, error) {
+	params := url.Values{}
+	params.Add("query", query)
+	if timeRFC3339 != "" {
+		t, err := time.Parse(time.RFC3339, timeRFC3339)
+		if err != nil {
+			return nil, fmt.Errorf("parsing time: %w", err)
+		}
+		params.Add("time", fmt.Sprintf("%d", t.UnixNano()))
+	}
+	if limit > 0 {
+		params.Add("limit", fmt.Sprintf("%d", limit))
+	}
+	if direction != "" {
+		params.Add("direction", direction)
+	}
+
+	var queryResponse instantQueryResponse
+	if err := c.makeRequest(ctx, "GET", "/loki/api/v1/query", params, &queryResponse); err != nil {
+		return nil, err
+	}
 	if queryResponse.Status != "success" {
-		return nil, fmt.Errorf("Loki API returned unexpected response format: %s", string(bodyBytes))
+		return nil, fmt.Errorf("Loki API returned unexpected status %q", queryResponse.Status)
 	}
+	recordLokiQueryStats(ctx, queryResponse.Data.Stats)
 
-	return queryResponse.Data.Result, nil
+	entries := []LogEntry{}
+	switch queryResponse.Data.ResultType {
+	case "vector":
+		var vector []struct {
+			Metric map[string]string  `json:"metric"`
+			Value  [2]json.RawMessage `json:"value"`
+		}
+		if err := json.Unmarshal(queryResponse.Data.Result, &vector); err != nil {
+			return nil, fmt.Errorf("unmarshalling vector result: %w", err)
+		}
+		for _, sample := range vector {
+			if v, ok := parseSampleValue(sample.Value[1]); ok {
+				entries = append(entries, LogEntry{Timestamp: string(sample.Value[0]), Value: &v, Labels: sample.Metric})
+			}
+		}
+	case "scalar":
+		var scalar [2]json.RawMessage
+		if err := json.Unmarshal(queryResponse.Data.Result, &scalar); err != nil {
+			return nil, fmt.Errorf("unmarshalling scalar result: %w", err)
+		}
+		if v, ok := parseSampleValue(scalar[1]); ok {
+			entries = append(entries, LogEntry{Timestamp: string(scalar[0]), Value: &v})
+		}
+	case "streams":
+		var streams []LogStream
+		if err := json.Unmarshal(queryResponse.Data.Result, &streams); err != nil {
+			return nil, fmt.Errorf("unmarshalling streams result: %w", err)
+		}
+		entries = streamsToEntries(streams)
+	default:
+		return nil, fmt.Errorf("Loki API returned unexpected result type %q", queryResponse.Data.ResultType)
+	}
+	return entries, nil
 }
 
 // QueryLokiLogsParams defines the parameters for querying Loki logs
 type QueryLokiLogsParams struct {
-	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
-	LogQL         string `json:"logql" jsonschema:"required,description=The LogQL query to execute against Loki. This can be a simple label matcher or a complex query with filters\\, parsers\\, and expressions. Supports full LogQL syntax including label matchers\\, filter operators\\, pattern expressions\\, and pipeline operations."`
-	StartRFC3339  string `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the query in RFC3339 format"`
-	EndRFC3339    string `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the query in RFC3339 format"`
-	Limit         int    `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of log lines to return (default: 10\\, max: 100)"`
-	Direction     string `json:"direction,omitempty" jsonschema:"description=Optionally\\, the direction of the query: 'forward' (oldest first) or 'backward' (newest first\\, default)"`
+	DatasourceUID string   `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	LogQL         string   `json:"logql" jsonschema:"required,description=The LogQL query to execute against Loki. This can be a simple label matcher or a complex query with filters\\, parsers\\, and expressions. Supports full LogQL syntax including label matchers\\, filter operators\\, pattern expressions\\, and pipeline operations."`
+	StartRFC3339  string   `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the query in RFC3339 format"`
+	EndRFC3339    string   `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the query in RFC3339 format. Instant queries are evaluated at this time (defaults to now)"`
+	QueryType     string   `json:"queryType,omitempty" jsonschema:"description=Optionally\\, the type of query: 'range' (default) returns entries over the time range\\, 'instant' evaluates a metric query at a single point in time and returns one value per series\\, e.g. the current error rate with 'sum by (app) (rate({env=\"prod\"} |= \"error\" [5m]))'"`
+	Limit         int      `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of entries to return. For log queries\\, the number of log lines (default: 10\\, max: 100). For metric queries\\, the number of samples across all series (default: 1000\\, max: 10000). Further samples are omitted with a note. The maximums may be configured differently on the server"`
+	Direction     string   `json:"direction,omitempty" jsonschema:"description=Optionally\\, the direction of the query: 'forward' (oldest first) or 'backward' (newest first\\, default)"`
+	OutputFormat  string   `json:"outputFormat,omitempty" jsonschema:"description=The format of the result: 'json' (default)\\, 'csv' or 'markdown-table'. Tables have one row per entry with a column per label\\, which is much more compact than JSON and convenient for further analysis"`
+	Format        string   `json:"format,omitempty" jsonschema:"description=Deprecated: use outputFormat. The format of the result: 'json' (default) or 'csv'"`
+	GroupByStream bool     `json:"groupByStream,omitempty" jsonschema:"description=Optionally\\, group the entries by stream\\, returning the labels of each stream once instead of with every entry. This greatly reduces the size of results with many labels. Not supported with the table formats"`
+	Fields        []string `json:"fields,omitempty" jsonschema:"description=Optionally\\, the names of the labels\\, structured metadata and parsed fields to return\\, dropping all others\\, e.g. ['service_name'\\, 'trace_id'\\, 'level']. Log lines and sample values are always returned"`
+	Summarize     bool     `json:"summarize,omitempty" jsonschema:"description=Optionally\\, if the query matches more log lines than the limit or more than 64KB of lines\\, return a summary instead of the lines: the number of lines per stream\\, the most frequent line patterns\\, the first and last timestamps and a sample of lines spread over the result (as many as the limit). Up to 5000 lines are summarized. Only supported with the json format"`
 }
 
 // LogEntry represents a single log entry or metric sample with metadata
@@ -378,60 +616,172 @@ type LogEntry struct {
 	Line      string            `json:"line,omitempty"`  // For log queries
 	Value     *float64          `json:"value,omitempty"` // For metric queries
 	Labels    map[string]string `json:"labels"`
+	// StructuredMetadata and Parsed are the structured metadata of a log
+	// line, e.g. the attributes of logs ingested with OTLP, and the labels
+	// extracted from it by parsers such as json or logfmt.
+	StructuredMetadata map[string]string `json:"structuredMetadata,omitempty"`
+	Parsed             map[string]string `json:"parsed,omitempty"`
+}
+
+// field returns the value of a label, structured metadata or parsed field.
+func (e LogEntry) field(name string) string {
+	if v, ok := e.Labels[name]; ok {
+		return v
+	}
+	if v, ok := e.StructuredMetadata[name]; ok {
+		return v
+	}
+	return e.Parsed[name]
+}
+
+// projectFields keeps only the named labels, structured metadata and parsed
+// fields of each entry.
+func projectFields(entries []LogEntry, fields []string) {
+	keep := func(m map[string]string) map[string]string {
+		if m == nil {
+			return nil
+		}
+		projected := map[string]string{}
+		for _, name := range fields {
+			if v, ok := m[name]; ok {
+				projected[name] = v
+			}
+		}
+		return projected
+	}
+	for i, e := range entries {
+		entries[i].Labels = keep(e.Labels)
+		if entries[i].StructuredMetadata = keep(e.StructuredMetadata); len(entries[i].StructuredMetadata) == 0 {
+			entries[i].StructuredMetadata = nil
+		}
+		if entries[i].Parsed = keep(e.Parsed); len(entries[i].Parsed) == 0 {
+			entries[i].Parsed = nil
+		}
+	}
+}
+
+// maxLokiLogLimit returns the maximum number of lines of a log query.
+func maxLokiLogLimit(ctx context.Context) int {
+	if n := mcpgrafana.GrafanaConfigFromContext(ctx).LokiLimits.MaxLogLines; n > 0 {
+		return n
+	}
+	return MaxLokiLogLimit
+}
+
+// maxLokiMetricLimit returns the maximum number of samples of a metric query.
+func maxLokiMetricLimit(ctx context.Context) int {
+	if n := mcpgrafana.GrafanaConfigFromContext(ctx).LokiLimits.MaxMetricSamples; n > 0 {
+		return n
+	}
+	return MaxLokiMetricLimit
 }
 
 // enforceLogLimit ensures a log limit value is within acceptable bounds
-func enforceLogLimit(requestedLimit int) int {
+func enforceLogLimit(ctx context.Context, requestedLimit int) int {
 	if requestedLimit <= 0 {
-		return DefaultLokiLogLimit
+		return min(DefaultLokiLogLimit, maxLokiLogLimit(ctx))
 	}
-	if requestedLimit > MaxLokiLogLimit {
-		return MaxLokiLogLimit
+	return min(requestedLimit, maxLokiLogLimit(ctx))
+}
+
+// enforceMetricLimit ensures a metric sample limit value is within
+// acceptable bounds
+func enforceMetricLimit(ctx context.Context, requestedLimit int) int {
+	if requestedLimit <= 0 {
+		return min(DefaultLokiMetricLimit, maxLokiMetricLimit(ctx))
 	}
-	return requestedLimit
+	return min(requestedLimit, maxLokiMetricLimit(ctx))
+}
+
+// isLokiMetricQuery reports whether a LogQL query is a metric query, which
+// returns samples instead of log lines. Invalid queries are reported as log
+// queries, leaving it to Loki to reject them.
+func isLokiMetricQuery(logql string) bool {
+	q, err := parseLogQL(logql)
+	return err == nil && q.metric
 }
 
-// queryLokiLogs queries logs from a Loki datasource using LogQL
+// queryLokiLogs queries logs from a Loki datasource using LogQL. Log queries
+// are limited to a number of lines and metric queries to a number of
+// samples, since a single series of a metric query may have more samples
+// than a log query has lines.
 func queryLokiLogs(ctx context.Context, args QueryLokiLogsParams) ([]LogEntry, error) {
-	client, err := newLokiClient(ctx, args.DatasourceUID)
+	if !isLokiMetricQuery(args.LogQL) {
+		return queryLokiLogsWithLimit(ctx, args, enforceLogLimit(ctx, args.Limit))
+	}
+	limit := enforceMetricLimit(ctx, args.Limit)
+	entries, err := queryLokiLogsWithLimit(ctx, args, limit)
 	if err != nil {
-		return nil, fmt.Errorf("creating Loki client: %w", err)
+		return nil, err
 	}
+	if len(entries) > limit {
+		mcpgrafana.SetResultNote(ctx, "lokiMetricLimit", fmt.Sprintf("%d more samples omitted; narrow the time range, aggregate the series or raise limit to see them.", len(entries)-limit))
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
 
+// queryLokiLogsWithLimit queries logs from a Loki datasource, returning at
+// most limit log lines regardless of the limit of args.
+func queryLokiLogsWithLimit(ctx context.Context, args QueryLokiLogsParams, limit int) ([]LogEntry, error) {
 	// Get default time range if not provided
 	startTime, endTime := getDefaultTimeRange(args.StartRFC3339, args.EndRFC3339)
 
-	// Apply limit constraints
-	limit := enforceLogLimit(args.Limit)
-
 	// Set default direction if not provided
 	direction := args.Direction
 	if direction == "" {
 		direction = "backward" // Most recent logs first
 	}
 
+	queryType := cmp.Or(args.QueryType, "range")
+	if queryType != "range" && queryType != "instant" {
+		return nil, fmt.Errorf("invalid query type %q: must be 'range' or 'instant'", args.QueryType)
+	}
+	if usesDSQuery(ctx, "loki") {
+		return queryLokiViaDSQuery(ctx, args.DatasourceUID, args.LogQL, queryType, startTime, endTime, limit, direction)
+	}
+
+	client, err := newLokiClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("creating Loki client: %w", err)
+	}
+	if queryType == "instant" {
+		return client.fetchInstant(ctx, args.LogQL, args.EndRFC3339, limit, direction)
+	}
+
 	streams, err := client.fetchLogs(ctx, args.LogQL, startTime, endTime, limit, direction)
 	if err != nil {
 		return nil, err
 	}
 
+	return streamsToEntries(streams), nil
+}
+
+// streamsToEntries converts Loki streams to a flat list of log entries,
+// skipping values which cannot be parsed.
+func streamsToEntries(streams []LogStream) []LogEntry {
 	// Handle empty results
 	if len(streams) == 0 {
-		return []LogEntry{}, nil
+		return []LogEntry{}
 	}
 
 	// Convert the streams to a flat list of log entries
 	var entries []LogEntry
 	for _, stream := range streams {
+		metric := stream.Stream["__type__"] == "metrics"
+		labels := stream.Stream
+		if stream.Metric != nil {
+			metric, labels = true, stream.Metric
+		}
 		for _, value := range stream.Values {
 			if len(value) >= 2 {
 				entry := LogEntry{
 					Timestamp: string(value[0]),
-					Labels:    stream.Stream,
+					Labels:    labels,
 				}
 
 				// Handle metric queries (numeric values) vs log queries
-				if stream.Stream["__type__"] == "metrics" {
+				if metric {
 					// For metric queries, parse the value as a number
 					var numStr string
 					if err := json.Unmarshal(value[1], &numStr); err == nil {
@@ -460,6 +810,13 @@ func queryLokiLogs(ctx context.Context, args QueryLokiLogsParams) ([]LogEntry, e
 						// Skip invalid log lines
 						continue
 					}
+					if len(value) >= 3 {
+						var metadata entryMetadata
+						if err := json.Unmarshal(value[2], &metadata); err == nil {
+							entry.StructuredMetadata = metadata.StructuredMetadata
+							entry.Parsed = metadata.Parsed
+						}
+					}
 				}
 
 				entries = append(entries, entry)
@@ -469,17 +826,99 @@ func queryLokiLogs(ctx context.Context, args QueryLokiLogsParams) ([]LogEntry, e
 
 	// If we processed all streams but still have no entries, return an empty slice
 	if len(entries) == 0 {
-		return []LogEntry{}, nil
+		return []LogEntry{}
+	}
+
+	return entries
+}
+
+// LogStreamGroup is the log entries or metric samples of a single stream,
+// whose labels are shared by all of them
+type LogStreamGroup struct {
+	Labels  map[string]string `json:"labels"`
+	Entries []LogStreamEntry  `json:"entries"`
+}
+
+// LogStreamEntry is a log entry or metric sample of a LogStreamGroup
+type LogStreamEntry struct {
+	Timestamp          string            `json:"timestamp"`
+	Line               string            `json:"line,omitempty"`
+	Value              *float64          `json:"value,omitempty"`
+	StructuredMetadata map[string]string `json:"structuredMetadata,omitempty"`
+	Parsed             map[string]string `json:"parsed,omitempty"`
+}
+
+// groupEntriesByStream groups log entries by their labels, in the order in
+// which each stream first appears.
+func groupEntriesByStream(entries []LogEntry) []LogStreamGroup {
+	groups := []LogStreamGroup{}
+	index := map[string]int{}
+	for _, e := range entries {
+		key := seriesKey(e.Labels)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, LogStreamGroup{Labels: e.Labels})
+		}
+		groups[i].Entries = append(groups[i].Entries, LogStreamEntry{
+			Timestamp:          e.Timestamp,
+			Line:               e.Line,
+			Value:              e.Value,
+			StructuredMetadata: e.StructuredMetadata,
+			Parsed:             e.Parsed,
+		})
+	}
+	return groups
+}
+
+// queryLokiLogsWithFormat runs a Loki query, returning the result in the
+// requested format and shape.
+func queryLokiLogsWithFormat(ctx context.Context, args QueryLokiLogsParams) (any, error) {
+	format, err := resultFormat(args.Format, args.OutputFormat)
+	if err != nil {
+		return nil, err
 	}
+	if args.GroupByStream && format != formatJSON {
+		return nil, fmt.Errorf("groupByStream is not supported with the %s format", format)
+	}
+	if args.Summarize {
+		if format != formatJSON {
+			return nil, fmt.Errorf("summarize is not supported with the %s format", format)
+		}
+		summary, entries, err := queryLokiLogsOrSummary(ctx, args)
+		if err != nil || summary != nil {
+			return summary, err
+		}
+		return formatLokiEntries(entries, args, format)
+	}
+	entries, err := queryLokiLogs(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return formatLokiEntries(entries, args, format)
+}
 
+// formatLokiEntries returns the entries of a Loki query in the requested
+// format and shape.
+func formatLokiEntries(entries []LogEntry, args QueryLokiLogsParams, format string) (any, error) {
+	if len(args.Fields) > 0 {
+		projectFields(entries, args.Fields)
+	}
+	switch {
+	case format != formatJSON:
+		return lokiTable(entries).render(format)
+	case args.GroupByStream:
+		return groupEntriesByStream(entries), nil
+	}
 	return entries, nil
 }
 
 // QueryLokiLogs is a tool for querying logs from Loki
 var QueryLokiLogs = mcpgrafana.MustTool(
 	"grafana_query_loki_logs",
-	"Executes a LogQL query against a Loki datasource to retrieve log entries or metric values. Returns a list of results, each containing a timestamp, labels, and either a log line (`line`) or a numeric metric value (`value`). Defaults to the last hour, a limit of 10 entries, and 'backward' direction (newest first). Supports full LogQL syntax for log and metric queries (e.g., `{app=\"foo\"} |= \"error\"`, `rate({app=\"bar\"}[1m])`). Prefer using `grafana_query_loki_stats` first to check stream size and `grafana_list_loki_label_names` and `grafana_list_loki_label_values` to verify labels exist.",
-	queryLokiLogs,
+	"Executes a LogQL query against a Loki datasource to retrieve log entries or metric values. Returns a list of results, each containing a timestamp, labels, and either a log line (`line`) or a numeric metric value (`value`). Defaults to the last hour, a limit of 10 log lines for log queries or 1000 samples for metric queries, and 'backward' direction (newest first). Set `queryType: 'instant'` to evaluate a metric query at a single point in time, e.g. to compute a current error rate without downloading log lines. Supports full LogQL syntax for log and metric queries (e.g., `{app=\"foo\"} |= \"error\"`, `rate({app=\"bar\"}[1m])`). Prefer using `grafana_query_loki_stats` first to check stream size and `grafana_list_loki_label_names` and `grafana_list_loki_label_values` to verify labels exist. Results can be returned as a compact CSV or Markdown table with `outputFormat: 'csv'` or `outputFormat: 'markdown-table'`, or grouped by stream with `groupByStream: true` so that each stream's labels are returned once. Set `summarize: true` to get a summary of the matching lines (line counts per stream, top patterns, first and last timestamps and a sample) instead of the lines themselves when they exceed the limit, e.g. for a first look at a noisy service. Log lines include their structured metadata (e.g. the attributes of logs ingested with OTLP) and the fields extracted by parsers such as `| json` separately from the stream labels; use `fields` to return only some of them. The `_meta` field of the result has the statistics of the query (`lokiQueryStats`), such as the bytes and lines Loki processed and its execution time, which tell how expensive it was.",
+	queryLokiLogsWithFormat,
 	mcp.WithTitleAnnotation("Query Loki logs"),
 	mcp.WithIdempotentHintAnnotation(true),
 	mcp.WithReadOnlyHintAnnotation(true),
@@ -495,15 +934,9 @@ func (c *Client) fetchStats(ctx context.Context, query, startRFC3339, endRFC3339
 		return nil, err
 	}
 
-	bodyBytes, err := c.makeRequest(ctx, "GET", "/loki/api/v1/index/stats", params)
-	if err != nil {
-		return nil, err
-	}
-
 	var stats Stats
-	err = json.Unmarshal(bodyBytes, &stats)
-	if err != nil {
-		return nil, fmt.Errorf("unmarshalling response (content: %s): %w", string(bodyBytes), err)
+	if err := c.makeRequest(ctx, "GET", "/loki/api/v1/index/stats", params, &stats); err != nil {
+		return nil, err
 	}
 
 	return &stats, nil
@@ -549,6 +982,12 @@ var QueryLokiStats = mcpgrafana.MustTool(
 func AddLokiTools(mcp *server.MCPServer) {
 	ListLokiLabelNames.Register(mcp)
 	ListLokiLabelValues.Register(mcp)
+	ListLokiSeries.Register(mcp)
+	ListLokiDetectedFields.Register(mcp)
 	QueryLokiStats.Register(mcp)
+	QueryLokiPatterns.Register(mcp)
 	QueryLokiLogs.Register(mcp)
+	QueryLokiLogsFederated.Register(mcp)
+	ValidateLogQL.Register(mcp)
+	TailLokiLogs.Register(mcp)
 }
@@ -0,0 +1,198 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	// DefaultLokiPatternLimit is the default number of log patterns to
+	// return if not specified
+	DefaultLokiPatternLimit = 20
+
+	// MaxLokiPatternLimit is the maximum number of log patterns that can be
+	// requested
+	MaxLokiPatternLimit = 100
+)
+
+// patternsResponse represents the http json response of Loki's patterns
+// endpoint
+type patternsResponse struct {
+	Status string `json:"status"`
+	Data   []struct {
+		Pattern string `json:"pattern"`
+		Level   string `json:"level,omitempty"`
+		// Samples are [unix seconds, count] pairs.
+		Samples [][2]int64 `json:"samples"`
+	} `json:"data"`
+}
+
+// LogPattern is a pattern of log lines detected by Loki, such as
+// `<_> level=error msg="connection refused" <_>`, with the number of lines
+// matching it.
+type LogPattern struct {
+	Pattern   string `json:"pattern"`
+	Level     string `json:"level,omitempty"`
+	Count     int64  `json:"count"`
+	FirstSeen string `json:"firstSeen,omitempty"`
+	LastSeen  string `json:"lastSeen,omitempty"`
+}
+
+// QueryLokiPatternsParams defines the parameters for querying Loki log
+// patterns
+type QueryLokiPatternsParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	LogQL         string `json:"logql" jsonschema:"required,description=A stream selector (e.g. '{app=\"api\"}') whose log lines to detect patterns in"`
+	StartRFC3339  string `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the query in RFC3339 format (defaults to 1 hour ago)"`
+	EndRFC3339    string `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the query in RFC3339 format (defaults to now)"`
+	Limit         int    `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of patterns to return\\, most frequent first (default 20\\, max 100)"`
+}
+
+// fetchPatterns fetches the log patterns of the streams matching a selector
+func (c *Client) fetchPatterns(ctx context.Context, query, startRFC3339, endRFC3339 string) ([]LogPattern, error) {
+	params := url.Values{}
+	params.Add("query", query)
+	if err := addTimeRangeParams(params, startRFC3339, endRFC3339); err != nil {
+		return nil, err
+	}
+
+	var response patternsResponse
+	if err := c.makeRequest(ctx, "GET", "/loki/api/v1/patterns", params, &response); err != nil {
+		return nil, err
+	}
+	if response.Status != "success" {
+		return nil, fmt.Errorf("Loki API returned unexpected status %q", response.Status)
+	}
+
+	patterns := make([]LogPattern, 0, len(response.Data))
+	for _, p := range response.Data {
+		pattern := LogPattern{Pattern: p.Pattern, Level: p.Level}
+		var first, last int64
+		for _, sample := range p.Samples {
+			pattern.Count += sample[1]
+			if first == 0 || sample[0] < first {
+				first = sample[0]
+			}
+			last = max(last, sample[0])
+		}
+		if first != 0 {
+			pattern.FirstSeen = time.Unix(first, 0).UTC().Format(time.RFC3339)
+			pattern.LastSeen = time.Unix(last, 0).UTC().Format(time.RFC3339)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// queryLokiPatterns returns the most frequent log patterns of the streams
+// matching a selector
+func queryLokiPatterns(ctx context.Context, args QueryLokiPatternsParams) ([]LogPattern, error) {
+	limit := args.Limit
+	if limit <= 0 {
+		limit = DefaultLokiPatternLimit
+	}
+	limit = min(limit, MaxLokiPatternLimit)
+
+	client, err := newLokiClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("creating Loki client: %w", err)
+	}
+
+	startTime, endTime := getDefaultTimeRange(args.StartRFC3339, args.EndRFC3339)
+	patterns, err := client.fetchPatterns(ctx, args.LogQL, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(patterns, func(a, b LogPattern) int { return cmp.Compare(b.Count, a.Count) })
+	return patterns[:min(len(patterns), limit)], nil
+}
+
+// QueryLokiPatterns is a tool for querying Loki log patterns
+var QueryLokiPatterns = mcpgrafana.MustTool(
+	"grafana_query_loki_patterns",
+	"Retrieves the log patterns Loki detected in the streams matching a stream selector within a time range, most frequent first. Each pattern is a log line template with variable parts replaced by `<_>` (e.g., `<_> level=error msg=\"connection refused\" addr=<_>`), with the number of matching lines and when they were first and last seen. Use it to triage noisy streams before writing LogQL queries, e.g. to find the kinds of errors a service logs. Requires pattern ingestion to be enabled in Loki. Defaults to the last hour if the time range is omitted.",
+	queryLokiPatterns,
+	mcp.WithTitleAnnotation("Query Loki log patterns"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// DetectedField is a field Loki detected in log lines, e.g. by parsing them
+// as logfmt or JSON
+type DetectedField struct {
+	Label       string   `json:"label"`
+	Type        string   `json:"type"`
+	Cardinality int      `json:"cardinality"`
+	Parsers     []string `json:"parsers,omitempty"`
+}
+
+// detectedFieldsResponse represents the http json response of Loki's
+// detected_fields endpoint
+type detectedFieldsResponse struct {
+	Fields []DetectedField `json:"fields"`
+}
+
+// ListLokiDetectedFieldsParams defines the parameters for listing the fields
+// detected in Loki log lines
+type ListLokiDetectedFieldsParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	LogQL         string `json:"logql" jsonschema:"required,description=A LogQL log query (e.g. '{app=\"api\"} |= \"error\"') whose log lines to detect fields in"`
+	StartRFC3339  string `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the query in RFC3339 format (defaults to 1 hour ago)"`
+	EndRFC3339    string `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the query in RFC3339 format (defaults to now)"`
+}
+
+// fetchDetectedFields fetches the fields detected in the log lines matching
+// a query
+func (c *Client) fetchDetectedFields(ctx context.Context, query, startRFC3339, endRFC3339 string) ([]DetectedField, error) {
+	params := url.Values{}
+	params.Add("query", query)
+	if err := addTimeRangeParams(params, startRFC3339, endRFC3339); err != nil {
+		return nil, err
+	}
+
+	var response detectedFieldsResponse
+	if err := c.makeRequest(ctx, "GET", "/loki/api/v1/detected_fields", params, &response); err != nil {
+		return nil, err
+	}
+	if response.Fields == nil {
+		return []DetectedField{}, nil
+	}
+	return response.Fields, nil
+}
+
+// listLokiDetectedFields lists the fields detected in the log lines matching
+// a query
+func listLokiDetectedFields(ctx context.Context, args ListLokiDetectedFieldsParams) ([]DetectedField, error) {
+	client, err := newLokiClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("creating Loki client: %w", err)
+	}
+
+	startTime, endTime := getDefaultTimeRange(args.StartRFC3339, args.EndRFC3339)
+	fields, err := client.fetchDetectedFields(ctx, args.LogQL, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(fields, func(a, b DetectedField) int { return cmp.Compare(a.Label, b.Label) })
+	return fields, nil
+}
+
+// ListLokiDetectedFields is a tool for listing the fields detected in Loki
+// log lines
+var ListLokiDetectedFields = mcpgrafana.MustTool(
+	"grafana_list_loki_detected_fields",
+	"Lists the fields Loki detected in the log lines matching a LogQL log query within a time range, such as the keys of logfmt or JSON lines and structured metadata. Returns each field's name, type (e.g. `string`, `int`, `duration`, `bytes`), number of distinct values and the parsers which extract it (e.g., `{\"label\": \"duration\", \"type\": \"duration\", \"cardinality\": 120, \"parsers\": [\"logfmt\"]}`). Use it to discover which fields can be filtered on or aggregated with `| logfmt` or `| json` before writing LogQL queries. Defaults to the last hour if the time range is omitted.",
+	listLokiDetectedFields,
+	mcp.WithTitleAnnotation("List Loki detected fields"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,58 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLokiDiscovery(t *testing.T) {
+	// query checks the query and start of a request to a Loki discovery
+	// endpoint.
+	query := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, `{app="api"}`, r.URL.Query().Get("query"))
+			assert.Equal(t, "1736164800000000000", r.URL.Query().Get("start"))
+			_, _ = w.Write([]byte(body))
+		}
+	}
+	server := newFakeGrafana(t, fakeRoutes{
+		"/loki/api/v1/patterns": query(`{"status":"success","data":[
+			{"pattern":"<_> level=info msg=\"request served\" <_>","level":"info","samples":[[1736164860,3],[1736164920,4]]},
+			{"pattern":"<_> level=error msg=\"connection refused\" <_>","level":"error","samples":[[1736164800,10],[1736165400,5]]}
+		]}`),
+		"/loki/api/v1/detected_fields": query(`{"fields":[
+			{"label":"status","type":"int","cardinality":4,"parsers":["logfmt"]},
+			{"label":"duration","type":"duration","cardinality":120,"parsers":["logfmt"]}
+		],"limit":1000}`),
+	})
+	client := &Client{httpClient: http.DefaultClient, baseURL: server.URL}
+	ctx := context.Background()
+
+	t.Run("patterns", func(t *testing.T) {
+		patterns, err := client.fetchPatterns(ctx, `{app="api"}`, "2025-01-06T12:00:00Z", "2025-01-06T13:00:00Z")
+		require.NoError(t, err)
+		require.Len(t, patterns, 2)
+		assert.Equal(t, LogPattern{
+			Pattern:   `<_> level=error msg="connection refused" <_>`,
+			Level:     "error",
+			Count:     15,
+			FirstSeen: "2025-01-06T12:00:00Z",
+			LastSeen:  "2025-01-06T12:10:00Z",
+		}, patterns[1])
+		assert.Equal(t, int64(7), patterns[0].Count)
+	})
+
+	t.Run("detected fields", func(t *testing.T) {
+		fields, err := client.fetchDetectedFields(ctx, `{app="api"}`, "2025-01-06T12:00:00Z", "2025-01-06T13:00:00Z")
+		require.NoError(t, err)
+		require.Len(t, fields, 2)
+		assert.Equal(t, DetectedField{Label: "duration", Type: "duration", Cardinality: 120, Parsers: []string{"logfmt"}}, fields[1])
+	})
+}
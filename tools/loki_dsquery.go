@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// usesDSQuery reports whether queries of datasources of the given type are
+// run through /api/ds/query instead of the datasource proxy.
+func usesDSQuery(ctx context.Context, dsType string) bool {
+	return slices.Contains(mcpgrafana.GrafanaConfigFromContext(ctx).DSQuery, dsType)
+}
+
+// queryLokiViaDSQuery runs a Loki query through /api/ds/query, returning the
+// same entries as the Loki API would.
+func queryLokiViaDSQuery(ctx context.Context, uid, logql, queryType, startRFC3339, endRFC3339 string, limit int, direction string) ([]LogEntry, error) {
+	ds, err := getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: uid})
+	if err != nil {
+		return nil, err
+	}
+	client, err := newDSQueryClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("creating query client: %w", err)
+	}
+	start, err := time.Parse(time.RFC3339, startRFC3339)
+	if err != nil {
+		return nil, fmt.Errorf("parsing start time: %w", err)
+	}
+	end, err := time.Parse(time.RFC3339, endRFC3339)
+	if err != nil {
+		return nil, fmt.Errorf("parsing end time: %w", err)
+	}
+	if queryType == "instant" {
+		start = end
+	}
+	frames, err := client.query(ctx, strconv.FormatInt(start.UnixMilli(), 10), strconv.FormatInt(end.UnixMilli(), 10), map[string]any{
+		"datasource": map[string]string{"uid": ds.UID, "type": ds.Type},
+		"expr":       logql,
+		"queryType":  queryType,
+		"maxLines":   limit,
+		"direction":  direction,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return framesToLogEntries(frames), nil
+}
+
+// framesToLogEntries converts the frames of a Loki query to log entries.
+// Log frames have a row per line, with its labels and their types, while
+// metric frames have a time and a value field per series.
+func framesToLogEntries(frames []dataFrame) []LogEntry {
+	entries := []LogEntry{}
+	for _, f := range frames {
+		fields := map[string]int{}
+		for i, field := range f.Schema.Fields {
+			fields[field.Name] = i
+		}
+		if line, ok := fields["Line"]; ok {
+			for _, row := range f.rows() {
+				entries = append(entries, frameLogEntry(row, fields, line))
+			}
+			continue
+		}
+		timeIndex, valueIndex := -1, -1
+		for i, field := range f.Schema.Fields {
+			switch field.Type {
+			case "time":
+				timeIndex = i
+			case "number":
+				valueIndex = i
+			}
+		}
+		if timeIndex < 0 || valueIndex < 0 {
+			continue
+		}
+		labels := f.Schema.Fields[valueIndex].Labels
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		for _, row := range f.rows() {
+			ms, ok := row[timeIndex].(float64)
+			if !ok {
+				continue
+			}
+			v, ok := row[valueIndex].(float64)
+			if !ok {
+				continue
+			}
+			entries = append(entries, LogEntry{
+				// Samples returned by the Loki API have timestamps in
+				// seconds.
+				Timestamp: strconv.FormatFloat(ms/1000, 'f', -1, 64),
+				Value:     &v,
+				Labels:    labels,
+			})
+		}
+	}
+	return entries
+}
+
+// frameLogEntry converts a row of a log frame to a log entry, splitting its
+// labels into stream labels, structured metadata and parsed fields by their
+// type.
+func frameLogEntry(row []any, fields map[string]int, line int) LogEntry {
+	value := func(name string) any {
+		if i, ok := fields[name]; ok {
+			return row[i]
+		}
+		return nil
+	}
+	entry := LogEntry{Labels: map[string]string{}}
+	entry.Line, _ = row[line].(string)
+	// Lines returned by the Loki API have timestamps in nanoseconds, encoded
+	// as JSON strings.
+	if ns, ok := value("tsNs").(string); ok {
+		entry.Timestamp = strconv.Quote(ns)
+	} else if ms, ok := value("Time").(float64); ok {
+		entry.Timestamp = strconv.Quote(strconv.FormatInt(int64(ms)*int64(time.Millisecond), 10))
+	}
+	labels, _ := value("labels").(map[string]any)
+	types, _ := value("labelTypes").(map[string]any)
+	for name, v := range labels {
+		s := fmt.Sprint(v)
+		switch types[name] {
+		case "S":
+			if entry.StructuredMetadata == nil {
+				entry.StructuredMetadata = map[string]string{}
+			}
+			entry.StructuredMetadata[name] = s
+		case "P":
+			if entry.Parsed == nil {
+				entry.Parsed = map[string]string{}
+			}
+			entry.Parsed[name] = s
+		default:
+			entry.Labels[name] = s
+		}
+	}
+	return entry
+}
@@ -0,0 +1,180 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	// MaxLokiFederatedDatasources is the maximum number of datasources
+	// queried by grafana_query_loki_logs_federated.
+	MaxLokiFederatedDatasources = 20
+
+	// lokiFederatedWorkers is the number of datasources queried at the same
+	// time.
+	lokiFederatedWorkers = 5
+)
+
+type QueryLokiLogsFederatedParams struct {
+	DatasourceUIDs []string `json:"datasourceUids,omitempty" jsonschema:"description=The UIDs of the Loki datasources to query (at most 20). Defaults to all Loki datasources"`
+	LogQL          string   `json:"logql" jsonschema:"required,description=The LogQL query to run against each datasource"`
+	StartRFC3339   string   `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the query in RFC3339 format (defaults to 1 hour ago)"`
+	EndRFC3339     string   `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the query in RFC3339 format (defaults to now)"`
+	QueryType      string   `json:"queryType,omitempty" jsonschema:"description=Optionally\\, the type of query: 'range' (default) or 'instant'"`
+	Limit          int      `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of log lines to return across all datasources (default: 10\\, max: 100). Metric queries are limited to a number of samples per datasource instead (default: 1000\\, max: 10000)"`
+	Direction      string   `json:"direction,omitempty" jsonschema:"description=Optionally\\, the direction of the query: 'forward' (oldest first) or 'backward' (newest first\\, default)"`
+	Fields         []string `json:"fields,omitempty" jsonschema:"description=Optionally\\, the names of the labels\\, structured metadata and parsed fields to return\\, dropping all others"`
+}
+
+// FederatedLogEntry is a log entry or metric sample of a federated query,
+// with the datasource it comes from.
+type FederatedLogEntry struct {
+	DatasourceUID string `json:"datasourceUid"`
+	LogEntry
+}
+
+// FederatedDatasource is the outcome of a federated query for one
+// datasource.
+type FederatedDatasource struct {
+	UID     string `json:"uid"`
+	Name    string `json:"name,omitempty"`
+	Entries int    `json:"entries"`
+	Error   string `json:"error,omitempty"`
+}
+
+type QueryLokiLogsFederatedResult struct {
+	Datasources []FederatedDatasource `json:"datasources"`
+	// Truncated is set if more log lines than the limit were returned by
+	// the datasources altogether.
+	Truncated bool                `json:"truncated,omitempty"`
+	Entries   []FederatedLogEntry `json:"entries"`
+}
+
+// lokiDatasources returns the datasources to query, all Loki datasources
+// if uids is empty.
+func lokiDatasources(ctx context.Context, uids []string) ([]FederatedDatasource, error) {
+	if len(uids) > 0 {
+		datasources := make([]FederatedDatasource, 0, len(uids))
+		for _, uid := range uids {
+			datasources = append(datasources, FederatedDatasource{UID: uid})
+		}
+		return datasources, nil
+	}
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.Datasources.GetDataSources()
+	if err != nil {
+		return nil, fmt.Errorf("list datasources: %w", err)
+	}
+	var datasources []FederatedDatasource
+	for _, ds := range resp.Payload {
+		if ds.Type == "loki" {
+			datasources = append(datasources, FederatedDatasource{UID: ds.UID, Name: ds.Name})
+		}
+	}
+	if len(datasources) == 0 {
+		return nil, fmt.Errorf("no Loki datasources found")
+	}
+	return datasources, nil
+}
+
+// logEntryTime returns the time of a log entry or metric sample.
+func logEntryTime(e LogEntry) time.Time {
+	ts := strings.Trim(e.Timestamp, `"`)
+	if e.Value != nil {
+		s, _ := strconv.ParseFloat(ts, 64)
+		return time.UnixMilli(int64(s * 1000))
+	}
+	ns, _ := strconv.ParseInt(ts, 10, 64)
+	return time.Unix(0, ns)
+}
+
+func queryLokiLogsFederated(ctx context.Context, args QueryLokiLogsFederatedParams) (*QueryLokiLogsFederatedResult, error) {
+	if len(args.DatasourceUIDs) > MaxLokiFederatedDatasources {
+		return nil, fmt.Errorf("at most %d datasources can be queried at once, got %d", MaxLokiFederatedDatasources, len(args.DatasourceUIDs))
+	}
+	datasources, err := lokiDatasources(ctx, args.DatasourceUIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(datasources) > MaxLokiFederatedDatasources {
+		return nil, fmt.Errorf("found %d Loki datasources, more than the %d which can be queried at once; select some with datasourceUids", len(datasources), MaxLokiFederatedDatasources)
+	}
+	limit := enforceLogLimit(ctx, args.Limit)
+
+	// Datasources which fail are reported with their error.
+	results := fanOut(ctx, "datasources", datasources, lokiFederatedWorkers, func(ctx context.Context, ds FederatedDatasource) ([]LogEntry, error) {
+		return queryLokiLogs(ctx, QueryLokiLogsParams{
+			DatasourceUID: ds.UID,
+			LogQL:         args.LogQL,
+			StartRFC3339:  args.StartRFC3339,
+			EndRFC3339:    args.EndRFC3339,
+			QueryType:     args.QueryType,
+			Limit:         args.Limit,
+			Direction:     args.Direction,
+		})
+	})
+	if err := allFailed("datasources", results); err != nil {
+		return nil, err
+	}
+	for i, r := range results {
+		if r.err != nil {
+			datasources[i].Error = r.err.Error()
+			continue
+		}
+		datasources[i].Entries = len(r.value)
+	}
+
+	result := &QueryLokiLogsFederatedResult{Datasources: datasources, Entries: []FederatedLogEntry{}}
+	lines := 0
+	for i, r := range results {
+		entries := r.value
+		if len(args.Fields) > 0 {
+			projectFields(entries, args.Fields)
+		}
+		for _, e := range entries {
+			result.Entries = append(result.Entries, FederatedLogEntry{DatasourceUID: datasources[i].UID, LogEntry: e})
+			if e.Value == nil {
+				lines++
+			}
+		}
+	}
+
+	// Merge the entries of all datasources by time, and keep the log lines
+	// within the limit, as a query of a single datasource would.
+	slices.SortStableFunc(result.Entries, func(a, b FederatedLogEntry) int {
+		c := logEntryTime(a.LogEntry).Compare(logEntryTime(b.LogEntry))
+		if args.Direction != "forward" {
+			c = -c
+		}
+		return c
+	})
+	if lines > limit {
+		result.Truncated = true
+		kept := 0
+		result.Entries = slices.DeleteFunc(result.Entries, func(e FederatedLogEntry) bool {
+			if e.Value != nil {
+				return false
+			}
+			kept++
+			return kept > limit
+		})
+	}
+	return result, nil
+}
+
+var QueryLokiLogsFederated = mcpgrafana.MustTool(
+	"grafana_query_loki_logs_federated",
+	"Run the same LogQL query against several Loki datasources at once, e.g. one per region or cluster, or against all Loki datasources if no UIDs are given. The datasources are queried concurrently and their entries merged by time (newest first by default), each labelled with the UID of its datasource. The limit applies to the log lines of all datasources together. A datasource which fails is reported with its error in `datasources` instead of failing the whole call.",
+	queryLokiLogsFederated,
+	mcp.WithTitleAnnotation("Query logs across Loki datasources"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"context"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// LokiQueryStatsMetaField is the field of the _meta of tool results holding
+// the statistics of the Loki queries they ran.
+const LokiQueryStatsMetaField = "lokiQueryStats"
+
+// lokiResponseStats is the stats block of the responses of Loki's query
+// APIs, of which only the summary is used.
+type lokiResponseStats struct {
+	Summary *struct {
+		BytesProcessedPerSecond int64   `json:"bytesProcessedPerSecond"`
+		TotalBytesProcessed     int64   `json:"totalBytesProcessed"`
+		TotalLinesProcessed     int64   `json:"totalLinesProcessed"`
+		ExecTime                float64 `json:"execTime"`
+		QueueTime               float64 `json:"queueTime"`
+		Subqueries              int64   `json:"subqueries"`
+		TotalEntriesReturned    int64   `json:"totalEntriesReturned"`
+		Splits                  int64   `json:"splits"`
+		Shards                  int64   `json:"shards"`
+	} `json:"summary"`
+}
+
+// LokiQueryStats are the statistics of the Loki queries run by a tool call,
+// summed over the queries if there are several. They tell how expensive the
+// queries were, so that agents can narrow them, e.g. with more selective
+// stream selectors or a shorter time range.
+type LokiQueryStats struct {
+	Queries int `json:"queries"`
+	// BytesProcessed and LinesProcessed are the size and number of the log
+	// lines read by Loki, before filtering.
+	BytesProcessed  int64 `json:"bytesProcessed"`
+	LinesProcessed  int64 `json:"linesProcessed"`
+	EntriesReturned int64 `json:"entriesReturned"`
+	// ExecTimeSeconds and QueueTimeSeconds are the time spent executing the
+	// queries and waiting in the queue of the query frontend.
+	ExecTimeSeconds  float64 `json:"execTimeSeconds"`
+	QueueTimeSeconds float64 `json:"queueTimeSeconds"`
+	// Splits and Shards are the number of subqueries the queries were split
+	// into by time and sharded into by stream.
+	Splits     int64 `json:"splits"`
+	Shards     int64 `json:"shards"`
+	Subqueries int64 `json:"subqueries"`
+}
+
+// recordLokiQueryStats adds the statistics of a Loki query to the _meta of
+// the result of the current tool call.
+func recordLokiQueryStats(ctx context.Context, stats lokiResponseStats) {
+	s := stats.Summary
+	if s == nil {
+		return
+	}
+	mcpgrafana.UpdateResultMeta(ctx, LokiQueryStatsMetaField, func(old any) any {
+		total, _ := old.(LokiQueryStats)
+		total.Queries++
+		total.BytesProcessed += s.TotalBytesProcessed
+		total.LinesProcessed += s.TotalLinesProcessed
+		total.EntriesReturned += s.TotalEntriesReturned
+		total.ExecTimeSeconds += s.ExecTime
+		total.QueueTimeSeconds += s.QueueTime
+		total.Splits += s.Splits
+		total.Shards += s.Shards
+		total.Subqueries += s.Subqueries
+		return total
+	})
+}
@@ -0,0 +1,194 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"regexp"
+	"slices"
+	"time"
+)
+
+const (
+	// MaxLokiSummaryLines is the maximum number of log lines fetched to
+	// summarize the result of a query, which is also the default maximum
+	// number of lines Loki returns for a query.
+	MaxLokiSummaryLines = 5000
+
+	// lokiSummaryThresholdBytes is the total size of the log lines of a
+	// result above which it is summarized, even if it has fewer lines than
+	// the limit.
+	lokiSummaryThresholdBytes = 64 * 1024
+
+	// maxLokiSummaryStreams and maxLokiSummaryPatterns are the maximum number
+	// of streams and patterns listed in a summary.
+	maxLokiSummaryStreams  = 20
+	maxLokiSummaryPatterns = 10
+
+	// maxLokiPatternLength is the maximum length in bytes of a pattern.
+	maxLokiPatternLength = 300
+)
+
+// LokiLogSummary summarizes the log lines matching a query.
+type LokiLogSummary struct {
+	// Summarized is always true, to tell a summary apart from log entries.
+	Summarized bool `json:"summarized"`
+	TotalLines int  `json:"totalLines"`
+	TotalBytes int  `json:"totalBytes"`
+	// Capped is set if more than MaxLokiSummaryLines lines match the query,
+	// in which case only the first lines in the direction of the query are
+	// summarized.
+	Capped         bool   `json:"capped,omitempty"`
+	FirstTimestamp string `json:"firstTimestamp"`
+	LastTimestamp  string `json:"lastTimestamp"`
+	// Streams are the streams with the most lines, and OtherStreams the
+	// number of streams which are not listed.
+	Streams      []LokiStreamSummary  `json:"streams"`
+	OtherStreams int                  `json:"otherStreams,omitempty"`
+	Patterns     []LokiPatternSummary `json:"patterns"`
+	// Sample is a sample of the lines, spread over the result.
+	Sample []LogEntry `json:"sample"`
+}
+
+// LokiStreamSummary is the number of lines of a stream in a summary.
+type LokiStreamSummary struct {
+	Labels map[string]string `json:"labels"`
+	Lines  int               `json:"lines"`
+}
+
+// LokiPatternSummary is a line pattern in a summary, with variable parts
+// such as numbers, IDs and timestamps replaced by <_>.
+type LokiPatternSummary struct {
+	Pattern string `json:"pattern"`
+	Count   int    `json:"count"`
+	// Example is the first line matching the pattern, cut to the maximum
+	// length of a pattern.
+	Example string `json:"example"`
+}
+
+// variableTokenRegexp matches the tokens of log lines which contain digits,
+// e.g. numbers, durations, addresses, UUIDs and timestamps.
+var variableTokenRegexp = regexp.MustCompile(`[\w.:+-]*\d[\w.:+-]*`)
+
+// repeatedPlaceholderRegexp matches consecutive placeholders.
+var repeatedPlaceholderRegexp = regexp.MustCompile(`<_>(?:[\s,]*<_>)+`)
+
+// logLinePattern returns the pattern of a log line, using the same
+// placeholder as Loki patterns for its variable parts.
+func logLinePattern(line string) string {
+	pattern := variableTokenRegexp.ReplaceAllString(line, "<_>")
+	pattern = repeatedPlaceholderRegexp.ReplaceAllString(pattern, "<_>")
+	if len(pattern) > maxLokiPatternLength {
+		pattern = truncateUTF8(pattern, maxLokiPatternLength) + "…"
+	}
+	return pattern
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a rune.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && s[n]&0xC0 == 0x80 {
+		n--
+	}
+	return s[:n]
+}
+
+// queryLokiLogsOrSummary runs a log query, returning a summary of its lines
+// if there are more than the limit or they are too large, and the entries
+// otherwise.
+func queryLokiLogsOrSummary(ctx context.Context, args QueryLokiLogsParams) (*LokiLogSummary, []LogEntry, error) {
+	if isLokiMetricQuery(args.LogQL) {
+		// Metric queries are not limited to a number of lines.
+		entries, err := queryLokiLogs(ctx, args)
+		return nil, entries, err
+	}
+	limit := enforceLogLimit(ctx, args.Limit)
+	entries, err := queryLokiLogsWithLimit(ctx, args, MaxLokiSummaryLines)
+	if err != nil {
+		return nil, nil, err
+	}
+	lines, size := 0, 0
+	for _, e := range entries {
+		if e.Value != nil {
+			// Metric queries are not limited to a number of lines.
+			return nil, entries, nil
+		}
+		lines++
+		size += len(e.Line)
+	}
+	if lines <= limit && size <= lokiSummaryThresholdBytes {
+		return nil, entries, nil
+	}
+	summary := summarizeLogEntries(entries, limit)
+	if len(args.Fields) > 0 {
+		projectFields(summary.Sample, args.Fields)
+	}
+	return summary, nil, nil
+}
+
+// summarizeLogEntries summarizes log entries, with a sample of sampleSize
+// lines.
+func summarizeLogEntries(entries []LogEntry, sampleSize int) *LokiLogSummary {
+	summary := &LokiLogSummary{
+		Summarized: true,
+		TotalLines: len(entries),
+		Capped:     len(entries) >= MaxLokiSummaryLines,
+		Streams:    []LokiStreamSummary{},
+		Patterns:   []LokiPatternSummary{},
+		Sample:     []LogEntry{},
+	}
+	var first, last time.Time
+	streams := map[string]int{}
+	patterns := map[string]int{}
+	for _, e := range entries {
+		summary.TotalBytes += len(e.Line)
+		t := logEntryTime(e)
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+		if t.After(last) {
+			last = t
+		}
+		key := seriesKey(e.Labels)
+		i, ok := streams[key]
+		if !ok {
+			i = len(summary.Streams)
+			streams[key] = i
+			summary.Streams = append(summary.Streams, LokiStreamSummary{Labels: e.Labels})
+		}
+		summary.Streams[i].Lines++
+		pattern := logLinePattern(e.Line)
+		j, ok := patterns[pattern]
+		if !ok {
+			j = len(summary.Patterns)
+			patterns[pattern] = j
+			summary.Patterns = append(summary.Patterns, LokiPatternSummary{Pattern: pattern, Example: truncateUTF8(e.Line, maxLokiPatternLength)})
+		}
+		summary.Patterns[j].Count++
+	}
+	if len(entries) > 0 {
+		summary.FirstTimestamp = first.UTC().Format(time.RFC3339Nano)
+		summary.LastTimestamp = last.UTC().Format(time.RFC3339Nano)
+	}
+
+	slices.SortStableFunc(summary.Streams, func(a, b LokiStreamSummary) int { return cmp.Compare(b.Lines, a.Lines) })
+	if len(summary.Streams) > maxLokiSummaryStreams {
+		summary.OtherStreams = len(summary.Streams) - maxLokiSummaryStreams
+		summary.Streams = summary.Streams[:maxLokiSummaryStreams]
+	}
+	slices.SortStableFunc(summary.Patterns, func(a, b LokiPatternSummary) int { return cmp.Compare(b.Count, a.Count) })
+	summary.Patterns = summary.Patterns[:min(len(summary.Patterns), maxLokiSummaryPatterns)]
+
+	// Pick lines evenly spread over the result, including the first and the
+	// last.
+	n := min(sampleSize, len(entries))
+	for i := range n {
+		k := 0
+		if n > 1 {
+			k = i * (len(entries) - 1) / (n - 1)
+		}
+		summary.Sample = append(summary.Sample, entries[k])
+	}
+	return summary
+}
@@ -0,0 +1,216 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	// DefaultLokiTailLimit is the default maximum number of log lines
+	// returned by a single call of grafana_tail_loki_logs.
+	DefaultLokiTailLimit = 50
+	// DefaultLokiTailLookbackSeconds is how far back the first call of
+	// grafana_tail_loki_logs looks for log lines.
+	DefaultLokiTailLookbackSeconds = 60
+	// MaxLokiTailLookbackSeconds is the maximum lookback of the first call.
+	MaxLokiTailLookbackSeconds = 3600
+	// DefaultLokiTailWaitSeconds is how long grafana_tail_loki_logs waits for
+	// new log lines if there are none yet.
+	DefaultLokiTailWaitSeconds = 10
+	// MaxLokiTailWaitSeconds is the maximum time to wait for new log lines.
+	MaxLokiTailWaitSeconds = 30
+
+	// lokiTailPollInterval is the interval between queries while waiting for
+	// new log lines.
+	lokiTailPollInterval = 2 * time.Second
+	// lokiTailIngestionDelay is how long log lines may take to be ingested
+	// by Loki after their timestamp. The cursor doesn't move past it without
+	// returning lines, so that lines ingested late are still returned.
+	lokiTailIngestionDelay = 30 * time.Second
+)
+
+type TailLokiLogsParams struct {
+	DatasourceUID   string `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	LogQL           string `json:"logql" jsonschema:"required,description=The LogQL log query to tail\\, e.g. '{app=\"api\"} |= \"error\"'. Metric queries are not supported"`
+	Cursor          string `json:"cursor,omitempty" jsonschema:"description=The cursor returned by the previous call. Omit it on the first call"`
+	LookbackSeconds int    `json:"lookbackSeconds,omitempty" jsonschema:"description=On the first call\\, how far back to look for log lines in seconds (default 60\\, max 3600). Ignored if a cursor is given"`
+	WaitSeconds     int    `json:"waitSeconds,omitempty" jsonschema:"description=How long to wait for new log lines if there are none yet in seconds (default 10\\, max 30)"`
+	Limit           int    `json:"limit,omitempty" jsonschema:"description=The maximum number of log lines to return (default 50\\, max 100)"`
+}
+
+// tailCursor is the position of a tail in the log stream. Log lines with the
+// same timestamp as the last one returned may still arrive, so the lines
+// already returned at that timestamp are remembered to skip them.
+type tailCursor struct {
+	// Timestamp is the timestamp in nanoseconds the next query starts at.
+	Timestamp int64 `json:"t"`
+	// Seen are the keys of the log lines at Timestamp already returned.
+	Seen []string `json:"s,omitempty"`
+}
+
+func (c tailCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeTailCursor(s string) (tailCursor, error) {
+	var c tailCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil || c.Timestamp <= 0 {
+		return tailCursor{}, fmt.Errorf("invalid cursor: pass the cursor returned by the previous call unchanged")
+	}
+	return c, nil
+}
+
+// tailEntry is a log line with its parsed timestamp and a key identifying it
+// across queries.
+type tailEntry struct {
+	LogEntry
+	ns  int64
+	key string
+}
+
+func newTailEntry(e LogEntry) (tailEntry, bool) {
+	ns, err := strconv.ParseInt(strings.Trim(e.Timestamp, `"`), 10, 64)
+	if err != nil {
+		return tailEntry{}, false
+	}
+	labels := make([]string, 0, len(e.Labels))
+	for k, v := range e.Labels {
+		labels = append(labels, k+"="+v)
+	}
+	slices.Sort(labels)
+	h := fnv.New64a()
+	h.Write([]byte(strings.Join(labels, ",")))
+	h.Write([]byte{0})
+	h.Write([]byte(e.Line))
+	return tailEntry{LogEntry: e, ns: ns, key: strconv.FormatUint(h.Sum64(), 36)}, true
+}
+
+type tailLokiLogsResult struct {
+	Entries []LogEntry `json:"entries"`
+	// Cursor is passed to the next call to continue where this one ended.
+	Cursor string `json:"cursor"`
+	// HasMore is true if there were more log lines than the limit, in which
+	// case the next call returns them immediately.
+	HasMore bool `json:"hasMore"`
+}
+
+// nextTailBatch returns the log lines after the cursor up to end, oldest
+// first, along with the cursor to continue from.
+func nextTailBatch(ctx context.Context, client *Client, query string, cursor tailCursor, end time.Time, limit int) (*tailLokiLogsResult, error) {
+	// The start of a range is inclusive, so lines at the cursor's timestamp
+	// which were already returned are queried again and skipped. The limit
+	// is raised by their number so that they don't crowd out new lines, and
+	// by one more line, which is only returned if there are more lines.
+	start := time.Unix(0, cursor.Timestamp).UTC().Format(time.RFC3339Nano)
+	streams, err := client.fetchLogs(ctx, query, start, end.UTC().Format(time.RFC3339Nano), limit+1+len(cursor.Seen), "forward")
+	if err != nil {
+		return nil, err
+	}
+	var entries []tailEntry
+	for _, e := range streamsToEntries(streams) {
+		te, ok := newTailEntry(e)
+		if !ok || te.ns < cursor.Timestamp || (te.ns == cursor.Timestamp && slices.Contains(cursor.Seen, te.key)) {
+			continue
+		}
+		entries = append(entries, te)
+	}
+	// Streams are returned separately, so lines are merged by timestamp.
+	slices.SortStableFunc(entries, func(a, b tailEntry) int { return cmp.Compare(a.ns, b.ns) })
+	result := &tailLokiLogsResult{Entries: []LogEntry{}, HasMore: len(entries) > limit}
+	entries = entries[:min(len(entries), limit)]
+	if len(entries) == 0 {
+		// Nothing new, so the next call continues from the end of this
+		// window, less the time lines may take to be ingested.
+		if settled := end.Add(-lokiTailIngestionDelay).UnixNano(); settled > cursor.Timestamp {
+			cursor = tailCursor{Timestamp: settled}
+		}
+		result.Cursor = cursor.encode()
+		return result, nil
+	}
+	next := tailCursor{Timestamp: entries[len(entries)-1].ns}
+	if next.Timestamp == cursor.Timestamp {
+		next.Seen = slices.Clone(cursor.Seen)
+	}
+	for _, e := range entries {
+		result.Entries = append(result.Entries, e.LogEntry)
+		if e.ns == next.Timestamp {
+			next.Seen = append(next.Seen, e.key)
+		}
+	}
+	result.Cursor = next.encode()
+	return result, nil
+}
+
+func tailLokiLogs(ctx context.Context, args TailLokiLogsParams) (*tailLokiLogsResult, error) {
+	limit := args.Limit
+	if limit <= 0 {
+		limit = DefaultLokiTailLimit
+	}
+	limit = min(limit, maxLokiLogLimit(ctx))
+	wait := time.Duration(args.WaitSeconds) * time.Second
+	if args.WaitSeconds <= 0 {
+		wait = DefaultLokiTailWaitSeconds * time.Second
+	}
+	wait = min(wait, MaxLokiTailWaitSeconds*time.Second)
+
+	var cursor tailCursor
+	if args.Cursor != "" {
+		var err error
+		if cursor, err = decodeTailCursor(args.Cursor); err != nil {
+			return nil, err
+		}
+	} else {
+		lookback := args.LookbackSeconds
+		if lookback <= 0 {
+			lookback = DefaultLokiTailLookbackSeconds
+		}
+		lookback = min(lookback, MaxLokiTailLookbackSeconds)
+		cursor.Timestamp = time.Now().Add(-time.Duration(lookback) * time.Second).UnixNano()
+	}
+
+	client, err := newLokiClient(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("creating Loki client: %w", err)
+	}
+	deadline := time.Now().Add(wait)
+	for {
+		result, err := nextTailBatch(ctx, client, args.LogQL, cursor, time.Now(), limit)
+		if err != nil {
+			return nil, err
+		}
+		remaining := time.Until(deadline)
+		if len(result.Entries) > 0 || remaining <= 0 {
+			return result, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(min(lokiTailPollInterval, remaining)):
+		}
+	}
+}
+
+var TailLokiLogs = mcpgrafana.MustTool(
+	"grafana_tail_loki_logs",
+	"Tail the most recent log lines matching a LogQL log query, e.g. to watch the logs of a service while it is redeployed. The first call returns the lines of the last minute (see `lookbackSeconds`); each call returns a cursor, and passing it to the next call returns only the lines received since, oldest first. If there are no new lines yet, the call waits up to `waitSeconds` for some to arrive. `hasMore` is true if the limit was reached and more lines are available immediately. Lines ingested more than 30 seconds late, with older timestamps than the cursor, may not be returned.",
+	tailLokiLogs,
+	mcp.WithTitleAnnotation("Tail Loki logs"),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,109 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextTailBatch(t *testing.T) {
+	base := time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC).UnixNano()
+	type line struct {
+		app string
+		ns  int64
+		msg string
+	}
+	// Loki applies the limit to the oldest lines, then returns them grouped
+	// by stream.
+	lines := []line{
+		{"api", base + 1, "a1"}, {"web", base + 2, "w2"},
+		{"api", base + 3, "a3"}, {"api", base + 3, "a3 again"}, {"web", base + 3, "w3"},
+	}
+	var queries []string
+	server := newFakeGrafana(t, fakeRoutes{
+		"/loki/api/v1/query_range": func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			assert.Equal(t, "forward", q.Get("direction"))
+			queries = append(queries, q.Get("start")+"/"+q.Get("limit"))
+			start, _ := strconv.ParseInt(q.Get("start"), 10, 64)
+			limit, _ := strconv.Atoi(q.Get("limit"))
+			streams := map[string][][]string{}
+			n := 0
+			for _, l := range lines {
+				if l.ns >= start && n < limit {
+					streams[l.app] = append(streams[l.app], []string{strconv.FormatInt(l.ns, 10), l.msg})
+					n++
+				}
+			}
+			result := []map[string]any{}
+			for app, values := range streams {
+				result = append(result, map[string]any{"stream": map[string]string{"app": app}, "values": values})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"status": "success", "data": map[string]any{"resultType": "streams", "result": result}})
+		},
+	})
+	client := &Client{httpClient: http.DefaultClient, baseURL: server.URL}
+	end := time.Unix(0, base+10)
+	messages := func(r *tailLokiLogsResult) []string {
+		var out []string
+		for _, e := range r.Entries {
+			out = append(out, e.Line)
+		}
+		return out
+	}
+
+	first, err := nextTailBatch(context.Background(), client, `{app=~".+"}`, tailCursor{Timestamp: base}, end, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a1", "w2", "a3"}, messages(first))
+	assert.True(t, first.HasMore)
+
+	cursor, err := decodeTailCursor(first.Cursor)
+	require.NoError(t, err)
+	assert.Equal(t, base+3, cursor.Timestamp)
+	assert.Len(t, cursor.Seen, 1)
+
+	// Lines at the cursor's timestamp which were already returned are
+	// skipped, without crowding out new ones.
+	second, err := nextTailBatch(context.Background(), client, `{app=~".+"}`, cursor, end, 3)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a3 again", "w3"}, messages(second))
+	assert.False(t, second.HasMore)
+	assert.Equal(t, strconv.FormatInt(base+3, 10)+"/5", queries[1])
+
+	// With nothing new, the cursor doesn't move past lines which may still
+	// be ingested late.
+	cursor, err = decodeTailCursor(second.Cursor)
+	require.NoError(t, err)
+	third, err := nextTailBatch(context.Background(), client, `{app=~".+"}`, cursor, end, 3)
+	require.NoError(t, err)
+	assert.Empty(t, third.Entries)
+	assert.Equal(t, second.Cursor, third.Cursor)
+
+	// Once the ingestion delay has passed, it moves to the end of the window
+	// less that delay.
+	later := end.Add(time.Minute)
+	fourth, err := nextTailBatch(context.Background(), client, `{app=~".+"}`, cursor, later, 3)
+	require.NoError(t, err)
+	assert.Empty(t, fourth.Entries)
+	cursor, err = decodeTailCursor(fourth.Cursor)
+	require.NoError(t, err)
+	assert.Equal(t, tailCursor{Timestamp: later.Add(-lokiTailIngestionDelay).UnixNano()}, cursor)
+
+	// A batch with exactly as many lines as the limit has no more lines.
+	all, err := nextTailBatch(context.Background(), client, `{app=~".+"}`, tailCursor{Timestamp: base}, end, len(lines))
+	require.NoError(t, err)
+	assert.Len(t, all.Entries, len(lines))
+	assert.False(t, all.HasMore)
+
+	_, err = decodeTailCursor("not a cursor")
+	assert.Error(t, err)
+}
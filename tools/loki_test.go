@@ -29,6 +29,19 @@ func TestLokiTools(t *testing.T) {
 		assert.NotEmpty(t, result, "Should have at least one container label value")
 	})
 
+	t.Run("list loki series", func(t *testing.T) {
+		ctx := newTestContext()
+		result, err := listLokiSeries(ctx, ListLokiSeriesParams{
+			DatasourceUID: "loki",
+			Matchers:      []string{`{container=~".+"}`},
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, result.Series, "Should have at least one series")
+		for _, series := range result.Series {
+			assert.NotEmpty(t, series["container"])
+		}
+	})
+
 	t.Run("query loki stats", func(t *testing.T) {
 		ctx := newTestContext()
 		result, err := queryLokiStats(ctx, QueryLokiStatsParams{
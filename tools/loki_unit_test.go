@@ -0,0 +1,391 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupEntriesByStream(t *testing.T) {
+	api := map[string]string{"app": "api", "env": "prod"}
+	web := map[string]string{"app": "web", "env": "prod"}
+	entries := []LogEntry{
+		{Timestamp: "3", Line: "api 3", Labels: api},
+		{Timestamp: "2", Line: "web 2", Labels: web},
+		// A stream with equal labels in a different map is the same stream.
+		{Timestamp: "1", Line: "api 1", Labels: map[string]string{"env": "prod", "app": "api"}},
+	}
+	groups := groupEntriesByStream(entries)
+	require.Len(t, groups, 2)
+	assert.Equal(t, api, groups[0].Labels)
+	assert.Equal(t, []LogStreamEntry{{Timestamp: "3", Line: "api 3"}, {Timestamp: "1", Line: "api 1"}}, groups[0].Entries)
+	assert.Equal(t, web, groups[1].Labels)
+
+	data, err := json.Marshal(groups[1])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"labels":{"app":"web","env":"prod"},"entries":[{"timestamp":"2","line":"web 2"}]}`, string(data))
+
+	assert.Empty(t, groupEntriesByStream(nil))
+}
+
+func TestFetchInstant(t *testing.T) {
+	var results = map[string]string{
+		"vector": `{"status":"success","data":{"resultType":"vector","result":[
+			{"metric":{"app":"api"},"value":[1736164800.5,"0.25"]},
+			{"metric":{"app":"web"},"value":[1736164800.5,"NaN"]}
+		]}}`,
+		"scalar": `{"status":"success","data":{"resultType":"scalar","result":[1736164800,"3"]}}`,
+	}
+	server := newFakeGrafana(t, fakeRoutes{
+		"/loki/api/v1/query": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "1736164800000000000", r.URL.Query().Get("time"))
+			_, _ = w.Write([]byte(results[r.URL.Query().Get("query")]))
+		},
+	})
+	client := &Client{httpClient: http.DefaultClient, baseURL: server.URL}
+
+	entries, err := client.fetchInstant(context.Background(), "vector", "2025-01-06T12:00:00Z", 10, "")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "1736164800.5", entries[0].Timestamp)
+	assert.Equal(t, 0.25, *entries[0].Value)
+	assert.Equal(t, map[string]string{"app": "api"}, entries[0].Labels)
+	assert.Equal(t, "2025-01-06T12:00:00.5Z", lokiTimestamp(entries[0].Timestamp, true))
+
+	entries, err = client.fetchInstant(context.Background(), "scalar", "2025-01-06T12:00:00Z", 10, "")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, 3.0, *entries[0].Value)
+}
+
+func TestFetchLogsCategorizedLabels(t *testing.T) {
+	server := newFakeGrafana(t, fakeRoutes{
+		"/loki/api/v1/query_range": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "categorize-labels", r.Header.Get(lokiEncodingFlagsHeader))
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"streams","encodingFlags":["categorize-labels"],"result":[
+				{"stream":{"service_name":"api"},"values":[
+					["1736164800000000000","GET /users",{"structuredMetadata":{"trace_id":"abc"},"parsed":{"status":"500","level":"error"}}],
+					["1736164799000000000","GET /health"]
+				]}
+			]}}`))
+		},
+	})
+	client := &Client{httpClient: http.DefaultClient, baseURL: server.URL}
+
+	streams, err := client.fetchLogs(context.Background(), `{service_name="api"} | logfmt`, "", "", 10, "")
+	require.NoError(t, err)
+	entries := streamsToEntries(streams)
+	require.Len(t, entries, 2)
+	assert.Equal(t, LogEntry{
+		Timestamp:          `"1736164800000000000"`,
+		Line:               "GET /users",
+		Labels:             map[string]string{"service_name": "api"},
+		StructuredMetadata: map[string]string{"trace_id": "abc"},
+		Parsed:             map[string]string{"status": "500", "level": "error"},
+	}, entries[0])
+	assert.Nil(t, entries[1].StructuredMetadata)
+
+	csv, err := lokiTable(entries).csv()
+	require.NoError(t, err)
+	assert.Equal(t, "timestamp,line,level,service_name,status,trace_id\n"+
+		"2025-01-06T12:00:00Z,GET /users,error,api,500,abc\n"+
+		"2025-01-06T11:59:59Z,GET /health,,api,,\n", csv)
+
+	projectFields(entries, []string{"trace_id", "status"})
+	assert.Equal(t, map[string]string{}, entries[0].Labels)
+	assert.Equal(t, map[string]string{"trace_id": "abc"}, entries[0].StructuredMetadata)
+	assert.Equal(t, map[string]string{"status": "500"}, entries[0].Parsed)
+}
+
+func TestLokiClientCompression(t *testing.T) {
+	server := newFakeGrafana(t, fakeRoutes{
+		"/loki/api/v1/labels": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "gzip", r.Header.Get("Accept-Encoding"))
+			w.Header().Set("Content-Encoding", "gzip")
+			zw := gzip.NewWriter(w)
+			_, _ = zw.Write([]byte(`{"status":"success","data":["app","env"]}`))
+			_ = zw.Close()
+		},
+	})
+	client := &Client{httpClient: &http.Client{Transport: mcpgrafana.WithCompression(http.DefaultTransport)}, baseURL: server.URL}
+
+	labels, err := client.fetchData(context.Background(), "/loki/api/v1/labels", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app", "env"}, labels)
+}
+
+func TestGrafanaHTTPClient(t *testing.T) {
+	var attempts int
+	server := newFakeGrafana(t, fakeRoutes{
+		"/": func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			assert.Equal(t, "Bearer test-api-key", r.Header.Get("Authorization"))
+			assert.Equal(t, "2", r.Header.Get(mcpgrafana.OrgIDHeader))
+			assert.Equal(t, "mcp", r.Header.Get("X-Team"))
+			assert.Equal(t, "gzip", r.Header.Get("Accept-Encoding"))
+			if attempts == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = w.Write([]byte(`ok`))
+		},
+	})
+	ctx := fakeGrafanaContext(server)
+	config := mcpgrafana.GrafanaConfigFromContext(ctx)
+	config.OrgID = 2
+	config.ExtraHeaders = http.Header{"X-Team": []string{"mcp"}}
+	config.Retry = &mcpgrafana.RetryConfig{MaxRetries: 1, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	ctx = mcpgrafana.WithGrafanaConfig(ctx, config)
+
+	client, err := newGrafanaHTTPClient(ctx)
+	require.NoError(t, err)
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, attempts)
+}
+
+func TestQueryLokiLogsViaDSQuery(t *testing.T) {
+	var gotQuery map[string]any
+	var gotFrom, gotTo string
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/datasources/uid/loki": jsonBody(`{"uid": "loki", "type": "loki"}`),
+		"/api/ds/query": func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Queries []map[string]any `json:"queries"`
+				From    string           `json:"from"`
+				To      string           `json:"to"`
+			}
+			if !decodeJSONBody(t, w, r, &body) {
+				return
+			}
+			gotQuery, gotFrom, gotTo = body.Queries[0], body.From, body.To
+			if gotQuery["queryType"] == "instant" {
+				_, _ = w.Write([]byte(`{"results": {"A": {"frames": [{
+					"schema": {"fields": [{"name": "Time", "type": "time"}, {"name": "Value", "type": "number", "labels": {"app": "api"}}]},
+					"data": {"values": [[1736121600000], [2.5]]}
+				}]}}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"results": {"A": {"frames": [{
+				"schema": {"fields": [
+					{"name": "labels", "type": "other"}, {"name": "Time", "type": "time"}, {"name": "Line", "type": "string"},
+					{"name": "tsNs", "type": "string"}, {"name": "labelTypes", "type": "other"}
+				]},
+				"data": {"values": [
+					[{"app": "api", "trace_id": "abc", "level": "error"}],
+					[1736121600000],
+					["level=error msg=timeout"],
+					["1736121600000000001"],
+					[{"app": "I", "trace_id": "S", "level": "P"}]
+				]}
+			}]}}}`))
+		},
+	})
+	ctx := fakeGrafanaContext(server)
+	config := mcpgrafana.GrafanaConfigFromContext(ctx)
+	config.DSQuery = []string{"loki"}
+	ctx = mcpgrafana.WithGrafanaConfig(ctx, config)
+
+	entries, err := queryLokiLogs(ctx, QueryLokiLogsParams{
+		DatasourceUID: "loki",
+		LogQL:         `{app="api"} | logfmt`,
+		StartRFC3339:  "2025-01-06T00:00:00Z",
+		EndRFC3339:    "2025-01-06T01:00:00Z",
+		Limit:         5,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"refId":      "A",
+		"datasource": map[string]any{"uid": "loki", "type": "loki"},
+		"expr":       `{app="api"} | logfmt`,
+		"queryType":  "range",
+		"maxLines":   float64(5),
+		"direction":  "backward",
+	}, gotQuery)
+	assert.Equal(t, "1736121600000", gotFrom)
+	assert.Equal(t, "1736125200000", gotTo)
+	assert.Equal(t, []LogEntry{{
+		Timestamp:          `"1736121600000000001"`,
+		Line:               "level=error msg=timeout",
+		Labels:             map[string]string{"app": "api"},
+		StructuredMetadata: map[string]string{"trace_id": "abc"},
+		Parsed:             map[string]string{"level": "error"},
+	}}, entries)
+
+	entries, err = queryLokiLogs(ctx, QueryLokiLogsParams{
+		DatasourceUID: "loki",
+		LogQL:         `sum by (app) (rate({app="api"}[5m]))`,
+		EndRFC3339:    "2025-01-06T00:00:00Z",
+		QueryType:     "instant",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, gotFrom, gotTo)
+	v := 2.5
+	assert.Equal(t, []LogEntry{{Timestamp: "1736121600", Value: &v, Labels: map[string]string{"app": "api"}}}, entries)
+}
+
+func TestQueryLokiLogsFederated(t *testing.T) {
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/datasources": jsonBody(`[
+				{"uid": "loki-eu", "name": "Loki EU", "type": "loki"},
+				{"uid": "prom", "name": "Prometheus", "type": "prometheus"},
+				{"uid": "loki-us", "name": "Loki US", "type": "loki"},
+				{"uid": "loki-ap", "name": "Loki AP", "type": "loki"}
+			]`),
+		"/api/datasources/uid/loki-eu": jsonBody(`{"type": "loki"}`),
+		"/api/datasources/uid/loki-us": jsonBody(`{"type": "loki"}`),
+		"/api/datasources/proxy/uid/loki-eu/loki/api/v1/query_range": jsonBody(`{"status": "success", "data": {"resultType": "streams", "result": [
+				{"stream": {"region": "eu"}, "values": [["3000000000", "eu 3"], ["1000000000", "eu 1"]]}
+			]}}`),
+		"/api/datasources/proxy/uid/loki-us/loki/api/v1/query_range": jsonBody(`{"status": "success", "data": {"resultType": "streams", "result": [
+				{"stream": {"region": "us"}, "values": [["2000000000", "us 2"]]}
+			]}}`),
+	})
+	ctx := fakeGrafanaContext(server)
+
+	result, err := queryLokiLogsFederated(ctx, QueryLokiLogsFederatedParams{LogQL: `{job="api"}`, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []FederatedDatasource{
+		{UID: "loki-eu", Name: "Loki EU", Entries: 2},
+		{UID: "loki-us", Name: "Loki US", Entries: 1},
+		{UID: "loki-ap", Name: "Loki AP", Error: result.Datasources[2].Error},
+	}, result.Datasources)
+	assert.NotEmpty(t, result.Datasources[2].Error)
+	assert.True(t, result.Truncated)
+	require.Len(t, result.Entries, 2)
+	assert.Equal(t, "loki-eu", result.Entries[0].DatasourceUID)
+	assert.Equal(t, "eu 3", result.Entries[0].Line)
+	assert.Equal(t, "loki-us", result.Entries[1].DatasourceUID)
+	assert.Equal(t, "us 2", result.Entries[1].Line)
+
+	result, err = queryLokiLogsFederated(ctx, QueryLokiLogsFederatedParams{DatasourceUIDs: []string{"loki-us", "loki-eu"}, LogQL: `{job="api"}`, Direction: "forward"})
+	require.NoError(t, err)
+	assert.False(t, result.Truncated)
+	var lines []string
+	for _, e := range result.Entries {
+		lines = append(lines, e.Line)
+	}
+	assert.Equal(t, []string{"eu 1", "us 2", "eu 3"}, lines)
+
+	_, err = queryLokiLogsFederated(ctx, QueryLokiLogsFederatedParams{DatasourceUIDs: []string{"loki-ap"}, LogQL: `{job="api"}`})
+	assert.ErrorContains(t, err, "all datasources failed")
+}
+
+func TestLogLinePattern(t *testing.T) {
+	assert.Equal(t, "GET /users/<_> took <_>", logLinePattern("GET /users/42 took 12.5ms"))
+	assert.Equal(t, `level=error ts=<_> msg="connection refused" addr=<_>`, logLinePattern(`level=error ts=2025-01-06T12:00:00Z msg="connection refused" addr=10.0.0.1:5432`))
+	assert.Equal(t, "retrying <_>", logLinePattern("retrying 1, 2, 3"))
+}
+
+func TestQueryLokiLogsOrSummary(t *testing.T) {
+	var values []string
+	for i := range 30 {
+		values = append(values, fmt.Sprintf(`["%d","request %d failed"]`, 1736164800000000000+i*int(time.Second), i))
+	}
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/datasources/uid/loki": jsonBody(`{"uid":"loki","type":"loki"}`),
+		"/api/datasources/proxy/uid/loki/loki/api/v1/query_range": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, strconv.Itoa(MaxLokiSummaryLines), r.URL.Query().Get("limit"))
+			_, _ = fmt.Fprintf(w, `{"status":"success","data":{"resultType":"streams","result":[
+				{"stream":{"app":"api"},"values":[%s]},
+				{"stream":{"app":"web"},"values":[["1736164900000000000","GET / 200"]]}
+			]}}`, strings.Join(values, ","))
+		},
+	})
+	ctx := fakeGrafanaContext(server)
+
+	summary, entries, err := queryLokiLogsOrSummary(ctx, QueryLokiLogsParams{DatasourceUID: "loki", LogQL: `{app=~".+"}`, Limit: 5})
+	require.NoError(t, err)
+	require.Nil(t, entries)
+	assert.True(t, summary.Summarized)
+	assert.Equal(t, 31, summary.TotalLines)
+	assert.False(t, summary.Capped)
+	assert.Equal(t, "2025-01-06T12:00:00Z", summary.FirstTimestamp)
+	assert.Equal(t, "2025-01-06T12:01:40Z", summary.LastTimestamp)
+	assert.Equal(t, []LokiStreamSummary{{Labels: map[string]string{"app": "api"}, Lines: 30}, {Labels: map[string]string{"app": "web"}, Lines: 1}}, summary.Streams)
+	assert.Equal(t, LokiPatternSummary{Pattern: "request <_> failed", Count: 30, Example: "request 0 failed"}, summary.Patterns[0])
+	require.Len(t, summary.Sample, 5)
+	assert.Equal(t, "request 0 failed", summary.Sample[0].Line)
+	assert.Equal(t, "GET / 200", summary.Sample[4].Line)
+
+	// Results within the limit are returned as they are.
+	summary, entries, err = queryLokiLogsOrSummary(ctx, QueryLokiLogsParams{DatasourceUID: "loki", LogQL: `{app=~".+"}`, Limit: 100})
+	require.NoError(t, err)
+	assert.Nil(t, summary)
+	assert.Len(t, entries, 31)
+}
+
+func TestQueryLokiLogsStatsMeta(t *testing.T) {
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/datasources/uid/loki": jsonBody(`{"uid": "loki", "type": "loki"}`),
+		"/api/datasources/proxy/uid/loki/loki/api/v1/query_range": jsonBody(`{"status": "success", "data": {"resultType": "streams",
+				"result": [{"stream": {"app": "api"}, "values": [["1736121600000000000", "hello"]]}],
+				"stats": {"summary": {"bytesProcessedPerSecond": 1000, "totalBytesProcessed": 52428800, "totalLinesProcessed": 100000,
+					"execTime": 1.5, "queueTime": 0.25, "subqueries": 4, "totalEntriesReturned": 1, "splits": 4, "shards": 16}}}}`),
+	})
+	ctx := fakeGrafanaContext(server)
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = QueryLokiLogs.Tool.Name
+	request.Params.Arguments = map[string]any{"datasourceUid": "loki", "logql": `{app="api"}`}
+	result, err := QueryLokiLogs.Handler(ctx, request)
+	require.NoError(t, err)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "hello")
+	assert.Equal(t, LokiQueryStats{
+		Queries:          1,
+		BytesProcessed:   52428800,
+		LinesProcessed:   100000,
+		EntriesReturned:  1,
+		ExecTimeSeconds:  1.5,
+		QueueTimeSeconds: 0.25,
+		Splits:           4,
+		Shards:           16,
+		Subqueries:       4,
+	}, result.Meta[LokiQueryStatsMetaField])
+}
+
+func TestQueryLokiLogsMetricLimit(t *testing.T) {
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/datasources/uid/loki": jsonBody(`{"uid": "loki", "type": "loki"}`),
+		"/api/datasources/proxy/uid/loki/loki/api/v1/query_range": jsonBody(`{"status": "success", "data": {"resultType": "matrix", "result": [
+			{"metric": {"app": "api"}, "values": [[1736121600, "1"], [1736121660, "2"], [1736121720, "3"]]},
+			{"metric": {"app": "web"}, "values": [[1736121600, "4"], [1736121660, "5"], [1736121720, "6"]]}
+		]}}`),
+	})
+	ctx := fakeGrafanaContext(server)
+	config := mcpgrafana.GrafanaConfigFromContext(ctx)
+	config.LokiLimits = mcpgrafana.LokiLimits{MaxMetricSamples: 4}
+	ctx = mcpgrafana.WithGrafanaConfig(ctx, config)
+
+	// The default limit of log queries doesn't apply to metric queries.
+	entries, err := queryLokiLogs(ctx, QueryLokiLogsParams{DatasourceUID: "loki", LogQL: `sum by (app) (rate({env="prod"}[1m]))`, Limit: 5})
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	assert.Equal(t, map[string]string{"app": "web"}, entries[3].Labels)
+	assert.Equal(t, 4.0, *entries[3].Value)
+	assert.Empty(t, entries[3].Line)
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = QueryLokiLogs.Tool.Name
+	request.Params.Arguments = map[string]any{"datasourceUid": "loki", "logql": `sum by (app) (rate({env="prod"}[1m]))`, "limit": 3}
+	result, err := QueryLokiLogs.Handler(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, result.Content, 2)
+	assert.Equal(t, "3 more samples omitted; narrow the time range, aggregate the series or raise limit to see them.", result.Content[1].(mcp.TextContent).Text)
+}
@@ -0,0 +1,235 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// metricQueryFields are the fields of panel targets and alert rule queries
+// holding the query text of common datasources, e.g. PromQL in expr and
+// Graphite in target.
+var metricQueryFields = []string{"expr", "query", "target"}
+
+type FindMetricUsagesParams struct {
+	Metric    string `json:"metric" jsonschema:"required,description=The name of the metric to find\\, e.g. 'http_requests_total'"`
+	FolderUID string `json:"folderUid,omitempty" jsonschema:"description=Optionally\\, only search the dashboards and alert rules in this folder"`
+}
+
+// DashboardMetricUsage is a query of a dashboard panel referencing a metric.
+type DashboardMetricUsage struct {
+	DashboardUID   string         `json:"dashboardUid"`
+	DashboardTitle string         `json:"dashboardTitle,omitempty"`
+	PanelID        int            `json:"panelId,omitempty"`
+	PanelTitle     string         `json:"panelTitle,omitempty"`
+	RefID          string         `json:"refId,omitempty"`
+	Datasource     datasourceInfo `json:"datasource"`
+	Query          string         `json:"query"`
+}
+
+// AlertRuleMetricUsage is a query of an alert rule referencing a metric.
+type AlertRuleMetricUsage struct {
+	RuleUID       string `json:"ruleUid"`
+	RuleTitle     string `json:"ruleTitle,omitempty"`
+	FolderUID     string `json:"folderUid,omitempty"`
+	RuleGroup     string `json:"ruleGroup,omitempty"`
+	RefID         string `json:"refId,omitempty"`
+	DatasourceUID string `json:"datasourceUid,omitempty"`
+	Query         string `json:"query"`
+}
+
+type MetricUsages struct {
+	Metric     string                 `json:"metric"`
+	Dashboards []DashboardMetricUsage `json:"dashboards"`
+	AlertRules []AlertRuleMetricUsage `json:"alertRules"`
+	// DashboardsSearched is the number of dashboards whose queries were
+	// searched.
+	DashboardsSearched int `json:"dashboardsSearched"`
+	// Errors are the dashboards or alert rules which could not be searched.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// metricPattern returns a pattern matching a metric name in a query, but
+// not as part of a longer name, e.g. 'up' in 'backup_total'.
+func metricPattern(metric string) *regexp.Regexp {
+	return regexp.MustCompile(`(^|[^a-zA-Z0-9_:])` + regexp.QuoteMeta(metric) + `($|[^a-zA-Z0-9_:])`)
+}
+
+// targetQueries returns the query texts of a panel target or alert rule
+// query model.
+func targetQueries(target map[string]any) []string {
+	var queries []string
+	for _, field := range metricQueryFields {
+		if q, ok := target[field].(string); ok && q != "" {
+			queries = append(queries, q)
+		}
+	}
+	return queries
+}
+
+// panelDatasource returns the datasource of a panel or target, which may be
+// a template variable.
+func panelDatasource(v any) datasourceInfo {
+	var info datasourceInfo
+	if ds, ok := v.(map[string]any); ok {
+		info.UID, _ = ds["uid"].(string)
+		info.Type, _ = ds["type"].(string)
+	}
+	return info
+}
+
+// findMetricInPanels returns the queries of panels, including those nested
+// inside collapsed rows, which reference a metric.
+func findMetricInPanels(panels []any, pattern *regexp.Regexp) []DashboardMetricUsage {
+	var usages []DashboardMetricUsage
+	for _, p := range panels {
+		panel, ok := p.(map[string]any)
+		if !ok {
+			continue
+		}
+		if nested, ok := panel["panels"].([]any); ok {
+			usages = append(usages, findMetricInPanels(nested, pattern)...)
+		}
+		targets, _ := panel["targets"].([]any)
+		for _, t := range targets {
+			target, ok := t.(map[string]any)
+			if !ok {
+				continue
+			}
+			for _, q := range targetQueries(target) {
+				if !pattern.MatchString(q) {
+					continue
+				}
+				usage := DashboardMetricUsage{Query: q, Datasource: panelDatasource(panel["datasource"])}
+				if id, ok := panel["id"].(float64); ok {
+					usage.PanelID = int(id)
+				}
+				usage.PanelTitle, _ = panel["title"].(string)
+				usage.RefID, _ = target["refId"].(string)
+				// Targets of panels using the mixed datasource have their
+				// own datasource.
+				if ds := panelDatasource(target["datasource"]); ds.UID != "" {
+					usage.Datasource = ds
+				}
+				usages = append(usages, usage)
+			}
+		}
+	}
+	return usages
+}
+
+// findMetricInDashboards returns the panel queries of all dashboards,
+// optionally only those in a folder, which reference a metric, and the
+// number of dashboards searched.
+func findMetricInDashboards(ctx context.Context, folderUID string, pattern *regexp.Regexp) ([]DashboardMetricUsage, int, []string, error) {
+	hits, err := listAllDashboards(ctx, folderUID)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	uids := make([]string, 0, len(hits))
+	for uid := range hits {
+		uids = append(uids, uid)
+	}
+	slices.Sort(uids)
+
+	results := fanOut(ctx, "dashboards", uids, dashboardSummaryWorkers, func(ctx context.Context, uid string) ([]DashboardMetricUsage, error) {
+		dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: uid})
+		if err != nil {
+			return nil, fmt.Errorf("dashboard %s: %w", uid, err)
+		}
+		db, ok := dashboard.Dashboard.(map[string]any)
+		if !ok {
+			return nil, nil
+		}
+		panels, _ := db["panels"].([]any)
+		title, _ := db["title"].(string)
+		usages := findMetricInPanels(panels, pattern)
+		for i := range usages {
+			usages[i].DashboardUID = uid
+			usages[i].DashboardTitle = title
+		}
+		return usages, nil
+	})
+
+	result := []DashboardMetricUsage{}
+	var errs []string
+	for _, r := range results {
+		if r.err != nil {
+			errs = append(errs, r.err.Error())
+		}
+		result = append(result, r.value...)
+	}
+	return result, len(uids), errs, nil
+}
+
+// findMetricInAlertRules returns the queries of Grafana-managed alert rules,
+// optionally only those in a folder, which reference a metric.
+func findMetricInAlertRules(ctx context.Context, folderUID string, pattern *regexp.Regexp) ([]AlertRuleMetricUsage, error) {
+	rules, err := listAlertRulesByUID(ctx, folderUID)
+	if err != nil {
+		return nil, err
+	}
+	usages := []AlertRuleMetricUsage{}
+	for uid, rule := range rules {
+		data, _ := rule["data"].([]any)
+		for _, d := range data {
+			query, ok := d.(map[string]any)
+			if !ok {
+				continue
+			}
+			model, _ := query["model"].(map[string]any)
+			for _, q := range targetQueries(model) {
+				if !pattern.MatchString(q) {
+					continue
+				}
+				usage := AlertRuleMetricUsage{RuleUID: uid, Query: q}
+				usage.RuleTitle, _ = rule["title"].(string)
+				usage.FolderUID, _ = rule["folderUID"].(string)
+				usage.RuleGroup, _ = rule["ruleGroup"].(string)
+				usage.RefID, _ = query["refId"].(string)
+				usage.DatasourceUID, _ = query["datasourceUid"].(string)
+				usages = append(usages, usage)
+			}
+		}
+	}
+	slices.SortFunc(usages, func(a, b AlertRuleMetricUsage) int {
+		return strings.Compare(a.RuleUID+"/"+a.RefID, b.RuleUID+"/"+b.RefID)
+	})
+	return usages, nil
+}
+
+func findMetricUsages(ctx context.Context, args FindMetricUsagesParams) (*MetricUsages, error) {
+	if strings.TrimSpace(args.Metric) == "" {
+		return nil, fmt.Errorf("metric must not be empty")
+	}
+	pattern := metricPattern(args.Metric)
+	result := &MetricUsages{Metric: args.Metric}
+	var err error
+	result.Dashboards, result.DashboardsSearched, result.Errors, err = findMetricInDashboards(ctx, args.FolderUID, pattern)
+	if err != nil {
+		return nil, err
+	}
+	// Alert rules may not be readable, e.g. by service accounts limited to
+	// dashboards, which shouldn't hide the usages in dashboards.
+	result.AlertRules, err = findMetricInAlertRules(ctx, args.FolderUID, pattern)
+	if err != nil {
+		result.AlertRules = []AlertRuleMetricUsage{}
+		result.Errors = append(result.Errors, fmt.Sprintf("alert rules: %s", err))
+	}
+	return result, nil
+}
+
+var FindMetricUsages = mcpgrafana.MustTool(
+	"grafana_find_metric_usages",
+	"Find where a metric is referenced: searches the queries of the panels of all dashboards (including panels in collapsed rows) and of all Grafana-managed alert rules for the metric name, e.g. before deprecating or renaming a metric, or to find out why a rename broke dashboards. Returns each referencing dashboard panel with its dashboard UID and title, panel ID and title, datasource and query, and each referencing alert rule with its UID, title, folder, group, datasource and query. The name is matched as a whole word in PromQL, LogQL, Graphite and other query texts, so `up` doesn't match `backup_total`; queries built from template variables are not expanded. Dashboards or alert rules which could not be searched are listed in `errors`.",
+	findMetricUsages,
+	mcp.WithTitleAnnotation("Find metric usages"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,60 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricPattern(t *testing.T) {
+	pattern := metricPattern("up")
+	assert.True(t, pattern.MatchString("up"))
+	assert.True(t, pattern.MatchString(`sum(up{job="api"})`))
+	assert.True(t, pattern.MatchString(`{__name__="up"}`))
+	assert.False(t, pattern.MatchString("backup_total"))
+	assert.False(t, pattern.MatchString("up_time:rate5m"))
+}
+
+func TestFindMetricUsages(t *testing.T) {
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/search": jsonBody(`[{"uid": "a", "title": "A"}, {"uid": "b", "title": "B"}]`),
+		"/api/dashboards/uid/a": jsonBody(`{"dashboard": {"uid": "a", "title": "API", "panels": [
+			{"id": 1, "title": "Requests", "datasource": {"uid": "prom", "type": "prometheus"}, "targets": [
+				{"refId": "A", "expr": "sum(rate(http_requests_total[5m]))"},
+				{"refId": "B", "expr": "sum(rate(http_requests_total_errors[5m]))"}
+			]},
+			{"id": 2, "title": "Row", "type": "row", "panels": [
+				{"id": 3, "title": "By route", "datasource": {"uid": "-- Mixed --"}, "targets": [
+					{"refId": "A", "expr": "http_requests_total{route=\"/\"}", "datasource": {"uid": "prom2", "type": "prometheus"}}
+				]}
+			]}
+		]}, "meta": {}}`),
+		"/api/dashboards/uid/b": jsonBody(`{"dashboard": {"uid": "b", "title": "Other", "panels": [
+			{"id": 1, "title": "CPU", "targets": [{"refId": "A", "expr": "node_cpu_seconds_total"}]}
+		]}, "meta": {}}`),
+		"/api/v1/provisioning/alert-rules": jsonBody(`[
+			{"uid": "r1", "title": "High traffic", "folderUID": "f", "ruleGroup": "api", "data": [
+				{"refId": "A", "datasourceUid": "prom", "model": {"expr": "sum(rate(http_requests_total[5m]))"}},
+				{"refId": "B", "datasourceUid": "__expr__", "model": {"type": "threshold"}}
+			]},
+			{"uid": "r2", "title": "Down", "folderUID": "f", "data": [{"refId": "A", "model": {"expr": "up == 0"}}]}
+		]`),
+	})
+	ctx := fakeGrafanaContext(server)
+
+	result, err := findMetricUsages(ctx, FindMetricUsagesParams{Metric: "http_requests_total"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.DashboardsSearched)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, []DashboardMetricUsage{
+		{DashboardUID: "a", DashboardTitle: "API", PanelID: 1, PanelTitle: "Requests", RefID: "A", Datasource: datasourceInfo{UID: "prom", Type: "prometheus"}, Query: "sum(rate(http_requests_total[5m]))"},
+		{DashboardUID: "a", DashboardTitle: "API", PanelID: 3, PanelTitle: "By route", RefID: "A", Datasource: datasourceInfo{UID: "prom2", Type: "prometheus"}, Query: `http_requests_total{route="/"}`},
+	}, result.Dashboards)
+	assert.Equal(t, []AlertRuleMetricUsage{
+		{RuleUID: "r1", RuleTitle: "High traffic", FolderUID: "f", RuleGroup: "api", RefID: "A", DatasourceUID: "prom", Query: "sum(rate(http_requests_total[5m]))"},
+	}, result.AlertRules)
+}
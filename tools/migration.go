@@ -0,0 +1,384 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/grafana/grafana-openapi-client-go/client/search"
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	// MaxMigrationDashboards is the maximum number of dashboards present on
+	// both instances which grafana_compare_dashboards_with_target compares.
+	MaxMigrationDashboards = 500
+
+	// maxMigrationDifferences is the maximum number of differing fields
+	// listed for a resource.
+	maxMigrationDifferences = 20
+
+	// migrationWorkers is the number of dashboards fetched at the same time.
+	migrationWorkers = 5
+)
+
+// Statuses of the resources in a migration report.
+const (
+	// migrationMissing resources exist on this instance but not on the
+	// target.
+	migrationMissing = "missing"
+	// migrationChanged resources exist on both instances with different
+	// contents.
+	migrationChanged = "changed"
+	// migrationExtra resources exist only on the target.
+	migrationExtra = "extra"
+)
+
+// migrationTargetContext returns a context in which the Grafana clients talk
+// to the migration target instead of the configured instance.
+func migrationTargetContext(ctx context.Context) (context.Context, error) {
+	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+	target := cfg.MigrationTarget
+	if target == nil {
+		return nil, fmt.Errorf("no migration target is configured: start the server with --migration-target-url")
+	}
+	cfg.URL = strings.TrimRight(target.URL, "/")
+	cfg.APIKey = target.APIKey
+	cfg.AccessToken, cfg.IDToken = "", ""
+	cfg.OrgID = target.OrgID
+	cfg.ExtraHeaders = nil
+	cfg.Failover = nil
+	ctx = mcpgrafana.WithGrafanaConfig(ctx, cfg)
+	return mcpgrafana.WithGrafanaClient(ctx, mcpgrafana.NewGrafanaClient(ctx, cfg.URL, cfg.APIKey)), nil
+}
+
+// MigrationItem is a resource which is missing or different on the
+// migration target.
+type MigrationItem struct {
+	UID    string `json:"uid"`
+	Title  string `json:"title,omitempty"`
+	Status string `json:"status"`
+	// SourceVersion and TargetVersion are the versions of a dashboard on
+	// each instance.
+	SourceVersion int64 `json:"sourceVersion,omitempty"`
+	TargetVersion int64 `json:"targetVersion,omitempty"`
+	// Differences are the paths of the first fields which differ between
+	// the instances, e.g. panels[2].targets[0].expr.
+	Differences     []string `json:"differences,omitempty"`
+	DifferenceCount int      `json:"differenceCount,omitempty"`
+	Error           string   `json:"error,omitempty"`
+}
+
+// MigrationReport lists the resources of a kind which are missing or
+// different on the migration target.
+type MigrationReport struct {
+	Target    string          `json:"target"`
+	Unchanged int             `json:"unchanged"`
+	Missing   int             `json:"missing"`
+	Changed   int             `json:"changed"`
+	Extra     int             `json:"extra"`
+	Items     []MigrationItem `json:"items"`
+}
+
+func newMigrationReport(targetCtx context.Context) *MigrationReport {
+	return &MigrationReport{Target: mcpgrafana.GrafanaConfigFromContext(targetCtx).URL, Items: []MigrationItem{}}
+}
+
+// add adds an item to the report unless it is unchanged.
+func (r *MigrationReport) add(item MigrationItem) {
+	switch item.Status {
+	case migrationMissing:
+		r.Missing++
+	case migrationChanged:
+		r.Changed++
+	case migrationExtra:
+		r.Extra++
+	case "":
+		r.Unchanged++
+		return
+	}
+	r.Items = append(r.Items, item)
+}
+
+// diffResources sets the status and differences of an item present on both
+// instances from the JSON representation of each.
+func diffResources(item *MigrationItem, source, target any) {
+	var diffs []alertRuleFieldDiff
+	diffJSON("", "", source, target, &diffs)
+	if len(diffs) == 0 {
+		return
+	}
+	item.Status = migrationChanged
+	item.DifferenceCount = len(diffs)
+	for _, d := range diffs[:min(len(diffs), maxMigrationDifferences)] {
+		item.Differences = append(item.Differences, d.Path)
+	}
+}
+
+// compareByUID adds the resources present on only one instance to the
+// report, returning the UIDs of those present on both.
+func compareByUID[T any](r *MigrationReport, source, target map[string]T, title func(T) string) []string {
+	var common []string
+	for _, uid := range slices.Sorted(maps.Keys(source)) {
+		if _, ok := target[uid]; ok {
+			common = append(common, uid)
+			continue
+		}
+		r.add(MigrationItem{UID: uid, Title: title(source[uid]), Status: migrationMissing})
+	}
+	for _, uid := range slices.Sorted(maps.Keys(target)) {
+		if _, ok := source[uid]; !ok {
+			r.add(MigrationItem{UID: uid, Title: title(target[uid]), Status: migrationExtra})
+		}
+	}
+	return common
+}
+
+// toJSONValue converts v to plain JSON values, so that resources fetched
+// with different clients can be compared.
+func toJSONValue(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	if err := json.Unmarshal(b, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// listAllDashboards returns the dashboards of an instance by UID, optionally
+// only those in a folder.
+func listAllDashboards(ctx context.Context, folderUID string) (map[string]*models.Hit, error) {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	limit := int64(1000)
+	dashboards := map[string]*models.Hit{}
+	for page := int64(1); ; page++ {
+		params := search.NewSearchParamsWithContext(ctx).WithType(&dashboardTypeStr).WithLimit(&limit).WithPage(&page)
+		if folderUID != "" {
+			params.SetFolderUIDs([]string{folderUID})
+		}
+		resp, err := c.Search.Search(params)
+		if err != nil {
+			return nil, fmt.Errorf("search dashboards: %w", err)
+		}
+		for _, hit := range resp.Payload {
+			dashboards[hit.UID] = hit
+		}
+		if int64(len(resp.Payload)) < limit {
+			return dashboards, nil
+		}
+	}
+}
+
+// dashboardContent returns the JSON model of a dashboard without the fields
+// which differ between instances regardless of its contents.
+func dashboardContent(ctx context.Context, uid string) (any, int64, error) {
+	dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: uid})
+	if err != nil {
+		return nil, 0, err
+	}
+	content, err := toJSONValue(dashboard.Dashboard)
+	if err != nil {
+		return nil, 0, fmt.Errorf("converting dashboard %s: %w", uid, err)
+	}
+	if m, ok := content.(map[string]any); ok {
+		delete(m, "version")
+		delete(m, "iteration")
+	}
+	var version int64
+	if dashboard.Meta != nil {
+		version = dashboard.Meta.Version
+	}
+	return content, version, nil
+}
+
+type CompareDashboardsWithTargetParams struct {
+	FolderUID string `json:"folderUid,omitempty" jsonschema:"description=Optionally\\, only compare the dashboards in this folder"`
+}
+
+func compareDashboardsWithTarget(ctx context.Context, args CompareDashboardsWithTargetParams) (*MigrationReport, error) {
+	targetCtx, err := migrationTargetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	source, err := listAllDashboards(ctx, args.FolderUID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := listAllDashboards(targetCtx, args.FolderUID)
+	if err != nil {
+		return nil, fmt.Errorf("migration target: %w", err)
+	}
+	report := newMigrationReport(targetCtx)
+	common := compareByUID(report, source, target, func(h *models.Hit) string { return h.Title })
+	if len(common) > MaxMigrationDashboards {
+		return nil, fmt.Errorf("%d dashboards exist on both instances, more than the %d which can be compared at once; compare one folder at a time with folderUid", len(common), MaxMigrationDashboards)
+	}
+
+	// Dashboards which can't be fetched from either instance are reported
+	// as changed, with the error.
+	results := fanOut(ctx, "dashboards", common, migrationWorkers, func(ctx context.Context, uid string) (MigrationItem, error) {
+		item := MigrationItem{UID: uid, Title: source[uid].Title}
+		sourceContent, sourceVersion, err := dashboardContent(ctx, uid)
+		if err != nil {
+			item.Status, item.Error = migrationChanged, err.Error()
+			return item, err
+		}
+		targetContent, targetVersion, err := dashboardContent(targetCtx, uid)
+		if err != nil {
+			item.Status, item.Error = migrationChanged, "migration target: "+err.Error()
+			return item, err
+		}
+		diffResources(&item, sourceContent, targetContent)
+		if item.Status != "" {
+			item.SourceVersion, item.TargetVersion = sourceVersion, targetVersion
+		}
+		return item, nil
+	})
+	items := fanOutValues(results)
+	for _, item := range items {
+		report.add(item)
+	}
+	return report, nil
+}
+
+var CompareDashboardsWithTarget = mcpgrafana.MustTool(
+	"grafana_compare_dashboards_with_target",
+	"Compare the dashboards of this Grafana instance with those of the migration target configured for the server, by UID. Reports dashboards which are missing on the target, which only exist on the target (extra), and which exist on both with different contents (changed), with their version on each instance and the paths of the differing fields. Dashboard IDs and versions are ignored when comparing contents, since they differ between instances. Use this to check the progress of a staged migration.",
+	compareDashboardsWithTarget,
+	mcp.WithTitleAnnotation("Compare dashboards with migration target"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type CompareDatasourcesWithTargetParams struct {
+	Type string `json:"type,omitempty" jsonschema:"description=Optionally\\, only compare datasources of this type\\, e.g. 'prometheus'"`
+}
+
+// listDatasourcesByUID returns the datasources of an instance by UID as
+// plain JSON values.
+func listDatasourcesByUID(ctx context.Context, dsType string) (map[string]map[string]any, error) {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.Datasources.GetDataSources()
+	if err != nil {
+		return nil, fmt.Errorf("list datasources: %w", err)
+	}
+	datasources := map[string]map[string]any{}
+	for _, ds := range filterDatasources(resp.Payload, dsType) {
+		value, err := toJSONValue(ds)
+		if err != nil {
+			return nil, fmt.Errorf("converting datasource %s: %w", ds.UID, err)
+		}
+		m, _ := value.(map[string]any)
+		// The logo and type name depend on the plugin version.
+		delete(m, "typeLogoUrl")
+		delete(m, "typeName")
+		datasources[ds.UID] = m
+	}
+	return datasources, nil
+}
+
+func compareDatasourcesWithTarget(ctx context.Context, args CompareDatasourcesWithTargetParams) (*MigrationReport, error) {
+	targetCtx, err := migrationTargetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	source, err := listDatasourcesByUID(ctx, args.Type)
+	if err != nil {
+		return nil, err
+	}
+	target, err := listDatasourcesByUID(targetCtx, args.Type)
+	if err != nil {
+		return nil, fmt.Errorf("migration target: %w", err)
+	}
+	report := newMigrationReport(targetCtx)
+	name := func(ds map[string]any) string { s, _ := ds["name"].(string); return s }
+	for _, uid := range compareByUID(report, source, target, name) {
+		item := MigrationItem{UID: uid, Title: name(source[uid])}
+		diffResources(&item, source[uid], target[uid])
+		report.add(item)
+	}
+	return report, nil
+}
+
+var CompareDatasourcesWithTarget = mcpgrafana.MustTool(
+	"grafana_compare_datasources_with_target",
+	"Compare the datasources of this Grafana instance with those of the migration target configured for the server, by UID. Reports datasources which are missing on the target, which only exist on the target (extra), and which differ (changed) in their name, type, URL, access, database, user or JSON settings, with the paths of the differing fields. Secure settings such as passwords cannot be read, so they are not compared.",
+	compareDatasourcesWithTarget,
+	mcp.WithTitleAnnotation("Compare datasources with migration target"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type CompareAlertRulesWithTargetParams struct {
+	FolderUID string `json:"folderUid,omitempty" jsonschema:"description=Optionally\\, only compare the alert rules in this folder"`
+}
+
+// listAlertRulesByUID returns the alert rules of an instance by UID, as
+// returned by the provisioning API.
+func listAlertRulesByUID(ctx context.Context, folderUID string) (map[string]map[string]any, error) {
+	c, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("creating alerting client: %w", err)
+	}
+	var rules []map[string]any
+	if err := c.getJSON(ctx, "/api/v1/provisioning/alert-rules", nil, &rules); err != nil {
+		return nil, fmt.Errorf("list alert rules: %w", err)
+	}
+	byUID := map[string]map[string]any{}
+	for _, rule := range rules {
+		uid, _ := rule["uid"].(string)
+		if folderUID != "" && rule["folderUID"] != folderUID {
+			continue
+		}
+		byUID[uid] = rule
+	}
+	return byUID, nil
+}
+
+func compareAlertRulesWithTarget(ctx context.Context, args CompareAlertRulesWithTargetParams) (*MigrationReport, error) {
+	targetCtx, err := migrationTargetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	source, err := listAlertRulesByUID(ctx, args.FolderUID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := listAlertRulesByUID(targetCtx, args.FolderUID)
+	if err != nil {
+		return nil, fmt.Errorf("migration target: %w", err)
+	}
+	report := newMigrationReport(targetCtx)
+	title := func(rule map[string]any) string { s, _ := rule["title"].(string); return s }
+	for _, uid := range compareByUID(report, source, target, title) {
+		item := MigrationItem{UID: uid, Title: title(source[uid])}
+		diffResources(&item, source[uid], target[uid])
+		report.add(item)
+	}
+	return report, nil
+}
+
+var CompareAlertRulesWithTarget = mcpgrafana.MustTool(
+	"grafana_compare_alert_rules_with_target",
+	"Compare the Grafana-managed alert rules of this Grafana instance with those of the migration target configured for the server, by UID. Reports rules which are missing on the target, which only exist on the target (extra), and which differ (changed) in their queries, condition, folder, group, labels, annotations or other settings, with the paths of the differing fields. Durations are compared by value, e.g. '5m' equals '300s'.",
+	compareAlertRulesWithTarget,
+	mcp.WithTitleAnnotation("Compare alert rules with migration target"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+func AddMigrationTools(mcp *server.MCPServer) {
+	CompareDashboardsWithTarget.Register(mcp)
+	CompareDatasourcesWithTarget.Register(mcp)
+	CompareAlertRulesWithTarget.Register(mcp)
+}
@@ -0,0 +1,108 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMigrationTestContext(source, target *httptest.Server) context.Context {
+	ctx := fakeGrafanaContext(source)
+	config := mcpgrafana.GrafanaConfigFromContext(ctx)
+	config.MigrationTarget = &mcpgrafana.MigrationTarget{URL: target.URL, APIKey: "target-key"}
+	return mcpgrafana.WithGrafanaConfig(ctx, config)
+}
+
+func TestCompareDashboardsWithTarget(t *testing.T) {
+	source := newFakeGrafana(t, fakeRoutes{
+		"/api/search":           jsonBody(`[{"uid": "a", "title": "A"}, {"uid": "b", "title": "B"}, {"uid": "c", "title": "C"}]`),
+		"/api/dashboards/uid/a": jsonBody(`{"dashboard": {"id": 1, "uid": "a", "title": "A", "version": 5, "panels": [{"id": 1, "title": "CPU"}]}, "meta": {"version": 5}}`),
+		"/api/dashboards/uid/b": jsonBody(`{"dashboard": {"id": 2, "uid": "b", "title": "B", "version": 3, "panels": [{"id": 1, "title": "Memory"}]}, "meta": {"version": 3}}`),
+	})
+	target := newFakeGrafana(t, fakeRoutes{
+		"/api/search":           jsonBody(`[{"uid": "a", "title": "A"}, {"uid": "b", "title": "B"}, {"uid": "d", "title": "D"}]`),
+		"/api/dashboards/uid/a": jsonBody(`{"dashboard": {"id": 7, "uid": "a", "title": "A", "version": 1, "panels": [{"id": 1, "title": "CPU"}]}, "meta": {"version": 1}}`),
+		"/api/dashboards/uid/b": jsonBody(`{"dashboard": {"id": 8, "uid": "b", "title": "B", "version": 1, "panels": [{"id": 1, "title": "Mem"}]}, "meta": {"version": 1}}`),
+	})
+	ctx := newMigrationTestContext(source, target)
+
+	report, err := compareDashboardsWithTarget(ctx, CompareDashboardsWithTargetParams{})
+	require.NoError(t, err)
+	assert.Equal(t, &MigrationReport{
+		Target:    target.URL,
+		Unchanged: 1,
+		Missing:   1,
+		Changed:   1,
+		Extra:     1,
+		Items: []MigrationItem{
+			{UID: "c", Title: "C", Status: migrationMissing},
+			{UID: "d", Title: "D", Status: migrationExtra},
+			{UID: "b", Title: "B", Status: migrationChanged, SourceVersion: 3, TargetVersion: 1, Differences: []string{"panels[0].title"}, DifferenceCount: 1},
+		},
+	}, report)
+}
+
+func TestCompareDatasourcesWithTarget(t *testing.T) {
+	source := newFakeGrafana(t, fakeRoutes{
+		"/api/datasources": jsonBody(`[
+			{"id": 1, "uid": "prom", "name": "Prometheus", "type": "prometheus", "url": "http://prom:9090", "jsonData": {"httpMethod": "POST"}},
+			{"id": 2, "uid": "loki", "name": "Loki", "type": "loki", "url": "http://loki:3100"}
+		]`),
+	})
+	target := newFakeGrafana(t, fakeRoutes{
+		"/api/datasources": jsonBody(`[
+			{"id": 5, "uid": "prom", "name": "Prometheus", "type": "prometheus", "url": "http://prom.new:9090", "jsonData": {"httpMethod": "GET"}}
+		]`),
+	})
+	ctx := newMigrationTestContext(source, target)
+
+	report, err := compareDatasourcesWithTarget(ctx, CompareDatasourcesWithTargetParams{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Missing)
+	assert.Equal(t, 1, report.Changed)
+	assert.Equal(t, []MigrationItem{
+		{UID: "loki", Title: "Loki", Status: migrationMissing},
+		{UID: "prom", Title: "Prometheus", Status: migrationChanged, Differences: []string{"jsonData.httpMethod", "url"}, DifferenceCount: 2},
+	}, report.Items)
+
+	report, err = compareDatasourcesWithTarget(ctx, CompareDatasourcesWithTargetParams{Type: "loki"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Missing)
+	assert.Equal(t, 0, report.Changed)
+}
+
+func TestCompareAlertRulesWithTarget(t *testing.T) {
+	source := newFakeGrafana(t, fakeRoutes{
+		"/api/v1/provisioning/alert-rules": jsonBody(`[
+			{"id": 1, "uid": "r1", "title": "High CPU", "folderUID": "f", "for": "5m", "labels": {"team": "a"}},
+			{"id": 2, "uid": "r2", "title": "Errors", "folderUID": "f", "for": "1m"}
+		]`),
+	})
+	target := newFakeGrafana(t, fakeRoutes{
+		"/api/v1/provisioning/alert-rules": jsonBody(`[
+			{"id": 9, "uid": "r1", "title": "High CPU", "folderUID": "f", "for": "300s", "labels": {"team": "a"}},
+			{"id": 8, "uid": "r2", "title": "Errors", "folderUID": "f", "for": "2m"}
+		]`),
+	})
+	ctx := newMigrationTestContext(source, target)
+
+	report, err := compareAlertRulesWithTarget(ctx, CompareAlertRulesWithTargetParams{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Unchanged)
+	assert.Equal(t, []MigrationItem{
+		{UID: "r2", Title: "Errors", Status: migrationChanged, Differences: []string{"for"}, DifferenceCount: 1},
+	}, report.Items)
+}
+
+func TestMigrationTargetNotConfigured(t *testing.T) {
+	ctx := mcpgrafana.WithGrafanaConfig(context.Background(), mcpgrafana.GrafanaConfig{URL: "http://localhost:3000"})
+	_, err := compareDashboardsWithTarget(ctx, CompareDashboardsWithTargetParams{})
+	assert.ErrorContains(t, err, "--migration-target-url")
+}
@@ -0,0 +1,400 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	// MaxNotebooksPerSession is the maximum number of notebooks a
+	// client session can keep.
+	MaxNotebooksPerSession = 20
+	// MaxNotebookEntries is the maximum number of entries of a
+	// notebook.
+	MaxNotebookEntries = 500
+	// notebookIdleTimeout is how long the notebooks of a session
+	// are kept after it was last used.
+	notebookIdleTimeout = 24 * time.Hour
+	// notebookURIPrefix is the prefix of the URIs of the resources
+	// holding notebooks.
+	notebookURIPrefix = "notebook://"
+)
+
+// notebookEntryKinds are the kinds of entries of a notebook.
+var notebookEntryKinds = []string{"query", "finding", "conclusion", "link", "note"}
+
+// NotebookEntry is an entry of a notebook, e.g. a query which was
+// run or a conclusion drawn from it.
+type NotebookEntry struct {
+	Time          time.Time `json:"time"`
+	Kind          string    `json:"kind"`
+	Text          string    `json:"text"`
+	Query         string    `json:"query,omitempty"`
+	DatasourceUID string    `json:"datasourceUid,omitempty"`
+	URL           string    `json:"url,omitempty"`
+}
+
+// Notebook is a notebook of the findings of an investigation,
+// kept by the server for the client session which wrote it.
+type Notebook struct {
+	Name    string          `json:"name"`
+	URI     string          `json:"uri"`
+	Created time.Time       `json:"created"`
+	Entries []NotebookEntry `json:"entries"`
+}
+
+// notebookSession holds the notebooks of a client session.
+type notebookSession struct {
+	notebooks map[string]*Notebook
+	lastUsed  time.Time
+}
+
+// notebookStore keeps the notebooks of client sessions in memory,
+// discarding those of sessions which have gone idle.
+type notebookStore struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	sessions  map[string]*notebookSession
+	lastPrune time.Time
+}
+
+func newNotebookStore() *notebookStore {
+	return &notebookStore{now: time.Now, sessions: map[string]*notebookSession{}}
+}
+
+var notebooks = newNotebookStore()
+
+// session returns the notebooks of the client session of ctx. It must
+// be called with the lock held.
+func (s *notebookStore) session(ctx context.Context) *notebookSession {
+	now := s.now()
+	if now.Sub(s.lastPrune) > notebookIdleTimeout {
+		for id, session := range s.sessions {
+			if now.Sub(session.lastUsed) > notebookIdleTimeout {
+				delete(s.sessions, id)
+			}
+		}
+		s.lastPrune = now
+	}
+	id := ""
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		id = session.SessionID()
+	}
+	session, ok := s.sessions[id]
+	if !ok {
+		session = &notebookSession{notebooks: map[string]*Notebook{}}
+		s.sessions[id] = session
+	}
+	session.lastUsed = now
+	return session
+}
+
+// add appends an entry to the named notebook of the client session of
+// ctx, creating the notebook if needed.
+func (s *notebookStore) add(ctx context.Context, name string, entry NotebookEntry) (Notebook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session := s.session(ctx)
+	inv, ok := session.notebooks[name]
+	if !ok {
+		if len(session.notebooks) >= MaxNotebooksPerSession {
+			return Notebook{}, fmt.Errorf("at most %d notebooks can be kept per session", MaxNotebooksPerSession)
+		}
+		inv = &Notebook{Name: name, URI: notebookURI(name), Created: s.now(), Entries: []NotebookEntry{}}
+		session.notebooks[name] = inv
+	}
+	if len(inv.Entries) >= MaxNotebookEntries {
+		return Notebook{}, fmt.Errorf("notebook %q already has the maximum of %d entries", name, MaxNotebookEntries)
+	}
+	entry.Time = s.now()
+	inv.Entries = append(inv.Entries, entry)
+	return inv.clone(), nil
+}
+
+// get returns the named notebook of the client session of ctx.
+func (s *notebookStore) get(ctx context.Context, name string) (Notebook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inv, ok := s.session(ctx).notebooks[name]
+	if !ok {
+		return Notebook{}, fmt.Errorf("notebook %q not found in this session", name)
+	}
+	return inv.clone(), nil
+}
+
+// list returns the notebooks of the client session of ctx, ordered by
+// name.
+func (s *notebookStore) list(ctx context.Context) []Notebook {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := []Notebook{}
+	for _, inv := range s.session(ctx).notebooks {
+		result = append(result, inv.clone())
+	}
+	slices.SortFunc(result, func(a, b Notebook) int { return strings.Compare(a.Name, b.Name) })
+	return result
+}
+
+func (inv *Notebook) clone() Notebook {
+	c := *inv
+	c.Entries = slices.Clone(inv.Entries)
+	return c
+}
+
+// notebookURI returns the URI of the resource holding a notebook.
+func notebookURI(name string) string {
+	return notebookURIPrefix + url.PathEscape(name)
+}
+
+// markdown renders the notebook as Markdown, with the times of its
+// entries in the given locale.
+func (inv Notebook) markdown(locale mcpgrafana.Locale) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Investigation: %s\n\nStarted %s.\n", inv.Name, locale.FormatTime(inv.Created))
+	for _, e := range inv.Entries {
+		fmt.Fprintf(&b, "\n- **%s** (%s): %s\n", e.Kind, locale.FormatTime(e.Time), e.Text)
+		if e.Query != "" {
+			if e.DatasourceUID != "" {
+				fmt.Fprintf(&b, "  - Query on datasource `%s`: `%s`\n", e.DatasourceUID, e.Query)
+			} else {
+				fmt.Fprintf(&b, "  - Query: `%s`\n", e.Query)
+			}
+		}
+		if e.URL != "" {
+			fmt.Fprintf(&b, "  - Link: %s\n", e.URL)
+		}
+	}
+	return b.String()
+}
+
+type AddNotebookEntryParams struct {
+	Name          string `json:"name" jsonschema:"required,description=The name of the notebook\\, e.g. 'checkout-latency-2025-01-06'. It is created by its first entry"`
+	Kind          string `json:"kind" jsonschema:"required,enum=query,enum=finding,enum=conclusion,enum=link,enum=note,description=The kind of entry: 'query' for a query which was run\\, 'finding' for an observation\\, 'conclusion' for a conclusion drawn from findings\\, 'link' for a relevant URL such as a dashboard or 'note'"`
+	Text          string `json:"text" jsonschema:"required,description=The text of the entry\\, e.g. what a query showed"`
+	Query         string `json:"query,omitempty" jsonschema:"description=Optionally\\, the query which was run\\, e.g. a PromQL or LogQL expression"`
+	DatasourceUID string `json:"datasourceUid,omitempty" jsonschema:"description=Optionally\\, the UID of the datasource the query was run against"`
+	URL           string `json:"url,omitempty" jsonschema:"description=Optionally\\, a URL related to the entry\\, e.g. of a dashboard or trace"`
+}
+
+// NotebookEntryAdded is the result of adding an entry to a
+// notebook.
+type NotebookEntryAdded struct {
+	Name    string `json:"name"`
+	URI     string `json:"uri"`
+	Entries int    `json:"entries"`
+}
+
+func addNotebookEntry(ctx context.Context, args AddNotebookEntryParams) (*NotebookEntryAdded, error) {
+	if strings.TrimSpace(args.Name) == "" {
+		return nil, fmt.Errorf("name must not be empty")
+	}
+	if !slices.Contains(notebookEntryKinds, args.Kind) {
+		return nil, fmt.Errorf("invalid kind %q: must be one of %s", args.Kind, strings.Join(notebookEntryKinds, ", "))
+	}
+	if strings.TrimSpace(args.Text) == "" {
+		return nil, fmt.Errorf("text must not be empty")
+	}
+	inv, err := notebooks.add(ctx, args.Name, NotebookEntry{
+		Kind:          args.Kind,
+		Text:          args.Text,
+		Query:         args.Query,
+		DatasourceUID: args.DatasourceUID,
+		URL:           args.URL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &NotebookEntryAdded{Name: inv.Name, URI: inv.URI, Entries: len(inv.Entries)}, nil
+}
+
+var AddNotebookEntry = mcpgrafana.MustTool(
+	"grafana_add_notebook_entry",
+	"Record an entry in a named investigation notebook kept by the server for this session: a query which was run, a finding, a conclusion, a link or a note. Use it while investigating an issue to keep track of what was checked and learned, so that it can be reviewed and exported at the end with `grafana_export_notebook`. The notebook is created by its first entry and is also available as the MCP resource `notebook://<name>`. Returns the URI of the resource and the number of entries.",
+	addNotebookEntry,
+	mcp.WithTitleAnnotation("Add notebook entry"),
+	mcp.WithIdempotentHintAnnotation(false),
+	mcp.WithReadOnlyHintAnnotation(false),
+)
+
+type GetNotebookParams struct {
+	Name string `json:"name,omitempty" jsonschema:"description=The name of the notebook. If empty\\, the notebooks of this session are listed without their entries"`
+}
+
+func getNotebook(ctx context.Context, args GetNotebookParams) (any, error) {
+	if args.Name != "" {
+		inv, err := notebooks.get(ctx, args.Name)
+		if err != nil {
+			return nil, err
+		}
+		return inv, nil
+	}
+	type notebookListItem struct {
+		Name    string    `json:"name"`
+		URI     string    `json:"uri"`
+		Created time.Time `json:"created"`
+		Entries int       `json:"entries"`
+	}
+	items := []notebookListItem{}
+	for _, inv := range notebooks.list(ctx) {
+		items = append(items, notebookListItem{Name: inv.Name, URI: inv.URI, Created: inv.Created, Entries: len(inv.Entries)})
+	}
+	return items, nil
+}
+
+var GetNotebook = mcpgrafana.MustTool(
+	"grafana_get_notebook",
+	"Get an investigation notebook of this session with all its entries, or list the notebooks of this session with their number of entries if no name is given.",
+	getNotebook,
+	mcp.WithTitleAnnotation("Get notebook"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type ExportNotebookParams struct {
+	Name         string `json:"name" jsonschema:"required,description=The name of the notebook to export"`
+	IncidentID   string `json:"incidentId,omitempty" jsonschema:"description=The ID of an incident to add the notebook to as a note"`
+	DashboardUID string `json:"dashboardUid,omitempty" jsonschema:"description=The UID of a dashboard to add the notebook to as a text panel at its bottom"`
+}
+
+// ExportNotebookResult is the result of exporting a notebook.
+type ExportNotebookResult struct {
+	IncidentID   string `json:"incidentId,omitempty"`
+	ActivityID   string `json:"activityId,omitempty"`
+	DashboardUID string `json:"dashboardUid,omitempty"`
+	DashboardURL string `json:"dashboardUrl,omitempty"`
+	PanelID      int    `json:"panelId,omitempty"`
+}
+
+// notebookPanel returns a Markdown text panel with the contents of a
+// notebook, placed below the given panels with an unused ID.
+func notebookPanel(panels []any, title, content string) map[string]any {
+	id, bottom := 0, 0
+	for _, p := range panels {
+		panel, ok := p.(map[string]any)
+		if !ok {
+			continue
+		}
+		if v, ok := panel["id"].(float64); ok {
+			id = max(id, int(v))
+		}
+		if pos, ok := panel["gridPos"].(map[string]any); ok {
+			y, _ := pos["y"].(float64)
+			h, _ := pos["h"].(float64)
+			bottom = max(bottom, int(y+h))
+		}
+	}
+	return map[string]any{
+		"id":      id + 1,
+		"type":    "text",
+		"title":   title,
+		"gridPos": map[string]any{"x": 0, "y": bottom, "w": 24, "h": 12},
+		"options": map[string]any{"mode": "markdown", "content": content},
+	}
+}
+
+func exportNotebookToDashboard(ctx context.Context, uid string, inv Notebook, content string) (*ExportNotebookResult, error) {
+	dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: uid})
+	if err != nil {
+		return nil, err
+	}
+	db, ok := dashboard.Dashboard.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("dashboard is not a JSON object")
+	}
+	panels, _ := db["panels"].([]any)
+	panel := notebookPanel(panels, "Investigation: "+inv.Name, content)
+	db["panels"] = append(panels, panel)
+	folderUID := ""
+	if dashboard.Meta != nil {
+		folderUID = dashboard.Meta.FolderUID
+	}
+	saved, err := updateDashboard(ctx, UpdateDashboardParams{
+		Dashboard: db,
+		FolderUID: folderUID,
+		Message:   fmt.Sprintf("Add investigation %s", inv.Name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := &ExportNotebookResult{DashboardUID: uid, PanelID: panel["id"].(int)}
+	if saved.URL != nil {
+		result.DashboardURL = *saved.URL
+	}
+	return result, nil
+}
+
+func exportNotebook(ctx context.Context, args ExportNotebookParams) (*ExportNotebookResult, error) {
+	if (args.IncidentID == "") == (args.DashboardUID == "") {
+		return nil, fmt.Errorf("exactly one of incidentId and dashboardUid must be given")
+	}
+	inv, err := notebooks.get(ctx, args.Name)
+	if err != nil {
+		return nil, err
+	}
+	content := inv.markdown(mcpgrafana.GrafanaConfigFromContext(ctx).Locale)
+	if args.DashboardUID != "" {
+		return exportNotebookToDashboard(ctx, args.DashboardUID, inv, content)
+	}
+	activity, err := addActivityToIncident(ctx, AddActivityToIncidentParams{IncidentID: args.IncidentID, Body: content})
+	if err != nil {
+		return nil, err
+	}
+	return &ExportNotebookResult{IncidentID: args.IncidentID, ActivityID: activity.ActivityItemID}, nil
+}
+
+var ExportNotebook = mcpgrafana.MustTool(
+	"grafana_export_notebook",
+	"Export an investigation notebook of this session as Markdown, either as a note on the timeline of an incident (`incidentId`) or as a text panel added at the bottom of a dashboard (`dashboardUid`), e.g. at the end of an investigation so that its findings are kept for the retrospective.",
+	exportNotebook,
+	mcp.WithTitleAnnotation("Export notebook"),
+	mcp.WithIdempotentHintAnnotation(false),
+	mcp.WithReadOnlyHintAnnotation(false),
+)
+
+// readNotebookResource returns a notebook of the client session
+// as a Markdown resource.
+func readNotebookResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	name, err := url.PathUnescape(strings.TrimPrefix(request.Params.URI, notebookURIPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("invalid notebook URI %q: %w", request.Params.URI, err)
+	}
+	inv, err := notebooks.get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{mcp.TextResourceContents{
+		URI:      request.Params.URI,
+		MIMEType: "text/markdown",
+		Text:     inv.markdown(mcpgrafana.GrafanaConfigFromContext(ctx).Locale),
+	}}, nil
+}
+
+// AddNotebookTools registers the investigation notebook tools and the
+// resource template of notebooks. The export tool is only registered if
+// write tools are enabled, see mcpgrafana.WriteToolsEnabled.
+func AddNotebookTools(mcp *server.MCPServer) {
+	AddNotebookEntry.Register(mcp)
+	GetNotebook.Register(mcp)
+	if mcpgrafana.WriteToolsEnabled() {
+		ExportNotebook.Register(mcp)
+	}
+	mcp.AddResourceTemplate(notebookResourceTemplate, readNotebookResource)
+}
+
+var notebookResourceTemplate = mcp.NewResourceTemplate(
+	notebookURIPrefix+"{name}",
+	"Investigation notebook",
+	mcp.WithTemplateDescription("An investigation notebook of this session, with the queries, findings, conclusions and links recorded with grafana_add_notebook_entry, as Markdown."),
+	mcp.WithTemplateMIMEType("text/markdown"),
+)
@@ -0,0 +1,154 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withNotebookStore replaces the notebooks of the server with an empty
+// store for the duration of a test.
+func withNotebookStore(t *testing.T) *notebookStore {
+	previous := notebooks
+	notebooks = newNotebookStore()
+	notebooks.now = func() time.Time { return time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { notebooks = previous })
+	return notebooks
+}
+
+func TestAddNotebookEntry(t *testing.T) {
+	withNotebookStore(t)
+	ctx := context.Background()
+
+	added, err := addNotebookEntry(ctx, AddNotebookEntryParams{Name: "checkout latency", Kind: "query", Text: "p99 doubled at 09:40", Query: "histogram_quantile(0.99, rate(http_duration_seconds_bucket[5m]))", DatasourceUID: "prom"})
+	require.NoError(t, err)
+	assert.Equal(t, "notebook://checkout%20latency", added.URI)
+	assert.Equal(t, 1, added.Entries)
+
+	added, err = addNotebookEntry(ctx, AddNotebookEntryParams{Name: "checkout latency", Kind: "conclusion", Text: "caused by the 09:38 deploy"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, added.Entries)
+
+	_, err = addNotebookEntry(ctx, AddNotebookEntryParams{Name: "checkout latency", Kind: "guess", Text: "x"})
+	assert.ErrorContains(t, err, "invalid kind")
+	_, err = addNotebookEntry(ctx, AddNotebookEntryParams{Name: "checkout latency", Kind: "note"})
+	assert.ErrorContains(t, err, "text must not be empty")
+
+	got, err := getNotebook(ctx, GetNotebookParams{Name: "checkout latency"})
+	require.NoError(t, err)
+	notebook := got.(Notebook)
+	require.Len(t, notebook.Entries, 2)
+	assert.Equal(t, "prom", notebook.Entries[0].DatasourceUID)
+	assert.Equal(t, "conclusion", notebook.Entries[1].Kind)
+
+	_, err = getNotebook(ctx, GetNotebookParams{Name: "other"})
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestNotebookLimits(t *testing.T) {
+	withNotebookStore(t)
+	ctx := context.Background()
+
+	for i := range MaxNotebookEntries {
+		_, err := addNotebookEntry(ctx, AddNotebookEntryParams{Name: "full", Kind: "note", Text: "entry"})
+		require.NoError(t, err, "entry %d", i)
+	}
+	_, err := addNotebookEntry(ctx, AddNotebookEntryParams{Name: "full", Kind: "note", Text: "entry"})
+	assert.ErrorContains(t, err, "maximum")
+
+	for i := 1; i < MaxNotebooksPerSession; i++ {
+		_, err := addNotebookEntry(ctx, AddNotebookEntryParams{Name: string(rune('a' + i)), Kind: "note", Text: "entry"})
+		require.NoError(t, err)
+	}
+	_, err = addNotebookEntry(ctx, AddNotebookEntryParams{Name: "one too many", Kind: "note", Text: "entry"})
+	assert.ErrorContains(t, err, "at most")
+}
+
+func TestNotebookIdleSessionsPruned(t *testing.T) {
+	store := withNotebookStore(t)
+	now := time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, err := addNotebookEntry(ctx, AddNotebookEntryParams{Name: "old", Kind: "note", Text: "entry"})
+	require.NoError(t, err)
+
+	now = now.Add(notebookIdleTimeout + time.Minute)
+	_, err = getNotebook(ctx, GetNotebookParams{Name: "old"})
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestNotebookResource(t *testing.T) {
+	withNotebookStore(t)
+	ctx := context.Background()
+	_, err := addNotebookEntry(ctx, AddNotebookEntryParams{Name: "checkout latency", Kind: "link", Text: "checkout dashboard", URL: "https://grafana.example.com/d/checkout"})
+	require.NoError(t, err)
+
+	request := mcp.ReadResourceRequest{}
+	request.Params.URI = "notebook://checkout%20latency"
+	contents, err := readNotebookResource(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, contents, 1)
+	text := contents[0].(mcp.TextResourceContents)
+	assert.Equal(t, "text/markdown", text.MIMEType)
+	assert.Contains(t, text.Text, "# Investigation: checkout latency")
+	assert.Contains(t, text.Text, "- **link**")
+	assert.Contains(t, text.Text, "  - Link: https://grafana.example.com/d/checkout")
+}
+
+func TestNotebookPanel(t *testing.T) {
+	var panels []any
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"id": 2, "gridPos": {"x": 0, "y": 0, "w": 12, "h": 8}},
+		{"id": 7, "type": "row", "gridPos": {"x": 0, "y": 8, "w": 24, "h": 1}},
+		{"id": 5, "gridPos": {"x": 12, "y": 9, "w": 12, "h": 10}}
+	]`), &panels))
+
+	panel := notebookPanel(panels, "Investigation: x", "content")
+	assert.Equal(t, 8, panel["id"])
+	assert.Equal(t, map[string]any{"x": 0, "y": 19, "w": 24, "h": 12}, panel["gridPos"])
+	assert.Equal(t, map[string]any{"mode": "markdown", "content": "content"}, panel["options"])
+}
+
+func TestExportNotebookToDashboard(t *testing.T) {
+	withNotebookStore(t)
+	var saved map[string]any
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/dashboards/uid/checkout": jsonBody(`{"dashboard": {"uid": "checkout", "title": "Checkout", "panels": [{"id": 1, "gridPos": {"x": 0, "y": 0, "w": 24, "h": 8}}]}, "meta": {"folderUid": "shop"}}`),
+		"/api/dashboards/db": func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			require.NoError(t, json.Unmarshal(body, &saved))
+			_, _ = w.Write([]byte(`{"uid": "checkout", "url": "/d/checkout/checkout", "status": "success", "version": 2}`))
+		},
+	})
+	ctx := fakeGrafanaContext(server)
+
+	_, err := addNotebookEntry(ctx, AddNotebookEntryParams{Name: "checkout latency", Kind: "finding", Text: "errors started at 09:40"})
+	require.NoError(t, err)
+
+	_, err = exportNotebook(ctx, ExportNotebookParams{Name: "checkout latency"})
+	assert.ErrorContains(t, err, "exactly one of")
+
+	result, err := exportNotebook(ctx, ExportNotebookParams{Name: "checkout latency", DashboardUID: "checkout"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.PanelID)
+	assert.Equal(t, "/d/checkout/checkout", result.DashboardURL)
+
+	require.NotNil(t, saved)
+	assert.Equal(t, "shop", saved["folderUid"])
+	panels := saved["dashboard"].(map[string]any)["panels"].([]any)
+	require.Len(t, panels, 2)
+	panel := panels[1].(map[string]any)
+	assert.Equal(t, "text", panel["type"])
+	assert.Contains(t, panel["options"].(map[string]any)["content"], "errors started at 09:40")
+}
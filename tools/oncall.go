@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	aapi "github.com/grafana/amixr-api-go-client"
@@ -28,8 +29,13 @@ func getOnCallURLFromSettings(ctx context.Context, grafanaURL, grafanaAPIKey str
 	if grafanaAPIKey != "" {
 		req.Header.Set("Authorization", "Bearer "+grafanaAPIKey)
 	}
+	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+	if cfg.OrgID != 0 {
+		req.Header.Set(mcpgrafana.OrgIDHeader, strconv.FormatInt(cfg.OrgID, 10))
+	}
 
-	resp, err := http.DefaultClient.Do(req)
+	client := &http.Client{Transport: mcpgrafana.WithExtraHeaders(http.DefaultTransport, cfg.ExtraHeaders)}
+	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("fetching settings: %w", err)
 	}
@@ -121,6 +127,7 @@ type ListOnCallSchedulesParams struct {
 	TeamID     string `json:"teamId,omitempty" jsonschema:"description=The ID of the team to list schedules for"`
 	ScheduleID string `json:"scheduleId,omitempty" jsonschema:"description=The ID of the schedule to get details for. If provided\\, returns only that schedule's details"`
 	Page       int    `json:"page,omitempty" jsonschema:"description=The page number to return (1-based)"`
+	Unscoped   bool   `json:"unscoped,omitempty" jsonschema:"description=Set to true to ignore the server's default scope\\, e.g. a team\\, and list the schedules of all teams"`
 }
 
 // ScheduleSummary represents a simplified view of an OnCall schedule
@@ -161,6 +168,8 @@ func listOnCallSchedules(ctx context.Context, args ListOnCallSchedulesParams) ([
 	}
 	if args.TeamID != "" {
 		listOptions.TeamID = args.TeamID
+	} else if scope := mcpgrafana.GrafanaConfigFromContext(ctx).Scope; !args.Unscoped {
+		listOptions.TeamID = scope.OnCallTeamID
 	}
 
 	response, _, err := scheduleService.ListSchedules(listOptions)
@@ -188,7 +197,7 @@ func listOnCallSchedules(ctx context.Context, args ListOnCallSchedulesParams) ([
 
 var ListOnCallSchedules = mcpgrafana.MustTool(
 	"grafana_list_oncall_schedules",
-	"List Grafana OnCall schedules, optionally filtering by team ID. If a specific schedule ID is provided, retrieves details for only that schedule. Returns a list of schedule summaries including ID, name, team ID, timezone, and shift IDs. Supports pagination.",
+	"List Grafana OnCall schedules, optionally filtering by team ID. If a specific schedule ID is provided, retrieves details for only that schedule. Returns a list of schedule summaries including ID, name, team ID, timezone, and shift IDs. Supports pagination. If the server has a default scope with an OnCall team, only that team's schedules are listed unless another team ID is given or `unscoped` is set.",
 	listOnCallSchedules,
 	mcp.WithTitleAnnotation("List OnCall schedules"),
 	mcp.WithIdempotentHintAnnotation(true),
@@ -222,6 +231,9 @@ var GetOnCallShift = mcpgrafana.MustTool(
 	mcp.WithReadOnlyHintAnnotation(true),
 )
 
+// onCallUserWorkers is the number of users fetched concurrently.
+const onCallUserWorkers = 4
+
 // CurrentOnCallUsers represents the currently on-call users for a schedule
 type CurrentOnCallUsers struct {
 	ScheduleID   string       `json:"scheduleId" jsonschema:"description=The ID of the schedule"`
@@ -262,15 +274,22 @@ func getCurrentOnCallUsers(ctx context.Context, args GetCurrentOnCallUsersParams
 		return nil, fmt.Errorf("getting OnCall user service: %w", err)
 	}
 
-	// Fetch details for each user currently on call
-	for _, userID := range schedule.OnCallNow {
+	// Fetch details for each user currently on call. Users which can't be
+	// fetched are left out, and the result is marked as partial.
+	results := fanOut(ctx, "users", schedule.OnCallNow, onCallUserWorkers, func(ctx context.Context, userID string) (*aapi.User, error) {
 		user, _, err := userService.GetUser(userID, &aapi.GetUserOptions{})
 		if err != nil {
-			// Log the error but continue with other users
-			fmt.Printf("Error fetching user %s: %v\n", userID, err)
-			continue
+			return nil, fmt.Errorf("getting user %s: %w", userID, err)
+		}
+		return user, nil
+	})
+	if err := allFailed("users", results); err != nil {
+		return nil, err
+	}
+	for _, r := range results {
+		if r.err == nil {
+			result.Users = append(result.Users, r.value)
 		}
-		result.Users = append(result.Users, user)
 	}
 
 	return result, nil
@@ -285,6 +304,63 @@ var GetCurrentOnCallUsers = mcpgrafana.MustTool(
 	mcp.WithReadOnlyHintAnnotation(true),
 )
 
+// onCallScheduleWorkers is the number of schedules whose on-call users are
+// fetched concurrently.
+const onCallScheduleWorkers = 4
+
+type ListCurrentOnCallUsersParams struct {
+	ScheduleIDs []string `json:"scheduleIds,omitempty" jsonschema:"description=Optionally\\, the IDs of the schedules. Defaults to the schedules of the first page of grafana_list_oncall_schedules\\, i.e. of the server's default OnCall team if it has a default scope"`
+	Unscoped    bool     `json:"unscoped,omitempty" jsonschema:"description=Set to true to ignore the server's default scope when no schedule IDs are given\\, and use the schedules of all teams"`
+}
+
+// CurrentOnCallUsersBySchedule is the users currently on call for several
+// schedules.
+type CurrentOnCallUsersBySchedule struct {
+	Schedules []*CurrentOnCallUsers `json:"schedules"`
+	// Errors are the schedules whose on-call users could not be fetched.
+	Errors []string `json:"errors,omitempty"`
+}
+
+func listCurrentOnCallUsers(ctx context.Context, args ListCurrentOnCallUsersParams) (*CurrentOnCallUsersBySchedule, error) {
+	scheduleIDs := args.ScheduleIDs
+	if len(scheduleIDs) == 0 {
+		schedules, err := listOnCallSchedules(ctx, ListOnCallSchedulesParams{Unscoped: args.Unscoped})
+		if err != nil {
+			return nil, err
+		}
+		for _, schedule := range schedules {
+			scheduleIDs = append(scheduleIDs, schedule.ID)
+		}
+	}
+
+	// Schedules which can't be read are listed in the errors, and the
+	// result is marked as partial.
+	results := fanOut(ctx, "schedules", scheduleIDs, onCallScheduleWorkers, func(ctx context.Context, id string) (*CurrentOnCallUsers, error) {
+		return getCurrentOnCallUsers(ctx, GetCurrentOnCallUsersParams{ScheduleID: id})
+	})
+	if err := allFailed("schedules", results); err != nil {
+		return nil, err
+	}
+	result := &CurrentOnCallUsersBySchedule{Schedules: []*CurrentOnCallUsers{}}
+	for _, r := range results {
+		if r.err != nil {
+			result.Errors = append(result.Errors, r.err.Error())
+			continue
+		}
+		result.Schedules = append(result.Schedules, r.value)
+	}
+	return result, nil
+}
+
+var ListCurrentOnCallUsers = mcpgrafana.MustTool(
+	"grafana_list_current_oncall_users",
+	"Get the users currently on call across several Grafana OnCall schedules in a single call, e.g. to find out who to contact about an incident spanning teams. Without `scheduleIds`, the schedules listed by grafana_list_oncall_schedules are used. Returns the schedule ID, name and users on call for each schedule. Schedules which could not be read are listed in `errors` while the others are still returned.",
+	listCurrentOnCallUsers,
+	mcp.WithTitleAnnotation("List current on-call users across schedules"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
 type ListOnCallTeamsParams struct {
 	Page int `json:"page,omitempty" jsonschema:"description=The page number to return"`
 }
@@ -363,10 +439,28 @@ var ListOnCallUsers = mcpgrafana.MustTool(
 	mcp.WithReadOnlyHintAnnotation(true),
 )
 
+// AddOnCallTools registers the OnCall tools. The paging drill, which pages
+// people, the actions on alert groups, overrides and shift swaps are only
+// registered if write tools are enabled, see mcpgrafana.WriteToolsEnabled.
 func AddOnCallTools(mcp *server.MCPServer) {
 	ListOnCallSchedules.Register(mcp)
 	GetOnCallShift.Register(mcp)
 	GetCurrentOnCallUsers.Register(mcp)
+	ListCurrentOnCallUsers.Register(mcp)
 	ListOnCallTeams.Register(mcp)
 	ListOnCallUsers.Register(mcp)
+	CheckOnCallSchedule.Register(mcp)
+	ListOnCallEscalationChains.Register(mcp)
+	ListOnCallIntegrations.Register(mcp)
+	ListOnCallShiftSwaps.Register(mcp)
+	if mcpgrafana.WriteToolsEnabled() {
+		RunOnCallPagingDrill.Register(mcp)
+		AcknowledgeOnCallAlertGroup.Register(mcp)
+		UnacknowledgeOnCallAlertGroup.Register(mcp)
+		ResolveOnCallAlertGroup.Register(mcp)
+		SilenceOnCallAlertGroup.Register(mcp)
+		CreateOnCallOverride.Register(mcp)
+		RequestOnCallShiftSwap.Register(mcp)
+		TakeOnCallShiftSwap.Register(mcp)
+	}
 }
@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	aapi "github.com/grafana/amixr-api-go-client"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// oncallSilenceDelays are the durations OnCall can silence alert groups for.
+var oncallSilenceDelays = []time.Duration{
+	30 * time.Minute, time.Hour, 2 * time.Hour, 3 * time.Hour, 4 * time.Hour, 6 * time.Hour,
+	12 * time.Hour, 16 * time.Hour, 20 * time.Hour, 24 * time.Hour, 7 * 24 * time.Hour,
+}
+
+type OnCallAlertGroupParams struct {
+	AlertGroupID string `json:"alertGroupId" jsonschema:"required,description=The ID of the OnCall alert group"`
+}
+
+// oncallAlertGroupAction performs an action on an alert group, e.g.
+// acknowledge, and returns the alert group after it.
+func oncallAlertGroupAction(ctx context.Context, id, action string, body any) (*aapi.AlertGroup, error) {
+	if id == "" {
+		return nil, fmt.Errorf("%s alert group: alertGroupId is required", action)
+	}
+	client, err := oncallClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting OnCall client: %w", err)
+	}
+	req, err := client.NewRequest(http.MethodPost, fmt.Sprintf("alert_groups/%s/%s", url.PathEscape(id), action), body)
+	if err != nil {
+		return nil, fmt.Errorf("%s alert group %s: %w", action, id, err)
+	}
+	if _, err := client.Do(req, nil); err != nil {
+		return nil, fmt.Errorf("%s alert group %s: %w", action, id, err)
+	}
+	resp, _, err := aapi.NewAlertGroupService(client).ListAlertGroups(&aapi.ListAlertGroupOptions{AlertGroupID: id})
+	if err != nil {
+		return nil, fmt.Errorf("getting alert group %s: %w", id, err)
+	}
+	if len(resp.AlertGroups) == 0 {
+		return nil, fmt.Errorf("alert group %s not found after the %s action", id, action)
+	}
+	return resp.AlertGroups[0], nil
+}
+
+func acknowledgeOnCallAlertGroup(ctx context.Context, args OnCallAlertGroupParams) (*aapi.AlertGroup, error) {
+	return oncallAlertGroupAction(ctx, args.AlertGroupID, "acknowledge", nil)
+}
+
+var AcknowledgeOnCallAlertGroup = mcpgrafana.MustTool(
+	"grafana_acknowledge_oncall_alert_group",
+	"Acknowledge an OnCall alert group, which stops its escalation: nobody else is paged for it. Only acknowledge on behalf of the user who takes care of the alert. Returns the alert group with its new state.",
+	acknowledgeOnCallAlertGroup,
+	mcp.WithTitleAnnotation("Acknowledge OnCall alert group"),
+	mcp.WithReadOnlyHintAnnotation(false),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithIdempotentHintAnnotation(false),
+)
+
+func unacknowledgeOnCallAlertGroup(ctx context.Context, args OnCallAlertGroupParams) (*aapi.AlertGroup, error) {
+	return oncallAlertGroupAction(ctx, args.AlertGroupID, "unacknowledge", nil)
+}
+
+var UnacknowledgeOnCallAlertGroup = mcpgrafana.MustTool(
+	"grafana_unacknowledge_oncall_alert_group",
+	"Unacknowledge an OnCall alert group, which restarts its escalation and pages people again, e.g. to hand it over. Returns the alert group with its new state.",
+	unacknowledgeOnCallAlertGroup,
+	mcp.WithTitleAnnotation("Unacknowledge OnCall alert group"),
+	mcp.WithReadOnlyHintAnnotation(false),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithIdempotentHintAnnotation(false),
+)
+
+func resolveOnCallAlertGroup(ctx context.Context, args OnCallAlertGroupParams) (*aapi.AlertGroup, error) {
+	return oncallAlertGroupAction(ctx, args.AlertGroupID, "resolve", nil)
+}
+
+var ResolveOnCallAlertGroup = mcpgrafana.MustTool(
+	"grafana_resolve_oncall_alert_group",
+	"Resolve an OnCall alert group by hand, e.g. once an incident is mitigated, even if its alerts still fire; new alerts of the group then start a new alert group. Returns the alert group with its new state.",
+	resolveOnCallAlertGroup,
+	mcp.WithTitleAnnotation("Resolve OnCall alert group"),
+	mcp.WithReadOnlyHintAnnotation(false),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithIdempotentHintAnnotation(false),
+)
+
+type SilenceOnCallAlertGroupParams struct {
+	AlertGroupID string `json:"alertGroupId" jsonschema:"required,description=The ID of the OnCall alert group"`
+	Duration     string `json:"duration" jsonschema:"required,description=How long to silence the alert group: 30m\\, 1h\\, 2h\\, 3h\\, 4h\\, 6h\\, 12h\\, 16h\\, 20h\\, 24h\\, 7d or forever"`
+}
+
+// oncallSilenceDelay returns the delay of a silence in seconds, as expected
+// by the OnCall API, which is -1 to silence forever.
+func oncallSilenceDelay(duration string) (int, error) {
+	if duration == "forever" {
+		return -1, nil
+	}
+	d, ok := parseAnyDuration(duration)
+	if !ok || !slices.Contains(oncallSilenceDelays, d) {
+		return 0, fmt.Errorf("invalid duration %q: must be one of 30m, 1h, 2h, 3h, 4h, 6h, 12h, 16h, 20h, 24h, 7d or forever", duration)
+	}
+	return int(d.Seconds()), nil
+}
+
+func silenceOnCallAlertGroup(ctx context.Context, args SilenceOnCallAlertGroupParams) (*aapi.AlertGroup, error) {
+	delay, err := oncallSilenceDelay(args.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("silence alert group: %w", err)
+	}
+	return oncallAlertGroupAction(ctx, args.AlertGroupID, "silence", map[string]int{"delay": delay})
+}
+
+var SilenceOnCallAlertGroup = mcpgrafana.MustTool(
+	"grafana_silence_oncall_alert_group",
+	"Silence an OnCall alert group for a while, which stops its escalation until the silence ends and then restarts it if the alert group is still firing. Use it for known issues which are being worked on without acknowledging them. Returns the alert group with its new state.",
+	silenceOnCallAlertGroup,
+	mcp.WithTitleAnnotation("Silence OnCall alert group"),
+	mcp.WithReadOnlyHintAnnotation(false),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithIdempotentHintAnnotation(false),
+)
@@ -0,0 +1,82 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnCallAlertGroupActions(t *testing.T) {
+	state := "firing"
+	var silenceBody map[string]any
+	var server *httptest.Server
+	server = newFakeGrafana(t, fakeRoutes{
+		"/api/plugins/grafana-irm-app/settings": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"jsonData": {"onCallApiUrl": %q}}`, server.URL)
+		},
+		"/api/v1/alert_groups/": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "AG1", r.URL.Query().Get("alert_group_id"))
+			_ = json.NewEncoder(w).Encode(map[string]any{"count": 1, "results": []any{map[string]any{"id": "AG1", "state": state}}})
+		},
+		"/api/v1/alert_groups/AG1/acknowledge": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			state = "acknowledged"
+		},
+		"/api/v1/alert_groups/AG1/unacknowledge": func(w http.ResponseWriter, r *http.Request) {
+			state = "firing"
+		},
+		"/api/v1/alert_groups/AG1/resolve": func(w http.ResponseWriter, r *http.Request) {
+			state = "resolved"
+		},
+		"/api/v1/alert_groups/AG1/silence": func(w http.ResponseWriter, r *http.Request) {
+			if !decodeJSONBody(t, w, r, &silenceBody) {
+				return
+			}
+			state = "silenced"
+		},
+		"/api/v1/alert_groups/AG2/acknowledge": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"detail": "Can't acknowledge a resolved alert group"}`))
+		},
+	})
+	ctx := fakeGrafanaContext(server)
+
+	group, err := acknowledgeOnCallAlertGroup(ctx, OnCallAlertGroupParams{AlertGroupID: "AG1"})
+	require.NoError(t, err)
+	assert.Equal(t, "acknowledged", group.State)
+
+	group, err = unacknowledgeOnCallAlertGroup(ctx, OnCallAlertGroupParams{AlertGroupID: "AG1"})
+	require.NoError(t, err)
+	assert.Equal(t, "firing", group.State)
+
+	group, err = silenceOnCallAlertGroup(ctx, SilenceOnCallAlertGroupParams{AlertGroupID: "AG1", Duration: "2h"})
+	require.NoError(t, err)
+	assert.Equal(t, "silenced", group.State)
+	assert.Equal(t, map[string]any{"delay": float64(7200)}, silenceBody)
+
+	group, err = resolveOnCallAlertGroup(ctx, OnCallAlertGroupParams{AlertGroupID: "AG1"})
+	require.NoError(t, err)
+	assert.Equal(t, "resolved", group.State)
+
+	_, err = acknowledgeOnCallAlertGroup(ctx, OnCallAlertGroupParams{AlertGroupID: "AG2"})
+	assert.ErrorContains(t, err, "Can't acknowledge a resolved alert group")
+
+	_, err = silenceOnCallAlertGroup(ctx, SilenceOnCallAlertGroupParams{AlertGroupID: "AG1", Duration: "5h"})
+	assert.ErrorContains(t, err, "invalid duration")
+}
+
+func TestOnCallSilenceDelay(t *testing.T) {
+	for duration, want := range map[string]int{"30m": 1800, "24h": 86400, "7d": 604800, "forever": -1} {
+		delay, err := oncallSilenceDelay(duration)
+		require.NoError(t, err, duration)
+		assert.Equal(t, want, delay, duration)
+	}
+}
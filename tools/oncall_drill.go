@@ -0,0 +1,239 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	aapi "github.com/grafana/amixr-api-go-client"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	// DefaultOnCallDrillWait is how long a paging drill waits for the test
+	// page to be acknowledged by default.
+	DefaultOnCallDrillWait = 2 * time.Minute
+	// MaxOnCallDrillWait is the longest a paging drill can wait for the test
+	// page to be acknowledged.
+	MaxOnCallDrillWait = 5 * time.Minute
+)
+
+// oncallDrillPollInterval is how often a paging drill checks whether the
+// test page was acknowledged.
+var oncallDrillPollInterval = 5 * time.Second
+
+// alertmanagerIntegrationTypes are the types of OnCall integrations which
+// receive alerts in the Alertmanager webhook format, while the others
+// receive the formatted webhook format.
+var alertmanagerIntegrationTypes = []string{"alertmanager", "grafana_alerting", "legacy_alertmanager", "legacy_grafana_alerting"}
+
+// webhookIntegrationTypes are the types of OnCall integrations which can
+// receive a test page over HTTP.
+var webhookIntegrationTypes = append([]string{"webhook", "formatted_webhook"}, alertmanagerIntegrationTypes...)
+
+type RunOnCallPagingDrillParams struct {
+	IntegrationID string `json:"integrationId" jsonschema:"required,description=The ID of the OnCall integration to send the test page to. Its routes decide which escalation chain pages whom"`
+	Message       string `json:"message,omitempty" jsonschema:"description=Optionally\\, a message to add to the test page\\, e.g. the name of the drill"`
+	WaitSeconds   int    `json:"waitSeconds,omitempty" jsonschema:"description=How long to wait for the test page to be acknowledged\\, in seconds (default 120\\, maximum 300)"`
+	KeepOpen      bool   `json:"keepOpen,omitempty" jsonschema:"description=Don't resolve the test page after it was acknowledged or the wait timed out"`
+}
+
+// OnCallPagingDrillResult is the outcome of a paging drill.
+type OnCallPagingDrillResult struct {
+	IntegrationID   string     `json:"integrationId"`
+	IntegrationName string     `json:"integrationName,omitempty"`
+	Title           string     `json:"title"`
+	SentAt          time.Time  `json:"sentAt"`
+	AlertGroupID    string     `json:"alertGroupId,omitempty"`
+	AlertGroupURL   string     `json:"alertGroupUrl,omitempty"`
+	CreatedAt       *time.Time `json:"createdAt,omitempty"`
+	AcknowledgedAt  *time.Time `json:"acknowledgedAt,omitempty"`
+	State           string     `json:"state,omitempty"`
+	// AckLatencySeconds is the time from the creation of the alert group to
+	// its acknowledgment.
+	AckLatencySeconds float64 `json:"ackLatencySeconds,omitempty"`
+	AckLatency        string  `json:"ackLatency,omitempty"`
+	// TimedOut is true if the test page wasn't acknowledged while waiting.
+	TimedOut bool `json:"timedOut"`
+	Resolved bool `json:"resolved"`
+}
+
+// drillPayload returns the payload of a test page for an integration type,
+// firing or resolving the alert with the given ID.
+func drillPayload(integrationType, alertID, title, message string, sentAt time.Time, firing bool) map[string]any {
+	if slices.Contains(alertmanagerIntegrationTypes, integrationType) {
+		status := "firing"
+		if !firing {
+			status = "resolved"
+		}
+		labels := map[string]string{"alertname": title, "drill": "true", "drill_id": alertID}
+		return map[string]any{
+			"version":     "4",
+			"status":      status,
+			"groupKey":    alertID,
+			"groupLabels": labels,
+			"alerts": []map[string]any{{
+				"status":      status,
+				"labels":      labels,
+				"annotations": map[string]string{"summary": title, "description": message},
+				"startsAt":    sentAt.Format(time.RFC3339),
+				"fingerprint": alertID,
+			}},
+		}
+	}
+	state := "alerting"
+	if !firing {
+		state = "ok"
+	}
+	return map[string]any{"alert_uid": alertID, "title": title, "message": message, "state": state}
+}
+
+// sendDrillPage sends a test page to the inbound URL of an integration.
+func sendDrillPage(ctx context.Context, url string, payload map[string]any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshalling test page: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending test page: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("integration returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// findDrillAlertGroup returns the alert group of the integration with the
+// title of a test page, or nil if it wasn't created yet.
+func findDrillAlertGroup(service *aapi.AlertGroupService, integrationID, title string) (*aapi.AlertGroup, error) {
+	resp, _, err := service.ListAlertGroups(&aapi.ListAlertGroupOptions{IntegrationID: integrationID})
+	if err != nil {
+		return nil, fmt.Errorf("listing alert groups: %w", err)
+	}
+	for _, group := range resp.AlertGroups {
+		if group.Title == title {
+			return group, nil
+		}
+	}
+	return nil, nil
+}
+
+// parseOnCallTime parses a time of the OnCall API, returning nil if it is
+// empty or invalid.
+func parseOnCallTime(s string) *time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+func runOnCallPagingDrill(ctx context.Context, args RunOnCallPagingDrillParams) (*OnCallPagingDrillResult, error) {
+	wait := DefaultOnCallDrillWait
+	if args.WaitSeconds > 0 {
+		wait = min(time.Duration(args.WaitSeconds)*time.Second, MaxOnCallDrillWait)
+	}
+	client, err := oncallClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting OnCall client: %w", err)
+	}
+	integration, _, err := aapi.NewIntegrationService(client).GetIntegration(args.IntegrationID, &aapi.GetIntegrationOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("getting integration %s: %w", args.IntegrationID, err)
+	}
+	if !slices.Contains(webhookIntegrationTypes, integration.Type) || integration.Link == "" {
+		return nil, fmt.Errorf("integration %s of type %q can't receive test pages: use an integration of type webhook, formatted_webhook, alertmanager or grafana_alerting", args.IntegrationID, integration.Type)
+	}
+
+	sentAt := time.Now().UTC()
+	alertID := fmt.Sprintf("mcp-grafana-drill-%d", sentAt.UnixNano())
+	result := &OnCallPagingDrillResult{
+		IntegrationID:   args.IntegrationID,
+		IntegrationName: integration.Name,
+		Title:           fmt.Sprintf("[TEST] Paging drill %s", sentAt.Format(time.RFC3339)),
+		SentAt:          sentAt,
+	}
+	message := "This is a test page sent by a paging drill. Please acknowledge it; no action is needed."
+	if args.Message != "" {
+		message += "\n\n" + args.Message
+	}
+	if err := sendDrillPage(ctx, integration.Link, drillPayload(integration.Type, alertID, result.Title, message, sentAt, true)); err != nil {
+		return nil, err
+	}
+
+	// Wait for the alert group of the test page to be acknowledged, or
+	// resolved by hand, which also ends the drill.
+	groups := aapi.NewAlertGroupService(client)
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+	ticker := time.NewTicker(oncallDrillPollInterval)
+	defer ticker.Stop()
+	var pollErr error
+poll:
+	for {
+		group, err := findDrillAlertGroup(groups, args.IntegrationID, result.Title)
+		if err != nil {
+			pollErr = err
+			break
+		}
+		if group != nil {
+			result.AlertGroupID = group.ID
+			result.AlertGroupURL = group.Permalinks["web"]
+			result.State = group.State
+			result.CreatedAt = parseOnCallTime(group.CreatedAt)
+			result.AcknowledgedAt = parseOnCallTime(group.AcknowledgedAt)
+			if result.AcknowledgedAt != nil || group.State == "resolved" {
+				break
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-deadline.C:
+			result.TimedOut = true
+			break poll
+		case <-ctx.Done():
+			result.TimedOut = true
+			break poll
+		}
+	}
+	if result.AcknowledgedAt != nil && result.CreatedAt != nil {
+		latency := result.AcknowledgedAt.Sub(*result.CreatedAt)
+		result.AckLatencySeconds = latency.Seconds()
+		result.AckLatency = mcpgrafana.GrafanaConfigFromContext(ctx).Locale.FormatDuration(latency)
+	}
+
+	if !args.KeepOpen && result.State != "resolved" {
+		// Resolve the test page even if the call was cancelled, so that it
+		// doesn't keep paging.
+		if err := sendDrillPage(context.WithoutCancel(ctx), integration.Link, drillPayload(integration.Type, alertID, result.Title, message, sentAt, false)); err != nil {
+			return nil, fmt.Errorf("resolving test page: %w", err)
+		}
+		result.Resolved = true
+	}
+	if pollErr != nil {
+		return nil, pollErr
+	}
+	return result, nil
+}
+
+var RunOnCallPagingDrill = mcpgrafana.MustTool(
+	"grafana_run_oncall_paging_drill",
+	"Run a paging drill: send a test page, titled '[TEST] Paging drill <time>' (and labelled drill=true for Alertmanager integrations), to an OnCall integration of type webhook, formatted_webhook, alertmanager or grafana_alerting, which routes it to an escalation chain like a real alert, then wait for it to be acknowledged and report the acknowledgment latency. The test page is resolved afterwards unless `keepOpen` is set. This pages real people: only use it after confirmation from the user, e.g. for periodic drills checking that pages reach whoever is on call.",
+	runOnCallPagingDrill,
+	mcp.WithTitleAnnotation("Run OnCall paging drill"),
+	mcp.WithIdempotentHintAnnotation(false),
+	mcp.WithReadOnlyHintAnnotation(false),
+)
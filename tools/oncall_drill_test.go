@@ -0,0 +1,123 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOnCall is an OnCall API with a single integration, whose alert group
+// for a test page is acknowledged after a number of polls.
+type fakeOnCall struct {
+	integrationType string
+	ackAfterPolls   int
+
+	mu       sync.Mutex
+	payloads []map[string]any
+	title    string
+	polls    int
+}
+
+func (f *fakeOnCall) serve(t *testing.T) *httptest.Server {
+	var server *httptest.Server
+	locked := func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			handler(w, r)
+		}
+	}
+	server = newFakeGrafana(t, fakeRoutes{
+		"/api/plugins/grafana-irm-app/settings": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"jsonData": {"onCallApiUrl": %q}}`, server.URL)
+		},
+		"/api/v1/integrations/I1/": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"id": "I1", "name": "Checkout", "type": %q, "link": %q}`, f.integrationType, server.URL+"/inbound/")
+		},
+		"/inbound/": locked(func(w http.ResponseWriter, r *http.Request) {
+			var payload map[string]any
+			if !decodeJSONBody(t, w, r, &payload) {
+				return
+			}
+			f.payloads = append(f.payloads, payload)
+			if title, ok := payload["title"].(string); ok {
+				f.title = title
+			}
+			if alerts, ok := payload["alerts"].([]any); ok {
+				f.title = alerts[0].(map[string]any)["annotations"].(map[string]any)["summary"].(string)
+			}
+		}),
+		"/api/v1/alert_groups/": locked(func(w http.ResponseWriter, r *http.Request) {
+			f.polls++
+			group := map[string]any{"id": "AG1", "integration_id": "I1", "title": f.title, "state": "firing", "created_at": "2025-01-06T10:00:00Z", "permalinks": map[string]string{"web": "https://oncall.example.com/AG1"}}
+			if f.ackAfterPolls > 0 && f.polls >= f.ackAfterPolls {
+				group["state"] = "acknowledged"
+				group["acknowledged_at"] = "2025-01-06T10:01:30Z"
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"count": 1, "results": []any{group}})
+		}),
+	})
+	return server
+}
+
+func TestRunOnCallPagingDrill(t *testing.T) {
+	previous := oncallDrillPollInterval
+	oncallDrillPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { oncallDrillPollInterval = previous })
+
+	t.Run("acknowledged", func(t *testing.T) {
+		f := &fakeOnCall{integrationType: "formatted_webhook", ackAfterPolls: 3}
+		ctx := fakeGrafanaContext(f.serve(t))
+
+		result, err := runOnCallPagingDrill(ctx, RunOnCallPagingDrillParams{IntegrationID: "I1", Message: "Q1 drill"})
+		require.NoError(t, err)
+		assert.Equal(t, "Checkout", result.IntegrationName)
+		assert.Equal(t, "AG1", result.AlertGroupID)
+		assert.Equal(t, "https://oncall.example.com/AG1", result.AlertGroupURL)
+		assert.False(t, result.TimedOut)
+		assert.Equal(t, 90.0, result.AckLatencySeconds)
+		assert.True(t, result.Resolved)
+
+		require.Len(t, f.payloads, 2)
+		assert.Equal(t, "alerting", f.payloads[0]["state"])
+		assert.Contains(t, f.payloads[0]["title"], "[TEST] Paging drill")
+		assert.Contains(t, f.payloads[0]["message"], "Q1 drill")
+		assert.Equal(t, "ok", f.payloads[1]["state"])
+		assert.Equal(t, f.payloads[0]["alert_uid"], f.payloads[1]["alert_uid"])
+	})
+
+	t.Run("timed out and kept open", func(t *testing.T) {
+		f := &fakeOnCall{integrationType: "alertmanager"}
+		ctx := fakeGrafanaContext(f.serve(t))
+
+		result, err := runOnCallPagingDrill(ctx, RunOnCallPagingDrillParams{IntegrationID: "I1", WaitSeconds: 1, KeepOpen: true})
+		require.NoError(t, err)
+		assert.True(t, result.TimedOut)
+		assert.Nil(t, result.AcknowledgedAt)
+		assert.False(t, result.Resolved)
+
+		require.Len(t, f.payloads, 1)
+		assert.Equal(t, "firing", f.payloads[0]["status"])
+		labels := f.payloads[0]["alerts"].([]any)[0].(map[string]any)["labels"].(map[string]any)
+		assert.Equal(t, "true", labels["drill"])
+	})
+
+	t.Run("unsupported integration", func(t *testing.T) {
+		f := &fakeOnCall{integrationType: "direct_paging"}
+		ctx := fakeGrafanaContext(f.serve(t))
+
+		_, err := runOnCallPagingDrill(ctx, RunOnCallPagingDrillParams{IntegrationID: "I1"})
+		assert.ErrorContains(t, err, "can't receive test pages")
+		assert.Empty(t, f.payloads)
+	})
+}
@@ -0,0 +1,382 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	aapi "github.com/grafana/amixr-api-go-client"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// maxOnCallPages bounds the number of pages fetched when following the
+// pagination of an OnCall list.
+const maxOnCallPages = 20
+
+// OnCallRef refers to an OnCall resource by ID, with its name when it could
+// be looked up.
+type OnCallRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// oncallNames looks up the names of OnCall users and schedules by ID,
+// caching them. Resources which can't be looked up are left unnamed.
+type oncallNames struct {
+	client    *aapi.Client
+	users     map[string]string
+	schedules map[string]string
+}
+
+func newOnCallNames(client *aapi.Client) *oncallNames {
+	return &oncallNames{client: client, users: map[string]string{}, schedules: map[string]string{}}
+}
+
+func (n *oncallNames) user(id string) OnCallRef {
+	name, ok := n.users[id]
+	if !ok {
+		if user, _, err := aapi.NewUserService(n.client).GetUser(id, &aapi.GetUserOptions{}); err == nil {
+			name = user.Username
+		}
+		n.users[id] = name
+	}
+	return OnCallRef{ID: id, Name: name}
+}
+
+func (n *oncallNames) schedule(id string) OnCallRef {
+	name, ok := n.schedules[id]
+	if !ok {
+		if schedule, _, err := aapi.NewScheduleService(n.client).GetSchedule(id, &aapi.GetScheduleOptions{}); err == nil {
+			name = schedule.Name
+		}
+		n.schedules[id] = name
+	}
+	return OnCallRef{ID: id, Name: name}
+}
+
+// OnCallEscalationStep is a step of an escalation chain. Only the fields of
+// its type are set.
+type OnCallEscalationStep struct {
+	Position int `json:"position"`
+	// Type is e.g. wait, notify_persons, notify_person_next_each_time,
+	// notify_on_call_from_schedule, notify_user_group, trigger_webhook,
+	// notify_if_time_from_to, repeat_escalation or resolve.
+	Type string `json:"type"`
+	// Important is true if the step notifies with the important
+	// notification rules of users.
+	Important bool        `json:"important,omitempty"`
+	Wait      string      `json:"wait,omitempty"`
+	Users     []OnCallRef `json:"users,omitempty"`
+	// UsersEachTime are notified one after the other, a different one each
+	// time the step is reached.
+	UsersEachTime []OnCallRef `json:"usersEachTime,omitempty"`
+	Schedule      *OnCallRef  `json:"schedule,omitempty"`
+	UserGroupID   string      `json:"userGroupId,omitempty"`
+	TeamID        string      `json:"teamId,omitempty"`
+	WebhookID     string      `json:"webhookId,omitempty"`
+	TimeFrom      string      `json:"timeFrom,omitempty"`
+	TimeTo        string      `json:"timeTo,omitempty"`
+	Severity      string      `json:"severity,omitempty"`
+}
+
+// OnCallEscalationChain is an escalation chain with its steps, in order.
+type OnCallEscalationChain struct {
+	ID     string                 `json:"id"`
+	Name   string                 `json:"name"`
+	TeamID string                 `json:"teamId,omitempty"`
+	Steps  []OnCallEscalationStep `json:"steps"`
+}
+
+type escalationPolicyOptions struct {
+	aapi.ListOptions
+	EscalationChainID string `url:"escalation_chain_id,omitempty"`
+}
+
+type paginatedEscalationPolicies struct {
+	aapi.PaginatedResponse
+	Policies []*aapi.Escalation `json:"results"`
+}
+
+// fetchEscalationPolicies returns the policies of an escalation chain,
+// following pagination.
+func fetchEscalationPolicies(client *aapi.Client, chainID string) ([]*aapi.Escalation, error) {
+	opts := &escalationPolicyOptions{ListOptions: aapi.ListOptions{Page: 1}, EscalationChainID: chainID}
+	var policies []*aapi.Escalation
+	for ; opts.Page <= maxOnCallPages; opts.Page++ {
+		req, err := client.NewRequest("GET", "escalation_policies", opts)
+		if err != nil {
+			return nil, fmt.Errorf("creating escalation policies request: %w", err)
+		}
+		var page paginatedEscalationPolicies
+		if _, err := client.Do(req, &page); err != nil {
+			return nil, fmt.Errorf("listing the escalation policies of chain %s: %w", chainID, err)
+		}
+		policies = append(policies, page.Policies...)
+		if page.Next == nil {
+			break
+		}
+	}
+	return policies, nil
+}
+
+// escalationStep converts an escalation policy to a step.
+func escalationStep(p *aapi.Escalation, names *oncallNames) OnCallEscalationStep {
+	str := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	step := OnCallEscalationStep{
+		Position:    p.Position,
+		Type:        str(p.Type),
+		UserGroupID: str(p.GroupToNotify),
+		TeamID:      str(p.TeamToNotify),
+		WebhookID:   str(p.ActionToTrigger),
+		TimeFrom:    str(p.NotifyIfTimeFrom),
+		TimeTo:      str(p.NotifyIfTimeTo),
+		Severity:    str(p.Severity),
+	}
+	if p.Important != nil {
+		step.Important = *p.Important
+	}
+	if p.Duration != nil && step.Type == "wait" {
+		step.Wait = (time.Duration(*p.Duration) * time.Second).String()
+	}
+	if p.PersonsToNotify != nil {
+		for _, id := range *p.PersonsToNotify {
+			step.Users = append(step.Users, names.user(id))
+		}
+	}
+	if p.PersonsToNotifyEachTime != nil {
+		for _, id := range *p.PersonsToNotifyEachTime {
+			step.UsersEachTime = append(step.UsersEachTime, names.user(id))
+		}
+	}
+	if id := str(p.NotifyOnCallFromSchedule); id != "" {
+		schedule := names.schedule(id)
+		step.Schedule = &schedule
+	}
+	return step
+}
+
+type ListOnCallEscalationChainsParams struct {
+	EscalationChainID string `json:"escalationChainId,omitempty" jsonschema:"description=Optionally\\, the ID of the escalation chain to get"`
+	Name              string `json:"name,omitempty" jsonschema:"description=Optionally\\, only list the escalation chain with this name"`
+	Page              int    `json:"page,omitempty" jsonschema:"description=The page number to return"`
+}
+
+func listOnCallEscalationChains(ctx context.Context, args ListOnCallEscalationChainsParams) ([]OnCallEscalationChain, error) {
+	client, err := oncallClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting OnCall client: %w", err)
+	}
+	service := aapi.NewEscalationChainService(client)
+	var chains []*aapi.EscalationChain
+	if args.EscalationChainID != "" {
+		chain, _, err := service.GetEscalationChain(args.EscalationChainID, &aapi.GetEscalationChainOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("getting escalation chain %s: %w", args.EscalationChainID, err)
+		}
+		chains = append(chains, chain)
+	} else {
+		opts := &aapi.ListEscalationChainOptions{Name: args.Name}
+		if args.Page > 0 {
+			opts.Page = args.Page
+		}
+		response, _, err := service.ListEscalationChains(opts)
+		if err != nil {
+			return nil, fmt.Errorf("listing escalation chains: %w", err)
+		}
+		chains = response.EscalationChains
+	}
+
+	names := newOnCallNames(client)
+	result := make([]OnCallEscalationChain, 0, len(chains))
+	for _, chain := range chains {
+		policies, err := fetchEscalationPolicies(client, chain.ID)
+		if err != nil {
+			return nil, err
+		}
+		summary := OnCallEscalationChain{ID: chain.ID, Name: chain.Name, TeamID: chain.TeamId, Steps: []OnCallEscalationStep{}}
+		for _, p := range policies {
+			summary.Steps = append(summary.Steps, escalationStep(p, names))
+		}
+		slices.SortStableFunc(summary.Steps, func(a, b OnCallEscalationStep) int { return a.Position - b.Position })
+		result = append(result, summary)
+	}
+	return result, nil
+}
+
+var ListOnCallEscalationChains = mcpgrafana.MustTool(
+	"grafana_list_oncall_escalation_chains",
+	"List Grafana OnCall escalation chains with their steps in order: who is notified, directly, one after the other, or from an on-call schedule, with the usernames and schedule names, how long each wait lasts, and which steps notify with important notifications. Together with `grafana_list_oncall_integrations`, which tells which escalation chain each route of an integration uses, this answers who gets paged for an alert. Supports pagination.",
+	listOnCallEscalationChains,
+	mcp.WithTitleAnnotation("List OnCall escalation chains"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// OnCallRoute is a route of an integration, which sends the alert groups
+// matching it to an escalation chain.
+type OnCallRoute struct {
+	ID       string `json:"id"`
+	Position int    `json:"position"`
+	// RoutingType is jinja2 for template routes or regex.
+	RoutingType  string `json:"routingType,omitempty"`
+	RoutingRegex string `json:"routingRegex,omitempty"`
+	// IsDefault is true for the route of the alert groups which match no
+	// other route.
+	IsDefault       bool       `json:"isDefault"`
+	EscalationChain *OnCallRef `json:"escalationChain,omitempty"`
+}
+
+// OnCallIntegration is an integration receiving alerts, with its routes in
+// order and its templates.
+type OnCallIntegration struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Type        string            `json:"type"`
+	TeamID      string            `json:"teamId,omitempty"`
+	AlertGroups int               `json:"alertGroups"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Routes      []OnCallRoute     `json:"routes"`
+	Templates   *aapi.Templates   `json:"templates,omitempty"`
+}
+
+// fetchRoutes returns the routes of an integration, following pagination.
+func fetchRoutes(client *aapi.Client, integrationID string) ([]*aapi.Route, error) {
+	service := aapi.NewRouteService(client)
+	opts := &aapi.ListRouteOptions{ListOptions: aapi.ListOptions{Page: 1}, IntegrationId: integrationID}
+	var routes []*aapi.Route
+	for ; opts.Page <= maxOnCallPages; opts.Page++ {
+		page, _, err := service.ListRoutes(opts)
+		if err != nil {
+			return nil, fmt.Errorf("listing the routes of integration %s: %w", integrationID, err)
+		}
+		routes = append(routes, page.Routes...)
+		if page.Next == nil {
+			break
+		}
+	}
+	return routes, nil
+}
+
+// fetchEscalationChainNames returns the names of all escalation chains by
+// ID.
+func fetchEscalationChainNames(client *aapi.Client) (map[string]string, error) {
+	service := aapi.NewEscalationChainService(client)
+	opts := &aapi.ListEscalationChainOptions{ListOptions: aapi.ListOptions{Page: 1}}
+	names := map[string]string{}
+	for ; opts.Page <= maxOnCallPages; opts.Page++ {
+		page, _, err := service.ListEscalationChains(opts)
+		if err != nil {
+			return nil, fmt.Errorf("listing escalation chains: %w", err)
+		}
+		for _, chain := range page.EscalationChains {
+			names[chain.ID] = chain.Name
+		}
+		if page.Next == nil {
+			break
+		}
+	}
+	return names, nil
+}
+
+type ListOnCallIntegrationsParams struct {
+	IntegrationID string `json:"integrationId,omitempty" jsonschema:"description=Optionally\\, the ID of the integration to get"`
+	Page          int    `json:"page,omitempty" jsonschema:"description=The page number to return"`
+}
+
+func listOnCallIntegrations(ctx context.Context, args ListOnCallIntegrationsParams) ([]OnCallIntegration, error) {
+	client, err := oncallClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting OnCall client: %w", err)
+	}
+	service := aapi.NewIntegrationService(client)
+	var integrations []*aapi.Integration
+	if args.IntegrationID != "" {
+		integration, _, err := service.GetIntegration(args.IntegrationID, &aapi.GetIntegrationOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("getting integration %s: %w", args.IntegrationID, err)
+		}
+		integrations = append(integrations, integration)
+	} else {
+		opts := &aapi.ListIntegrationOptions{}
+		if args.Page > 0 {
+			opts.Page = args.Page
+		}
+		response, _, err := service.ListIntegrations(opts)
+		if err != nil {
+			return nil, fmt.Errorf("listing integrations: %w", err)
+		}
+		integrations = response.Integrations
+	}
+	chainNames, err := fetchEscalationChainNames(client)
+	if err != nil {
+		return nil, err
+	}
+	chain := func(id string) *OnCallRef {
+		if id == "" {
+			return nil
+		}
+		return &OnCallRef{ID: id, Name: chainNames[id]}
+	}
+
+	result := make([]OnCallIntegration, 0, len(integrations))
+	for _, integration := range integrations {
+		// The inbound URL of the integration is left out, since anyone
+		// knowing it can send alerts.
+		summary := OnCallIntegration{
+			ID:          integration.ID,
+			Name:        integration.Name,
+			Type:        integration.Type,
+			TeamID:      integration.TeamId,
+			AlertGroups: integration.IncidentsCount,
+			Routes:      []OnCallRoute{},
+			Templates:   integration.Templates,
+		}
+		for _, label := range integration.Labels {
+			if summary.Labels == nil {
+				summary.Labels = map[string]string{}
+			}
+			summary.Labels[label.Key.Name] = label.Value.Name
+		}
+		routes, err := fetchRoutes(client, integration.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, route := range routes {
+			summary.Routes = append(summary.Routes, OnCallRoute{
+				ID:              route.ID,
+				Position:        route.Position,
+				RoutingType:     route.RoutingType,
+				RoutingRegex:    route.RoutingRegex,
+				IsDefault:       route.IsTheLastRoute,
+				EscalationChain: chain(route.EscalationChainId),
+			})
+		}
+		if len(summary.Routes) == 0 && integration.DefaultRoute != nil {
+			route := OnCallRoute{ID: integration.DefaultRoute.ID, IsDefault: true}
+			if integration.DefaultRoute.EscalationChainId != nil {
+				route.EscalationChain = chain(*integration.DefaultRoute.EscalationChainId)
+			}
+			summary.Routes = append(summary.Routes, route)
+		}
+		slices.SortStableFunc(summary.Routes, func(a, b OnCallRoute) int { return a.Position - b.Position })
+		result = append(result, summary)
+	}
+	return result, nil
+}
+
+var ListOnCallIntegrations = mcpgrafana.MustTool(
+	"grafana_list_oncall_integrations",
+	"List Grafana OnCall integrations, which receive alerts from Grafana Alerting, Alertmanager, webhooks and other sources. Each comes with its routes in order, with their routing template or regex and the escalation chain they page, the default route last, and its templates for grouping, auto-resolving and notification messages. Use `grafana_list_oncall_escalation_chains` to see who the escalation chains notify. Supports pagination.",
+	listOnCallIntegrations,
+	mcp.WithTitleAnnotation("List OnCall integrations"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
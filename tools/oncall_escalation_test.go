@@ -0,0 +1,99 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListOnCallEscalationChains(t *testing.T) {
+	var server *httptest.Server
+	server = newFakeGrafana(t, fakeRoutes{
+		"/api/plugins/grafana-irm-app/settings": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"jsonData": {"onCallApiUrl": %q}}`, server.URL)
+		},
+		"/api/v1/escalation_chains/": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{"count": 1, "results": []any{map[string]any{"id": "EC1", "name": "Primary", "team_id": "T1"}}})
+		},
+		"/api/v1/escalation_policies": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "EC1", r.URL.Query().Get("escalation_chain_id"))
+			_ = json.NewEncoder(w).Encode(map[string]any{"count": 3, "results": []any{
+				map[string]any{"id": "P3", "escalation_chain_id": "EC1", "position": 2, "type": "notify_persons", "persons_to_notify": []string{"U1", "U2"}, "important": true},
+				map[string]any{"id": "P1", "escalation_chain_id": "EC1", "position": 0, "type": "notify_on_call_from_schedule", "notify_on_call_from_schedule": "S1"},
+				map[string]any{"id": "P2", "escalation_chain_id": "EC1", "position": 1, "type": "wait", "duration": 300},
+			}})
+		},
+		"/api/v1/users/U1/": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "U1", "username": "alice"})
+		},
+		"/api/v1/schedules/S1/": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "S1", "name": "Primary rotation"})
+		},
+	})
+	ctx := fakeGrafanaContext(server)
+
+	chains, err := listOnCallEscalationChains(ctx, ListOnCallEscalationChainsParams{})
+	require.NoError(t, err)
+	require.Len(t, chains, 1)
+	assert.Equal(t, "Primary", chains[0].Name)
+	require.Len(t, chains[0].Steps, 3)
+	assert.Equal(t, &OnCallRef{ID: "S1", Name: "Primary rotation"}, chains[0].Steps[0].Schedule)
+	assert.Equal(t, "5m0s", chains[0].Steps[1].Wait)
+	assert.True(t, chains[0].Steps[2].Important)
+	assert.Equal(t, []OnCallRef{{ID: "U1", Name: "alice"}, {ID: "U2"}}, chains[0].Steps[2].Users)
+}
+
+func TestListOnCallIntegrations(t *testing.T) {
+	var server *httptest.Server
+	server = newFakeGrafana(t, fakeRoutes{
+		"/api/plugins/grafana-irm-app/settings": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"jsonData": {"onCallApiUrl": %q}}`, server.URL)
+		},
+		"/api/v1/integrations/": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{"count": 1, "results": []any{map[string]any{
+				"id": "I1", "name": "Alertmanager", "type": "alertmanager", "link": "https://oncall.example.com/secret", "incidents_count": 7,
+				"labels":    []any{map[string]any{"key": map[string]any{"name": "team"}, "value": map[string]any{"name": "infra"}}},
+				"templates": map[string]any{"grouping_key": "{{ payload.groupKey }}"},
+			}}})
+		},
+		"/api/v1/routes": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "I1", r.URL.Query().Get("integration_id"))
+			_ = json.NewEncoder(w).Encode(map[string]any{"count": 2, "results": []any{
+				map[string]any{"id": "R2", "integration_id": "I1", "escalation_chain_id": "EC1", "position": 1, "is_the_last_route": true},
+				map[string]any{"id": "R1", "integration_id": "I1", "escalation_chain_id": "EC2", "position": 0, "routing_type": "jinja2", "routing_regex": "{{ payload.severity == \"critical\" }}"},
+			}})
+		},
+		"/api/v1/escalation_chains/": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{"count": 2, "results": []any{
+				map[string]any{"id": "EC1", "name": "Default"},
+				map[string]any{"id": "EC2", "name": "Critical"},
+			}})
+		},
+	})
+	ctx := fakeGrafanaContext(server)
+
+	integrations, err := listOnCallIntegrations(ctx, ListOnCallIntegrationsParams{})
+	require.NoError(t, err)
+	require.Len(t, integrations, 1)
+	integration := integrations[0]
+	assert.Equal(t, 7, integration.AlertGroups)
+	assert.Equal(t, map[string]string{"team": "infra"}, integration.Labels)
+	require.NotNil(t, integration.Templates)
+	require.Len(t, integration.Routes, 2)
+	assert.Equal(t, "R1", integration.Routes[0].ID)
+	assert.Equal(t, &OnCallRef{ID: "EC2", Name: "Critical"}, integration.Routes[0].EscalationChain)
+	assert.True(t, integration.Routes[1].IsDefault)
+	assert.Equal(t, "Default", integration.Routes[1].EscalationChain.Name)
+
+	out, err := json.Marshal(integrations)
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "secret")
+}
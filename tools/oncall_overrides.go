@@ -0,0 +1,278 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	aapi "github.com/grafana/amixr-api-go-client"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// oncallShiftSourceAPI is the source of on-call shifts created through the
+// OnCall API.
+const oncallShiftSourceAPI = 2
+
+// oncallTimeLayout is the layout of times without time zone in the OnCall
+// API.
+const oncallTimeLayout = "2006-01-02T15:04:05"
+
+// parseOnCallPeriod parses the start and end of an override or shift swap,
+// which must end after it starts and in the future.
+func parseOnCallPeriod(start, end string) (time.Time, time.Time, error) {
+	from, err := parseTime(start)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("parsing start: %w", err)
+	}
+	to, err := parseTime(end)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("parsing end: %w", err)
+	}
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("end %s must be after start %s", to.Format(time.RFC3339), from.Format(time.RFC3339))
+	}
+	if !to.After(time.Now()) {
+		return time.Time{}, time.Time{}, fmt.Errorf("end %s is in the past", to.Format(time.RFC3339))
+	}
+	return from, to, nil
+}
+
+type CreateOnCallOverrideParams struct {
+	ScheduleID string   `json:"scheduleId" jsonschema:"required,description=The ID of the OnCall schedule to override"`
+	UserIDs    []string `json:"userIds" jsonschema:"required,description=The IDs of the OnCall users on call during the override instead of the scheduled ones. Use grafana_list_oncall_users to find them"`
+	Start      string   `json:"start" jsonschema:"required,description=When the override starts\\, as RFC3339 or relative to now\\, e.g. now+2h"`
+	End        string   `json:"end" jsonschema:"required,description=When the override ends\\, as RFC3339 or relative to now\\, e.g. now+14h"`
+	Name       string   `json:"name,omitempty" jsonschema:"description=Optionally\\, the name of the override\\, e.g. the reason for it"`
+}
+
+// OnCallOverride is an override created in a schedule.
+type OnCallOverride struct {
+	ScheduleID   string            `json:"scheduleId"`
+	ScheduleName string            `json:"scheduleName"`
+	Shift        *aapi.OnCallShift `json:"shift"`
+}
+
+func createOnCallOverride(ctx context.Context, args CreateOnCallOverrideParams) (*OnCallOverride, error) {
+	if len(args.UserIDs) == 0 {
+		return nil, fmt.Errorf("create override: userIds is required")
+	}
+	start, end, err := parseOnCallPeriod(args.Start, args.End)
+	if err != nil {
+		return nil, fmt.Errorf("create override: %w", err)
+	}
+	client, err := oncallClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting OnCall client: %w", err)
+	}
+	schedules := aapi.NewScheduleService(client)
+	schedule, _, err := schedules.GetSchedule(args.ScheduleID, &aapi.GetScheduleOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("getting OnCall schedule %s: %w", args.ScheduleID, err)
+	}
+	// Only the shifts of calendar schedules are managed through the API: web
+	// schedules are changed by shift swaps, and iCal schedules by their
+	// overrides calendar.
+	switch schedule.Type {
+	case "calendar":
+	case "web":
+		return nil, fmt.Errorf("schedule %s is a web schedule, whose overrides can only be created in the OnCall UI: request a shift swap with grafana_request_oncall_shift_swap instead", schedule.Name)
+	default:
+		return nil, fmt.Errorf("schedule %s is an iCal schedule, whose overrides come from its overrides calendar", schedule.Name)
+	}
+
+	users := args.UserIDs
+	timeZone := "UTC"
+	shift, _, err := aapi.NewOnCallShiftService(client).CreateOnCallShift(&aapi.CreateOnCallShiftOptions{
+		TeamId:   schedule.TeamId,
+		Type:     "override",
+		Name:     cmp.Or(args.Name, fmt.Sprintf("Override %s", start.UTC().Format(time.RFC3339))),
+		Start:    start.UTC().Format(oncallTimeLayout),
+		Duration: int(end.Sub(start).Seconds()),
+		Users:    &users,
+		Source:   oncallShiftSourceAPI,
+		TimeZone: &timeZone,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating override: %w", err)
+	}
+
+	// Updating a schedule replaces all its settings, which are carried over.
+	var shifts []string
+	if schedule.Shifts != nil {
+		shifts = *schedule.Shifts
+	}
+	shifts = append(shifts, shift.ID)
+	_, _, err = schedules.UpdateSchedule(schedule.ID, &aapi.UpdateScheduleOptions{
+		Name:               schedule.Name,
+		TeamId:             schedule.TeamId,
+		ICalUrlPrimary:     schedule.ICalUrlPrimary,
+		ICalUrlOverrides:   schedule.ICalUrlOverrides,
+		TimeZone:           schedule.TimeZone,
+		EnableWebOverrides: schedule.EnableWebOverrides,
+		Slack:              schedule.Slack,
+		Shifts:             &shifts,
+	})
+	if err != nil {
+		// Don't leave an override which isn't part of any schedule behind.
+		_, _ = aapi.NewOnCallShiftService(client).DeleteOnCallShift(shift.ID, &aapi.DeleteOnCallShiftOptions{})
+		return nil, fmt.Errorf("adding override %s to schedule %s: %w", shift.ID, schedule.Name, err)
+	}
+	return &OnCallOverride{ScheduleID: schedule.ID, ScheduleName: schedule.Name, Shift: shift}, nil
+}
+
+var CreateOnCallOverride = mcpgrafana.MustTool(
+	"grafana_create_oncall_override",
+	"Create an override in a Grafana OnCall calendar schedule, putting the given users on call instead of the scheduled ones for a period, e.g. to cover for someone who is sick tonight. The override takes precedence over the other shifts of the schedule. Web schedules can't be overridden through the API: request a shift swap with `grafana_request_oncall_shift_swap` for them instead. Check who is on call afterwards with `grafana_get_current_oncall_users`.",
+	createOnCallOverride,
+	mcp.WithTitleAnnotation("Create OnCall override"),
+	mcp.WithIdempotentHintAnnotation(false),
+	mcp.WithReadOnlyHintAnnotation(false),
+)
+
+// OnCallShiftSwap is a request of a user, the beneficiary, for someone else,
+// the benefactor, to take their shifts of a schedule during a period.
+type OnCallShiftSwap struct {
+	ID          string `json:"id"`
+	Schedule    string `json:"schedule"`
+	SwapStart   string `json:"swap_start"`
+	SwapEnd     string `json:"swap_end"`
+	Beneficiary string `json:"beneficiary"`
+	// Benefactor is set once the swap was taken.
+	Benefactor  *string `json:"benefactor"`
+	Description string  `json:"description,omitempty"`
+	// Status is open, taken, past_due or deleted.
+	Status    string `json:"status"`
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+type paginatedShiftSwaps struct {
+	aapi.PaginatedResponse
+	ShiftSwaps []*OnCallShiftSwap `json:"results"`
+}
+
+type RequestOnCallShiftSwapParams struct {
+	ScheduleID  string `json:"scheduleId" jsonschema:"required,description=The ID of the OnCall schedule"`
+	UserID      string `json:"userId" jsonschema:"required,description=The ID of the OnCall user whose shifts need to be taken by someone else"`
+	Start       string `json:"start" jsonschema:"required,description=When the swap starts\\, as RFC3339 or relative to now\\, e.g. now+2h. It must be in the future"`
+	End         string `json:"end" jsonschema:"required,description=When the swap ends\\, as RFC3339 or relative to now\\, e.g. now+14h"`
+	Description string `json:"description,omitempty" jsonschema:"description=Optionally\\, why the swap is needed"`
+}
+
+func requestOnCallShiftSwap(ctx context.Context, args RequestOnCallShiftSwapParams) (*OnCallShiftSwap, error) {
+	start, end, err := parseOnCallPeriod(args.Start, args.End)
+	if err != nil {
+		return nil, fmt.Errorf("request shift swap: %w", err)
+	}
+	client, err := oncallClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting OnCall client: %w", err)
+	}
+	req, err := client.NewRequest(http.MethodPost, "shift_swaps/", map[string]string{
+		"schedule":    args.ScheduleID,
+		"beneficiary": args.UserID,
+		"swap_start":  start.UTC().Format(time.RFC3339),
+		"swap_end":    end.UTC().Format(time.RFC3339),
+		"description": args.Description,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("request shift swap: %w", err)
+	}
+	var swap OnCallShiftSwap
+	if _, err := client.Do(req, &swap); err != nil {
+		return nil, fmt.Errorf("requesting shift swap in schedule %s: %w", args.ScheduleID, err)
+	}
+	return &swap, nil
+}
+
+var RequestOnCallShiftSwap = mcpgrafana.MustTool(
+	"grafana_request_oncall_shift_swap",
+	"Request a shift swap in a Grafana OnCall web schedule: ask for someone else to take the shifts of a user during a period, e.g. when they are sick tonight. The user's teammates are notified of the request, and the swap takes effect once someone takes it, which can be done with `grafana_take_oncall_shift_swap`. Returns the open shift swap request.",
+	requestOnCallShiftSwap,
+	mcp.WithTitleAnnotation("Request OnCall shift swap"),
+	mcp.WithIdempotentHintAnnotation(false),
+	mcp.WithReadOnlyHintAnnotation(false),
+)
+
+type ListOnCallShiftSwapsParams struct {
+	ScheduleID string `json:"scheduleId,omitempty" jsonschema:"description=Optionally\\, only list the shift swaps of this OnCall schedule"`
+	UserID     string `json:"userId,omitempty" jsonschema:"description=Optionally\\, only list the shift swaps requested by this OnCall user"`
+	OpenOnly   bool   `json:"openOnly,omitempty" jsonschema:"description=Only list the shift swaps which are still open"`
+	Page       int    `json:"page,omitempty" jsonschema:"description=The page number to return"`
+}
+
+type shiftSwapOptions struct {
+	aapi.ListOptions
+	ScheduleID  string `url:"schedule_id,omitempty"`
+	Beneficiary string `url:"beneficiary,omitempty"`
+	OpenOnly    bool   `url:"open_only,omitempty"`
+}
+
+func listOnCallShiftSwaps(ctx context.Context, args ListOnCallShiftSwapsParams) ([]*OnCallShiftSwap, error) {
+	client, err := oncallClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting OnCall client: %w", err)
+	}
+	opts := &shiftSwapOptions{ScheduleID: args.ScheduleID, Beneficiary: args.UserID, OpenOnly: args.OpenOnly}
+	if args.Page > 0 {
+		opts.Page = args.Page
+	}
+	req, err := client.NewRequest(http.MethodGet, "shift_swaps/", opts)
+	if err != nil {
+		return nil, fmt.Errorf("list shift swaps: %w", err)
+	}
+	var page paginatedShiftSwaps
+	if _, err := client.Do(req, &page); err != nil {
+		return nil, fmt.Errorf("listing shift swaps: %w", err)
+	}
+	if page.ShiftSwaps == nil {
+		return []*OnCallShiftSwap{}, nil
+	}
+	return page.ShiftSwaps, nil
+}
+
+var ListOnCallShiftSwaps = mcpgrafana.MustTool(
+	"grafana_list_oncall_shift_swaps",
+	"List shift swap requests of Grafana OnCall web schedules, with their period, the user who requested them, the user who took them if any and their status: open, taken, past_due or deleted. Supports pagination.",
+	listOnCallShiftSwaps,
+	mcp.WithTitleAnnotation("List OnCall shift swaps"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type TakeOnCallShiftSwapParams struct {
+	ShiftSwapID string `json:"shiftSwapId" jsonschema:"required,description=The ID of the open shift swap request"`
+	UserID      string `json:"userId" jsonschema:"required,description=The ID of the OnCall user taking the shifts"`
+}
+
+func takeOnCallShiftSwap(ctx context.Context, args TakeOnCallShiftSwapParams) (*OnCallShiftSwap, error) {
+	if args.ShiftSwapID == "" || args.UserID == "" {
+		return nil, fmt.Errorf("take shift swap: shiftSwapId and userId are required")
+	}
+	client, err := oncallClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting OnCall client: %w", err)
+	}
+	req, err := client.NewRequest(http.MethodPost, fmt.Sprintf("shift_swaps/%s/take", url.PathEscape(args.ShiftSwapID)), map[string]string{"benefactor": args.UserID})
+	if err != nil {
+		return nil, fmt.Errorf("take shift swap %s: %w", args.ShiftSwapID, err)
+	}
+	var swap OnCallShiftSwap
+	if _, err := client.Do(req, &swap); err != nil {
+		return nil, fmt.Errorf("taking shift swap %s: %w", args.ShiftSwapID, err)
+	}
+	return &swap, nil
+}
+
+var TakeOnCallShiftSwap = mcpgrafana.MustTool(
+	"grafana_take_oncall_shift_swap",
+	"Take an open Grafana OnCall shift swap request on behalf of a user, who is then on call instead of the requesting user during the swap. This approves the swap: only take it for a user who agreed to cover the shifts. Returns the shift swap with its new status.",
+	takeOnCallShiftSwap,
+	mcp.WithTitleAnnotation("Take OnCall shift swap"),
+	mcp.WithIdempotentHintAnnotation(false),
+	mcp.WithReadOnlyHintAnnotation(false),
+)
@@ -0,0 +1,116 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateOnCallOverride(t *testing.T) {
+	var shiftBody, scheduleBody map[string]any
+	var server *httptest.Server
+	server = newFakeGrafana(t, fakeRoutes{
+		"/api/plugins/grafana-irm-app/settings": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"jsonData": {"onCallApiUrl": %q}}`, server.URL)
+		},
+		"/api/v1/schedules/S1/": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPut {
+				if !decodeJSONBody(t, w, r, &scheduleBody) {
+					return
+				}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "S1", "name": "Primary", "type": "calendar", "team_id": "T1", "time_zone": "Europe/Paris", "ical_url_overrides": "https://example.com/overrides.ics", "shifts": []string{"OS1"}})
+		},
+		"/api/v1/schedules/S2/": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "S2", "name": "Secondary", "type": "web"})
+		},
+		"/api/v1/on_call_shifts/": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			if !decodeJSONBody(t, w, r, &shiftBody) {
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "OS2", "type": "override", "name": shiftBody["name"], "start": shiftBody["start"], "duration": shiftBody["duration"], "users": shiftBody["users"]})
+		},
+	})
+	ctx := fakeGrafanaContext(server)
+
+	override, err := createOnCallOverride(ctx, CreateOnCallOverrideParams{ScheduleID: "S1", UserIDs: []string{"U2"}, Start: "2099-01-01T18:00:00Z", End: "2099-01-02T06:00:00Z", Name: "Cover for Alice"})
+	require.NoError(t, err)
+	assert.Equal(t, "Primary", override.ScheduleName)
+	assert.Equal(t, "OS2", override.Shift.ID)
+	assert.Equal(t, "override", shiftBody["type"])
+	assert.Equal(t, "Cover for Alice", shiftBody["name"])
+	assert.Equal(t, "2099-01-01T18:00:00", shiftBody["start"])
+	assert.Equal(t, float64(12*60*60), shiftBody["duration"])
+	assert.Equal(t, "UTC", shiftBody["time_zone"])
+	assert.Equal(t, []any{"OS1", "OS2"}, scheduleBody["shifts"])
+	assert.Equal(t, "https://example.com/overrides.ics", scheduleBody["ical_url_overrides"])
+	assert.Equal(t, "Europe/Paris", scheduleBody["time_zone"])
+
+	_, err = createOnCallOverride(ctx, CreateOnCallOverrideParams{ScheduleID: "S2", UserIDs: []string{"U2"}, Start: "now", End: "now+1h"})
+	assert.ErrorContains(t, err, "grafana_request_oncall_shift_swap")
+
+	_, err = createOnCallOverride(ctx, CreateOnCallOverrideParams{ScheduleID: "S1", UserIDs: []string{"U2"}, Start: "now-2h", End: "now-1h"})
+	assert.ErrorContains(t, err, "in the past")
+}
+
+func TestOnCallShiftSwaps(t *testing.T) {
+	var requestBody, takeBody map[string]any
+	var server *httptest.Server
+	server = newFakeGrafana(t, fakeRoutes{
+		"/api/plugins/grafana-irm-app/settings": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"jsonData": {"onCallApiUrl": %q}}`, server.URL)
+		},
+		"/api/v1/shift_swaps/": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				if !decodeJSONBody(t, w, r, &requestBody) {
+					return
+				}
+				_ = json.NewEncoder(w).Encode(map[string]any{"id": "SSR1", "schedule": "S1", "beneficiary": "U1", "status": "open", "swap_start": requestBody["swap_start"], "swap_end": requestBody["swap_end"]})
+				return
+			}
+			assert.Equal(t, "S1", r.URL.Query().Get("schedule_id"))
+			assert.Equal(t, "true", r.URL.Query().Get("open_only"))
+			_ = json.NewEncoder(w).Encode(map[string]any{"count": 1, "results": []any{map[string]any{"id": "SSR1", "schedule": "S1", "beneficiary": "U1", "status": "open"}}})
+		},
+		"/api/v1/shift_swaps/SSR1/take": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			if !decodeJSONBody(t, w, r, &takeBody) {
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "SSR1", "schedule": "S1", "beneficiary": "U1", "benefactor": takeBody["benefactor"], "status": "taken"})
+		},
+		"/api/v1/shift_swaps/SSR2/take": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"detail": "Shift swap request is not open"}`))
+		},
+	})
+	ctx := fakeGrafanaContext(server)
+
+	swap, err := requestOnCallShiftSwap(ctx, RequestOnCallShiftSwapParams{ScheduleID: "S1", UserID: "U1", Start: "2099-01-01T18:00:00Z", End: "2099-01-02T06:00:00Z", Description: "Sick"})
+	require.NoError(t, err)
+	assert.Equal(t, "open", swap.Status)
+	assert.Equal(t, map[string]any{"schedule": "S1", "beneficiary": "U1", "swap_start": "2099-01-01T18:00:00Z", "swap_end": "2099-01-02T06:00:00Z", "description": "Sick"}, requestBody)
+
+	swaps, err := listOnCallShiftSwaps(ctx, ListOnCallShiftSwapsParams{ScheduleID: "S1", OpenOnly: true})
+	require.NoError(t, err)
+	require.Len(t, swaps, 1)
+	assert.Equal(t, "SSR1", swaps[0].ID)
+
+	swap, err = takeOnCallShiftSwap(ctx, TakeOnCallShiftSwapParams{ShiftSwapID: "SSR1", UserID: "U2"})
+	require.NoError(t, err)
+	assert.Equal(t, "taken", swap.Status)
+	require.NotNil(t, swap.Benefactor)
+	assert.Equal(t, "U2", *swap.Benefactor)
+
+	_, err = takeOnCallShiftSwap(ctx, TakeOnCallShiftSwapParams{ShiftSwapID: "SSR2", UserID: "U2"})
+	assert.ErrorContains(t, err, "not open")
+}
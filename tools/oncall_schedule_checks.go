@@ -0,0 +1,298 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	aapi "github.com/grafana/amixr-api-go-client"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	// DefaultScheduleCheckHorizonDays is the number of days analyzed by
+	// grafana_check_oncall_schedule when no horizon is given.
+	DefaultScheduleCheckHorizonDays = 14
+	// MaxScheduleCheckHorizonDays is the maximum number of days that can be
+	// analyzed at once.
+	MaxScheduleCheckHorizonDays = 90
+	// DefaultMaxSinglePersonHours is the length of a stretch covered by a
+	// single person above which it is reported.
+	DefaultMaxSinglePersonHours = 24
+
+	// maxFinalShiftPages bounds the number of pages of final shifts fetched
+	// for a single schedule.
+	maxFinalShiftPages = 50
+)
+
+// Kinds of schedule findings.
+const (
+	findingGap                 = "gap"
+	findingOverlap             = "overlap"
+	findingSinglePersonStretch = "single_person_stretch"
+)
+
+type CheckOnCallScheduleParams struct {
+	ScheduleID           string `json:"scheduleId" jsonschema:"required,description=The ID of the schedule to check"`
+	HorizonDays          int    `json:"horizonDays,omitempty" jsonschema:"description=The number of days from now to analyze. Defaults to 14; at most 90"`
+	MaxSinglePersonHours int    `json:"maxSinglePersonHours,omitempty" jsonschema:"description=Report stretches where a single person is on call alone for longer than this many hours. Defaults to 24"`
+}
+
+// finalShift is a shift of the final schedule, after rotations and overrides
+// have been applied.
+type finalShift struct {
+	UserPK       string    `json:"user_pk"`
+	UserEmail    string    `json:"user_email"`
+	UserUsername string    `json:"user_username"`
+	ShiftStart   time.Time `json:"shift_start"`
+	ShiftEnd     time.Time `json:"shift_end"`
+}
+
+func (s finalShift) user() string {
+	if s.UserUsername != "" {
+		return s.UserUsername
+	}
+	if s.UserEmail != "" {
+		return s.UserEmail
+	}
+	return s.UserPK
+}
+
+type finalShiftsOptions struct {
+	aapi.ListOptions
+	StartDate string `url:"start_date"`
+	EndDate   string `url:"end_date"`
+}
+
+type paginatedFinalShifts struct {
+	aapi.PaginatedResponse
+	Shifts []finalShift `json:"results"`
+}
+
+// scheduleFinding is a potential problem with the coverage of a schedule.
+type scheduleFinding struct {
+	Kind          string    `json:"kind"`
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	DurationHours float64   `json:"durationHours"`
+	Users         []string  `json:"users,omitempty"`
+	Message       string    `json:"message"`
+}
+
+type scheduleCheckReport struct {
+	ScheduleID      string            `json:"scheduleId"`
+	ScheduleName    string            `json:"scheduleName"`
+	TimeZone        string            `json:"timeZone,omitempty"`
+	Start           time.Time         `json:"start"`
+	End             time.Time         `json:"end"`
+	Shifts          int               `json:"shifts"`
+	CoveragePercent float64           `json:"coveragePercent"`
+	Findings        []scheduleFinding `json:"findings"`
+}
+
+// fetchFinalShifts returns the final shifts of a schedule between the given
+// dates, following pagination.
+func fetchFinalShifts(client *aapi.Client, scheduleID string, start, end time.Time) ([]finalShift, error) {
+	opts := &finalShiftsOptions{
+		ListOptions: aapi.ListOptions{Page: 1},
+		StartDate:   start.UTC().Format(time.DateOnly),
+		EndDate:     end.UTC().Format(time.DateOnly),
+	}
+	var shifts []finalShift
+	for ; opts.Page <= maxFinalShiftPages; opts.Page++ {
+		req, err := client.NewRequest("GET", fmt.Sprintf("schedules/%s/final_shifts/", scheduleID), opts)
+		if err != nil {
+			return nil, fmt.Errorf("creating final shifts request: %w", err)
+		}
+		var page paginatedFinalShifts
+		if _, err := client.Do(req, &page); err != nil {
+			return nil, fmt.Errorf("getting final shifts for schedule %s: %w", scheduleID, err)
+		}
+		shifts = append(shifts, page.Shifts...)
+		if page.Next == nil {
+			break
+		}
+	}
+	return shifts, nil
+}
+
+func roundedHours(d time.Duration) float64 {
+	return float64(d.Round(time.Minute)) / float64(time.Hour)
+}
+
+// checkShifts analyzes the shifts covering [start, end) and returns the
+// findings, ordered by start time, along with the percentage of the period
+// covered by at least one person.
+func checkShifts(shifts []finalShift, start, end time.Time, maxSinglePerson time.Duration) ([]scheduleFinding, float64) {
+	// Split the period into segments at every shift boundary, and find who is
+	// on call during each segment.
+	boundaries := []time.Time{start, end}
+	for _, s := range shifts {
+		if s.ShiftStart.After(start) && s.ShiftStart.Before(end) {
+			boundaries = append(boundaries, s.ShiftStart)
+		}
+		if s.ShiftEnd.After(start) && s.ShiftEnd.Before(end) {
+			boundaries = append(boundaries, s.ShiftEnd)
+		}
+	}
+	sort.Slice(boundaries, func(i, j int) bool { return boundaries[i].Before(boundaries[j]) })
+	boundaries = slices.CompactFunc(boundaries, time.Time.Equal)
+
+	type segment struct {
+		start, end time.Time
+		users      []string
+	}
+	segments := make([]segment, 0, len(boundaries)-1)
+	for i := 0; i+1 < len(boundaries); i++ {
+		seg := segment{start: boundaries[i], end: boundaries[i+1]}
+		for _, s := range shifts {
+			if s.ShiftStart.After(seg.start) || !s.ShiftEnd.After(seg.start) {
+				continue
+			}
+			if u := s.user(); !slices.Contains(seg.users, u) {
+				seg.users = append(seg.users, u)
+			}
+		}
+		slices.Sort(seg.users)
+		segments = append(segments, seg)
+	}
+
+	var findings []scheduleFinding
+	var covered time.Duration
+	// Merge consecutive segments with the same kind of coverage into a single
+	// finding.
+	for i := 0; i < len(segments); {
+		seg := segments[i]
+		j := i + 1
+		users := slices.Clone(seg.users)
+		switch {
+		case len(seg.users) == 0:
+			for j < len(segments) && len(segments[j].users) == 0 {
+				j++
+			}
+		case len(seg.users) == 1:
+			for j < len(segments) && slices.Equal(segments[j].users, seg.users) {
+				j++
+			}
+		default:
+			for j < len(segments) && len(segments[j].users) > 1 {
+				for _, u := range segments[j].users {
+					if !slices.Contains(users, u) {
+						users = append(users, u)
+					}
+				}
+				j++
+			}
+			slices.Sort(users)
+		}
+		stretchStart, stretchEnd := seg.start, segments[j-1].end
+		d := stretchEnd.Sub(stretchStart)
+		finding := scheduleFinding{Start: stretchStart, End: stretchEnd, DurationHours: roundedHours(d), Users: users}
+		switch {
+		case len(seg.users) == 0:
+			finding.Kind = findingGap
+			findings = append(findings, finding)
+		case len(seg.users) == 1:
+			covered += d
+			if d > maxSinglePerson {
+				finding.Kind = findingSinglePersonStretch
+				findings = append(findings, finding)
+			}
+		default:
+			covered += d
+			finding.Kind = findingOverlap
+			findings = append(findings, finding)
+		}
+		i = j
+	}
+
+	coverage := 0.0
+	if total := end.Sub(start); total > 0 {
+		coverage = float64(covered) / float64(total) * 100
+	}
+	return findings, coverage
+}
+
+// describeFindings sets the messages of the findings, formatting their start
+// times in the schedule's time zone.
+func describeFindings(findings []scheduleFinding, locale mcpgrafana.Locale, tz *time.Location) {
+	for i := range findings {
+		f := &findings[i]
+		start := locale.FormatTime(f.Start.In(tz))
+		d := locale.FormatDuration(f.End.Sub(f.Start).Round(time.Minute))
+		switch f.Kind {
+		case findingGap:
+			f.Message = fmt.Sprintf("Nobody is on call from %s for %s.", start, d)
+		case findingSinglePersonStretch:
+			f.Message = fmt.Sprintf("%s is the only person on call from %s for %s without a break.", f.Users[0], start, d)
+		case findingOverlap:
+			f.Message = fmt.Sprintf("%d people are on call at the same time from %s for %s.", len(f.Users), start, d)
+		}
+	}
+}
+
+func checkOnCallSchedule(ctx context.Context, args CheckOnCallScheduleParams) (*scheduleCheckReport, error) {
+	horizonDays := args.HorizonDays
+	if horizonDays <= 0 {
+		horizonDays = DefaultScheduleCheckHorizonDays
+	}
+	if horizonDays > MaxScheduleCheckHorizonDays {
+		return nil, fmt.Errorf("horizonDays must be at most %d", MaxScheduleCheckHorizonDays)
+	}
+	maxSinglePersonHours := args.MaxSinglePersonHours
+	if maxSinglePersonHours <= 0 {
+		maxSinglePersonHours = DefaultMaxSinglePersonHours
+	}
+
+	client, err := oncallClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting OnCall client: %w", err)
+	}
+	schedule, _, err := aapi.NewScheduleService(client).GetSchedule(args.ScheduleID, &aapi.GetScheduleOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("getting schedule %s: %w", args.ScheduleID, err)
+	}
+
+	start := time.Now().UTC().Truncate(time.Minute)
+	end := start.AddDate(0, 0, horizonDays)
+	// Final shifts are requested by date, so the last day is included to
+	// cover the whole horizon.
+	shifts, err := fetchFinalShifts(client, args.ScheduleID, start, end.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	findings, coverage := checkShifts(shifts, start, end, time.Duration(maxSinglePersonHours)*time.Hour)
+	tz, err := time.LoadLocation(schedule.TimeZone)
+	if err != nil {
+		tz = time.UTC
+	}
+	describeFindings(findings, mcpgrafana.GrafanaConfigFromContext(ctx).Locale, tz)
+	if findings == nil {
+		findings = []scheduleFinding{}
+	}
+	return &scheduleCheckReport{
+		ScheduleID:      schedule.ID,
+		ScheduleName:    schedule.Name,
+		TimeZone:        schedule.TimeZone,
+		Start:           start,
+		End:             end,
+		Shifts:          len(shifts),
+		CoveragePercent: math.Round(coverage*10) / 10,
+		Findings:        findings,
+	}, nil
+}
+
+var CheckOnCallSchedule = mcpgrafana.MustTool(
+	"grafana_check_oncall_schedule",
+	"Check the quality of a Grafana OnCall schedule over the coming days. Analyzes the final schedule, including overrides, and reports gaps where nobody is on call, overlaps where several people are on call at once, and long stretches covered by a single person. Returns the coverage percentage and a list of findings with their time ranges and users, which can be raised with the team owning the schedule.",
+	checkOnCallSchedule,
+	mcp.WithTitleAnnotation("Check OnCall schedule"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
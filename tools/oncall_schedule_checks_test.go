@@ -0,0 +1,86 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+func TestCheckShifts(t *testing.T) {
+	start := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return start.Add(time.Duration(h) * time.Hour) }
+	shift := func(user string, from, to int) finalShift {
+		return finalShift{UserUsername: user, ShiftStart: at(from), ShiftEnd: at(to)}
+	}
+
+	t.Run("full coverage", func(t *testing.T) {
+		findings, coverage := checkShifts([]finalShift{
+			shift("alice", -12, 12),
+			shift("bob", 12, 36),
+			shift("alice", 36, 60),
+		}, start, at(48), 24*time.Hour)
+		assert.Empty(t, findings)
+		assert.Equal(t, 100.0, coverage)
+	})
+
+	t.Run("findings", func(t *testing.T) {
+		findings, coverage := checkShifts([]finalShift{
+			shift("alice", 0, 10),
+			shift("bob", 8, 12),
+			shift("bob", 12, 20),
+			shift("carol", 24, 40),
+			shift("carol", 40, 60),
+		}, start, at(60), 24*time.Hour)
+		require.Len(t, findings, 3)
+
+		assert.Equal(t, findingOverlap, findings[0].Kind)
+		assert.Equal(t, at(8), findings[0].Start)
+		assert.Equal(t, at(10), findings[0].End)
+		assert.Equal(t, []string{"alice", "bob"}, findings[0].Users)
+
+		assert.Equal(t, findingGap, findings[1].Kind)
+		assert.Equal(t, at(20), findings[1].Start)
+		assert.Equal(t, 4.0, findings[1].DurationHours)
+
+		// Back to back shifts of the same person form a single stretch.
+		assert.Equal(t, findingSinglePersonStretch, findings[2].Kind)
+		assert.Equal(t, at(24), findings[2].Start)
+		assert.Equal(t, at(60), findings[2].End)
+		assert.Equal(t, []string{"carol"}, findings[2].Users)
+
+		assert.InDelta(t, 56.0/60*100, coverage, 0.01)
+	})
+
+	t.Run("no shifts", func(t *testing.T) {
+		findings, coverage := checkShifts(nil, start, at(24), 24*time.Hour)
+		require.Len(t, findings, 1)
+		assert.Equal(t, findingGap, findings[0].Kind)
+		assert.Zero(t, coverage)
+	})
+}
+
+func TestDescribeFindings(t *testing.T) {
+	start := time.Date(2025, 1, 6, 20, 0, 0, 0, time.UTC)
+	findings := []scheduleFinding{
+		{Kind: findingGap, Start: start, End: start.Add(90 * time.Minute)},
+		{Kind: findingSinglePersonStretch, Start: start, End: start.Add(36 * time.Hour), Users: []string{"carol"}},
+	}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	de, err := mcpgrafana.ParseLocale("de")
+	require.NoError(t, err)
+
+	describeFindings(findings, de, berlin)
+	assert.Equal(t, "Nobody is on call from 06.01.2025 21:00 CET for 1,5 h.", findings[0].Message)
+	assert.Equal(t, "carol is the only person on call from 06.01.2025 21:00 CET for 36 h without a break.", findings[1].Message)
+
+	describeFindings(findings, mcpgrafana.Locale{}, time.UTC)
+	assert.Equal(t, "Nobody is on call from 2025-01-06 20:00 UTC for 1.5 h.", findings[0].Message)
+}
@@ -0,0 +1,50 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListCurrentOnCallUsers(t *testing.T) {
+	var server *httptest.Server
+	server = newFakeGrafana(t, fakeRoutes{
+		"/api/plugins/grafana-irm-app/settings": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"jsonData": {"onCallApiUrl": %q}}`, server.URL)
+		},
+		"/api/v1/schedules/": jsonBody(`{"count": 3, "next": null, "previous": null, "results": [
+			{"id": "S1", "name": "Primary", "on_call_now": ["U1"]},
+			{"id": "S2", "name": "Secondary", "on_call_now": []},
+			{"id": "S3", "name": "Broken", "on_call_now": []}
+		]}`),
+		"/api/v1/schedules/S1/": jsonBody(`{"id": "S1", "name": "Primary", "on_call_now": ["U1"]}`),
+		"/api/v1/schedules/S2/": jsonBody(`{"id": "S2", "name": "Secondary", "on_call_now": []}`),
+		"/api/v1/users/U1/":     jsonBody(`{"id": "U1", "username": "alice"}`),
+	})
+	ctx := fakeGrafanaContext(server)
+
+	result, err := listCurrentOnCallUsers(ctx, ListCurrentOnCallUsersParams{})
+	require.NoError(t, err)
+	require.Len(t, result.Schedules, 2)
+	assert.Equal(t, "Primary", result.Schedules[0].ScheduleName)
+	require.Len(t, result.Schedules[0].Users, 1)
+	assert.Equal(t, "alice", result.Schedules[0].Users[0].Username)
+	assert.Empty(t, result.Schedules[1].Users)
+	require.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0], "S3")
+
+	result, err = listCurrentOnCallUsers(ctx, ListCurrentOnCallUsersParams{ScheduleIDs: []string{"S2"}})
+	require.NoError(t, err)
+	require.Len(t, result.Schedules, 1)
+	assert.Equal(t, "S2", result.Schedules[0].ScheduleID)
+
+	_, err = listCurrentOnCallUsers(ctx, ListCurrentOnCallUsersParams{ScheduleIDs: []string{"S3"}})
+	assert.ErrorContains(t, err, "all schedules failed")
+}
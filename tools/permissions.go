@@ -0,0 +1,165 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// permissionLevels are the permissions which can be granted on dashboards
+// and folders, by name.
+var permissionLevels = map[string]models.PermissionType{
+	"View":  1,
+	"Edit":  2,
+	"Admin": 4,
+}
+
+// permissionName returns the name of a permission level.
+func permissionName(p models.PermissionType) string {
+	for name, level := range permissionLevels {
+		if level == p {
+			return name
+		}
+	}
+	return fmt.Sprintf("%d", p)
+}
+
+// Permission is a permission granted on a dashboard or folder to a role, a
+// team or a user.
+type Permission struct {
+	Permission string `json:"permission"`
+	Role       string `json:"role,omitempty"`
+	TeamID     int64  `json:"teamId,omitempty"`
+	Team       string `json:"team,omitempty"`
+	UserID     int64  `json:"userId,omitempty"`
+	UserLogin  string `json:"userLogin,omitempty"`
+	// Inherited is true for the permissions of a dashboard which are granted
+	// on its folder. They can only be changed on the folder.
+	Inherited bool `json:"inherited,omitempty"`
+}
+
+type ListPermissionsParams struct {
+	DashboardUID string `json:"dashboardUid,omitempty" jsonschema:"description=The UID of the dashboard whose permissions to list"`
+	FolderUID    string `json:"folderUid,omitempty" jsonschema:"description=The UID of the folder whose permissions to list"`
+}
+
+func newPermissions(dtos []*models.DashboardACLInfoDTO) []Permission {
+	permissions := make([]Permission, 0, len(dtos))
+	for _, d := range dtos {
+		permissions = append(permissions, Permission{
+			Permission: permissionName(d.Permission),
+			Role:       d.Role,
+			TeamID:     d.TeamID,
+			Team:       d.Team,
+			UserID:     d.UserID,
+			UserLogin:  d.UserLogin,
+			Inherited:  d.Inherited,
+		})
+	}
+	return permissions
+}
+
+func listPermissions(ctx context.Context, args ListPermissionsParams) ([]Permission, error) {
+	if (args.DashboardUID == "") == (args.FolderUID == "") {
+		return nil, fmt.Errorf("exactly one of dashboardUid and folderUid must be given")
+	}
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	if args.DashboardUID != "" {
+		resp, err := c.DashboardPermissions.GetDashboardPermissionsListByUID(args.DashboardUID)
+		if err != nil {
+			return nil, fmt.Errorf("get permissions of dashboard %s: %w", args.DashboardUID, classifyAPIError(err))
+		}
+		return newPermissions(resp.Payload), nil
+	}
+	resp, err := c.FolderPermissions.GetFolderPermissionList(args.FolderUID)
+	if err != nil {
+		return nil, fmt.Errorf("get permissions of folder %s: %w", args.FolderUID, classifyAPIError(err))
+	}
+	return newPermissions(resp.Payload), nil
+}
+
+var ListPermissions = mcpgrafana.MustTool(
+	"grafana_list_permissions",
+	"List who can view, edit or administer a dashboard or folder: the permissions granted to roles (Viewer, Editor, Admin), teams and users. The permissions of a dashboard include those inherited from its folder, marked `inherited`. Use it to audit access, e.g. \"who can edit the production dashboard\". Requires admin permissions on the dashboard or folder.",
+	listPermissions,
+	mcp.WithTitleAnnotation("List permissions"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// PermissionItem is a permission to grant on a dashboard or folder to
+// exactly one of a role, a team or a user.
+type PermissionItem struct {
+	Permission string `json:"permission" jsonschema:"required,enum=View,enum=Edit,enum=Admin,description=The permission to grant"`
+	Role       string `json:"role,omitempty" jsonschema:"enum=Viewer,enum=Editor,description=The role to grant the permission to"`
+	TeamID     int64  `json:"teamId,omitempty" jsonschema:"description=The ID of the team to grant the permission to"`
+	UserID     int64  `json:"userId,omitempty" jsonschema:"description=The ID of the user to grant the permission to"`
+}
+
+type SetPermissionsParams struct {
+	DashboardUID string           `json:"dashboardUid,omitempty" jsonschema:"description=The UID of the dashboard whose permissions to set"`
+	FolderUID    string           `json:"folderUid,omitempty" jsonschema:"description=The UID of the folder whose permissions to set"`
+	Permissions  []PermissionItem `json:"permissions" jsonschema:"required,description=The complete list of permissions of the dashboard or folder. Permissions which are not listed are removed"`
+}
+
+// aclItems converts permissions to the items of an update of the
+// permissions of a dashboard or folder.
+func aclItems(permissions []PermissionItem) ([]*models.DashboardACLUpdateItem, error) {
+	items := make([]*models.DashboardACLUpdateItem, 0, len(permissions))
+	for i, p := range permissions {
+		level, ok := permissionLevels[p.Permission]
+		if !ok {
+			return nil, fmt.Errorf("permission %d: invalid permission %q: must be View, Edit or Admin", i, p.Permission)
+		}
+		subjects := 0
+		for _, set := range []bool{p.Role != "", p.TeamID != 0, p.UserID != 0} {
+			if set {
+				subjects++
+			}
+		}
+		if subjects != 1 {
+			return nil, fmt.Errorf("permission %d: exactly one of role, teamId and userId must be given", i)
+		}
+		if p.Role != "" && p.Role != "Viewer" && p.Role != "Editor" {
+			// Admins always have admin permissions.
+			return nil, fmt.Errorf("permission %d: invalid role %q: must be Viewer or Editor", i, p.Role)
+		}
+		items = append(items, &models.DashboardACLUpdateItem{Permission: level, Role: p.Role, TeamID: p.TeamID, UserID: p.UserID})
+	}
+	return items, nil
+}
+
+func setPermissions(ctx context.Context, args SetPermissionsParams) ([]Permission, error) {
+	if (args.DashboardUID == "") == (args.FolderUID == "") {
+		return nil, fmt.Errorf("exactly one of dashboardUid and folderUid must be given")
+	}
+	items, err := aclItems(args.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	body := &models.UpdateDashboardACLCommand{Items: items}
+	if args.DashboardUID != "" {
+		if _, err := c.DashboardPermissions.UpdateDashboardPermissionsByUID(args.DashboardUID, body); err != nil {
+			return nil, fmt.Errorf("set permissions of dashboard %s: %w", args.DashboardUID, classifyAPIError(err))
+		}
+	} else {
+		if _, err := c.FolderPermissions.UpdateFolderPermissions(args.FolderUID, body); err != nil {
+			return nil, fmt.Errorf("set permissions of folder %s: %w", args.FolderUID, classifyAPIError(err))
+		}
+	}
+	return listPermissions(ctx, ListPermissionsParams{DashboardUID: args.DashboardUID, FolderUID: args.FolderUID})
+}
+
+var SetPermissions = mcpgrafana.MustTool(
+	"grafana_set_permissions",
+	"Replace the permissions of a dashboard or folder with the given list of View, Edit or Admin permissions granted to roles (Viewer or Editor), teams and users. Permissions which are not in the list are removed, so list the current permissions with `grafana_list_permissions` first and include those to keep; inherited permissions of dashboards can only be changed on their folder. Returns the permissions after the change. Only use it after confirmation from the user, as it can lock people out.",
+	setPermissions,
+	mcp.WithTitleAnnotation("Set permissions"),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithIdempotentHintAnnotation(true),
+)
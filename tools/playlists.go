@@ -0,0 +1,256 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+
+	"github.com/grafana/grafana-openapi-client-go/client/playlists"
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	// DefaultPlaylistsLimit is the default maximum number of playlists
+	// listed.
+	DefaultPlaylistsLimit = 50
+
+	// defaultPlaylistInterval is the time each dashboard of a playlist is
+	// shown for, if none is given.
+	defaultPlaylistInterval = "5m"
+
+	// playlistWorkers is the number of playlists or dashboards fetched
+	// concurrently.
+	playlistWorkers = 5
+
+	playlistItemDashboardByUID = "dashboard_by_uid"
+	playlistItemDashboardByTag = "dashboard_by_tag"
+)
+
+// PlaylistItem is a dashboard of a playlist, or a tag whose dashboards are
+// all in the playlist.
+type PlaylistItem struct {
+	Type  string `json:"type" jsonschema:"required,enum=dashboard_by_uid,enum=dashboard_by_tag,description=Whether the item is a dashboard or all the dashboards with a tag"`
+	Value string `json:"value" jsonschema:"required,description=The UID of the dashboard or the tag"`
+	// Title is the title of the dashboard, filled in from Grafana.
+	Title string `json:"title,omitempty" jsonschema:"description=Ignored: the title of dashboards is filled in from Grafana"`
+}
+
+// Playlist is a playlist of dashboards, shown one after the other, e.g. on
+// a NOC screen.
+type Playlist struct {
+	UID      string         `json:"uid"`
+	Name     string         `json:"name"`
+	Interval string         `json:"interval"`
+	URL      string         `json:"url,omitempty"`
+	Items    []PlaylistItem `json:"items"`
+	// Error is set if the items of the playlist could not be fetched.
+	Error string `json:"error,omitempty"`
+}
+
+// playlistURL returns the URL which starts a playlist.
+func playlistURL(ctx context.Context, uid string) string {
+	base, _ := deeplinkBase(ctx)
+	return fmt.Sprintf("%s/playlists/play/%s", base, uid)
+}
+
+// getPlaylist returns a playlist with its items.
+func getPlaylist(ctx context.Context, uid string) (*Playlist, error) {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.Playlists.GetPlaylist(uid)
+	if err != nil {
+		return nil, fmt.Errorf("get playlist %s: %w", uid, classifyAPIError(err))
+	}
+	p := &Playlist{UID: resp.Payload.UID, Name: resp.Payload.Name, Interval: resp.Payload.Interval, URL: playlistURL(ctx, resp.Payload.UID), Items: []PlaylistItem{}}
+	items, err := c.Playlists.GetPlaylistItems(uid)
+	if err != nil {
+		return p, fmt.Errorf("get items of playlist %s: %w", uid, classifyAPIError(err))
+	}
+	for _, item := range items.Payload {
+		p.Items = append(p.Items, PlaylistItem{Type: item.Type, Value: item.Value, Title: item.Title})
+	}
+	return p, nil
+}
+
+type ListPlaylistsParams struct {
+	Query string `json:"query,omitempty" jsonschema:"description=Optionally\\, only list playlists whose name contains this text"`
+	Limit int    `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of playlists to list (default 50)"`
+}
+
+func listPlaylists(ctx context.Context, args ListPlaylistsParams) ([]Playlist, error) {
+	limit := int64(cmp.Or(args.Limit, DefaultPlaylistsLimit))
+	params := playlists.NewSearchPlaylistsParamsWithContext(ctx).WithLimit(&limit)
+	if args.Query != "" {
+		params.SetQuery(&args.Query)
+	}
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.Playlists.SearchPlaylists(params)
+	if err != nil {
+		return nil, fmt.Errorf("search playlists: %w", classifyAPIError(err))
+	}
+	uids := make([]string, 0, len(resp.Payload))
+	for _, p := range resp.Payload {
+		uids = append(uids, p.UID)
+	}
+	// Playlists whose items can't be fetched are returned with their error.
+	results := fanOut(ctx, "playlists", uids, playlistWorkers, func(ctx context.Context, uid string) (Playlist, error) {
+		p, err := getPlaylist(ctx, uid)
+		if err != nil {
+			if p == nil {
+				p = &Playlist{UID: uid, Items: []PlaylistItem{}}
+			}
+			p.Error = err.Error()
+			return *p, err
+		}
+		return *p, nil
+	})
+	list := fanOutValues(results)
+	for i, p := range resp.Payload {
+		list[i].Name = cmp.Or(list[i].Name, p.Name)
+		list[i].Interval = cmp.Or(list[i].Interval, p.Interval)
+	}
+	return list, nil
+}
+
+var ListPlaylists = mcpgrafana.MustTool(
+	"grafana_list_playlists",
+	"List the playlists of dashboards, e.g. those shown on NOC screens, with their interval, the URL to start them and their items: dashboards by UID, or all the dashboards with a tag.",
+	listPlaylists,
+	mcp.WithTitleAnnotation("List playlists"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// playlistItems validates the items of a playlist and converts them for
+// the API. The dashboards must exist, and their titles are filled in.
+func playlistItems(ctx context.Context, items []PlaylistItem) ([]*models.PlaylistItem, error) {
+	for i, item := range items {
+		if item.Type != playlistItemDashboardByUID && item.Type != playlistItemDashboardByTag {
+			return nil, fmt.Errorf("item %d: invalid type %q: must be %s or %s", i, item.Type, playlistItemDashboardByUID, playlistItemDashboardByTag)
+		}
+		if item.Value == "" {
+			return nil, fmt.Errorf("item %d: a value is required", i)
+		}
+	}
+	results := fanOut(ctx, "dashboards", items, playlistWorkers, func(ctx context.Context, item PlaylistItem) (*models.PlaylistItem, error) {
+		if item.Type == playlistItemDashboardByTag {
+			return &models.PlaylistItem{Type: item.Type, Value: item.Value, Title: item.Value}, nil
+		}
+		dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: item.Value})
+		if err != nil {
+			return nil, fmt.Errorf("dashboard %s: %w", item.Value, classifyAPIError(err))
+		}
+		db, _ := dashboard.Dashboard.(map[string]any)
+		title, _ := db["title"].(string)
+		return &models.PlaylistItem{Type: item.Type, Value: item.Value, Title: cmp.Or(title, item.Value)}, nil
+	})
+	apiItems := make([]*models.PlaylistItem, len(results))
+	for i, r := range results {
+		if r.err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, r.err)
+		}
+		r.value.Order = int64(i + 1)
+		apiItems[i] = r.value
+	}
+	return apiItems, nil
+}
+
+// validatePlaylistInterval checks that an interval is a duration, such as
+// 5m.
+func validatePlaylistInterval(interval string) error {
+	if d, ok := parseAnyDuration(interval); !ok || d <= 0 {
+		return fmt.Errorf("invalid interval %q: must be a duration such as 30s or 5m", interval)
+	}
+	return nil
+}
+
+type CreatePlaylistParams struct {
+	Name     string         `json:"name" jsonschema:"required,description=The name of the playlist"`
+	Interval string         `json:"interval,omitempty" jsonschema:"description=Optionally\\, how long each dashboard is shown\\, e.g. 30s or 5m (default 5m)"`
+	Items    []PlaylistItem `json:"items" jsonschema:"required,description=The dashboards of the playlist\\, in the order they are shown"`
+}
+
+func createPlaylist(ctx context.Context, args CreatePlaylistParams) (*Playlist, error) {
+	if args.Name == "" {
+		return nil, fmt.Errorf("a name is required")
+	}
+	if len(args.Items) == 0 {
+		return nil, fmt.Errorf("at least one item is required")
+	}
+	interval := cmp.Or(args.Interval, defaultPlaylistInterval)
+	if err := validatePlaylistInterval(interval); err != nil {
+		return nil, err
+	}
+	items, err := playlistItems(ctx, args.Items)
+	if err != nil {
+		return nil, err
+	}
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.Playlists.CreatePlaylist(&models.CreatePlaylistCommand{Name: args.Name, Interval: interval, Items: items})
+	if err != nil {
+		return nil, fmt.Errorf("create playlist: %w", classifyAPIError(err))
+	}
+	return getPlaylist(ctx, resp.Payload.UID)
+}
+
+var CreatePlaylist = mcpgrafana.MustTool(
+	"grafana_create_playlist",
+	"Create a playlist of dashboards shown one after the other, e.g. to put the dashboards relevant to an incident on a NOC screen. Items are dashboards by UID, which must exist, or all the dashboards with a tag. Returns the playlist with the URL to start it.",
+	createPlaylist,
+	mcp.WithTitleAnnotation("Create playlist"),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithIdempotentHintAnnotation(false),
+)
+
+type UpdatePlaylistParams struct {
+	UID      string         `json:"uid" jsonschema:"required,description=The UID of the playlist to update"`
+	Name     string         `json:"name,omitempty" jsonschema:"description=Optionally\\, the new name of the playlist"`
+	Interval string         `json:"interval,omitempty" jsonschema:"description=Optionally\\, how long each dashboard is shown\\, e.g. 30s or 5m"`
+	Items    []PlaylistItem `json:"items,omitempty" jsonschema:"description=Optionally\\, the new items of the playlist\\, replacing all of its items. List the current items with grafana_list_playlists to keep some of them"`
+}
+
+func updatePlaylist(ctx context.Context, args UpdatePlaylistParams) (*Playlist, error) {
+	if args.Interval != "" {
+		if err := validatePlaylistInterval(args.Interval); err != nil {
+			return nil, err
+		}
+	}
+	current, err := getPlaylist(ctx, args.UID)
+	if err != nil {
+		return nil, err
+	}
+	// The API replaces the whole playlist, so the current items are sent
+	// again unless new ones are given.
+	var apiItems []*models.PlaylistItem
+	if len(args.Items) > 0 {
+		if apiItems, err = playlistItems(ctx, args.Items); err != nil {
+			return nil, err
+		}
+	} else {
+		for i, item := range current.Items {
+			apiItems = append(apiItems, &models.PlaylistItem{Type: item.Type, Value: item.Value, Title: item.Title, Order: int64(i + 1)})
+		}
+	}
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	body := &models.UpdatePlaylistCommand{
+		UID:      args.UID,
+		Name:     cmp.Or(args.Name, current.Name),
+		Interval: cmp.Or(args.Interval, current.Interval),
+		Items:    apiItems,
+	}
+	if _, err := c.Playlists.UpdatePlaylist(args.UID, body); err != nil {
+		return nil, fmt.Errorf("update playlist %s: %w", args.UID, classifyAPIError(err))
+	}
+	return getPlaylist(ctx, args.UID)
+}
+
+var UpdatePlaylist = mcpgrafana.MustTool(
+	"grafana_update_playlist",
+	"Update a playlist of dashboards: rename it, change how long each dashboard is shown, or replace its items, e.g. to add the dashboards relevant to an ongoing incident. Fields which are not given are kept. Returns the playlist after the update.",
+	updatePlaylist,
+	mcp.WithTitleAnnotation("Update playlist"),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithIdempotentHintAnnotation(true),
+)
@@ -0,0 +1,116 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlaylists(t *testing.T) {
+	type playlist struct {
+		UID      string           `json:"uid"`
+		Name     string           `json:"name"`
+		Interval string           `json:"interval"`
+		Items    []map[string]any `json:"items"`
+	}
+	playlists := map[string]*playlist{
+		"noc": {UID: "noc", Name: "NOC", Interval: "1m", Items: []map[string]any{{"type": "dashboard_by_tag", "value": "noc", "title": "noc", "order": 1}}},
+	}
+	var searches []string
+	routes := fakeRoutes{
+		"/api/dashboards/uid/missing": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "Dashboard not found"}`))
+		},
+		"/api/dashboards/uid/api": jsonBody(`{"dashboard": {"uid": "api", "title": "API"}, "meta": {}}`),
+		"GET /api/playlists": func(w http.ResponseWriter, r *http.Request) {
+			searches = append(searches, r.URL.RawQuery)
+			list := []*playlist{}
+			for _, p := range playlists {
+				list = append(list, &playlist{UID: p.UID, Name: p.Name, Interval: p.Interval})
+			}
+			_ = json.NewEncoder(w).Encode(list)
+		},
+		"POST /api/playlists": func(w http.ResponseWriter, r *http.Request) {
+			var p playlist
+			if !decodeJSONBody(t, w, r, &p) {
+				return
+			}
+			p.UID = "created"
+			playlists[p.UID] = &p
+			_ = json.NewEncoder(w).Encode(p)
+		},
+	}
+	for _, uid := range []string{"noc", "created"} {
+		routes["GET /api/playlists/"+uid] = func(w http.ResponseWriter, r *http.Request) {
+			p := playlists[uid]
+			_ = json.NewEncoder(w).Encode(&playlist{UID: p.UID, Name: p.Name, Interval: p.Interval})
+		}
+		routes["GET /api/playlists/"+uid+"/items"] = func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(playlists[uid].Items)
+		}
+		routes["PUT /api/playlists/"+uid] = func(w http.ResponseWriter, r *http.Request) {
+			var p playlist
+			if !decodeJSONBody(t, w, r, &p) {
+				return
+			}
+			playlists[p.UID] = &p
+			_ = json.NewEncoder(w).Encode(p)
+		}
+	}
+	server := newFakeGrafana(t, routes)
+	ctx := fakeGrafanaContext(server)
+
+	t.Run("list", func(t *testing.T) {
+		list, err := listPlaylists(ctx, ListPlaylistsParams{Query: "NOC"})
+		require.NoError(t, err)
+		assert.Equal(t, []Playlist{{
+			UID: "noc", Name: "NOC", Interval: "1m", URL: server.URL + "/playlists/play/noc",
+			Items: []PlaylistItem{{Type: "dashboard_by_tag", Value: "noc", Title: "noc"}},
+		}}, list)
+		assert.Equal(t, []string{"limit=50&query=NOC"}, searches)
+	})
+
+	t.Run("create", func(t *testing.T) {
+		p, err := createPlaylist(ctx, CreatePlaylistParams{Name: "Incident", Items: []PlaylistItem{
+			{Type: "dashboard_by_uid", Value: "api"},
+			{Type: "dashboard_by_tag", Value: "payments"},
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, "created", p.UID)
+		assert.Equal(t, "5m", p.Interval)
+		assert.Equal(t, []PlaylistItem{{Type: "dashboard_by_uid", Value: "api", Title: "API"}, {Type: "dashboard_by_tag", Value: "payments", Title: "payments"}}, p.Items)
+		assert.Equal(t, float64(2), playlists["created"].Items[1]["order"])
+
+		_, err = createPlaylist(ctx, CreatePlaylistParams{Name: "Incident", Items: []PlaylistItem{{Type: "dashboard_by_uid", Value: "missing"}}})
+		assert.ErrorContains(t, err, "item 0: dashboard missing")
+		assert.ErrorIs(t, err, errNotFound)
+		_, err = createPlaylist(ctx, CreatePlaylistParams{Name: "Incident", Interval: "often", Items: []PlaylistItem{{Type: "dashboard_by_tag", Value: "noc"}}})
+		assert.ErrorContains(t, err, "invalid interval")
+		_, err = createPlaylist(ctx, CreatePlaylistParams{Name: "Incident", Items: []PlaylistItem{{Type: "dashboard_by_id", Value: "1"}}})
+		assert.ErrorContains(t, err, "invalid type")
+	})
+
+	t.Run("update", func(t *testing.T) {
+		// The items are kept when only the interval changes.
+		p, err := updatePlaylist(ctx, UpdatePlaylistParams{UID: "noc", Interval: "30s"})
+		require.NoError(t, err)
+		assert.Equal(t, "NOC", p.Name)
+		assert.Equal(t, "30s", p.Interval)
+		assert.Equal(t, []PlaylistItem{{Type: "dashboard_by_tag", Value: "noc", Title: "noc"}}, p.Items)
+
+		p, err = updatePlaylist(ctx, UpdatePlaylistParams{UID: "noc", Items: []PlaylistItem{{Type: "dashboard_by_uid", Value: "api"}}})
+		require.NoError(t, err)
+		assert.Equal(t, "30s", p.Interval)
+		assert.Equal(t, []PlaylistItem{{Type: "dashboard_by_uid", Value: "api", Title: "API"}}, p.Items)
+
+		_, err = updatePlaylist(ctx, UpdatePlaylistParams{UID: "missing", Name: "x"})
+		assert.ErrorIs(t, err, errNotFound)
+	})
+}
@@ -2,9 +2,13 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -29,6 +33,26 @@ var (
 	}
 )
 
+// PrometheusWarningsMetaField is the field of the _meta of tool results
+// holding the warnings returned by Prometheus for their requests.
+const PrometheusWarningsMetaField = "prometheusWarnings"
+
+// recordPrometheusWarnings reports the warnings returned by Prometheus for a
+// request, e.g. about partial results or too many samples, in the result of
+// the current tool call, both in its _meta and as a note for the model.
+func recordPrometheusWarnings(ctx context.Context, warnings promv1.Warnings) {
+	for _, w := range warnings {
+		mcpgrafana.UpdateResultMeta(ctx, PrometheusWarningsMetaField, func(old any) any {
+			all, _ := old.([]string)
+			if slices.Contains(all, w) {
+				return all
+			}
+			return append(all, w)
+		})
+		mcpgrafana.SetResultNote(ctx, PrometheusWarningsMetaField+":"+w, "Prometheus warning, the result may be incomplete: "+w)
+	}
+}
+
 func promClientFromContext(ctx context.Context, uid string) (promv1.API, error) {
 	// First check if the datasource exists
 	_, err := getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: uid})
@@ -65,9 +89,18 @@ func promClientFromContext(ctx context.Context, uid string) (promv1.API, error)
 			"Bearer", config.NewInlineSecret(cfg.APIKey), rt,
 		)
 	}
+	if cfg.OrgID != 0 {
+		rt = config.NewHeadersRoundTripper(&config.Headers{
+			Headers: map[string]config.Header{
+				mcpgrafana.OrgIDHeader: {
+					Values: []string{strconv.FormatInt(cfg.OrgID, 10)},
+				},
+			},
+		}, rt)
+	}
 	c, err := api.NewClient(api.Config{
 		Address:      url,
-		RoundTripper: rt,
+		RoundTripper: cfg.Retry.RoundTripper(mcpgrafana.WithExtraHeaders(rt, cfg.ExtraHeaders)),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("creating Prometheus client: %w", err)
@@ -111,12 +144,19 @@ var ListPrometheusMetricMetadata = mcpgrafana.MustTool(
 )
 
 type QueryPrometheusParams struct {
-	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
-	Expr          string `json:"expr" jsonschema:"required,description=The PromQL expression to query"`
-	StartTime     string `json:"startTime" jsonschema:"required,description=The start time. Supported formats are RFC3339 or relative to now (e.g. 'now'\\, 'now-1.5h'\\, 'now-2h45m'). Valid time units are 'ns'\\, 'us' (or 'µs')\\, 'ms'\\, 's'\\, 'm'\\, 'h'\\, 'd'."`
-	EndTime       string `json:"endTime,omitempty" jsonschema:"description=The end time. Required if queryType is 'range'\\, ignored if queryType is 'instant' Supported formats are RFC3339 or relative to now (e.g. 'now'\\, 'now-1.5h'\\, 'now-2h45m'). Valid time units are 'ns'\\, 'us' (or 'µs')\\, 'ms'\\, 's'\\, 'm'\\, 'h'\\, 'd'."`
-	StepSeconds   int    `json:"stepSeconds,omitempty" jsonschema:"description=The time series step size in seconds. Required if queryType is 'range'\\, ignored if queryType is 'instant'"`
-	QueryType     string `json:"queryType,omitempty" jsonschema:"description=The type of query to use. Either 'range' or 'instant'"`
+	DatasourceUID string   `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	Expr          string   `json:"expr" jsonschema:"required,description=The PromQL expression to query"`
+	StartTime     string   `json:"startTime" jsonschema:"required,description=The start time. Supported formats are RFC3339 or relative to now (e.g. 'now'\\, 'now-1.5h'\\, 'now-2h45m'). Valid time units are 'ns'\\, 'us' (or 'µs')\\, 'ms'\\, 's'\\, 'm'\\, 'h'\\, 'd'."`
+	EndTime       string   `json:"endTime,omitempty" jsonschema:"description=The end time. Required if queryType is 'range'\\, ignored if queryType is 'instant' Supported formats are RFC3339 or relative to now (e.g. 'now'\\, 'now-1.5h'\\, 'now-2h45m'). Valid time units are 'ns'\\, 'us' (or 'µs')\\, 'ms'\\, 's'\\, 'm'\\, 'h'\\, 'd'."`
+	StepSeconds   int      `json:"stepSeconds,omitempty" jsonschema:"description=The time series step size in seconds. Required if queryType is 'range'\\, ignored if queryType is 'instant'"`
+	QueryType     string   `json:"queryType,omitempty" jsonschema:"description=The type of query to use. Either 'range' or 'instant'"`
+	OutputFormat  string   `json:"outputFormat,omitempty" jsonschema:"description=The format of the result: 'json' (default)\\, 'csv' or 'markdown-table'. Tables have one row per sample with a column per label\\, which is much more compact than JSON and convenient for further analysis"`
+	Format        string   `json:"format,omitempty" jsonschema:"description=Deprecated: use outputFormat. The format of the result: 'json' (default) or 'csv'"`
+	MaxSeries     int      `json:"maxSeries,omitempty" jsonschema:"description=Optionally\\, the maximum number of series to return. Series are sorted by their labels and further series are omitted with a note saying how many. Use it to keep queries which may match many series from flooding the result"`
+	DropLabels    []string `json:"dropLabels,omitempty" jsonschema:"description=Optionally\\, labels to remove from every series in the result\\, e.g. high-churn labels such as 'pod' or 'instance' which are not needed to answer the question. Series left with the same labels are not merged; aggregate them in the query instead"`
+	MaxDataPoints int      `json:"maxDataPoints,omitempty" jsonschema:"description=Optionally\\, the maximum number of samples per series of a range query. Series with more samples are downsampled by aggregating consecutive samples into buckets\\, like Grafana panels do"`
+	Downsample    string   `json:"downsample,omitempty" jsonschema:"description=Optionally\\, how samples are aggregated when downsampling: 'avg' (default)\\, 'min' or 'max'. Use 'max' to keep spikes and 'min' to keep dips"`
+	Summarize     bool     `json:"summarize,omitempty" jsonschema:"description=Optionally\\, return summary statistics of each series of a range query (count\\, min\\, max\\, avg\\, first and last values) instead of its samples"`
 }
 
 func parseTime(timeStr string) (time.Time, error) {
@@ -128,35 +168,43 @@ func parseTime(timeStr string) (time.Time, error) {
 }
 
 func queryPrometheus(ctx context.Context, args QueryPrometheusParams) (model.Value, error) {
-	promClient, err := promClientFromContext(ctx, args.DatasourceUID)
-	if err != nil {
-		return nil, fmt.Errorf("getting Prometheus client: %w", err)
-	}
-
 	queryType := args.QueryType
 	if queryType == "" {
 		queryType = "range"
 	}
+	if queryType != "range" && queryType != "instant" {
+		return nil, fmt.Errorf("invalid query type: %s", queryType)
+	}
 
-	var startTime time.Time
-	startTime, err = parseTime(args.StartTime)
+	startTime, err := parseTime(args.StartTime)
 	if err != nil {
 		return nil, fmt.Errorf("parsing start time: %w", err)
 	}
 
+	var endTime time.Time
+	var step time.Duration
 	if queryType == "range" {
 		if args.StepSeconds == 0 {
 			return nil, fmt.Errorf("stepSeconds must be provided when queryType is 'range'")
 		}
-
-		var endTime time.Time
 		endTime, err = parseTime(args.EndTime)
 		if err != nil {
 			return nil, fmt.Errorf("parsing end time: %w", err)
 		}
+		step = time.Duration(args.StepSeconds) * time.Second
+	}
 
-		step := time.Duration(args.StepSeconds) * time.Second
-		result, _, err := promClient.QueryRange(ctx, args.Expr, promv1.Range{
+	if usesDSQuery(ctx, "prometheus") {
+		return queryPrometheusViaDSQuery(ctx, args.DatasourceUID, args.Expr, queryType, startTime, endTime, step)
+	}
+
+	promClient, err := promClientFromContext(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+
+	if queryType == "range" {
+		result, warnings, err := promClient.QueryRange(ctx, args.Expr, promv1.Range{
 			Start: startTime,
 			End:   endTime,
 			Step:  step,
@@ -164,22 +212,159 @@ func queryPrometheus(ctx context.Context, args QueryPrometheusParams) (model.Val
 		if err != nil {
 			return nil, fmt.Errorf("querying Prometheus range: %w", err)
 		}
+		recordPrometheusWarnings(ctx, warnings)
 		return result, nil
-	} else if queryType == "instant" {
-		result, _, err := promClient.Query(ctx, args.Expr, startTime)
+	}
+
+	result, warnings, err := promClient.Query(ctx, args.Expr, startTime)
+	if err != nil {
+		return nil, fmt.Errorf("querying Prometheus instant: %w", err)
+	}
+	recordPrometheusWarnings(ctx, warnings)
+	return result, nil
+}
+
+// trimPrometheusResult removes the given labels from the series of a query
+// result, sorts them by their labels and keeps the first maxSeries of them,
+// if maxSeries is positive. It returns the number of series omitted. Series
+// left with identical labels are not merged, as their samples can't be
+// combined without an aggregation, but a note saying so is added to the
+// result.
+func trimPrometheusResult(ctx context.Context, v model.Value, dropLabels []string, maxSeries int) (model.Value, int) {
+	var metrics []model.Metric
+	switch v := v.(type) {
+	case model.Matrix:
+		for _, s := range v {
+			metrics = append(metrics, s.Metric)
+		}
+	case model.Vector:
+		for _, s := range v {
+			metrics = append(metrics, s.Metric)
+		}
+	default:
+		return v, 0
+	}
+	for _, m := range metrics {
+		for _, name := range dropLabels {
+			delete(m, model.LabelName(name))
+		}
+	}
+	if len(dropLabels) > 0 {
+		if duplicates := duplicateMetrics(metrics); duplicates > 0 {
+			mcpgrafana.SetResultNote(ctx, "prometheusDuplicateSeries", fmt.Sprintf("%d series have the same labels as another series after dropping %s; aggregate them in the query, e.g. with sum without (%s), to merge them.", duplicates, strings.Join(dropLabels, ", "), strings.Join(dropLabels, ", ")))
+		}
+	}
+
+	omitted := 0
+	switch v := v.(type) {
+	case model.Matrix:
+		sort.Stable(v)
+		if maxSeries > 0 && len(v) > maxSeries {
+			omitted = len(v) - maxSeries
+			return v[:maxSeries], omitted
+		}
+	case model.Vector:
+		sort.Stable(v)
+		if maxSeries > 0 && len(v) > maxSeries {
+			omitted = len(v) - maxSeries
+			return v[:maxSeries], omitted
+		}
+	}
+	return v, omitted
+}
+
+// duplicateMetrics returns the number of metrics equal to an earlier one.
+func duplicateMetrics(metrics []model.Metric) int {
+	seen := make(map[model.Fingerprint]bool, len(metrics))
+	duplicates := 0
+	for _, m := range metrics {
+		fp := m.Fingerprint()
+		if seen[fp] {
+			duplicates++
+		}
+		seen[fp] = true
+	}
+	return duplicates
+}
+
+// queryPrometheusWithFormat runs a Prometheus query, returning the result in
+// the requested format.
+func queryPrometheusWithFormat(ctx context.Context, args QueryPrometheusParams) (any, error) {
+	format, err := resultFormat(args.Format, args.OutputFormat)
+	if err != nil {
+		return nil, err
+	}
+	if args.MaxSeries < 0 {
+		return nil, fmt.Errorf("maxSeries must not be negative")
+	}
+	aggregation, err := validateDownsample(args)
+	if err != nil {
+		return nil, err
+	}
+	if args.Summarize && format != formatJSON {
+		return nil, fmt.Errorf("summarize is not supported with the %s format", format)
+	}
+	result, err := queryPrometheus(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	result, omitted := trimPrometheusResult(ctx, result, args.DropLabels, args.MaxSeries)
+	if matrix, ok := result.(model.Matrix); ok {
+		if args.Summarize {
+			return prometheusSummaryResult(summarizeMatrix(matrix), omitted)
+		}
+		result = downsampleMatrix(matrix, args.MaxDataPoints, aggregation)
+	} else if args.Summarize {
+		return nil, fmt.Errorf("summarize is only supported for range queries")
+	}
+
+	var text string
+	if format != formatJSON {
+		if text, err = prometheusTable(result).render(format); err != nil {
+			return nil, err
+		}
+	} else {
+		if omitted == 0 {
+			return result, nil
+		}
+		data, err := json.Marshal(result)
 		if err != nil {
-			return nil, fmt.Errorf("querying Prometheus instant: %w", err)
+			return nil, fmt.Errorf("marshalling result: %w", err)
 		}
-		return result, nil
+		text = string(data)
 	}
+	if omitted == 0 {
+		return text, nil
+	}
+	return omittedSeriesResult(text, omitted), nil
+}
+
+// omittedSeriesResult returns a result with a note saying how many series
+// were omitted from it.
+func omittedSeriesResult(text string, omitted int) *mcp.CallToolResult {
+	return &mcp.CallToolResult{Content: []mcp.Content{
+		mcp.NewTextContent(text),
+		mcp.NewTextContent(fmt.Sprintf("%d more series omitted; narrow the query or raise maxSeries to see them.", omitted)),
+	}}
+}
 
-	return nil, fmt.Errorf("invalid query type: %s", queryType)
+// prometheusSummaryResult returns the summaries of the series of a range
+// query as a result.
+func prometheusSummaryResult(summaries []PrometheusSeriesSummary, omitted int) (any, error) {
+	if omitted == 0 {
+		return summaries, nil
+	}
+	data, err := json.Marshal(summaries)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling result: %w", err)
+	}
+	return omittedSeriesResult(string(data), omitted), nil
 }
 
 var QueryPrometheus = mcpgrafana.MustTool(
 	"grafana_query_prometheus",
-	"Query Prometheus using a PromQL expression. Supports both instant queries (at a single point in time) and range queries (over a time range). Time can be specified either in RFC3339 format or as relative time expressions like 'now', 'now-1h', 'now-30m', etc.",
-	queryPrometheus,
+	"Query Prometheus using a PromQL expression. Supports both instant queries (at a single point in time) and range queries (over a time range). Time can be specified either in RFC3339 format or as relative time expressions like 'now', 'now-1h', 'now-30m', etc. Results can be returned as a compact CSV or Markdown table with `outputFormat: 'csv'` or `outputFormat: 'markdown-table'`. Use `maxSeries` to cap the number of series returned and `dropLabels` to remove high-churn labels from them. Range queries with many samples per series can be downsampled to at most `maxDataPoints` samples per series, aggregated with `downsample` ('avg', 'min' or 'max'), or reduced to summary statistics per series with `summarize: true`.",
+	queryPrometheusWithFormat,
 	mcp.WithTitleAnnotation("Query Prometheus metrics"),
 	mcp.WithIdempotentHintAnnotation(true),
 	mcp.WithReadOnlyHintAnnotation(true),
@@ -209,10 +394,11 @@ func listPrometheusMetricNames(ctx context.Context, args ListPrometheusMetricNam
 	}
 
 	// Get all metric names by querying for __name__ label values
-	labelValues, _, err := promClient.LabelValues(ctx, "__name__", nil, time.Time{}, time.Time{})
+	labelValues, warnings, err := promClient.LabelValues(ctx, "__name__", nil, time.Time{}, time.Time{})
 	if err != nil {
 		return nil, fmt.Errorf("listing Prometheus metric names: %w", err)
 	}
+	recordPrometheusWarnings(ctx, warnings)
 
 	// Filter by regex if provided
 	matches := []string{}
@@ -338,10 +524,11 @@ func listPrometheusLabelNames(ctx context.Context, args ListPrometheusLabelNames
 		matchers = append(matchers, m.String())
 	}
 
-	labelNames, _, err := promClient.LabelNames(ctx, matchers, startTime, endTime)
+	labelNames, warnings, err := promClient.LabelNames(ctx, matchers, startTime, endTime)
 	if err != nil {
 		return nil, fmt.Errorf("listing Prometheus label names: %w", err)
 	}
+	recordPrometheusWarnings(ctx, warnings)
 
 	// Apply limit
 	if len(labelNames) > limit {
@@ -397,10 +584,11 @@ func listPrometheusLabelValues(ctx context.Context, args ListPrometheusLabelValu
 		matchers = append(matchers, m.String())
 	}
 
-	labelValues, _, err := promClient.LabelValues(ctx, args.LabelName, matchers, startTime, endTime)
+	labelValues, warnings, err := promClient.LabelValues(ctx, args.LabelName, matchers, startTime, endTime)
 	if err != nil {
 		return nil, fmt.Errorf("listing Prometheus label values: %w", err)
 	}
+	recordPrometheusWarnings(ctx, warnings)
 
 	// Apply limit
 	if len(labelValues) > limit {
@@ -419,10 +607,80 @@ var ListPrometheusLabelValues = mcpgrafana.MustTool(
 	mcp.WithReadOnlyHintAnnotation(true),
 )
 
+type ListPrometheusSeriesParams struct {
+	DatasourceUID string     `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	Matches       []Selector `json:"matches" jsonschema:"required,description=The selectors of the series to list. At least one is required"`
+	StartRFC3339  string     `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start time of the time range to filter the results by"`
+	EndRFC3339    string     `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end time of the time range to filter the results by"`
+	Limit         int        `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of series to return (default 100)"`
+}
+
+func listPrometheusSeries(ctx context.Context, args ListPrometheusSeriesParams) ([]model.LabelSet, error) {
+	if len(args.Matches) == 0 {
+		return nil, fmt.Errorf("at least one selector is required")
+	}
+	promClient, err := promClientFromContext(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+
+	limit := args.Limit
+	if limit == 0 {
+		limit = 100
+	}
+
+	var startTime, endTime time.Time
+	if args.StartRFC3339 != "" {
+		if startTime, err = time.Parse(time.RFC3339, args.StartRFC3339); err != nil {
+			return nil, fmt.Errorf("parsing start time: %w", err)
+		}
+	}
+	if args.EndRFC3339 != "" {
+		if endTime, err = time.Parse(time.RFC3339, args.EndRFC3339); err != nil {
+			return nil, fmt.Errorf("parsing end time: %w", err)
+		}
+	}
+
+	var matchers []string
+	for _, m := range args.Matches {
+		matchers = append(matchers, m.String())
+	}
+
+	// The limit is also passed to Prometheus, which older versions ignore.
+	series, warnings, err := promClient.Series(ctx, matchers, startTime, endTime, promv1.WithLimit(uint64(limit)))
+	if err != nil {
+		return nil, fmt.Errorf("listing Prometheus series: %w", err)
+	}
+	recordPrometheusWarnings(ctx, warnings)
+
+	// Apply limit
+	if len(series) > limit {
+		series = series[:limit]
+	}
+
+	return series, nil
+}
+
+var ListPrometheusSeries = mcpgrafana.MustTool(
+	"grafana_list_prometheus_series",
+	"List the series matching selectors in a Prometheus datasource, with their full label sets. Use it to discover which combinations of label values actually exist, which listing label names and values separately doesn't tell. Allows filtering by time range.",
+	listPrometheusSeries,
+	mcp.WithTitleAnnotation("List Prometheus series"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
 func AddPrometheusTools(mcp *server.MCPServer) {
 	ListPrometheusMetricMetadata.Register(mcp)
 	QueryPrometheus.Register(mcp)
 	ListPrometheusMetricNames.Register(mcp)
 	ListPrometheusLabelNames.Register(mcp)
 	ListPrometheusLabelValues.Register(mcp)
+	ListPrometheusSeries.Register(mcp)
+	SummarizePrometheusLabels.Register(mcp)
+	ListPrometheusRules.Register(mcp)
+	ListPrometheusAlerts.Register(mcp)
+	ListPrometheusTargets.Register(mcp)
+	GetPrometheusRemoteWriteHealth.Register(mcp)
+	GetPrometheusTSDBStatus.Register(mcp)
 }
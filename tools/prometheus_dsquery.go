@@ -0,0 +1,129 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// queryPrometheusViaDSQuery runs a PromQL query through /api/ds/query,
+// returning the same matrix or vector as the Prometheus API would. The step
+// of range queries is passed as the minimum interval of the query, which
+// Grafana may still raise to keep the number of samples bounded.
+func queryPrometheusViaDSQuery(ctx context.Context, uid, expr, queryType string, start, end time.Time, step time.Duration) (model.Value, error) {
+	ds, err := getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: uid})
+	if err != nil {
+		return nil, err
+	}
+	client, err := newDSQueryClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("creating query client: %w", err)
+	}
+	query := map[string]any{
+		"datasource": map[string]string{"uid": ds.UID, "type": ds.Type},
+		"expr":       expr,
+		"range":      queryType == "range",
+		"instant":    queryType == "instant",
+	}
+	if queryType == "instant" {
+		end = start
+	} else {
+		query["interval"] = fmt.Sprintf("%ds", int64(step/time.Second))
+		query["intervalMs"] = step.Milliseconds()
+	}
+	frames, err := client.query(ctx, strconv.FormatInt(start.UnixMilli(), 10), strconv.FormatInt(end.UnixMilli(), 10), query)
+	if err != nil {
+		return nil, err
+	}
+	var warnings promv1.Warnings
+	for _, f := range frames {
+		for _, n := range f.Schema.Meta.Notices {
+			if n.Severity == "warning" {
+				warnings = append(warnings, n.Text)
+			}
+		}
+	}
+	recordPrometheusWarnings(ctx, warnings)
+	matrix := framesToMatrix(frames)
+	if queryType == "range" {
+		return matrix, nil
+	}
+	vector := model.Vector{}
+	for _, s := range matrix {
+		if len(s.Values) == 0 {
+			continue
+		}
+		last := s.Values[len(s.Values)-1]
+		vector = append(vector, &model.Sample{Metric: s.Metric, Value: last.Value, Timestamp: last.Timestamp})
+	}
+	return vector, nil
+}
+
+// framesToMatrix converts the frames of a Prometheus query, which have a
+// time and a value field per series, to a matrix.
+func framesToMatrix(frames []dataFrame) model.Matrix {
+	matrix := model.Matrix{}
+	for _, f := range frames {
+		timeIndex, valueIndex := -1, -1
+		for i, field := range f.Schema.Fields {
+			switch field.Type {
+			case "time":
+				timeIndex = i
+			case "number":
+				valueIndex = i
+			}
+		}
+		if timeIndex < 0 || valueIndex < 0 {
+			continue
+		}
+		metric := model.Metric{}
+		for name, value := range f.Schema.Fields[valueIndex].Labels {
+			metric[model.LabelName(name)] = model.LabelValue(value)
+		}
+		specials := frameSpecialFloats(f, valueIndex)
+		stream := &model.SampleStream{Metric: metric, Values: []model.SamplePair{}}
+		for i, row := range f.rows() {
+			ms, ok := row[timeIndex].(float64)
+			if !ok {
+				continue
+			}
+			v, ok := row[valueIndex].(float64)
+			if !ok {
+				if v, ok = specials[i]; !ok {
+					continue
+				}
+			}
+			stream.Values = append(stream.Values, model.SamplePair{
+				Timestamp: model.Time(int64(ms)),
+				Value:     model.SampleValue(v),
+			})
+		}
+		matrix = append(matrix, stream)
+	}
+	return matrix
+}
+
+// frameSpecialFloats returns the special float values of a field of a frame
+// by row, which are encoded as null in its values.
+func frameSpecialFloats(f dataFrame, field int) map[int]float64 {
+	specials := map[int]float64{}
+	if field >= len(f.Data.Entities) || f.Data.Entities[field] == nil {
+		return specials
+	}
+	e := f.Data.Entities[field]
+	for _, i := range e.NaN {
+		specials[i] = math.NaN()
+	}
+	for _, i := range e.Inf {
+		specials[i] = math.Inf(1)
+	}
+	for _, i := range e.NegInf {
+		specials[i] = math.Inf(-1)
+	}
+	return specials
+}
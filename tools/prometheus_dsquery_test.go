@@ -0,0 +1,82 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"math"
+	"net/http"
+	"testing"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryPrometheusViaDSQuery(t *testing.T) {
+	var gotQuery map[string]any
+	var gotFrom, gotTo string
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/datasources/uid/prom": jsonBody(`{"uid": "prom", "type": "prometheus"}`),
+		"/api/ds/query": func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Queries []map[string]any `json:"queries"`
+				From    string           `json:"from"`
+				To      string           `json:"to"`
+			}
+			if !decodeJSONBody(t, w, r, &body) {
+				return
+			}
+			gotQuery, gotFrom, gotTo = body.Queries[0], body.From, body.To
+			_, _ = w.Write([]byte(`{"results": {"A": {"frames": [{
+				"schema": {
+					"fields": [{"name": "Time", "type": "time"}, {"name": "Value", "type": "number", "labels": {"job": "api"}}],
+					"meta": {"notices": [{"severity": "warning", "text": "partial result"}]}
+				},
+				"data": {"values": [[1736121600000, 1736121660000], [1.5, null]], "entities": [null, {"NaN": [1]}]}
+			}]}}}`))
+		},
+	})
+	ctx := fakeGrafanaContext(server)
+	config := mcpgrafana.GrafanaConfigFromContext(ctx)
+	config.DSQuery = []string{"prometheus"}
+	ctx = mcpgrafana.WithGrafanaConfig(ctx, config)
+
+	result, err := queryPrometheus(ctx, QueryPrometheusParams{
+		DatasourceUID: "prom",
+		Expr:          "up",
+		StartTime:     "2025-01-06T00:00:00Z",
+		EndTime:       "2025-01-06T01:00:00Z",
+		StepSeconds:   60,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"refId":      "A",
+		"datasource": map[string]any{"uid": "prom", "type": "prometheus"},
+		"expr":       "up",
+		"range":      true,
+		"instant":    false,
+		"interval":   "60s",
+		"intervalMs": float64(60000),
+	}, gotQuery)
+	assert.Equal(t, "1736121600000", gotFrom)
+	assert.Equal(t, "1736125200000", gotTo)
+	matrix := result.(model.Matrix)
+	require.Len(t, matrix, 1)
+	assert.Equal(t, model.Metric{"job": "api"}, matrix[0].Metric)
+	require.Len(t, matrix[0].Values, 2)
+	assert.Equal(t, model.SamplePair{Timestamp: 1736121600000, Value: 1.5}, matrix[0].Values[0])
+	assert.True(t, math.IsNaN(float64(matrix[0].Values[1].Value)))
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = QueryPrometheus.Tool.Name
+	request.Params.Arguments = map[string]any{"datasourceUid": "prom", "expr": "up", "startTime": "2025-01-06T00:00:00Z", "queryType": "instant"}
+	toolResult, err := QueryPrometheus.Handler(ctx, request)
+	require.NoError(t, err)
+	assert.Equal(t, gotFrom, gotTo)
+	assert.Equal(t, true, gotQuery["instant"])
+	assert.Contains(t, toolResult.Content[0].(mcp.TextContent).Text, `"value":[1736121660,"NaN"]`)
+	assert.Equal(t, []string{"partial result"}, toolResult.Meta[PrometheusWarningsMetaField])
+}
@@ -0,0 +1,186 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	// defaultLabelSummaryValues and maxLabelSummaryValues are the default and
+	// maximum number of values listed for each label.
+	defaultLabelSummaryValues = 10
+	maxLabelSummaryValues     = 100
+
+	// defaultLabelSummaryLabels and maxLabelSummaryLabels are the default
+	// and maximum number of labels summarized in a call.
+	defaultLabelSummaryLabels = 30
+	maxLabelSummaryLabels     = 100
+
+	// labelSummaryWorkers is the number of labels summarized concurrently.
+	labelSummaryWorkers = 8
+)
+
+type SummarizePrometheusLabelsParams struct {
+	DatasourceUID string   `json:"datasourceUid" jsonschema:"required,description=The UID of the datasource to query"`
+	Selector      Selector `json:"selector" jsonschema:"required,description=The selector of the series whose labels to summarize. It must have at least one filter\\, e.g. on __name__ or job"`
+	Labels        []string `json:"labels,omitempty" jsonschema:"description=Optionally\\, the names of the labels to summarize. Defaults to all the labels of the selected series"`
+	TopValues     int      `json:"topValues,omitempty" jsonschema:"description=Optionally\\, the number of values to list for each label\\, with the most series first (default 10\\, max 100)"`
+	MaxLabels     int      `json:"maxLabels,omitempty" jsonschema:"description=Optionally\\, the maximum number of labels to summarize (default 30\\, max 100)"`
+	StartRFC3339  string   `json:"startRfc3339,omitempty" jsonschema:"description=Optionally\\, the start of the time range in which to look for label names"`
+	EndRFC3339    string   `json:"endRfc3339,omitempty" jsonschema:"description=Optionally\\, the end of the time range in which to look for label names\\, and the time at which series are counted. Defaults to now"`
+}
+
+// LabelValueCount is a value of a label and the number of series having it.
+type LabelValueCount struct {
+	Value  string `json:"value"`
+	Series int    `json:"series"`
+}
+
+// LabelSummary summarizes the values of a label in the selected series.
+type LabelSummary struct {
+	Name string `json:"name"`
+	// ValueCount is the number of distinct values of the label, of which
+	// Values are those with the most series.
+	ValueCount int               `json:"valueCount"`
+	Values     []LabelValueCount `json:"values"`
+	// Error is set if the label could not be summarized.
+	Error string `json:"error,omitempty"`
+}
+
+type SummarizePrometheusLabelsResult struct {
+	Labels []LabelSummary `json:"labels"`
+	// OmittedLabels is the number of labels of the selected series which are
+	// not summarized because of maxLabels.
+	OmittedLabels int `json:"omittedLabels,omitempty"`
+}
+
+// queryPrometheusVector runs an instant query returning a vector.
+func queryPrometheusVector(ctx context.Context, promClient promv1.API, query string, at time.Time) (model.Vector, error) {
+	value, warnings, err := promClient.Query(ctx, query, at)
+	if err != nil {
+		return nil, err
+	}
+	recordPrometheusWarnings(ctx, warnings)
+	vector, ok := value.(model.Vector)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result type %s", value.Type())
+	}
+	return vector, nil
+}
+
+// summarizeLabel counts the distinct values of a label in the selected
+// series, and the series of its top values.
+func summarizeLabel(ctx context.Context, promClient promv1.API, selector, name string, topValues int, at time.Time) LabelSummary {
+	summary := LabelSummary{Name: name, Values: []LabelValueCount{}}
+	// Label names are inserted in the queries unquoted.
+	if !model.LabelName(name).IsValidLegacy() {
+		summary.Error = "the label name is not a valid PromQL identifier"
+		return summary
+	}
+	count, err := queryPrometheusVector(ctx, promClient, fmt.Sprintf("count(count by (%s) (%s))", name, selector), at)
+	if err != nil {
+		summary.Error = err.Error()
+		return summary
+	}
+	if len(count) > 0 {
+		summary.ValueCount = int(count[0].Value)
+	}
+	top, err := queryPrometheusVector(ctx, promClient, fmt.Sprintf("topk(%d, count by (%s) (%s))", topValues, name, selector), at)
+	if err != nil {
+		summary.Error = err.Error()
+		return summary
+	}
+	for _, s := range top {
+		summary.Values = append(summary.Values, LabelValueCount{Value: string(s.Metric[model.LabelName(name)]), Series: int(s.Value)})
+	}
+	slices.SortFunc(summary.Values, func(a, b LabelValueCount) int {
+		if a.Series != b.Series {
+			return b.Series - a.Series
+		}
+		return strings.Compare(a.Value, b.Value)
+	})
+	return summary
+}
+
+func summarizePrometheusLabels(ctx context.Context, args SummarizePrometheusLabelsParams) (*SummarizePrometheusLabelsResult, error) {
+	if len(args.Selector.Filters) == 0 {
+		return nil, fmt.Errorf("the selector must have at least one filter")
+	}
+	topValues := args.TopValues
+	if topValues <= 0 {
+		topValues = defaultLabelSummaryValues
+	}
+	topValues = min(topValues, maxLabelSummaryValues)
+	maxLabels := args.MaxLabels
+	if maxLabels <= 0 {
+		maxLabels = defaultLabelSummaryLabels
+	}
+	maxLabels = min(maxLabels, maxLabelSummaryLabels)
+
+	var startTime, endTime time.Time
+	var err error
+	if args.StartRFC3339 != "" {
+		if startTime, err = time.Parse(time.RFC3339, args.StartRFC3339); err != nil {
+			return nil, fmt.Errorf("parsing start time: %w", err)
+		}
+	}
+	if args.EndRFC3339 != "" {
+		if endTime, err = time.Parse(time.RFC3339, args.EndRFC3339); err != nil {
+			return nil, fmt.Errorf("parsing end time: %w", err)
+		}
+	}
+	at := endTime
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	promClient, err := promClientFromContext(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+	selector := args.Selector.String()
+	names := args.Labels
+	if len(names) == 0 {
+		var warnings promv1.Warnings
+		names, warnings, err = promClient.LabelNames(ctx, []string{selector}, startTime, endTime)
+		if err != nil {
+			return nil, fmt.Errorf("listing Prometheus label names: %w", err)
+		}
+		recordPrometheusWarnings(ctx, warnings)
+	}
+	result := &SummarizePrometheusLabelsResult{}
+	if len(names) > maxLabels {
+		result.OmittedLabels = len(names) - maxLabels
+		names = names[:maxLabels]
+	}
+
+	// Labels which fail to be summarized are returned with their error.
+	results := fanOut(ctx, "labels", names, labelSummaryWorkers, func(ctx context.Context, name string) (LabelSummary, error) {
+		summary := summarizeLabel(ctx, promClient, selector, name, topValues, at)
+		if summary.Error != "" {
+			return summary, errors.New(summary.Error)
+		}
+		return summary, nil
+	})
+	result.Labels = fanOutValues(results)
+	return result, nil
+}
+
+var SummarizePrometheusLabels = mcpgrafana.MustTool(
+	"grafana_summarize_prometheus_labels",
+	"Summarize the labels of the series matching a selector in a single call: for each label name, the number of distinct values and its top values with the number of series having each, counted at the end of the time range. Use it instead of listing label names and then the values of each label one by one, e.g. to find out which jobs, namespaces or instances a metric is reported by. The selector must have at least one filter. Labels which fail to be summarized have an `error` and the others are still returned.",
+	summarizePrometheusLabels,
+	mcp.WithTitleAnnotation("Summarize Prometheus labels"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
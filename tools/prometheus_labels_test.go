@@ -0,0 +1,142 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+func TestSummarizePrometheusLabels(t *testing.T) {
+	// The series of up{job="api"}: three instances in two namespaces.
+	vectors := map[string]string{
+		`count(count by (__name__) ({job='api'}))`:    `[{"metric": {}, "value": [0, "1"]}]`,
+		`topk(2, count by (__name__) ({job='api'}))`:  `[{"metric": {"__name__": "up"}, "value": [0, "3"]}]`,
+		`count(count by (namespace) ({job='api'}))`:   `[{"metric": {}, "value": [0, "2"]}]`,
+		`topk(2, count by (namespace) ({job='api'}))`: `[{"metric": {"namespace": "dev"}, "value": [0, "1"]}, {"metric": {"namespace": "prod"}, "value": [0, "2"]}]`,
+		`count(count by (instance) ({job='api'}))`:    `[{"metric": {}, "value": [0, "3"]}]`,
+	}
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/datasources/uid/prom": jsonBody(`{"uid": "prom", "type": "prometheus"}`),
+		"/api/datasources/proxy/uid/prom/api/v1/labels": func(w http.ResponseWriter, r *http.Request) {
+			assert.NoError(t, r.ParseForm())
+			assert.Equal(t, []string{"{job='api'}"}, r.Form["match[]"])
+			_, _ = w.Write([]byte(`{"status": "success", "data": ["__name__", "instance", "namespace"]}`))
+		},
+		"/api/datasources/proxy/uid/prom/api/v1/query": func(w http.ResponseWriter, r *http.Request) {
+			vector, ok := vectors[r.FormValue("query")]
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"status": "error", "errorType": "bad_data", "error": "query failed"}`))
+				return
+			}
+			_, _ = fmt.Fprintf(w, `{"status": "success", "data": {"resultType": "vector", "result": %s}}`, vector)
+		},
+	})
+	ctx := fakeGrafanaContext(server)
+
+	selector := Selector{Filters: []LabelMatcher{{Name: "job", Value: "api", Type: "="}}}
+	result, err := summarizePrometheusLabels(ctx, SummarizePrometheusLabelsParams{
+		DatasourceUID: "prom",
+		Selector:      selector,
+		TopValues:     2,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Labels, 3)
+	assert.Equal(t, LabelSummary{Name: "__name__", ValueCount: 1, Values: []LabelValueCount{{Value: "up", Series: 3}}}, result.Labels[0])
+	// The failure of a label doesn't fail the others.
+	assert.Equal(t, "instance", result.Labels[1].Name)
+	assert.Equal(t, 3, result.Labels[1].ValueCount)
+	assert.True(t, strings.Contains(result.Labels[1].Error, "query failed"), result.Labels[1].Error)
+	// Values are sorted by number of series.
+	assert.Equal(t, LabelSummary{Name: "namespace", ValueCount: 2, Values: []LabelValueCount{{Value: "prod", Series: 2}, {Value: "dev", Series: 1}}}, result.Labels[2])
+
+	result, err = summarizePrometheusLabels(ctx, SummarizePrometheusLabelsParams{
+		DatasourceUID: "prom",
+		Selector:      selector,
+		Labels:        []string{"namespace", "__name__"},
+		TopValues:     2,
+		MaxLabels:     1,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Labels, 1)
+	assert.Equal(t, "namespace", result.Labels[0].Name)
+	assert.Equal(t, 1, result.OmittedLabels)
+
+	_, err = summarizePrometheusLabels(ctx, SummarizePrometheusLabelsParams{DatasourceUID: "prom"})
+	assert.ErrorContains(t, err, "at least one filter")
+	result, err = summarizePrometheusLabels(ctx, SummarizePrometheusLabelsParams{DatasourceUID: "prom", Selector: selector, Labels: []string{"bad label"}})
+	require.NoError(t, err)
+	assert.Equal(t, "the label name is not a valid PromQL identifier", result.Labels[0].Error)
+}
+
+func TestSummarizePrometheusLabelsRedaction(t *testing.T) {
+	ctx := newFakeGrafanaContext(t, fakeRoutes{
+		"/api/datasources/uid/prom":                     jsonBody(`{"uid": "prom", "type": "prometheus"}`),
+		"/api/datasources/proxy/uid/prom/api/v1/labels": jsonBody(`{"status": "success", "data": ["email"]}`),
+		"/api/datasources/proxy/uid/prom/api/v1/query": func(w http.ResponseWriter, r *http.Request) {
+			result := `[{"metric": {}, "value": [0, "1"]}]`
+			if strings.HasPrefix(r.FormValue("query"), "topk") {
+				result = `[{"metric": {"email": "alice@example.com"}, "value": [0, "3"]}]`
+			}
+			_, _ = fmt.Fprintf(w, `{"status": "success", "data": {"resultType": "vector", "result": %s}}`, result)
+		},
+	})
+	handler := mcpgrafana.RedactionMiddleware(mcpgrafana.RedactionConfig{Labels: []string{"email"}, Mode: mcpgrafana.RedactionModeMask})(SummarizePrometheusLabels.Handler)
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = SummarizePrometheusLabels.Tool.Name
+	request.Params.Arguments = map[string]any{
+		"datasourceUid": "prom",
+		"selector":      map[string]any{"filters": []any{map[string]any{"name": "job", "value": "api", "type": "="}}},
+	}
+	result, err := handler(ctx, request)
+	require.NoError(t, err)
+	text := result.Content[0].(mcp.TextContent).Text
+	assert.NotContains(t, text, "alice@example.com")
+	assert.Contains(t, text, `"value":"[REDACTED]"`)
+}
+
+func TestListPrometheusSeries(t *testing.T) {
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/datasources/uid/prom": jsonBody(`{"uid": "prom", "type": "prometheus"}`),
+		"/api/datasources/proxy/uid/prom/api/v1/series": func(w http.ResponseWriter, r *http.Request) {
+			assert.NoError(t, r.ParseForm())
+			assert.Equal(t, []string{"{__name__='up', job=~'api|web'}"}, r.Form["match[]"])
+			assert.Equal(t, "2", r.Form.Get("limit"))
+			assert.NotEmpty(t, r.Form.Get("start"))
+			// Older versions of Prometheus ignore the limit.
+			_, _ = w.Write([]byte(`{"status": "success", "data": [
+				{"__name__": "up", "job": "api", "instance": "a"},
+				{"__name__": "up", "job": "api", "instance": "b"},
+				{"__name__": "up", "job": "web", "instance": "c"}
+			]}`))
+		},
+	})
+	ctx := fakeGrafanaContext(server)
+
+	series, err := listPrometheusSeries(ctx, ListPrometheusSeriesParams{
+		DatasourceUID: "prom",
+		Matches: []Selector{{Filters: []LabelMatcher{
+			{Name: "__name__", Value: "up", Type: "="},
+			{Name: "job", Value: "api|web", Type: "=~"},
+		}}},
+		StartRFC3339: "2025-01-06T00:00:00Z",
+		Limit:        2,
+	})
+	require.NoError(t, err)
+	require.Len(t, series, 2)
+	assert.Equal(t, "b", string(series[1]["instance"]))
+
+	_, err = listPrometheusSeries(ctx, ListPrometheusSeriesParams{DatasourceUID: "prom"})
+	assert.ErrorContains(t, err, "at least one selector is required")
+}
@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/prometheus/common/model"
+)
+
+// prometheusDownsampleFuncs are the functions which can aggregate the
+// samples of a bucket when downsampling a range query result.
+var prometheusDownsampleFuncs = map[string]func(values []float64) float64{
+	"avg": func(values []float64) float64 {
+		sum := 0.0
+		for _, v := range values {
+			sum += v
+		}
+		return sum / float64(len(values))
+	},
+	"min": func(values []float64) float64 {
+		m := values[0]
+		for _, v := range values[1:] {
+			m = math.Min(m, v)
+		}
+		return m
+	},
+	"max": func(values []float64) float64 {
+		m := values[0]
+		for _, v := range values[1:] {
+			m = math.Max(m, v)
+		}
+		return m
+	},
+}
+
+// validateDownsample checks the downsampling parameters of a query,
+// returning the aggregation function to use.
+func validateDownsample(args QueryPrometheusParams) (string, error) {
+	if args.MaxDataPoints < 0 {
+		return "", fmt.Errorf("maxDataPoints must not be negative")
+	}
+	aggregation := args.Downsample
+	if aggregation == "" {
+		aggregation = "avg"
+	}
+	if _, ok := prometheusDownsampleFuncs[aggregation]; !ok {
+		return "", fmt.Errorf("invalid downsample function %q, expected 'avg', 'min' or 'max'", args.Downsample)
+	}
+	return aggregation, nil
+}
+
+// downsampleMatrix reduces each series of a range query result to at most
+// maxPoints samples, aggregating consecutive samples into buckets with the
+// given function. Each bucket has the timestamp of its first sample. NaN
+// values, e.g. from divisions by zero, are ignored unless a bucket only has
+// NaN values.
+func downsampleMatrix(m model.Matrix, maxPoints int, aggregation string) model.Matrix {
+	aggregate := prometheusDownsampleFuncs[aggregation]
+	for _, s := range m {
+		n := len(s.Values)
+		if maxPoints <= 0 || n <= maxPoints {
+			continue
+		}
+		size := (n + maxPoints - 1) / maxPoints
+		values := make([]model.SamplePair, 0, maxPoints)
+		bucket := make([]float64, 0, size)
+		for start := 0; start < n; start += size {
+			bucket = bucket[:0]
+			for _, p := range s.Values[start:min(start+size, n)] {
+				if !math.IsNaN(float64(p.Value)) {
+					bucket = append(bucket, float64(p.Value))
+				}
+			}
+			v := math.NaN()
+			if len(bucket) > 0 {
+				v = aggregate(bucket)
+			}
+			values = append(values, model.SamplePair{Timestamp: s.Values[start].Timestamp, Value: model.SampleValue(v)})
+		}
+		s.Values = values
+	}
+	return m
+}
+
+// PrometheusSeriesSummary summarizes the samples of a series of a range
+// query result.
+type PrometheusSeriesSummary struct {
+	Metric model.Metric `json:"metric"`
+	// Count is the number of samples, of which NaN values are excluded from
+	// the other statistics. Like sample values, the statistics are encoded
+	// as strings.
+	Count int               `json:"count"`
+	Min   model.SampleValue `json:"min"`
+	Max   model.SampleValue `json:"max"`
+	Avg   model.SampleValue `json:"avg"`
+	// First and Last are the values of the first and last samples, at
+	// FirstTimestamp and LastTimestamp.
+	First          model.SampleValue `json:"first"`
+	Last           model.SampleValue `json:"last"`
+	FirstTimestamp model.Time        `json:"firstTimestamp"`
+	LastTimestamp  model.Time        `json:"lastTimestamp"`
+}
+
+// summarizeMatrix returns summary statistics of each series of a range query
+// result. The minimum, maximum and average of series without any value other
+// than NaN are NaN.
+func summarizeMatrix(m model.Matrix) []PrometheusSeriesSummary {
+	summaries := make([]PrometheusSeriesSummary, 0, len(m))
+	for _, s := range m {
+		nan := model.SampleValue(math.NaN())
+		summary := PrometheusSeriesSummary{Metric: s.Metric, Count: len(s.Values), Min: nan, Max: nan, Avg: nan, First: nan, Last: nan}
+		if len(s.Values) > 0 {
+			summary.FirstTimestamp = s.Values[0].Timestamp
+			summary.LastTimestamp = s.Values[len(s.Values)-1].Timestamp
+			summary.First = s.Values[0].Value
+			summary.Last = s.Values[len(s.Values)-1].Value
+		}
+		var values []float64
+		for _, p := range s.Values {
+			if !math.IsNaN(float64(p.Value)) {
+				values = append(values, float64(p.Value))
+			}
+		}
+		if len(values) > 0 {
+			summary.Min = model.SampleValue(prometheusDownsampleFuncs["min"](values))
+			summary.Max = model.SampleValue(prometheusDownsampleFuncs["max"](values))
+			summary.Avg = model.SampleValue(prometheusDownsampleFuncs["avg"](values))
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
@@ -0,0 +1,257 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// remoteWriteLagThreshold is the lag of a remote-write queue above which it
+// is reported as falling behind.
+const remoteWriteLagThreshold = time.Minute
+
+// remoteWriteQueries are the queries of the metrics of remote-write queues,
+// by the field of RemoteWriteQueue they fill. Metrics renamed in Prometheus
+// 2.23 are queried under both names.
+var remoteWriteQueries = map[string]string{
+	"lag":     `prometheus_remote_storage_highest_timestamp_in_seconds - ignoring(remote_name, url) group_right prometheus_remote_storage_queue_highest_sent_timestamp_seconds`,
+	"shards":  `prometheus_remote_storage_shards`,
+	"desired": `prometheus_remote_storage_shards_desired`,
+	"max":     `prometheus_remote_storage_shards_max`,
+	"pending": `prometheus_remote_storage_samples_pending or prometheus_remote_storage_pending_samples`,
+	"sent":    `rate(prometheus_remote_storage_samples_total[5m]) or rate(prometheus_remote_storage_succeeded_samples_total[5m])`,
+	"failed":  `rate(prometheus_remote_storage_samples_failed_total[5m]) or rate(prometheus_remote_storage_failed_samples_total[5m])`,
+	"retried": `rate(prometheus_remote_storage_samples_retried_total[5m]) or rate(prometheus_remote_storage_retried_samples_total[5m])`,
+	"dropped": `rate(prometheus_remote_storage_samples_dropped_total[5m]) or rate(prometheus_remote_storage_dropped_samples_total[5m])`,
+}
+
+// walProblemQueries are the queries of the WAL problems of the last hour, by
+// their description.
+var walProblemQueries = map[string]string{
+	"WAL corruptions":         `sum by (instance) (increase(prometheus_tsdb_wal_corruptions_total[1h])) > 0`,
+	"failed WAL writes":       `sum by (instance) (increase(prometheus_tsdb_wal_writes_failed_total[1h])) > 0`,
+	"failed WAL truncations":  `sum by (instance) (increase(prometheus_tsdb_wal_truncations_failed_total[1h])) > 0`,
+	"failed head compactions": `sum by (instance) (increase(prometheus_tsdb_compactions_failed_total[1h])) > 0`,
+}
+
+type GetPrometheusRemoteWriteHealthParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the Prometheus datasource"`
+}
+
+// RemoteWriteQueue is the state of a remote-write queue of a Prometheus
+// instance. Metrics which Prometheus doesn't expose are left out.
+type RemoteWriteQueue struct {
+	Instance   string `json:"instance,omitempty"`
+	RemoteName string `json:"remoteName,omitempty"`
+	URL        string `json:"url,omitempty"`
+	// LagSeconds is how far the samples sent by the queue are behind the
+	// newest samples ingested.
+	LagSeconds     *float64 `json:"lagSeconds,omitempty"`
+	Shards         *float64 `json:"shards,omitempty"`
+	DesiredShards  *float64 `json:"desiredShards,omitempty"`
+	MaxShards      *float64 `json:"maxShards,omitempty"`
+	SamplesPending *float64 `json:"samplesPending,omitempty"`
+	// The rates of samples are per second, over the last 5 minutes.
+	SentPerSecond    *float64 `json:"sentPerSecond,omitempty"`
+	FailedPerSecond  *float64 `json:"failedPerSecond,omitempty"`
+	RetriedPerSecond *float64 `json:"retriedPerSecond,omitempty"`
+	DroppedPerSecond *float64 `json:"droppedPerSecond,omitempty"`
+}
+
+// PrometheusRuntime is the runtime information of Prometheus relevant to its
+// storage.
+type PrometheusRuntime struct {
+	StartTime           time.Time `json:"startTime"`
+	StorageRetention    string    `json:"storageRetention,omitempty"`
+	ReloadConfigSuccess bool      `json:"reloadConfigSuccess"`
+	CorruptionCount     int       `json:"corruptionCount"`
+}
+
+// PrometheusRemoteWriteHealth is the state of the remote-write queues, WAL
+// and TSDB head of a Prometheus datasource.
+type PrometheusRemoteWriteHealth struct {
+	Runtime   *PrometheusRuntime      `json:"runtime,omitempty"`
+	WALReplay *promv1.WalReplayStatus `json:"walReplay,omitempty"`
+	Head      *promv1.TSDBHeadStats   `json:"head,omitempty"`
+	Queues    []RemoteWriteQueue      `json:"queues"`
+	// Findings describe problems found, e.g. queues falling behind.
+	Findings []string `json:"findings"`
+	// Errors are the endpoints or metrics which could not be read.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// queueKey identifies a remote-write queue by its labels.
+func queueKey(m model.Metric) string {
+	return strings.Join([]string{string(m["instance"]), string(m["remote_name"]), string(m["url"])}, "\x00")
+}
+
+// remoteWriteQueues collects the metrics of the remote-write queues of a
+// Prometheus, ordered by instance and remote name.
+func remoteWriteQueues(ctx context.Context, promClient promv1.API, now time.Time) ([]RemoteWriteQueue, []string) {
+	queues := map[string]*RemoteWriteQueue{}
+	var errs []string
+	for field, expr := range remoteWriteQueries {
+		result, _, err := promClient.Query(ctx, expr, now)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("remote-write %s: %s", field, err))
+			continue
+		}
+		vector, _ := result.(model.Vector)
+		for _, s := range vector {
+			key := queueKey(s.Metric)
+			q, ok := queues[key]
+			if !ok {
+				q = &RemoteWriteQueue{Instance: string(s.Metric["instance"]), RemoteName: string(s.Metric["remote_name"]), URL: string(s.Metric["url"])}
+				queues[key] = q
+			}
+			v := float64(s.Value)
+			switch field {
+			case "lag":
+				q.LagSeconds = &v
+			case "shards":
+				q.Shards = &v
+			case "desired":
+				q.DesiredShards = &v
+			case "max":
+				q.MaxShards = &v
+			case "pending":
+				q.SamplesPending = &v
+			case "sent":
+				q.SentPerSecond = &v
+			case "failed":
+				q.FailedPerSecond = &v
+			case "retried":
+				q.RetriedPerSecond = &v
+			case "dropped":
+				q.DroppedPerSecond = &v
+			}
+		}
+	}
+	result := make([]RemoteWriteQueue, 0, len(queues))
+	for _, q := range queues {
+		result = append(result, *q)
+	}
+	slices.SortFunc(result, func(a, b RemoteWriteQueue) int {
+		return strings.Compare(a.Instance+"\x00"+a.RemoteName+"\x00"+a.URL, b.Instance+"\x00"+b.RemoteName+"\x00"+b.URL)
+	})
+	slices.Sort(errs)
+	return result, errs
+}
+
+// remoteWriteFindings describes the problems of remote-write queues.
+func remoteWriteFindings(queues []RemoteWriteQueue) []string {
+	var findings []string
+	for _, q := range queues {
+		name := q.RemoteName
+		if name == "" {
+			name = q.URL
+		}
+		if q.Instance != "" {
+			name = fmt.Sprintf("%s on %s", name, q.Instance)
+		}
+		if q.LagSeconds != nil && *q.LagSeconds > remoteWriteLagThreshold.Seconds() {
+			findings = append(findings, fmt.Sprintf("queue %s is %.0fs behind: samples arrive late at the remote storage", name, *q.LagSeconds))
+		}
+		if q.DesiredShards != nil && q.MaxShards != nil && *q.DesiredShards > *q.MaxShards {
+			findings = append(findings, fmt.Sprintf("queue %s wants %.0f shards but is limited to %.0f: raise max_shards or the remote storage can't keep up", name, *q.DesiredShards, *q.MaxShards))
+		}
+		if q.FailedPerSecond != nil && *q.FailedPerSecond > 0 {
+			findings = append(findings, fmt.Sprintf("queue %s fails to send %.1f samples/s", name, *q.FailedPerSecond))
+		}
+		if q.RetriedPerSecond != nil && *q.RetriedPerSecond > 0 {
+			findings = append(findings, fmt.Sprintf("queue %s retries %.1f samples/s, e.g. because the remote storage is rate limiting or failing", name, *q.RetriedPerSecond))
+		}
+		if q.DroppedPerSecond != nil && *q.DroppedPerSecond > 0 {
+			findings = append(findings, fmt.Sprintf("queue %s drops %.1f samples/s", name, *q.DroppedPerSecond))
+		}
+	}
+	return findings
+}
+
+// walFindings describes the WAL and compaction problems of the last hour.
+func walFindings(ctx context.Context, promClient promv1.API, now time.Time) ([]string, []string) {
+	var findings, errs []string
+	for problem, expr := range walProblemQueries {
+		result, _, err := promClient.Query(ctx, expr, now)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", problem, err))
+			continue
+		}
+		vector, _ := result.(model.Vector)
+		for _, s := range vector {
+			instance := string(s.Metric["instance"])
+			findings = append(findings, fmt.Sprintf("%s: %.0f %s in the last hour", cmp.Or(instance, "prometheus"), float64(s.Value), problem))
+		}
+	}
+	slices.Sort(findings)
+	slices.Sort(errs)
+	return findings, errs
+}
+
+func getPrometheusRemoteWriteHealth(ctx context.Context, args GetPrometheusRemoteWriteHealthParams) (*PrometheusRemoteWriteHealth, error) {
+	promClient, err := promClientFromContext(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+	now := time.Now()
+	result := &PrometheusRemoteWriteHealth{Findings: []string{}}
+
+	// The status endpoints are only served by Prometheus itself, and not by
+	// e.g. Mimir, which shouldn't hide the metrics.
+	if info, err := promClient.Runtimeinfo(ctx); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("runtime info: %s", err))
+	} else {
+		result.Runtime = &PrometheusRuntime{
+			StartTime:           info.StartTime,
+			StorageRetention:    info.StorageRetention,
+			ReloadConfigSuccess: info.ReloadConfigSuccess,
+			CorruptionCount:     info.CorruptionCount,
+		}
+		if info.CorruptionCount > 0 {
+			result.Findings = append(result.Findings, fmt.Sprintf("the TSDB reported %d corruptions", info.CorruptionCount))
+		}
+	}
+	if replay, err := promClient.WalReplay(ctx); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("WAL replay status: %s", err))
+	} else {
+		result.WALReplay = &replay
+		if replay.Max > 0 && replay.Current < replay.Max {
+			result.Findings = append(result.Findings, fmt.Sprintf("the WAL is being replayed (segment %d of %d): Prometheus is starting up and not ingesting yet", replay.Current, replay.Max))
+		}
+	}
+	if tsdb, err := promClient.TSDB(ctx, promv1.WithLimit(1)); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("TSDB status: %s", err))
+	} else {
+		result.Head = &tsdb.HeadStats
+	}
+
+	var errs []string
+	result.Queues, errs = remoteWriteQueues(ctx, promClient, now)
+	result.Errors = append(result.Errors, errs...)
+	result.Findings = append(result.Findings, remoteWriteFindings(result.Queues)...)
+	findings, errs := walFindings(ctx, promClient, now)
+	result.Findings = append(result.Findings, findings...)
+	result.Errors = append(result.Errors, errs...)
+	if len(result.Queues) == 0 {
+		result.Findings = append(result.Findings, "no remote-write queue metrics found: the datasource doesn't remote-write, or doesn't scrape the metrics of the Prometheus instances which do")
+	}
+	return result, nil
+}
+
+var GetPrometheusRemoteWriteHealth = mcpgrafana.MustTool(
+	"grafana_get_prometheus_remote_write_health",
+	"Check the health of the remote-write queues and the WAL of a Prometheus datasource, e.g. to diagnose metrics arriving late at Mimir or another remote storage. Reports for each queue how far it is behind, its current, desired and maximum shards, pending samples and the rates of samples sent, failed, retried and dropped, read from the `prometheus_remote_storage_*` metrics of the Prometheus instances the datasource scrapes, together with the runtime information, WAL replay status and head block statistics of the status endpoints. Problems such as lagging queues, too few shards, failing samples or WAL corruptions are listed in `findings`. Endpoints or metrics which could not be read, e.g. because the datasource is not Prometheus itself, are listed in `errors`.",
+	getPrometheusRemoteWriteHealth,
+	mcp.WithTitleAnnotation("Get Prometheus remote-write health"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
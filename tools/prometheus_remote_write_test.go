@@ -0,0 +1,64 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPrometheusRemoteWriteHealth(t *testing.T) {
+	// vectors are the results of the queries containing a metric name.
+	vectors := map[string]string{
+		"queue_highest_sent_timestamp": `[{"metric": {"instance": "prom-0", "remote_name": "mimir", "url": "http://mimir/push"}, "value": [1736157600, "185"]}]`,
+		"shards_desired":               `[{"metric": {"instance": "prom-0", "remote_name": "mimir", "url": "http://mimir/push"}, "value": [1736157600, "60"]}]`,
+		"shards_max":                   `[{"metric": {"instance": "prom-0", "remote_name": "mimir", "url": "http://mimir/push"}, "value": [1736157600, "50"]}]`,
+		"samples_failed_total":         `[{"metric": {"instance": "prom-0", "remote_name": "mimir", "url": "http://mimir/push"}, "value": [1736157600, "0"]}]`,
+		"wal_corruptions_total":        `[{"metric": {"instance": "prom-0"}, "value": [1736157600, "2"]}]`,
+	}
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/datasources/uid/prom":                                 jsonBody(`{"uid": "prom", "type": "prometheus"}`),
+		"/api/datasources/proxy/uid/prom/api/v1/status/runtimeinfo": jsonBody(`{"status": "success", "data": {"startTime": "2025-01-06T09:00:00Z", "reloadConfigSuccess": true, "corruptionCount": 0, "storageRetention": "15d"}}`),
+		"/api/datasources/proxy/uid/prom/api/v1/status/tsdb":        jsonBody(`{"status": "success", "data": {"headStats": {"numSeries": 1200, "chunkCount": 3400, "minTime": 1736150000000, "maxTime": 1736157600000}, "seriesCountByMetricName": [], "labelValueCountByLabelName": [], "memoryInBytesByLabelName": [], "seriesCountByLabelValuePair": []}}`),
+		"/api/datasources/proxy/uid/prom/api/v1/query": func(w http.ResponseWriter, r *http.Request) {
+			result := `[]`
+			for name, vector := range vectors {
+				if strings.Contains(r.FormValue("query"), name) {
+					result = vector
+				}
+			}
+			_, _ = w.Write([]byte(`{"status": "success", "data": {"resultType": "vector", "result": ` + result + `}}`))
+		},
+	})
+	ctx := fakeGrafanaContext(server)
+
+	health, err := getPrometheusRemoteWriteHealth(ctx, GetPrometheusRemoteWriteHealthParams{DatasourceUID: "prom"})
+	require.NoError(t, err)
+
+	require.NotNil(t, health.Runtime)
+	assert.Equal(t, "15d", health.Runtime.StorageRetention)
+	require.NotNil(t, health.Head)
+	assert.Equal(t, 1200, health.Head.NumSeries)
+	assert.Nil(t, health.WALReplay)
+	require.Len(t, health.Errors, 1)
+	assert.Contains(t, health.Errors[0], "WAL replay status")
+
+	require.Len(t, health.Queues, 1)
+	queue := health.Queues[0]
+	assert.Equal(t, "mimir", queue.RemoteName)
+	assert.Equal(t, 185.0, *queue.LagSeconds)
+	assert.Equal(t, 60.0, *queue.DesiredShards)
+	assert.Equal(t, 0.0, *queue.FailedPerSecond)
+	assert.Nil(t, queue.Shards)
+
+	assert.Equal(t, []string{
+		"queue mimir on prom-0 is 185s behind: samples arrive late at the remote storage",
+		"queue mimir on prom-0 wants 60 shards but is limited to 50: raise max_shards or the remote storage can't keep up",
+		"prom-0: 2 WAL corruptions in the last hour",
+	}, health.Findings)
+}
@@ -0,0 +1,198 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+type ListPrometheusRulesParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the Prometheus or Mimir datasource"`
+	Type          string `json:"type,omitempty" jsonschema:"description=Optionally\\, only return alerting ('alert') or recording ('record') rules"`
+	Group         string `json:"group,omitempty" jsonschema:"description=Optionally\\, only return the rules of groups whose name contains this string"`
+	Name          string `json:"name,omitempty" jsonschema:"description=Optionally\\, only return the rules whose name contains this string"`
+	Health        string `json:"health,omitempty" jsonschema:"description=Optionally\\, only return the rules with this health: ok\\, err or unknown\\, e.g. 'err' for rules whose last evaluation failed"`
+	State         string `json:"state,omitempty" jsonschema:"description=Optionally\\, only return the alerting rules in this state: inactive\\, pending or firing"`
+}
+
+// PrometheusRule is a recording or alerting rule evaluated by Prometheus or
+// the Mimir ruler.
+type PrometheusRule struct {
+	Name string `json:"name"`
+	// Type is "alert" or "record".
+	Type           string            `json:"type"`
+	Query          string            `json:"query"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	Health         string            `json:"health"`
+	LastError      string            `json:"lastError,omitempty"`
+	LastEvaluation time.Time         `json:"lastEvaluation"`
+	// EvaluationTimeSeconds is the duration of the last evaluation.
+	EvaluationTimeSeconds float64 `json:"evaluationTimeSeconds"`
+
+	// The fields of alerting rules: how long their condition must hold before
+	// they fire, and their state and number of active alerts.
+	ForSeconds   float64           `json:"forSeconds,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	State        string            `json:"state,omitempty"`
+	ActiveAlerts int               `json:"activeAlerts,omitempty"`
+}
+
+// PrometheusRuleGroup is a group of rules evaluated together.
+type PrometheusRuleGroup struct {
+	Name            string           `json:"name"`
+	File            string           `json:"file"`
+	IntervalSeconds float64          `json:"intervalSeconds"`
+	Rules           []PrometheusRule `json:"rules"`
+}
+
+func labelSetMap(ls model.LabelSet) map[string]string {
+	if len(ls) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(ls))
+	for k, v := range ls {
+		m[string(k)] = string(v)
+	}
+	return m
+}
+
+// prometheusRule converts a rule returned by the Prometheus API, returning
+// false if it is of an unknown type.
+func prometheusRule(rule any) (PrometheusRule, bool) {
+	switch r := rule.(type) {
+	case promv1.AlertingRule:
+		return PrometheusRule{
+			Name:                  r.Name,
+			Type:                  "alert",
+			Query:                 r.Query,
+			Labels:                labelSetMap(r.Labels),
+			Health:                string(r.Health),
+			LastError:             r.LastError,
+			LastEvaluation:        r.LastEvaluation,
+			EvaluationTimeSeconds: r.EvaluationTime,
+			ForSeconds:            r.Duration,
+			Annotations:           labelSetMap(r.Annotations),
+			State:                 r.State,
+			ActiveAlerts:          len(r.Alerts),
+		}, true
+	case promv1.RecordingRule:
+		return PrometheusRule{
+			Name:                  r.Name,
+			Type:                  "record",
+			Query:                 r.Query,
+			Labels:                labelSetMap(r.Labels),
+			Health:                string(r.Health),
+			LastError:             r.LastError,
+			LastEvaluation:        r.LastEvaluation,
+			EvaluationTimeSeconds: r.EvaluationTime,
+		}, true
+	}
+	return PrometheusRule{}, false
+}
+
+func (args ListPrometheusRulesParams) matches(r PrometheusRule) bool {
+	return (args.Type == "" || r.Type == args.Type) &&
+		strings.Contains(r.Name, args.Name) &&
+		(args.Health == "" || r.Health == args.Health) &&
+		(args.State == "" || r.State == args.State)
+}
+
+func listPrometheusRules(ctx context.Context, args ListPrometheusRulesParams) ([]PrometheusRuleGroup, error) {
+	if args.Type != "" && args.Type != "alert" && args.Type != "record" {
+		return nil, fmt.Errorf("invalid rule type %q, expected 'alert' or 'record'", args.Type)
+	}
+	promClient, err := promClientFromContext(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+	rules, err := promClient.Rules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing Prometheus rules: %w", err)
+	}
+	groups := []PrometheusRuleGroup{}
+	for _, g := range rules.Groups {
+		if !strings.Contains(g.Name, args.Group) {
+			continue
+		}
+		group := PrometheusRuleGroup{Name: g.Name, File: g.File, IntervalSeconds: g.Interval, Rules: []PrometheusRule{}}
+		for _, rule := range g.Rules {
+			if r, ok := prometheusRule(rule); ok && args.matches(r) {
+				group.Rules = append(group.Rules, r)
+			}
+		}
+		if len(group.Rules) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	return groups, nil
+}
+
+var ListPrometheusRules = mcpgrafana.MustTool(
+	"grafana_list_prometheus_rules",
+	"List the recording and alerting rules evaluated by a Prometheus or Mimir datasource, from its `/api/v1/rules` endpoint, grouped by rule group. Each rule has its query, health, last error, time and duration of its last evaluation, and, for alerting rules, their state and number of active alerts. Unlike `grafana_list_alert_rules`, which only sees Grafana-managed rules, this also covers rules managed by Prometheus itself or the Mimir ruler. Filter by type, group, name, health or state to keep the result small, e.g. `health: err` to find broken rules.",
+	listPrometheusRules,
+	mcp.WithTitleAnnotation("List Prometheus rules"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type ListPrometheusAlertsParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the Prometheus or Mimir datasource"`
+	AlertName     string `json:"alertName,omitempty" jsonschema:"description=Optionally\\, only return the alerts whose alertname label contains this string"`
+	State         string `json:"state,omitempty" jsonschema:"description=Optionally\\, only return the alerts in this state: pending or firing"`
+}
+
+// PrometheusAlert is an active alert of an alerting rule.
+type PrometheusAlert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	State       string            `json:"state"`
+	ActiveAt    time.Time         `json:"activeAt"`
+	// Value is the value of the rule's expression at its last evaluation.
+	Value string `json:"value"`
+}
+
+func listPrometheusAlerts(ctx context.Context, args ListPrometheusAlertsParams) ([]PrometheusAlert, error) {
+	promClient, err := promClientFromContext(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+	result, err := promClient.Alerts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing Prometheus alerts: %w", err)
+	}
+	alerts := []PrometheusAlert{}
+	for _, a := range result.Alerts {
+		if !strings.Contains(string(a.Labels[model.AlertNameLabel]), args.AlertName) ||
+			(args.State != "" && string(a.State) != args.State) {
+			continue
+		}
+		alerts = append(alerts, PrometheusAlert{
+			Labels:      labelSetMap(a.Labels),
+			Annotations: labelSetMap(a.Annotations),
+			State:       string(a.State),
+			ActiveAt:    a.ActiveAt,
+			Value:       a.Value,
+		})
+	}
+	// Show the longest active alerts first.
+	slices.SortStableFunc(alerts, func(a, b PrometheusAlert) int { return a.ActiveAt.Compare(b.ActiveAt) })
+	return alerts, nil
+}
+
+var ListPrometheusAlerts = mcpgrafana.MustTool(
+	"grafana_list_prometheus_alerts",
+	"List the active (pending or firing) alerts of the alerting rules evaluated by a Prometheus or Mimir datasource, from its `/api/v1/alerts` endpoint, with their labels, annotations, state, the time they became active and the current value of their expression. The longest active alerts come first. Use `grafana_list_prometheus_rules` to see the rules themselves.",
+	listPrometheusAlerts,
+	mcp.WithTitleAnnotation("List Prometheus alerts"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
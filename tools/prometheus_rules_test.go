@@ -0,0 +1,92 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPrometheusRulesTestContext(t *testing.T) context.Context {
+	return newFakeGrafanaContext(t, fakeRoutes{
+		"/api/datasources/uid/mimir": jsonBody(`{"uid": "mimir", "type": "prometheus"}`),
+		"/api/datasources/proxy/uid/mimir/api/v1/rules": jsonBody(`{"status": "success", "data": {"groups": [
+			{"name": "node", "file": "tenant/node.yaml", "interval": 60, "rules": [
+				{"type": "recording", "name": "node:cpu:rate5m", "query": "rate(node_cpu_seconds_total[5m])", "health": "ok",
+				 "evaluationTime": 0.01, "lastEvaluation": "2025-01-06T00:00:00Z"},
+				{"type": "alerting", "name": "NodeDown", "query": "up == 0", "duration": 300, "health": "ok", "state": "firing",
+				 "labels": {"severity": "critical"}, "annotations": {"summary": "Node down"},
+				 "alerts": [{"labels": {"alertname": "NodeDown"}, "annotations": {}, "state": "firing", "activeAt": "2025-01-05T23:00:00Z", "value": "0"}],
+				 "evaluationTime": 0.02, "lastEvaluation": "2025-01-06T00:00:00Z"}
+			]},
+			{"name": "broken", "file": "tenant/broken.yaml", "interval": 30, "rules": [
+				{"type": "recording", "name": "job:errors:rate5m", "query": "rate(errors[5m]", "health": "err", "lastError": "parse error",
+				 "evaluationTime": 0, "lastEvaluation": "2025-01-06T00:00:00Z"}
+			]}
+		]}}`),
+		"/api/datasources/proxy/uid/mimir/api/v1/alerts": jsonBody(`{"status": "success", "data": {"alerts": [
+			{"labels": {"alertname": "HighLatency", "service": "api"}, "annotations": {}, "state": "pending", "activeAt": "2025-01-06T00:00:00Z", "value": "1.2"},
+			{"labels": {"alertname": "NodeDown", "instance": "a"}, "annotations": {"summary": "Node down"}, "state": "firing", "activeAt": "2025-01-05T23:00:00Z", "value": "0"}
+		]}}`),
+	})
+}
+
+func TestListPrometheusRules(t *testing.T) {
+	ctx := newPrometheusRulesTestContext(t)
+
+	groups, err := listPrometheusRules(ctx, ListPrometheusRulesParams{DatasourceUID: "mimir"})
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	assert.Equal(t, "node", groups[0].Name)
+	assert.Equal(t, float64(60), groups[0].IntervalSeconds)
+	require.Len(t, groups[0].Rules, 2)
+	assert.Equal(t, PrometheusRule{
+		Name:                  "NodeDown",
+		Type:                  "alert",
+		Query:                 "up == 0",
+		Labels:                map[string]string{"severity": "critical"},
+		Health:                "ok",
+		LastEvaluation:        time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC),
+		EvaluationTimeSeconds: 0.02,
+		ForSeconds:            300,
+		Annotations:           map[string]string{"summary": "Node down"},
+		State:                 "firing",
+		ActiveAlerts:          1,
+	}, groups[0].Rules[1])
+
+	// Groups without matching rules are omitted.
+	groups, err = listPrometheusRules(ctx, ListPrometheusRulesParams{DatasourceUID: "mimir", Health: "err"})
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, "parse error", groups[0].Rules[0].LastError)
+
+	groups, err = listPrometheusRules(ctx, ListPrometheusRulesParams{DatasourceUID: "mimir", Type: "alert"})
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, "NodeDown", groups[0].Rules[0].Name)
+
+	_, err = listPrometheusRules(ctx, ListPrometheusRulesParams{DatasourceUID: "mimir", Type: "alerting"})
+	assert.ErrorContains(t, err, `invalid rule type "alerting"`)
+}
+
+func TestListPrometheusAlerts(t *testing.T) {
+	ctx := newPrometheusRulesTestContext(t)
+
+	alerts, err := listPrometheusAlerts(ctx, ListPrometheusAlertsParams{DatasourceUID: "mimir"})
+	require.NoError(t, err)
+	require.Len(t, alerts, 2)
+	// The longest active alert comes first.
+	assert.Equal(t, "NodeDown", alerts[0].Labels["alertname"])
+	assert.Equal(t, "firing", alerts[0].State)
+	assert.Equal(t, "1.2", alerts[1].Value)
+
+	alerts, err = listPrometheusAlerts(ctx, ListPrometheusAlertsParams{DatasourceUID: "mimir", State: "pending"})
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "HighLatency", alerts[0].Labels["alertname"])
+}
@@ -0,0 +1,138 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// defaultPrometheusTargetsLimit is the default maximum number of targets
+// listed.
+const defaultPrometheusTargetsLimit = 100
+
+type ListPrometheusTargetsParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the Prometheus datasource"`
+	Health        string `json:"health,omitempty" jsonschema:"description=Optionally\\, only list the targets with this health: up\\, down or unknown\\, e.g. 'down' to find failing scrapes"`
+	ScrapePool    string `json:"scrapePool,omitempty" jsonschema:"description=Optionally\\, only list the targets of scrape pools whose name contains this string\\, usually the job name"`
+	Limit         int    `json:"limit,omitempty" jsonschema:"description=Optionally\\, the maximum number of targets to list (default 100). The counts of the result cover all the targets"`
+}
+
+// PrometheusTarget is an active scrape target of Prometheus.
+type PrometheusTarget struct {
+	ScrapePool string            `json:"scrapePool"`
+	ScrapeURL  string            `json:"scrapeUrl"`
+	Labels     map[string]string `json:"labels"`
+	Health     string            `json:"health"`
+	LastError  string            `json:"lastError,omitempty"`
+	LastScrape time.Time         `json:"lastScrape"`
+	// LastScrapeDurationSeconds is the duration of the last scrape.
+	LastScrapeDurationSeconds float64 `json:"lastScrapeDurationSeconds"`
+}
+
+type ListPrometheusTargetsResult struct {
+	// Up, Down and Unknown count the active targets by health, and Dropped
+	// counts the discovered targets dropped by relabelling.
+	Up      int `json:"up"`
+	Down    int `json:"down"`
+	Unknown int `json:"unknown"`
+	Dropped int `json:"dropped"`
+	// Targets are the matching active targets, with the failing targets
+	// first. Truncated is set if some were left out because of the limit.
+	Targets   []PrometheusTarget `json:"targets"`
+	Truncated bool               `json:"truncated,omitempty"`
+}
+
+func listPrometheusTargets(ctx context.Context, args ListPrometheusTargetsParams) (*ListPrometheusTargetsResult, error) {
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultPrometheusTargetsLimit
+	}
+	promClient, err := promClientFromContext(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+	targets, err := promClient.Targets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing Prometheus targets: %w", err)
+	}
+	result := &ListPrometheusTargetsResult{Dropped: len(targets.Dropped), Targets: []PrometheusTarget{}}
+	var healthy []PrometheusTarget
+	for _, t := range targets.Active {
+		switch t.Health {
+		case promv1.HealthGood:
+			result.Up++
+		case promv1.HealthBad:
+			result.Down++
+		default:
+			result.Unknown++
+		}
+		if (args.Health != "" && string(t.Health) != args.Health) || !strings.Contains(t.ScrapePool, args.ScrapePool) {
+			continue
+		}
+		target := PrometheusTarget{
+			ScrapePool:                t.ScrapePool,
+			ScrapeURL:                 t.ScrapeURL,
+			Labels:                    labelSetMap(t.Labels),
+			Health:                    string(t.Health),
+			LastError:                 t.LastError,
+			LastScrape:                t.LastScrape,
+			LastScrapeDurationSeconds: t.LastScrapeDuration,
+		}
+		if t.Health == promv1.HealthGood {
+			healthy = append(healthy, target)
+		} else {
+			result.Targets = append(result.Targets, target)
+		}
+	}
+	result.Targets = append(result.Targets, healthy...)
+	if len(result.Targets) > limit {
+		result.Targets = result.Targets[:limit]
+		result.Truncated = true
+	}
+	return result, nil
+}
+
+var ListPrometheusTargets = mcpgrafana.MustTool(
+	"grafana_list_prometheus_targets",
+	"List the scrape targets of a Prometheus datasource, from its `/api/v1/targets` endpoint, with their health, last error and time and duration of their last scrape, and counts of targets by health. Failing targets come first. Use it to find out why a metric is missing, e.g. with `health: down` to list the targets which fail to be scraped. Mimir and other remote storage backends don't scrape targets and don't support it.",
+	listPrometheusTargets,
+	mcp.WithTitleAnnotation("List Prometheus targets"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type GetPrometheusTSDBStatusParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the Prometheus datasource"`
+	Limit         int    `json:"limit,omitempty" jsonschema:"description=Optionally\\, the number of items to return in each list of top series counts (default 10)"`
+}
+
+func getPrometheusTSDBStatus(ctx context.Context, args GetPrometheusTSDBStatusParams) (*promv1.TSDBResult, error) {
+	promClient, err := promClientFromContext(ctx, args.DatasourceUID)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus client: %w", err)
+	}
+	var opts []promv1.Option
+	if args.Limit > 0 {
+		opts = append(opts, promv1.WithLimit(uint64(args.Limit)))
+	}
+	status, err := promClient.TSDB(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("getting Prometheus TSDB status: %w", err)
+	}
+	return &status, nil
+}
+
+var GetPrometheusTSDBStatus = mcpgrafana.MustTool(
+	"grafana_get_prometheus_tsdb_status",
+	"Get the cardinality statistics of the head block of a Prometheus datasource, from its `/api/v1/status/tsdb` endpoint: the number of series, label pairs and chunks, and the metric names with the most series, the label names with the most values, the label names using the most memory and the label pairs with the most series. Use it to diagnose cardinality problems, e.g. which metric or label causes an explosion of series.",
+	getPrometheusTSDBStatus,
+	mcp.WithTitleAnnotation("Get Prometheus TSDB status"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,48 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListPrometheusTargets(t *testing.T) {
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/datasources/uid/prom": jsonBody(`{"uid": "prom", "type": "prometheus"}`),
+		"/api/datasources/proxy/uid/prom/api/v1/targets": jsonBody(`{"status": "success", "data": {
+			"activeTargets": [
+				{"scrapePool": "api", "scrapeUrl": "http://api-1:8080/metrics", "labels": {"job": "api", "instance": "api-1"}, "health": "up", "lastError": "", "lastScrape": "2025-01-06T00:00:00Z", "lastScrapeDuration": 0.05},
+				{"scrapePool": "api", "scrapeUrl": "http://api-2:8080/metrics", "labels": {"job": "api", "instance": "api-2"}, "health": "down", "lastError": "connection refused", "lastScrape": "2025-01-06T00:00:00Z", "lastScrapeDuration": 0.001},
+				{"scrapePool": "node", "scrapeUrl": "http://node:9100/metrics", "labels": {"job": "node"}, "health": "unknown", "lastScrape": "0001-01-01T00:00:00Z"}
+			],
+			"droppedTargets": [{"discoveredLabels": {"__address__": "x"}}]
+		}}`),
+	})
+	ctx := fakeGrafanaContext(server)
+
+	result, err := listPrometheusTargets(ctx, ListPrometheusTargetsParams{DatasourceUID: "prom"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Up)
+	assert.Equal(t, 1, result.Down)
+	assert.Equal(t, 1, result.Unknown)
+	assert.Equal(t, 1, result.Dropped)
+	require.Len(t, result.Targets, 3)
+	// Failing targets come first.
+	assert.Equal(t, "connection refused", result.Targets[0].LastError)
+	assert.Equal(t, "up", result.Targets[2].Health)
+
+	result, err = listPrometheusTargets(ctx, ListPrometheusTargetsParams{DatasourceUID: "prom", ScrapePool: "api", Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, result.Targets, 1)
+	assert.Equal(t, "http://api-2:8080/metrics", result.Targets[0].ScrapeURL)
+	assert.True(t, result.Truncated)
+
+	result, err = listPrometheusTargets(ctx, ListPrometheusTargetsParams{DatasourceUID: "prom", Health: "up"})
+	require.NoError(t, err)
+	require.Len(t, result.Targets, 1)
+	assert.Equal(t, map[string]string{"job": "api", "instance": "api-1"}, result.Targets[0].Labels)
+}
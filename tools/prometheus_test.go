@@ -7,6 +7,7 @@ package tools
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -272,6 +273,36 @@ func TestPrometheusQueries(t *testing.T) {
 		assert.Equal(t, scalar[0].Metric["__name__"], model.LabelValue("up"))
 	})
 
+	t.Run("query prometheus instant as CSV", func(t *testing.T) {
+		ctx := newTestContext()
+		result, err := queryPrometheusWithFormat(ctx, QueryPrometheusParams{
+			DatasourceUID: "prometheus",
+			Expr:          "up",
+			StartTime:     "now",
+			QueryType:     "instant",
+			Format:        "csv",
+		})
+		require.NoError(t, err)
+		csv := result.(string)
+		assert.True(t, strings.HasPrefix(csv, "timestamp,value,__name__,"), csv)
+		assert.Contains(t, csv, ",1,up,")
+	})
+
+	t.Run("query prometheus instant as a Markdown table", func(t *testing.T) {
+		ctx := newTestContext()
+		result, err := queryPrometheusWithFormat(ctx, QueryPrometheusParams{
+			DatasourceUID: "prometheus",
+			Expr:          "up",
+			StartTime:     "now",
+			QueryType:     "instant",
+			OutputFormat:  "markdown-table",
+		})
+		require.NoError(t, err)
+		table := result.(string)
+		assert.True(t, strings.HasPrefix(table, "| timestamp | value | __name__ |"), table)
+		assert.Contains(t, table, " | 1 | up |")
+	})
+
 	t.Run("query prometheus instant with relative timestamps", func(t *testing.T) {
 		ctx := newTestContext()
 		beforeQuery := model.TimeFromUnix(time.Now().Unix())
@@ -1,9 +1,13 @@
 package tools
 
 import (
+	"context"
+	"encoding/json"
+	"math"
 	"testing"
 	"time"
 
+	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -115,3 +119,84 @@ func TestParseRelativeTime(t *testing.T) {
 		})
 	}
 }
+
+func TestTrimPrometheusResult(t *testing.T) {
+	series := func(pod string) *model.SampleStream {
+		return &model.SampleStream{
+			Metric: model.Metric{"__name__": "up", "job": "api", "pod": model.LabelValue(pod)},
+			Values: []model.SamplePair{{Timestamp: 0, Value: 1}},
+		}
+	}
+	matrix := model.Matrix{series("c"), series("a"), series("b")}
+
+	result, omitted := trimPrometheusResult(context.Background(), matrix, nil, 2)
+	assert.Equal(t, 1, omitted)
+	require.Len(t, result.(model.Matrix), 2)
+	assert.Equal(t, model.LabelValue("a"), result.(model.Matrix)[0].Metric["pod"], "series should be sorted before they are truncated")
+	assert.Equal(t, model.LabelValue("b"), result.(model.Matrix)[1].Metric["pod"])
+
+	vector := model.Vector{{Metric: model.Metric{"job": "web"}}, {Metric: model.Metric{"job": "api", "pod": "a"}}}
+	result, omitted = trimPrometheusResult(context.Background(), vector, nil, 0)
+	assert.Equal(t, 0, omitted)
+	assert.Len(t, result.(model.Vector), 2)
+	result, omitted = trimPrometheusResult(context.Background(), vector, []string{"pod"}, 5)
+	assert.Equal(t, 0, omitted)
+	assert.Equal(t, model.Metric{"job": "api"}, result.(model.Vector)[0].Metric)
+
+	scalar := &model.Scalar{Value: 1}
+	result, omitted = trimPrometheusResult(context.Background(), scalar, []string{"pod"}, 1)
+	assert.Equal(t, scalar, result)
+	assert.Equal(t, 0, omitted)
+}
+
+func TestDownsampleMatrix(t *testing.T) {
+	series := func() model.Matrix {
+		s := &model.SampleStream{Metric: model.Metric{"job": "api"}}
+		for i, v := range []float64{1, 5, 3, math.NaN(), 2, 8, 4} {
+			s.Values = append(s.Values, model.SamplePair{Timestamp: model.Time(i * 1000), Value: model.SampleValue(v)})
+		}
+		return model.Matrix{s}
+	}
+	values := func(m model.Matrix) []float64 {
+		var vs []float64
+		for _, p := range m[0].Values {
+			vs = append(vs, float64(p.Value))
+		}
+		return vs
+	}
+
+	// 7 samples into at most 3 buckets of 3 samples, the last one shorter. The
+	// NaN value of the second bucket is ignored.
+	assert.Equal(t, []float64{3, 5, 4}, values(downsampleMatrix(series(), 3, "avg")))
+	assert.Equal(t, []float64{5, 8, 4}, values(downsampleMatrix(series(), 3, "max")))
+	assert.Equal(t, []float64{1, 2, 4}, values(downsampleMatrix(series(), 3, "min")))
+	assert.Equal(t, []model.Time{0, 3000, 6000}, []model.Time{
+		downsampleMatrix(series(), 3, "avg")[0].Values[0].Timestamp,
+		downsampleMatrix(series(), 3, "avg")[0].Values[1].Timestamp,
+		downsampleMatrix(series(), 3, "avg")[0].Values[2].Timestamp,
+	})
+	// Series with few enough samples are left as they are.
+	assert.Len(t, downsampleMatrix(series(), 7, "avg")[0].Values, 7)
+	assert.Len(t, downsampleMatrix(series(), 0, "avg")[0].Values, 7)
+
+	_, err := validateDownsample(QueryPrometheusParams{Downsample: "median"})
+	assert.ErrorContains(t, err, `invalid downsample function "median"`)
+	_, err = validateDownsample(QueryPrometheusParams{MaxDataPoints: -1})
+	assert.ErrorContains(t, err, "maxDataPoints must not be negative")
+}
+
+func TestSummarizeMatrix(t *testing.T) {
+	matrix := model.Matrix{
+		{Metric: model.Metric{"job": "api"}, Values: []model.SamplePair{{Timestamp: 1000, Value: 4}, {Timestamp: 2000, Value: model.SampleValue(math.NaN())}, {Timestamp: 3000, Value: 2}}},
+		{Metric: model.Metric{"job": "web"}, Values: []model.SamplePair{{Timestamp: 1000, Value: model.SampleValue(math.NaN())}}},
+	}
+	summaries := summarizeMatrix(matrix)
+	require.Len(t, summaries, 2)
+	data, err := json.Marshal(summaries[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"metric": {"job": "api"}, "count": 3, "min": "2", "max": "4", "avg": "3", "first": "4", "last": "2", "firstTimestamp": 1, "lastTimestamp": 3}`, string(data))
+	// Statistics of series without values are NaN, which can be encoded.
+	data, err = json.Marshal(summaries[1])
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"avg":"NaN"`)
+}
@@ -0,0 +1,57 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryPrometheusWarnings(t *testing.T) {
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/datasources/uid/prom": jsonBody(`{"uid": "prom", "type": "prometheus"}`),
+		"/api/datasources/proxy/uid/prom/api/v1/query": jsonBody(`{"status": "success",
+			"data": {"resultType": "vector", "result": [{"metric": {"job": "api"}, "value": [1736121600, "1"]}]},
+			"warnings": ["partial result: store gateway unavailable", "partial result: store gateway unavailable"]}`),
+	})
+	ctx := fakeGrafanaContext(server)
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = QueryPrometheus.Tool.Name
+	request.Params.Arguments = map[string]any{"datasourceUid": "prom", "expr": "up", "startTime": "now", "queryType": "instant"}
+	result, err := QueryPrometheus.Handler(ctx, request)
+	require.NoError(t, err)
+	// Repeated warnings are only reported once.
+	require.Len(t, result.Content, 2)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, `"job":"api"`)
+	assert.Equal(t, "Prometheus warning, the result may be incomplete: partial result: store gateway unavailable", result.Content[1].(mcp.TextContent).Text)
+	assert.Equal(t, []string{"partial result: store gateway unavailable"}, result.Meta[PrometheusWarningsMetaField])
+}
+
+func TestQueryPrometheusDropLabelsDuplicateSeries(t *testing.T) {
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/datasources/uid/prom": jsonBody(`{"uid": "prom", "type": "prometheus"}`),
+		"/api/datasources/proxy/uid/prom/api/v1/query": jsonBody(`{"status": "success",
+			"data": {"resultType": "vector", "result": [
+				{"metric": {"job": "api", "pod": "a"}, "value": [1736121600, "1"]},
+				{"metric": {"job": "api", "pod": "b"}, "value": [1736121600, "2"]},
+				{"metric": {"job": "web", "pod": "c"}, "value": [1736121600, "3"]}
+			]}}`),
+	})
+	ctx := fakeGrafanaContext(server)
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = QueryPrometheus.Tool.Name
+	request.Params.Arguments = map[string]any{"datasourceUid": "prom", "expr": "up", "startTime": "now", "queryType": "instant", "dropLabels": []any{"pod"}}
+	result, err := QueryPrometheus.Handler(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, result.Content, 2)
+	// Series with the same labels are kept rather than merged.
+	assert.Equal(t, 2, strings.Count(result.Content[0].(mcp.TextContent).Text, `"job":"api"`))
+	assert.Equal(t, "1 series have the same labels as another series after dropping pod; aggregate them in the query, e.g. with sum without (pod), to merge them.", result.Content[1].(mcp.TextContent).Text)
+}
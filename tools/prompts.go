@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// Prompt is an MCP prompt describing a common workflow in terms of the tools
+// provided by this server.
+type Prompt struct {
+	Prompt mcp.Prompt
+	// Categories are the tool categories the prompt relies on. The prompt is
+	// only registered if all of them are enabled.
+	Categories []string
+	// Render returns the instructions for the workflow given the prompt's
+	// arguments. Required arguments are guaranteed to be present.
+	Render func(args map[string]string) string
+}
+
+// Register adds the Prompt to the given MCPServer if all of the categories
+// it relies on are enabled.
+func (p Prompt) Register(s *server.MCPServer) {
+	for _, c := range p.Categories {
+		if !mcpgrafana.CategoryEnabled(c) {
+			return
+		}
+	}
+	s.AddPrompt(p.Prompt, p.handle)
+}
+
+func (p Prompt) handle(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	args := request.Params.Arguments
+	for _, arg := range p.Prompt.Arguments {
+		if arg.Required && strings.TrimSpace(args[arg.Name]) == "" {
+			return nil, fmt.Errorf("%s: argument %s is required", p.Prompt.Name, arg.Name)
+		}
+	}
+	return mcp.NewGetPromptResult(
+		p.Prompt.Description,
+		[]mcp.PromptMessage{
+			mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(p.Render(args))),
+		},
+	), nil
+}
+
+// argOrDefault returns the named argument, or def if it is not set.
+func argOrDefault(args map[string]string, name, def string) string {
+	if v := strings.TrimSpace(args[name]); v != "" {
+		return v
+	}
+	return def
+}
+
+// optionalStep returns step if the given category is enabled, so that
+// prompts only refer to tools that are available.
+func optionalStep(category, step string) string {
+	if mcpgrafana.CategoryEnabled(category) {
+		return step
+	}
+	return ""
+}
+
+// numberSteps formats the non-empty steps as a numbered list.
+func numberSteps(steps ...string) string {
+	var b strings.Builder
+	n := 0
+	for _, s := range steps {
+		if s == "" {
+			continue
+		}
+		n++
+		fmt.Fprintf(&b, "%d. %s\n", n, s)
+	}
+	return b.String()
+}
+
+var InvestigateAlert = Prompt{
+	Prompt: mcp.NewPrompt(
+		"grafana_investigate_alert",
+		mcp.WithPromptDescription("Investigate why a Grafana alert rule is firing and summarize the likely cause."),
+		mcp.WithArgument("alert_uid", mcp.RequiredArgument(), mcp.ArgumentDescription("The UID of the alert rule to investigate")),
+		mcp.WithArgument("time_range", mcp.ArgumentDescription("How far back to look, e.g. '1h' (defaults to 1h)")),
+	),
+	Categories: []string{"alerting"},
+	Render: func(args map[string]string) string {
+		timeRange := argOrDefault(args, "time_range", "1h")
+		return fmt.Sprintf("Investigate the Grafana alert rule with UID %q over the last %s.\n\n", args["alert_uid"], timeRange) +
+			numberSteps(
+				fmt.Sprintf("Use %s to fetch the rule's configuration, including its queries, condition, labels and annotations.", GetAlertRuleByUID.Tool.Name),
+				fmt.Sprintf("Use %s to find the rule's current state and the labels of any firing alerts.", ListAlertRules.Tool.Name),
+				optionalStep("prometheus", fmt.Sprintf("For Prometheus queries in the rule, use %s to evaluate them over the time range and identify when and where the threshold was crossed.", QueryPrometheus.Tool.Name)),
+				optionalStep("loki", fmt.Sprintf("Use %s to look for errors in logs from the affected services around the time the alert started firing.", QueryLokiLogs.Tool.Name)),
+				optionalStep("search", fmt.Sprintf("Use %s to find dashboards related to the affected services, and check the dashboard linked in the rule's annotations if there is one.", SearchDashboards.Tool.Name)),
+				optionalStep("sift", fmt.Sprintf("Use %s to check for error patterns in the affected services' logs.", FindErrorPatternLogs.Tool.Name)),
+				optionalStep("oncall", fmt.Sprintf("Use %s to find who is currently on call if escalation is needed.", GetCurrentOnCallUsers.Tool.Name)),
+				"Summarize why the alert is firing, the likely root cause, the impact, and suggested next steps. Clearly distinguish evidence from speculation.",
+			)
+	},
+}
+
+var AnalyzeDashboard = Prompt{
+	Prompt: mcp.NewPrompt(
+		"grafana_analyze_dashboard",
+		mcp.WithPromptDescription("Analyze a Grafana dashboard, checking its panels and queries and reporting anything unusual."),
+		mcp.WithArgument("dashboard_uid", mcp.RequiredArgument(), mcp.ArgumentDescription("The UID of the dashboard to analyze")),
+		mcp.WithArgument("time_range", mcp.ArgumentDescription("How far back to look, e.g. '6h' (defaults to 1h)")),
+	),
+	Categories: []string{"dashboard"},
+	Render: func(args map[string]string) string {
+		timeRange := argOrDefault(args, "time_range", "1h")
+		return fmt.Sprintf("Analyze the Grafana dashboard with UID %q over the last %s.\n\n", args["dashboard_uid"], timeRange) +
+			numberSteps(
+				fmt.Sprintf("Use %s to get an overview of the dashboard's panels and template variables.", GetDashboardSummaries.Tool.Name),
+				fmt.Sprintf("Use %s to get the queries and datasources of each panel.", GetDashboardPanelQueries.Tool.Name),
+				optionalStep("datasource", fmt.Sprintf("If a panel's datasource is a template variable, use %s to find a matching datasource.", ListDatasources.Tool.Name)),
+				optionalStep("prometheus", fmt.Sprintf("Use %s to run the Prometheus queries over the time range, substituting template variables with sensible values.", QueryPrometheus.Tool.Name)),
+				optionalStep("loki", fmt.Sprintf("Use %s to run the Loki queries over the time range.", QueryLokiLogs.Tool.Name)),
+				"Summarize what the dashboard monitors and its current state, and point out anomalies, panels returning no data, and queries that look broken or inefficient.",
+			)
+	},
+}
+
+var FindErrorLogs = Prompt{
+	Prompt: mcp.NewPrompt(
+		"grafana_find_error_logs",
+		mcp.WithPromptDescription("Find and summarize recent error logs for a service using Loki."),
+		mcp.WithArgument("service", mcp.RequiredArgument(), mcp.ArgumentDescription("The name of the service, as it appears in log labels")),
+		mcp.WithArgument("time_range", mcp.ArgumentDescription("How far back to look, e.g. '30m' (defaults to 1h)")),
+		mcp.WithArgument("datasource_uid", mcp.ArgumentDescription("The UID of the Loki datasource to use (defaults to discovering one)")),
+	),
+	Categories: []string{"loki"},
+	Render: func(args map[string]string) string {
+		timeRange := argOrDefault(args, "time_range", "1h")
+		datasourceStep := fmt.Sprintf("Use the Loki datasource with UID %q.", args["datasource_uid"])
+		if args["datasource_uid"] == "" {
+			datasourceStep = optionalStep("datasource", fmt.Sprintf("Use %s to find the Loki datasources, and pick the one most likely to contain the service's logs.", ListDatasources.Tool.Name))
+		}
+		return fmt.Sprintf("Find error logs for the service %q over the last %s.\n\n", args["service"], timeRange) +
+			numberSteps(
+				datasourceStep,
+				fmt.Sprintf("Use %s and %s to find the label identifying the service (e.g. 'app', 'service_name' or 'job') and its value for %q.", ListLokiLabelNames.Tool.Name, ListLokiLabelValues.Tool.Name, args["service"]),
+				fmt.Sprintf("Use %s to check how many log lines the service produces before querying them.", QueryLokiStats.Tool.Name),
+				fmt.Sprintf("Use %s with a line filter such as `|~ \"(?i)(error|exception|fatal|panic)\"` to fetch recent error logs.", QueryLokiLogs.Tool.Name),
+				optionalStep("sift", fmt.Sprintf("Use %s to compare error patterns with the period before.", FindErrorPatternLogs.Tool.Name)),
+				"Group the errors by message pattern, and summarize the most frequent and most recent ones, when they started, and their likely cause.",
+			)
+	},
+}
+
+// AddPrompts registers the prompts whose tool categories are enabled. It
+// must be called after the tools have been registered.
+func AddPrompts(s *server.MCPServer) {
+	InvestigateAlert.Register(s)
+	AnalyzeDashboard.Register(s)
+	FindErrorLogs.Register(s)
+}
@@ -0,0 +1,56 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+func TestPrompts(t *testing.T) {
+	t.Run("missing required argument", func(t *testing.T) {
+		request := mcp.GetPromptRequest{}
+		request.Params.Arguments = map[string]string{"time_range": "1h"}
+		_, err := InvestigateAlert.handle(context.Background(), request)
+		require.Error(t, err)
+	})
+
+	t.Run("renders instructions", func(t *testing.T) {
+		request := mcp.GetPromptRequest{}
+		request.Params.Arguments = map[string]string{"service": "checkout", "datasource_uid": "loki-uid"}
+		result, err := FindErrorLogs.handle(context.Background(), request)
+		require.NoError(t, err)
+		require.Len(t, result.Messages, 1)
+		assert.Equal(t, mcp.RoleUser, result.Messages[0].Role)
+		text := result.Messages[0].Content.(mcp.TextContent).Text
+		assert.Contains(t, text, `"checkout" over the last 1h`)
+		assert.Contains(t, text, `"loki-uid"`)
+		assert.Contains(t, text, QueryLokiLogs.Tool.Name)
+	})
+
+	t.Run("registered only if categories are enabled", func(t *testing.T) {
+		s := server.NewMCPServer("test", "0.0.0")
+		mcpgrafana.RegisterCategory(s, "test-prompts", false, func(*server.MCPServer) {})
+		p := Prompt{
+			Prompt:     mcp.NewPrompt("test_prompt"),
+			Categories: []string{"test-prompts"},
+			Render:     func(map[string]string) string { return "" },
+		}
+		p.Register(s)
+		_, ok := s.HandleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"prompts/get","params":{"name":"test_prompt"}}`)).(mcp.JSONRPCError)
+		assert.True(t, ok, "prompt should not be registered")
+
+		mcpgrafana.RegisterCategory(s, "test-prompts", true, func(*server.MCPServer) {})
+		p.Register(s)
+		_, ok = s.HandleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"prompts/get","params":{"name":"test_prompt"}}`)).(mcp.JSONRPCResponse)
+		assert.True(t, ok, "prompt should be registered")
+	})
+}
@@ -0,0 +1,313 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// PublicDashboard is the public sharing of a dashboard, through which
+// anyone with its URL, or the recipients it was shared with, can view the
+// dashboard without signing in.
+type PublicDashboard struct {
+	UID          string `json:"uid"`
+	DashboardUID string `json:"dashboardUid"`
+	Title        string `json:"title,omitempty"`
+	// IsEnabled is false for paused public dashboards, whose URL doesn't
+	// work until they are enabled again.
+	IsEnabled bool   `json:"isEnabled"`
+	URL       string `json:"url,omitempty"`
+	// The settings are only returned for the public dashboard of a given
+	// dashboard.
+	Share                string `json:"share,omitempty"`
+	TimeSelectionEnabled *bool  `json:"timeSelectionEnabled,omitempty"`
+	AnnotationsEnabled   *bool  `json:"annotationsEnabled,omitempty"`
+	CreatedAt            string `json:"createdAt,omitempty"`
+	UpdatedAt            string `json:"updatedAt,omitempty"`
+}
+
+// publicDashboardURL returns the URL of a public dashboard.
+func publicDashboardURL(ctx context.Context, accessToken string) string {
+	if accessToken == "" {
+		return ""
+	}
+	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+	return strings.TrimRight(cfg.URL, "/") + "/public-dashboards/" + accessToken
+}
+
+func newPublicDashboard(ctx context.Context, p *models.PublicDashboard) *PublicDashboard {
+	pd := &PublicDashboard{
+		UID:                  p.UID,
+		DashboardUID:         p.DashboardUID,
+		IsEnabled:            p.IsEnabled,
+		URL:                  publicDashboardURL(ctx, p.AccessToken),
+		Share:                string(p.Share),
+		TimeSelectionEnabled: &p.TimeSelectionEnabled,
+		AnnotationsEnabled:   &p.AnnotationsEnabled,
+	}
+	if t := time.Time(p.CreatedAt); !t.IsZero() {
+		pd.CreatedAt = t.UTC().Format(time.RFC3339)
+	}
+	if t := time.Time(p.UpdatedAt); !t.IsZero() {
+		pd.UpdatedAt = t.UTC().Format(time.RFC3339)
+	}
+	return pd
+}
+
+// getPublicDashboard returns the public dashboard of a dashboard, or nil if
+// it has none.
+func getPublicDashboard(ctx context.Context, dashboardUID string) (*models.PublicDashboard, error) {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.DashboardPublic.GetPublicDashboard(dashboardUID)
+	if err != nil {
+		if err := classifyAPIError(err); errors.Is(err, errNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get public dashboard of dashboard %s: %w", dashboardUID, classifyAPIError(err))
+	}
+	if resp.Payload == nil || resp.Payload.UID == "" {
+		// Older versions of Grafana return an empty public dashboard.
+		return nil, nil
+	}
+	return resp.Payload, nil
+}
+
+type ListPublicDashboardsParams struct {
+	DashboardUID string `json:"dashboardUid,omitempty" jsonschema:"description=Optionally\\, the UID of a dashboard to return the public dashboard and sharing settings of. Otherwise all public dashboards of the organization are listed"`
+}
+
+func listPublicDashboards(ctx context.Context, args ListPublicDashboardsParams) ([]*PublicDashboard, error) {
+	if args.DashboardUID != "" {
+		p, err := getPublicDashboard(ctx, args.DashboardUID)
+		if err != nil {
+			return nil, err
+		}
+		if p == nil {
+			return []*PublicDashboard{}, nil
+		}
+		return []*PublicDashboard{newPublicDashboard(ctx, p)}, nil
+	}
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.DashboardPublic.ListPublicDashboards()
+	if err != nil {
+		return nil, fmt.Errorf("list public dashboards: %w", classifyAPIError(err))
+	}
+	dashboards := make([]*PublicDashboard, 0, len(resp.Payload.PublicDashboards))
+	for _, p := range resp.Payload.PublicDashboards {
+		dashboards = append(dashboards, &PublicDashboard{
+			UID:          p.UID,
+			DashboardUID: p.DashboardUID,
+			Title:        p.Title,
+			IsEnabled:    p.IsEnabled,
+			URL:          publicDashboardURL(ctx, p.AccessToken),
+		})
+	}
+	return dashboards, nil
+}
+
+var ListPublicDashboards = mcpgrafana.MustTool(
+	"grafana_list_public_dashboards",
+	"List the dashboards which are shared publicly, with their public URL and whether they are enabled or paused. Anyone with the URL of an enabled public dashboard can view it without signing in, unless it is only shared with email recipients. Use it to audit which dashboards are exposed. Given a dashboard UID, returns the public dashboard of that dashboard with its sharing settings, or an empty list if it isn't shared publicly.",
+	listPublicDashboards,
+	mcp.WithTitleAnnotation("List public dashboards"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+// publicDashboardSettings are the settings of a public dashboard which are
+// changed. Unset settings are kept as they are.
+type publicDashboardSettings struct {
+	IsEnabled            *bool  `json:"isEnabled,omitempty"`
+	TimeSelectionEnabled *bool  `json:"timeSelectionEnabled,omitempty"`
+	AnnotationsEnabled   *bool  `json:"annotationsEnabled,omitempty"`
+	Share                string `json:"share,omitempty"`
+}
+
+func (s publicDashboardSettings) validate() error {
+	if s.Share != "" && s.Share != "public" && s.Share != "email" {
+		return fmt.Errorf("invalid share %q: must be public or email", s.Share)
+	}
+	return nil
+}
+
+// updatePublicDashboard changes the settings of a public dashboard. The
+// request is made directly, as the OpenAPI client omits settings which are
+// false, so that they couldn't be disabled.
+func updatePublicDashboard(ctx context.Context, p *models.PublicDashboard, settings publicDashboardSettings) (*models.PublicDashboard, error) {
+	body, err := json.Marshal(settings)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling settings: %w", err)
+	}
+	c, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	path := fmt.Sprintf("/api/dashboards/uid/%s/public-dashboards/%s", url.PathEscape(p.DashboardUID), url.PathEscape(p.UID))
+	resp, err := c.doRequest(ctx, http.MethodPatch, path, nil, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("update public dashboard of dashboard %s: %w", p.DashboardUID, err)
+	}
+	defer resp.Body.Close()
+	var updated models.PublicDashboard
+	if err := decodeJSONResponse(resp.Body, &updated); err != nil {
+		return nil, fmt.Errorf("update public dashboard of dashboard %s: %w", p.DashboardUID, err)
+	}
+	return &updated, nil
+}
+
+type EnablePublicDashboardParams struct {
+	DashboardUID         string `json:"dashboardUid" jsonschema:"required,description=The UID of the dashboard to share publicly"`
+	TimeSelectionEnabled *bool  `json:"timeSelectionEnabled,omitempty" jsonschema:"description=Optionally\\, whether viewers can change the time range. Disabled by default for new public dashboards"`
+	AnnotationsEnabled   *bool  `json:"annotationsEnabled,omitempty" jsonschema:"description=Optionally\\, whether annotations are shown. Disabled by default for new public dashboards"`
+	Share                string `json:"share,omitempty" jsonschema:"enum=public,enum=email,description=Optionally\\, whether anyone with the URL can view the dashboard (public\\, the default for new public dashboards) or only the email recipients it is shared with (email\\, Grafana Cloud only)"`
+}
+
+func enablePublicDashboard(ctx context.Context, args EnablePublicDashboardParams) (*PublicDashboard, error) {
+	enabled := true
+	settings := publicDashboardSettings{
+		IsEnabled:            &enabled,
+		TimeSelectionEnabled: args.TimeSelectionEnabled,
+		AnnotationsEnabled:   args.AnnotationsEnabled,
+		Share:                args.Share,
+	}
+	if err := settings.validate(); err != nil {
+		return nil, err
+	}
+	p, err := getPublicDashboard(ctx, args.DashboardUID)
+	if err != nil {
+		return nil, err
+	}
+	if p != nil {
+		updated, err := updatePublicDashboard(ctx, p, settings)
+		if err != nil {
+			return nil, err
+		}
+		return newPublicDashboard(ctx, updated), nil
+	}
+
+	body := &models.PublicDashboardDTO{IsEnabled: true, Share: models.ShareType(args.Share)}
+	if args.TimeSelectionEnabled != nil {
+		body.TimeSelectionEnabled = *args.TimeSelectionEnabled
+	}
+	if args.AnnotationsEnabled != nil {
+		body.AnnotationsEnabled = *args.AnnotationsEnabled
+	}
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	resp, err := c.DashboardPublic.CreatePublicDashboard(args.DashboardUID, body)
+	if err != nil {
+		return nil, fmt.Errorf("create public dashboard of dashboard %s: %w", args.DashboardUID, classifyAPIError(err))
+	}
+	return newPublicDashboard(ctx, resp.Payload), nil
+}
+
+var EnablePublicDashboard = mcpgrafana.MustTool(
+	"grafana_enable_public_dashboard",
+	"Share a dashboard publicly, so that anyone with the returned URL can view it without signing in, or enable its public dashboard again if it was paused. The URL of a paused public dashboard stays the same. Only use it after confirmation from the user, as it exposes the dashboard's data outside of Grafana.",
+	enablePublicDashboard,
+	mcp.WithTitleAnnotation("Enable public dashboard"),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithIdempotentHintAnnotation(true),
+)
+
+type ConfigurePublicDashboardParams struct {
+	DashboardUID         string `json:"dashboardUid" jsonschema:"required,description=The UID of the dashboard whose public dashboard to configure"`
+	IsEnabled            *bool  `json:"isEnabled,omitempty" jsonschema:"description=Optionally\\, false to pause the public dashboard\\, so that its URL stops working until it is enabled again\\, or true to enable it"`
+	TimeSelectionEnabled *bool  `json:"timeSelectionEnabled,omitempty" jsonschema:"description=Optionally\\, whether viewers can change the time range"`
+	AnnotationsEnabled   *bool  `json:"annotationsEnabled,omitempty" jsonschema:"description=Optionally\\, whether annotations are shown"`
+	Share                string `json:"share,omitempty" jsonschema:"enum=public,enum=email,description=Optionally\\, whether anyone with the URL can view the dashboard (public) or only the email recipients it is shared with (email\\, Grafana Cloud only)"`
+}
+
+func configurePublicDashboard(ctx context.Context, args ConfigurePublicDashboardParams) (*PublicDashboard, error) {
+	settings := publicDashboardSettings{
+		IsEnabled:            args.IsEnabled,
+		TimeSelectionEnabled: args.TimeSelectionEnabled,
+		AnnotationsEnabled:   args.AnnotationsEnabled,
+		Share:                args.Share,
+	}
+	if err := settings.validate(); err != nil {
+		return nil, err
+	}
+	if settings == (publicDashboardSettings{}) {
+		return nil, fmt.Errorf("no setting to change was given")
+	}
+	p, err := getPublicDashboard(ctx, args.DashboardUID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("dashboard %s is not shared publicly: use grafana_enable_public_dashboard to share it", args.DashboardUID)
+	}
+	updated, err := updatePublicDashboard(ctx, p, settings)
+	if err != nil {
+		return nil, err
+	}
+	return newPublicDashboard(ctx, updated), nil
+}
+
+var ConfigurePublicDashboard = mcpgrafana.MustTool(
+	"grafana_configure_public_dashboard",
+	"Change the settings of the public dashboard of a dashboard: pause or enable it, allow viewers to change the time range, show annotations, or share it with anyone with the URL or only with email recipients. Settings which are not given are kept. Returns the public dashboard after the change.",
+	configurePublicDashboard,
+	mcp.WithTitleAnnotation("Configure public dashboard"),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithIdempotentHintAnnotation(true),
+)
+
+type RevokePublicDashboardParams struct {
+	DashboardUID      string `json:"dashboardUid" jsonschema:"required,description=The UID of the dashboard whose public dashboard to revoke"`
+	ConfirmationToken string `json:"confirmationToken,omitempty" jsonschema:"description=The token returned by a previous call for this dashboard. Without it nothing is revoked and the impact of the revocation is returned with a token"`
+}
+
+// RevokePublicDashboardResult describes a public dashboard which was
+// revoked, or would be revoked if the revocation was confirmed.
+type RevokePublicDashboardResult struct {
+	Revoked         bool             `json:"revoked"`
+	PublicDashboard *PublicDashboard `json:"publicDashboard"`
+	Confirmation    *Confirmation    `json:"confirmation,omitempty"`
+}
+
+func revokePublicDashboard(ctx context.Context, args RevokePublicDashboardParams) (*RevokePublicDashboardResult, error) {
+	p, err := getPublicDashboard(ctx, args.DashboardUID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("dashboard %s is not shared publicly", args.DashboardUID)
+	}
+	pd := newPublicDashboard(ctx, p)
+	if args.ConfirmationToken == "" {
+		impact := []string{fmt.Sprintf("Deletes the public dashboard of the dashboard %s, whose URL %s stops working for good.", args.DashboardUID, pd.URL)}
+		return &RevokePublicDashboardResult{
+			PublicDashboard: pd,
+			Confirmation:    newConfirmation(ctx, revokePublicDashboardAction, impact, args.DashboardUID, p.UID),
+		}, nil
+	}
+	if err := checkConfirmation(ctx, args.ConfirmationToken, revokePublicDashboardAction, args.DashboardUID, p.UID); err != nil {
+		return nil, err
+	}
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	if _, err := c.DashboardPublic.DeletePublicDashboard(p.UID, args.DashboardUID); err != nil {
+		return nil, fmt.Errorf("revoke public dashboard of dashboard %s: %w", args.DashboardUID, classifyAPIError(err))
+	}
+	return &RevokePublicDashboardResult{Revoked: true, PublicDashboard: pd}, nil
+}
+
+var RevokePublicDashboard = mcpgrafana.MustTool(
+	"grafana_revoke_public_dashboard",
+	"Stop sharing a dashboard publicly by deleting its public dashboard, so that its URL stops working for good. Sharing it again creates a new URL; use `grafana_configure_public_dashboard` to pause it instead. "+revokePublicDashboardAction.description(),
+	revokePublicDashboard,
+	mcp.WithTitleAnnotation("Revoke public dashboard"),
+	mcp.WithDestructiveHintAnnotation(true),
+	mcp.WithIdempotentHintAnnotation(false),
+)
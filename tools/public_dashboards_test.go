@@ -0,0 +1,155 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+func TestPublicDashboards(t *testing.T) {
+	// public holds the public dashboards by dashboard UID.
+	public := map[string]map[string]any{
+		"shared": {"uid": "pd1", "dashboardUid": "shared", "accessToken": "token1", "isEnabled": true, "share": "public", "timeSelectionEnabled": true, "createdAt": "2024-05-01T10:00:00Z"},
+	}
+	var patches []map[string]any
+	var deleted []string
+	// publicDashboard handles /api/dashboards/uid/<dashboard UID>/public-dashboards[/<UID>].
+	publicDashboard := func(dashboardUID, uid string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			p, ok := public[dashboardUID]
+			switch r.Method {
+			case http.MethodGet:
+				if !ok {
+					w.WriteHeader(http.StatusNotFound)
+					_, _ = w.Write([]byte(`{"message":"Public dashboard not found"}`))
+					return
+				}
+				_ = json.NewEncoder(w).Encode(p)
+			case http.MethodPost:
+				var body map[string]any
+				if !decodeJSONBody(t, w, r, &body) {
+					return
+				}
+				body["uid"], body["dashboardUid"], body["accessToken"] = "pd2", dashboardUID, "token2"
+				public[dashboardUID] = body
+				_ = json.NewEncoder(w).Encode(body)
+			case http.MethodPatch:
+				assert.Equal(t, p["uid"], uid)
+				var body map[string]any
+				if !decodeJSONBody(t, w, r, &body) {
+					return
+				}
+				patches = append(patches, body)
+				for k, v := range body {
+					p[k] = v
+				}
+				_ = json.NewEncoder(w).Encode(p)
+			case http.MethodDelete:
+				assert.Equal(t, p["uid"], uid)
+				deleted = append(deleted, dashboardUID)
+				delete(public, dashboardUID)
+			}
+		}
+	}
+	routes := fakeRoutes{
+		"/api/dashboards/public-dashboards": func(w http.ResponseWriter, r *http.Request) {
+			list := []map[string]any{}
+			for uid, p := range public {
+				list = append(list, map[string]any{"uid": p["uid"], "dashboardUid": uid, "title": "Shared", "accessToken": p["accessToken"], "isEnabled": p["isEnabled"]})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"publicDashboards": list, "totalCount": len(list)})
+		},
+	}
+	for _, dashboardUID := range []string{"shared", "private"} {
+		path := "/api/dashboards/uid/" + dashboardUID + "/public-dashboards"
+		routes[path] = publicDashboard(dashboardUID, "")
+		for _, uid := range []string{"pd1", "pd2"} {
+			routes[path+"/"+uid] = publicDashboard(dashboardUID, uid)
+		}
+	}
+	server := newFakeGrafana(t, routes)
+
+	ctx := fakeGrafanaContext(server)
+
+	t.Run("list", func(t *testing.T) {
+		dashboards, err := listPublicDashboards(ctx, ListPublicDashboardsParams{})
+		require.NoError(t, err)
+		assert.Equal(t, []*PublicDashboard{{UID: "pd1", DashboardUID: "shared", Title: "Shared", IsEnabled: true, URL: server.URL + "/public-dashboards/token1"}}, dashboards)
+
+		dashboards, err = listPublicDashboards(ctx, ListPublicDashboardsParams{DashboardUID: "shared"})
+		require.NoError(t, err)
+		require.Len(t, dashboards, 1)
+		assert.Equal(t, "public", dashboards[0].Share)
+		assert.True(t, *dashboards[0].TimeSelectionEnabled)
+		assert.False(t, *dashboards[0].AnnotationsEnabled)
+		assert.Equal(t, "2024-05-01T10:00:00Z", dashboards[0].CreatedAt)
+
+		dashboards, err = listPublicDashboards(ctx, ListPublicDashboardsParams{DashboardUID: "private"})
+		require.NoError(t, err)
+		assert.Empty(t, dashboards)
+	})
+
+	t.Run("configure", func(t *testing.T) {
+		disabled := false
+		pd, err := configurePublicDashboard(ctx, ConfigurePublicDashboardParams{DashboardUID: "shared", IsEnabled: &disabled, TimeSelectionEnabled: &disabled})
+		require.NoError(t, err)
+		assert.False(t, pd.IsEnabled)
+		assert.False(t, *pd.TimeSelectionEnabled)
+		// Settings which are false are sent, and those not given are not.
+		assert.Equal(t, []map[string]any{{"isEnabled": false, "timeSelectionEnabled": false}}, patches)
+
+		_, err = configurePublicDashboard(ctx, ConfigurePublicDashboardParams{DashboardUID: "shared"})
+		assert.ErrorContains(t, err, "no setting")
+		_, err = configurePublicDashboard(ctx, ConfigurePublicDashboardParams{DashboardUID: "shared", Share: "everyone"})
+		assert.ErrorContains(t, err, "invalid share")
+		_, err = configurePublicDashboard(ctx, ConfigurePublicDashboardParams{DashboardUID: "private", IsEnabled: &disabled})
+		assert.ErrorContains(t, err, "not shared publicly")
+	})
+
+	t.Run("enable", func(t *testing.T) {
+		patches = nil
+		pd, err := enablePublicDashboard(ctx, EnablePublicDashboardParams{DashboardUID: "shared"})
+		require.NoError(t, err)
+		assert.True(t, pd.IsEnabled)
+		assert.Equal(t, []map[string]any{{"isEnabled": true}}, patches)
+
+		enabled := true
+		pd, err = enablePublicDashboard(ctx, EnablePublicDashboardParams{DashboardUID: "private", AnnotationsEnabled: &enabled})
+		require.NoError(t, err)
+		assert.Equal(t, "pd2", pd.UID)
+		assert.True(t, pd.IsEnabled)
+		assert.True(t, *pd.AnnotationsEnabled)
+		assert.Equal(t, server.URL+"/public-dashboards/token2", pd.URL)
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		result, err := revokePublicDashboard(ctx, RevokePublicDashboardParams{DashboardUID: "shared"})
+		require.NoError(t, err)
+		assert.False(t, result.Revoked)
+		assert.Equal(t, "pd1", result.PublicDashboard.UID)
+		require.NotNil(t, result.Confirmation)
+		assert.Contains(t, result.Confirmation.Message, "The public dashboard was not revoked.")
+		assert.Empty(t, deleted)
+
+		_, err = revokePublicDashboard(ctx, RevokePublicDashboardParams{DashboardUID: "private", ConfirmationToken: result.Confirmation.Token})
+		assert.ErrorIs(t, err, mcpgrafana.ErrInvalidConfirmationToken)
+		assert.Empty(t, deleted)
+
+		token := result.Confirmation.Token
+		result, err = revokePublicDashboard(ctx, RevokePublicDashboardParams{DashboardUID: "shared", ConfirmationToken: token})
+		require.NoError(t, err)
+		assert.True(t, result.Revoked)
+		assert.Equal(t, []string{"shared"}, deleted)
+
+		_, err = revokePublicDashboard(ctx, RevokePublicDashboardParams{DashboardUID: "shared", ConfirmationToken: token})
+		assert.ErrorContains(t, err, "not shared publicly")
+	})
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"net/url"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -218,6 +220,9 @@ profile type does not exist for that query. In such a case, consider trying a re
 Matchers are not required, but highly recommended, they are generally used to select an application by the service_name
 label (e.g. {service_name="foo"}). Use the list_pyroscope_label_names tool to fetch available label names, and the
 list_pyroscope_label_values tool to fetch available label values. The returned profile is in DOT format.
+If span_ids are given, for example the IDs of the slow spans of a trace fetched with grafana_get_tempo_trace, the profile
+is returned in a JSON object along with the functions which spent the most time during these spans. This requires the
+application to be profiled with the tracing integration of its profiler, which labels samples with the active span.
 `
 
 var FetchPyroscopeProfile = mcpgrafana.MustTool(
@@ -230,15 +235,16 @@ var FetchPyroscopeProfile = mcpgrafana.MustTool(
 )
 
 type FetchPyroscopeProfileParams struct {
-	DataSourceUID string `json:"data_source_uid" jsonschema:"required,description=The UID of the datasource to query"`
-	ProfileType   string `json:"profile_type" jsonschema:"required,description=Type profile type\\, use the list_pyroscope_profile_types tool to fetch available profile types"`
-	Matchers      string `json:"matchers,omitempty" jsonschema:"description=Optionally\\, Prometheus style matchers used to filter the result set (defaults to: {})"`
-	MaxNodeDepth  int    `json:"max_node_depth,omitempty" jsonschema:"description=Optionally\\, the maximum depth of nodes in the resulting profile. Less depth results in smaller profiles that execute faster\\, more depth result in larger profiles that have more detail. A value of -1 indicates to use an unbounded node depth (default: 100). Reducing max node depth from the default will negatively impact the accuracy of the profile"`
-	StartRFC3339  string `json:"start_rfc_3339,omitempty" jsonschema:"description=Optionally\\, the start time of the query in RFC3339 format (defaults to 1 hour ago)"`
-	EndRFC3339    string `json:"end_rfc_3339,omitempty" jsonschema:"description=Optionally\\, the end time of the query in RFC3339 format (defaults to now)"`
+	DataSourceUID string   `json:"data_source_uid" jsonschema:"required,description=The UID of the datasource to query"`
+	ProfileType   string   `json:"profile_type" jsonschema:"required,description=Type profile type\\, use the list_pyroscope_profile_types tool to fetch available profile types"`
+	Matchers      string   `json:"matchers,omitempty" jsonschema:"description=Optionally\\, Prometheus style matchers used to filter the result set (defaults to: {})"`
+	MaxNodeDepth  int      `json:"max_node_depth,omitempty" jsonschema:"description=Optionally\\, the maximum depth of nodes in the resulting profile. Less depth results in smaller profiles that execute faster\\, more depth result in larger profiles that have more detail. A value of -1 indicates to use an unbounded node depth (default: 100). Reducing max node depth from the default will negatively impact the accuracy of the profile"`
+	StartRFC3339  string   `json:"start_rfc_3339,omitempty" jsonschema:"description=Optionally\\, the start time of the query in RFC3339 format (defaults to 1 hour ago)"`
+	EndRFC3339    string   `json:"end_rfc_3339,omitempty" jsonschema:"description=Optionally\\, the end time of the query in RFC3339 format (defaults to now)"`
+	SpanIDs       []string `json:"span_ids,omitempty" jsonschema:"description=Optionally\\, the hex IDs of spans to also return the hottest functions during. Requires the application to be profiled with the tracing integration"`
 }
 
-func fetchPyroscopeProfile(ctx context.Context, args FetchPyroscopeProfileParams) (string, error) {
+func fetchPyroscopeProfile(ctx context.Context, args FetchPyroscopeProfileParams) (any, error) {
 	args.Matchers = stringOrDefault(args.Matchers, "{}")
 	matchersRegex := regexp.MustCompile(`^\{.*\}$`)
 	if !matchersRegex.MatchString(args.Matchers) {
@@ -249,22 +255,22 @@ func fetchPyroscopeProfile(ctx context.Context, args FetchPyroscopeProfileParams
 
 	start, err := rfc3339OrDefault(args.StartRFC3339, time.Time{})
 	if err != nil {
-		return "", fmt.Errorf("failed to parse start timestamp %q: %w", args.StartRFC3339, err)
+		return nil, fmt.Errorf("failed to parse start timestamp %q: %w", args.StartRFC3339, err)
 	}
 
 	end, err := rfc3339OrDefault(args.EndRFC3339, time.Time{})
 	if err != nil {
-		return "", fmt.Errorf("failed to parse end timestamp %q: %w", args.EndRFC3339, err)
+		return nil, fmt.Errorf("failed to parse end timestamp %q: %w", args.EndRFC3339, err)
 	}
 
 	start, end, err = validateTimeRange(start, end)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	client, err := newPyroscopeClient(ctx, args.DataSourceUID)
 	if err != nil {
-		return "", fmt.Errorf("failed to create Pyroscope client: %w", err)
+		return nil, fmt.Errorf("failed to create Pyroscope client: %w", err)
 	}
 
 	req := &renderRequest{
@@ -277,26 +283,99 @@ func fetchPyroscopeProfile(ctx context.Context, args FetchPyroscopeProfileParams
 	}
 	res, err := client.Render(ctx, req)
 	if err != nil {
-		return "", fmt.Errorf("failed to call Pyroscope API: %w", err)
+		return nil, fmt.Errorf("failed to call Pyroscope API: %w", err)
 	}
 
 	res = cleanupDotProfile(res)
-	return res, nil
+	if len(args.SpanIDs) == 0 {
+		return res, nil
+	}
+
+	spans, err := client.SelectMergeSpanProfile(ctx, connect.NewRequest(&querierv1.SelectMergeSpanProfileRequest{
+		ProfileTypeID: args.ProfileType,
+		LabelSelector: args.Matchers,
+		SpanSelector:  args.SpanIDs,
+		Start:         start.UnixMilli(),
+		End:           end.UnixMilli(),
+		Format:        querierv1.ProfileFormat_PROFILE_FORMAT_FLAMEGRAPH,
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Pyroscope API: %w", err)
+	}
+	result := &profileWithSpanHotFunctions{
+		Profile:      res,
+		HotFunctions: flameGraphHotFunctions(spans.Msg.Flamegraph, maxHotFunctions),
+	}
+	if len(result.HotFunctions) == 0 {
+		result.Note = "No samples were taken during the given spans. Linking profiles to spans requires profiling the application with the tracing integration of its profiler (e.g. otel-profiling-go), which labels samples with the active span."
+	}
+	return result, nil
+}
+
+// maxHotFunctions is the number of functions returned for spans.
+const maxHotFunctions = 10
+
+// hotFunction is a function with a large self value in a profile.
+type hotFunction struct {
+	Name        string  `json:"name"`
+	SelfValue   int64   `json:"selfValue"`
+	SelfPercent float64 `json:"selfPercent"`
+}
+
+type profileWithSpanHotFunctions struct {
+	Profile string `json:"profile"`
+	// HotFunctions are the functions with the largest self values during
+	// the spans, rather than in the whole profile.
+	HotFunctions []hotFunction `json:"spanHotFunctions"`
+	Note         string        `json:"note,omitempty"`
+}
+
+// flameGraphHotFunctions returns the functions with the largest self values
+// in a flame graph.
+func flameGraphHotFunctions(fg *querierv1.FlameGraph, limit int) []hotFunction {
+	if fg == nil {
+		return nil
+	}
+	self := map[string]int64{}
+	for _, level := range fg.Levels {
+		// The values of each node are its offset, total, self and name.
+		for i := 0; i+3 < len(level.Values); i += 4 {
+			value, name := level.Values[i+2], level.Values[i+3]
+			if value > 0 && name >= 0 && int(name) < len(fg.Names) {
+				self[fg.Names[name]] += value
+			}
+		}
+	}
+
+	result := make([]hotFunction, 0, len(self))
+	for name, value := range self {
+		f := hotFunction{Name: name, SelfValue: value}
+		if fg.Total > 0 {
+			f.SelfPercent = math.Round(float64(value)/float64(fg.Total)*10000) / 100
+		}
+		result = append(result, f)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].SelfValue != result[j].SelfValue {
+			return result[i].SelfValue > result[j].SelfValue
+		}
+		return result[i].Name < result[j].Name
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result
 }
 
 func newPyroscopeClient(ctx context.Context, uid string) (*pyroscopeClient, error) {
 	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
-	httpClient := &http.Client{
-		Transport: &authRoundTripper{
-			accessToken: cfg.AccessToken,
-			idToken:     cfg.IDToken,
-			apiKey:      cfg.APIKey,
-			underlying:  http.DefaultTransport,
-		},
-		Timeout: 10 * time.Second,
+	httpClient, err := newGrafanaHTTPClient(ctx)
+	if err != nil {
+		return nil, err
 	}
+	httpClient.Timeout = 10 * time.Second
 
-	_, err := getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: uid})
+	_, err = getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: uid})
 	if err != nil {
 		return nil, err
 	}
@@ -381,7 +460,7 @@ func (c *pyroscopeClient) get(ctx context.Context, path string, params url.Value
 	}
 
 	const limit = 1 << 25 // 32 MiB
-	body, err := io.ReadAll(io.LimitReader(res.Body, limit))
+	body, err := io.ReadAll(mcpgrafana.LimitResponseBody(res.Body, limit))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
@@ -0,0 +1,34 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"testing"
+
+	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlameGraphHotFunctions(t *testing.T) {
+	fg := &querierv1.FlameGraph{
+		Names: []string{"total", "main", "handler", "compress"},
+		// Each node is its offset, total, self and name index.
+		Levels: []*querierv1.Level{
+			{Values: []int64{0, 100, 0, 0}},
+			{Values: []int64{0, 100, 5, 1}},
+			{Values: []int64{0, 80, 10, 2, 0, 15, 15, 3}},
+			{Values: []int64{0, 70, 70, 3}},
+		},
+		Total: 100,
+	}
+
+	hot := flameGraphHotFunctions(fg, 2)
+	require.Len(t, hot, 2)
+	assert.Equal(t, hotFunction{Name: "compress", SelfValue: 85, SelfPercent: 85}, hot[0])
+	assert.Equal(t, hotFunction{Name: "handler", SelfValue: 10, SelfPercent: 10}, hot[1])
+
+	assert.Empty(t, flameGraphHotFunctions(&querierv1.FlameGraph{Names: []string{"total"}, Levels: []*querierv1.Level{{Values: []int64{0, 0, 0, 0}}}}, 10))
+	assert.Empty(t, flameGraphHotFunctions(nil, 10))
+}
@@ -0,0 +1,360 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	// DefaultRenderWidth and DefaultRenderHeight are the default size in
+	// pixels of rendered panels.
+	DefaultRenderWidth  = 1000
+	DefaultRenderHeight = 500
+	// MaxRenderSize is the maximum width and height in pixels of rendered
+	// panels.
+	MaxRenderSize = 3000
+	// DefaultTimelineWindowMinutes is the default length of the time range
+	// shown in each image of a panel timeline.
+	DefaultTimelineWindowMinutes = 60
+	// MaxTimelineImages is the maximum number of images rendered in a single
+	// panel timeline, i.e. the number of panels times the number of
+	// timestamps.
+	MaxTimelineImages = 24
+
+	// renderWorkers is the number of panels rendered concurrently. The image
+	// renderer runs a headless browser per request, so this is kept low.
+	renderWorkers = 2
+	// renderTimeout is the timeout of a single render request.
+	renderTimeout = 90 * time.Second
+)
+
+// renderClient renders dashboard panels to PNG images using the Grafana
+// image renderer.
+type renderClient struct {
+	httpClient *http.Client
+	baseURL    string
+	orgID      int64
+}
+
+func newRenderClient(ctx context.Context) (*renderClient, error) {
+	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+	httpClient, err := newGrafanaHTTPClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	httpClient.Timeout = renderTimeout
+	return &renderClient{
+		httpClient: httpClient,
+		baseURL:    strings.TrimRight(cfg.URL, "/"),
+		orgID:      cfg.OrgID,
+	}, nil
+}
+
+// panelRender describes a panel to render.
+type panelRender struct {
+	dashboardUID string
+	panelID      int
+	from, to     time.Time
+	width        int
+	height       int
+	variables    map[string]string
+	theme        string
+}
+
+// renderPanel renders a single panel, returning the PNG image.
+func (c *renderClient) renderPanel(ctx context.Context, r panelRender) ([]byte, error) {
+	params := url.Values{}
+	params.Set("panelId", strconv.Itoa(r.panelID))
+	params.Set("from", strconv.FormatInt(r.from.UnixMilli(), 10))
+	params.Set("to", strconv.FormatInt(r.to.UnixMilli(), 10))
+	params.Set("width", strconv.Itoa(r.width))
+	params.Set("height", strconv.Itoa(r.height))
+	params.Set("tz", "UTC")
+	if r.theme != "" {
+		params.Set("theme", r.theme)
+	}
+	if c.orgID != 0 {
+		params.Set("orgId", strconv.FormatInt(c.orgID, 10))
+	}
+	for name, value := range r.variables {
+		params.Add("var-"+name, value)
+	}
+	// The dashboard slug is ignored by Grafana, so a placeholder is used.
+	u := fmt.Sprintf("%s/render/d-solo/%s/_?%s", c.baseURL, url.PathEscape(r.dashboardUID), params.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024*48)) // 48MB limit
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rendering panel %d failed with status %d (is the Grafana image renderer installed?): %s", r.panelID, resp.StatusCode, strings.TrimSpace(string(body[:min(len(body), 256)])))
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "image/png") {
+		return nil, fmt.Errorf("rendering panel %d returned %q instead of a PNG image", r.panelID, ct)
+	}
+	return body, nil
+}
+
+// renderSize returns the size of rendered images, applying the defaults and
+// checking the maximum.
+func renderSize(width, height int) (int, int, error) {
+	if width <= 0 {
+		width = DefaultRenderWidth
+	}
+	if height <= 0 {
+		height = DefaultRenderHeight
+	}
+	if width > MaxRenderSize || height > MaxRenderSize {
+		return 0, 0, fmt.Errorf("width and height must be at most %d pixels", MaxRenderSize)
+	}
+	return width, height, nil
+}
+
+type RenderPanelParams struct {
+	DashboardUID string            `json:"dashboardUid" jsonschema:"required,description=The UID of the dashboard"`
+	PanelID      int               `json:"panelId" jsonschema:"required,description=The ID of the panel to render"`
+	StartTime    string            `json:"startTime,omitempty" jsonschema:"description=The start of the time range to show\\, in RFC3339 format or relative to now (default 'now-1h')"`
+	EndTime      string            `json:"endTime,omitempty" jsonschema:"description=The end of the time range to show\\, in RFC3339 format or relative to now (default 'now')"`
+	Variables    map[string]string `json:"variables,omitempty" jsonschema:"description=Values of the dashboard's template variables by variable name"`
+	Width        int               `json:"width,omitempty" jsonschema:"description=The width of the image in pixels (default 1000)"`
+	Height       int               `json:"height,omitempty" jsonschema:"description=The height of the image in pixels (default 500)"`
+	Theme        string            `json:"theme,omitempty" jsonschema:"enum=light,enum=dark,description=The theme of the image (default the theme of the Grafana instance)"`
+}
+
+func renderPanelImage(ctx context.Context, args RenderPanelParams) (*mcp.CallToolResult, error) {
+	width, height, err := renderSize(args.Width, args.Height)
+	if err != nil {
+		return nil, err
+	}
+	if args.Theme != "" && args.Theme != "light" && args.Theme != "dark" {
+		return nil, fmt.Errorf("invalid theme %q: must be light or dark", args.Theme)
+	}
+	from, to := time.Now().Add(-time.Hour), time.Now()
+	if args.StartTime != "" {
+		if from, err = parseTime(args.StartTime); err != nil {
+			return nil, fmt.Errorf("parsing start time: %w", err)
+		}
+	}
+	if args.EndTime != "" {
+		if to, err = parseTime(args.EndTime); err != nil {
+			return nil, fmt.Errorf("parsing end time: %w", err)
+		}
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("the start time must be before the end time")
+	}
+
+	dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: args.DashboardUID})
+	if err != nil {
+		return nil, err
+	}
+	panels, err := timelinePanels(summarizeDashboard(args.DashboardUID, dashboard), []int{args.PanelID})
+	if err != nil {
+		return nil, err
+	}
+	client, err := newRenderClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	image, err := client.renderPanel(ctx, panelRender{
+		dashboardUID: args.DashboardUID,
+		panelID:      args.PanelID,
+		from:         from.UTC(),
+		to:           to.UTC(),
+		width:        width,
+		height:       height,
+		variables:    args.Variables,
+		theme:        args.Theme,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	title := panels[0].Title
+	if title == "" {
+		title = "(untitled)"
+	}
+	locale := mcpgrafana.GrafanaConfigFromContext(ctx).Locale
+	caption := fmt.Sprintf("Panel %d %q from %s to %s", args.PanelID, title, locale.FormatTime(from.UTC()), locale.FormatTime(to.UTC()))
+	return &mcp.CallToolResult{Content: []mcp.Content{
+		mcp.NewTextContent(caption),
+		mcp.NewImageContent(base64.StdEncoding.EncodeToString(image), "image/png"),
+	}}, nil
+}
+
+var RenderPanel = mcpgrafana.MustTool(
+	"grafana_render_panel",
+	"Render a dashboard panel as a PNG image for a time range, returned as image content after a caption with the panel and time range. Models which can read images can then see the chart itself, which is often easier to interpret than the raw series, e.g. to spot a spike or compare series. Use `grafana_get_dashboard_summary` to find the IDs of panels. Requires the Grafana image renderer.",
+	renderPanelImage,
+	mcp.WithTitleAnnotation("Render panel"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type RenderPanelTimelineParams struct {
+	DashboardUID  string            `json:"dashboardUid" jsonschema:"required,description=The UID of the dashboard"`
+	PanelIDs      []int             `json:"panelIds,omitempty" jsonschema:"description=The IDs of the panels to render. Defaults to all panels of the dashboard except rows"`
+	Timestamps    []string          `json:"timestamps" jsonschema:"required,description=The times to render the panels at\\, in RFC3339 format or relative to now (e.g. 'now-2h'). Each image shows the window ending at its timestamp\\, e.g. before\\, during and after an incident"`
+	WindowMinutes int               `json:"windowMinutes,omitempty" jsonschema:"description=The length of the time range shown in each image in minutes (default 60)"`
+	Variables     map[string]string `json:"variables,omitempty" jsonschema:"description=Values of the dashboard's template variables by variable name"`
+	Width         int               `json:"width,omitempty" jsonschema:"description=The width of each image in pixels (default 1000)"`
+	Height        int               `json:"height,omitempty" jsonschema:"description=The height of each image in pixels (default 500)"`
+}
+
+// timelineFrame is a panel rendered at one timestamp of a timeline.
+type timelineFrame struct {
+	panel panelSummary
+	at    time.Time
+	image []byte
+	err   error
+}
+
+// timelinePanels returns the panels of the dashboard to render, in the
+// requested order.
+func timelinePanels(dashboard dashboardSummary, ids []int) ([]panelSummary, error) {
+	if len(ids) == 0 {
+		var panels []panelSummary
+		for _, p := range dashboard.Panels {
+			if p.Type != "row" {
+				panels = append(panels, p)
+			}
+		}
+		return panels, nil
+	}
+	panels := make([]panelSummary, 0, len(ids))
+	for _, id := range ids {
+		found := false
+		for _, p := range dashboard.Panels {
+			if p.ID == id {
+				panels = append(panels, p)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("panel %d not found in dashboard %s", id, dashboard.UID)
+		}
+	}
+	return panels, nil
+}
+
+// caption describes a frame of a timeline, formatting its times in the
+// locale of the request.
+func (f timelineFrame) caption(locale mcpgrafana.Locale, window time.Duration) string {
+	title := f.panel.Title
+	if title == "" {
+		title = "(untitled)"
+	}
+	s := fmt.Sprintf("Panel %d %q at %s, showing the preceding %s", f.panel.ID, title, locale.FormatTime(f.at), locale.FormatDuration(window))
+	if f.err != nil {
+		s += fmt.Sprintf(": failed to render: %s", f.err)
+	}
+	return s
+}
+
+func renderPanelTimeline(ctx context.Context, args RenderPanelTimelineParams) (*mcp.CallToolResult, error) {
+	if len(args.Timestamps) == 0 {
+		return nil, fmt.Errorf("at least one timestamp is required")
+	}
+	window := time.Duration(args.WindowMinutes) * time.Minute
+	if window <= 0 {
+		window = DefaultTimelineWindowMinutes * time.Minute
+	}
+	width, height, err := renderSize(args.Width, args.Height)
+	if err != nil {
+		return nil, err
+	}
+	times := make([]time.Time, len(args.Timestamps))
+	for i, ts := range args.Timestamps {
+		t, err := parseTime(ts)
+		if err != nil {
+			return nil, fmt.Errorf("parsing timestamp %q: %w", ts, err)
+		}
+		times[i] = t.UTC()
+	}
+
+	dashboard, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: args.DashboardUID})
+	if err != nil {
+		return nil, err
+	}
+	panels, err := timelinePanels(summarizeDashboard(args.DashboardUID, dashboard), args.PanelIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(panels) == 0 {
+		return nil, fmt.Errorf("dashboard %s has no panels to render", args.DashboardUID)
+	}
+	if n := len(panels) * len(times); n > MaxTimelineImages {
+		return nil, fmt.Errorf("rendering %d panels at %d timestamps would produce %d images, more than the maximum of %d; choose fewer panels with panelIds or fewer timestamps", len(panels), len(times), n, MaxTimelineImages)
+	}
+
+	client, err := newRenderClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Frames are ordered by timestamp, then by panel, so that the timeline
+	// reads chronologically.
+	frames := make([]timelineFrame, 0, len(panels)*len(times))
+	for _, t := range times {
+		for _, p := range panels {
+			frames = append(frames, timelineFrame{panel: p, at: t})
+		}
+	}
+	results := fanOut(ctx, "images", frames, renderWorkers, func(ctx context.Context, f timelineFrame) ([]byte, error) {
+		return client.renderPanel(ctx, panelRender{
+			dashboardUID: args.DashboardUID,
+			panelID:      f.panel.ID,
+			from:         f.at.Add(-window),
+			to:           f.at,
+			width:        width,
+			height:       height,
+			variables:    args.Variables,
+		})
+	})
+	// Fail the whole call if nothing could be rendered, e.g. because the
+	// image renderer is not installed.
+	if err := allFailed("images", results); err != nil {
+		return nil, err
+	}
+	for i, r := range results {
+		frames[i].image, frames[i].err = r.value, r.err
+	}
+
+	locale := mcpgrafana.GrafanaConfigFromContext(ctx).Locale
+	result := &mcp.CallToolResult{}
+	for _, f := range frames {
+		result.Content = append(result.Content, mcp.NewTextContent(f.caption(locale, window)))
+		if f.err == nil {
+			result.Content = append(result.Content, mcp.NewImageContent(base64.StdEncoding.EncodeToString(f.image), "image/png"))
+		}
+	}
+	return result, nil
+}
+
+var RenderPanelTimeline = mcpgrafana.MustTool(
+	"grafana_render_panel_timeline",
+	"Render dashboard panels as PNG images at several timestamps, e.g. before, during and after an incident, producing a visual timeline in a single call. Each image shows the window ending at its timestamp and is preceded by a caption with the panel and time. Renders all panels of the dashboard unless panel IDs are given; at most 24 images per call. Requires the Grafana image renderer.",
+	renderPanelTimeline,
+	mcp.WithTitleAnnotation("Render panel timeline"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
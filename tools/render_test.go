@@ -0,0 +1,140 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderPanelTimeline(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nfake")
+	var mu sync.Mutex
+	var rendered []string
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/dashboards/uid/incident": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"dashboard": map[string]any{"uid": "incident", "panels": []any{
+					map[string]any{"id": 1, "title": "Errors", "type": "timeseries"},
+					map[string]any{"id": 2, "title": "Details", "type": "row", "panels": []any{
+						map[string]any{"id": 3, "title": "Latency", "type": "timeseries"},
+					}},
+				}},
+			})
+		},
+		"/render/d-solo/incident/_": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "Bearer test-api-key", r.Header.Get("Authorization"))
+			q := r.URL.Query()
+			assert.Equal(t, "prod", q.Get("var-env"))
+			mu.Lock()
+			rendered = append(rendered, q.Get("panelId")+"@"+q.Get("from")+"-"+q.Get("to"))
+			mu.Unlock()
+			if q.Get("panelId") == "3" && q.Get("to") == "1736125200000" {
+				w.Header().Set("Content-Type", "text/plain")
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte("renderer crashed"))
+				return
+			}
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(png)
+		},
+	})
+
+	ctx := fakeGrafanaContext(server)
+
+	result, err := renderPanelTimeline(ctx, RenderPanelTimelineParams{
+		DashboardUID:  "incident",
+		Timestamps:    []string{"2025-01-06T00:00:00Z", "2025-01-06T01:00:00Z"},
+		WindowMinutes: 30,
+		Variables:     map[string]string{"env": "prod"},
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"1@1736119800000-1736121600000", "3@1736119800000-1736121600000",
+		"1@1736123400000-1736125200000", "3@1736123400000-1736125200000",
+	}, rendered)
+
+	// Frames are chronological; the row panel is skipped, and the failed
+	// frame only has a caption.
+	require.Len(t, result.Content, 7)
+	assert.Equal(t, `Panel 1 "Errors" at 2025-01-06 00:00 UTC, showing the preceding 30 min`, result.Content[0].(mcp.TextContent).Text)
+	image := result.Content[1].(mcp.ImageContent)
+	assert.Equal(t, "image/png", image.MIMEType)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(png), image.Data)
+	assert.Equal(t, `Panel 3 "Latency" at 2025-01-06 00:00 UTC, showing the preceding 30 min`, result.Content[2].(mcp.TextContent).Text)
+	last := result.Content[6].(mcp.TextContent).Text
+	assert.True(t, strings.HasPrefix(last, `Panel 3 "Latency" at 2025-01-06 01:00 UTC, showing the preceding 30 min: failed to render:`), last)
+
+	t.Run("unknown panel", func(t *testing.T) {
+		_, err := renderPanelTimeline(ctx, RenderPanelTimelineParams{DashboardUID: "incident", PanelIDs: []int{9}, Timestamps: []string{"now"}})
+		assert.ErrorContains(t, err, "panel 9 not found")
+	})
+
+	t.Run("too many images", func(t *testing.T) {
+		timestamps := make([]string, 13)
+		for i := range timestamps {
+			timestamps[i] = "now"
+		}
+		_, err := renderPanelTimeline(ctx, RenderPanelTimelineParams{DashboardUID: "incident", Timestamps: timestamps})
+		assert.ErrorContains(t, err, "more than the maximum of 24")
+	})
+}
+
+func TestRenderPanel(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nfake")
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/dashboards/uid/service": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"dashboard": map[string]any{"uid": "service", "panels": []any{
+					map[string]any{"id": 4, "title": "Requests", "type": "timeseries"},
+				}},
+			})
+		},
+		"/render/d-solo/service/_": func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			assert.Equal(t, "4", q.Get("panelId"))
+			assert.Equal(t, "1736121600000", q.Get("from"))
+			assert.Equal(t, "1736125200000", q.Get("to"))
+			assert.Equal(t, "800", q.Get("width"))
+			assert.Equal(t, "dark", q.Get("theme"))
+			assert.Equal(t, "prod", q.Get("var-env"))
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(png)
+		},
+	})
+
+	ctx := fakeGrafanaContext(server)
+
+	result, err := renderPanelImage(ctx, RenderPanelParams{
+		DashboardUID: "service",
+		PanelID:      4,
+		StartTime:    "2025-01-06T00:00:00Z",
+		EndTime:      "2025-01-06T01:00:00Z",
+		Variables:    map[string]string{"env": "prod"},
+		Width:        800,
+		Theme:        "dark",
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Content, 2)
+	assert.Equal(t, `Panel 4 "Requests" from 2025-01-06 00:00 UTC to 2025-01-06 01:00 UTC`, result.Content[0].(mcp.TextContent).Text)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(png), result.Content[1].(mcp.ImageContent).Data)
+
+	t.Run("unknown panel", func(t *testing.T) {
+		_, err := renderPanelImage(ctx, RenderPanelParams{DashboardUID: "service", PanelID: 9})
+		assert.ErrorContains(t, err, "panel 9 not found")
+	})
+
+	t.Run("invalid time range", func(t *testing.T) {
+		_, err := renderPanelImage(ctx, RenderPanelParams{DashboardUID: "service", PanelID: 4, StartTime: "now", EndTime: "now-1h"})
+		assert.ErrorContains(t, err, "before the end time")
+	})
+}
@@ -15,7 +15,8 @@ import (
 var dashboardTypeStr = "dash-db"
 
 type SearchDashboardsParams struct {
-	Query string `json:"query" jsonschema:"description=The query to search for"`
+	Query    string `json:"query" jsonschema:"description=The query to search for"`
+	Unscoped bool   `json:"unscoped,omitempty" jsonschema:"description=Set to true to ignore the server's default scope\\, e.g. a team's dashboard tags\\, and search all dashboards"`
 }
 
 func searchDashboards(ctx context.Context, args SearchDashboardsParams) (models.HitList, error) {
@@ -25,6 +26,9 @@ func searchDashboards(ctx context.Context, args SearchDashboardsParams) (models.
 		params.SetQuery(&args.Query)
 		params.SetType(&dashboardTypeStr)
 	}
+	if scope := mcpgrafana.GrafanaConfigFromContext(ctx).Scope; !args.Unscoped && len(scope.DashboardTags) > 0 {
+		params.SetTag(scope.DashboardTags)
+	}
 	search, err := c.Search.Search(params)
 	if err != nil {
 		return nil, fmt.Errorf("search dashboards for %+v: %w", c, err)
@@ -34,7 +38,7 @@ func searchDashboards(ctx context.Context, args SearchDashboardsParams) (models.
 
 var SearchDashboards = mcpgrafana.MustTool(
 	"grafana_search_dashboards",
-	"Search for Grafana dashboards by a query string. Returns a list of matching dashboards with details like title, UID, folder, tags, and URL.",
+	"Search for Grafana dashboards by a query string. Returns a list of matching dashboards with details like title, UID, folder, tags, and URL. If the server has a default scope, e.g. a team, only dashboards with its tags are returned unless `unscoped` is set.",
 	searchDashboards,
 	mcp.WithTitleAnnotation("Search dashboards"),
 	mcp.WithIdempotentHintAnnotation(true),
@@ -0,0 +1,177 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/prometheus/common/model"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	// defaultServiceHealthLogWindow is the default time range over which
+	// the error logs of a service are counted.
+	defaultServiceHealthLogWindow = 15 * time.Minute
+	// serviceHealthWorkers is the number of checks of a service run
+	// concurrently.
+	serviceHealthWorkers = 3
+	// serviceHealthErrorPattern matches the log lines counted as errors.
+	serviceHealthErrorPattern = "(?i)(error|fatal|panic|exception)"
+)
+
+type GetServiceHealthParams struct {
+	Service                 string    `json:"service" jsonschema:"required,description=The name of the service. Alert rules are matched on their service label\\, as set by grafana_generate_golden_signal_alert_rules and grafana_bootstrap_workspace"`
+	PrometheusDatasourceUID string    `json:"prometheusDatasourceUid,omitempty" jsonschema:"description=Optionally\\, the UID of the Prometheus datasource scraping the service\\, to count its targets which are up and down"`
+	Selector                *Selector `json:"selector,omitempty" jsonschema:"description=Optionally\\, the selector of the Prometheus targets of the service\\, e.g. on namespace and job. Defaults to the job label matching the service name"`
+	LokiDatasourceUID       string    `json:"lokiDatasourceUid,omitempty" jsonschema:"description=Optionally\\, the UID of the Loki datasource with the logs of the service\\, to count its error logs"`
+	LokiSelector            string    `json:"lokiSelector,omitempty" jsonschema:"description=Optionally\\, the LogQL stream selector of the logs of the service\\, e.g. '{namespace=\"shop\"\\, app=\"checkout\"}'. Defaults to the service_name label matching the service name"`
+	LogWindow               string    `json:"logWindow,omitempty" jsonschema:"description=Optionally\\, the time range over which error logs are counted\\, as a duration such as '1h' (default 15m)"`
+}
+
+// ServiceTargets counts the Prometheus targets of a service by their state.
+type ServiceTargets struct {
+	Up   int `json:"up"`
+	Down int `json:"down"`
+}
+
+// ServiceHealth is a snapshot of the health of a service.
+type ServiceHealth struct {
+	Service string `json:"service"`
+	// Alerts are the firing and pending alert rules of the service.
+	Alerts []alertRuleSummary `json:"alerts"`
+	// Targets is set if a Prometheus datasource was given.
+	Targets *ServiceTargets `json:"targets,omitempty"`
+	// ErrorLogs is the number of error log lines in the log window, if a
+	// Loki datasource was given.
+	ErrorLogs *int   `json:"errorLogs,omitempty"`
+	LogWindow string `json:"logWindow,omitempty"`
+	// Errors are the checks which could not be run.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// serviceHealthCheck is a check of the health of a service, which sets its
+// part of the snapshot.
+type serviceHealthCheck struct {
+	name string
+	run  func(ctx context.Context, health *ServiceHealth) error
+}
+
+func getServiceHealth(ctx context.Context, args GetServiceHealthParams) (*ServiceHealth, error) {
+	if args.Service == "" {
+		return nil, fmt.Errorf("service is required")
+	}
+	window := defaultServiceHealthLogWindow
+	if args.LogWindow != "" {
+		d, err := model.ParseDuration(args.LogWindow)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid log window %q: must be a positive duration such as '15m'", args.LogWindow)
+		}
+		window = time.Duration(d)
+	}
+
+	health := &ServiceHealth{Service: args.Service, Alerts: []alertRuleSummary{}}
+	checks := []serviceHealthCheck{{name: "alerts", run: func(ctx context.Context, health *ServiceHealth) error {
+		rules, err := listAlertRules(ctx, ListAlertRulesParams{
+			LabelSelectors: []Selector{{Filters: []LabelMatcher{{Name: "service", Value: args.Service, Type: "="}}}},
+			States:         []string{"firing", "pending"},
+		})
+		health.Alerts = append(health.Alerts, rules...)
+		return err
+	}}}
+	if args.PrometheusDatasourceUID != "" {
+		selector := cmp.Or(args.Selector, &Selector{Filters: []LabelMatcher{{Name: "job", Value: args.Service, Type: "="}}})
+		checks = append(checks, serviceHealthCheck{name: "targets", run: func(ctx context.Context, health *ServiceHealth) error {
+			targets, err := serviceTargets(ctx, args.PrometheusDatasourceUID, *selector)
+			health.Targets = targets
+			return err
+		}})
+	}
+	if args.LokiDatasourceUID != "" {
+		selector := cmp.Or(args.LokiSelector, "{service_name="+strconv.Quote(args.Service)+"}")
+		health.LogWindow = model.Duration(window).String()
+		checks = append(checks, serviceHealthCheck{name: "errorLogs", run: func(ctx context.Context, health *ServiceHealth) error {
+			count, err := serviceErrorLogs(ctx, args.LokiDatasourceUID, selector, window)
+			health.ErrorLogs = count
+			return err
+		}})
+	}
+
+	// Each check sets its own part of the snapshot, so that they can run
+	// concurrently. Checks which fail are listed in the errors, unless all
+	// of them fail.
+	results := fanOut(ctx, "checks", checks, serviceHealthWorkers, func(ctx context.Context, check serviceHealthCheck) (string, error) {
+		if err := check.run(ctx, health); err != nil {
+			return check.name, fmt.Errorf("%s: %w", check.name, err)
+		}
+		return check.name, nil
+	})
+	if err := allFailed("checks", results); err != nil {
+		return nil, err
+	}
+	for _, r := range results {
+		if r.err != nil {
+			health.Errors = append(health.Errors, r.err.Error())
+		}
+	}
+	return health, nil
+}
+
+// serviceTargets counts the targets of a service which are up and down.
+func serviceTargets(ctx context.Context, datasourceUID string, selector Selector) (*ServiceTargets, error) {
+	result, err := queryPrometheus(ctx, QueryPrometheusParams{
+		DatasourceUID: datasourceUID,
+		Expr:          "up" + selector.String(),
+		StartTime:     "now",
+		QueryType:     "instant",
+	})
+	if err != nil {
+		return nil, err
+	}
+	vector, ok := result.(model.Vector)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result type %s", result.Type())
+	}
+	targets := &ServiceTargets{}
+	for _, sample := range vector {
+		if sample.Value == 1 {
+			targets.Up++
+		} else {
+			targets.Down++
+		}
+	}
+	return targets, nil
+}
+
+// serviceErrorLogs counts the error log lines of the streams matching the
+// selector over the window.
+func serviceErrorLogs(ctx context.Context, datasourceUID, selector string, window time.Duration) (*int, error) {
+	entries, err := queryLokiLogs(ctx, QueryLokiLogsParams{
+		DatasourceUID: datasourceUID,
+		LogQL:         fmt.Sprintf("sum(count_over_time(%s |~ %s [%s]))", selector, strconv.Quote(serviceHealthErrorPattern), model.Duration(window)),
+		QueryType:     "instant",
+	})
+	if err != nil {
+		return nil, err
+	}
+	count := 0
+	for _, e := range entries {
+		if e.Value != nil {
+			count += int(*e.Value)
+		}
+	}
+	return &count, nil
+}
+
+var GetServiceHealth = mcpgrafana.MustTool(
+	"grafana_get_service_health",
+	"Get a snapshot of the health of a service in a single call: its firing and pending alert rules, the number of its Prometheus targets which are up and down if a Prometheus datasource is given, and the number of its error log lines over the log window (15 minutes by default) if a Loki datasource is given. Use it as the first step of an investigation or to check a service after a change. The checks run concurrently; checks which fail are listed in `errors` while the results of the others are still returned.",
+	getServiceHealth,
+	mcp.WithTitleAnnotation("Get service health snapshot"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
@@ -0,0 +1,62 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetServiceHealth(t *testing.T) {
+	ctx := newFakeGrafanaContext(t, fakeRoutes{
+		rulesEndpointPath: jsonBody(`{"status": "success", "data": {"groups": [{"name": "checkout", "folderUid": "f1", "rules": [
+			{"uid": "r1", "name": "Checkout errors", "state": "firing", "type": "alerting", "health": "ok", "labels": {"service": "checkout"}},
+			{"uid": "r2", "name": "Search errors", "state": "firing", "type": "alerting", "health": "ok", "labels": {"service": "search"}}
+		]}]}}`),
+		"/api/datasources/uid/prom": jsonBody(`{"uid": "prom", "type": "prometheus"}`),
+		"/api/datasources/proxy/uid/prom/api/v1/query": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, `up{job='checkout'}`, r.FormValue("query"))
+			_, _ = w.Write([]byte(`{"status": "success", "data": {"resultType": "vector", "result": [
+				{"metric": {"instance": "a"}, "value": [1736121600, "1"]},
+				{"metric": {"instance": "b"}, "value": [1736121600, "1"]},
+				{"metric": {"instance": "c"}, "value": [1736121600, "0"]}
+			]}}`))
+		},
+		"/api/datasources/uid/loki": jsonBody(`{"uid": "loki", "type": "loki"}`),
+		"/api/datasources/proxy/uid/loki/loki/api/v1/query": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, `sum(count_over_time({service_name="checkout"} |~ "(?i)(error|fatal|panic|exception)" [1h]))`, r.FormValue("query"))
+			_, _ = w.Write([]byte(`{"status": "success", "data": {"resultType": "vector", "result": [{"metric": {}, "value": [1736121600, "42"]}]}}`))
+		},
+	})
+
+	t.Run("all checks", func(t *testing.T) {
+		health, err := getServiceHealth(ctx, GetServiceHealthParams{Service: "checkout", PrometheusDatasourceUID: "prom", LokiDatasourceUID: "loki", LogWindow: "1h"})
+		require.NoError(t, err)
+		assert.Empty(t, health.Errors)
+		require.Len(t, health.Alerts, 1)
+		assert.Equal(t, "r1", health.Alerts[0].UID)
+		assert.Equal(t, &ServiceTargets{Up: 2, Down: 1}, health.Targets)
+		require.NotNil(t, health.ErrorLogs)
+		assert.Equal(t, 42, *health.ErrorLogs)
+		assert.Equal(t, "1h", health.LogWindow)
+	})
+
+	t.Run("failed check", func(t *testing.T) {
+		health, err := getServiceHealth(ctx, GetServiceHealthParams{Service: "checkout", LokiDatasourceUID: "missing"})
+		require.NoError(t, err)
+		assert.Len(t, health.Alerts, 1)
+		assert.Nil(t, health.Targets)
+		assert.Nil(t, health.ErrorLogs)
+		require.Len(t, health.Errors, 1)
+		assert.Contains(t, health.Errors[0], "errorLogs:")
+	})
+
+	t.Run("invalid log window", func(t *testing.T) {
+		_, err := getServiceHealth(ctx, GetServiceHealthParams{Service: "checkout", LogWindow: "soon"})
+		assert.ErrorContains(t, err, "invalid log window")
+	})
+}
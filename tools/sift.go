@@ -30,6 +30,8 @@ const errorPatternLogExampleLimit = 3
 
 type analysisStatus string
 
+const analysisStatusFinished analysisStatus = "finished"
+
 type investigationRequest struct {
 	AlertLabels map[string]string `json:"alertLabels,omitempty"`
 	Labels      map[string]string `json:"labels"`
@@ -110,35 +112,19 @@ type siftClient struct {
 	url    string
 }
 
-func newSiftClient(cfg mcpgrafana.GrafanaConfig) (*siftClient, error) {
-	// Create custom transport with TLS configuration if available
-	var transport http.RoundTripper = http.DefaultTransport
-	if tlsConfig := cfg.TLSConfig; tlsConfig != nil {
-		var err error
-		transport, err = tlsConfig.HTTPTransport(transport.(*http.Transport))
-		if err != nil {
-			return nil, fmt.Errorf("failed to create custom transport: %w", err)
-		}
-	}
-
-	client := &http.Client{
-		Transport: &authRoundTripper{
-			accessToken: cfg.AccessToken,
-			idToken:     cfg.IDToken,
-			apiKey:      cfg.APIKey,
-			underlying:  transport,
-		},
+func newSiftClient(ctx context.Context) (*siftClient, error) {
+	client, err := newGrafanaHTTPClient(ctx)
+	if err != nil {
+		return nil, err
 	}
 	return &siftClient{
 		client: client,
-		url:    cfg.URL,
+		url:    mcpgrafana.GrafanaConfigFromContext(ctx).URL,
 	}, nil
 }
 
 func siftClientFromContext(ctx context.Context) (*siftClient, error) {
-	// Get the standard Grafana URL and API key
-	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
-	client, err := newSiftClient(cfg)
+	client, err := newSiftClient(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("creating Sift client: %w", err)
 	}
@@ -171,7 +157,15 @@ func getSiftInvestigation(ctx context.Context, args GetSiftInvestigationParams)
 		return nil, fmt.Errorf("invalid investigation ID format: %w", err)
 	}
 
-	investigation, err := client.getSiftInvestigation(ctx, id)
+	// Investigations which have finished or failed no longer change.
+	investigation, err := mcpgrafana.CachedArtifact(ctx, "sift-investigation", id.String(), func() (*Investigation, bool, error) {
+		investigation, err := client.getSiftInvestigation(ctx, id)
+		if err != nil {
+			return nil, false, err
+		}
+		done := investigation.Status == investigationStatusFinished || investigation.Status == investigationStatusFailed
+		return investigation, done, nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("getting investigation: %w", err)
 	}
@@ -213,7 +207,15 @@ func getSiftAnalysis(ctx context.Context, args GetSiftAnalysisParams) (*analysis
 		return nil, fmt.Errorf("invalid analysis ID format: %w", err)
 	}
 
-	analysis, err := client.getSiftAnalysis(ctx, investigationID, analysisID)
+	// Analyses no longer change once finished.
+	id := investigationID.String() + "/" + analysisID.String()
+	analysis, err := mcpgrafana.CachedArtifact(ctx, "sift-analysis", id, func() (*analysis, bool, error) {
+		a, err := client.getSiftAnalysis(ctx, investigationID, analysisID)
+		if err != nil {
+			return nil, false, err
+		}
+		return a, a.Status == analysisStatusFinished, nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("getting analysis: %w", err)
 	}
@@ -302,7 +304,7 @@ func findErrorPatternLogs(ctx context.Context, args FindErrorPatternLogsParams)
 	}
 
 	// Get all analyses from the completed investigation
-	slog.Debug("Getting analyses", "investigation_id", completedInvestigation.ID)
+	slog.DebugContext(ctx, "Getting analyses", "investigation_id", completedInvestigation.ID)
 	analyses, err := client.getSiftAnalyses(ctx, completedInvestigation.ID)
 	if err != nil {
 		return nil, fmt.Errorf("getting analyses: %w", err)
@@ -320,7 +322,7 @@ func findErrorPatternLogs(ctx context.Context, args FindErrorPatternLogsParams)
 	if errorPatternLogsAnalysis == nil {
 		return nil, fmt.Errorf("ErrorPatternLogs analysis not found in investigation %s", completedInvestigation.ID)
 	}
-	slog.Debug("Found ErrorPatternLogs analysis", "analysis_id", errorPatternLogsAnalysis.ID)
+	slog.DebugContext(ctx, "Found ErrorPatternLogs analysis", "analysis_id", errorPatternLogsAnalysis.ID)
 
 	datasourceUID := completedInvestigation.Datasources.LokiDatasource.UID
 
@@ -515,12 +517,12 @@ func (c *siftClient) createSiftInvestigation(ctx context.Context, investigation
 		return nil, fmt.Errorf("marshaling investigation: %w", err)
 	}
 
-	slog.Debug("Creating investigation", "payload", string(jsonData))
+	slog.DebugContext(ctx, "Creating investigation", "payload", string(jsonData))
 	buf, err := c.makeRequest(ctx, "POST", "/api/plugins/grafana-ml-app/resources/sift/api/v1/investigations", jsonData)
 	if err != nil {
 		return nil, err
 	}
-	slog.Debug("Investigation created", "response", string(buf))
+	slog.DebugContext(ctx, "Investigation created", "response", string(buf))
 
 	investigationResponse := struct {
 		Status string        `json:"status"`
@@ -544,7 +546,7 @@ func (c *siftClient) createSiftInvestigation(ctx context.Context, investigation
 		case <-timeout:
 			return nil, fmt.Errorf("timeout waiting for investigation completion after 5 minutes")
 		case <-ticker.C:
-			slog.Debug("Polling investigation status", "investigation_id", investigationResponse.Data.ID)
+			slog.DebugContext(ctx, "Polling investigation status", "investigation_id", investigationResponse.Data.ID)
 			investigation, err := c.getSiftInvestigation(ctx, investigationResponse.Data.ID)
 			if err != nil {
 				return nil, err
@@ -0,0 +1,261 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// sqlDialect holds the queries listing the schema of a SQL database, read
+// from its information schema.
+type sqlDialect struct {
+	databases string
+	// tables returns the query listing tables with their schema, name and
+	// type. Empty arguments select the datasource's defaults.
+	tables func(database, schema string) string
+	// columns returns the query listing the columns of a table with their
+	// name, type and whether they are nullable.
+	columns func(database, schema, table string) string
+}
+
+// sqlString quotes s as a SQL string literal.
+func sqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+var (
+	mysqlDialect = sqlDialect{
+		databases: "SELECT schema_name FROM information_schema.schemata WHERE schema_name NOT IN ('information_schema', 'mysql', 'performance_schema', 'sys') ORDER BY 1",
+		tables: func(database, _ string) string {
+			return "SELECT table_schema, table_name, table_type FROM information_schema.tables WHERE table_schema = " + mysqlDatabase(database) + " ORDER BY 1, 2"
+		},
+		columns: func(database, _, table string) string {
+			return "SELECT column_name, column_type, is_nullable FROM information_schema.columns WHERE table_schema = " + mysqlDatabase(database) + " AND table_name = " + sqlString(table) + " ORDER BY ordinal_position"
+		},
+	}
+	postgresDialect = sqlDialect{
+		databases: "SELECT datname FROM pg_database WHERE NOT datistemplate ORDER BY 1",
+		tables: func(_, schema string) string {
+			return "SELECT table_schema, table_name, table_type FROM information_schema.tables WHERE " + postgresSchema(schema) + " ORDER BY 1, 2"
+		},
+		columns: func(_, schema, table string) string {
+			if schema == "" {
+				schema = "current_schema()"
+			} else {
+				schema = sqlString(schema)
+			}
+			return "SELECT column_name, data_type, is_nullable FROM information_schema.columns WHERE table_schema = " + schema + " AND table_name = " + sqlString(table) + " ORDER BY ordinal_position"
+		},
+	}
+	mssqlDialect = sqlDialect{
+		databases: "SELECT name FROM sys.databases WHERE database_id > 4 ORDER BY 1",
+		tables: func(database, schema string) string {
+			q := "SELECT TABLE_SCHEMA, TABLE_NAME, TABLE_TYPE FROM " + mssqlInformationSchema(database, "TABLES")
+			if schema != "" {
+				q += " WHERE TABLE_SCHEMA = " + sqlString(schema)
+			}
+			return q + " ORDER BY 1, 2"
+		},
+		columns: func(database, schema, table string) string {
+			q := "SELECT COLUMN_NAME, DATA_TYPE, IS_NULLABLE FROM " + mssqlInformationSchema(database, "COLUMNS") + " WHERE TABLE_NAME = " + sqlString(table)
+			if schema != "" {
+				q += " AND TABLE_SCHEMA = " + sqlString(schema)
+			}
+			return q + " ORDER BY ORDINAL_POSITION"
+		},
+	}
+
+	// sqlDialects maps the types of SQL datasources to their dialect.
+	sqlDialects = map[string]sqlDialect{
+		"mysql":                         mysqlDialect,
+		"postgres":                      postgresDialect,
+		"grafana-postgresql-datasource": postgresDialect,
+		"mssql":                         mssqlDialect,
+	}
+)
+
+func mysqlDatabase(database string) string {
+	if database == "" {
+		return "DATABASE()"
+	}
+	return sqlString(database)
+}
+
+// postgresSchema returns the condition selecting tables in the given
+// schema, or in any schema but the system ones.
+func postgresSchema(schema string) string {
+	if schema == "" {
+		return "table_schema NOT IN ('pg_catalog', 'information_schema')"
+	}
+	return "table_schema = " + sqlString(schema)
+}
+
+// mssqlInformationSchema returns the name of an information schema view in
+// the given database, or the datasource's database.
+func mssqlInformationSchema(database, view string) string {
+	if database == "" {
+		return "INFORMATION_SCHEMA." + view
+	}
+	return "[" + strings.ReplaceAll(database, "]", "]]") + "].INFORMATION_SCHEMA." + view
+}
+
+// runSQL runs a query against a SQL datasource, returning the rows of the
+// result as strings.
+func runSQL(ctx context.Context, uid string, query func(sqlDialect) string) ([][]string, error) {
+	ds, err := getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: uid})
+	if err != nil {
+		return nil, err
+	}
+	dialect, ok := sqlDialects[ds.Type]
+	if !ok {
+		return nil, fmt.Errorf("datasource %s is a %s datasource, not a MySQL, PostgreSQL or Microsoft SQL Server datasource", uid, ds.Type)
+	}
+	client, err := newDSQueryClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	frames, err := client.query(ctx, "now-5m", "now", map[string]any{
+		"datasource": map[string]string{"uid": ds.UID, "type": ds.Type},
+		"rawSql":     query(dialect),
+		"rawQuery":   true,
+		"editorMode": "code",
+		"format":     "table",
+	})
+	if err != nil {
+		return nil, err
+	}
+	var rows [][]string
+	for _, f := range frames {
+		for _, row := range f.rows() {
+			values := make([]string, len(row))
+			for i, v := range row {
+				if v != nil {
+					values[i] = fmt.Sprint(v)
+				}
+			}
+			rows = append(rows, values)
+		}
+	}
+	return rows, nil
+}
+
+type ListSQLDatabasesParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the MySQL\\, PostgreSQL or Microsoft SQL Server datasource"`
+}
+
+func listSQLDatabases(ctx context.Context, args ListSQLDatabasesParams) ([]string, error) {
+	rows, err := runSQL(ctx, args.DatasourceUID, func(d sqlDialect) string { return d.databases })
+	if err != nil {
+		return nil, err
+	}
+	databases := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if len(row) > 0 {
+			databases = append(databases, row[0])
+		}
+	}
+	return databases, nil
+}
+
+var ListSQLDatabases = mcpgrafana.MustTool(
+	"grafana_list_sql_databases",
+	"List the databases of a MySQL, PostgreSQL or Microsoft SQL Server datasource, excluding system databases. The query runs with the datasource's credentials, so only databases visible to its user are listed.",
+	listSQLDatabases,
+	mcp.WithTitleAnnotation("List SQL databases"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type ListSQLTablesParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the MySQL\\, PostgreSQL or Microsoft SQL Server datasource"`
+	Database      string `json:"database,omitempty" jsonschema:"description=The database to list tables of (MySQL and Microsoft SQL Server only). Defaults to the datasource's database"`
+	Schema        string `json:"schema,omitempty" jsonschema:"description=The schema to list tables of (PostgreSQL and Microsoft SQL Server only). Defaults to all schemas but the system ones"`
+}
+
+// SQLTable is a table or view of a SQL database.
+type SQLTable struct {
+	Schema string `json:"schema"`
+	Name   string `json:"name"`
+	// Type is "table" or "view", or the type reported by the database for
+	// other kinds of tables.
+	Type string `json:"type"`
+}
+
+func listSQLTables(ctx context.Context, args ListSQLTablesParams) ([]SQLTable, error) {
+	rows, err := runSQL(ctx, args.DatasourceUID, func(d sqlDialect) string { return d.tables(args.Database, args.Schema) })
+	if err != nil {
+		return nil, err
+	}
+	tables := make([]SQLTable, 0, len(rows))
+	for _, row := range rows {
+		if len(row) < 3 {
+			continue
+		}
+		t := SQLTable{Schema: row[0], Name: row[1], Type: strings.ToLower(row[2])}
+		switch t.Type {
+		case "base table":
+			t.Type = "table"
+		case "system view":
+			t.Type = "view"
+		}
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
+
+var ListSQLTables = mcpgrafana.MustTool(
+	"grafana_list_sql_tables",
+	"List the tables and views of a MySQL, PostgreSQL or Microsoft SQL Server datasource with their schema, e.g. to write the query of a SQL panel. Use `grafana_list_sql_columns` to get the columns of a table.",
+	listSQLTables,
+	mcp.WithTitleAnnotation("List SQL tables"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+type ListSQLColumnsParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the MySQL\\, PostgreSQL or Microsoft SQL Server datasource"`
+	Table         string `json:"table" jsonschema:"required,description=The name of the table or view"`
+	Database      string `json:"database,omitempty" jsonschema:"description=The database of the table (MySQL and Microsoft SQL Server only). Defaults to the datasource's database"`
+	Schema        string `json:"schema,omitempty" jsonschema:"description=The schema of the table (PostgreSQL and Microsoft SQL Server only). Defaults to the current schema in PostgreSQL and to any schema in Microsoft SQL Server"`
+}
+
+// SQLColumn is a column of a SQL table.
+type SQLColumn struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+}
+
+func listSQLColumns(ctx context.Context, args ListSQLColumnsParams) ([]SQLColumn, error) {
+	if args.Table == "" {
+		return nil, fmt.Errorf("table is required")
+	}
+	rows, err := runSQL(ctx, args.DatasourceUID, func(d sqlDialect) string { return d.columns(args.Database, args.Schema, args.Table) })
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("table %s not found or has no columns visible to the datasource's user", args.Table)
+	}
+	columns := make([]SQLColumn, 0, len(rows))
+	for _, row := range rows {
+		if len(row) < 3 {
+			continue
+		}
+		columns = append(columns, SQLColumn{Name: row[0], Type: row[1], Nullable: strings.EqualFold(row[2], "yes")})
+	}
+	return columns, nil
+}
+
+var ListSQLColumns = mcpgrafana.MustTool(
+	"grafana_list_sql_columns",
+	"List the columns of a table or view of a MySQL, PostgreSQL or Microsoft SQL Server datasource with their type and whether they are nullable, in the order they are defined, e.g. to write a valid SQL panel query instead of guessing column names.",
+	listSQLColumns,
+	mcp.WithTitleAnnotation("List SQL columns"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
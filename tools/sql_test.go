@@ -0,0 +1,73 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSQLTestContext returns a context with a fake Grafana serving a
+// PostgreSQL and a Loki datasource. Queries are recorded in queries and
+// answered with the given frame values.
+func newSQLTestContext(t *testing.T, queries *[]map[string]any, values string) context.Context {
+	return newFakeGrafanaContext(t, fakeRoutes{
+		"/api/datasources/uid/pg":   jsonBody(`{"uid": "pg", "type": "grafana-postgresql-datasource"}`),
+		"/api/datasources/uid/loki": jsonBody(`{"uid": "loki", "type": "loki"}`),
+		"/api/ds/query": func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Queries []map[string]any `json:"queries"`
+			}
+			if !decodeJSONBody(t, w, r, &body) {
+				return
+			}
+			*queries = append(*queries, body.Queries...)
+			if values == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"results": {"A": {"status": 400, "error": "db query error: permission denied"}}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"results": {"A": {"status": 200, "frames": [{"schema": {"refId": "A"}, "data": {"values": ` + values + `}}]}}}`))
+		},
+	})
+}
+
+func TestListSQLTables(t *testing.T) {
+	var queries []map[string]any
+	ctx := newSQLTestContext(t, &queries, `[["public", "public"], ["orders", "active_orders"], ["BASE TABLE", "VIEW"]]`)
+
+	tables, err := listSQLTables(ctx, ListSQLTablesParams{DatasourceUID: "pg", Schema: "public"})
+	require.NoError(t, err)
+	assert.Equal(t, []SQLTable{{Schema: "public", Name: "orders", Type: "table"}, {Schema: "public", Name: "active_orders", Type: "view"}}, tables)
+	require.Len(t, queries, 1)
+	assert.Equal(t, map[string]any{"uid": "pg", "type": "grafana-postgresql-datasource"}, queries[0]["datasource"])
+	assert.Equal(t, "table", queries[0]["format"])
+	assert.Equal(t, "SELECT table_schema, table_name, table_type FROM information_schema.tables WHERE table_schema = 'public' ORDER BY 1, 2", queries[0]["rawSql"])
+
+	_, err = listSQLTables(ctx, ListSQLTablesParams{DatasourceUID: "loki"})
+	assert.ErrorContains(t, err, "not a MySQL, PostgreSQL or Microsoft SQL Server datasource")
+}
+
+func TestListSQLColumns(t *testing.T) {
+	var queries []map[string]any
+	ctx := newSQLTestContext(t, &queries, `[["id", "note"], ["integer", "text"], ["NO", "YES"]]`)
+
+	columns, err := listSQLColumns(ctx, ListSQLColumnsParams{DatasourceUID: "pg", Table: "o'rders"})
+	require.NoError(t, err)
+	assert.Equal(t, []SQLColumn{{Name: "id", Type: "integer"}, {Name: "note", Type: "text", Nullable: true}}, columns)
+	assert.Equal(t, "SELECT column_name, data_type, is_nullable FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = 'o''rders' ORDER BY ordinal_position", queries[0]["rawSql"])
+
+	ctx = newSQLTestContext(t, &queries, "")
+	_, err = listSQLDatabases(ctx, ListSQLDatabasesParams{DatasourceUID: "pg"})
+	assert.EqualError(t, err, "query failed: db query error: permission denied")
+}
+
+func TestSQLDialects(t *testing.T) {
+	assert.Equal(t, "SELECT column_name, column_type, is_nullable FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = 'orders' ORDER BY ordinal_position", mysqlDialect.columns("", "", "orders"))
+	assert.Equal(t, "SELECT TABLE_SCHEMA, TABLE_NAME, TABLE_TYPE FROM [sales]]db].INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = 'dbo' ORDER BY 1, 2", mssqlDialect.tables("sales]db", "dbo"))
+}
@@ -0,0 +1,531 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/prometheus/common/model"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	// DefaultTraceSpanLimit is the default maximum number of spans returned
+	// by grafana_get_tempo_trace.
+	DefaultTraceSpanLimit = 100
+	// MaxTraceSpanLimit is the maximum number of spans returned.
+	MaxTraceSpanLimit = 1000
+)
+
+// defaultTraceToLogsTags are the span attributes used to build the stream
+// selector of logs pivots if the datasource doesn't configure any, as in
+// Grafana's trace view.
+var defaultTraceToLogsTags = []string{"cluster", "hostname", "namespace", "pod", "service.name", "service.namespace"}
+
+// tempoClient queries a Tempo datasource through the Grafana datasource
+// proxy.
+type tempoClient struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+func newTempoClient(ctx context.Context, ds *models.DataSource) (*tempoClient, error) {
+	cfg := mcpgrafana.GrafanaConfigFromContext(ctx)
+	httpClient, err := newGrafanaHTTPClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tempoClient{
+		httpClient: httpClient,
+		baseURL:    fmt.Sprintf("%s/api/datasources/proxy/uid/%s", strings.TrimRight(cfg.URL, "/"), ds.UID),
+	}, nil
+}
+
+// getTrace fetches a trace in the OTLP JSON format.
+func (c *tempoClient) getTrace(ctx context.Context, traceID string) (*otlpTrace, error) {
+	u := fmt.Sprintf("%s/api/traces/%s", c.baseURL, url.PathEscape(traceID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024*48)) // 48MB limit
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("trace %s not found", traceID)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Tempo API returned status code %d: %s", resp.StatusCode, string(body))
+	}
+	var trace otlpTrace
+	if err := json.Unmarshal(body, &trace); err != nil {
+		return nil, fmt.Errorf("unmarshalling trace: %w", err)
+	}
+	return &trace, nil
+}
+
+// otlpTrace is a trace as returned by Tempo, in the OTLP JSON format.
+type otlpTrace struct {
+	Batches []struct {
+		Resource struct {
+			Attributes []otlpAttribute `json:"attributes"`
+		} `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+		// InstrumentationLibrarySpans is the name of ScopeSpans in older
+		// versions of Tempo.
+		InstrumentationLibrarySpans []otlpScopeSpans `json:"instrumentationLibrarySpans"`
+	} `json:"batches"`
+}
+
+type otlpScopeSpans struct {
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId"`
+	Name              string          `json:"name"`
+	Kind              json.RawMessage `json:"kind"`
+	StartTimeUnixNano otlpInt         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   otlpInt         `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes"`
+	Status            struct {
+		Code    json.RawMessage `json:"code"`
+		Message string          `json:"message"`
+	} `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue *string         `json:"stringValue"`
+		BoolValue   *bool           `json:"boolValue"`
+		IntValue    *otlpInt        `json:"intValue"`
+		DoubleValue *float64        `json:"doubleValue"`
+		Other       json.RawMessage `json:"arrayValue"`
+	} `json:"value"`
+}
+
+func (a otlpAttribute) value() string {
+	v := a.Value
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return strconv.FormatBool(*v.BoolValue)
+	case v.IntValue != nil:
+		return strconv.FormatInt(int64(*v.IntValue), 10)
+	case v.DoubleValue != nil:
+		return strconv.FormatFloat(*v.DoubleValue, 'f', -1, 64)
+	}
+	return string(v.Other)
+}
+
+// otlpInt is a 64-bit integer, which OTLP JSON encodes as a string.
+type otlpInt int64
+
+func (i *otlpInt) UnmarshalJSON(data []byte) error {
+	n, err := strconv.ParseInt(strings.Trim(string(data), `"`), 10, 64)
+	*i = otlpInt(n)
+	return err
+}
+
+// otlpID converts a trace or span ID to hex. Tempo encodes IDs in base64,
+// while other producers of OTLP JSON use hex.
+func otlpID(id string) string {
+	if b, err := hex.DecodeString(id); err == nil && (len(b) == 8 || len(b) == 16) {
+		return strings.ToLower(id)
+	}
+	if b, err := base64.StdEncoding.DecodeString(id); err == nil {
+		return hex.EncodeToString(b)
+	}
+	return id
+}
+
+// otlpEnum returns the name of an enum such as a span kind, which may be
+// encoded as its name or number, without its prefix.
+func otlpEnum(raw json.RawMessage, prefix string, names []string) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return strings.ToLower(strings.TrimPrefix(s, prefix))
+	}
+	var n int
+	if err := json.Unmarshal(raw, &n); err == nil && n > 0 && n < len(names) {
+		return names[n]
+	}
+	return ""
+}
+
+// correlationTag maps a span attribute to a label of the target datasource.
+// Older versions of the trace-to-logs settings list attribute names only.
+type correlationTag struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+func (t *correlationTag) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &t.Key); err == nil {
+		return nil
+	}
+	type tag correlationTag
+	return json.Unmarshal(data, (*tag)(t))
+}
+
+// label returns the label the attribute maps to. Dots are replaced with
+// underscores, as they are invalid in Loki and Prometheus label names.
+func (t correlationTag) label() string {
+	return strings.ReplaceAll(cmp.Or(t.Value, t.Key), ".", "_")
+}
+
+type tracesToLogsConfig struct {
+	DatasourceUID      string           `json:"datasourceUid"`
+	Tags               []correlationTag `json:"tags"`
+	MappedTags         []correlationTag `json:"mappedTags"`
+	MapTagNamesEnabled bool             `json:"mapTagNamesEnabled"`
+	SpanStartTimeShift string           `json:"spanStartTimeShift"`
+	SpanEndTimeShift   string           `json:"spanEndTimeShift"`
+	FilterByTraceID    bool             `json:"filterByTraceID"`
+	FilterBySpanID     bool             `json:"filterBySpanID"`
+	CustomQuery        bool             `json:"customQuery"`
+	Query              string           `json:"query"`
+}
+
+type tracesToMetricsConfig struct {
+	DatasourceUID      string           `json:"datasourceUid"`
+	Tags               []correlationTag `json:"tags"`
+	SpanStartTimeShift string           `json:"spanStartTimeShift"`
+	SpanEndTimeShift   string           `json:"spanEndTimeShift"`
+	Queries            []struct {
+		Name  string `json:"name"`
+		Query string `json:"query"`
+	} `json:"queries"`
+}
+
+// traceCorrelations are the trace-to-logs and trace-to-metrics settings of
+// a Tempo datasource.
+type traceCorrelations struct {
+	logs    *tracesToLogsConfig
+	metrics *tracesToMetricsConfig
+}
+
+func parseTraceCorrelations(jsonData any) (traceCorrelations, error) {
+	var settings struct {
+		TracesToLogsV2  *tracesToLogsConfig    `json:"tracesToLogsV2"`
+		TracesToLogs    *tracesToLogsConfig    `json:"tracesToLogs"`
+		TracesToMetrics *tracesToMetricsConfig `json:"tracesToMetrics"`
+	}
+	data, err := json.Marshal(jsonData)
+	if err == nil {
+		err = json.Unmarshal(data, &settings)
+	}
+	if err != nil {
+		return traceCorrelations{}, fmt.Errorf("parsing datasource settings: %w", err)
+	}
+	var c traceCorrelations
+	if l := settings.TracesToLogsV2; l != nil && l.DatasourceUID != "" {
+		c.logs = l
+	} else if l := settings.TracesToLogs; l != nil && l.DatasourceUID != "" {
+		// The legacy settings map tag names separately.
+		if l.MapTagNamesEnabled {
+			for i, tag := range l.Tags {
+				for _, m := range l.MappedTags {
+					if m.Key == tag.Key {
+						l.Tags[i].Value = m.Value
+					}
+				}
+			}
+		}
+		c.logs = l
+	}
+	if m := settings.TracesToMetrics; m != nil && m.DatasourceUID != "" {
+		c.metrics = m
+	}
+	return c, nil
+}
+
+// TracePivot is a query in another datasource correlated with a span.
+type TracePivot struct {
+	Name          string `json:"name,omitempty"`
+	DatasourceUID string `json:"datasourceUid"`
+	Query         string `json:"query"`
+	Start         string `json:"start"`
+	End           string `json:"end"`
+}
+
+// SpanPivots are the logs and metrics queries correlated with a span.
+type SpanPivots struct {
+	Logs    *TracePivot  `json:"logs,omitempty"`
+	Metrics []TracePivot `json:"metrics,omitempty"`
+}
+
+// TraceSpan is a span of a trace.
+type TraceSpan struct {
+	SpanID       string            `json:"spanId"`
+	ParentSpanID string            `json:"parentSpanId,omitempty"`
+	Name         string            `json:"name"`
+	Service      string            `json:"service,omitempty"`
+	Kind         string            `json:"kind,omitempty"`
+	Start        string            `json:"start"`
+	DurationMs   float64           `json:"durationMs"`
+	Status       string            `json:"status,omitempty"`
+	StatusText   string            `json:"statusMessage,omitempty"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+	Pivots       *SpanPivots       `json:"pivots,omitempty"`
+
+	traceID    string
+	start, end time.Time
+	// tags are the attributes of the span and its resource.
+	tags map[string]string
+}
+
+// parseShift parses a time shift of the correlation settings, e.g. "-1h".
+func parseShift(s string, def time.Duration) time.Duration {
+	s = strings.TrimSpace(s)
+	neg := strings.HasPrefix(s, "-")
+	d, err := model.ParseDuration(strings.TrimPrefix(s, "-"))
+	if s == "" || err != nil {
+		return def
+	}
+	if neg {
+		return -time.Duration(d)
+	}
+	return time.Duration(d)
+}
+
+// pivotRange returns the time range of a pivot, shifting the span's range.
+func (s TraceSpan) pivotRange(startShift, endShift string, def time.Duration) (string, string) {
+	return s.start.Add(parseShift(startShift, -def)).UTC().Format(time.RFC3339Nano),
+		s.end.Add(parseShift(endShift, def)).UTC().Format(time.RFC3339Nano)
+}
+
+// matchers returns a matcher for each tag the span has, e.g. `service_name="api"`.
+func (s TraceSpan) matchers(tags []correlationTag) []string {
+	var matchers []string
+	for _, tag := range tags {
+		if v, ok := s.tags[tag.Key]; ok {
+			matchers = append(matchers, tag.label()+"="+strconv.Quote(v))
+		}
+	}
+	return matchers
+}
+
+var traceVariablePattern = regexp.MustCompile(`\$\{([^}]+)\}|\$__tags`)
+
+// interpolate replaces the variables of a correlation query, as supported
+// by Grafana's trace view, with the values of the span.
+func (s TraceSpan) interpolate(query string, matchers []string) string {
+	return traceVariablePattern.ReplaceAllStringFunc(query, func(v string) string {
+		name := strings.TrimSuffix(strings.TrimPrefix(v, "${"), "}")
+		switch {
+		case name == "$__tags" || name == "__tags":
+			return strings.Join(matchers, ", ")
+		case name == "__span.traceId" || name == "__trace.traceId":
+			return s.traceID
+		case name == "__span.spanId":
+			return s.SpanID
+		case name == "__span.name":
+			return s.Name
+		case strings.HasPrefix(name, "__span.tags.") || strings.HasPrefix(name, "__span.tags["):
+			// Tags are referenced as ${__span.tags.key} or, for keys
+			// containing dots, ${__span.tags["key"]}.
+			key := strings.TrimPrefix(name, "__span.tags")
+			key = strings.Trim(strings.TrimPrefix(key, "."), `[]"'`)
+			return s.tags[key]
+		}
+		return v
+	})
+}
+
+func (s TraceSpan) logsPivot(c *tracesToLogsConfig) *TracePivot {
+	tags := c.Tags
+	if len(tags) == 0 {
+		for _, key := range defaultTraceToLogsTags {
+			tags = append(tags, correlationTag{Key: key})
+		}
+	}
+	matchers := s.matchers(tags)
+	var query string
+	if c.CustomQuery && c.Query != "" {
+		query = s.interpolate(c.Query, matchers)
+	} else {
+		// Without a matching attribute there is no stream selector, so
+		// Grafana doesn't link to logs either.
+		if len(matchers) == 0 {
+			return nil
+		}
+		query = "{" + strings.Join(matchers, ", ") + "}"
+		if c.FilterByTraceID {
+			query += " |= " + strconv.Quote(s.traceID)
+		}
+		if c.FilterBySpanID {
+			query += " |= " + strconv.Quote(s.SpanID)
+		}
+	}
+	start, end := s.pivotRange(c.SpanStartTimeShift, c.SpanEndTimeShift, 0)
+	return &TracePivot{DatasourceUID: c.DatasourceUID, Query: query, Start: start, End: end}
+}
+
+func (s TraceSpan) metricsPivots(c *tracesToMetricsConfig) []TracePivot {
+	matchers := s.matchers(c.Tags)
+	queries := c.Queries
+	if len(queries) == 0 {
+		queries = append(queries, struct {
+			Name  string `json:"name"`
+			Query string `json:"query"`
+		}{Name: "Sample query", Query: "histogram_quantile(0.5, sum(rate(traces_spanmetrics_latency_bucket{$__tags}[5m])) by (le))"})
+	}
+	// Metrics are sampled at intervals, so the range is widened by default.
+	start, end := s.pivotRange(c.SpanStartTimeShift, c.SpanEndTimeShift, 2*time.Minute)
+	pivots := make([]TracePivot, 0, len(queries))
+	for _, q := range queries {
+		if q.Query == "" {
+			continue
+		}
+		pivots = append(pivots, TracePivot{Name: q.Name, DatasourceUID: c.DatasourceUID, Query: s.interpolate(q.Query, matchers), Start: start, End: end})
+	}
+	return pivots
+}
+
+var (
+	otlpSpanKinds   = []string{"unspecified", "internal", "server", "client", "producer", "consumer"}
+	otlpStatusCodes = []string{"unset", "ok", "error"}
+)
+
+// traceSpans converts the spans of a trace, ordered by start time, adding the
+// pivots of the correlation settings.
+func traceSpans(trace *otlpTrace, correlations traceCorrelations) []TraceSpan {
+	var spans []TraceSpan
+	for _, batch := range trace.Batches {
+		resource := map[string]string{}
+		for _, a := range batch.Resource.Attributes {
+			resource[a.Key] = a.value()
+		}
+		for _, scope := range append(batch.ScopeSpans, batch.InstrumentationLibrarySpans...) {
+			for _, s := range scope.Spans {
+				span := TraceSpan{
+					SpanID:       otlpID(s.SpanID),
+					ParentSpanID: otlpID(s.ParentSpanID),
+					Name:         s.Name,
+					Service:      resource["service.name"],
+					Kind:         otlpEnum(s.Kind, "SPAN_KIND_", otlpSpanKinds),
+					Status:       otlpEnum(s.Status.Code, "STATUS_CODE_", otlpStatusCodes),
+					StatusText:   s.Status.Message,
+					traceID:      otlpID(s.TraceID),
+					start:        time.Unix(0, int64(s.StartTimeUnixNano)),
+					end:          time.Unix(0, int64(s.EndTimeUnixNano)),
+					tags:         maps.Clone(resource),
+				}
+				if span.Status == "unset" {
+					span.Status = ""
+				}
+				span.Start = span.start.UTC().Format(time.RFC3339Nano)
+				span.DurationMs = float64(span.end.Sub(span.start).Microseconds()) / 1000
+				if len(s.Attributes) > 0 {
+					span.Attributes = make(map[string]string, len(s.Attributes))
+					for _, a := range s.Attributes {
+						span.Attributes[a.Key] = a.value()
+						span.tags[a.Key] = a.value()
+					}
+				}
+				pivots := &SpanPivots{}
+				if correlations.logs != nil {
+					pivots.Logs = span.logsPivot(correlations.logs)
+				}
+				if correlations.metrics != nil {
+					pivots.Metrics = span.metricsPivots(correlations.metrics)
+				}
+				if pivots.Logs != nil || len(pivots.Metrics) > 0 {
+					span.Pivots = pivots
+				}
+				spans = append(spans, span)
+			}
+		}
+	}
+	slices.SortStableFunc(spans, func(a, b TraceSpan) int { return a.start.Compare(b.start) })
+	return spans
+}
+
+type GetTempoTraceParams struct {
+	DatasourceUID string `json:"datasourceUid" jsonschema:"required,description=The UID of the Tempo datasource"`
+	TraceID       string `json:"traceId" jsonschema:"required,description=The ID of the trace in hex"`
+	Limit         int    `json:"limit,omitempty" jsonschema:"description=The maximum number of spans to return\\, earliest first (default 100\\, max 1000)"`
+}
+
+type GetTempoTraceResult struct {
+	TraceID   string      `json:"traceId"`
+	SpanCount int         `json:"spanCount"`
+	Truncated bool        `json:"truncated,omitempty"`
+	Spans     []TraceSpan `json:"spans"`
+}
+
+func getTempoTrace(ctx context.Context, args GetTempoTraceParams) (*GetTempoTraceResult, error) {
+	limit := args.Limit
+	if limit <= 0 {
+		limit = DefaultTraceSpanLimit
+	}
+	limit = min(limit, MaxTraceSpanLimit)
+
+	ds, err := getDatasourceByUID(ctx, GetDatasourceByUIDParams{UID: args.DatasourceUID})
+	if err != nil {
+		return nil, err
+	}
+	if ds.Type != "tempo" {
+		return nil, fmt.Errorf("datasource %s is a %s datasource, not a Tempo datasource", args.DatasourceUID, ds.Type)
+	}
+	correlations, err := parseTraceCorrelations(ds.JSONData)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newTempoClient(ctx, ds)
+	if err != nil {
+		return nil, fmt.Errorf("creating Tempo client: %w", err)
+	}
+	trace, err := client.getTrace(ctx, strings.TrimSpace(args.TraceID))
+	if err != nil {
+		return nil, err
+	}
+	spans := traceSpans(trace, correlations)
+	return &GetTempoTraceResult{
+		TraceID:   strings.ToLower(strings.TrimSpace(args.TraceID)),
+		SpanCount: len(spans),
+		Truncated: len(spans) > limit,
+		Spans:     spans[:min(len(spans), limit)],
+	}, nil
+}
+
+var GetTempoTrace = mcpgrafana.MustTool(
+	"grafana_get_tempo_trace",
+	"Get a trace from a Tempo datasource by its ID, returning its spans earliest first with their service, duration, status and attributes. If the datasource has trace-to-logs or trace-to-metrics settings, each span includes `pivots`: ready-made LogQL and PromQL queries with their datasource UID and time range, built from the span's attributes the same way as the links in Grafana's trace view. Run them with `grafana_query_loki_logs` or `grafana_query_prometheus` to see the logs or metrics of a span.",
+	getTempoTrace,
+	mcp.WithTitleAnnotation("Get Tempo trace"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+func AddTempoTools(mcp *server.MCPServer) {
+	GetTempoTrace.Register(mcp)
+}
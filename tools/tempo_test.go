@@ -0,0 +1,117 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const tempoTestTrace = `{"batches": [{
+	"resource": {"attributes": [
+		{"key": "service.name", "value": {"stringValue": "api"}},
+		{"key": "namespace", "value": {"stringValue": "prod"}}
+	]},
+	"scopeSpans": [{"spans": [
+		{
+			"traceId": "AAECAwQFBgcICQoLDA0ODw==", "spanId": "AQIDBAUGBwk=", "parentSpanId": "AQIDBAUGBwg=",
+			"name": "SELECT", "kind": "SPAN_KIND_CLIENT",
+			"startTimeUnixNano": "1700000000500000000", "endTimeUnixNano": "1700000000750000000",
+			"attributes": [{"key": "db.system", "value": {"stringValue": "postgres"}}],
+			"status": {"code": "STATUS_CODE_ERROR", "message": "timeout"}
+		},
+		{
+			"traceId": "AAECAwQFBgcICQoLDA0ODw==", "spanId": "AQIDBAUGBwg=",
+			"name": "GET /users", "kind": 2,
+			"startTimeUnixNano": "1700000000000000000", "endTimeUnixNano": "1700000001000000000",
+			"attributes": [{"key": "http.status_code", "value": {"intValue": "500"}}]
+		}
+	]}]
+}]}`
+
+func TestGetTempoTrace(t *testing.T) {
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/datasources/uid/tempo": jsonBody(`{"uid": "tempo", "type": "tempo", "jsonData": {
+				"tracesToLogsV2": {"datasourceUid": "loki", "tags": [{"key": "service.name", "value": "app"}, {"key": "namespace"}], "filterByTraceID": true, "spanStartTimeShift": "-1m"},
+				"tracesToMetrics": {"datasourceUid": "prom", "tags": [{"key": "service.name", "value": "service"}], "queries": [
+					{"name": "Errors", "query": "sum(rate(errors_total{$__tags}[5m]))"}
+				]}
+			}}`),
+		"/api/datasources/uid/loki": jsonBody(`{"uid": "loki", "type": "loki"}`),
+		"/api/datasources/proxy/uid/tempo/api/traces/000102030405060708090a0b0c0d0e0f": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(tempoTestTrace))
+		},
+	})
+	ctx := fakeGrafanaContext(server)
+
+	result, err := getTempoTrace(ctx, GetTempoTraceParams{DatasourceUID: "tempo", TraceID: "000102030405060708090a0b0c0d0e0f"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.SpanCount)
+	assert.False(t, result.Truncated)
+	require.Len(t, result.Spans, 2)
+
+	root, child := result.Spans[0], result.Spans[1]
+	assert.Equal(t, "0102030405060708", root.SpanID)
+	assert.Equal(t, "GET /users", root.Name)
+	assert.Equal(t, "api", root.Service)
+	assert.Equal(t, "server", root.Kind)
+	assert.Equal(t, "2023-11-14T22:13:20Z", root.Start)
+	assert.Equal(t, float64(1000), root.DurationMs)
+	assert.Equal(t, map[string]string{"http.status_code": "500"}, root.Attributes)
+
+	assert.Equal(t, "0102030405060709", child.SpanID)
+	assert.Equal(t, "0102030405060708", child.ParentSpanID)
+	assert.Equal(t, "client", child.Kind)
+	assert.Equal(t, "error", child.Status)
+	assert.Equal(t, "timeout", child.StatusText)
+	require.NotNil(t, child.Pivots)
+	assert.Equal(t, &TracePivot{
+		DatasourceUID: "loki",
+		Query:         `{app="api", namespace="prod"} |= "000102030405060708090a0b0c0d0e0f"`,
+		Start:         "2023-11-14T22:12:20.5Z",
+		End:           "2023-11-14T22:13:20.75Z",
+	}, child.Pivots.Logs)
+	assert.Equal(t, []TracePivot{{
+		Name:          "Errors",
+		DatasourceUID: "prom",
+		Query:         `sum(rate(errors_total{service="api"}[5m]))`,
+		Start:         "2023-11-14T22:11:20.5Z",
+		End:           "2023-11-14T22:15:20.75Z",
+	}}, child.Pivots.Metrics)
+
+	result, err = getTempoTrace(ctx, GetTempoTraceParams{DatasourceUID: "tempo", TraceID: "000102030405060708090a0b0c0d0e0f", Limit: 1})
+	require.NoError(t, err)
+	assert.True(t, result.Truncated)
+	assert.Len(t, result.Spans, 1)
+
+	_, err = getTempoTrace(ctx, GetTempoTraceParams{DatasourceUID: "tempo", TraceID: "ff"})
+	assert.ErrorContains(t, err, "trace ff not found")
+	_, err = getTempoTrace(ctx, GetTempoTraceParams{DatasourceUID: "loki", TraceID: "ff"})
+	assert.ErrorContains(t, err, "not a Tempo datasource")
+}
+
+func TestParseTraceCorrelationsLegacy(t *testing.T) {
+	c, err := parseTraceCorrelations(map[string]any{
+		"tracesToLogs": map[string]any{
+			"datasourceUid":      "loki",
+			"tags":               []any{"cluster", "service.name"},
+			"mappedTags":         []any{map[string]any{"key": "service.name", "value": "service"}},
+			"mapTagNamesEnabled": true,
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, c.logs)
+	assert.Nil(t, c.metrics)
+	assert.Equal(t, []correlationTag{{Key: "cluster"}, {Key: "service.name", Value: "service"}}, c.logs.Tags)
+
+	span := TraceSpan{SpanID: "01", traceID: "02", tags: map[string]string{"service.name": "api", "k8s.pod": "api-0"}}
+	assert.Equal(t, `{service="api"}`, span.logsPivot(c.logs).Query)
+
+	c.logs.CustomQuery = true
+	c.logs.Query = `{${__tags}, pod="${__span.tags["k8s.pod"]}"} |= "${__span.traceId}"`
+	assert.Equal(t, `{service="api", pod="api-0"} |= "02"`, span.logsPivot(c.logs).Query)
+}
@@ -0,0 +1,271 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-openapi-client-go/client/annotations"
+	"github.com/grafana/incident-go"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+const (
+	// DefaultTimelineLimit is the default maximum number of events of a
+	// timeline.
+	DefaultTimelineLimit = 500
+	// MaxTimelineLimit is the maximum number of events of a timeline which
+	// can be requested.
+	MaxTimelineLimit = 2000
+	// maxTimelineIncidents is the maximum number of incidents whose events
+	// are added to a timeline when no incidents are given.
+	maxTimelineIncidents = 20
+)
+
+// timelineSources are the sources of the events of a timeline.
+var timelineSources = []string{"annotation", "alert", "incident"}
+
+type GetTimelineParams struct {
+	StartTime    string   `json:"startTime" jsonschema:"required,description=The start of the time range\\, in RFC3339 or relative to now\\, e.g. 'now-6h'"`
+	EndTime      string   `json:"endTime,omitempty" jsonschema:"description=The end of the time range\\, in RFC3339 or relative to now. Defaults to now"`
+	Sources      []string `json:"sources,omitempty" jsonschema:"description=Optionally\\, the sources of events to include: 'annotation' for annotations\\, 'alert' for alert state changes and 'incident' for the activity of incidents. Defaults to all of them"`
+	DashboardUID string   `json:"dashboardUid,omitempty" jsonschema:"description=Optionally\\, only include the annotations and alert state changes of this dashboard"`
+	Tags         []string `json:"tags,omitempty" jsonschema:"description=Optionally\\, only include annotations with all of these tags"`
+	IncidentIDs  []string `json:"incidentIds,omitempty" jsonschema:"description=Optionally\\, the IDs of the incidents whose activity to include. Defaults to the non-drill incidents overlapping the time range"`
+	Limit        int      `json:"limit,omitempty" jsonschema:"description=The maximum number of events to return (default 500\\, maximum 2000). The earliest events are kept"`
+}
+
+// TimelineEvent is an event of a timeline: an annotation, a change of the
+// state of an alert or an activity of an incident.
+type TimelineEvent struct {
+	Time    time.Time  `json:"time"`
+	EndTime *time.Time `json:"endTime,omitempty"`
+	// Source is the source of the event: 'annotation', 'alert' or
+	// 'incident'.
+	Source       string   `json:"source"`
+	Text         string   `json:"text,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	User         string   `json:"user,omitempty"`
+	DashboardUID string   `json:"dashboardUid,omitempty"`
+	PanelID      int64    `json:"panelId,omitempty"`
+	AlertName    string   `json:"alertName,omitempty"`
+	PrevState    string   `json:"prevState,omitempty"`
+	NewState     string   `json:"newState,omitempty"`
+	IncidentID   string   `json:"incidentId,omitempty"`
+	// IncidentTitle is only set for incidents found by their time range.
+	IncidentTitle string `json:"incidentTitle,omitempty"`
+	// Kind is the kind of an incident activity, e.g. 'userNote' or
+	// 'statusChanged'.
+	Kind string `json:"kind,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+// Timeline is the chronologically ordered events of a time range.
+type Timeline struct {
+	Start  time.Time       `json:"start"`
+	End    time.Time       `json:"end"`
+	Events []TimelineEvent `json:"events"`
+	// Omitted is the number of events left out because of the limit.
+	Omitted int `json:"omitted,omitempty"`
+	// Errors are the sources which could not be read.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// millisToTime converts a Unix time in milliseconds to a time, or nil for
+// zero.
+func millisToTime(ms int64) *time.Time {
+	if ms == 0 {
+		return nil
+	}
+	t := time.UnixMilli(ms).UTC()
+	return &t
+}
+
+// annotationEvents returns the annotations and alert state changes of a time
+// range. Grafana stores the state history of alert rules as annotations,
+// which are told apart by their new state.
+func annotationEvents(ctx context.Context, args GetTimelineParams, start, end time.Time, limit int) ([]TimelineEvent, error) {
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	from, to, n := start.UnixMilli(), end.UnixMilli(), int64(limit)
+	params := annotations.NewGetAnnotationsParamsWithContext(ctx)
+	params.From, params.To, params.Limit = &from, &to, &n
+	if args.DashboardUID != "" {
+		params.DashboardUID = &args.DashboardUID
+	}
+	params.Tags = args.Tags
+	resp, err := c.Annotations.GetAnnotations(params)
+	if err != nil {
+		return nil, fmt.Errorf("get annotations: %w", err)
+	}
+	var events []TimelineEvent
+	for _, a := range resp.Payload {
+		event := TimelineEvent{
+			Time:         time.UnixMilli(a.Time).UTC(),
+			Source:       "annotation",
+			Text:         a.Text,
+			Tags:         a.Tags,
+			User:         a.Login,
+			DashboardUID: a.DashboardUID,
+			PanelID:      a.PanelID,
+		}
+		if a.TimeEnd != a.Time {
+			event.EndTime = millisToTime(a.TimeEnd)
+		}
+		if a.NewState != "" {
+			event.Source = "alert"
+			event.AlertName = a.AlertName
+			event.PrevState = a.PrevState
+			event.NewState = a.NewState
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// timelineIncidents returns the non-drill incidents overlapping a time range,
+// by ID with their titles.
+func timelineIncidents(ctx context.Context, client *incident.Client, start, end time.Time) (map[string]string, error) {
+	resp, err := incident.NewIncidentsService(client).QueryIncidentPreviews(ctx, incident.QueryIncidentPreviewsRequest{
+		Query: incident.IncidentPreviewsQuery{
+			QueryString:    "isdrill:false",
+			OrderDirection: "DESC",
+			Limit:          maxTimelineIncidents,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list incidents: %w", err)
+	}
+	incidents := map[string]string{}
+	for _, i := range resp.IncidentPreviews {
+		started, err := time.Parse(time.RFC3339, cmp.Or(i.IncidentStart, i.CreatedTime))
+		if err != nil || started.After(end) {
+			continue
+		}
+		// Incidents which are still active have no end.
+		if ended, err := time.Parse(time.RFC3339, i.IncidentEnd); err == nil && ended.Before(start) {
+			continue
+		}
+		incidents[i.IncidentID] = i.Title
+	}
+	return incidents, nil
+}
+
+// incidentEvents returns the activity of incidents in a time range, either
+// of the given incidents or of those overlapping the time range.
+func incidentEvents(ctx context.Context, ids []string, start, end time.Time, limit int) ([]TimelineEvent, error) {
+	client := mcpgrafana.IncidentClientFromContext(ctx)
+	if client == nil {
+		return nil, fmt.Errorf("incident client not configured")
+	}
+	incidents := map[string]string{}
+	for _, id := range ids {
+		incidents[id] = ""
+	}
+	if len(ids) == 0 {
+		var err error
+		if incidents, err = timelineIncidents(ctx, client, start, end); err != nil {
+			return nil, err
+		}
+	}
+	activity := incident.NewActivityService(client)
+	var events []TimelineEvent
+	for _, id := range slices.Sorted(maps.Keys(incidents)) {
+		resp, err := activity.QueryActivity(ctx, incident.QueryActivityRequest{
+			Query: incident.ActivityQuery{IncidentID: id, Limit: limit, OrderDirection: "ASC"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("query activity of incident %s: %w", id, err)
+		}
+		for _, item := range resp.ActivityItems {
+			t, err := time.Parse(time.RFC3339, cmp.Or(item.EventTime, item.CreatedTime))
+			if err != nil || t.Before(start) || t.After(end) {
+				continue
+			}
+			events = append(events, TimelineEvent{
+				Time:          t.UTC(),
+				Source:        "incident",
+				Text:          item.Body,
+				Tags:          item.Tags,
+				User:          item.User.Name,
+				IncidentID:    id,
+				IncidentTitle: incidents[id],
+				Kind:          item.ActivityKind,
+				URL:           item.URL,
+			})
+		}
+	}
+	return events, nil
+}
+
+func getTimeline(ctx context.Context, args GetTimelineParams) (*Timeline, error) {
+	start, err := parseTime(args.StartTime)
+	if err != nil {
+		return nil, fmt.Errorf("parsing start time: %w", err)
+	}
+	end, err := parseTime(cmp.Or(args.EndTime, "now"))
+	if err != nil {
+		return nil, fmt.Errorf("parsing end time: %w", err)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end time %s is before start time %s", end.Format(time.RFC3339), start.Format(time.RFC3339))
+	}
+	sources := args.Sources
+	if len(sources) == 0 {
+		sources = timelineSources
+	}
+	for _, s := range sources {
+		if !slices.Contains(timelineSources, s) {
+			return nil, fmt.Errorf("invalid source %q: must be one of %s", s, strings.Join(timelineSources, ", "))
+		}
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = DefaultTimelineLimit
+	}
+	limit = min(limit, MaxTimelineLimit)
+
+	// Sources which can't be read, e.g. when the incident plugin isn't
+	// installed, shouldn't hide the events of the others.
+	result := &Timeline{Start: start.UTC(), End: end.UTC(), Events: []TimelineEvent{}}
+	if slices.Contains(sources, "annotation") || slices.Contains(sources, "alert") {
+		events, err := annotationEvents(ctx, args, start, end, limit)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("annotations: %s", err))
+		}
+		for _, e := range events {
+			if slices.Contains(sources, e.Source) {
+				result.Events = append(result.Events, e)
+			}
+		}
+	}
+	if slices.Contains(sources, "incident") {
+		events, err := incidentEvents(ctx, args.IncidentIDs, start, end, limit)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("incidents: %s", err))
+		}
+		result.Events = append(result.Events, events...)
+	}
+
+	slices.SortStableFunc(result.Events, func(a, b TimelineEvent) int { return a.Time.Compare(b.Time) })
+	if len(result.Events) > limit {
+		result.Omitted = len(result.Events) - limit
+		result.Events = result.Events[:limit]
+		mcpgrafana.SetResultNote(ctx, "timelineLimit", fmt.Sprintf("%d later events omitted; narrow the time range, filter the sources or raise limit to see them.", result.Omitted))
+	}
+	return result, nil
+}
+
+var GetTimeline = mcpgrafana.MustTool(
+	"grafana_get_timeline",
+	"Get a single chronologically ordered timeline of what happened in a time range, merging annotations (e.g. deploys), alert state changes and the activity of incidents (notes, status and severity changes, ...), e.g. as the backbone of a retrospective. Annotations and alert state changes can be limited to a dashboard or annotation tags. Without `incidentIds`, the activity of the non-drill incidents overlapping the time range is included. Sources which could not be read, e.g. when Grafana Incident isn't installed, are listed in `errors` while the events of the others are still returned.",
+	getTimeline,
+	mcp.WithTitleAnnotation("Get timeline"),
+	mcp.WithIdempotentHintAnnotation(true),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
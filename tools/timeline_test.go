@@ -0,0 +1,81 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/grafana/incident-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+func TestGetTimeline(t *testing.T) {
+	server := newFakeGrafana(t, fakeRoutes{
+		"/api/annotations": jsonBody(`[
+			{"id": 1, "time": 1736157600000, "timeEnd": 1736157600000, "text": "deploy checkout v42", "tags": ["deploy"], "login": "ci"},
+			{"id": 2, "alertId": 7, "alertName": "HighLatency", "time": 1736157900000, "timeEnd": 1736157900000, "prevState": "Normal", "newState": "Alerting", "dashboardUID": "checkout", "panelId": 3}
+		]`),
+		"/incident/IncidentsService.QueryIncidentPreviews": jsonBody(`{"incidentPreviews": [
+			{"incidentID": "12", "title": "Checkout slow", "incidentStart": "2025-01-06T10:06:00Z"},
+			{"incidentID": "9", "title": "Old outage", "incidentStart": "2025-01-01T10:00:00Z", "incidentEnd": "2025-01-01T11:00:00Z"}
+		]}`),
+		"/incident/ActivityService.QueryActivity": jsonBody(`{"activityItems": [
+			{"activityItemID": "a1", "incidentID": "12", "activityKind": "incidentCreated", "eventTime": "2025-01-06T10:06:00Z", "body": "Incident declared", "user": {"name": "Alice"}},
+			{"activityItemID": "a2", "incidentID": "12", "activityKind": "userNote", "eventTime": "2025-01-06T09:30:00Z", "body": "before the range"}
+		]}`),
+		"/missing/IncidentsService.QueryIncidentPreviews": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": "plugin not found"}`))
+		},
+	})
+	ctx := fakeGrafanaContext(server)
+	ctx = mcpgrafana.WithIncidentClient(ctx, incident.NewClient(server.URL+"/incident/", "test-api-key"))
+
+	t.Run("all sources", func(t *testing.T) {
+		timeline, err := getTimeline(ctx, GetTimelineParams{StartTime: "2025-01-06T09:55:00Z", EndTime: "2025-01-06T11:00:00Z"})
+		require.NoError(t, err)
+		assert.Empty(t, timeline.Errors)
+		require.Len(t, timeline.Events, 3)
+
+		assert.Equal(t, "annotation", timeline.Events[0].Source)
+		assert.Equal(t, "deploy checkout v42", timeline.Events[0].Text)
+		assert.Nil(t, timeline.Events[0].EndTime)
+
+		assert.Equal(t, "alert", timeline.Events[1].Source)
+		assert.Equal(t, "HighLatency", timeline.Events[1].AlertName)
+		assert.Equal(t, "Alerting", timeline.Events[1].NewState)
+
+		assert.Equal(t, "incident", timeline.Events[2].Source)
+		assert.Equal(t, "12", timeline.Events[2].IncidentID)
+		assert.Equal(t, "Checkout slow", timeline.Events[2].IncidentTitle)
+		assert.Equal(t, "incidentCreated", timeline.Events[2].Kind)
+		assert.Equal(t, "Alice", timeline.Events[2].User)
+	})
+
+	t.Run("filtered sources and limit", func(t *testing.T) {
+		timeline, err := getTimeline(ctx, GetTimelineParams{StartTime: "2025-01-06T09:55:00Z", EndTime: "2025-01-06T11:00:00Z", Sources: []string{"alert", "incident"}, Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, timeline.Events, 1)
+		assert.Equal(t, "alert", timeline.Events[0].Source)
+		assert.Equal(t, 1, timeline.Omitted)
+	})
+
+	t.Run("invalid source", func(t *testing.T) {
+		_, err := getTimeline(ctx, GetTimelineParams{StartTime: "now-1h", Sources: []string{"logs"}})
+		assert.ErrorContains(t, err, "invalid source")
+	})
+
+	t.Run("unreadable source", func(t *testing.T) {
+		ctx := mcpgrafana.WithIncidentClient(ctx, incident.NewClient(server.URL+"/missing/", "test-api-key"))
+		timeline, err := getTimeline(ctx, GetTimelineParams{StartTime: "2025-01-06T09:55:00Z", EndTime: "2025-01-06T11:00:00Z"})
+		require.NoError(t, err)
+		assert.Len(t, timeline.Events, 2)
+		require.Len(t, timeline.Errors, 1)
+		assert.Contains(t, timeline.Errors[0], "incidents:")
+	})
+}
@@ -0,0 +1,403 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	aapi "github.com/grafana/amixr-api-go-client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	mcpgrafana "github.com/grafana/mcp-grafana"
+)
+
+// Templates of the starter dashboard and baseline alert rules of a
+// workspace.
+const (
+	workspaceTemplateHTTP   = "http"
+	workspaceTemplateWorker = "worker"
+)
+
+// Actions reported for each resource of a workspace.
+const (
+	workspaceActionCreated = "created"
+	workspaceActionExists  = "exists"
+	workspaceActionSkipped = "skipped"
+)
+
+// workspaceRuleInterval is the evaluation interval of the baseline alert
+// rules, in seconds.
+const workspaceRuleInterval = 60
+
+// serviceNamePattern matches service names usable in dashboard UIDs, label
+// values and OnCall routing templates.
+var serviceNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,29}$`)
+
+type BootstrapWorkspaceParams struct {
+	ServiceName           string `json:"serviceName" jsonschema:"required,description=The name of the service\\, in lower case with digits\\, '-' and '_' (at most 30 characters). It names the folder\\, dashboard and alert rules and is set as the service label of the alerts"`
+	Team                  string `json:"team,omitempty" jsonschema:"description=The team owning the service\\, set as the team label of the alerts and a tag of the dashboard"`
+	Template              string `json:"template,omitempty" jsonschema:"enum=http,enum=worker,description=The kind of service: http (default) for request rate\\, errors and latency of an HTTP service\\, worker for a service without HTTP metrics"`
+	PrometheusUID         string `json:"prometheusUid" jsonschema:"required,description=The UID of the Prometheus datasource with the metrics of the service"`
+	LokiUID               string `json:"lokiUid,omitempty" jsonschema:"description=Optionally\\, the UID of a Loki datasource with the logs of the service\\, shown on the dashboard"`
+	Selector              string `json:"selector,omitempty" jsonschema:"description=The label matchers selecting the metrics and logs of the service (default: the job label equal to the service name)"`
+	FolderTitle           string `json:"folderTitle,omitempty" jsonschema:"description=The title of the folder of the dashboard and alert rules (default the service name). An existing folder with this title is used"`
+	OnCallIntegrationID   string `json:"oncallIntegrationId,omitempty" jsonschema:"description=Optionally\\, the ID of the OnCall integration receiving the alerts of Grafana\\, to add a route for the alerts of the service to"`
+	OnCallEscalationChain string `json:"oncallEscalationChainId,omitempty" jsonschema:"description=The ID of the escalation chain the OnCall route pages. Required with oncallIntegrationId"`
+}
+
+// WorkspaceResource is a resource of a workspace, and whether it was created
+// or already existed.
+type WorkspaceResource struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	UID    string `json:"uid,omitempty"`
+	URL    string `json:"url,omitempty"`
+	Action string `json:"action"`
+}
+
+type BootstrapWorkspaceResult struct {
+	ServiceName string              `json:"serviceName"`
+	FolderUID   string              `json:"folderUid"`
+	Resources   []WorkspaceResource `json:"resources"`
+	// Skipped are the optional resources which weren't asked for.
+	Skipped []WorkspaceResource `json:"skipped,omitempty"`
+}
+
+// workspaceQuery is a PromQL query of a panel of the starter dashboard of a
+// workspace.
+type workspaceQuery struct {
+	title string
+	unit  string
+	expr  string
+	// alert is the condition of the baseline alert rule on the query, if
+	// there is one.
+	alert *workspaceAlert
+}
+
+// workspaceAlert is a baseline alert rule, firing when the value of a query
+// compares to the threshold with the evaluator for the pending period.
+type workspaceAlert struct {
+	title     string
+	summary   string
+	evaluator string
+	threshold float64
+	pending   string
+	// noDataState is the state of the rule when its query returns no data,
+	// NoData if it is not set.
+	noDataState string
+}
+
+// workspaceQueries returns the queries of a workspace template, using the
+// selector of the service.
+func workspaceQueries(template, selector string) []workspaceQuery {
+	queries := []workspaceQuery{{
+		title: "Instances up",
+		unit:  "none",
+		expr:  fmt.Sprintf("sum(up{%s})", selector),
+		// The up series disappear with the targets of the service, so the
+		// rule fires when there is no data.
+		alert: &workspaceAlert{title: "down", summary: "No instance of the service is up", evaluator: "lt", threshold: 1, pending: "5m", noDataState: "Alerting"},
+	}}
+	switch template {
+	case workspaceTemplateWorker:
+		queries = append(queries,
+			workspaceQuery{title: "CPU usage", unit: "percentunit", expr: fmt.Sprintf("sum(rate(process_cpu_seconds_total{%s}[5m]))", selector)},
+			workspaceQuery{title: "Memory usage", unit: "bytes", expr: fmt.Sprintf("sum(process_resident_memory_bytes{%s})", selector)},
+		)
+	default:
+		queries = append(queries,
+			workspaceQuery{title: "Request rate", unit: "reqps", expr: fmt.Sprintf("sum(rate(http_requests_total{%s}[5m]))", selector)},
+			workspaceQuery{
+				title: "Error ratio",
+				unit:  "percentunit",
+				expr:  fmt.Sprintf(`sum(rate(http_requests_total{%[1]s, status=~"5.."}[5m])) / sum(rate(http_requests_total{%[1]s}[5m]))`, selector),
+				alert: &workspaceAlert{title: "high error ratio", summary: "More than 5% of requests fail", evaluator: "gt", threshold: 0.05, pending: "10m"},
+			},
+			workspaceQuery{
+				title: "p95 latency",
+				unit:  "s",
+				expr:  fmt.Sprintf("histogram_quantile(0.95, sum by (le) (rate(http_request_duration_seconds_bucket{%s}[5m])))", selector),
+				alert: &workspaceAlert{title: "high latency", summary: "The 95th percentile latency is above 1s", evaluator: "gt", threshold: 1, pending: "10m"},
+			},
+		)
+	}
+	return queries
+}
+
+// starterDashboard returns the starter dashboard of a workspace, with a time
+// series panel per query and a logs panel if there is a Loki datasource.
+func starterDashboard(uid string, args BootstrapWorkspaceParams, selector string, queries []workspaceQuery) map[string]any {
+	panels := make([]any, 0, len(queries)+1)
+	for i, q := range queries {
+		panels = append(panels, map[string]any{
+			"id":         i + 1,
+			"type":       "timeseries",
+			"title":      q.title,
+			"gridPos":    map[string]any{"x": (i % 2) * 12, "y": (i / 2) * 8, "w": 12, "h": 8},
+			"datasource": map[string]any{"type": "prometheus", "uid": args.PrometheusUID},
+			"fieldConfig": map[string]any{
+				"defaults":  map[string]any{"unit": q.unit},
+				"overrides": []any{},
+			},
+			"targets": []any{map[string]any{"refId": "A", "expr": q.expr, "datasource": map[string]any{"type": "prometheus", "uid": args.PrometheusUID}}},
+		})
+	}
+	if args.LokiUID != "" {
+		panels = append(panels, map[string]any{
+			"id":         len(queries) + 1,
+			"type":       "logs",
+			"title":      "Logs",
+			"gridPos":    map[string]any{"x": 0, "y": ((len(queries) + 1) / 2) * 8, "w": 24, "h": 10},
+			"datasource": map[string]any{"type": "loki", "uid": args.LokiUID},
+			"targets":    []any{map[string]any{"refId": "A", "expr": fmt.Sprintf("{%s}", selector), "datasource": map[string]any{"type": "loki", "uid": args.LokiUID}}},
+		})
+	}
+	tags := []any{args.ServiceName}
+	if args.Team != "" {
+		tags = append(tags, args.Team)
+	}
+	return map[string]any{
+		"uid":           uid,
+		"title":         fmt.Sprintf("%s overview", args.ServiceName),
+		"tags":          tags,
+		"time":          map[string]any{"from": "now-6h", "to": "now"},
+		"schemaVersion": 39,
+		"panels":        panels,
+	}
+}
+
+// baselineRules returns the baseline alert rules of a workspace, in the
+// format of the provisioning API.
+func baselineRules(args BootstrapWorkspaceParams, folderUID, group, dashboardUID string, queries []workspaceQuery) []any {
+	labels := map[string]string{"service": args.ServiceName}
+	if args.Team != "" {
+		labels["team"] = args.Team
+	}
+	var rules []any
+	for i, q := range queries {
+		if q.alert == nil {
+			continue
+		}
+		rules = append(rules, map[string]any{
+			"title":     fmt.Sprintf("%s %s", args.ServiceName, q.alert.title),
+			"folderUID": folderUID,
+			"ruleGroup": group,
+			"condition": "B",
+			"data": []any{
+				map[string]any{
+					"refId":             "A",
+					"datasourceUid":     args.PrometheusUID,
+					"relativeTimeRange": map[string]any{"from": 600, "to": 0},
+					"model":             map[string]any{"refId": "A", "expr": q.expr, "instant": true},
+				},
+				map[string]any{
+					"refId":         "B",
+					"datasourceUid": "__expr__",
+					"model": map[string]any{
+						"refId":      "B",
+						"type":       "threshold",
+						"expression": "A",
+						"conditions": []any{map[string]any{"evaluator": map[string]any{"type": q.alert.evaluator, "params": []any{q.alert.threshold}}}},
+					},
+				},
+			},
+			"for":          q.alert.pending,
+			"noDataState":  cmp.Or(q.alert.noDataState, "NoData"),
+			"execErrState": "Error",
+			"labels":       labels,
+			"annotations": map[string]string{
+				"summary":          q.alert.summary,
+				"__dashboardUid__": dashboardUID,
+				"__panelId__":      fmt.Sprintf("%d", i+1),
+			},
+		})
+	}
+	return rules
+}
+
+// bootstrapDashboard creates the starter dashboard of a workspace unless a
+// dashboard with its UID exists.
+func bootstrapDashboard(ctx context.Context, uid, folderUID string, args BootstrapWorkspaceParams, selector string, queries []workspaceQuery) (WorkspaceResource, error) {
+	resource := WorkspaceResource{Kind: "dashboard", Name: fmt.Sprintf("%s overview", args.ServiceName), UID: uid}
+	existing, err := getDashboardByUID(ctx, GetDashboardByUIDParams{UID: uid})
+	if err == nil {
+		resource.Action = workspaceActionExists
+		if existing.Meta != nil {
+			resource.URL = existing.Meta.URL
+		}
+		return resource, nil
+	}
+	if err := classifyAPIError(err); !errors.Is(err, errNotFound) {
+		return resource, fmt.Errorf("get dashboard %s: %w", uid, err)
+	}
+	saved, err := updateDashboard(ctx, UpdateDashboardParams{
+		Dashboard: starterDashboard(uid, args, selector, queries),
+		FolderUID: folderUID,
+		Message:   "Created by grafana_bootstrap_workspace",
+	})
+	if err != nil {
+		return resource, err
+	}
+	resource.Action = workspaceActionCreated
+	if saved.URL != nil {
+		resource.URL = *saved.URL
+	}
+	return resource, nil
+}
+
+// bootstrapRuleGroup creates the rule group of the baseline alert rules of a
+// workspace unless it exists.
+func bootstrapRuleGroup(ctx context.Context, folderUID, dashboardUID string, args BootstrapWorkspaceParams, queries []workspaceQuery) (WorkspaceResource, error) {
+	group := fmt.Sprintf("%s baseline", args.ServiceName)
+	resource := WorkspaceResource{Kind: "alertRuleGroup", Name: group}
+	c := mcpgrafana.GrafanaClientFromContext(ctx)
+	_, err := c.Provisioning.GetAlertRuleGroup(group, folderUID)
+	if err == nil {
+		resource.Action = workspaceActionExists
+		return resource, nil
+	}
+	if err := classifyAPIError(err); !errors.Is(err, errNotFound) {
+		return resource, fmt.Errorf("get rule group %s: %w", group, err)
+	}
+	ac, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return resource, err
+	}
+	body := map[string]any{
+		"title":     group,
+		"folderUid": folderUID,
+		"interval":  workspaceRuleInterval,
+		"rules":     baselineRules(args, folderUID, group, dashboardUID, queries),
+	}
+	path := ruleGroupPath(folderUID, group)
+	if err := ac.sendJSON(ctx, http.MethodPut, path, body, nil); err != nil {
+		return resource, fmt.Errorf("create rule group %s: %w", group, err)
+	}
+	resource.Action = workspaceActionCreated
+	return resource, nil
+}
+
+// bootstrapOnCallRoute adds a route for the alerts of the service to an
+// OnCall integration unless the integration has one.
+func bootstrapOnCallRoute(ctx context.Context, args BootstrapWorkspaceParams) (WorkspaceResource, error) {
+	template := fmt.Sprintf(`{{ payload.commonLabels.service == "%s" }}`, args.ServiceName)
+	resource := WorkspaceResource{Kind: "oncallRoute", Name: template}
+	client, err := oncallClientFromContext(ctx)
+	if err != nil {
+		return resource, fmt.Errorf("getting OnCall client: %w", err)
+	}
+	routes := aapi.NewRouteService(client)
+	existing, _, err := routes.ListRoutes(&aapi.ListRouteOptions{IntegrationId: args.OnCallIntegrationID, RoutingRegex: template})
+	if err != nil {
+		return resource, fmt.Errorf("listing routes of integration %s: %w", args.OnCallIntegrationID, err)
+	}
+	for _, r := range existing.Routes {
+		if r.RoutingRegex == template {
+			resource.UID = r.ID
+			resource.Action = workspaceActionExists
+			return resource, nil
+		}
+	}
+	route, _, err := routes.CreateRoute(&aapi.CreateRouteOptions{
+		IntegrationId:     args.OnCallIntegrationID,
+		EscalationChainId: args.OnCallEscalationChain,
+		RoutingType:       "jinja2",
+		RoutingRegex:      template,
+	})
+	if err != nil {
+		return resource, fmt.Errorf("creating route of integration %s: %w", args.OnCallIntegrationID, err)
+	}
+	resource.UID = route.ID
+	resource.Action = workspaceActionCreated
+	return resource, nil
+}
+
+func bootstrapWorkspace(ctx context.Context, args BootstrapWorkspaceParams) (*BootstrapWorkspaceResult, error) {
+	if !serviceNamePattern.MatchString(args.ServiceName) {
+		return nil, fmt.Errorf("invalid service name %q: use at most 30 lower case letters, digits, '-' and '_'", args.ServiceName)
+	}
+	template := args.Template
+	if template == "" {
+		template = workspaceTemplateHTTP
+	}
+	if template != workspaceTemplateHTTP && template != workspaceTemplateWorker {
+		return nil, fmt.Errorf("invalid template %q: must be http or worker", args.Template)
+	}
+	if (args.OnCallIntegrationID == "") != (args.OnCallEscalationChain == "") {
+		return nil, fmt.Errorf("oncallIntegrationId and oncallEscalationChainId must be given together")
+	}
+	selector := args.Selector
+	if selector == "" {
+		selector = fmt.Sprintf("job=%q", args.ServiceName)
+	}
+	folderTitle := args.FolderTitle
+	if folderTitle == "" {
+		folderTitle = args.ServiceName
+	}
+	queries := workspaceQueries(template, selector)
+	result := &BootstrapWorkspaceResult{ServiceName: args.ServiceName, Resources: []WorkspaceResource{}}
+
+	// Resources are created in dependency order, and existing ones are left
+	// as they are, so that a failed bootstrap can be completed by calling
+	// the tool again.
+	ac, err := newAlertingClientFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("bootstrap workspace: %w", err)
+	}
+	folders := &folderResolver{c: ac}
+	folderUID, err := folders.resolve(ctx, folderTitle)
+	if err != nil {
+		return nil, fmt.Errorf("bootstrap workspace: %w", err)
+	}
+	result.FolderUID = folderUID
+	action := workspaceActionExists
+	if folders.created[folderTitle] {
+		action = workspaceActionCreated
+	}
+	result.Resources = append(result.Resources, WorkspaceResource{Kind: "folder", Name: folderTitle, UID: folderUID, Action: action})
+
+	dashboardUID := args.ServiceName + "-overview"
+	steps := []func() (WorkspaceResource, error){
+		func() (WorkspaceResource, error) {
+			return bootstrapDashboard(ctx, dashboardUID, folderUID, args, selector, queries)
+		},
+		func() (WorkspaceResource, error) {
+			return bootstrapRuleGroup(ctx, folderUID, dashboardUID, args, queries)
+		},
+	}
+	if args.OnCallIntegrationID != "" {
+		steps = append(steps, func() (WorkspaceResource, error) { return bootstrapOnCallRoute(ctx, args) })
+	} else {
+		result.Skipped = append(result.Skipped, WorkspaceResource{Kind: "oncallRoute", Action: workspaceActionSkipped})
+	}
+	for _, step := range steps {
+		resource, err := step()
+		if err != nil {
+			return nil, fmt.Errorf("bootstrap workspace: %w (%d resources were set up before the failure; call again to complete the workspace)", err, len(result.Resources))
+		}
+		result.Resources = append(result.Resources, resource)
+	}
+	return result, nil
+}
+
+var BootstrapWorkspace = mcpgrafana.MustTool(
+	"grafana_bootstrap_workspace",
+	"Set up the standard observability workspace of a new service in one call: a folder, a starter dashboard (`<serviceName>-overview`) with the request rate, error ratio and p95 latency of an HTTP service or the CPU and memory usage of a worker, plus its logs if a Loki datasource is given, a rule group of baseline alert rules (service down, and high error ratio and latency for HTTP services) labelled with the service and team, and optionally a route of an OnCall integration paging an escalation chain for the alerts of the service. Metrics are selected by `job=\"<serviceName>\"` unless `selector` is given. Resources which already exist are left unchanged and reported as `exists`, so the tool can be called again after a failure. Only use it after confirmation from the user.",
+	bootstrapWorkspace,
+	mcp.WithTitleAnnotation("Bootstrap service workspace"),
+	mcp.WithDestructiveHintAnnotation(false),
+	mcp.WithIdempotentHintAnnotation(true),
+)
+
+// AddWorkspaceTools registers the tools setting up workspaces of services,
+// which all create resources and are only registered if write tools are
+// enabled, see mcpgrafana.WriteToolsEnabled.
+func AddWorkspaceTools(mcp *server.MCPServer) {
+	if mcpgrafana.WriteToolsEnabled() {
+		BootstrapWorkspace.Register(mcp)
+	}
+}
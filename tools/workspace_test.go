@@ -0,0 +1,177 @@
+//go:build unit
+// +build unit
+
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWorkspaceGrafana is a Grafana instance with OnCall, recording the
+// resources created by a workspace bootstrap.
+type fakeWorkspaceGrafana struct {
+	mu         sync.Mutex
+	folders    map[string]string
+	dashboards map[string]map[string]any
+	ruleGroups map[string]map[string]any
+	routes     []map[string]any
+}
+
+func (f *fakeWorkspaceGrafana) serve(t *testing.T) *httptest.Server {
+	f.folders = map[string]string{}
+	f.dashboards = map[string]map[string]any{}
+	f.ruleGroups = map[string]map[string]any{}
+	decode := func(w http.ResponseWriter, r *http.Request) map[string]any {
+		var body map[string]any
+		_ = decodeJSONBody(t, w, r, &body)
+		return body
+	}
+	var server *httptest.Server
+	routes := fakeRoutes{
+		"GET /api/folders": func(w http.ResponseWriter, r *http.Request) {
+			folders := []map[string]string{}
+			for title, uid := range f.folders {
+				folders = append(folders, map[string]string{"uid": uid, "title": title})
+			}
+			_ = json.NewEncoder(w).Encode(folders)
+		},
+		"POST /api/folders": func(w http.ResponseWriter, r *http.Request) {
+			title := decode(w, r)["title"].(string)
+			f.folders[title] = "f-" + title
+			fmt.Fprintf(w, `{"uid": %q}`, "f-"+title)
+		},
+		"/api/dashboards/db": func(w http.ResponseWriter, r *http.Request) {
+			body := decode(w, r)
+			dashboard := body["dashboard"].(map[string]any)
+			f.dashboards[dashboard["uid"].(string)] = body
+			fmt.Fprintf(w, `{"uid": %q, "url": "/d/%s/x", "status": "success"}`, dashboard["uid"], dashboard["uid"])
+		},
+		"/api/dashboards/uid/checkout-overview": func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := f.dashboards["checkout-overview"]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"message": "Dashboard not found"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"dashboard": {"uid": "checkout-overview"}, "meta": {"url": "/d/checkout-overview/x"}}`))
+		},
+		"/api/v1/provisioning/folder/f-checkout/rule-groups/checkout baseline": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPut {
+				f.ruleGroups["checkout baseline"] = decode(w, r)
+			}
+			group, ok := f.ruleGroups["checkout baseline"]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"message": "rule group not found"}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(group)
+		},
+		"/api/plugins/grafana-irm-app/settings": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"jsonData": {"onCallApiUrl": %q}}`, server.URL)
+		},
+		"GET /api/v1/routes": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]any{"count": len(f.routes), "results": f.routes})
+		},
+		"/api/v1/routes/": func(w http.ResponseWriter, r *http.Request) {
+			route := decode(w, r)
+			route["id"] = "R1"
+			f.routes = append(f.routes, route)
+			_ = json.NewEncoder(w).Encode(route)
+		},
+	}
+	for path, handler := range routes {
+		routes[path] = func(w http.ResponseWriter, r *http.Request) {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			handler(w, r)
+		}
+	}
+	server = newFakeGrafana(t, routes)
+	return server
+}
+
+func TestBootstrapWorkspace(t *testing.T) {
+	f := &fakeWorkspaceGrafana{}
+	server := f.serve(t)
+	ctx := fakeGrafanaContext(server)
+	args := BootstrapWorkspaceParams{
+		ServiceName:           "checkout",
+		Team:                  "payments",
+		PrometheusUID:         "prom",
+		LokiUID:               "loki",
+		OnCallIntegrationID:   "I1",
+		OnCallEscalationChain: "E1",
+	}
+
+	t.Run("creates resources", func(t *testing.T) {
+		result, err := bootstrapWorkspace(ctx, args)
+		require.NoError(t, err)
+		assert.Equal(t, "f-checkout", result.FolderUID)
+		require.Len(t, result.Resources, 4)
+		for _, r := range result.Resources {
+			assert.Equal(t, workspaceActionCreated, r.Action, r.Kind)
+		}
+		assert.Equal(t, "R1", result.Resources[3].UID)
+
+		saved := f.dashboards["checkout-overview"]
+		assert.Equal(t, "f-checkout", saved["folderUid"])
+		panels := saved["dashboard"].(map[string]any)["panels"].([]any)
+		require.Len(t, panels, 5)
+		assert.Equal(t, "logs", panels[4].(map[string]any)["type"])
+		assert.Equal(t, `{job="checkout"}`, panels[4].(map[string]any)["targets"].([]any)[0].(map[string]any)["expr"])
+
+		rules := f.ruleGroups["checkout baseline"]["rules"].([]any)
+		require.Len(t, rules, 3)
+		rule := rules[0].(map[string]any)
+		assert.Equal(t, "checkout down", rule["title"])
+		assert.Equal(t, map[string]any{"service": "checkout", "team": "payments"}, rule["labels"])
+		assert.Equal(t, "checkout-overview", rule["annotations"].(map[string]any)["__dashboardUid__"])
+		assert.Equal(t, "Alerting", rule["noDataState"])
+		assert.Equal(t, "NoData", rules[1].(map[string]any)["noDataState"])
+
+		require.Len(t, f.routes, 1)
+		assert.Equal(t, "E1", f.routes[0]["escalation_chain_id"])
+		assert.Equal(t, `{{ payload.commonLabels.service == "checkout" }}`, f.routes[0]["routing_regex"])
+	})
+
+	t.Run("keeps existing resources", func(t *testing.T) {
+		result, err := bootstrapWorkspace(ctx, args)
+		require.NoError(t, err)
+		require.Len(t, result.Resources, 4)
+		for _, r := range result.Resources {
+			assert.Equal(t, workspaceActionExists, r.Action, r.Kind)
+		}
+		assert.Len(t, f.routes, 1)
+	})
+
+	t.Run("without OnCall route", func(t *testing.T) {
+		result, err := bootstrapWorkspace(ctx, BootstrapWorkspaceParams{ServiceName: "checkout", PrometheusUID: "prom"})
+		require.NoError(t, err)
+		require.Len(t, result.Resources, 3)
+		assert.Equal(t, []string{"folder", "dashboard", "alertRuleGroup"}, []string{result.Resources[0].Kind, result.Resources[1].Kind, result.Resources[2].Kind})
+		assert.Equal(t, []WorkspaceResource{{Kind: "oncallRoute", Action: workspaceActionSkipped}}, result.Skipped)
+	})
+
+	t.Run("worker template", func(t *testing.T) {
+		queries := workspaceQueries(workspaceTemplateWorker, `job="batch"`)
+		require.Len(t, queries, 3)
+		rules := baselineRules(BootstrapWorkspaceParams{ServiceName: "batch", PrometheusUID: "prom"}, "f", "batch baseline", "batch-overview", queries)
+		require.Len(t, rules, 1)
+		assert.Equal(t, map[string]string{"service": "batch"}, rules[0].(map[string]any)["labels"])
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		_, err := bootstrapWorkspace(ctx, BootstrapWorkspaceParams{ServiceName: "Check Out", PrometheusUID: "prom"})
+		assert.ErrorContains(t, err, "invalid service name")
+		_, err = bootstrapWorkspace(ctx, BootstrapWorkspaceParams{ServiceName: "checkout", PrometheusUID: "prom", OnCallIntegrationID: "I1"})
+		assert.ErrorContains(t, err, "must be given together")
+	})
+}
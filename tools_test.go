@@ -524,3 +524,31 @@ func TestCreateJSONSchemaFromHandler(t *testing.T) {
 	assert.Equal(t, "boolean", optionalProperty.Type)
 	assert.Equal(t, "An optional parameter", optionalProperty.Description)
 }
+
+func TestWithCallArguments(t *testing.T) {
+	registry.mu.Lock()
+	limits := registry.limits
+	registry.limits = map[string]any{}
+	registry.mu.Unlock()
+	t.Cleanup(func() {
+		registry.mu.Lock()
+		registry.limits = limits
+		registry.mu.Unlock()
+	})
+	tool := MustTool("test_call_arguments", "A tool", func(ctx context.Context, args testToolParams) (string, error) { return "", nil })
+
+	assert.Equal(t, tool.Tool, withCallArguments(tool.Tool), "the schema is unchanged without call arguments")
+
+	RecordLimit("maxCallTimeoutSeconds", 600.0)
+	RecordLimit("maxCallRetries", 5)
+	extended := withCallArguments(tool.Tool)
+	assert.Equal(t, map[string]any{"type": "integer", "minimum": 1, "maximum": 600, "description": "Optionally, the timeout of this call in seconds, overriding the server's default, up to 600"}, extended.InputSchema.Properties[TimeoutArgument])
+	assert.Contains(t, extended.InputSchema.Properties, MaxRetriesArgument)
+	assert.Contains(t, extended.InputSchema.Properties, "name")
+	assert.NotContains(t, extended.InputSchema.Required, TimeoutArgument)
+	assert.NotContains(t, tool.Tool.InputSchema.Properties, TimeoutArgument, "the tool itself is unchanged")
+
+	RecordLimit("maxCallTimeoutSeconds", 0.0)
+	RecordLimit("maxCallRetries", 0)
+	assert.Equal(t, tool.Tool, withCallArguments(tool.Tool))
+}
@@ -0,0 +1,264 @@
+package mcpgrafana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// truncationReserveBytes is the part of the byte budget reserved for the
+// truncation marker and continuation hints added to truncated results.
+const truncationReserveBytes = 512
+
+// maxTrimmedArrays is the maximum number of arrays trimmed when truncating a
+// JSON result, before falling back to cutting the serialized text.
+const maxTrimmedArrays = 16
+
+// narrowingParameters are tool parameters which can typically be used to
+// retrieve a smaller or different part of a result, in order of preference.
+var narrowingParameters = []string{
+	"limit", "page", "offset", "perpage",
+	"startRfc3339", "endRfc3339", "startTime", "endTime",
+	"label_selectors", "labelSelectors", "matches", "name", "query",
+}
+
+// TruncationConfig configures the truncation of large tool results.
+type TruncationConfig struct {
+	// MaxBytes is the maximum size in bytes of the text content of a tool
+	// result. Larger results are truncated. Zero disables truncation.
+	MaxBytes int
+}
+
+// Enabled returns true if truncation is enabled.
+func (c TruncationConfig) Enabled() bool {
+	return c.MaxBytes > 0
+}
+
+// trimmedArray describes an array that was shortened to fit a JSON result
+// within the byte budget.
+type trimmedArray struct {
+	Path          string `json:"path"`
+	TotalItems    int    `json:"totalItems"`
+	ReturnedItems int    `json:"returnedItems"`
+}
+
+type truncationInfo struct {
+	BudgetBytes   int            `json:"budgetBytes"`
+	OriginalBytes int            `json:"originalBytes"`
+	Trimmed       []trimmedArray `json:"trimmed,omitempty"`
+	Hint          string         `json:"hint"`
+}
+
+// jsonArrayRef refers to an array within a decoded JSON document, allowing
+// it to be replaced.
+type jsonArrayRef struct {
+	path  string
+	items []any
+	set   func([]any)
+}
+
+func collectArrays(v any, path string, out *[]jsonArrayRef) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			childPath := k
+			if path != "" {
+				childPath = path + "." + k
+			}
+			if items, ok := child.([]any); ok {
+				*out = append(*out, jsonArrayRef{path: childPath, items: items, set: func(items []any) { v[k] = items }})
+			}
+			collectArrays(child, childPath, out)
+		}
+	case []any:
+		for i, child := range v {
+			childPath := fmt.Sprintf("%s[%d]", path, i)
+			if items, ok := child.([]any); ok {
+				*out = append(*out, jsonArrayRef{path: childPath, items: items, set: func(items []any) { v[i] = items }})
+			}
+			collectArrays(child, childPath, out)
+		}
+	}
+}
+
+func jsonSize(v any) int {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return len(b)
+}
+
+// trimJSON shortens the largest arrays in doc until it fits within budget
+// bytes when serialized, returning the arrays that were trimmed.
+func trimJSON(doc map[string]any, budget int) []trimmedArray {
+	var trimmed []trimmedArray
+	for range maxTrimmedArrays {
+		if jsonSize(doc) <= budget {
+			break
+		}
+		var refs []jsonArrayRef
+		collectArrays(doc, "", &refs)
+		largest, largestSize := -1, 0
+		for i, ref := range refs {
+			if len(ref.items) == 0 {
+				continue
+			}
+			if size := jsonSize(ref.items); size > largestSize {
+				largest, largestSize = i, size
+			}
+		}
+		if largest < 0 {
+			break
+		}
+		ref := refs[largest]
+		// Find the largest prefix of the array for which the document fits.
+		lo, hi := 0, len(ref.items)-1
+		for lo < hi {
+			mid := (lo + hi + 1) / 2
+			ref.set(ref.items[:mid])
+			if jsonSize(doc) <= budget {
+				lo = mid
+			} else {
+				hi = mid - 1
+			}
+		}
+		ref.set(ref.items[:lo])
+		trimmed = append(trimmed, trimmedArray{Path: ref.path, TotalItems: len(ref.items), ReturnedItems: lo})
+	}
+	return trimmed
+}
+
+// continuationHint returns a hint telling the client how to retrieve the
+// rest of a truncated result from the given tool.
+func continuationHint(tool string) string {
+	params := ToolParameters(tool)
+	var supported []string
+	for _, p := range narrowingParameters {
+		if slices.Contains(params, p) {
+			supported = append(supported, p)
+		}
+	}
+	if len(supported) == 0 {
+		return "The result was too large and has been truncated. Make a more specific request to see the rest."
+	}
+	return fmt.Sprintf("The result was too large and has been truncated. Use the %s parameters of %s to make a more specific request or page through the rest.", strings.Join(supported, ", "), tool)
+}
+
+// cutText cuts text to at most budget bytes without splitting a UTF-8
+// encoded character, appending a truncation marker. The marker itself is cut
+// if the budget is smaller than it.
+func cutText(text string, budget int, hint string) string {
+	marker := fmt.Sprintf("\n\n[truncated: true. The output exceeded the %d byte budget. %s]", budget, hint)
+	if budget <= len(marker) {
+		return cutString(marker, budget)
+	}
+	return cutString(text, budget-len(marker)) + marker
+}
+
+// cutString returns the longest prefix of s of at most n bytes which doesn't
+// split a UTF-8 encoded character.
+func cutString(s string, n int) string {
+	if n >= len(s) {
+		return s
+	}
+	n = max(0, n)
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// truncateText truncates a single text result to the given budget. JSON
+// results are kept valid by trimming their largest arrays and adding a
+// `truncated: true` marker with details of what was removed; other text is
+// cut and suffixed with a marker.
+func truncateText(text string, budget int, hint string) string {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err == nil && !dec.More() {
+		var doc map[string]any
+		switch v := v.(type) {
+		case map[string]any:
+			doc = v
+		case []any:
+			doc = map[string]any{"items": v}
+		}
+		if doc != nil {
+			info := truncationInfo{BudgetBytes: budget, OriginalBytes: len(text), Hint: hint}
+			info.Trimmed = trimJSON(doc, budget-truncationReserveBytes)
+			doc["truncated"] = true
+			doc["truncation"] = info
+			var buf bytes.Buffer
+			enc := json.NewEncoder(&buf)
+			enc.SetEscapeHTML(false)
+			if err := enc.Encode(doc); err == nil && buf.Len() <= budget {
+				return strings.TrimSuffix(buf.String(), "\n")
+			}
+		}
+	}
+	return cutText(text, budget, hint)
+}
+
+// TruncationMiddleware returns a tool handler middleware which truncates
+// the text content of tool results larger than the configured byte budget.
+// Truncated results are marked with `truncated: true`, both in the content
+// and in the result's metadata, and include hints on how to retrieve the
+// rest of the result.
+func TruncationMiddleware(config TruncationConfig) server.ToolHandlerMiddleware {
+	RecordLimit("maxOutputBytes", config.MaxBytes)
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			result, err := next(ctx, request)
+			if err != nil || result == nil {
+				return result, err
+			}
+			size := 0
+			for _, c := range result.Content {
+				if text, ok := c.(mcp.TextContent); ok {
+					size += len(text.Text)
+				}
+			}
+			if size <= config.MaxBytes {
+				return result, nil
+			}
+
+			hint := continuationHint(request.Params.Name)
+			remaining := config.MaxBytes
+			content := make([]mcp.Content, 0, len(result.Content))
+			for _, c := range result.Content {
+				text, ok := c.(mcp.TextContent)
+				if !ok {
+					content = append(content, c)
+					continue
+				}
+				// Text following a truncated item is dropped.
+				if remaining == 0 {
+					continue
+				}
+				if len(text.Text) > remaining {
+					text.Text = truncateText(text.Text, remaining, hint)
+					remaining = 0
+				} else {
+					remaining -= len(text.Text)
+				}
+				content = append(content, text)
+			}
+			result.Content = content
+			if result.Meta == nil {
+				result.Meta = map[string]any{}
+			}
+			result.Meta["truncated"] = true
+			result.Meta["originalBytes"] = size
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,123 @@
+//go:build unit
+// +build unit
+
+package mcpgrafana
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type truncationTestParams struct {
+	Limit int    `json:"limit" jsonschema:"description=Limit"`
+	Query string `json:"query" jsonschema:"description=Query"`
+}
+
+func TestTruncateText(t *testing.T) {
+	t.Run("json object", func(t *testing.T) {
+		panels := make([]any, 200)
+		for i := range panels {
+			panels[i] = map[string]any{"id": i, "title": strings.Repeat("x", 50)}
+		}
+		b, err := json.Marshal(map[string]any{"title": "Big dashboard", "panels": panels})
+		require.NoError(t, err)
+
+		out := truncateText(string(b), 2000, "hint")
+		assert.LessOrEqual(t, len(out), 2000)
+
+		var doc map[string]any
+		require.NoError(t, json.Unmarshal([]byte(out), &doc))
+		assert.Equal(t, true, doc["truncated"])
+		assert.Equal(t, "Big dashboard", doc["title"])
+		truncation := doc["truncation"].(map[string]any)
+		assert.Equal(t, "hint", truncation["hint"])
+		assert.Equal(t, float64(len(b)), truncation["originalBytes"])
+		trimmed := truncation["trimmed"].([]any)[0].(map[string]any)
+		assert.Equal(t, "panels", trimmed["path"])
+		assert.Equal(t, float64(200), trimmed["totalItems"])
+		assert.Equal(t, float64(len(doc["panels"].([]any))), trimmed["returnedItems"])
+		assert.NotEmpty(t, doc["panels"])
+	})
+
+	t.Run("json array", func(t *testing.T) {
+		items := make([]string, 100)
+		for i := range items {
+			items[i] = fmt.Sprintf("line %d %s", i, strings.Repeat("y", 40))
+		}
+		b, err := json.Marshal(items)
+		require.NoError(t, err)
+
+		out := truncateText(string(b), 1500, "hint")
+		assert.LessOrEqual(t, len(out), 1500)
+		var doc map[string]any
+		require.NoError(t, json.Unmarshal([]byte(out), &doc))
+		assert.Equal(t, true, doc["truncated"])
+		assert.Equal(t, "line 0 "+strings.Repeat("y", 40), doc["items"].([]any)[0])
+	})
+
+	t.Run("plain text", func(t *testing.T) {
+		text := strings.Repeat("é", 1000)
+		out := truncateText(text, 500, "hint")
+		assert.LessOrEqual(t, len(out), 500)
+		assert.Contains(t, out, "[truncated: true.")
+		assert.True(t, strings.HasPrefix(out, "éé"))
+		assert.True(t, json.Valid([]byte(fmt.Sprintf("%q", out))))
+	})
+
+	t.Run("budget smaller than the marker", func(t *testing.T) {
+		out := truncateText(strings.Repeat("a", 100), 20, "hint")
+		assert.Equal(t, "\n\n[truncated: true. ", out)
+		assert.Empty(t, truncateText("abc", 0, "hint"))
+	})
+}
+
+func TestTruncationMiddleware(t *testing.T) {
+	s := server.NewMCPServer("test", "0.0.0")
+	tool := MustTool("test_truncation", "A tool with a large result", func(ctx context.Context, args truncationTestParams) (string, error) {
+		return "", nil
+	})
+	tool.Register(s)
+
+	output := strings.Repeat("a", 100)
+	handler := TruncationMiddleware(TruncationConfig{MaxBytes: 1000})(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText(output), nil
+	})
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "test_truncation"
+
+	result, err := handler(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, output, result.Content[0].(mcp.TextContent).Text)
+	assert.Nil(t, result.Meta)
+
+	output = strings.Repeat("a", 5000)
+	result, err = handler(context.Background(), request)
+	require.NoError(t, err)
+	text := result.Content[0].(mcp.TextContent).Text
+	assert.LessOrEqual(t, len(text), 1000)
+	assert.Contains(t, text, "Use the limit, query parameters of test_truncation")
+	assert.Equal(t, true, result.Meta["truncated"])
+	assert.Equal(t, 5000, result.Meta["originalBytes"])
+
+	// Text items after the budget is spent are dropped.
+	handler = TruncationMiddleware(TruncationConfig{MaxBytes: 1000})(func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return &mcp.CallToolResult{Content: []mcp.Content{
+			mcp.NewTextContent(strings.Repeat("a", 600)),
+			mcp.NewTextContent(strings.Repeat("b", 600)),
+			mcp.NewTextContent(strings.Repeat("c", 600)),
+		}}, nil
+	})
+	result, err = handler(context.Background(), request)
+	require.NoError(t, err)
+	require.Len(t, result.Content, 2)
+	assert.Equal(t, strings.Repeat("a", 600), result.Content[0].(mcp.TextContent).Text)
+	assert.LessOrEqual(t, len(result.Content[1].(mcp.TextContent).Text), 400)
+}